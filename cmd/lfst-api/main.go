@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os"
+
+	"github.com/mslinn/git-lfs-test/pkg/cli/apicmd"
+)
+
+var version = "dev" // Set by -ldflags during build
+
+func main() {
+	apicmd.Main(version, os.Args[1:])
+}
@@ -4,8 +4,26 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
-	"syscall"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/cli/apicmd"
+	"github.com/mslinn/git-lfs-test/pkg/cli/checksumcmd"
+	"github.com/mslinn/git-lfs-test/pkg/cli/configcmd"
+	"github.com/mslinn/git-lfs-test/pkg/cli/evalrepocmd"
+	"github.com/mslinn/git-lfs-test/pkg/cli/importcmd"
+	"github.com/mslinn/git-lfs-test/pkg/cli/querycmd"
+	"github.com/mslinn/git-lfs-test/pkg/cli/runcmd"
+	"github.com/mslinn/git-lfs-test/pkg/cli/scenariocmd"
+	"github.com/mslinn/git-lfs-test/pkg/cli/testdatacmd"
+	"github.com/mslinn/git-lfs-test/pkg/completion"
+	"github.com/mslinn/git-lfs-test/pkg/config"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var version = "dev" // Set by -ldflags during build
@@ -21,75 +39,288 @@ var subcommands = []struct {
 	{"import", "Import checksum data"},
 	{"run", "Manage test run lifecycle"},
 	{"query", "Query and report on test data"},
+	{"api", "Serve test results as a JSON API"},
 	{"testdata", "Download Git LFS test data files"},
 	{"create-eval-repo", "Create Git LFS evaluation repository"},
+	{"completion", "Write a shell completion script"},
+}
+
+// tools define the flags of the subcommands, all but completion, on the flag set of their
+// command and return the function that runs them with the arguments left after the flags
+var tools = map[string]func(version string, fs *pflag.FlagSet) func(args []string){
+	"config":           configcmd.Command,
+	"scenario":         scenariocmd.Command,
+	"checksum":         checksumcmd.Command,
+	"import":           importcmd.Command,
+	"run":              runcmd.Command,
+	"query":            querycmd.Command,
+	"api":              apicmd.Command,
+	"testdata":         testdatacmd.Command,
+	"create-eval-repo": evalrepocmd.Command,
+}
+
+// toolCommands return the flags of the commands of the subcommands that have commands
+var toolCommands = map[string]func() map[string]*pflag.FlagSet{
+	"config":   configcmd.Commands,
+	"checksum": checksumcmd.Commands,
+	"run":      runcmd.Commands,
+	"query":    querycmd.Commands,
+	"testdata": testdatacmd.Commands,
 }
 
+// globalFlags are the options of lfst itself, before the subcommand
+var globalFlags = []string{"--help", "--version", "--quiet", "--no-color", "--log-file", "--log-format"}
+
+// positionals are the kinds of value the arguments of subcommands take, for completion
+var positionals = map[string]string{
+	"scenario":         completion.Scenarios,
+	"create-eval-repo": completion.Scenarios,
+	"run":              completion.Runs,
+}
+
+// completedRuns is how many of the latest runs are offered when completing a run ID
+const completedRuns = 100
+
 func main() {
-	// Handle version flag
-	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-V") {
-		fmt.Printf("lfst version %s\n", version)
-		os.Exit(0)
+	if err := newRootCommand().Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		printUsage()
+		os.Exit(1)
 	}
+}
 
-	// Handle help flag
-	if len(os.Args) == 1 || os.Args[1] == "--help" || os.Args[1] == "-h" {
-		printHelp()
-		os.Exit(0)
+// newRootCommand returns the lfst command, with a command for each of subcommands. The
+// flags of each tool are registered on its command and the tools print their own help,
+// so lfst T behaves like lfst-T.
+func newRootCommand() *cobra.Command {
+	var (
+		showVersion bool
+		quiet       bool
+		noColor     bool
+		logFile     string
+		logFormat   string
+	)
+
+	root := &cobra.Command{
+		Use:              "lfst",
+		Args:             cobra.ArbitraryArgs,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+		TraverseChildren: true,
+		CompletionOptions: cobra.CompletionOptions{
+			DisableDefaultCmd: true,
+		},
+		// Output options before the subcommand apply to it through the environment
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if quiet {
+				os.Setenv(term.QuietEnv, "1")
+			}
+			if noColor {
+				os.Setenv(term.NoColorEnv, "1")
+			}
+			if logFile != "" {
+				os.Setenv(log.FileEnv, logFile)
+			}
+			if logFormat != "" {
+				os.Setenv(log.FormatEnv, logFormat)
+			}
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case showVersion:
+				fmt.Printf("lfst version %s\n", version)
+			case len(args) > 0:
+				return fmt.Errorf("unknown subcommand '%s'", args[0])
+			case cmd.Flags().NFlag() > 0:
+				return fmt.Errorf("no subcommand")
+			default:
+				printHelp()
+			}
+			return nil
+		},
 	}
+	root.SetHelpFunc(func(cmd *cobra.Command, args []string) { printHelp() })
 
-	// Get subcommand
-	subcommand := os.Args[1]
+	flags := root.Flags()
+	flags.BoolVarP(&showVersion, "version", "V", false, "Show version")
+	flags.BoolVarP(&quiet, "quiet", "q", false, "Print errors only")
+	flags.BoolVar(&noColor, "no-color", false, "Plain output without colors")
+	flags.StringVar(&logFile, "log-file", "", "Also append every message, debug included, to PATH")
+	flags.StringVar(&logFormat, "log-format", "", "Format of the log file: text or json")
 
-	// Check if it's a valid subcommand
-	validSubcommand := false
 	for _, sc := range subcommands {
-		if sc.name == subcommand {
-			validSubcommand = true
-			break
+		cmd := &cobra.Command{Use: sc.name, Short: sc.description}
+		var run func(args []string)
+		if sc.name == "completion" {
+			run = completionCommand(root, cmd.Flags())
+		} else {
+			run = tools[sc.name](version, cmd.Flags())
 		}
+		cmd.Run = func(cmd *cobra.Command, args []string) { run(args) }
+		// lfst T --help and lfst help T print the help of the tool
+		cmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+			cmd.Flags().Set("help", "true")
+			run(nil)
+		})
+		root.AddCommand(cmd)
 	}
+	return root
+}
 
-	if !validSubcommand {
-		fmt.Fprintf(os.Stderr, "Error: unknown subcommand '%s'\n\n", subcommand)
-		printUsage()
-		os.Exit(1)
+// completionCommand defines the flags of lfst completion on fs and returns the function
+// that writes the completion script of a shell for the commands of root, or with --list
+// the scenario or run IDs that the scripts offer
+func completionCommand(root *cobra.Command, fs *pflag.FlagSet) func(args []string) {
+	list := fs.String("list", "", "Print the IDs the completion scripts offer: runs or scenarios")
+	help := fs.BoolP("help", "h", false, "Show this help message")
+
+	return func(args []string) {
+		if *help {
+			printCompletionHelp(fs)
+			return
+		}
+
+		if *list != "" {
+			var values []completion.Value
+			var err error
+			switch *list {
+			case completion.Runs:
+				values, err = runValues()
+			case completion.Scenarios:
+				values, err = scenarioValues()
+			default:
+				err = fmt.Errorf("invalid --list '%s' (use %s)", *list, strings.Join(completion.Kinds, " or "))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			completion.WriteValues(os.Stdout, values)
+			return
+		}
+
+		if len(args) != 1 || !slices.Contains(completion.Shells, args[0]) {
+			fmt.Fprintf(os.Stderr, "Usage: lfst completion %s\n", strings.Join(completion.Shells, "|"))
+			os.Exit(1)
+		}
+		if err := completion.Write(os.Stdout, args[0], completionCommands(root), globalFlags); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
+}
 
-	// Build the command name
-	cmdName := "lfst-" + subcommand
+// completionCommands returns the subcommands of root with the flags registered on each,
+// and on the commands of the tools that have commands
+func completionCommands(root *cobra.Command) []completion.Command {
+	var commands []completion.Command
+	for _, sc := range subcommands {
+		cmd, _, err := root.Find([]string{sc.name})
+		if err != nil {
+			continue
+		}
+		command := completion.Command{
+			Name:        sc.name,
+			Description: sc.description,
+			Flags:       completion.Flags(cmd.Flags()),
+			Positional:  positionals[sc.name],
+		}
+		if sc.name == "completion" {
+			command.Commands = completion.Shells
+		}
+		if toolCommands[sc.name] != nil {
+			for name, fs := range toolCommands[sc.name]() {
+				command.Commands = append(command.Commands, name)
+				command.Flags = append(command.Flags, completion.Flags(fs)...)
+			}
+			slices.Sort(command.Commands)
+			slices.Sort(command.Flags)
+			command.Flags = slices.Compact(command.Flags)
+		}
+		commands = append(commands, command)
+	}
+	return commands
+}
 
-	// Find the full path to the command
-	cmdPath, err := exec.LookPath(cmdName)
+// scenarioValues returns the scenarios that lfst scenario --list prints
+func scenarioValues() ([]completion.Value, error) {
+	self, err := os.Executable()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: command '%s' not found in PATH\n", cmdName)
-		fmt.Fprintf(os.Stderr, "Make sure it is installed (try: sudo make install)\n")
-		os.Exit(1)
+		return nil, err
 	}
-
-	// Prepare arguments (skip 'lfst' and the subcommand name)
-	args := []string{filepath.Base(cmdPath)}
-	if len(os.Args) > 2 {
-		args = append(args, os.Args[2:]...)
+	output, err := exec.Command(self, "scenario", "--list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("lfst scenario --list failed: %w", err)
 	}
+	return completion.ParseScenarioList(string(output)), nil
+}
 
-	// Execute the subcommand using execve (replaces current process)
-	// This ensures the subcommand receives signals directly
-	if err := syscall.Exec(cmdPath, args, os.Environ()); err != nil {
-		// If exec fails, fall back to running as subprocess
-		cmd := exec.Command(cmdPath, args[1:]...)
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		if err := cmd.Run(); err != nil {
-			if exitErr, ok := err.(*exec.ExitError); ok {
-				os.Exit(exitErr.ExitCode())
-			}
-			fmt.Fprintf(os.Stderr, "Error executing %s: %v\n", cmdName, err)
-			os.Exit(1)
+// runValues returns the latest test runs of the configured database, newest first. A
+// database that does not exist yet has none; completing never creates it.
+func runValues() ([]completion.Value, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	dbPath, err := cfg.GetDatabasePath()
+	if err != nil {
+		return nil, err
+	}
+	if !database.IsPostgresURL(dbPath) {
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			return nil, nil
 		}
 	}
+	db, err := database.Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	runs, err := db.ListTestRuns()
+	if err != nil {
+		return nil, err
+	}
+	var values []completion.Value
+	for _, run := range runs[:min(len(runs), completedRuns)] {
+		values = append(values, completion.Value{
+			Name: strconv.FormatInt(run.ID, 10),
+			Description: fmt.Sprintf("scenario %d, %s, %s %s", run.ScenarioID, run.ServerType, run.Status,
+				run.StartedAt.Format("2006-01-02 15:04")),
+		})
+	}
+	return values, nil
+}
+
+func printCompletionHelp(fs *pflag.FlagSet) {
+	fmt.Printf("lfst completion - Write a shell completion script\n\n")
+	fmt.Printf("Version: %s\n\n", version)
+	fmt.Printf("DESCRIPTION:\n")
+	fmt.Printf("  Writes a completion script for bash, zsh or fish to standard output. It completes\n")
+	fmt.Printf("  the lfst commands, the commands and flags of each of them as this lfst registers\n")
+	fmt.Printf("  them, scenario IDs from lfst scenario --list,\n")
+	fmt.Printf("  and the IDs of the latest %d runs in the configured database\n", completedRuns)
+	fmt.Printf("  after %s. Write the script again after\n", strings.Join(completion.RunFlags, ", "))
+	fmt.Printf("  upgrading lfst; IDs are always looked up when completing.\n\n")
+
+	fmt.Printf("USAGE:\n")
+	fmt.Printf("  lfst completion %s\n\n", strings.Join(completion.Shells, "|"))
+
+	fmt.Printf("OPTIONS:\n")
+	fs.PrintDefaults()
+
+	fmt.Printf("\nEXAMPLES:\n")
+	fmt.Printf("  # Complete in the current bash session\n")
+	fmt.Printf("  source <(lfst completion bash)\n\n")
+
+	fmt.Printf("  # Install for every bash session\n")
+	fmt.Printf("  lfst completion bash | sudo tee /etc/bash_completion.d/lfst\n\n")
+
+	fmt.Printf("  # Install for zsh, in a directory of $fpath\n")
+	fmt.Printf("  lfst completion zsh > \"${fpath[1]}/_lfst\"\n\n")
+
+	fmt.Printf("  # Install for fish\n")
+	fmt.Printf("  lfst completion fish > ~/.config/fish/completions/lfst.fish\n")
 }
 
 func printUsage() {
@@ -106,7 +337,8 @@ func printHelp() {
 	fmt.Printf("Version: %s\n\n", version)
 	fmt.Printf("DESCRIPTION:\n")
 	fmt.Printf("  Comprehensive testing framework for evaluating Git LFS server implementations.\n")
-	fmt.Printf("  This is a unified command that dispatches to the individual lfst-* tools.\n\n")
+	fmt.Printf("  This single command includes all the lfst-* tools: lfst T [options] works like\n")
+	fmt.Printf("  lfst-T [options], without lfst-T being installed.\n\n")
 
 	fmt.Printf("USAGE:\n")
 	fmt.Printf("  lfst <command> [options]\n\n")
@@ -117,8 +349,12 @@ func printHelp() {
 	}
 
 	fmt.Printf("\nGLOBAL OPTIONS:\n")
-	fmt.Printf("  -h, --help       Show this help message\n")
-	fmt.Printf("  -V, --version    Show version\n\n")
+	fmt.Printf("  -h, --help             Show this help message\n")
+	fmt.Printf("  -V, --version          Show version\n")
+	fmt.Printf("  -q, --quiet            Print errors only\n")
+	fmt.Printf("      --no-color         Plain output without colors (automatic when not a terminal)\n")
+	fmt.Printf("      --log-file PATH    Also append every message, debug included, to PATH\n")
+	fmt.Printf("      --log-format FMT   Format of the log file: text or json (default text)\n\n")
 
 	fmt.Printf("EXAMPLES:\n")
 	fmt.Printf("  # Show configuration\n")
@@ -133,9 +369,18 @@ func printHelp() {
 	fmt.Printf("  # Compute checksums for a directory\n")
 	fmt.Printf("  lfst checksum --skip-db --dir /path/to/repo\n\n")
 
+	fmt.Printf("  # Run a scenario from a CI job, printing only errors\n")
+	fmt.Printf("  lfst --quiet scenario 6\n\n")
+
+	fmt.Printf("  # Keep a JSON log of a long run, debug messages included, to audit it later\n")
+	fmt.Printf("  lfst --log-file run.log --log-format json scenario 6\n\n")
+
 	fmt.Printf("  # Query database statistics\n")
 	fmt.Printf("  lfst query stats\n\n")
 
+	fmt.Printf("  # Complete commands, flags, scenario and run IDs in bash\n")
+	fmt.Printf("  source <(lfst completion bash)\n\n")
+
 	fmt.Printf("GETTING STARTED:\n")
 	fmt.Printf("  1. Set up configuration:\n")
 	fmt.Printf("       lfst config init\n")
@@ -0,0 +1,301 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+// Server serves a read-only JSON API over the test database:
+//
+//	GET /api/runs                      list test runs (?scenario=N to filter)
+//	GET /api/runs/{id}                 run details with step results
+//	GET /api/runs/{id}/operations      operations of a run (?step=N to filter)
+//	GET /api/runs/{id}/diff?from=&to=  checksum differences between two steps or snapshot labels
+type Server struct {
+	DB          *database.DB
+	AllowOrigin string // Access-Control-Allow-Origin value for browser clients; empty disables CORS
+}
+
+// Run is the JSON representation of a test run
+type Run struct {
+	ID          int64      `json:"id"`
+	ScenarioID  int        `json:"scenario_id"`
+	ServerType  string     `json:"server_type"`
+	Protocol    string     `json:"protocol"`
+	GitServer   string     `json:"git_server"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Status      string     `json:"status"`
+	Notes       string     `json:"notes,omitempty"`
+}
+
+// Step is the JSON representation of a step result
+type Step struct {
+	StepNumber  int        `json:"step_number"`
+	Name        string     `json:"name,omitempty"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DurationMs  int64      `json:"duration_ms"`
+	Error       string     `json:"error,omitempty"`
+	Interface   string     `json:"interface,omitempty"`
+	RxBytes     *int64     `json:"rx_bytes,omitempty"`
+	TxBytes     *int64     `json:"tx_bytes,omitempty"`
+}
+
+// RunDetails is the response of GET /api/runs/{id}
+type RunDetails struct {
+	Run
+	Steps          []*Step `json:"steps"`
+	OperationCount int     `json:"operation_count"`
+	FailedCount    int     `json:"failed_count"`
+	DurationMs     int64   `json:"duration_ms"`
+}
+
+// Operation is the JSON representation of a timed operation
+type Operation struct {
+	ID               int64     `json:"id"`
+	StepNumber       int       `json:"step_number"`
+	Operation        string    `json:"operation"`
+	StartedAt        time.Time `json:"started_at"`
+	DurationMs       int64     `json:"duration_ms"`
+	FileCount        *int      `json:"file_count,omitempty"`
+	TotalBytes       *int64    `json:"total_bytes,omitempty"`
+	Status           string    `json:"status"`
+	Error            string    `json:"error,omitempty"`
+	SnapshotBeforeID *int64    `json:"snapshot_before_id,omitempty"`
+	SnapshotAfterID  *int64    `json:"snapshot_after_id,omitempty"`
+}
+
+// Difference is the JSON representation of a checksum difference
+type Difference struct {
+	FilePath   string `json:"file_path"`
+	OldPath    string `json:"old_path,omitempty"` // Previous path of a renamed file
+	ChangeType string `json:"change_type"`
+	OldCRC32   string `json:"old_crc32,omitempty"`
+	OldSize    int64  `json:"old_size,omitempty"`
+	NewCRC32   string `json:"new_crc32,omitempty"`
+	NewSize    int64  `json:"new_size,omitempty"`
+}
+
+// Diff is the response of GET /api/runs/{id}/diff
+type Diff struct {
+	RunID       int64         `json:"run_id"`
+	From        string        `json:"from"`
+	To          string        `json:"to"`
+	Differences []*Difference `json:"differences"`
+}
+
+// New creates an API server backed by db
+func New(db *database.DB) *Server {
+	return &Server{DB: db}
+}
+
+// Handler returns the HTTP handler serving the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/runs", s.handleListRuns)
+	mux.HandleFunc("GET /api/runs/{id}", s.handleGetRun)
+	mux.HandleFunc("GET /api/runs/{id}/operations", s.handleOperations)
+	mux.HandleFunc("GET /api/runs/{id}/diff", s.handleDiff)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if s.AllowOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", s.AllowOrigin)
+		}
+		mux.ServeHTTP(w, req)
+	})
+}
+
+func (s *Server) handleListRuns(w http.ResponseWriter, req *http.Request) {
+	var filter []int
+	if v := req.URL.Query().Get("scenario"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid scenario '%s'", v))
+			return
+		}
+		filter = append(filter, id)
+	}
+
+	runs, err := s.DB.ListTestRuns(filter...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	result := make([]*Run, 0, len(runs))
+	for _, run := range runs {
+		result = append(result, newRun(run))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleGetRun(w http.ResponseWriter, req *http.Request) {
+	run, ok := s.lookupRun(w, req)
+	if !ok {
+		return
+	}
+
+	results, err := s.DB.ListStepResults(run.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ops, err := s.DB.ListOperations(run.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	details := &RunDetails{Run: *newRun(run), Steps: make([]*Step, 0, len(results)), OperationCount: len(ops)}
+	for _, sr := range results {
+		details.Steps = append(details.Steps, &Step{
+			StepNumber:  sr.StepNumber,
+			Name:        sr.Name,
+			Status:      sr.Status,
+			StartedAt:   sr.StartedAt,
+			CompletedAt: sr.CompletedAt,
+			DurationMs:  sr.DurationMs,
+			Error:       sr.Error,
+			Interface:   sr.Interface,
+			RxBytes:     sr.RxBytes,
+			TxBytes:     sr.TxBytes,
+		})
+	}
+	for _, op := range ops {
+		details.DurationMs += op.DurationMs
+		if op.Status != "success" {
+			details.FailedCount++
+		}
+	}
+	writeJSON(w, http.StatusOK, details)
+}
+
+func (s *Server) handleOperations(w http.ResponseWriter, req *http.Request) {
+	run, ok := s.lookupRun(w, req)
+	if !ok {
+		return
+	}
+
+	step := 0
+	if v := req.URL.Query().Get("step"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid step '%s'", v))
+			return
+		}
+		step = n
+	}
+
+	ops, err := s.DB.ListOperations(run.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	result := make([]*Operation, 0, len(ops))
+	for _, op := range ops {
+		if step != 0 && op.StepNumber != step {
+			continue
+		}
+		result = append(result, &Operation{
+			ID:               op.ID,
+			StepNumber:       op.StepNumber,
+			Operation:        op.Operation,
+			StartedAt:        op.StartedAt,
+			DurationMs:       op.DurationMs,
+			FileCount:        op.FileCount,
+			TotalBytes:       op.TotalBytes,
+			Status:           op.Status,
+			Error:            op.Error,
+			SnapshotBeforeID: op.SnapshotBeforeID,
+			SnapshotAfterID:  op.SnapshotAfterID,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleDiff(w http.ResponseWriter, req *http.Request) {
+	run, ok := s.lookupRun(w, req)
+	if !ok {
+		return
+	}
+
+	from := req.URL.Query().Get("from")
+	to := req.URL.Query().Get("to")
+	if from == "" || to == "" {
+		writeError(w, http.StatusBadRequest, "from and to are required")
+		return
+	}
+
+	diffs, err := checksum.CompareSnapshots(s.DB, run.ID, from, to)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	result := &Diff{RunID: run.ID, From: from, To: to, Differences: make([]*Difference, 0, len(diffs))}
+	for _, d := range diffs {
+		result.Differences = append(result.Differences, &Difference{
+			FilePath:   d.FilePath,
+			OldPath:    d.OldPath,
+			ChangeType: d.ChangeType,
+			OldCRC32:   d.OldCRC32,
+			OldSize:    d.OldSize,
+			NewCRC32:   d.NewCRC32,
+			NewSize:    d.NewSize,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// lookupRun loads the run named by the {id} path parameter, writing an error response if it cannot
+func (s *Server) lookupRun(w http.ResponseWriter, req *http.Request) (*database.TestRun, bool) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid run id '%s'", req.PathValue("id")))
+		return nil, false
+	}
+
+	run, err := s.DB.GetTestRun(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("run %d not found", id))
+		return nil, false
+	}
+	return run, true
+}
+
+func newRun(run *database.TestRun) *Run {
+	return &Run{
+		ID:          run.ID,
+		ScenarioID:  run.ScenarioID,
+		ServerType:  run.ServerType,
+		Protocol:    run.Protocol,
+		GitServer:   run.GitServer,
+		StartedAt:   run.StartedAt,
+		CompletedAt: run.CompletedAt,
+		Status:      run.Status,
+		Notes:       run.Notes,
+	}
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
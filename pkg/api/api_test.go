@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+// newTestServer starts an API server over a database holding one run with two checksummed steps
+func newTestServer(t *testing.T) (*httptest.Server, *database.TestRun) {
+	t.Helper()
+
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	run := &database.TestRun{ScenarioID: 6, ServerType: "bare", Protocol: "local", GitServer: "bare", StartedAt: time.Now(), Status: "completed"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("Failed to create test run: %v", err)
+	}
+
+	ops := []*database.Operation{
+		{RunID: run.ID, StepNumber: 1, Operation: "init", StartedAt: time.Now(), DurationMs: 40, Status: "success"},
+		{RunID: run.ID, StepNumber: 2, Operation: "push", StartedAt: time.Now(), DurationMs: 900, Status: "failed", Error: "rejected"},
+	}
+	for _, op := range ops {
+		if err := db.CreateOperation(op); err != nil {
+			t.Fatalf("CreateOperation failed: %v", err)
+		}
+	}
+
+	if err := checksum.StoreChecksums(db, run.ID, 1, []*checksum.FileChecksum{
+		{Path: "a.pdf", CRC32: 0x11111111, SizeBytes: 10},
+	}, checksum.ConflictReplace); err != nil {
+		t.Fatalf("StoreChecksums failed: %v", err)
+	}
+	if err := checksum.StoreChecksums(db, run.ID, 2, []*checksum.FileChecksum{
+		{Path: "a.pdf", CRC32: 0x22222222, SizeBytes: 12},
+		{Path: "b.zip", CRC32: 0x33333333, SizeBytes: 20},
+	}, checksum.ConflictReplace); err != nil {
+		t.Fatalf("StoreChecksums failed: %v", err)
+	}
+
+	s := New(db)
+	s.AllowOrigin = "*"
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts, run
+}
+
+// get fetches path and decodes the JSON response into v
+func get(t *testing.T, ts *httptest.Server, path string, v interface{}) *http.Response {
+	t.Helper()
+
+	resp, err := http.Get(ts.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("Failed to decode %s: %v", path, err)
+		}
+	}
+	return resp
+}
+
+func TestListAndGetRuns(t *testing.T) {
+	ts, run := newTestServer(t)
+
+	var runs []*Run
+	resp := get(t, ts, "/api/runs", &runs)
+	if len(runs) != 1 || runs[0].ID != run.ID || runs[0].ScenarioID != 6 {
+		t.Fatalf("Unexpected runs: %+v", runs)
+	}
+	if resp.Header.Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("Expected CORS header")
+	}
+
+	get(t, ts, "/api/runs?scenario=3", &runs)
+	if len(runs) != 0 {
+		t.Errorf("Expected no runs for scenario 3, got %d", len(runs))
+	}
+
+	var details RunDetails
+	get(t, ts, "/api/runs/1", &details)
+	if details.OperationCount != 2 || details.FailedCount != 1 || details.DurationMs != 940 {
+		t.Errorf("Unexpected run details: %+v", details)
+	}
+
+	if resp := get(t, ts, "/api/runs/99", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Missing run status = %d, want 404", resp.StatusCode)
+	}
+	if resp := get(t, ts, "/api/runs/abc", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Invalid run id status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestOperationsAndDiff(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	var ops []*Operation
+	get(t, ts, "/api/runs/1/operations?step=2", &ops)
+	if len(ops) != 1 || ops[0].Operation != "push" || ops[0].Error != "rejected" {
+		t.Errorf("Unexpected operations: %+v", ops)
+	}
+
+	var diff Diff
+	get(t, ts, "/api/runs/1/diff?from=1&to=2", &diff)
+	changes := make(map[string]string)
+	for _, d := range diff.Differences {
+		changes[d.FilePath] = d.ChangeType
+	}
+	if changes["a.pdf"] == "" || changes["b.zip"] != "added" || len(changes) != 2 {
+		t.Errorf("Unexpected differences: %+v", changes)
+	}
+
+	if resp := get(t, ts, "/api/runs/1/diff?from=1", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Diff without 'to' status = %d, want 400", resp.StatusCode)
+	}
+}
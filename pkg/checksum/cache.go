@@ -0,0 +1,120 @@
+package checksum
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheFileName is the file in a run's directory that holds its checksum cache
+const CacheFileName = "checksum-cache.json"
+
+// racyWindow is how recently a file may have been modified for its checksum not to be
+// cached: a file rewritten with the same size within the same tick of a file system with
+// coarse timestamps (HFS+ and ext3 keep whole seconds) would look unchanged
+const racyWindow = time.Second
+
+// Cache remembers the checksums of files by path, size and modification time, so
+// checksumming the same tree again only reads the files that changed. It is safe for
+// concurrent use. Tools that rewrite a file and then restore its size and modification
+// time would fool it, which is why lfst-scenario --no-cache turns it off.
+type Cache struct {
+	path    string // File the cache is saved to; empty keeps it in memory
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	changed bool
+
+	hits, misses atomic.Int64
+}
+
+// cacheEntry is the checksum of a file as it was when it was read
+type cacheEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime_ns"`
+	CRC32   uint32 `json:"crc32"`
+}
+
+// NewCache returns an empty cache kept in memory
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]cacheEntry)}
+}
+
+// LoadCache returns the cache saved at path; a missing file gives an empty cache
+func LoadCache(path string) (*Cache, error) {
+	c := NewCache()
+	c.path = path
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksum cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse checksum cache %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the cache to the file it was loaded from, if it changed
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.path == "" || !c.changed {
+		return nil
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode checksum cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create checksum cache directory: %w", err)
+	}
+	// Write a new file and rename it, so an interrupted run never leaves half a cache
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checksum cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write checksum cache: %w", err)
+	}
+	c.changed = false
+	return nil
+}
+
+// lookupFile returns the checksum of the file at path if it has not changed since it
+// was read; a nil cache has none
+func (c *Cache) lookupFile(path string, info os.FileInfo) (uint32, bool) {
+	if c == nil {
+		return 0, false
+	}
+	c.mu.Lock()
+	e, ok := c.entries[path]
+	c.mu.Unlock()
+	if ok && e.Size == info.Size() && e.ModTime == info.ModTime().UnixNano() {
+		c.hits.Add(1)
+		return e.CRC32, true
+	}
+	c.misses.Add(1)
+	return 0, false
+}
+
+// putFile records the checksum of the file at path as info describes it
+func (c *Cache) putFile(path string, info os.FileInfo, crc uint32) {
+	if c == nil || time.Since(info.ModTime()) < racyWindow {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[path] = cacheEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), CRC32: crc}
+	c.changed = true
+}
+
+// Stats returns how many files were found unchanged in the cache and how many were read
+func (c *Cache) Stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
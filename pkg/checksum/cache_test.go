@@ -0,0 +1,97 @@
+package checksum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/workerpool"
+)
+
+// writeOld writes a file with a modification time an hour ago, outside racyWindow
+func writeOld(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestComputeDirectoryCached(t *testing.T) {
+	dir := t.TempDir()
+	writeOld(t, filepath.Join(dir, "video.mov"), "a large video")
+	writeOld(t, filepath.Join(dir, "doc.pdf"), "a document")
+	cachePath := filepath.Join(t.TempDir(), CacheFileName)
+
+	cache, err := LoadCache(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCache of a missing file failed: %v", err)
+	}
+	first, err := ComputeDirectoryCached(dir, nil, cache, workerpool.Options{})
+	if err != nil {
+		t.Fatalf("ComputeDirectoryCached failed: %v", err)
+	}
+	if hits, misses := cache.Stats(); hits != 0 || misses != 2 {
+		t.Errorf("first pass: %d hits, %d misses; want 0, 2", hits, misses)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// A resumed run loads the cache; a file changed since is read again
+	writeOld(t, filepath.Join(dir, "doc.pdf"), "a new document")
+	cache, err = LoadCache(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCache failed: %v", err)
+	}
+	second, err := ComputeDirectoryCached(dir, nil, cache, workerpool.Options{})
+	if err != nil {
+		t.Fatalf("ComputeDirectoryCached failed: %v", err)
+	}
+	if hits, misses := cache.Stats(); hits != 1 || misses != 1 {
+		t.Errorf("second pass: %d hits, %d misses; want 1, 1", hits, misses)
+	}
+	uncached, err := ComputeDirectory(dir)
+	if err != nil {
+		t.Fatalf("ComputeDirectory failed: %v", err)
+	}
+	for i := range uncached {
+		if *second[i] != *uncached[i] {
+			t.Errorf("cached checksum %+v, read %+v", second[i], uncached[i])
+		}
+	}
+	if second[1].CRC32 != first[1].CRC32 || second[0].CRC32 == first[0].CRC32 {
+		t.Errorf("only doc.pdf should have changed: %+v then %+v", first, second)
+	}
+}
+
+func TestCacheSkipsRecentFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "fresh.bin"), []byte("just written"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewCache()
+	for range 2 {
+		if _, err := ComputeDirectoryCached(dir, nil, cache, workerpool.Options{}); err != nil {
+			t.Fatalf("ComputeDirectoryCached failed: %v", err)
+		}
+	}
+	if hits, _ := cache.Stats(); hits != 0 {
+		t.Errorf("a file modified within racyWindow was taken from the cache %d times", hits)
+	}
+}
+
+func TestLoadCacheDamaged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), CacheFileName)
+	if err := os.WriteFile(path, []byte("{truncated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCache(path); err == nil {
+		t.Error("LoadCache should fail for a damaged file")
+	}
+}
@@ -1,16 +1,20 @@
 package checksum
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"hash/crc32"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/workerpool"
 )
 
 // FileChecksum represents a file's checksum and metadata
@@ -45,49 +49,96 @@ func ComputeFile(path string) (*FileChecksum, error) {
 	}, nil
 }
 
+// DefaultExclude matches the files operating systems and editors leave in directories,
+// which would otherwise show up as spurious added files
+var DefaultExclude = []string{".DS_Store", "._*", "[Tt]humbs.db", "desktop.ini", "*.swp", "*.swo", "*~", ".#*"}
+
 // ComputeDirectory recursively computes checksums for all files in a directory
-// It skips .git directories and the .checksums file
+// It skips .git directories, the .checksums file and the files matching DefaultExclude
 func ComputeDirectory(dir string) ([]*FileChecksum, error) {
-	var checksums []*FileChecksum
+	return ComputeDirectoryWith(dir, DefaultExclude, workerpool.Options{})
+}
+
+// ComputeDirectoryWith is ComputeDirectory with its own exclusion patterns, which match
+// like those of Filter, and control over parallelism and progress reporting
+func ComputeDirectoryWith(dir string, exclude []string, opts workerpool.Options) ([]*FileChecksum, error) {
+	return ComputeDirectoryCached(dir, exclude, nil, opts)
+}
+
+// ComputeDirectoryCached is ComputeDirectoryWith taking the checksums of the files that
+// have not changed from cache, and adding those of the others to it; a nil cache reads
+// every file
+func ComputeDirectoryCached(dir string, exclude []string, cache *Cache, opts workerpool.Options) ([]*FileChecksum, error) {
+	var paths []string
+	var infos []os.FileInfo
+	var totalBytes int64
 
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		excluded := relPath != "." && matchAny(exclude, filepath.ToSlash(relPath))
+
 		// Skip directories
 		if info.IsDir() {
 			// Skip .git directories
-			if info.Name() == ".git" {
+			if info.Name() == ".git" || excluded {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
 		// Skip .checksums file
-		if info.Name() == ".checksums" {
+		if info.Name() == ".checksums" || excluded {
 			return nil
 		}
 
-		// Compute checksum for regular files
-		cs, err := ComputeFile(path)
-		if err != nil {
-			return fmt.Errorf("failed to compute checksum for %s: %w", path, err)
+		paths = append(paths, path)
+		infos = append(infos, info)
+		totalBytes += info.Size()
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	// Compute checksums for regular files in parallel
+	checksums := make([]*FileChecksum, len(paths))
+	progress := workerpool.NewProgress(opts.Progress, "Checksumming", len(paths), totalBytes)
+	err = workerpool.Run(len(paths), opts, func(i int) error {
+		var cs *FileChecksum
+		if crc, ok := cache.lookupFile(paths[i], infos[i]); ok {
+			cs = &FileChecksum{CRC32: crc, SizeBytes: infos[i].Size()}
+		} else {
+			var err error
+			if cs, err = ComputeFile(paths[i]); err != nil {
+				return fmt.Errorf("failed to compute checksum for %s: %w", paths[i], err)
+			}
+			cache.putFile(paths[i], infos[i], cs.CRC32)
 		}
 
 		// Store relative path
-		relPath, err := filepath.Rel(dir, path)
+		relPath, err := filepath.Rel(dir, paths[i])
 		if err != nil {
-			relPath = path
+			relPath = paths[i]
 		}
 		cs.Path = relPath
 
-		checksums = append(checksums, cs)
+		checksums[i] = cs
+		progress.Add(cs.SizeBytes)
 		return nil
 	})
-
+	if len(paths) > 0 {
+		progress.Finish()
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to walk directory: %w", err)
+		return nil, err
 	}
 
 	// Sort by path for consistent ordering
@@ -98,50 +149,234 @@ func ComputeDirectory(dir string) ([]*FileChecksum, error) {
 	return checksums, nil
 }
 
-// StoreChecksums stores checksums in the database
-func StoreChecksums(db *database.DB, runID int64, stepNumber int, checksums []*FileChecksum) error {
-	now := time.Now()
+// StoreChecksums stores checksums in the database as a snapshot of the given step; the
+// conflict mode says what happens to checksums the step has already
+func StoreChecksums(db *database.DB, runID int64, stepNumber int, checksums []*FileChecksum, conflict string) error {
+	_, err := storeSnapshot(db, runID, stepNumber, "", checksums, conflict)
+	return err
+}
 
-	for _, cs := range checksums {
-		dbChecksum := &database.Checksum{
-			RunID:      runID,
-			StepNumber: stepNumber,
-			FilePath:   cs.Path,
-			CRC32:      fmt.Sprintf("%08x", cs.CRC32),
-			SizeBytes:  cs.SizeBytes,
-			ComputedAt: now,
+// StoreSnapshot stores checksums under an arbitrary label (e.g. "pre-migration")
+// instead of a step number, so ad-hoc investigations need not invent fake steps
+func StoreSnapshot(db *database.DB, runID int64, label string, checksums []*FileChecksum) (*database.Snapshot, error) {
+	if label == "" {
+		return nil, fmt.Errorf("snapshot label must not be empty")
+	}
+	return storeSnapshot(db, runID, 0, label, checksums, ConflictReplace)
+}
+
+// CheckLabel returns an error if ResolveSnapshot would read label as a step number
+func CheckLabel(label string) error {
+	if _, err := strconv.Atoi(label); err == nil {
+		return fmt.Errorf("snapshot label '%s' is a number, which refers to a step; use a name such as pre-migration", label)
+	}
+	return nil
+}
+
+// storeSnapshot creates a snapshot record and stores its checksums
+func storeSnapshot(db *database.DB, runID int64, stepNumber int, label string, checksums []*FileChecksum, conflict string) (*database.Snapshot, error) {
+	snapshot := &database.Snapshot{
+		RunID:      runID,
+		StepNumber: stepNumber,
+		Label:      label,
+		CreatedAt:  time.Now(),
+	}
+	err := storeChecksums(db, snapshot, conflict, func(store func(cs *database.Checksum) error) error {
+		for _, cs := range checksums {
+			if err := store(toChecksum(cs, snapshot.CreatedAt)); err != nil {
+				return fmt.Errorf("failed to store checksum for %s: %w", cs.Path, err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// toChecksum converts a computed checksum to a database checksum; storing it fills in
+// the run, step and snapshot
+func toChecksum(cs *FileChecksum, computedAt time.Time) *database.Checksum {
+	return &database.Checksum{
+		FilePath:   cs.Path,
+		CRC32:      fmt.Sprintf("%08x", cs.CRC32),
+		SizeBytes:  cs.SizeBytes,
+		ComputedAt: computedAt,
+	}
+}
+
+// ResolveSnapshot returns the checksums referenced by ref, which is either
+// a step number ("3") or a snapshot label ("pre-migration"). A step the run has
+// no checksums for is an error, rather than an empty snapshot.
+func ResolveSnapshot(db *database.DB, runID int64, ref string) ([]*database.Checksum, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("empty snapshot reference")
+	}
 
-		if err := db.CreateChecksum(dbChecksum); err != nil {
-			return fmt.Errorf("failed to store checksum for %s: %w", cs.Path, err)
+	if step, err := strconv.Atoi(ref); err == nil {
+		// Labelled snapshots are stored as step 0
+		if step < 1 {
+			return nil, fmt.Errorf("invalid step %d: steps are numbered from 1", step)
 		}
+		checksums, err := db.ListChecksums(runID, step)
+		if err != nil {
+			return nil, err
+		}
+		if len(checksums) == 0 {
+			return nil, fmt.Errorf("run %d has no checksums for step %d", runID, step)
+		}
+		return checksums, nil
 	}
 
-	return nil
+	snapshot, err := db.GetSnapshotByLabel(runID, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.ListChecksumsBySnapshot(snapshot.ID)
 }
 
 // Difference represents a checksum difference between two steps
 type Difference struct {
-	FilePath    string
-	OldCRC32    string
-	OldSize     int64
-	NewCRC32    string
-	NewSize     int64
-	ChangeType  string // "added", "modified", "deleted", "size-changed"
+	FilePath   string
+	OldPath    string // Previous path of a renamed file
+	OldCRC32   string
+	OldSize    int64
+	NewCRC32   string
+	NewSize    int64
+	ChangeType string // "added", "modified", "deleted", "size-changed", "renamed"
 }
 
 // CompareChecksums compares checksums between two steps
 func CompareChecksums(db *database.DB, runID int64, oldStep, newStep int) ([]*Difference, error) {
-	oldChecksums, err := db.ListChecksums(runID, oldStep)
+	return CompareStored(DatabaseStore{DB: db}, runID, oldStep, newStep)
+}
+
+// CompareSnapshots compares two snapshots, each given as a step number or a label
+func CompareSnapshots(db *database.DB, runID int64, oldRef, newRef string) ([]*Difference, error) {
+	return CompareSnapshotsWith(db, runID, oldRef, newRef, DiffOptions{})
+}
+
+// CompareSnapshotsWith is CompareSnapshots comparing the files opts.Filter selects, as opts
+// describes, e.g. leaving out files that only one of the snapshots excluded
+func CompareSnapshotsWith(db *database.DB, runID int64, oldRef, newRef string, opts DiffOptions) ([]*Difference, error) {
+	oldChecksums, err := ResolveSnapshot(db, runID, oldRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checksums for %s: %w", oldRef, err)
+	}
+
+	newChecksums, err := ResolveSnapshot(db, runID, newRef)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get checksums for step %d: %w", oldStep, err)
+		return nil, fmt.Errorf("failed to get checksums for %s: %w", newRef, err)
 	}
 
-	newChecksums, err := db.ListChecksums(runID, newStep)
+	return DiffChecksumsWith(oldChecksums, newChecksums, opts), nil
+}
+
+// Verify checksums dir again and compares it with recorded, the checksums of an earlier
+// snapshot of it, so files whose content has diverged since are reported as modified,
+// deleted and so on. Files matching exclude are left out of both sides.
+func Verify(recorded []*database.Checksum, dir string, exclude []string, opts workerpool.Options) ([]*Difference, error) {
+	computed, err := ComputeDirectoryWith(dir, exclude, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get checksums for step %d: %w", newStep, err)
+		return nil, err
 	}
 
+	now := time.Now()
+	current := make([]*database.Checksum, len(computed))
+	for i, cs := range computed {
+		current[i] = &database.Checksum{
+			FilePath:   cs.Path,
+			CRC32:      fmt.Sprintf("%08x", cs.CRC32),
+			SizeBytes:  cs.SizeBytes,
+			ComputedAt: now,
+		}
+	}
+	return DiffChecksumsWith(recorded, current, DiffOptions{Filter: Filter{Exclude: exclude}}), nil
+}
+
+// Filter selects the files a comparison covers with glob patterns (see path.Match).
+// A pattern matches a file if it matches its path or name, or the path or name of one
+// of its directories.
+// Without Include patterns every file is included; Exclude wins over Include.
+type Filter struct {
+	Include []string
+	Exclude []string
+}
+
+// Validate reports the first malformed pattern
+func (f Filter) Validate() error {
+	for _, pattern := range append(append([]string(nil), f.Include...), f.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether the filter selects the file at filePath (slash-separated)
+func (f Filter) Match(filePath string) bool {
+	if matchAny(f.Exclude, filePath) {
+		return false
+	}
+	return len(f.Include) == 0 || matchAny(f.Include, filePath)
+}
+
+// Apply returns the checksums of the files the filter selects
+func (f Filter) Apply(checksums []*database.Checksum) []*database.Checksum {
+	if len(f.Include) == 0 && len(f.Exclude) == 0 {
+		return checksums
+	}
+	var selected []*database.Checksum
+	for _, cs := range checksums {
+		if f.Match(cs.FilePath) {
+			selected = append(selected, cs)
+		}
+	}
+	return selected
+}
+
+// matchAny reports whether any pattern matches filePath or its name, or the path or name
+// of one of its directories
+func matchAny(patterns []string, filePath string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, filePath); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(filePath)); ok {
+			return true
+		}
+		for dir := path.Dir(filePath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if ok, _ := path.Match(pattern, dir); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, path.Base(dir)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DiffOptions selects how two checksum sets are compared
+type DiffOptions struct {
+	Filter    Filter // Files to compare; the zero Filter compares all
+	NoRenames bool   // Report renames as a deletion and an addition
+	SizeOnly  bool   // Ignore CRC32 differences of files whose size is unchanged
+}
+
+// DiffChecksums computes the differences between two checksum sets
+func DiffChecksums(oldChecksums, newChecksums []*database.Checksum) []*Difference {
+	return DiffChecksumsWith(oldChecksums, newChecksums, DiffOptions{})
+}
+
+// DiffChecksumsWith computes the differences between the files of two checksum sets
+// that opts.Filter selects, comparing them as opts describes
+func DiffChecksumsWith(oldChecksums, newChecksums []*database.Checksum, opts DiffOptions) []*Difference {
+	oldChecksums = opts.Filter.Apply(oldChecksums)
+	newChecksums = opts.Filter.Apply(newChecksums)
+
 	// Create maps for easy lookup
 	oldMap := make(map[string]*database.Checksum)
 	for _, cs := range oldChecksums {
@@ -166,7 +401,7 @@ func CompareChecksums(db *database.DB, runID int64, oldStep, newStep int) ([]*Di
 				OldSize:    oldCS.SizeBytes,
 				ChangeType: "deleted",
 			})
-		} else if oldCS.CRC32 != newCS.CRC32 {
+		} else if oldCS.CRC32 != newCS.CRC32 && (!opts.SizeOnly || oldCS.SizeBytes != newCS.SizeBytes) {
 			// File was modified
 			changeType := "modified"
 			if oldCS.SizeBytes != newCS.SizeBytes {
@@ -200,7 +435,70 @@ func CompareChecksums(db *database.DB, runID int64, oldStep, newStep int) ([]*Di
 		return diffs[i].FilePath < diffs[j].FilePath
 	})
 
-	return diffs, nil
+	if opts.NoRenames {
+		return diffs
+	}
+	return detectRenames(diffs)
+}
+
+// detectRenames replaces each deleted file whose CRC32 and size match an added file with
+// one "renamed" difference. diffs must be sorted by path; candidates with identical content
+// are paired in path order. Empty files are never paired, since any two of them match.
+func detectRenames(diffs []*Difference) []*Difference {
+	type content struct {
+		crc  string
+		size int64
+	}
+
+	added := make(map[content][]*Difference)
+	for _, d := range diffs {
+		if d.ChangeType == "added" && d.NewSize > 0 {
+			key := content{d.NewCRC32, d.NewSize}
+			added[key] = append(added[key], d)
+		}
+	}
+
+	paired := make(map[*Difference]*Difference) // Deleted and added halves -> rename
+	for _, d := range diffs {
+		if d.ChangeType != "deleted" {
+			continue
+		}
+		key := content{d.OldCRC32, d.OldSize}
+		candidates := added[key]
+		if len(candidates) == 0 {
+			continue
+		}
+		to := candidates[0]
+		added[key] = candidates[1:]
+
+		rename := &Difference{
+			FilePath:   to.FilePath,
+			OldPath:    d.FilePath,
+			OldCRC32:   d.OldCRC32,
+			OldSize:    d.OldSize,
+			NewCRC32:   to.NewCRC32,
+			NewSize:    to.NewSize,
+			ChangeType: "renamed",
+		}
+		paired[d] = rename
+		paired[to] = rename
+	}
+	if len(paired) == 0 {
+		return diffs
+	}
+
+	// Keep each rename at the position of its new path
+	result := make([]*Difference, 0, len(diffs)-len(paired)/2)
+	for _, d := range diffs {
+		rename, ok := paired[d]
+		switch {
+		case !ok:
+			result = append(result, d)
+		case d.ChangeType == "added":
+			result = append(result, rename)
+		}
+	}
+	return result
 }
 
 // FormatSize formats bytes in human-readable format
@@ -219,17 +517,25 @@ func FormatSize(bytes int64) string {
 
 // ChecksumExport represents checksums in JSON format for export
 type ChecksumExport struct {
-	RunID      int64            `json:"run_id"`
-	StepNumber int              `json:"step_number"`
-	Checksums  []*FileChecksum  `json:"checksums"`
-	ComputedAt time.Time        `json:"computed_at"`
+	RunID      int64           `json:"run_id"`
+	StepNumber int             `json:"step_number"`
+	Label      string          `json:"label,omitempty"`
+	Checksums  []*FileChecksum `json:"checksums"`
+	ComputedAt time.Time       `json:"computed_at"`
 }
 
 // ExportJSON exports checksums to JSON format
 func ExportJSON(runID int64, stepNumber int, checksums []*FileChecksum) ([]byte, error) {
+	return ExportSnapshotJSON(runID, stepNumber, "", checksums)
+}
+
+// ExportSnapshotJSON exports checksums to JSON format, tagged with a snapshot
+// label when label is non-empty
+func ExportSnapshotJSON(runID int64, stepNumber int, label string, checksums []*FileChecksum) ([]byte, error) {
 	export := &ChecksumExport{
 		RunID:      runID,
 		StepNumber: stepNumber,
+		Label:      label,
 		Checksums:  checksums,
 		ComputedAt: time.Now(),
 	}
@@ -242,32 +548,44 @@ func ExportJSON(runID int64, stepNumber int, checksums []*FileChecksum) ([]byte,
 	return data, nil
 }
 
-// ImportJSON imports checksums from JSON format and stores in database
-func ImportJSON(db *database.DB, data []byte) error {
-	var export ChecksumExport
-	if err := json.Unmarshal(data, &export); err != nil {
-		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+// ImportJSON imports checksums in either of the formats of ImportStream and stores them
+// in the database as the conflict mode says
+func ImportJSON(db *database.DB, data []byte, conflict string) error {
+	_, err := ImportStream(db, bytes.NewReader(data), conflict)
+	return err
+}
+
+// importExport stores exported checksums in the database as a snapshot
+func importExport(db *database.DB, export *ChecksumExport, conflict string) error {
+	snapshot := &database.Snapshot{
+		RunID:      export.RunID,
+		StepNumber: export.StepNumber,
+		Label:      export.Label,
+		CreatedAt:  export.ComputedAt,
 	}
+	return storeChecksums(db, snapshot, conflict, func(store func(cs *database.Checksum) error) error {
+		for _, cs := range export.Checksums {
+			if err := store(toChecksum(cs, export.ComputedAt)); err != nil {
+				return fmt.Errorf("failed to store checksum for %s: %w", cs.Path, err)
+			}
+		}
+		return nil
+	})
+}
 
-	// Convert to database checksums
+// toDatabase converts exported checksums to database checksums of a snapshot
+func toDatabase(export *ChecksumExport, snapshotID int64) []*database.Checksum {
 	dbChecksums := make([]*database.Checksum, len(export.Checksums))
 	for i, cs := range export.Checksums {
 		dbChecksums[i] = &database.Checksum{
 			RunID:      export.RunID,
 			StepNumber: export.StepNumber,
+			SnapshotID: snapshotID,
 			FilePath:   cs.Path,
 			CRC32:      fmt.Sprintf("%08x", cs.CRC32),
 			SizeBytes:  cs.SizeBytes,
 			ComputedAt: export.ComputedAt,
 		}
 	}
-
-	// Store in database
-	for _, cs := range dbChecksums {
-		if err := db.CreateChecksum(cs); err != nil {
-			return fmt.Errorf("failed to store checksum for %s: %w", cs.FilePath, err)
-		}
-	}
-
-	return nil
+	return dbChecksums
 }
@@ -1,9 +1,14 @@
 package checksum
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/workerpool"
 )
 
 func TestComputeFile(t *testing.T) {
@@ -155,6 +160,47 @@ func TestComputeDirectory_SkipsGit(t *testing.T) {
 	}
 }
 
+func TestComputeDirectory_Exclude(t *testing.T) {
+	tempDir := t.TempDir()
+	files := []string{"file.txt", ".DS_Store", "docs/.notes.md.swp", "docs/Thumbs.db", "docs/report.pdf", "scratch/tmp.bin"}
+	for _, name := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	checksums, err := ComputeDirectory(tempDir)
+	if err != nil {
+		t.Fatalf("ComputeDirectory failed: %v", err)
+	}
+	var paths []string
+	for _, cs := range checksums {
+		paths = append(paths, cs.Path)
+	}
+	want := []string{"docs/report.pdf", "file.txt", "scratch/tmp.bin"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("ComputeDirectory checksummed %v, want %v", paths, want)
+	}
+
+	// Patterns replace the defaults, and a directory pattern skips the whole directory
+	checksums, err = ComputeDirectoryWith(tempDir, []string{"scratch", "*.pdf"}, workerpool.Options{})
+	if err != nil {
+		t.Fatalf("ComputeDirectoryWith failed: %v", err)
+	}
+	if len(checksums) != 4 {
+		t.Errorf("ComputeDirectoryWith checksummed %d files, want 4", len(checksums))
+	}
+	for _, cs := range checksums {
+		if strings.HasPrefix(cs.Path, "scratch") || strings.HasSuffix(cs.Path, ".pdf") {
+			t.Errorf("ComputeDirectoryWith checksummed excluded file %s", cs.Path)
+		}
+	}
+}
+
 func TestFormatSize(t *testing.T) {
 	tests := []struct {
 		bytes    int64
@@ -251,3 +297,233 @@ func TestDifferenceTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestStoreSnapshot_CompareLabels(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	run := &database.TestRun{ScenarioID: 1, ServerType: "bare", Protocol: "local", GitServer: "bare", Status: "running"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("Failed to create test run: %v", err)
+	}
+
+	step := []*FileChecksum{
+		{Path: "keep.bin", CRC32: 0x11111111, SizeBytes: 10},
+		{Path: "gone.bin", CRC32: 0x22222222, SizeBytes: 20},
+	}
+	if err := StoreChecksums(db, run.ID, 3, step, ConflictReplace); err != nil {
+		t.Fatalf("StoreChecksums failed: %v", err)
+	}
+
+	labelled := []*FileChecksum{
+		{Path: "keep.bin", CRC32: 0x11111111, SizeBytes: 10},
+		{Path: "new.bin", CRC32: 0x33333333, SizeBytes: 30},
+	}
+	snapshot, err := StoreSnapshot(db, run.ID, "after-gc", labelled)
+	if err != nil {
+		t.Fatalf("StoreSnapshot failed: %v", err)
+	}
+	if snapshot.Label != "after-gc" || snapshot.StepNumber != 0 {
+		t.Errorf("Snapshot = %+v, want label after-gc and step 0", snapshot)
+	}
+
+	diffs, err := CompareSnapshots(db, run.ID, "3", "after-gc")
+	if err != nil {
+		t.Fatalf("CompareSnapshots failed: %v", err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 differences, got %d", len(diffs))
+	}
+	if diffs[0].FilePath != "gone.bin" || diffs[0].ChangeType != "deleted" {
+		t.Errorf("diffs[0] = %+v, want gone.bin deleted", diffs[0])
+	}
+	if diffs[1].FilePath != "new.bin" || diffs[1].ChangeType != "added" {
+		t.Errorf("diffs[1] = %+v, want new.bin added", diffs[1])
+	}
+
+	// Excluding gone.bin now leaves it out of step 3 as well
+	diffs, err = CompareSnapshotsWith(db, run.ID, "3", "after-gc", DiffOptions{Filter: Filter{Exclude: []string{"gone.*"}}})
+	if err != nil {
+		t.Fatalf("CompareSnapshotsWith failed: %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].FilePath != "new.bin" {
+		t.Errorf("CompareSnapshotsWith = %+v, want only new.bin added", diffs)
+	}
+
+	if _, err := StoreSnapshot(db, run.ID, "", labelled); err == nil {
+		t.Error("StoreSnapshot should reject an empty label")
+	}
+	if _, err := ResolveSnapshot(db, run.ID, "missing"); err == nil {
+		t.Error("ResolveSnapshot should fail for an unknown label")
+	}
+
+	// A number refers to a step, so it cannot be a label; labelled snapshots are step 0
+	if _, err := StoreSnapshot(db, run.ID, "7", labelled); err == nil {
+		t.Error("StoreSnapshot should reject a numeric label")
+	}
+	for _, ref := range []string{"0", "-1", "9"} {
+		if _, err := ResolveSnapshot(db, run.ID, ref); err == nil {
+			t.Errorf("ResolveSnapshot(%q) should fail", ref)
+		}
+	}
+}
+
+func TestDiffChecksums_Renames(t *testing.T) {
+	before := []*database.Checksum{
+		{FilePath: "zip2.zip", CRC32: "aaaa0001", SizeBytes: 100},
+		{FilePath: "video1.m4v", CRC32: "aaaa0002", SizeBytes: 200},
+		{FilePath: "a.txt", CRC32: "aaaa0003", SizeBytes: 0},
+	}
+	after := []*database.Checksum{
+		{FilePath: "zip2_renamed.zip", CRC32: "aaaa0001", SizeBytes: 100},
+		{FilePath: "other.bin", CRC32: "aaaa0002", SizeBytes: 201},
+		{FilePath: "b.txt", CRC32: "aaaa0003", SizeBytes: 0},
+	}
+
+	diffs := DiffChecksums(before, after)
+
+	got := make(map[string]string)
+	for _, d := range diffs {
+		got[d.FilePath] = d.ChangeType
+	}
+	want := map[string]string{
+		"zip2_renamed.zip": "renamed",
+		"video1.m4v":       "deleted", // Same CRC but a different size is not a rename
+		"other.bin":        "added",
+		"a.txt":            "deleted", // Empty files are never paired
+		"b.txt":            "added",
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("Expected %d differences, got %d: %v", len(want), len(diffs), got)
+	}
+	for path, changeType := range want {
+		if got[path] != changeType {
+			t.Errorf("%s: ChangeType = %q, want %q", path, got[path], changeType)
+		}
+	}
+
+	for _, d := range diffs {
+		if d.ChangeType == "renamed" && (d.OldPath != "zip2.zip" || d.NewSize != 100) {
+			t.Errorf("rename = %+v, want zip2.zip → zip2_renamed.zip (100 bytes)", d)
+		}
+	}
+}
+
+func TestDiffChecksums_DuplicateContentRenames(t *testing.T) {
+	// Two copies of the same content renamed at once pair up in path order
+	before := []*database.Checksum{
+		{FilePath: "a1", CRC32: "bbbb0001", SizeBytes: 10},
+		{FilePath: "a2", CRC32: "bbbb0001", SizeBytes: 10},
+	}
+	after := []*database.Checksum{
+		{FilePath: "b1", CRC32: "bbbb0001", SizeBytes: 10},
+		{FilePath: "b2", CRC32: "bbbb0001", SizeBytes: 10},
+	}
+
+	diffs := DiffChecksums(before, after)
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 renames, got %d", len(diffs))
+	}
+	if diffs[0].OldPath != "a1" || diffs[0].FilePath != "b1" || diffs[1].OldPath != "a2" || diffs[1].FilePath != "b2" {
+		t.Errorf("renames = %+v, %+v; want a1 → b1 and a2 → b2", diffs[0], diffs[1])
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f := Filter{Include: []string{"*.mov", "video", "docs/*.pdf"}, Exclude: []string{"video/tmp"}}
+	cases := map[string]bool{
+		"a.mov":             true,  // Name matches
+		"clips/b.mov":       true,  // Name matches in a subdirectory
+		"video/c.m4v":       true,  // Directory matches
+		"video/tmp/d.m4v":   false, // Excluded directory wins
+		"e.pdf":             false,
+		"clips/video.pdf":   false,
+		"clips/video/f.avi": true, // Any directory level matches
+		"docs/g.pdf":        true, // Path matches
+	}
+	for p, want := range cases {
+		if got := f.Match(p); got != want {
+			t.Errorf("Match(%s) = %v, want %v", p, got, want)
+		}
+	}
+
+	if !(Filter{}).Match("anything") {
+		t.Error("The zero Filter should match every file")
+	}
+	if err := (Filter{Include: []string{"[a-"}}).Validate(); err == nil {
+		t.Error("Validate should reject a malformed pattern")
+	}
+}
+
+func TestDiffChecksumsWith(t *testing.T) {
+	before := []*database.Checksum{
+		{FilePath: "a.mov", CRC32: "aaaa0001", SizeBytes: 100},
+		{FilePath: "b.pdf", CRC32: "aaaa0002", SizeBytes: 200},
+		{FilePath: "c.zip", CRC32: "aaaa0003", SizeBytes: 300},
+	}
+	after := []*database.Checksum{
+		{FilePath: "a.mov", CRC32: "bbbb0001", SizeBytes: 100},
+		{FilePath: "b.pdf", CRC32: "bbbb0002", SizeBytes: 201},
+		{FilePath: "d.zip", CRC32: "aaaa0003", SizeBytes: 300},
+	}
+
+	diffs := DiffChecksumsWith(before, after, DiffOptions{Filter: Filter{Include: []string{"*.mov"}}})
+	if len(diffs) != 1 || diffs[0].FilePath != "a.mov" || diffs[0].ChangeType != "modified" {
+		t.Errorf("--include *.mov differences = %+v", diffs)
+	}
+
+	diffs = DiffChecksumsWith(before, after, DiffOptions{SizeOnly: true, NoRenames: true})
+	got := make(map[string]string)
+	for _, d := range diffs {
+		got[d.FilePath] = d.ChangeType
+	}
+	want := map[string]string{"b.pdf": "size-changed", "c.zip": "deleted", "d.zip": "added"}
+	if len(got) != len(want) {
+		t.Fatalf("size-only differences = %v, want %v", got, want)
+	}
+	for p, changeType := range want {
+		if got[p] != changeType {
+			t.Errorf("%s: ChangeType = %q, want %q", p, got[p], changeType)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"keep.bin": "unchanged", "flip.bin": "original", "gone.bin": "deleted", ".DS_Store": "x"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	computed, err := ComputeDirectory(dir)
+	if err != nil {
+		t.Fatalf("ComputeDirectory failed: %v", err)
+	}
+	var recorded []*database.Checksum
+	for _, cs := range computed {
+		recorded = append(recorded, &database.Checksum{FilePath: cs.Path, CRC32: fmt.Sprintf("%08x", cs.CRC32), SizeBytes: cs.SizeBytes})
+	}
+
+	diffs, err := Verify(recorded, dir, DefaultExclude, workerpool.Options{})
+	if err != nil || len(diffs) != 0 {
+		t.Fatalf("Verify of an unchanged directory = %v, %v", diffs, err)
+	}
+
+	// Same size, other content: only the checksum tells
+	os.WriteFile(filepath.Join(dir, "flip.bin"), []byte("ORIGINAL"), 0644)
+	os.Remove(filepath.Join(dir, "gone.bin"))
+	diffs, err = Verify(recorded, dir, DefaultExclude, workerpool.Options{})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	got := make(map[string]string)
+	for _, d := range diffs {
+		got[d.FilePath] = d.ChangeType
+	}
+	if len(got) != 2 || got["flip.bin"] != "modified" || got["gone.bin"] != "deleted" {
+		t.Errorf("Verify = %v, want flip.bin modified and gone.bin deleted", got)
+	}
+}
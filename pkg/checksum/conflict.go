@@ -0,0 +1,35 @@
+package checksum
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+// Conflict modes: what storing the checksums of a step that has checksums already does,
+// e.g. when lfst-checksum runs twice for a step or the same export is imported again.
+// Labelled snapshots are never in conflict, as each is a snapshot of its own.
+const (
+	ConflictReplace = "replace" // The new checksums replace those of the step
+	ConflictError   = "error"   // Storing fails, leaving the checksums of the step as they were
+)
+
+// ConflictModes lists the conflict modes
+var ConflictModes = []string{ConflictReplace, ConflictError}
+
+// storeChecksums creates snapshot with the checksums each hands to its store function,
+// as the conflict mode says, in one transaction: if storing any fails, none is stored.
+// An empty conflict mode is ConflictReplace.
+func storeChecksums(db *database.DB, snapshot *database.Snapshot, conflict string, each func(store func(cs *database.Checksum) error) error) error {
+	if snapshot.Label != "" {
+		if err := CheckLabel(snapshot.Label); err != nil {
+			return err
+		}
+	}
+	err := db.StoreSnapshot(snapshot, conflict != ConflictError, each)
+	if errors.Is(err, database.ErrStepHasChecksums) {
+		return fmt.Errorf("%w (checksum_conflict is %s)", err, ConflictError)
+	}
+	return err
+}
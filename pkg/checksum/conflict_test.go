@@ -0,0 +1,66 @@
+package checksum
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestStoreChecksums_Conflict(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	run := &database.TestRun{ScenarioID: 1, ServerType: "bare", Protocol: "local", GitServer: "bare", Status: "running"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("Failed to create test run: %v", err)
+	}
+
+	first := []*FileChecksum{
+		{Path: "keep.bin", CRC32: 0x11111111, SizeBytes: 10},
+		{Path: "gone.bin", CRC32: 0x22222222, SizeBytes: 20},
+	}
+	second := []*FileChecksum{
+		{Path: "keep.bin", CRC32: 0x33333333, SizeBytes: 10},
+	}
+	if err := StoreChecksums(db, run.ID, 2, first, ConflictReplace); err != nil {
+		t.Fatalf("StoreChecksums failed: %v", err)
+	}
+	before, _ := db.ListChecksums(run.ID, 2)
+
+	// Storing the step again replaces its checksums, those of files since gone included
+	if err := StoreChecksums(db, run.ID, 2, second, ConflictReplace); err != nil {
+		t.Fatalf("StoreChecksums again failed: %v", err)
+	}
+	stored, _ := db.ListChecksums(run.ID, 2)
+	if len(stored) != 1 || stored[0].FilePath != "keep.bin" || stored[0].CRC32 != "33333333" {
+		t.Errorf("Checksums of step 2 = %+v, want keep.bin 33333333 only", stored)
+	}
+	for _, cs := range before {
+		if cs.FilePath == "keep.bin" && len(stored) == 1 && stored[0].ID != cs.ID {
+			t.Errorf("keep.bin was stored as row %d, want row %d updated in place", stored[0].ID, cs.ID)
+		}
+	}
+
+	err = StoreChecksums(db, run.ID, 2, first, ConflictError)
+	if err == nil || !strings.Contains(err.Error(), "already has 1 checksums") {
+		t.Errorf("StoreChecksums in error mode = %v, want a conflict", err)
+	}
+	if stored, _ := db.ListChecksums(run.ID, 2); len(stored) != 1 || stored[0].CRC32 != "33333333" {
+		t.Errorf("A refused store changed step 2: %+v", stored)
+	}
+	if err := StoreChecksums(db, run.ID, 3, first, ConflictError); err != nil {
+		t.Errorf("StoreChecksums of a new step failed in error mode: %v", err)
+	}
+
+	// Labelled snapshots never conflict
+	for range 2 {
+		if _, err := StoreSnapshot(db, run.ID, "after-gc", first); err != nil {
+			t.Errorf("StoreSnapshot failed in error mode: %v", err)
+		}
+	}
+}
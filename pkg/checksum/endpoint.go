@@ -0,0 +1,145 @@
+package checksum
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+// ImportPath is the path at which lfst-import --listen accepts checksums
+const ImportPath = "/import"
+
+// JSONLContentType is the media type of checksums posted as JSON Lines
+const JSONLContentType = "application/x-ndjson"
+
+// MaxImportBytes is the largest request body ImportServer accepts by default, about
+// three million checksums of JSON Lines
+const MaxImportBytes = 256 << 20
+
+// ImportServer stores the checksums that clients POST to ImportPath, in either format
+// ImportStream reads, so that lfst-checksum can record them on a database host it cannot
+// reach over SSH. Clients authenticate with Token as a bearer token.
+type ImportServer struct {
+	DB       *database.DB
+	Token    string                        // Required of every client; an empty token refuses all
+	Logf     func(format string, a ...any) // Told of each import, if set
+	MaxBytes int64                         // Largest request body accepted; 0 means MaxImportBytes
+	Conflict string                        // Conflict mode of steps imported again; empty is ConflictReplace
+
+	mu sync.Mutex // One import at a time, as SQLite has a single writer
+}
+
+// importResult is the response to a successful import
+type importResult struct {
+	Imported int `json:"imported"`
+}
+
+func (s *ImportServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != ImportPath {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(req) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="lfst-import"`)
+		http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	maxBytes := s.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = MaxImportBytes
+	}
+	s.mu.Lock()
+	n, err := ImportStream(s.DB, http.MaxBytesReader(w, req.Body, maxBytes), s.Conflict)
+	s.mu.Unlock()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.Logf != nil {
+		s.Logf("Imported %d checksums from %s\n", n, req.RemoteAddr)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(importResult{Imported: n})
+}
+
+// authorized reports whether a request presents the server's token
+func (s *ImportServer) authorized(req *http.Request) bool {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && s.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+// UploadClient returns a client for Upload that trusts the certificates in caFile, a PEM
+// file, e.g. the self-signed certificate of lfst-import --listen; an empty caFile trusts
+// the system's certificate authorities only
+func UploadClient(caFile string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// Upload streams checksums as JSON Lines to the import endpoint at baseURL, e.g.
+// https://gojira:9443, and returns how many it stored
+func Upload(client *http.Client, baseURL, token string, runID int64, stepNumber int, label string, checksums []*FileChecksum) (int, error) {
+	// Write the checksums as they are sent, so the request body is never held whole
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(WriteJSONL(pw, runID, stepNumber, label, checksums))
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(baseURL, "/")+ImportPath, pr)
+	if err != nil {
+		pr.Close()
+		return 0, fmt.Errorf("invalid import URL: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", JSONLContentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send checksums: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return 0, fmt.Errorf("import endpoint refused the checksums: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var result importResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("invalid response from import endpoint: %w", err)
+	}
+	return result.Imported, nil
+}
@@ -0,0 +1,92 @@
+package checksum
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestImportServer_Upload(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	run := &database.TestRun{ScenarioID: 1, ServerType: "bare", Protocol: "local", GitServer: "bare", Status: "running"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("Failed to create test run: %v", err)
+	}
+
+	server := httptest.NewTLSServer(&ImportServer{DB: db, Token: "s3cr3t-token", MaxBytes: 4096})
+	defer server.Close()
+
+	// Trust the server's self-signed certificate as import_ca_cert would
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+	client, err := UploadClient(caFile)
+	if err != nil {
+		t.Fatalf("UploadClient failed: %v", err)
+	}
+
+	checksums := []*FileChecksum{
+		{Path: "a.bin", CRC32: 0x11111111, SizeBytes: 10},
+		{Path: "b.bin", CRC32: 0x22222222, SizeBytes: 20},
+	}
+	n, err := Upload(client, server.URL+"/", "s3cr3t-token", run.ID, 2, "", checksums)
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Upload stored %d checksums, want 2", n)
+	}
+	stored, err := db.GetChecksumsByRunAndStep(run.ID, 2)
+	if err != nil {
+		t.Fatalf("GetChecksumsByRunAndStep failed: %v", err)
+	}
+	if len(stored) != 2 {
+		t.Errorf("Stored %d checksums, want 2", len(stored))
+	}
+
+	if _, err := Upload(client, server.URL, "wrong-token", run.ID, 3, "", checksums); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Upload with a wrong token = %v, want 401", err)
+	}
+	if _, err := Upload(http.DefaultClient, server.URL, "s3cr3t-token", run.ID, 3, "", checksums); err == nil {
+		t.Error("Upload should fail when the certificate is not trusted")
+	}
+
+	// Bodies over MaxBytes are refused before they are read whole
+	body := strings.NewReader(strings.Repeat(" ", 4097))
+	req, _ := http.NewRequest(http.MethodPost, server.URL+ImportPath, body)
+	req.Header.Set("Authorization", "Bearer s3cr3t-token")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("POST of 4097 bytes = %d, want 413", resp.StatusCode)
+	}
+
+	resp, err = client.Get(server.URL + ImportPath)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET %s = %d, want 405", ImportPath, resp.StatusCode)
+	}
+
+	if _, err := UploadClient(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("UploadClient should fail for a missing CA file")
+	}
+}
@@ -0,0 +1,181 @@
+package checksum
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+// JSONLFormat names the streamed format of checksums in its header line
+const JSONLFormat = "lfst-checksums-jsonl"
+
+// jsonlVersion is the version of the streamed format this package writes and reads
+const jsonlVersion = 1
+
+// jsonlHeader is the first line of the streamed format; each following line is a
+// FileChecksum. Unlike a ChecksumExport, the stream is written and read one checksum at
+// a time, so neither side holds it all in memory.
+type jsonlHeader struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	RunID      int64     `json:"run_id"`
+	StepNumber int       `json:"step_number"`
+	Label      string    `json:"label,omitempty"`
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// WriteJSONL writes checksums to w as JSON Lines: a header line with the run, step and
+// label, then a line per checksum
+func WriteJSONL(w io.Writer, runID int64, stepNumber int, label string, checksums []*FileChecksum) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	header := jsonlHeader{
+		Format:     JSONLFormat,
+		Version:    jsonlVersion,
+		RunID:      runID,
+		StepNumber: stepNumber,
+		Label:      label,
+		ComputedAt: time.Now(),
+	}
+	if err := enc.Encode(header); err != nil {
+		return fmt.Errorf("failed to write checksums: %w", err)
+	}
+	for _, cs := range checksums {
+		if err := enc.Encode(cs); err != nil {
+			return fmt.Errorf("failed to write checksums: %w", err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write checksums: %w", err)
+	}
+	return nil
+}
+
+// ImportStream stores the checksums read from r in the database as a snapshot, as they
+// arrive, and returns how many it stored. r holds either JSON Lines written by WriteJSONL
+// or a single JSON document written by ExportJSON, which is read whole. The snapshot is
+// stored in one transaction, so if r is invalid or cut short, nothing is stored. The
+// conflict mode says what happens to checksums the step has already.
+func ImportStream(db *database.DB, r io.Reader, conflict string) (int, error) {
+	br := bufio.NewReader(r)
+	header, err := readHeader(br)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		export, err := decodeExport(br)
+		if err != nil {
+			return 0, err
+		}
+		if err := importExport(db, export, conflict); err != nil {
+			return 0, err
+		}
+		return len(export.Checksums), nil
+	}
+
+	snapshot := &database.Snapshot{
+		RunID:      header.RunID,
+		StepNumber: header.StepNumber,
+		Label:      header.Label,
+		CreatedAt:  header.ComputedAt,
+	}
+	// Each line is stored as it is read, all in one transaction
+	n := 0
+	err = storeChecksums(db, snapshot, conflict, func(store func(cs *database.Checksum) error) error {
+		return readLines(br, func(cs *FileChecksum) error {
+			if err := store(toChecksum(cs, header.ComputedAt)); err != nil {
+				return fmt.Errorf("failed to store checksum for %s: %w", cs.Path, err)
+			}
+			n++
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ReadExport reads checksums in either format into memory, e.g. a manifest
+func ReadExport(r io.Reader) (*ChecksumExport, error) {
+	br := bufio.NewReader(r)
+	header, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return decodeExport(br)
+	}
+
+	export := &ChecksumExport{
+		RunID:      header.RunID,
+		StepNumber: header.StepNumber,
+		Label:      header.Label,
+		ComputedAt: header.ComputedAt,
+	}
+	err = readLines(br, func(cs *FileChecksum) error {
+		export.Checksums = append(export.Checksums, cs)
+		return nil
+	})
+	return export, err
+}
+
+// readHeader returns the header of JSON Lines, or nil without consuming anything if br
+// holds a single JSON document instead
+func readHeader(br *bufio.Reader) (*jsonlHeader, error) {
+	// A JSON Lines header is a complete object on the first line, while the first line
+	// of an indented document is just "{"
+	line, err := br.Peek(4096)
+	if len(bytes.TrimSpace(line)) == 0 && err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("no checksums provided")
+		}
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	var header jsonlHeader
+	if json.Unmarshal(line, &header) != nil || header.Format != JSONLFormat {
+		return nil, nil
+	}
+	if header.Version > jsonlVersion {
+		return nil, fmt.Errorf("checksums are in version %d of %s, newer than this lfst reads (%d); update lfst",
+			header.Version, JSONLFormat, jsonlVersion)
+	}
+	if _, err := br.ReadBytes('\n'); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+	return &header, nil
+}
+
+// readLines calls fn with the checksum of each line of br after the header
+func readLines(br *bufio.Reader, fn func(cs *FileChecksum) error) error {
+	dec := json.NewDecoder(br)
+	for line := 2; ; line++ {
+		var cs FileChecksum
+		if err := dec.Decode(&cs); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("invalid checksum on line %d: %w", line, err)
+		}
+		if err := fn(&cs); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeExport reads a single JSON document written by ExportJSON
+func decodeExport(r io.Reader) (*ChecksumExport, error) {
+	var export ChecksumExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return &export, nil
+}
@@ -0,0 +1,126 @@
+package checksum
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestWriteJSONL_ImportStream(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	run := &database.TestRun{ScenarioID: 1, ServerType: "bare", Protocol: "local", GitServer: "bare", Status: "running"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("Failed to create test run: %v", err)
+	}
+
+	checksums := []*FileChecksum{
+		{Path: "a.bin", CRC32: 0x11111111, SizeBytes: 10},
+		{Path: "dir/b.bin", CRC32: 0x22222222, SizeBytes: 20},
+	}
+	var buf bytes.Buffer
+	if err := WriteJSONL(&buf, run.ID, 4, "remote", checksums); err != nil {
+		t.Fatalf("WriteJSONL failed: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Errorf("WriteJSONL wrote %d lines, want a header and 2 checksums", lines)
+	}
+
+	n, err := ImportStream(db, bytes.NewReader(buf.Bytes()), ConflictReplace)
+	if err != nil {
+		t.Fatalf("ImportStream failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("ImportStream stored %d checksums, want 2", n)
+	}
+	snapshot, err := db.GetSnapshotByLabel(run.ID, "remote")
+	if err != nil {
+		t.Fatalf("GetSnapshotByLabel failed: %v", err)
+	}
+	if snapshot.StepNumber != 4 {
+		t.Errorf("Snapshot step = %d, want 4", snapshot.StepNumber)
+	}
+	stored, err := db.ListChecksumsBySnapshot(snapshot.ID)
+	if err != nil {
+		t.Fatalf("ListChecksumsBySnapshot failed: %v", err)
+	}
+	if len(stored) != 2 || stored[1].FilePath != "dir/b.bin" || stored[1].CRC32 != "22222222" {
+		t.Errorf("Stored checksums = %+v", stored)
+	}
+
+	// The single JSON document written by older versions is still accepted
+	legacy, err := ExportJSON(run.ID, 5, checksums)
+	if err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+	if n, err := ImportStream(db, bytes.NewReader(legacy), ConflictReplace); err != nil || n != 2 {
+		t.Errorf("ImportStream(legacy) = %d, %v; want 2, nil", n, err)
+	}
+
+	// A stream that breaks off stores nothing, not even the lines before the break
+	buf.Reset()
+	if err := WriteJSONL(&buf, run.ID, 6, "", checksums); err != nil {
+		t.Fatalf("WriteJSONL failed: %v", err)
+	}
+	broken := buf.String()[:buf.Len()-10]
+	if n, err := ImportStream(db, strings.NewReader(broken), ConflictReplace); err == nil || n != 0 {
+		t.Errorf("ImportStream of a broken stream = %d, %v; want 0 and an error", n, err)
+	}
+	if stored, _ := db.ListChecksums(run.ID, 6); len(stored) != 0 {
+		t.Errorf("A broken stream stored %d checksums", len(stored))
+	}
+	if snapshots, _ := db.ListSnapshots(run.ID); len(snapshots) != 2 {
+		t.Errorf("A broken stream left a snapshot: %d snapshots, want 2", len(snapshots))
+	}
+}
+
+func TestReadExport(t *testing.T) {
+	checksums := []*FileChecksum{{Path: "a.bin", CRC32: 1, SizeBytes: 1}}
+
+	var buf bytes.Buffer
+	if err := WriteJSONL(&buf, 7, 2, "", checksums); err != nil {
+		t.Fatalf("WriteJSONL failed: %v", err)
+	}
+	legacy, err := ExportJSON(7, 2, checksums)
+	if err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+
+	for name, data := range map[string][]byte{"jsonl": buf.Bytes(), "legacy": legacy} {
+		export, err := ReadExport(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: ReadExport failed: %v", name, err)
+			continue
+		}
+		if export.RunID != 7 || export.StepNumber != 2 || len(export.Checksums) != 1 || export.Checksums[0].Path != "a.bin" {
+			t.Errorf("%s: ReadExport = %+v", name, export)
+		}
+	}
+}
+
+func TestReadExport_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", "no checksums provided"},
+		{"blank", "  \n", "no checksums provided"},
+		{"newer version", `{"format":"lfst-checksums-jsonl","version":99,"run_id":1,"step_number":1}` + "\n", "newer than this lfst reads"},
+		{"bad line", `{"format":"lfst-checksums-jsonl","version":1,"run_id":1,"step_number":1}` + "\n" +
+			`{"Path":"a.bin","CRC32":1,"SizeBytes":1}` + "\n" + "not json\n", "line 3"},
+	}
+	for _, tt := range tests {
+		_, err := ReadExport(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ReadExport error = %v, want one containing %q", tt.name, err, tt.want)
+		}
+	}
+}
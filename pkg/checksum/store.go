@@ -0,0 +1,217 @@
+package checksum
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+// Where a run stores the checksums of its steps
+const (
+	StoreDatabase = "database" // The checksums table (the default)
+	StoreManifest = "manifest" // A manifest file per step in the run's directory
+	StoreBoth     = "both"     // Both of them
+)
+
+// StoreModes lists the valid checksum store modes
+var StoreModes = []string{StoreDatabase, StoreManifest, StoreBoth}
+
+// ManifestDirName is the directory in a run's directory that holds its checksum manifests
+const ManifestDirName = "checksums"
+
+// manifestPattern matches the names of the manifest files of steps
+var manifestPattern = regexp.MustCompile(`^step-(\d+)\.json$`)
+
+// Store keeps the checksums of the steps of runs
+type Store interface {
+	// Store saves the checksums of a step
+	Store(runID int64, stepNumber int, checksums []*FileChecksum) error
+	// Load returns the checksums saved for a step, sorted by path
+	Load(runID int64, stepNumber int) ([]*database.Checksum, error)
+}
+
+// NewStore returns the store of a mode; manifestDir is where a manifest store writes, and
+// conflict the conflict mode of a database store
+func NewStore(mode string, db *database.DB, manifestDir, conflict string) (Store, error) {
+	switch mode {
+	case "", StoreDatabase:
+		return DatabaseStore{DB: db, Conflict: conflict}, nil
+	case StoreManifest:
+		return ManifestStore{Dir: manifestDir}, nil
+	case StoreBoth:
+		// Loading prefers the database, which a run that was resumed elsewhere may
+		// have filled without the manifests
+		return multiStore{DatabaseStore{DB: db, Conflict: conflict}, ManifestStore{Dir: manifestDir}}, nil
+	}
+	return nil, fmt.Errorf("invalid checksum store '%s' (valid: %s, %s, %s)", mode, StoreDatabase, StoreManifest, StoreBoth)
+}
+
+// DatabaseStore keeps checksums in the checksums table, a snapshot per step
+type DatabaseStore struct {
+	DB       *database.DB
+	Conflict string // Conflict mode of steps stored again; empty is ConflictReplace
+}
+
+// Store stores checksums as a snapshot of the given step
+func (s DatabaseStore) Store(runID int64, stepNumber int, checksums []*FileChecksum) error {
+	return StoreChecksums(s.DB, runID, stepNumber, checksums, s.Conflict)
+}
+
+// Load returns the checksums of the latest snapshot of a step
+func (s DatabaseStore) Load(runID int64, stepNumber int) ([]*database.Checksum, error) {
+	return s.DB.ListChecksums(runID, stepNumber)
+}
+
+// ManifestStore keeps the checksums of each step in a manifest file in Dir, in the JSON
+// format of ExportJSON, so clients without a database connection can record checksums
+// and import them later with ImportManifests
+type ManifestStore struct {
+	Dir string
+}
+
+// ManifestPath returns the path of the manifest of a step in dir
+func ManifestPath(dir string, stepNumber int) string {
+	return filepath.Join(dir, fmt.Sprintf("step-%d.json", stepNumber))
+}
+
+// Store writes the manifest of a step, replacing an earlier one
+func (s ManifestStore) Store(runID int64, stepNumber int, checksums []*FileChecksum) error {
+	data, err := ExportJSON(runID, stepNumber, checksums)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+
+	// Write a temporary file first, so a crash never leaves half a manifest
+	path := ManifestPath(s.Dir, stepNumber)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Load reads the manifest of a step
+func (s ManifestStore) Load(runID int64, stepNumber int) ([]*database.Checksum, error) {
+	path := ManifestPath(s.Dir, stepNumber)
+	export, err := readManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	if export.RunID != runID {
+		return nil, fmt.Errorf("manifest %s belongs to run %d, not run %d", path, export.RunID, runID)
+	}
+
+	checksums := toDatabase(export, 0)
+	sort.Slice(checksums, func(i, j int) bool {
+		return checksums[i].FilePath < checksums[j].FilePath
+	})
+	return checksums, nil
+}
+
+// multiStore stores in each of its stores and loads from the first
+type multiStore []Store
+
+func (m multiStore) Store(runID int64, stepNumber int, checksums []*FileChecksum) error {
+	for _, s := range m {
+		if err := s.Store(runID, stepNumber, checksums); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiStore) Load(runID int64, stepNumber int) ([]*database.Checksum, error) {
+	return m[0].Load(runID, stepNumber)
+}
+
+// CompareStored compares the checksums of two steps kept in store
+func CompareStored(store Store, runID int64, oldStep, newStep int) ([]*Difference, error) {
+	oldChecksums, err := store.Load(runID, oldStep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checksums for step %d: %w", oldStep, err)
+	}
+
+	newChecksums, err := store.Load(runID, newStep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checksums for step %d: %w", newStep, err)
+	}
+
+	return DiffChecksums(oldChecksums, newChecksums), nil
+}
+
+// ListManifests returns the manifest files of steps in dir, in step order
+func ListManifests(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest directory: %w", err)
+	}
+
+	steps := make(map[string]int)
+	var paths []string
+	for _, entry := range entries {
+		match := manifestPattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		steps[path], _ = strconv.Atoi(match[1])
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return steps[paths[i]] < steps[paths[j]]
+	})
+	return paths, nil
+}
+
+// ImportManifests stores the manifests of dir in the database as the conflict mode says.
+// A runID other than 0 replaces the run ID recorded in them, for a run that was itself
+// imported under a new ID. It returns the manifests it imported.
+func ImportManifests(db *database.DB, dir string, runID int64, conflict string) ([]string, error) {
+	paths, err := ListManifests(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no step manifests (step-N.json) in %s", dir)
+	}
+
+	for _, path := range paths {
+		export, err := readManifest(path)
+		if err != nil {
+			return nil, err
+		}
+		if runID != 0 {
+			export.RunID = runID
+		}
+		if err := importExport(db, export, conflict); err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", path, err)
+		}
+	}
+	return paths, nil
+}
+
+// readManifest reads a manifest file
+func readManifest(path string) (*ChecksumExport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	defer f.Close()
+	export, err := ReadExport(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	return export, nil
+}
@@ -0,0 +1,103 @@
+package checksum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestManifestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ManifestDirName)
+	store, err := NewStore(StoreManifest, nil, dir, "")
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+
+	step1 := []*FileChecksum{{Path: "b.zip", CRC32: 2, SizeBytes: 20}, {Path: "a.pdf", CRC32: 1, SizeBytes: 10}}
+	step2 := []*FileChecksum{{Path: "a.pdf", CRC32: 3, SizeBytes: 10}, {Path: "b.zip", CRC32: 2, SizeBytes: 20}}
+	if err := store.Store(7, 1, step1); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if err := store.Store(7, 10, step2); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	loaded, err := store.Load(7, 1)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded) != 2 || loaded[0].FilePath != "a.pdf" || loaded[0].CRC32 != "00000001" || loaded[1].SizeBytes != 20 {
+		t.Errorf("loaded = %+v", loaded)
+	}
+	if _, err := store.Load(8, 1); err == nil {
+		t.Error("Load accepted the manifest of another run")
+	}
+
+	diffs, err := CompareStored(store, 7, 1, 10)
+	if err != nil {
+		t.Fatalf("CompareStored failed: %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].FilePath != "a.pdf" || diffs[0].ChangeType != "modified" {
+		t.Errorf("diffs = %+v", diffs)
+	}
+
+	// Manifests sort by step number, not name; other files are ignored
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := ListManifests(dir)
+	if err != nil {
+		t.Fatalf("ListManifests failed: %v", err)
+	}
+	if len(paths) != 2 || filepath.Base(paths[0]) != "step-1.json" || filepath.Base(paths[1]) != "step-10.json" {
+		t.Errorf("manifests = %v", paths)
+	}
+
+	if _, err := NewStore("cloud", nil, dir, ""); err == nil {
+		t.Error("NewStore accepted an unknown mode")
+	}
+}
+
+func TestImportManifests(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+	run := &database.TestRun{ScenarioID: 1, ServerType: "lfs-test-server", Protocol: "http", Status: "completed"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("CreateTestRun failed: %v", err)
+	}
+
+	// An offline client wrote the manifests as its own run 3
+	dir := t.TempDir()
+	offline := ManifestStore{Dir: dir}
+	if err := offline.Store(3, 1, []*FileChecksum{{Path: "a.pdf", CRC32: 1, SizeBytes: 10}}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if err := offline.Store(3, 2, []*FileChecksum{{Path: "a.pdf", CRC32: 1, SizeBytes: 10}}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	paths, err := ImportManifests(db, dir, run.ID, ConflictReplace)
+	if err != nil {
+		t.Fatalf("ImportManifests failed: %v", err)
+	}
+	if len(paths) != 2 {
+		t.Errorf("imported %v, want 2 manifests", paths)
+	}
+	diffs, err := CompareChecksums(db, run.ID, 1, 2)
+	if err != nil {
+		t.Fatalf("CompareChecksums failed: %v", err)
+	}
+	checksums, err := db.ListChecksums(run.ID, 2)
+	if err != nil || len(checksums) != 1 || len(diffs) != 0 {
+		t.Errorf("step 2 checksums = %+v (%v), diffs = %+v", checksums, err, diffs)
+	}
+
+	if _, err := ImportManifests(db, t.TempDir(), run.ID, ConflictReplace); err == nil {
+		t.Error("ImportManifests accepted a directory without manifests")
+	}
+}
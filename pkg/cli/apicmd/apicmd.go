@@ -0,0 +1,176 @@
+// Package apicmd is lfst-api, which the lfst command also runs as lfst api
+package apicmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/api"
+	"github.com/mslinn/git-lfs-test/pkg/config"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/spf13/pflag"
+)
+
+var version string // Set by Command
+
+// Main runs lfst-api with args, the command line after the program name
+func Main(v string, args []string) {
+	run := Command(v, pflag.CommandLine)
+	pflag.CommandLine.Parse(args)
+	run(pflag.Args())
+}
+
+// Command defines the flags of lfst-api on fs and returns the function that runs it
+// with the arguments left once fs is parsed; lfst api registers them on its subcommand
+func Command(v string, fs *pflag.FlagSet) func(args []string) {
+	version = v
+
+	var (
+		showVersion bool
+		showHelp    bool
+		quiet       bool
+		noColor     bool
+		debug       bool
+		dbPath      string
+		addr        string
+		allowOrigin string
+	)
+
+	fs.BoolVarP(&showVersion, "version", "V", false, "Show version and exit")
+	fs.BoolVarP(&showHelp, "help", "h", false, "Show this help message")
+	fs.BoolVarP(&quiet, "quiet", "q", false, "Print errors only")
+	fs.BoolVar(&noColor, "no-color", false, "Plain output without colors (automatic when not a terminal)")
+	fs.BoolVarP(&debug, "debug", "d", false, "Enable debug output")
+	fs.BoolVarP(&debug, "verbose", "v", false, "Enable verbose output (alias for --debug)")
+	var logFile, logFormat string
+	fs.StringVar(&logFile, "log-file", "", "Also append every message, debug included, to this file")
+	fs.StringVar(&logFormat, "log-format", "", "Format of the log file: text or json (default text)")
+	fs.StringVar(&dbPath, "db", "", "Path to SQLite database (default from config)")
+	fs.StringVarP(&addr, "addr", "a", "127.0.0.1:8080", "Address to listen on")
+	fs.StringVar(&allowOrigin, "cors", "", "Access-Control-Allow-Origin value for browser clients (e.g. '*')")
+
+	return func(args []string) {
+		if showVersion {
+			fmt.Printf("lfst-api version %s\n", version)
+			os.Exit(0)
+		}
+
+		if showHelp {
+			printHelp()
+			os.Exit(0)
+		}
+
+		if err := term.Setup(quiet, noColor); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := log.Setup(debug, logFile, logFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Load configuration
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Use config database if not overridden
+		if dbPath == "" {
+			if dbPath, err = cfg.GetDatabasePath(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Validate database (creates directory if needed)
+		if err := cfg.ValidateDatabase(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error validating database: %v\n", err)
+			os.Exit(1)
+		}
+
+		db, err := database.Open(dbPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		s := api.New(db)
+		s.AllowOrigin = allowOrigin
+
+		server := &http.Server{Addr: addr, Handler: logRequests(s.Handler())}
+
+		// Shut down cleanly on Ctrl-C or SIGTERM
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		fmt.Printf("Serving %s on http://%s/api/runs\n", dbPath, addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// logRequests logs each request and how long it took at debug level
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, req)
+		log.Debugf("%s %s (%dms)\n", req.Method, req.URL.RequestURI(), time.Since(start).Milliseconds())
+	})
+}
+
+func printHelp() {
+	fmt.Printf("lfst-api - Serve Git LFS test results as a JSON API\n\n")
+	fmt.Printf("Version: %s\n\n", version)
+	fmt.Printf("DESCRIPTION:\n")
+	fmt.Printf("  Serve a read-only HTTP JSON API over the test database, so dashboards\n")
+	fmt.Printf("  and browser UIs can query results without access to the SQLite file.\n\n")
+
+	fmt.Printf("USAGE:\n")
+	fmt.Printf("  lfst-api [OPTIONS]\n\n")
+
+	fmt.Printf("ENDPOINTS:\n")
+	fmt.Printf("  GET /api/runs                       List test runs (?scenario=N to filter)\n")
+	fmt.Printf("  GET /api/runs/ID                    Run details with step results\n")
+	fmt.Printf("  GET /api/runs/ID/operations         Operations of a run (?step=N to filter)\n")
+	fmt.Printf("  GET /api/runs/ID/diff?from=A&to=B   Checksum differences between two steps or labels\n\n")
+
+	fmt.Printf("OPTIONS:\n")
+	fmt.Printf("  -a, --addr ADDR    Address to listen on (default: 127.0.0.1:8080)\n")
+	fmt.Printf("  --cors ORIGIN      Access-Control-Allow-Origin value for browser clients\n")
+	fmt.Printf("  -h, --help         Show this help message\n")
+	fmt.Printf("  -V, --version      Show version\n")
+	fmt.Printf("  -d, --debug        Log each request\n")
+	fmt.Printf("  -v, --verbose      Alias for --debug\n")
+	fmt.Printf("  --log-file PATH    Also append every message, debug included, to PATH\n")
+	fmt.Printf("  --log-format FMT   Format of the log file: text or json (default text)\n")
+	fmt.Printf("  --db PATH          Path to SQLite database\n\n")
+
+	fmt.Printf("EXAMPLES:\n")
+	fmt.Printf("  # Serve on the default address\n")
+	fmt.Printf("  lfst-api\n\n")
+
+	fmt.Printf("  # Listen on all interfaces and allow any browser origin\n")
+	fmt.Printf("  lfst-api --addr :8080 --cors '*'\n\n")
+
+	fmt.Printf("  # Query the API\n")
+	fmt.Printf("  curl http://127.0.0.1:8080/api/runs/5/diff?from=1&to=3\n\n")
+}
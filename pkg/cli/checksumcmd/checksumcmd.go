@@ -0,0 +1,582 @@
+// Package checksumcmd is lfst-checksum, which the lfst command also runs as lfst checksum
+package checksumcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/config"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/sshutil"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/mslinn/git-lfs-test/pkg/workerpool"
+	"github.com/spf13/pflag"
+)
+
+var version string // Set by Command
+
+// Commands returns the flags of each subcommand of lfst-checksum, for completion; verify
+// takes the options of lfst-checksum itself
+func Commands() map[string]*pflag.FlagSet {
+	return map[string]*pflag.FlagSet{"verify": pflag.NewFlagSet("verify", pflag.ContinueOnError)}
+}
+
+// Main runs lfst-checksum with args, the command line after the program name
+func Main(v string, args []string) {
+	run := Command(v, pflag.CommandLine)
+	pflag.CommandLine.Parse(args)
+	run(pflag.Args())
+}
+
+// Command defines the flags of lfst-checksum on fs and returns the function that runs it
+// with the arguments left once fs is parsed; lfst checksum registers them on its subcommand
+func Command(v string, fs *pflag.FlagSet) func(args []string) {
+	version = v
+
+	// Define flags
+	var (
+		showVersion  bool
+		showHelp     bool
+		quiet        bool
+		noColor      bool
+		debug        bool
+		dbPath       string
+		runID        int64
+		stepNumber   int
+		label        string
+		directory    string
+		compareWith  string
+		skipDatabase bool
+		forceLocal   bool
+		forceRemote  string
+		workers      int
+		exclude      []string
+		noExclude    bool
+	)
+
+	fs.BoolVarP(&showVersion, "version", "V", false, "Show version and exit")
+	fs.BoolVarP(&showHelp, "help", "h", false, "Show this help message")
+	fs.BoolVarP(&quiet, "quiet", "q", false, "Print errors only")
+	fs.BoolVar(&noColor, "no-color", false, "Plain output without colors (automatic when not a terminal)")
+	fs.BoolVarP(&debug, "debug", "d", false, "Enable debug output")
+	fs.BoolVarP(&debug, "verbose", "v", false, "Enable verbose output (alias for --debug)")
+	var logFile, logFormat string
+	fs.StringVar(&logFile, "log-file", "", "Also append every message, debug included, to this file")
+	fs.StringVar(&logFormat, "log-format", "", "Format of the log file: text or json (default text)")
+	fs.StringVar(&dbPath, "db", "", "Path to SQLite database (default from config)")
+	fs.Int64Var(&runID, "run-id", 0, "Test run ID (required unless --skip-db)")
+	fs.IntVar(&stepNumber, "step", 0, "Step number (required unless --skip-db)")
+	fs.StringVar(&label, "label", "", "Store as a named snapshot (e.g. pre-migration) instead of a step")
+	fs.StringVar(&directory, "dir", ".", "Directory to compute checksums for")
+	fs.StringVar(&compareWith, "compare", "", "Compare with checksums from this step number or snapshot label")
+	fs.BoolVar(&skipDatabase, "skip-db", false, "Skip database operations, just compute and display")
+	fs.BoolVar(&forceLocal, "local", false, "Force local database access (disable auto-remote)")
+	fs.StringVar(&forceRemote, "remote", "", "Force remote mode with specified host")
+	fs.IntVar(&workers, "workers", 0, "Number of files to checksum concurrently (default: one per CPU)")
+	fs.StringSliceVar(&exclude, "exclude", nil, "Also skip files and directories matching these patterns, e.g. 'node_modules,build/*.o' (repeatable)")
+	fs.BoolVar(&noExclude, "no-default-exclude", false, "Checksum the files checksum_exclude in the config file would skip")
+
+	return func(args []string) {
+		// Handle version
+		if showVersion {
+			fmt.Printf("lfst-checksum version %s\n", version)
+			os.Exit(0)
+		}
+
+		// Handle help
+		if showHelp {
+			printHelp(fs)
+			os.Exit(0)
+		}
+
+		if err := term.Setup(quiet, noColor); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := log.Setup(debug, logFile, logFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// The only subcommand is verify; without one, lfst-checksum computes and stores checksums
+		verify := false
+		switch {
+		case len(args) == 0:
+		case len(args) == 1 && args[0] == "verify":
+			verify = true
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown subcommand '%s'\n\n", strings.Join(args, " "))
+			printUsage(fs)
+			os.Exit(1)
+		}
+		if verify && (skipDatabase || compareWith != "") {
+			fmt.Fprintf(os.Stderr, "Error: verify compares with the database; it cannot be used with --skip-db or --compare\n")
+			os.Exit(1)
+		}
+
+		// Validate flags
+		if err := (checksum.Filter{Exclude: exclude}).Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --exclude: %v\n", err)
+			os.Exit(1)
+		}
+		if !skipDatabase {
+			if runID == 0 {
+				fmt.Fprintf(os.Stderr, "Error: --run-id is required (or use --skip-db)\n\n")
+				printUsage(fs)
+				os.Exit(1)
+			}
+			if stepNumber == 0 && label == "" {
+				fmt.Fprintf(os.Stderr, "Error: --step or --label is required (or use --skip-db)\n\n")
+				printUsage(fs)
+				os.Exit(1)
+			}
+			if stepNumber != 0 && label != "" {
+				fmt.Fprintf(os.Stderr, "Error: --step and --label are mutually exclusive\n\n")
+				printUsage(fs)
+				os.Exit(1)
+			}
+			if label != "" {
+				if err := checksum.CheckLabel(label); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: --label: %v\n", err)
+					os.Exit(1)
+				}
+			}
+		}
+
+		// Load configuration
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Use config database if not overridden
+		if dbPath == "" {
+			if dbPath, err = cfg.GetDatabasePath(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Determine if we should use remote mode
+		useRemote := false
+		remoteHost := ""
+		importURL := "" // Set when checksums go to lfst-import --listen instead of over SSH
+
+		if forceLocal {
+			useRemote = false
+		} else if forceRemote != "" {
+			useRemote = true
+			remoteHost = forceRemote
+		} else if !skipDatabase && cfg.IsRemoteHost() && !database.IsPostgresURL(dbPath) {
+			// A PostgreSQL server is reached directly from any machine
+			useRemote = true
+			remoteHost = cfg.RemoteHost
+			importURL = cfg.ImportURL
+		}
+
+		// Get absolute path
+		absDir, err := filepath.Abs(directory)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to get absolute path: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Check directory exists
+		if _, err := os.Stat(absDir); os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error: directory does not exist: %s\n", absDir)
+			os.Exit(1)
+		}
+
+		// Skip the files of checksum_exclude and --exclude
+		var patterns []string
+		if !noExclude {
+			patterns = append(patterns, cfg.GetChecksumExclude()...)
+		}
+		patterns = append(patterns, exclude...)
+		log.Debugf("Excluding: %s\n", strings.Join(patterns, " "))
+
+		opts := workerpool.Options{Workers: workers}
+		if debug {
+			opts.Progress = os.Stdout
+		}
+
+		if verify {
+			if useRemote {
+				fmt.Fprintf(os.Stderr, "Error: verify reads the checksums from the database, which is on %s; "+
+					"run it there, or use --local with a shared PostgreSQL database\n", remoteHost)
+				os.Exit(1)
+			}
+			os.Exit(verifyDirectory(dbPath, absDir, runID, stepNumber, label, patterns, opts))
+		}
+
+		log.Debugf("Computing checksums for: %s\n", absDir)
+		if !skipDatabase {
+			if importURL != "" {
+				log.Debugf("Remote mode: will send to %s\n", importURL)
+			} else if useRemote {
+				log.Debugf("Remote mode: will send to %s:%s\n", remoteHost, database.Redact(dbPath))
+			} else {
+				log.Debugf("Local mode: %s\n", database.Redact(dbPath))
+			}
+			log.Debugf("Run ID: %d\n", runID)
+			if label != "" {
+				log.Debugf("Label: %s\n", label)
+			} else {
+				log.Debugf("Step: %d\n", stepNumber)
+			}
+		}
+
+		// Compute checksums
+		checksums, err := checksum.ComputeDirectoryWith(absDir, patterns, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error computing checksums: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Computed %d checksums\n", len(checksums))
+
+		// Display checksums if debug or skip-db
+		if debug || skipDatabase {
+			for _, cs := range checksums {
+				fmt.Printf("  %08x  %10s  %s\n",
+					cs.CRC32,
+					checksum.FormatSize(cs.SizeBytes),
+					cs.Path,
+				)
+			}
+		}
+
+		// Skip database operations if requested
+		if skipDatabase {
+			os.Exit(0)
+		}
+
+		// Handle remote mode
+		if useRemote {
+			if importURL != "" {
+				n, err := executeUpload(cfg, runID, stepNumber, label, checksums)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error in remote mode: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Printf("%s Stored %d checksums at %s for %s\n", term.OK(), n, importURL, snapshotName(stepNumber, label))
+			} else {
+				if err := executeRemote(remoteHost, dbPath, runID, stepNumber, label, checksums, debug); err != nil {
+					fmt.Fprintf(os.Stderr, "Error in remote mode: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Printf("%s Stored %d checksums on %s for %s\n", term.OK(), len(checksums), remoteHost, snapshotName(stepNumber, label))
+			}
+
+			// No comparison in remote mode (would need to fetch data back)
+			if compareWith != "" {
+				fmt.Println("Note: --compare not supported in remote mode")
+			}
+			os.Exit(0)
+		}
+
+		// Local mode: validate database (creates directory if needed)
+		if err := cfg.ValidateDatabase(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error validating database: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Open database directly
+		db, err := database.Open(dbPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+		if !db.ChecksumsUnique() {
+			log.Warnf("the database holds duplicate checksums of steps; remove them with: lfst-query dedupe\n")
+		}
+
+		// Verify run exists
+		run, err := db.GetTestRun(runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: test run %d not found: %v\n", runID, err)
+			os.Exit(1)
+		}
+
+		log.Debugf("Test run: scenario %d, server %s, protocol %s\n", run.ScenarioID, run.ServerType, run.Protocol)
+
+		// Store checksums in database
+		if label != "" {
+			_, err = checksum.StoreSnapshot(db, runID, label, checksums)
+		} else {
+			err = checksum.StoreChecksums(db, runID, stepNumber, checksums, cfg.GetChecksumConflict())
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error storing checksums: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Stored checksums in database for %s\n", snapshotName(stepNumber, label))
+
+		// Compare with previous step if requested
+		if compareWith != "" {
+			fmt.Printf("\nComparing with %s:\n", compareWith)
+			current := label
+			if current == "" {
+				current = fmt.Sprintf("%d", stepNumber)
+			}
+			// Files excluded now are left out of the earlier snapshot too, rather than reported as deleted
+			diffs, err := checksum.CompareSnapshotsWith(db, runID, compareWith, current,
+				checksum.DiffOptions{Filter: checksum.Filter{Exclude: patterns}})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error comparing checksums: %v\n", err)
+				os.Exit(1)
+			}
+
+			if len(diffs) == 0 {
+				fmt.Println("  No differences found")
+			} else {
+				printDifferences(diffs)
+				fmt.Printf("\nTotal differences: %d\n", len(diffs))
+			}
+		}
+	}
+}
+
+// printDifferences lists differences between checksums, one per line
+func printDifferences(diffs []*checksum.Difference) {
+	for _, diff := range diffs {
+		switch diff.ChangeType {
+		case "added":
+			fmt.Printf("  ADDED:    %s (%s)\n",
+				diff.FilePath, checksum.FormatSize(diff.NewSize))
+		case "deleted":
+			fmt.Printf("  DELETED:  %s (was %s)\n",
+				diff.FilePath, checksum.FormatSize(diff.OldSize))
+		case "renamed":
+			fmt.Printf("  RENAMED:  %s → %s (%s)\n",
+				diff.OldPath, diff.FilePath, checksum.FormatSize(diff.NewSize))
+		case "modified":
+			fmt.Printf("  MODIFIED: %s (%s)\n",
+				diff.FilePath, checksum.FormatSize(diff.NewSize))
+			log.Debugf("            CRC: %s -> %s\n", diff.OldCRC32, diff.NewCRC32)
+		case "size-changed":
+			fmt.Printf("  SIZE:     %s (%s -> %s)\n",
+				diff.FilePath,
+				checksum.FormatSize(diff.OldSize),
+				checksum.FormatSize(diff.NewSize))
+			log.Debugf("            CRC: %s -> %s\n", diff.OldCRC32, diff.NewCRC32)
+		}
+	}
+}
+
+// verifyDirectory checksums dir and compares it with the checksums recorded for a step
+// or snapshot of a run, reading those of a run with --checksum-store manifest from its
+// manifest; it returns the exit status
+func verifyDirectory(dbPath, dir string, runID int64, stepNumber int, label string, patterns []string, opts workerpool.Options) int {
+	// Opening a database file that is not there would create an empty one
+	if !database.IsPostgresURL(dbPath) {
+		if _, err := os.Stat(dbPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: database %s not found\n", dbPath)
+			return 1
+		}
+	}
+	db, err := database.Open(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	run, err := db.GetTestRun(runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: test run %d not found: %v\n", runID, err)
+		return 1
+	}
+
+	var recorded []*database.Checksum
+	if label != "" {
+		recorded, err = checksum.ResolveSnapshot(db, runID, label)
+	} else {
+		recorded, err = db.ListChecksums(runID, stepNumber)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(recorded) == 0 && label == "" && run.WorkDir != "" {
+		manifests := checksum.ManifestStore{Dir: filepath.Join(run.WorkDir, checksum.ManifestDirName)}
+		if recorded, err = manifests.Load(runID, stepNumber); err != nil {
+			log.Debugf("No manifest: %v\n", err)
+		}
+	}
+	if len(recorded) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: run %d has no checksums recorded for %s\n", runID, snapshotName(stepNumber, label))
+		return 1
+	}
+
+	log.Debugf("Verifying %s against %d checksums of %s of run %d\n", dir, len(recorded), snapshotName(stepNumber, label), runID)
+	diffs, err := checksum.Verify(recorded, dir, patterns, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error computing checksums: %v\n", err)
+		return 1
+	}
+	if len(diffs) > 0 {
+		printDifferences(diffs)
+		fmt.Fprintf(os.Stderr, "\n%s %d files differ from %s of run %d\n", term.Fail(), len(diffs), snapshotName(stepNumber, label), runID)
+		return 1
+	}
+	checked := len(checksum.Filter{Exclude: patterns}.Apply(recorded))
+	fmt.Printf("%s All %d files match %s of run %d\n", term.OK(), checked, snapshotName(stepNumber, label), runID)
+	return 0
+}
+
+// snapshotName describes where checksums were stored, for user messages
+func snapshotName(stepNumber int, label string) string {
+	if label != "" {
+		return fmt.Sprintf("snapshot '%s'", label)
+	}
+	return fmt.Sprintf("step %d", stepNumber)
+}
+
+// executeRemote sends checksums to remote host via SSH, streamed as JSON Lines so that
+// neither side holds them all as one document
+func executeRemote(host, dbPath string, runID int64, stepNumber int, label string, checksums []*checksum.FileChecksum, debug bool) error {
+	// Build SSH command
+	sshCmd := fmt.Sprintf("lfst-import --stdin --db %s", dbPath)
+	cmd := sshutil.Command(host, sshCmd)
+
+	// Pipe checksums to stdin
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+
+	// Start command
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start SSH command: %w", err)
+	}
+
+	// Write checksums; if lfst-import stopped early, its own error says why
+	writeErr := checksum.WriteJSONL(stdin, runID, stepNumber, label, checksums)
+	stdin.Close()
+
+	// Wait for completion
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("SSH command failed: %w", err)
+	}
+	return writeErr
+}
+
+// executeUpload sends checksums to lfst-import --listen at import_url over HTTPS, for
+// database hosts that clients cannot reach over SSH, and returns how many it stored
+func executeUpload(cfg *config.Config, runID int64, stepNumber int, label string, checksums []*checksum.FileChecksum) (int, error) {
+	token, err := cfg.GetImportToken()
+	if err != nil {
+		return 0, err
+	}
+	if token == "" {
+		return 0, fmt.Errorf("import_url needs the token of lfst-import --listen; set it with: lfst-config set import_token TOKEN")
+	}
+	client, err := checksum.UploadClient(cfg.ImportCACert)
+	if err != nil {
+		return 0, err
+	}
+	return checksum.Upload(client, cfg.ImportURL, token, runID, stepNumber, label, checksums)
+}
+
+func printUsage(fs *pflag.FlagSet) {
+	fmt.Fprintf(os.Stderr, "Usage: lfst-checksum [verify] [OPTIONS]\n\n")
+	fs.PrintDefaults()
+}
+
+func printHelp(fs *pflag.FlagSet) {
+	fmt.Printf("lfst-checksum - Compute and verify CRC32 checksums for Git LFS testing\n\n")
+	fmt.Printf("Version: %s\n\n", version)
+	fmt.Printf("DESCRIPTION:\n")
+	fmt.Printf("  Computes CRC32 checksums for all files in a directory (recursively),\n")
+	fmt.Printf("  stores them in a SQLite database, and optionally compares with checksums\n")
+	fmt.Printf("  from a previous step to detect file changes. A deleted file whose CRC32 and\n")
+	fmt.Printf("  size match an added file is reported once, as RENAMED old → new.\n\n")
+	fmt.Printf("  Files in .git/ directories and files named .checksums are automatically skipped,\n")
+	fmt.Printf("  and so are the files matching 'checksum_exclude' in the config file, by default\n")
+	fmt.Printf("  stray OS and editor files: %s\n\n", strings.Join(checksum.DefaultExclude, " "))
+	fmt.Printf("  --exclude skips more, e.g. node_modules, build output or an LFS object cache when\n")
+	fmt.Printf("  checksumming an arbitrary repository. A pattern (see 'go doc path.Match') matches\n")
+	fmt.Printf("  a file by its path relative to --dir or by its name; a pattern that matches a\n")
+	fmt.Printf("  directory skips everything in it. --no-default-exclude drops checksum_exclude,\n")
+	fmt.Printf("  leaving only the --exclude patterns. --compare leaves the excluded files out of\n")
+	fmt.Printf("  the earlier checksums as well, so they are not reported as deleted.\n\n")
+
+	fmt.Printf("USAGE:\n")
+	fmt.Printf("  lfst-checksum --run-id ID --step N --dir PATH\n")
+	fmt.Printf("  lfst-checksum --run-id ID --step N --dir PATH --compare M\n")
+	fmt.Printf("  lfst-checksum --run-id ID --label NAME --dir PATH --compare M\n")
+	fmt.Printf("  lfst-checksum --skip-db --dir PATH [--exclude PATTERN ...]\n")
+	fmt.Printf("  lfst-checksum --local --run-id ID --step N --dir PATH\n")
+	fmt.Printf("  lfst-checksum --remote HOST --run-id ID --step N --dir PATH\n")
+	fmt.Printf("  lfst-checksum verify --run-id ID --step N --dir PATH\n")
+	fmt.Printf("  lfst-checksum verify --run-id ID --label NAME --dir PATH\n\n")
+
+	fmt.Printf("VERIFY:\n")
+	fmt.Printf("  'verify' computes the checksums of --dir again and compares them with those\n")
+	fmt.Printf("  recorded for the step or snapshot, without storing anything, so repositories kept\n")
+	fmt.Printf("  after a run can be checked for corruption later. Files whose content has diverged\n")
+	fmt.Printf("  are listed as MODIFIED, SIZE, DELETED, ADDED or RENAMED, and the exit status is 1.\n")
+	fmt.Printf("  The checksums of a run with --checksum-store manifest are read from the manifest in\n")
+	fmt.Printf("  its directory. --dir is the repository the step checksummed: repo1, or repo2 for the\n")
+	fmt.Printf("  steps of the second clone. verify needs the database on this machine or a shared\n")
+	fmt.Printf("  PostgreSQL database, and reads every file.\n\n")
+
+	fmt.Printf("OPTIONS:\n")
+	fs.PrintDefaults()
+
+	fmt.Printf("\nEXAMPLES:\n")
+	fmt.Printf("  # Compute and display checksums without database\n")
+	fmt.Printf("  lfst-checksum --skip-db --dir /path/to/repo\n\n")
+
+	fmt.Printf("  # Store checksums for step 1 of test run 5\n")
+	fmt.Printf("  lfst-checksum --run-id 5 --step 1 --dir /path/to/repo\n\n")
+
+	fmt.Printf("  # Store checksums for step 3 and compare with step 1\n")
+	fmt.Printf("  lfst-checksum --run-id 5 --step 3 --dir /path/to/repo --compare 1\n\n")
+
+	fmt.Printf("  # Store a named snapshot before an ad-hoc migration, then compare with step 3\n")
+	fmt.Printf("  lfst-checksum --run-id 5 --label pre-migration --dir /path/to/repo --compare 3\n\n")
+
+	fmt.Printf("  # Check a repository kept after run 5 against the checksums of its step 3\n")
+	fmt.Printf("  lfst-checksum verify --run-id 5 --step 3 --dir /tmp/lfst/run-5/repo1\n\n")
+
+	fmt.Printf("  # Checksum a JavaScript project without its dependencies and build output\n")
+	fmt.Printf("  lfst-checksum --skip-db --dir ~/work/webapp --exclude node_modules,dist --exclude '*.map'\n\n")
+
+	fmt.Printf("  # Debug mode with verbose output\n")
+	fmt.Printf("  lfst-checksum -d --run-id 5 --step 1 --dir /path/to/repo\n\n")
+
+	fmt.Printf("REMOTE MODE:\n")
+	fmt.Printf("  By default, lfst-checksum auto-detects if it's running on a remote machine\n")
+	fmt.Printf("  (hostname != gojira) and automatically uses SSH to send data to the server.\n\n")
+	fmt.Printf("  - --local: Force local mode (disable auto-remote)\n")
+	fmt.Printf("  - --remote HOST: Force remote mode with specific host\n")
+	fmt.Printf("  - Auto-remote can be disabled in ~/.lfs-test-config\n")
+	fmt.Printf("  - Checksums are streamed to lfst-import on the server as JSON Lines, one line per\n")
+	fmt.Printf("    file, so the server's lfst-import must be this version or newer\n")
+	fmt.Printf("  - With 'import_url' set, auto-remote mode sends them over HTTPS to lfst-import --listen\n")
+	fmt.Printf("    instead, presenting 'import_token', so no passwordless SSH is needed; --remote HOST\n")
+	fmt.Printf("    still uses SSH\n\n")
+
+	fmt.Printf("CONFIGURATION:\n")
+	fmt.Printf("  Configuration priority (highest to lowest):\n")
+	fmt.Printf("  1. Command-line flags (--db, --remote, --local)\n")
+	fmt.Printf("  2. Environment variables (LFS_TEST_DB, LFS_REMOTE_HOST)\n")
+	fmt.Printf("  3. Config file (~/.lfs-test-config)\n")
+	fmt.Printf("  4. Defaults (gojira, /home/mslinn/lfs_eval/lfs-test.db)\n\n")
+
+	fmt.Printf("NOTES:\n")
+	fmt.Printf("  - CRC32 values use the IEEE polynomial (same as cksum command)\n")
+	fmt.Printf("  - Checksums are stored with millisecond-precision timestamps\n")
+	fmt.Printf("  - The database file is created automatically if it doesn't exist\n")
+	fmt.Printf("  - Use --skip-db for quick checksum verification without database\n")
+	fmt.Printf("  - Remote mode requires passwordless SSH to the server, unless import_url is set\n\n")
+}
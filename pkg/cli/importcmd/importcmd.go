@@ -0,0 +1,468 @@
+// Package importcmd is lfst-import, which the lfst command also runs as lfst import
+package importcmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/config"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/legacy"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/spf13/pflag"
+)
+
+var version string // Set by Command
+
+// Main runs lfst-import with args, the command line after the program name
+func Main(v string, args []string) {
+	run := Command(v, pflag.CommandLine)
+	pflag.CommandLine.Parse(args)
+	run(pflag.Args())
+}
+
+// Command defines the flags of lfst-import on fs and returns the function that runs it
+// with the arguments left once fs is parsed; lfst import registers them on its subcommand
+func Command(v string, fs *pflag.FlagSet) func(args []string) {
+	version = v
+
+	// Define flags
+	var (
+		showVersion bool
+		showHelp    bool
+		quiet       bool
+		noColor     bool
+		debug       bool
+		dbPath      string
+		stdinMode   bool
+		runMode     bool
+		legacyMode  bool
+		manifests   bool
+		runID       int64
+		scenarioID  int
+		serverType  string
+		protocol    string
+		gitServer   string
+		startedArg  string
+		dryRun      bool
+		listen      string
+		tlsCert     string
+		tlsKey      string
+	)
+
+	fs.BoolVarP(&showVersion, "version", "V", false, "Show version and exit")
+	fs.BoolVarP(&showHelp, "help", "h", false, "Show this help message")
+	fs.BoolVarP(&quiet, "quiet", "q", false, "Print errors only")
+	fs.BoolVar(&noColor, "no-color", false, "Plain output without colors (automatic when not a terminal)")
+	fs.BoolVarP(&debug, "debug", "d", false, "Enable debug output")
+	fs.BoolVarP(&debug, "verbose", "v", false, "Enable verbose output (alias for --debug)")
+	var logFile, logFormat string
+	fs.StringVar(&logFile, "log-file", "", "Also append every message, debug included, to this file")
+	fs.StringVar(&logFormat, "log-format", "", "Format of the log file: text or json (default text)")
+	fs.StringVar(&dbPath, "db", "", "Path to SQLite database (default from config)")
+	fs.BoolVar(&stdinMode, "stdin", false, "Read JSON from stdin instead of file")
+	fs.BoolVar(&runMode, "run", false, "Import a test run exported by lfst-query export instead of checksums")
+	fs.BoolVar(&legacyMode, "legacy", false, "Import a directory of timing and checksum files written by the original bash scripts")
+	fs.BoolVar(&manifests, "manifests", false, "Import the checksum manifests (step-N.json) of a run's directory, written by lfst-scenario --checksum-store")
+	fs.Int64Var(&runID, "run-id", 0, "With --manifests: run the checksums belong to (default: the run recorded in the manifests)")
+	fs.IntVar(&scenarioID, "scenario", 0, "With --legacy: scenario the results belong to")
+	fs.StringVar(&serverType, "server", "", "With --legacy: LFS server type, e.g. lfs-test-server")
+	fs.StringVar(&protocol, "protocol", "", "With --legacy: protocol, e.g. http, ssh or local")
+	fs.StringVar(&gitServer, "git-server", "bare", "With --legacy: git server, e.g. bare or github")
+	fs.StringVar(&startedArg, "started", "", "With --legacy: when the run started, YYYY-MM-DD or RFC 3339 (default: oldest file's time)")
+	fs.BoolVar(&dryRun, "dry-run", false, "With --legacy: show what would be imported without importing it")
+	fs.StringVar(&listen, "listen", "", "Accept checksums POSTed over HTTPS on this address, e.g. :9443, instead of reading them")
+	fs.StringVar(&tlsCert, "tls-cert", "", "With --listen: PEM file of the server's certificate")
+	fs.StringVar(&tlsKey, "tls-key", "", "With --listen: PEM file of the certificate's private key")
+
+	return func(args []string) {
+		// Handle version
+		if showVersion {
+			fmt.Printf("lfst-import version %s\n", version)
+			os.Exit(0)
+		}
+
+		// Handle help
+		if showHelp {
+			printHelp(fs)
+			os.Exit(0)
+		}
+
+		if err := term.Setup(quiet, noColor); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := log.Setup(debug, logFile, logFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Load configuration
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Use config database if not overridden
+		if dbPath == "" {
+			if dbPath, err = cfg.GetDatabasePath(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		log.Debugf("Database: %s\n", database.Redact(dbPath))
+
+		if legacyMode {
+			if len(args) != 1 {
+				fmt.Fprintf(os.Stderr, "Error: --legacy needs the directory of the results\n")
+				os.Exit(1)
+			}
+			if scenarioID <= 0 || serverType == "" || protocol == "" {
+				fmt.Fprintf(os.Stderr, "Error: --legacy needs --scenario, --server and --protocol, which the files do not record\n")
+				os.Exit(1)
+			}
+			opts := legacy.Options{ScenarioID: scenarioID, ServerType: serverType, Protocol: protocol, GitServer: gitServer}
+			if startedArg != "" {
+				started, err := parseStarted(startedArg)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				opts.StartedAt = started
+			}
+			importLegacy(cfg, dbPath, args[0], opts, dryRun)
+			return
+		}
+
+		if listen != "" {
+			if tlsCert == "" || tlsKey == "" {
+				fmt.Fprintf(os.Stderr, "Error: --listen needs --tls-cert and --tls-key, as clients send a token with every request\n")
+				os.Exit(1)
+			}
+			token, err := cfg.GetImportToken()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if token == "" {
+				fmt.Fprintf(os.Stderr, "Error: --listen needs a token for clients to present; set one with: lfst-config set import_token TOKEN\n")
+				os.Exit(1)
+			}
+			serve(cfg, dbPath, listen, tlsCert, tlsKey, token)
+			return
+		}
+
+		if manifests {
+			if len(args) != 1 {
+				fmt.Fprintf(os.Stderr, "Error: --manifests needs the directory of the manifests\n")
+				os.Exit(1)
+			}
+			importManifests(cfg, dbPath, args[0], runID)
+			return
+		}
+
+		// Get JSON input, read as it is imported, so streamed checksums are never held whole
+		var input io.Reader = os.Stdin
+		if stdinMode || len(args) == 0 {
+			log.Debugf("Reading JSON from stdin...\n")
+		} else {
+			// Read from file
+			jsonFile := args[0]
+			log.Debugf("Reading JSON from file: %s\n", jsonFile)
+			f, err := os.Open(jsonFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			input = f
+		}
+
+		buffered := bufio.NewReader(input)
+		if _, err := buffered.Peek(1); err == io.EOF {
+			fmt.Fprintf(os.Stderr, "Error: no JSON data provided\n")
+			os.Exit(1)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Validate database (creates directory if needed)
+		if err := cfg.ValidateDatabase(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error validating database: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Open database
+		db, err := database.Open(dbPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		if runMode {
+			runID, err := db.ImportRun(buffered)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error importing run: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s Run imported as run %d\n", term.OK(), runID)
+			return
+		}
+
+		// Import checksums, as JSON Lines or a single document
+		n, err := checksum.ImportStream(db, buffered, cfg.GetChecksumConflict())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error importing checksums: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s %d checksums imported successfully\n", term.OK(), n)
+	}
+}
+
+// serve accepts checksums POSTed over HTTPS with the bearer token until interrupted
+func serve(cfg *config.Config, dbPath, addr, certFile, keyFile, token string) {
+	// Validate database (creates directory if needed)
+	if err := cfg.ValidateDatabase(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error validating database: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	handler := &checksum.ImportServer{DB: db, Token: token, Logf: log.Infof, Conflict: cfg.GetChecksumConflict()}
+	server := &http.Server{
+		Addr:    addr,
+		Handler: logRequests(handler),
+		// Clients that stall or hold idle connections do not tie up the server
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       5 * time.Minute,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	// Shut down cleanly on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Importing checksums into %s from https://%s%s\n", database.Redact(dbPath), addr, checksum.ImportPath)
+	if err := server.ListenAndServeTLS(certFile, keyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// logRequests logs each request, its status and how long it took at debug level
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, req)
+		log.Debugf("%s %s from %s: %d (%dms)\n", req.Method, req.URL.RequestURI(), req.RemoteAddr,
+			rec.status, time.Since(start).Milliseconds())
+	})
+}
+
+// statusRecorder remembers the status of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// parseStarted parses the start time of a legacy run: a date or an RFC 3339 time
+func parseStarted(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --started '%s' (use YYYY-MM-DD or RFC 3339, e.g. 2023-05-01T14:30:00Z)", value)
+	}
+	return t, nil
+}
+
+// importLegacy imports, or with dryRun only describes, the legacy results in dir
+func importLegacy(cfg *config.Config, dbPath, dir string, opts legacy.Options, dryRun bool) {
+	var result *legacy.Result
+	var err error
+	if dryRun {
+		result, err = legacy.Read(dir, opts)
+	} else {
+		if err := cfg.ValidateDatabase(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error validating database: %v\n", err)
+			os.Exit(1)
+		}
+		var db *database.DB
+		if db, err = database.Open(dbPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+		result, err = legacy.Import(db, dir, opts)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing legacy results: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, step := range result.Steps() {
+		var total time.Duration
+		for _, timing := range result.Timings[step] {
+			total += timing.Duration
+			log.Debugf("  Step %d: %-40s %s\n", step, timing.Command, timing.Duration)
+		}
+		fmt.Printf("Step %d: %d operations (%s), %d checksums\n",
+			step, len(result.Timings[step]), total.Round(time.Millisecond), len(result.Checksums[step]))
+	}
+	for _, name := range result.Skipped {
+		fmt.Printf("Skipped %s: not a timing or checksum file of a step\n", name)
+	}
+
+	if dryRun {
+		fmt.Printf("Would import scenario %d (%s via %s), started %s\n",
+			opts.ScenarioID, opts.ServerType, opts.Protocol, result.Run.StartedAt.Format(time.RFC3339))
+		return
+	}
+	fmt.Printf("%s Legacy results imported as run %d\n", term.OK(), result.Run.ID)
+}
+
+// importManifests imports the checksum manifests in dir into the run runID, or into the
+// run recorded in them if runID is 0
+func importManifests(cfg *config.Config, dbPath, dir string, runID int64) {
+	if err := cfg.ValidateDatabase(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error validating database: %v\n", err)
+		os.Exit(1)
+	}
+	db, err := database.Open(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if runID != 0 {
+		if _, err := db.GetTestRun(runID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	paths, err := checksum.ImportManifests(db, dir, runID, cfg.GetChecksumConflict())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing manifests: %v\n", err)
+		os.Exit(1)
+	}
+	for _, path := range paths {
+		log.Debugf("Imported %s\n", path)
+	}
+	fmt.Printf("%s %d manifests imported\n", term.OK(), len(paths))
+}
+
+func printHelp(fs *pflag.FlagSet) {
+	fmt.Printf("lfst-import - Import checksum data from JSON into database\n\n")
+	fmt.Printf("Version: %s\n\n", version)
+	fmt.Printf("DESCRIPTION:\n")
+	fmt.Printf("  Imports checksum data from JSON format (exported by lfst-checksum)\n")
+	fmt.Printf("  into the SQLite database. Reads from stdin or a file.\n")
+	fmt.Printf("  Checksums may be JSON Lines, a header line then a line per file, as lfst-checksum\n")
+	fmt.Printf("  streams them in remote mode, or the single JSON document of older versions. JSON Lines\n")
+	fmt.Printf("  are stored as they arrive, so millions of files never need to fit in memory.\n")
+	fmt.Printf("  With --run, imports a whole test run written by lfst-query export instead:\n")
+	fmt.Printf("  the run and its operations, logs, checksums and sizes are added with new IDs,\n")
+	fmt.Printf("  so results can be moved between machines or restored from an archive.\n")
+	fmt.Printf("  With --legacy, imports the results of the original bash evaluation scripts from a\n")
+	fmt.Printf("  directory as a completed run, so earlier measurements can be compared with new ones.\n")
+	fmt.Printf("  Files are matched to steps by name (step2-timing.txt, step2-checksums.txt, ...):\n")
+	fmt.Printf("    Timing files: each command echoed ('$ git add .') or traced by bash -x ('+ git add .'),\n")
+	fmt.Printf("      followed by the output of time ('real 0m3.241s') or GNU time ('0:03.24elapsed');\n")
+	fmt.Printf("      a 'Step N' line switches to another step\n")
+	fmt.Printf("    Checksum files: one 'CRC32 PATH' or 'CRC32 SIZE PATH' line per file, as from crc32\n")
+	fmt.Printf("  Operations are named as lfst-scenario names them (git lfs track is lfs-track) and\n")
+	fmt.Printf("  start one after another from the start of the run.\n")
+	fmt.Printf("  With --manifests, imports the checksum manifests that lfst-scenario --checksum-store\n")
+	fmt.Printf("  manifest wrote to WORK_DIR/run-ID/%s, one snapshot per step. They belong to the run\n", checksum.ManifestDirName)
+	fmt.Printf("  recorded in them unless --run-id names another, such as the run's ID after lfst-import --run.\n")
+	fmt.Printf("  With --listen, runs until interrupted as an HTTPS endpoint to which lfst-checksum sends\n")
+	fmt.Printf("  checksums when 'import_url' is set, so clients need no passwordless SSH to this host.\n")
+	fmt.Printf("  Clients present 'import_token' as a bearer token; %s on ADDR takes checksums\n", checksum.ImportPath)
+	fmt.Printf("  in either format, POSTed one import at a time. A self-signed certificate will do, if\n")
+	fmt.Printf("  clients trust it with 'import_ca_cert'.\n\n")
+
+	fmt.Printf("USAGE:\n")
+	fmt.Printf("  lfst-import [OPTIONS] [JSON_FILE]\n")
+	fmt.Printf("  lfst-import --stdin < checksums.json\n")
+	fmt.Printf("  cat checksums.json | lfst-import\n")
+	fmt.Printf("  lfst-import --run [RUN_JSONL_FILE]\n")
+	fmt.Printf("  lfst-import --legacy --scenario ID --server TYPE --protocol PROTOCOL DIR\n")
+	fmt.Printf("  lfst-import --manifests [--run-id ID] DIR\n")
+	fmt.Printf("  lfst-import --listen ADDR --tls-cert FILE --tls-key FILE\n\n")
+
+	fmt.Printf("OPTIONS:\n")
+	fs.PrintDefaults()
+
+	fmt.Printf("\nEXAMPLES:\n")
+	fmt.Printf("  # Import from file\n")
+	fmt.Printf("  lfst-import checksums.json\n\n")
+
+	fmt.Printf("  # Import from stdin\n")
+	fmt.Printf("  cat checksums.json | lfst-import\n\n")
+
+	fmt.Printf("  # Import via SSH (typical remote usage)\n")
+	fmt.Printf("  cat checksums.json | ssh gojira lfst-import --stdin\n\n")
+
+	fmt.Printf("  # Import run 5 exported on the laptop\n")
+	fmt.Printf("  ssh laptop lfst-query export --run-id 5 | lfst-import --run\n\n")
+
+	fmt.Printf("  # Check what the bash scripts' results of scenario 6 contain, then import them\n")
+	fmt.Printf("  lfst-import --legacy --dry-run --scenario 6 --server lfs-test-server --protocol http results/6\n")
+	fmt.Printf("  lfst-import --legacy --scenario 6 --server lfs-test-server --protocol http --started 2023-05-01 results/6\n\n")
+
+	fmt.Printf("  # Move run 3 of an offline laptop, imported as run 41, with its checksum manifests\n")
+	fmt.Printf("  ssh laptop lfst-query export --run-id 3 | lfst-import --run\n")
+	fmt.Printf("  scp -r laptop:/tmp/lfst/run-3/%s manifests-3\n", checksum.ManifestDirName)
+	fmt.Printf("  lfst-import --manifests --run-id 41 manifests-3\n\n")
+
+	fmt.Printf("  # Accept checksums over HTTPS on port 9443 with a self-signed certificate\n")
+	fmt.Printf("  openssl req -x509 -newkey rsa:2048 -nodes -days 825 -subj /CN=gojira \\\n")
+	fmt.Printf("    -addext subjectAltName=DNS:gojira -keyout import.key -out import.pem\n")
+	fmt.Printf("  lfst-config set import_token \"$(openssl rand -hex 32)\"\n")
+	fmt.Printf("  lfst-import --listen :9443 --tls-cert import.pem --tls-key import.key\n\n")
+
+	fmt.Printf("  # Custom database location\n")
+	fmt.Printf("  lfst-import --db /custom/path/test.db checksums.json\n\n")
+
+	fmt.Printf("CONFIGURATION:\n")
+	fmt.Printf("  Database path can be set via:\n")
+	fmt.Printf("  1. --db flag (highest priority)\n")
+	fmt.Printf("  2. LFS_TEST_DB environment variable\n")
+	fmt.Printf("  3. ~/.lfs-test-config file\n")
+	fmt.Printf("  4. Default: /home/mslinn/lfs_eval/lfs-test.db\n\n")
+}
@@ -0,0 +1,2295 @@
+// Package querycmd is lfst-query, which the lfst command also runs as lfst query
+package querycmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/completion"
+	"github.com/mslinn/git-lfs-test/pkg/config"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/githost"
+	"github.com/mslinn/git-lfs-test/pkg/i18n"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/report"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/spf13/pflag"
+)
+
+var version string // Set by Command
+
+// commands are the subcommands of lfst-query. Each defines its flags on the flag set it is
+// given and returns the function that runs it, so the flags can be listed without running it.
+var commands = map[string]func(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool){
+	"checksums":     checksumsCommand,
+	"compare":       compareCommand,
+	"stats":         statsCommand,
+	"operations":    operationsCommand,
+	"top":           topCommand,
+	"scoreboard":    scoreboardCommand,
+	"chart":         chartCommand,
+	"logs":          logsCommand,
+	"snapshots":     snapshotsCommand,
+	"report":        reportCommand,
+	"compare-runs":  compareRunsCommand,
+	"critical-path": criticalPathCommand,
+	"timeline":      timelineCommand,
+	"benchmarks":    benchmarksCommand,
+	"sizes":         sizesCommand,
+	"resources":     resourcesCommand,
+	"locks":         locksCommand,
+	"batch":         batchCommand,
+	"reverify":      reverifyCommand,
+	"export":        exportCommand,
+	"badge":         badgeCommand,
+	"dedupe":        dedupeCommand,
+}
+
+// Commands returns the flags of each subcommand of lfst-query, for completion
+func Commands() map[string]*pflag.FlagSet {
+	return completion.FlagSets(commands)
+}
+
+// Main runs lfst-query with args, the command line after the program name
+func Main(v string, args []string) {
+	run := Command(v, pflag.CommandLine)
+	pflag.CommandLine.Parse(args)
+	run(pflag.Args())
+}
+
+// Command defines the flags of lfst-query on fs and returns the function that runs it
+// with the arguments left once fs is parsed; lfst query registers them on its subcommand
+func Command(v string, fs *pflag.FlagSet) func(args []string) {
+	version = v
+
+	// Define global flags
+	var (
+		showVersion bool
+		showHelp    bool
+		quiet       bool
+		noColor     bool
+		debug       bool
+		dbPath      string
+		attach      []string
+	)
+
+	fs.BoolVarP(&showVersion, "version", "V", false, "Show version and exit")
+	fs.BoolVarP(&showHelp, "help", "h", false, "Show this help message")
+	fs.BoolVarP(&quiet, "quiet", "q", false, "Print errors only")
+	fs.BoolVar(&noColor, "no-color", false, "Plain output without colors (automatic when not a terminal)")
+	fs.BoolVarP(&debug, "debug", "d", false, "Enable debug output")
+	fs.BoolVarP(&debug, "verbose", "v", false, "Enable verbose output (alias for --debug)")
+	var logFile, logFormat string
+	fs.StringVar(&logFile, "log-file", "", "Also append every message, debug included, to this file")
+	fs.StringVar(&logFormat, "log-format", "", "Format of the log file: text or json (default text)")
+	fs.StringVar(&dbPath, "db", "", "Path to SQLite database (default from config)")
+	fs.StringArrayVar(&attach, "attach", nil, "Also query this database, e.g. another machine's (repeatable)")
+	var lang string
+	fs.StringVar(&lang, "lang", "", "Language of reports and badges: en or de (default from $LFST_LANG, config or locale)")
+
+	// Stop parsing at first non-flag argument (the subcommand)
+	fs.SetInterspersed(false)
+
+	return func(args []string) {
+		// Handle version
+		if showVersion {
+			fmt.Printf("lfst-query version %s\n", version)
+			os.Exit(0)
+		}
+
+		// Get subcommand
+		if len(args) == 0 || showHelp {
+			printHelp()
+			os.Exit(0)
+		}
+
+		if err := term.Setup(quiet, noColor); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := log.Setup(debug, logFile, logFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		subcommand := args[0]
+
+		// Load configuration
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := i18n.Setup(lang, cfg.Language); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Use config database if not overridden
+		if dbPath == "" {
+			if dbPath, err = cfg.GetDatabasePath(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Validate database (creates directory if needed)
+		if err := cfg.ValidateDatabase(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error validating database: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Open database, or the federation of it and the attached ones
+		var db *database.DB
+		if len(attach) > 0 {
+			locations := append([]string{dbPath}, attach...)
+			db, err = database.Federate(locations)
+			for i, location := range locations {
+				log.Debugf("Database %s: IDs offset by %d\n", database.Redact(location), int64(i)*database.FederationOffset)
+			}
+		} else {
+			db, err = database.Open(dbPath)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		// Execute subcommand
+		command, ok := commands[subcommand]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown subcommand '%s'\n\n", subcommand)
+			printUsage()
+			os.Exit(1)
+		}
+		if subcommand == "dedupe" && len(attach) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: dedupe changes the database; attached databases are copies\n")
+			os.Exit(1)
+		}
+		command(pflag.NewFlagSet(subcommand, pflag.ExitOnError))(db, args[1:], debug)
+	}
+}
+
+func checksumsCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (required)")
+	stepNumber := fs.Int("step", 0, "Step number (required unless --label)")
+	label := fs.String("label", "", "Snapshot label (instead of --step)")
+	limit := fs.Int("limit", 50, "Maximum number of checksums to display (default all with --format csv)")
+	format := fs.String("format", "table", "Output format: table, or csv for spreadsheets")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *runID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --run-id is required\n")
+			os.Exit(1)
+		}
+		checkFormat(*format)
+		if *stepNumber == 0 && *label == "" {
+			fmt.Fprintf(os.Stderr, "Error: --step or --label is required\n")
+			os.Exit(1)
+		}
+
+		ref := *label
+		name := fmt.Sprintf("snapshot '%s'", *label)
+		if ref == "" {
+			ref = fmt.Sprintf("%d", *stepNumber)
+			name = fmt.Sprintf("step %d", *stepNumber)
+		}
+
+		checksums, err := checksum.ResolveSnapshot(db, *runID, ref)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting checksums: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(checksums) == 0 && *format != "csv" {
+			fmt.Printf("No checksums found for run %d, %s\n", *runID, name)
+			return
+		}
+
+		// Apply limit
+		if len(checksums) > *limit && (*format != "csv" || fs.Changed("limit")) {
+			checksums = checksums[:*limit]
+		}
+
+		if *format == "csv" {
+			records := [][]string{{"crc32", "size_bytes", "path"}}
+			for _, cs := range checksums {
+				records = append(records, []string{cs.CRC32, strconv.FormatInt(cs.SizeBytes, 10), cs.FilePath})
+			}
+			writeCSV(records)
+			return
+		}
+
+		fmt.Printf("Checksums for run %d, %s:\n\n", *runID, name)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CRC32\tSize\tPath")
+		fmt.Fprintln(w, "-----\t----\t----")
+
+		for _, cs := range checksums {
+			fmt.Fprintf(w, "%s\t%s\t%s\n",
+				cs.CRC32,
+				checksum.FormatSize(cs.SizeBytes),
+				cs.FilePath,
+			)
+		}
+		w.Flush()
+
+		log.Debugf("\nTotal checksums: %d\n", len(checksums))
+	}
+}
+
+func compareCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (required)")
+	fromRef := fs.String("from", "", "Source step number or snapshot label (required)")
+	toRef := fs.String("to", "", "Target step number or snapshot label (required)")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *runID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --run-id is required\n")
+			os.Exit(1)
+		}
+		if *fromRef == "" {
+			fmt.Fprintf(os.Stderr, "Error: --from is required\n")
+			os.Exit(1)
+		}
+		if *toRef == "" {
+			fmt.Fprintf(os.Stderr, "Error: --to is required\n")
+			os.Exit(1)
+		}
+
+		diffs, err := checksum.CompareSnapshots(db, *runID, *fromRef, *toRef)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error comparing checksums: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(diffs) == 0 {
+			fmt.Printf("No differences between %s and %s\n", *fromRef, *toRef)
+			return
+		}
+
+		fmt.Printf("Changes from %s to %s:\n\n", *fromRef, *toRef)
+		printDifferences(diffs, debug)
+		fmt.Printf("\nTotal differences: %d\n", len(diffs))
+	}
+}
+
+// printDifferences prints one line per difference, with the CRCs in debug mode
+func printDifferences(diffs []*checksum.Difference, debug bool) {
+	for _, diff := range diffs {
+		switch diff.ChangeType {
+		case "added":
+			fmt.Printf("  ADDED:    %s (%s)\n",
+				diff.FilePath, checksum.FormatSize(diff.NewSize))
+		case "deleted":
+			fmt.Printf("  DELETED:  %s (was %s)\n",
+				diff.FilePath, checksum.FormatSize(diff.OldSize))
+		case "renamed":
+			fmt.Printf("  RENAMED:  %s → %s (%s)\n",
+				diff.OldPath, diff.FilePath, checksum.FormatSize(diff.NewSize))
+		case "modified":
+			fmt.Printf("  MODIFIED: %s (%s)\n",
+				diff.FilePath, checksum.FormatSize(diff.NewSize))
+			log.Debugf("            CRC: %s -> %s\n", diff.OldCRC32, diff.NewCRC32)
+		case "size-changed":
+			fmt.Printf("  SIZE:     %s (%s -> %s)\n",
+				diff.FilePath,
+				checksum.FormatSize(diff.OldSize),
+				checksum.FormatSize(diff.NewSize))
+			log.Debugf("            CRC: %s -> %s\n", diff.OldCRC32, diff.NewCRC32)
+		}
+	}
+}
+
+func reverifyCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (required)")
+	fromRef := fs.String("from", "", "Source step number or snapshot label (required)")
+	toRef := fs.String("to", "", "Target step number or snapshot label (required)")
+	include := fs.StringArray("include", nil, "Only compare files matching this glob (repeatable)")
+	exclude := fs.StringArray("exclude", nil, "Skip files matching this glob (repeatable)")
+	noRenames := fs.Bool("no-renames", false, "Report renames as a deletion and an addition")
+	sizeOnly := fs.Bool("size-only", false, "Ignore CRC32 differences of files whose size is unchanged")
+	expectChanges := fs.Bool("expect-changes", false, "Pass only if the files differ, e.g. across step 3's modifications")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *runID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --run-id is required\n")
+			os.Exit(1)
+		}
+		if *fromRef == "" || *toRef == "" {
+			fmt.Fprintf(os.Stderr, "Error: --from and --to are required\n")
+			os.Exit(1)
+		}
+
+		opts := checksum.DiffOptions{
+			Filter:    checksum.Filter{Include: *include, Exclude: *exclude},
+			NoRenames: *noRenames,
+			SizeOnly:  *sizeOnly,
+		}
+		if err := opts.Filter.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Only stored checksums are used; the run's repositories may be long gone
+		oldChecksums, err := checksum.ResolveSnapshot(db, *runID, *fromRef)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting checksums for %s: %v\n", *fromRef, err)
+			os.Exit(1)
+		}
+		newChecksums, err := checksum.ResolveSnapshot(db, *runID, *toRef)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting checksums for %s: %v\n", *toRef, err)
+			os.Exit(1)
+		}
+		if len(oldChecksums) == 0 && len(newChecksums) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: run %d has no checksums for %s or %s\n", *runID, *fromRef, *toRef)
+			os.Exit(1)
+		}
+
+		diffs := checksum.DiffChecksumsWith(oldChecksums, newChecksums, opts)
+
+		fmt.Printf("Re-verifying run %d from %s to %s:\n", *runID, *fromRef, *toRef)
+		fmt.Printf("  Files compared: %d of %d at %s, %d of %d at %s\n\n",
+			len(opts.Filter.Apply(oldChecksums)), len(oldChecksums), *fromRef,
+			len(opts.Filter.Apply(newChecksums)), len(newChecksums), *toRef)
+
+		if len(diffs) > 0 {
+			printDifferences(diffs, debug)
+			fmt.Println()
+		}
+
+		passed := len(diffs) == 0
+		if *expectChanges {
+			passed = !passed
+		}
+		if passed {
+			fmt.Printf("%s Re-verification passed (%d differences)\n", term.OK(), len(diffs))
+			return
+		}
+		fmt.Printf("%s Re-verification failed (%d differences)\n", term.Fail(), len(diffs))
+		os.Exit(1)
+	}
+}
+
+func statsCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (0 = all runs)")
+	format := fs.String("format", "table", "Output format: table, or csv for spreadsheets")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		checkFormat(*format)
+		if *format == "csv" {
+			if *runID > 0 {
+				writeRunStatsCSV(db, *runID)
+			} else {
+				writeStatsCSV(db)
+			}
+			return
+		}
+
+		if *runID > 0 {
+			// Stats for specific run
+			run, err := db.GetTestRun(*runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: test run %d not found: %v\n", *runID, err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Test Run %d Statistics:\n\n", *runID)
+			fmt.Printf("  Scenario:     %d\n", run.ScenarioID)
+			fmt.Printf("  Server:       %s\n", run.ServerType)
+			fmt.Printf("  Protocol:     %s\n", run.Protocol)
+			fmt.Printf("  Status:       %s\n", run.Status)
+
+			// Count checksums per step
+			rows, err := db.QueryRaw("SELECT step_number, COUNT(*) FROM checksums WHERE run_id = ? GROUP BY step_number ORDER BY step_number", *runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying checksums: %v\n", err)
+				os.Exit(1)
+			}
+			defer rows.Close()
+
+			fmt.Printf("\n  Checksums per step:\n")
+			for rows.Next() {
+				var step, count int
+				if err := rows.Scan(&step, &count); err != nil {
+					fmt.Fprintf(os.Stderr, "Error scanning row: %v\n", err)
+					continue
+				}
+				fmt.Printf("    Step %d: %d checksums\n", step, count)
+			}
+
+			// Count operations per step
+			rows2, err := db.QueryRaw("SELECT step_number, COUNT(*), AVG(duration_ms) FROM operations WHERE run_id = ? GROUP BY step_number ORDER BY step_number", *runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying operations: %v\n", err)
+				os.Exit(1)
+			}
+			defer rows2.Close()
+
+			fmt.Printf("\n  Operations per step:\n")
+			for rows2.Next() {
+				var step, count int
+				var avgDuration float64
+				if err := rows2.Scan(&step, &count, &avgDuration); err != nil {
+					fmt.Fprintf(os.Stderr, "Error scanning row: %v\n", err)
+					continue
+				}
+				fmt.Printf("    Step %d: %d operations (avg %.1fms)\n", step, count, avgDuration)
+			}
+
+			// Hosting service API calls, kept apart from the git and LFS data transfers
+			ops, err := db.ListOperations(*runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying operations: %v\n", err)
+				os.Exit(1)
+			}
+			printHostAPITimes(ops)
+
+			// Verification outcomes, listing the failures
+			verifications, err := db.ListVerifications(*runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying verifications: %v\n", err)
+				os.Exit(1)
+			}
+			if len(verifications) > 0 {
+				passed := 0
+				for _, v := range verifications {
+					if v.Status == "passed" {
+						passed++
+					}
+				}
+				fmt.Printf("\n  Verifications: %d passed, %d failed\n", passed, len(verifications)-passed)
+				for _, v := range verifications {
+					if v.Status != "passed" {
+						fmt.Printf("    Step %d %s (%s): %s\n", v.StepNumber, v.Name, v.Severity, v.Message)
+					}
+				}
+			}
+
+			// Restarts, stops and out-of-memory kills of the server, recorded with --server-service
+			events, err := db.ListServerEvents(*runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying server events: %v\n", err)
+				os.Exit(1)
+			}
+			if len(events) > 0 {
+				fmt.Printf("\n  Server events:\n")
+				for _, e := range events {
+					fmt.Printf("    Step %d %s %s: %s\n", e.StepNumber, e.OccurredAt.Local().Format("15:04:05"), e.Kind, e.Message)
+				}
+			}
+
+			// Objects checked directly in the server's storage backend, listing the discrepancies
+			storageChecks, err := db.ListStorageVerifications(*runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying storage verifications: %v\n", err)
+				os.Exit(1)
+			}
+			if len(storageChecks) > 0 {
+				ok := 0
+				for _, v := range storageChecks {
+					if v.Status == "ok" {
+						ok++
+					}
+				}
+				fmt.Printf("\n  Storage objects (%s): %d ok, %d missing or damaged\n",
+					storageChecks[0].Backend, ok, len(storageChecks)-ok)
+				for _, v := range storageChecks {
+					if v.Status != "ok" {
+						fmt.Printf("    Step %d %s %s: %s\n", v.StepNumber, v.Status, v.ObjectKey, v.Message)
+					}
+				}
+			}
+
+			// LFS objects counted on the server after each push, and whether identical content is stored once
+			serverObjects, err := db.ListServerObjects(*runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying server objects: %v\n", err)
+				os.Exit(1)
+			}
+			if len(serverObjects) > 0 {
+				fmt.Printf("\n  Server objects (%s, %s):\n", serverObjects[0].Location, serverObjects[0].Method)
+				for _, o := range serverObjects {
+					fmt.Printf("    Step %d %s: %s\n", o.StepNumber, o.Status, o.Message)
+				}
+			}
+
+			// Contention and throughput of the concurrent multi-client step
+			concurrency, err := db.ListConcurrencyResults(*runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying concurrency results: %v\n", err)
+				os.Exit(1)
+			}
+			if len(concurrency) > 0 {
+				clients, err := db.ListClientResults(*runID)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error querying client results: %v\n", err)
+					os.Exit(1)
+				}
+				for _, c := range concurrency {
+					fmt.Printf("\n  Concurrent clients (step %d): %d of %d completed, %.1f MB in %.1fs (%.2f MB/s)\n",
+						c.StepNumber, c.Clients-c.FailedClients, c.Clients, float64(c.TotalBytes)/1024/1024,
+						float64(c.WallMs)/1000, c.ThroughputMBps())
+					fmt.Printf("    %d push attempts, %d rejected pushes, %d lock errors\n", c.PushAttempts, c.RejectedPushes, c.LockErrors)
+					for _, cr := range clients {
+						if cr.StepNumber != c.StepNumber {
+							continue
+						}
+						fmt.Printf("    Client %d: %s in %dms, %d attempt(s), %d rejected, %d lock errors",
+							cr.Client, cr.Status, cr.DurationMs, cr.PushAttempts, cr.RejectedPushes, cr.LockErrors)
+						if cr.Error != "" {
+							fmt.Printf(" - %s", cr.Error)
+						}
+						fmt.Println()
+					}
+				}
+			}
+
+			// Upload of the very large file, interrupted and pushed again
+			largeFiles, err := db.ListLargeFileResults(*runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying large file results: %v\n", err)
+				os.Exit(1)
+			}
+			for _, l := range largeFiles {
+				fmt.Printf("\n  Large file (step %d): %s %s, %s", l.StepNumber, l.Path, checksum.FormatSize(l.SizeBytes), l.Status)
+				if l.Adapter != "" {
+					fmt.Printf(" with the %s adapter", l.Adapter)
+				}
+				fmt.Println()
+				if l.Message != "" {
+					fmt.Printf("    %s\n", l.Message)
+					continue
+				}
+				if !l.Interrupted {
+					fmt.Printf("    First push finished in %dms, before the interruption\n", l.FirstMs)
+				} else {
+					fmt.Printf("    First push interrupted after %dms, second push took %dms\n", l.FirstMs, l.ResumeMs)
+				}
+				if l.Resumed != nil {
+					outcome := "started over"
+					if *l.Resumed {
+						outcome = "resumed"
+					}
+					fmt.Printf("    Second push sent %s: the upload %s\n", checksum.FormatSize(*l.ResumeSentBytes), outcome)
+				}
+			}
+
+			// Small commits pushed one at a time
+			cadences, err := db.ListCadenceResults(*runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying cadence results: %v\n", err)
+				os.Exit(1)
+			}
+			for _, c := range cadences {
+				fmt.Printf("\n  Commit cadence (step %d): %d commits of %s %s, %s in %.1fs\n", c.StepNumber, c.Commits,
+					c.Path, checksum.FormatSize(c.FileSize), c.Status, float64(c.TotalMs)/1000)
+				if c.Message != "" {
+					fmt.Printf("    %s\n", c.Message)
+					continue
+				}
+				fmt.Printf("    Per commit: %dms mean, %dms median, %dms max; push %dms mean\n",
+					c.MeanCommitMs, c.MedianCommitMs, c.MaxCommitMs, c.MeanPushMs)
+				if c.SentBytes != nil {
+					fmt.Printf("    Sent %s per commit\n", checksum.FormatSize(c.BytesPerCommit()))
+				}
+				if c.BatchRequests != nil {
+					fmt.Printf("    %d upload batch requests, %.1f per commit\n", *c.BatchRequests, c.RequestsPerCommit())
+				}
+			}
+
+			// Client-side network traffic per step
+			results, err := db.ListStepResults(*runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying step results: %v\n", err)
+				os.Exit(1)
+			}
+
+			printedHeader := false
+			for _, sr := range results {
+				if sr.RxBytes == nil || sr.TxBytes == nil {
+					continue
+				}
+				if !printedHeader {
+					fmt.Printf("\n  Network traffic per step:\n")
+					printedHeader = true
+				}
+				fmt.Printf("    Step %d: %.1f MB received, %.1f MB sent (%s)\n", sr.StepNumber,
+					float64(*sr.RxBytes)/1024/1024, float64(*sr.TxBytes)/1024/1024, sr.Interface)
+			}
+
+		} else {
+			// Overall stats
+			fmt.Printf("Overall Statistics:\n\n")
+
+			// Count test runs by status
+			rows, err := db.QueryRaw("SELECT status, COUNT(*) FROM test_runs GROUP BY status")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying test runs: %v\n", err)
+				os.Exit(1)
+			}
+			defer rows.Close()
+
+			fmt.Printf("  Test runs by status:\n")
+			for rows.Next() {
+				var status string
+				var count int
+				if err := rows.Scan(&status, &count); err != nil {
+					fmt.Fprintf(os.Stderr, "Error scanning row: %v\n", err)
+					continue
+				}
+				fmt.Printf("    %s: %d\n", status, count)
+			}
+
+			// Count test runs by server type
+			rows2, err := db.QueryRaw("SELECT server_type, COUNT(*) FROM test_runs GROUP BY server_type")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying test runs: %v\n", err)
+				os.Exit(1)
+			}
+			defer rows2.Close()
+
+			fmt.Printf("\n  Test runs by server:\n")
+			for rows2.Next() {
+				var serverType string
+				var count int
+				if err := rows2.Scan(&serverType, &count); err != nil {
+					fmt.Fprintf(os.Stderr, "Error scanning row: %v\n", err)
+					continue
+				}
+				fmt.Printf("    %s: %d\n", serverType, count)
+			}
+
+			// Total checksums
+			var totalChecksums int
+			row := db.QueryRowRaw("SELECT COUNT(*) FROM checksums")
+			if err := row.Scan(&totalChecksums); err != nil {
+				fmt.Fprintf(os.Stderr, "Error counting checksums: %v\n", err)
+			} else {
+				fmt.Printf("\n  Total checksums: %d\n", totalChecksums)
+			}
+
+			// Total operations
+			var totalOps int
+			row2 := db.QueryRowRaw("SELECT COUNT(*) FROM operations")
+			if err := row2.Scan(&totalOps); err != nil {
+				fmt.Fprintf(os.Stderr, "Error counting operations: %v\n", err)
+			} else {
+				fmt.Printf("  Total operations: %d\n", totalOps)
+			}
+		}
+	}
+}
+
+// printHostAPITimes summarizes the hosting service API calls among ops by operation type,
+// next to the time of every other operation
+func printHostAPITimes(ops []*database.Operation) {
+	type apiTime struct {
+		count int
+		ms    int64
+	}
+	byType := make(map[string]*apiTime)
+	var apiMs, dataMs int64
+	for _, op := range ops {
+		if !githost.IsControlPlane(op.Operation) {
+			dataMs += op.DurationMs
+			continue
+		}
+		if byType[op.Operation] == nil {
+			byType[op.Operation] = &apiTime{}
+		}
+		byType[op.Operation].count++
+		byType[op.Operation].ms += op.DurationMs
+		apiMs += op.DurationMs
+	}
+	if len(byType) == 0 {
+		return
+	}
+
+	opTypes := make([]string, 0, len(byType))
+	for opType := range byType {
+		opTypes = append(opTypes, opType)
+	}
+	sort.Strings(opTypes)
+
+	fmt.Printf("\n  Hosting service API: %dms (git and LFS operations: %dms)\n", apiMs, dataMs)
+	for _, opType := range opTypes {
+		t := byType[opType]
+		fmt.Printf("    %s: %d call(s), %dms (avg %.1fms)\n", opType, t.count, t.ms, float64(t.ms)/float64(t.count))
+	}
+}
+
+func operationsCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (required)")
+	stepNumber := fs.Int("step", 0, "Step number (0 = all steps)")
+	limit := fs.Int("limit", 20, "Maximum number of operations to display (default all with --format csv)")
+	format := fs.String("format", "table", "Output format: table, or csv for spreadsheets")
+	resources := fs.Bool("resources", false, "Show the CPU, memory and disk I/O sampled with lfst-scenario --sample-resources")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *runID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --run-id is required\n")
+			os.Exit(1)
+		}
+		checkFormat(*format)
+		if *format == "csv" && !fs.Changed("limit") {
+			*limit = math.MaxInt
+		}
+
+		ops, err := db.ListOperations(*runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error querying operations: %v\n", err)
+			os.Exit(1)
+		}
+
+		snapshotNames := make(map[int64]string)
+		snapshots, err := db.ListSnapshots(*runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing snapshots: %v\n", err)
+			os.Exit(1)
+		}
+		for _, snap := range snapshots {
+			snapshotNames[snap.ID] = describeSnapshot(snap)
+		}
+
+		// Operations recorded without their output have no command
+		commands, err := db.ListOperationCommands(*runID, *stepNumber)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing commands: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *format == "csv" {
+			records := [][]string{{"step", "operation", "command", "started_at", "duration_ms", "file_count", "total_bytes",
+				"mb_per_s", "status", "preceded_by", "verified_by", "error", "error_kind", "server_event",
+				"cpu_ms", "peak_cpu_pct", "peak_rss_bytes", "avg_rss_bytes", "read_bytes", "write_bytes", "transfer_adapter"}}
+			for _, op := range ops {
+				if *stepNumber > 0 && op.StepNumber != *stepNumber {
+					continue
+				}
+				if len(records) > *limit {
+					break
+				}
+				var files, size, rate, before, after string
+				if op.FileCount != nil {
+					files = strconv.Itoa(*op.FileCount)
+				}
+				if op.TotalBytes != nil {
+					size = strconv.FormatInt(*op.TotalBytes, 10)
+					if op.DurationMs > 0 {
+						rate = fmt.Sprintf("%.2f", float64(*op.TotalBytes)/1024/1024/(float64(op.DurationMs)/1000))
+					}
+				}
+				if op.SnapshotBeforeID != nil {
+					before = snapshotNames[*op.SnapshotBeforeID]
+				}
+				if op.SnapshotAfterID != nil {
+					after = snapshotNames[*op.SnapshotAfterID]
+				}
+				usage := make([]string, 6)
+				if r := op.Resources; r != nil {
+					usage = []string{strconv.FormatInt(r.CPUMs, 10), fmt.Sprintf("%.1f", r.PeakCPUPercent),
+						strconv.FormatInt(r.PeakRSSBytes, 10), strconv.FormatInt(r.AvgRSSBytes, 10),
+						strconv.FormatInt(r.ReadBytes, 10), strconv.FormatInt(r.WriteBytes, 10)}
+				}
+				records = append(records, append([]string{strconv.Itoa(op.StepNumber), op.Operation, commands[op.ID], op.StartedAt.Format(time.RFC3339),
+					strconv.FormatInt(op.DurationMs, 10), files, size, rate, op.Status, before, after, op.Error, op.ErrorKind, op.ServerEvent},
+					append(usage, op.TransferAdapter)...))
+			}
+			writeCSV(records)
+			return
+		}
+
+		fmt.Printf("Operations for run %d:\n\n", *runID)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if *resources {
+			fmt.Fprintln(w, "Step\tOperation\tDuration\tCPU\tPeak CPU\tPeak RSS\tAvg RSS\tRead\tWritten\tStatus")
+			fmt.Fprintln(w, "----\t---------\t--------\t---\t--------\t--------\t-------\t----\t-------\t------")
+		} else {
+			fmt.Fprintln(w, "Step\tOperation\tCommand\tDuration\tFiles\tSize\tMB/s\tStatus\tPreceded By\tVerified By")
+			fmt.Fprintln(w, "----\t---------\t-------\t--------\t-----\t----\t----\t------\t-----------\t-----------")
+		}
+
+		count := 0
+		for _, op := range ops {
+			if *stepNumber > 0 && op.StepNumber != *stepNumber {
+				continue
+			}
+			if count >= *limit {
+				break
+			}
+
+			before, after := "-", "-"
+			if op.SnapshotBeforeID != nil {
+				before = snapshotNames[*op.SnapshotBeforeID]
+			}
+			if op.SnapshotAfterID != nil {
+				after = snapshotNames[*op.SnapshotAfterID]
+			}
+
+			files, size, rate := "-", "-", "-"
+			if op.FileCount != nil {
+				files = fmt.Sprintf("%d", *op.FileCount)
+			}
+			if op.TotalBytes != nil {
+				size = fmt.Sprintf("%.1f MB", float64(*op.TotalBytes)/1024/1024)
+				if op.DurationMs > 0 {
+					rate = fmt.Sprintf("%.2f", float64(*op.TotalBytes)/1024/1024/(float64(op.DurationMs)/1000))
+				}
+			}
+
+			status := op.Status
+			if op.ErrorKind != "" {
+				status += " (" + op.ErrorKind + ")"
+			}
+			operation := op.Operation
+			if op.TransferAdapter != "" {
+				operation += " (" + op.TransferAdapter + ")"
+			}
+			if *resources {
+				cpu, peakCPU, peakRSS, avgRSS, read, written := "-", "-", "-", "-", "-", "-"
+				if r := op.Resources; r != nil {
+					cpu = fmt.Sprintf("%dms", r.CPUMs)
+					peakCPU = fmt.Sprintf("%.0f%%", r.PeakCPUPercent)
+					peakRSS, avgRSS = formatMB(r.PeakRSSBytes), formatMB(r.AvgRSSBytes)
+					read, written = formatMB(r.ReadBytes), formatMB(r.WriteBytes)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%dms\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					op.StepNumber, operation, op.DurationMs, cpu, peakCPU, peakRSS, avgRSS, read, written, status)
+			} else {
+				// Truncate long commands
+				command := commands[op.ID]
+				if command == "" {
+					command = "-"
+				} else if len(command) > 50 {
+					command = command[:47] + "..."
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%dms\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					op.StepNumber, operation, command, op.DurationMs, files, size, rate, status, before, after)
+			}
+			if debug && op.Error != "" {
+				fmt.Fprintf(w, "\t  error: %s\t\t\t\t\t\t\t\n", op.Error)
+			}
+			if op.ServerEvent != "" {
+				fmt.Fprintf(w, "\t  server: %s\t\t\t\t\t\t\t\n", op.ServerEvent)
+			}
+			count++
+		}
+		w.Flush()
+
+		log.Debugf("\nShowing %d operations\n", count)
+	}
+}
+
+func topCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (0 = all runs)")
+	by := fs.String("by", database.TopByDuration, "Rank by duration, or by bytes transferred")
+	limit := fs.Int("limit", 20, "Maximum number of operations to display")
+	operation := fs.String("operation", "", "Only this operation type, e.g. push")
+	format := fs.String("format", "table", "Output format: table, or csv for spreadsheets")
+	tagArgs := fs.StringArray("tag", nil, "Only operations tagged KEY=VALUE, themselves or through their run (repeatable)")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		tags := parseTags(*tagArgs)
+		if !slices.Contains(database.TopOrders, *by) {
+			fmt.Fprintf(os.Stderr, "Error: unsupported order '%s' (supported: %s)\n", *by, strings.Join(database.TopOrders, ", "))
+			os.Exit(1)
+		}
+		if *limit < 1 {
+			fmt.Fprintf(os.Stderr, "Error: --limit must be at least 1\n")
+			os.Exit(1)
+		}
+		checkFormat(*format)
+
+		ops, err := db.TopOperations(database.TopOptions{RunID: *runID, Operation: *operation, By: *by, Limit: *limit, Tags: tags})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error querying operations: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *format == "csv" {
+			records := [][]string{{"run_id", "step", "step_name", "operation", "started_at", "duration_ms", "file_count",
+				"total_bytes", "mb_per_s", "server_type", "protocol", "git_server", "transfer_adapter", "status", "error"}}
+			for _, op := range ops {
+				var files, size, rate string
+				if op.FileCount != nil {
+					files = strconv.Itoa(*op.FileCount)
+				}
+				if op.TotalBytes != nil {
+					size = strconv.FormatInt(*op.TotalBytes, 10)
+					if op.DurationMs > 0 {
+						rate = fmt.Sprintf("%.2f", float64(*op.TotalBytes)/1024/1024/(float64(op.DurationMs)/1000))
+					}
+				}
+				records = append(records, []string{strconv.FormatInt(op.RunID, 10), strconv.Itoa(op.StepNumber), op.StepName,
+					op.Operation.Operation, op.StartedAt.Format(time.RFC3339), strconv.FormatInt(op.DurationMs, 10), files, size, rate,
+					op.ServerType, op.Protocol, op.GitServer, op.TransferAdapter, op.Status, op.Error})
+			}
+			writeCSV(records)
+			return
+		}
+
+		if len(ops) == 0 {
+			fmt.Println("No operations found")
+			return
+		}
+
+		scope := "all runs"
+		if *runID != 0 {
+			scope = fmt.Sprintf("run %d", *runID)
+		}
+		if *by == database.TopByBytes {
+			fmt.Printf("Operations of %s that transferred the most bytes:\n\n", scope)
+		} else {
+			fmt.Printf("Slowest operations of %s:\n\n", scope)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Run\tStep\tOperation\tDuration\tSize\tMB/s\tServer\tProtocol\tGit Server\tStatus")
+		fmt.Fprintln(w, "---\t----\t---------\t--------\t----\t----\t------\t--------\t----------\t------")
+		for _, op := range ops {
+			step := strconv.Itoa(op.StepNumber)
+			if op.StepName != "" {
+				step += " (" + op.StepName + ")"
+			}
+			operation := op.Operation.Operation
+			if op.TransferAdapter != "" {
+				operation += " (" + op.TransferAdapter + ")"
+			}
+			size, rate := "-", "-"
+			if op.TotalBytes != nil {
+				size = formatMB(*op.TotalBytes)
+				if op.DurationMs > 0 {
+					rate = fmt.Sprintf("%.2f", float64(*op.TotalBytes)/1024/1024/(float64(op.DurationMs)/1000))
+				}
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%dms\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				op.RunID, step, operation, op.DurationMs, size, rate, op.ServerType, op.Protocol, op.GitServer, op.Status)
+			if debug && op.Error != "" {
+				fmt.Fprintf(w, "\t\t  error: %s\t\t\t\t\t\t\t\n", op.Error)
+			}
+		}
+		w.Flush()
+
+		log.Debugf("\nShowing %d operations\n", len(ops))
+	}
+}
+
+func chartCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Chart the steps of this test run (default: compare the servers)")
+	scenarioID := fs.Int("scenario", 0, "Compare the servers over the runs of this scenario only (0 = all scenarios)")
+	metric := fs.String("metric", report.MetricDuration, "Metric to chart: duration or bytes of a run's steps; duration, push, pull or failure-rate of servers")
+	output := fs.StringP("output", "o", "chart.png", "Image file; its extension, .png, .svg or .pdf, selects the format")
+	tagArgs := fs.StringArray("tag", nil, "Compare the servers over the runs tagged KEY=VALUE only (repeatable)")
+	fs.SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "out" {
+			name = "output"
+		}
+		return pflag.NormalizedName(name)
+	})
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		tags := parseTags(*tagArgs)
+		if *runID != 0 && len(tags) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: --tag selects the runs of a server chart, so it cannot be used with --run-id\n")
+			os.Exit(1)
+		}
+
+		var chart *report.Chart
+		var err error
+		if *runID != 0 {
+			chart, err = report.RunChart(db, *runID, *metric)
+		} else {
+			var scoreboard []*database.ScoreboardRow
+			scoreboard, err = db.Scoreboard(*scenarioID, tags)
+			if err == nil {
+				chart, err = report.ServerChart(scoreboard, *metric)
+			}
+		}
+		if err == nil {
+			err = chart.Save(*output)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s Chart of %d bars written to %s\n", term.OK(), len(chart.Values), *output)
+	}
+}
+
+func scoreboardCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	scenarioID := fs.Int("scenario", 0, "Only the runs of this scenario (0 = all scenarios)")
+	format := fs.String("format", "table", "Output format: table, csv for spreadsheets, or markdown with Mermaid charts")
+	tagArgs := fs.StringArray("tag", nil, "Only the runs tagged KEY=VALUE (repeatable)")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		tags := parseTags(*tagArgs)
+		markdown := *format == "markdown" || *format == "md"
+		if !markdown {
+			checkFormat(*format)
+		}
+
+		scoreboard, err := db.Scoreboard(*scenarioID, tags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if markdown {
+			if err := report.RenderMarkdownScoreboard(os.Stdout, scoreboard, *scenarioID); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		// Throughputs are blank, or "-" in a table, where no transfer recorded its bytes
+		throughput := func(mbps float64, none string) string {
+			if mbps == 0 {
+				return none
+			}
+			return fmt.Sprintf("%.2f", mbps)
+		}
+
+		if *format == "csv" {
+			records := [][]string{{"server_type", "protocol", "runs", "failed_runs", "failure_rate_pct", "avg_duration_ms",
+				"push_mb_per_s", "pull_mb_per_s", "failed_checks"}}
+			for _, row := range scoreboard {
+				records = append(records, []string{row.ServerType, row.Protocol, strconv.Itoa(row.Runs),
+					strconv.Itoa(row.FailedRuns), fmt.Sprintf("%.1f", row.FailureRate()*100), strconv.FormatInt(row.AvgDurationMs, 10),
+					throughput(row.PushMBps, ""), throughput(row.PullMBps, ""), strconv.Itoa(row.FailedChecks)})
+			}
+			writeCSV(records)
+			return
+		}
+
+		if len(scoreboard) == 0 {
+			fmt.Println("No finished runs found")
+			return
+		}
+
+		scope := "all finished runs"
+		if *scenarioID != 0 {
+			scope = fmt.Sprintf("the finished runs of scenario %d", *scenarioID)
+		}
+		if len(tags) > 0 {
+			scope += " tagged " + strings.Join(*tagArgs, ", ")
+		}
+		fmt.Printf("Servers compared over %s, most reliable first:\n\n", scope)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Server\tProtocol\tRuns\tFailed\tFailure Rate\tAvg Duration\tPush MB/s\tPull MB/s\tFailed Checks")
+		fmt.Fprintln(w, "------\t--------\t----\t------\t------------\t------------\t---------\t---------\t-------------")
+		for _, row := range scoreboard {
+			duration := "-"
+			if row.AvgDurationMs > 0 {
+				duration = fmt.Sprintf("%dms", row.AvgDurationMs)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%.0f%%\t%s\t%s\t%s\t%d\n",
+				row.ServerType, row.Protocol, row.Runs, row.FailedRuns, row.FailureRate()*100, duration,
+				throughput(row.PushMBps, "-"), throughput(row.PullMBps, "-"), row.FailedChecks)
+		}
+		w.Flush()
+
+		fmt.Printf("\nAvg Duration is the wall-clock time of the completed runs; Pull MB/s includes clones.\n")
+	}
+}
+
+// parseTags parses the KEY=VALUE values of --tag options, exiting on an invalid one
+func parseTags(values []string) []database.Tag {
+	var tags []database.Tag
+	for _, value := range values {
+		tag, err := database.ParseTag(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func logsCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (required)")
+	stepNumber := fs.Int("step", 0, "Step number (0 = all steps)")
+	operation := fs.String("operation", "", "Show one operation type only, e.g. push")
+	failed := fs.Bool("failed", false, "Show failed operations only")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *runID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --run-id is required\n")
+			os.Exit(1)
+		}
+
+		ops, err := db.ListOperations(*runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error querying operations: %v\n", err)
+			os.Exit(1)
+		}
+		logs, err := db.ListOperationLogs(*runID, *stepNumber)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error querying operation logs: %v\n", err)
+			os.Exit(1)
+		}
+
+		count := 0
+		for _, op := range ops {
+			opLog := logs[op.ID]
+			if opLog == nil || (*operation != "" && op.Operation != *operation) || (*failed && op.Status != "failed") {
+				continue
+			}
+
+			fmt.Printf("=== Step %d: %s (%s, %dms, exit code %d)\n", op.StepNumber, op.Operation, op.Status, op.DurationMs, opLog.ExitCode)
+			if op.ErrorKind != "" {
+				fmt.Printf("Failure: %s\n", op.ErrorKind)
+			}
+			if op.ServerEvent != "" {
+				fmt.Printf("Server: %s\n", op.ServerEvent)
+			}
+			fmt.Printf("$ %s\n", opLog.Command)
+			printOutput("stdout", opLog.Stdout)
+			printOutput("stderr", opLog.Stderr)
+			fmt.Println()
+			count++
+		}
+
+		if count == 0 {
+			fmt.Printf("No command output recorded for run %d", *runID)
+			if *stepNumber > 0 {
+				fmt.Printf(", step %d", *stepNumber)
+			}
+			fmt.Println()
+			return
+		}
+
+		log.Debugf("Showing the output of %d operations\n", count)
+	}
+}
+
+// printOutput prints one output stream of a command under a heading
+func printOutput(name, output string) {
+	if output == "" {
+		return
+	}
+	fmt.Printf("--- %s ---\n", name)
+	// git reports progress with carriage returns; keep only the final state of each line
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if i := strings.LastIndex(strings.TrimRight(line, "\r"), "\r"); i >= 0 {
+			line = line[i+1:]
+		}
+		fmt.Println(strings.TrimRight(line, "\r"))
+	}
+}
+
+// describeSnapshot renders a snapshot reference such as "#12 (step 3)"
+func describeSnapshot(snap *database.Snapshot) string {
+	if snap.Label != "" {
+		return fmt.Sprintf("#%d (%s)", snap.ID, snap.Label)
+	}
+	return fmt.Sprintf("#%d (step %d)", snap.ID, snap.StepNumber)
+}
+
+func snapshotsCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (required)")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *runID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --run-id is required\n")
+			os.Exit(1)
+		}
+
+		snapshots, err := db.ListSnapshots(*runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing snapshots: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(snapshots) == 0 {
+			fmt.Printf("No snapshots found for run %d\n", *runID)
+			return
+		}
+
+		fmt.Printf("Snapshots for run %d:\n\n", *runID)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tStep\tLabel\tFiles\tCreated")
+		fmt.Fprintln(w, "--\t----\t-----\t-----\t-------")
+
+		for _, snap := range snapshots {
+			checksums, err := db.ListChecksumsBySnapshot(snap.ID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error listing checksums: %v\n", err)
+				continue
+			}
+
+			step := "-"
+			if snap.StepNumber > 0 {
+				step = fmt.Sprintf("%d", snap.StepNumber)
+			}
+			label := snap.Label
+			if label == "" {
+				label = "-"
+			}
+
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\n",
+				snap.ID, step, label, len(checksums), snap.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		w.Flush()
+
+		log.Debugf("\nTotal snapshots: %d\n", len(snapshots))
+	}
+}
+
+func reportCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (required)")
+	format := fs.String("format", "html", "Report format: html, markdown with Mermaid charts, or text for the narrative only")
+	output := fs.StringP("output", "o", "", "Write the report to this file (default: stdout)")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *runID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --run-id is required\n")
+			os.Exit(1)
+		}
+
+		render := report.RenderHTML
+		switch *format {
+		case "html":
+		case "markdown", "md":
+			render = report.RenderMarkdown
+		case "text":
+			render = report.RenderNarrative
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unsupported report format '%s' (supported: html, markdown, text)\n", *format)
+			os.Exit(1)
+		}
+
+		r, err := report.Build(db, *runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error building report: %v\n", err)
+			os.Exit(1)
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *output, err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		if err := render(out, r); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *output != "" {
+			fmt.Printf("Report for run %d written to %s\n", *runID, *output)
+		}
+		log.Debugf("Steps: %d, operations: %d, checksum diffs: %d\n", len(r.Steps), len(r.Operations), len(r.Diffs))
+	}
+}
+
+func exportCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (required)")
+	format := fs.String("format", "jsonl", "Export format: jsonl")
+	output := fs.StringP("output", "o", "", "Write the export to this file (default: stdout)")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *runID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --run-id is required\n")
+			os.Exit(1)
+		}
+		if *format != "jsonl" {
+			fmt.Fprintf(os.Stderr, "Error: unsupported export format '%s' (supported: jsonl)\n", *format)
+			os.Exit(1)
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *output, err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		if err := db.ExportRun(*runID, out); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting run %d: %v\n", *runID, err)
+			os.Exit(1)
+		}
+
+		if *output != "" {
+			fmt.Printf("Run %d exported to %s\n", *runID, *output)
+		}
+	}
+}
+
+func badgeCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runIDs := fs.Int64Slice("runs", nil, "Comma-separated run IDs (default: the latest finished run of each configuration)")
+	format := fs.String("format", "svg", "Output format: svg for a badge, or markdown for a summary table")
+	label := fs.String("label", "git lfs", "Left-hand text of the badge")
+	output := fs.StringP("output", "o", "", "Write the badge or table to this file (default: stdout)")
+	campaign := fs.String("campaign", "", "Only the runs of this campaign, i.e. tagged campaign=NAME (see: lfst-run tag)")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *campaign != "" && len(*runIDs) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: --campaign selects the latest runs, so it cannot be used with --runs\n")
+			os.Exit(1)
+		}
+		var tags []database.Tag
+		if *campaign != "" {
+			tags = append(tags, database.Tag{Key: database.CampaignTag, Value: *campaign})
+		}
+
+		var render func(io.Writer, *report.Summary) error
+		switch *format {
+		case "svg":
+			render = func(w io.Writer, s *report.Summary) error { return report.RenderBadge(w, s, *label) }
+		case "markdown", "md":
+			render = report.RenderMarkdownSummary
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unsupported badge format '%s' (supported: svg, markdown)\n", *format)
+			os.Exit(1)
+		}
+
+		summary, err := report.BuildSummary(db, *runIDs, tags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error summarizing runs: %v\n", err)
+			os.Exit(1)
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *output, err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		if err := render(out, summary); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *output != "" {
+			fmt.Printf("Summary of %d runs (%s) written to %s\n", len(summary.Rows), summary.Message(), *output)
+		}
+	}
+}
+
+func compareRunsCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runIDs := fs.Int64Slice("runs", nil, "Comma-separated run IDs; the first is the baseline (required)")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if len(*runIDs) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: --runs requires at least two run IDs (e.g. --runs 5,8,12)\n")
+			os.Exit(1)
+		}
+
+		cmp, err := report.CompareRuns(db, *runIDs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error comparing runs: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Comparing %d runs (baseline: run %d):\n\n", len(cmp.Runs), cmp.Runs[0].ID)
+		for _, run := range cmp.Runs {
+			fmt.Printf("  Run %d: scenario %d, %s via %s, git server %s (%s)\n",
+				run.ID, run.ScenarioID, run.ServerType, run.Protocol, run.GitServer, run.Status)
+		}
+		fmt.Println()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		header := "Step\tOperation"
+		dashes := "----\t---------"
+		for i, run := range cmp.Runs {
+			header += fmt.Sprintf("\tRun %d", run.ID)
+			dashes += "\t------"
+			if i > 0 {
+				header += "\tDelta"
+				dashes += "\t-----"
+			}
+		}
+		fmt.Fprintln(w, header)
+		fmt.Fprintln(w, dashes)
+
+		for _, row := range cmp.Rows {
+			line := fmt.Sprintf("%d\t%s", row.StepNumber, row.Operation)
+			for i, d := range row.DurationMs {
+				if d == nil {
+					line += "\t-"
+				} else {
+					line += fmt.Sprintf("\t%dms", *d)
+				}
+				if i > 0 {
+					if deltaMs, pct, ok := row.Delta(i); ok {
+						line += "\t" + report.FormatDelta(deltaMs, pct)
+					} else {
+						line += "\t-"
+					}
+				}
+			}
+			fmt.Fprintln(w, line)
+		}
+
+		total := "\tTotal"
+		for i, t := range cmp.Totals {
+			total += fmt.Sprintf("\t%dms", t)
+			if i > 0 {
+				total += "\t" + report.FormatDelta(cmp.TotalDelta(i))
+			}
+		}
+		fmt.Fprintln(w, total)
+		w.Flush()
+
+		log.Debugf("\nAligned %d step/operation pairs\n", len(cmp.Rows))
+	}
+}
+
+func benchmarksCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	id := fs.Int64("id", 0, "Show the statistics of one benchmark")
+	operation := fs.String("operation", "", "Add the median and standard deviation of this operation type to the list, e.g. push")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *id != 0 {
+			bench, err := db.GetBenchmark(*id)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			stats, err := db.ListBenchmarkStats(bench.ID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Benchmark %d: scenario %d, %d of %d run(s) made (%s)\n", bench.ID, bench.ScenarioID,
+				len(bench.RunIDs), bench.Iterations, describeBenchmarkRuns(db, bench))
+			if bench.CompletedAt == nil || len(stats) == 0 {
+				fmt.Printf("No statistics: the benchmark did not finish or none of its runs completed\n")
+				return
+			}
+			fmt.Println()
+			report.RenderBenchmarkStats(os.Stdout, stats)
+			return
+		}
+
+		benchmarks, err := db.ListBenchmarks()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(benchmarks) == 0 {
+			fmt.Printf("No benchmarks recorded (run one with: lfst-scenario --repeat N SCENARIO_ID)\n")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		header := "ID\tScenario\tServer\tRuns\tStarted"
+		dashes := "--\t--------\t------\t----\t-------"
+		if *operation != "" {
+			header += "\tMedian " + *operation + "\tStdDev"
+			dashes += "\t------" + strings.Repeat("-", len(*operation)+1) + "\t------"
+		}
+		fmt.Fprintln(w, header)
+		fmt.Fprintln(w, dashes)
+
+		for _, bench := range benchmarks {
+			server := "-"
+			if len(bench.RunIDs) > 0 {
+				if run, err := db.GetTestRun(bench.RunIDs[0]); err == nil {
+					server = fmt.Sprintf("%s via %s", run.ServerType, run.Protocol)
+				}
+			}
+			line := fmt.Sprintf("%d\t%d\t%s\t%d/%d\t%s", bench.ID, bench.ScenarioID, server,
+				len(bench.RunIDs), bench.Iterations, bench.StartedAt.Format("2006-01-02 15:04"))
+			if *operation != "" {
+				median, stddev := "-", "-"
+				stats, err := db.ListBenchmarkStats(bench.ID)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				for _, stat := range stats {
+					if stat.Operation == *operation {
+						median = (time.Duration(math.Round(stat.MedianMs)) * time.Millisecond).String()
+						stddev = (time.Duration(math.Round(stat.StdDevMs)) * time.Millisecond).String()
+					}
+				}
+				line += "\t" + median + "\t" + stddev
+			}
+			fmt.Fprintln(w, line)
+		}
+		w.Flush()
+
+		log.Debugf("\nShowing %d benchmark(s)\n", len(benchmarks))
+	}
+}
+
+// describeBenchmarkRuns names the runs of a benchmark with their status, e.g. "runs 4 completed, 5 failed"
+func describeBenchmarkRuns(db *database.DB, bench *database.Benchmark) string {
+	if len(bench.RunIDs) == 0 {
+		return "no runs"
+	}
+	var runs []string
+	for _, runID := range bench.RunIDs {
+		status := "purged"
+		if run, err := db.GetTestRun(runID); err == nil {
+			status = run.Status
+		}
+		runs = append(runs, fmt.Sprintf("%d %s", runID, status))
+	}
+	return "runs " + strings.Join(runs, ", ")
+}
+
+func criticalPathCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (required)")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *runID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --run-id is required\n")
+			os.Exit(1)
+		}
+
+		cp, err := report.BuildCriticalPath(db, *runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error computing critical path: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(cp.Operations) == 0 {
+			fmt.Printf("No operations found for run %d\n", *runID)
+			return
+		}
+
+		fmt.Printf("Critical path for run %d:\n\n", *runID)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "ID\tSTEP\tOPERATION\tDURATION\tREPOSITORY\n")
+		fmt.Fprintf(w, "--\t----\t---------\t--------\t----------\n")
+		for _, op := range cp.Operations {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%dms\t%s\n", op.ID, op.StepNumber, op.Operation, op.DurationMs, op.Repo)
+		}
+		w.Flush()
+
+		fmt.Printf("\nSerial (critical path): %dms of %dms (%.1f%%)\n", cp.DurationMs, cp.TotalMs, cp.SerialPercent())
+		fmt.Printf("Parallelizable:         %dms\n", cp.ParallelizableMs())
+
+		if debug {
+			deps, err := db.ListOperationDependencies(*runID)
+			if err == nil {
+				log.Debugf("Dependencies recorded: %d\n", len(deps))
+			}
+		}
+	}
+}
+
+func timelineCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (required)")
+	format := fs.String("format", "text", "Output format: text for an ASCII chart, or svg")
+	width := fs.Int("width", report.TimelineWidth, "Width of the bars of the text chart, in characters")
+	output := fs.StringP("output", "o", "", "Write the chart to this file (default: stdout)")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *runID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --run-id is required\n")
+			os.Exit(1)
+		}
+
+		var render func(io.Writer, *report.Timeline) error
+		switch *format {
+		case "text":
+			render = func(w io.Writer, t *report.Timeline) error { return report.RenderTimelineText(w, t, *width) }
+		case "svg":
+			render = report.RenderTimelineSVG
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unsupported timeline format '%s' (supported: text, svg)\n", *format)
+			os.Exit(1)
+		}
+
+		timeline, err := report.BuildTimeline(db, *runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error building timeline: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(timeline.Bars) == 0 {
+			fmt.Printf("No operations found for run %d\n", *runID)
+			return
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *output, err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		if err := render(out, timeline); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *output != "" {
+			fmt.Printf("Timeline of %d operations written to %s\n", len(timeline.Bars), *output)
+		}
+	}
+}
+
+func sizesCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (required)")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *runID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --run-id is required\n")
+			os.Exit(1)
+		}
+
+		sizes, err := db.ListRepositorySizes(*runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error querying repository sizes: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(sizes) == 0 {
+			fmt.Printf("No repository sizes recorded for run %d\n", *runID)
+			return
+		}
+
+		fmt.Printf("Repository sizes for run %d:\n\n", *runID)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Step\tLocation\tSize\tFiles\tChange")
+		fmt.Fprintln(w, "----\t--------\t----\t-----\t------")
+
+		previous := make(map[string]int64) // Last size seen at each location
+		for _, rs := range sizes {
+			files := "-"
+			if rs.FileCount != nil {
+				files = fmt.Sprintf("%d", *rs.FileCount)
+			}
+
+			change := "-"
+			if prev, ok := previous[rs.Location]; ok {
+				change = fmt.Sprintf("%+.1f MB", float64(rs.SizeBytes-prev)/1024/1024)
+			}
+			previous[rs.Location] = rs.SizeBytes
+
+			fmt.Fprintf(w, "%d\t%s\t%.1f MB\t%s\t%s\n",
+				rs.StepNumber, rs.Location, float64(rs.SizeBytes)/1024/1024, files, change)
+		}
+		w.Flush()
+
+		log.Debugf("\nShowing %d measurements\n", len(sizes))
+	}
+}
+
+func resourcesCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	operation := fs.String("operation", "", "Only this operation type, e.g. push")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		summaries, err := db.ResourceUsageByServer(*operation)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error querying resource usage: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(summaries) == 0 {
+			fmt.Println("No resource usage recorded; run scenarios with lfst-scenario --sample-resources")
+			return
+		}
+
+		fmt.Printf("Resource usage of successful operations by server, most memory first:\n\n")
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Server\tOperation\tCount\tMax Peak RSS\tAvg Peak RSS\tAvg CPU\tMax Peak CPU\tAvg Read\tAvg Written")
+		fmt.Fprintln(w, "------\t---------\t-----\t------------\t------------\t-------\t------------\t--------\t-----------")
+		for _, s := range summaries {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%dms\t%.0f%%\t%s\t%s\n",
+				s.ServerType, s.Operation, s.Operations, formatMB(s.MaxPeakRSS), formatMB(s.AvgPeakRSS),
+				s.AvgCPUMs, s.MaxPeakCPU, formatMB(s.AvgReadBytes), formatMB(s.AvgWriteBytes))
+		}
+		w.Flush()
+
+		log.Debugf("\nShowing %d server and operation types\n", len(summaries))
+	}
+}
+
+// formatMB formats a number of bytes in megabytes
+func formatMB(bytes int64) string {
+	return fmt.Sprintf("%.1f MB", float64(bytes)/1024/1024)
+}
+
+func locksCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Show the locking test of one run instead of the latest per server")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		results, err := db.ListLockResults(*runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error querying lock results: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(results) == 0 {
+			fmt.Printf("No locking tests recorded; run a scenario with: lfst-scenario --locking ID\n")
+			return
+		}
+
+		// Results are oldest first, so later runs replace earlier ones of the same server and protocol
+		if *runID == 0 {
+			latest := make(map[string]int)
+			var unique []*database.LockResult
+			for _, l := range results {
+				key := l.ServerType + "/" + l.Protocol
+				if i, ok := latest[key]; ok {
+					unique[i] = l
+					continue
+				}
+				latest[key] = len(unique)
+				unique = append(unique, l)
+			}
+			results = unique
+			fmt.Printf("LFS locking API support (latest run per server):\n\n")
+		} else {
+			fmt.Printf("LFS locking API test of run %d:\n\n", *runID)
+		}
+
+		yesNo := func(b bool) string {
+			if b {
+				return "yes"
+			}
+			return "no"
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Server\tProtocol\tRun\tSupported\tVisible\tEnforced\tReleased\tMessage")
+		fmt.Fprintln(w, "------\t--------\t---\t---------\t-------\t--------\t--------\t-------")
+		for _, l := range results {
+			visible, enforced, released := "-", "-", "-"
+			if l.Supported {
+				visible, enforced, released = yesNo(l.Visible), yesNo(l.Enforced), yesNo(l.Released)
+			}
+			message, _, _ := strings.Cut(l.Message, "\n")
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", l.ServerType, l.Protocol, l.RunID,
+				yesNo(l.Supported), visible, enforced, released, message)
+		}
+		w.Flush()
+
+		log.Debugf("\nShowing %d locking tests\n", len(results))
+	}
+}
+
+func batchCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	runID := fs.Int64("run-id", 0, "Test run ID (required)")
+	stepNumber := fs.Int("step", 0, "Show the requests of one step only")
+	objects := fs.Bool("objects", false, "Show each object's actions, href and error")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *runID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --run-id is required\n")
+			os.Exit(1)
+		}
+
+		requests, err := db.ListLFSBatchRequests(*runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error querying LFS batch requests: %v\n", err)
+			os.Exit(1)
+		}
+		if *stepNumber != 0 {
+			var filtered []*database.LFSBatchRequest
+			for _, b := range requests {
+				if b.StepNumber == *stepNumber {
+					filtered = append(filtered, b)
+				}
+			}
+			requests = filtered
+		}
+
+		if len(requests) == 0 {
+			fmt.Printf("No LFS batch requests recorded; run a scenario with: lfst-scenario --lfs-proxy ID\n")
+			return
+		}
+
+		fmt.Printf("LFS Batch API requests of run %d:\n\n", *runID)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Step\tTime\tOperation\tObjects\tSize (MB)\tStatus\tTransfer\tDuration\tMessage")
+		fmt.Fprintln(w, "----\t----\t---------\t-------\t---------\t------\t--------\t--------\t-------")
+		for _, b := range requests {
+			message, _, _ := strings.Cut(b.Message, "\n")
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%.2f\t%d\t%s\t%dms\t%s\n", b.StepNumber, b.RequestedAt.Format("15:04:05"),
+				b.Operation, len(b.Objects), float64(b.TotalBytes())/1024/1024, b.StatusCode, b.Transfer, b.DurationMs, message)
+
+			if !*objects {
+				continue
+			}
+			for _, obj := range b.Objects {
+				answer := strings.Join(obj.Actions, ",")
+				switch {
+				case obj.ErrorCode != 0:
+					answer = fmt.Sprintf("error %d: %s", obj.ErrorCode, obj.ErrorMessage)
+				case answer == "":
+					answer = "no actions (server has it)"
+				case obj.Href != "":
+					answer += " " + obj.Href
+				}
+				fmt.Fprintf(w, "\t\t  %.12s\t\t%.2f\t\t\t\t%s\n", obj.OID, float64(obj.Size)/1024/1024, answer)
+			}
+		}
+		w.Flush()
+
+		log.Debugf("\nShowing %d batch requests\n", len(requests))
+	}
+}
+
+// writeRunStatsCSV writes one row per step of a run: checksums, operations, their
+// durations and the network traffic
+func writeRunStatsCSV(db *database.DB, runID int64) {
+	if _, err := db.GetTestRun(runID); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: test run %d not found: %v\n", runID, err)
+		os.Exit(1)
+	}
+
+	type stepStats struct {
+		checksums, operations int
+		totalMs               int64
+		rx, tx                *int64
+	}
+	steps := make(map[int]*stepStats)
+	step := func(n int) *stepStats {
+		if steps[n] == nil {
+			steps[n] = &stepStats{}
+		}
+		return steps[n]
+	}
+
+	checksumCounts, err := db.QueryRaw("SELECT step_number, COUNT(*) FROM checksums WHERE run_id = ? GROUP BY step_number", runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error querying checksums: %v\n", err)
+		os.Exit(1)
+	}
+	defer checksumCounts.Close()
+	for checksumCounts.Next() {
+		var n, count int
+		if err := checksumCounts.Scan(&n, &count); err != nil {
+			fmt.Fprintf(os.Stderr, "Error scanning row: %v\n", err)
+			os.Exit(1)
+		}
+		step(n).checksums = count
+	}
+
+	ops, err := db.ListOperations(runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error querying operations: %v\n", err)
+		os.Exit(1)
+	}
+	for _, op := range ops {
+		step(op.StepNumber).operations++
+		step(op.StepNumber).totalMs += op.DurationMs
+	}
+
+	results, err := db.ListStepResults(runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error querying step results: %v\n", err)
+		os.Exit(1)
+	}
+	for _, sr := range results {
+		step(sr.StepNumber).rx, step(sr.StepNumber).tx = sr.RxBytes, sr.TxBytes
+	}
+
+	numbers := make([]int, 0, len(steps))
+	for n := range steps {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	records := [][]string{{"step", "checksums", "operations", "avg_duration_ms", "total_duration_ms", "rx_bytes", "tx_bytes"}}
+	for _, n := range numbers {
+		s := steps[n]
+		var avg, rx, tx string
+		if s.operations > 0 {
+			avg = fmt.Sprintf("%.1f", float64(s.totalMs)/float64(s.operations))
+		}
+		if s.rx != nil && s.tx != nil {
+			rx, tx = strconv.FormatInt(*s.rx, 10), strconv.FormatInt(*s.tx, 10)
+		}
+		records = append(records, []string{strconv.Itoa(n), strconv.Itoa(s.checksums), strconv.Itoa(s.operations),
+			avg, strconv.FormatInt(s.totalMs, 10), rx, tx})
+	}
+	writeCSV(records)
+}
+
+// writeStatsCSV writes one row per test run, for comparison tables of servers and protocols
+func writeStatsCSV(db *database.DB) {
+	runs, err := db.ListTestRuns()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error querying test runs: %v\n", err)
+		os.Exit(1)
+	}
+
+	checksums := make(map[int64]int)
+	rows, err := db.QueryRaw("SELECT run_id, COUNT(*) FROM checksums GROUP BY run_id")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error querying checksums: %v\n", err)
+		os.Exit(1)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			fmt.Fprintf(os.Stderr, "Error scanning row: %v\n", err)
+			os.Exit(1)
+		}
+		checksums[id] = count
+	}
+
+	type opStats struct {
+		count   int
+		totalMs int64
+	}
+	operations := make(map[int64]opStats)
+	rows2, err := db.QueryRaw("SELECT run_id, COUNT(*), COALESCE(SUM(duration_ms), 0) FROM operations GROUP BY run_id")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error querying operations: %v\n", err)
+		os.Exit(1)
+	}
+	defer rows2.Close()
+	for rows2.Next() {
+		var id int64
+		var s opStats
+		if err := rows2.Scan(&id, &s.count, &s.totalMs); err != nil {
+			fmt.Fprintf(os.Stderr, "Error scanning row: %v\n", err)
+			os.Exit(1)
+		}
+		operations[id] = s
+	}
+
+	records := [][]string{{"run_id", "scenario", "server", "protocol", "git_server", "status", "started_at",
+		"completed_at", "checksums", "operations", "total_duration_ms"}}
+	for _, run := range runs {
+		var completed string
+		if run.CompletedAt != nil {
+			completed = run.CompletedAt.Format(time.RFC3339)
+		}
+		records = append(records, []string{strconv.FormatInt(run.ID, 10), strconv.Itoa(run.ScenarioID), run.ServerType,
+			run.Protocol, run.GitServer, run.Status, run.StartedAt.Format(time.RFC3339), completed,
+			strconv.Itoa(checksums[run.ID]), strconv.Itoa(operations[run.ID].count),
+			strconv.FormatInt(operations[run.ID].totalMs, 10)})
+	}
+	writeCSV(records)
+}
+
+// checkFormat exits unless format is an output format of checksums, operations and stats
+func checkFormat(format string) {
+	if format != "table" && format != "csv" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported format '%s' (supported: table, csv)\n", format)
+		os.Exit(1)
+	}
+}
+
+// writeCSV writes records to stdout as CSV, with the header in the first record
+func writeCSV(records [][]string) {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.WriteAll(records); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func dedupeCommand(fs *pflag.FlagSet) func(db *database.DB, args []string, debug bool) {
+	dryRun := fs.Bool("dry-run", false, "Count the duplicate checksums without removing them")
+
+	return func(db *database.DB, args []string, debug bool) {
+		fs.Parse(args)
+
+		n, err := db.DedupeChecksums(*dryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *dryRun {
+			fmt.Printf("%d duplicate checksums would be removed\n", n)
+			return
+		}
+		fmt.Printf("%s Removed %d duplicate checksums; each file now has one checksum per step\n", term.OK(), n)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: lfst-query [OPTIONS] COMMAND [ARGS...]\n\n")
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	fmt.Fprintf(os.Stderr, "  checksums      Show checksums for a specific run and step\n")
+	fmt.Fprintf(os.Stderr, "  compare        Compare checksums between two steps\n")
+	fmt.Fprintf(os.Stderr, "  reverify       Re-run a step comparison on stored checksums with new filters\n")
+	fmt.Fprintf(os.Stderr, "  stats          Show statistics about test runs\n")
+	fmt.Fprintf(os.Stderr, "  operations     Show operations recorded for a test run\n")
+	fmt.Fprintf(os.Stderr, "  top            List the slowest operations of a run, or of all runs\n")
+	fmt.Fprintf(os.Stderr, "  scoreboard     Compare server types and protocols over all finished runs\n")
+	fmt.Fprintf(os.Stderr, "  chart          Draw a bar chart of a run's steps or of the servers as a PNG, SVG or PDF\n")
+	fmt.Fprintf(os.Stderr, "  logs           Show the complete output of the commands of a test run\n")
+	fmt.Fprintf(os.Stderr, "  snapshots      List checksum snapshots (steps and labels) for a test run\n")
+	fmt.Fprintf(os.Stderr, "  report         Generate a self-contained HTML report for a test run\n")
+	fmt.Fprintf(os.Stderr, "  compare-runs   Compare operation durations across several test runs\n")
+	fmt.Fprintf(os.Stderr, "  critical-path  Show the longest chain of dependent operations in a run\n")
+	fmt.Fprintf(os.Stderr, "  timeline       Chart the operations of a run by start time, as text or SVG\n")
+	fmt.Fprintf(os.Stderr, "  benchmarks     Show the timing statistics of scenarios run with lfst-scenario --repeat\n")
+	fmt.Fprintf(os.Stderr, "  sizes          Show client and server storage sizes after each step\n")
+	fmt.Fprintf(os.Stderr, "  resources      Compare the CPU, memory and disk I/O of operations by server\n")
+	fmt.Fprintf(os.Stderr, "  locks          Show which servers support the LFS file locking API\n")
+	fmt.Fprintf(os.Stderr, "  batch          Show the LFS Batch API requests recorded for a test run\n")
+	fmt.Fprintf(os.Stderr, "  export         Write a test run and all its results as JSON lines\n")
+	fmt.Fprintf(os.Stderr, "  badge          Write the latest evaluation status as an SVG badge or a Markdown table\n")
+	fmt.Fprintf(os.Stderr, "  dedupe         Remove the duplicate checksums of a step, keeping the latest\n")
+}
+
+func printHelp() {
+	fmt.Printf("lfst-query - Query and report on Git LFS test data\n\n")
+	fmt.Printf("Version: %s\n\n", version)
+	fmt.Printf("DESCRIPTION:\n")
+	fmt.Printf("  Query the test database to inspect checksums, compare steps,\n")
+	fmt.Printf("  view operations, and generate statistics.\n")
+	fmt.Printf("  With --attach, the results of other databases, e.g. those left on other client\n")
+	fmt.Printf("  machines or from earlier campaigns, are queried together with --db as one dataset.\n")
+	fmt.Printf("  The IDs of --db are unchanged; the IDs of the Nth attached database are offset by\n")
+	fmt.Printf("  N x %d, so run 12 of the first attached database is run %d.\n\n",
+		database.FederationOffset, 12+database.FederationOffset)
+
+	fmt.Printf("USAGE:\n")
+	fmt.Printf("  lfst-query [OPTIONS] COMMAND [ARGS...]\n\n")
+
+	fmt.Printf("COMMANDS:\n")
+	fmt.Printf("  checksums      Show checksums for a specific run and step\n")
+	fmt.Printf("  compare        Compare checksums between two steps\n")
+	fmt.Printf("  reverify       Re-run a step comparison on stored checksums with new filters or rules;\n")
+	fmt.Printf("                 exits with status 1 if it fails\n")
+	fmt.Printf("  stats          Show statistics about test runs\n")
+	fmt.Printf("  operations     Show operations recorded for a test run, with files, bytes and MB/s\n")
+	fmt.Printf("  top            List the slowest operations of a run, or with --by bytes those that\n")
+	fmt.Printf("                 transferred the most, across its steps with their server, protocol and git\n")
+	fmt.Printf("                 server, to find bottlenecks. Without --run-id, every run is ranked.\n")
+	fmt.Printf("                 --tag KEY=VALUE keeps the operations tagged with lfst-run tag, or of\n")
+	fmt.Printf("                 tagged runs.\n")
+	fmt.Printf("  scoreboard     Compare each server type and protocol over all finished runs, or those of\n")
+	fmt.Printf("                 --scenario: runs, failure rate, average wall-clock duration of completed\n")
+	fmt.Printf("                 runs, push and pull (clone included) throughput, and failed verifications;\n")
+	fmt.Printf("                 --format markdown adds Mermaid bar charts for an evaluation page;\n")
+	fmt.Printf("                 --tag KEY=VALUE keeps the runs tagged with lfst-run tag, e.g. disk=ssd\n")
+	fmt.Printf("  chart          Draw a bar chart as a PNG (or, by the --output extension, SVG or PDF)\n")
+	fmt.Printf("                 without external tools: with --run-id, the duration or bytes of each\n")
+	fmt.Printf("                 step; without, a scoreboard metric of each server type and protocol:\n")
+	fmt.Printf("                 duration, push, pull or failure-rate, over the runs of --scenario and --tag\n")
+	fmt.Printf("  logs           Show the stdout and stderr of each command of a test run, for failure forensics\n")
+	fmt.Printf("  snapshots      List checksum snapshots (steps and labels) for a test run\n")
+	fmt.Printf("  report         Generate a self-contained HTML report for a test run, the same report as\n")
+	fmt.Printf("                 Markdown with Mermaid charts for a wiki, or its step-by-step narrative as text\n")
+	fmt.Printf("  compare-runs   Compare operation durations across several test runs\n")
+	fmt.Printf("  critical-path  Show the longest chain of dependent operations and how much time is serial\n")
+	fmt.Printf("  timeline       Chart the operations of a run as a Gantt chart ordered by start time, in\n")
+	fmt.Printf("                 ASCII or SVG, with how long no operation ran and how many ran at once\n")
+	fmt.Printf("  benchmarks     List the benchmarks made with lfst-scenario --repeat, or with --id show one's\n")
+	fmt.Printf("                 mean, median, standard deviation, minimum and maximum per operation type\n")
+	fmt.Printf("  sizes          Show client git/LFS and server storage sizes after each step\n")
+	fmt.Printf("  resources      Compare the peak and average memory, CPU time and disk I/O of operations\n")
+	fmt.Printf("                 by server type, from runs made with lfst-scenario --sample-resources\n")
+	fmt.Printf("  locks          Show which servers support the LFS file locking API\n")
+	fmt.Printf("  batch          Show the LFS Batch API requests recorded with lfst-scenario --lfs-proxy\n")
+	fmt.Printf("  export         Write a test run with all its operations, logs, checksums and sizes as\n")
+	fmt.Printf("                 JSON lines, for lfst-import --run on another machine or for archiving\n")
+	fmt.Printf("  badge          Write the latest evaluation status, the last finished run of each scenario,\n")
+	fmt.Printf("                 server, protocol and git server, as an SVG badge or a compact Markdown table\n")
+	fmt.Printf("                 for a README or wiki. --campaign NAME summarizes the runs tagged\n")
+	fmt.Printf("                 campaign=NAME.\n")
+	fmt.Printf("  dedupe         Remove the checksums that a file has more than once in a step, keeping the\n")
+	fmt.Printf("                 one stored last, e.g. after lfst-checksum ran twice for the step with an\n")
+	fmt.Printf("                 older version. The database then keeps them unique, as checksum_conflict\n")
+	fmt.Printf("                 says: replace (default) or error. --dry-run only counts them.\n\n")
+
+	fmt.Printf("GLOBAL OPTIONS:\n")
+	fmt.Printf("  -h, --help         Show this help message\n")
+	fmt.Printf("  -V, --version      Show version\n")
+	fmt.Printf("  -d, --debug        Enable debug output\n")
+	fmt.Printf("  -v, --verbose      Enable verbose output (alias for --debug)\n")
+	fmt.Printf("  -q, --quiet        Print errors only\n")
+	fmt.Printf("  --no-color         Plain output without colors (automatic when not a terminal)\n")
+	fmt.Printf("  --log-file PATH    Also append every message, debug included, to PATH\n")
+	fmt.Printf("  --log-format FMT   Format of the log file: text or json (default text)\n")
+	fmt.Printf("  --db PATH          Path to SQLite database\n")
+	fmt.Printf("  --attach PATH      Also query this database (repeatable)\n")
+	fmt.Printf("  --lang LANG        Language of reports and badges: en or de (default from\n")
+	fmt.Printf("                     $LFST_LANG, the language config setting, or the locale)\n\n")
+
+	fmt.Printf("EXAMPLES:\n")
+	fmt.Printf("  # Show checksums for run 5, step 1\n")
+	fmt.Printf("  lfst-query checksums --run-id 5 --step 1\n\n")
+
+	fmt.Printf("  # Compare checksums between step 1 and step 3\n")
+	fmt.Printf("  lfst-query compare --run-id 5 --from 1 --to 3\n\n")
+	fmt.Printf("  # See step 3's renames; identical content under a new path shows as RENAMED old → new\n")
+	fmt.Printf("  lfst-query compare --run-id 5 --from 2 --to 3\n\n")
+
+	fmt.Printf("  # Check an old run again, comparing only the videos and ignoring CRCs of same-size files\n")
+	fmt.Printf("  lfst-query reverify --run-id 5 --from 3 --to 4 --include '*.mov' --size-only\n\n")
+
+	fmt.Printf("  # Compare a named snapshot with step 7\n")
+	fmt.Printf("  lfst-query compare --run-id 5 --from pre-migration --to 7\n\n")
+
+	fmt.Printf("  # Show statistics for test run 5\n")
+	fmt.Printf("  lfst-query stats --run-id 5\n\n")
+
+	fmt.Printf("  # Per-run totals for a comparison table in a spreadsheet\n")
+	fmt.Printf("  lfst-query stats --format csv > runs.csv\n\n")
+
+	fmt.Printf("  # Every operation of run 5, with raw milliseconds and bytes\n")
+	fmt.Printf("  lfst-query operations --run-id 5 --format csv > run5-operations.csv\n\n")
+
+	fmt.Printf("  # Show overall database statistics\n")
+	fmt.Printf("  lfst-query stats\n\n")
+
+	fmt.Printf("  # Show operations for test run 5, step 2\n")
+	fmt.Printf("  lfst-query operations --run-id 5 --step 2\n\n")
+
+	fmt.Printf("  # See why the commands of run 5, step 2 failed\n")
+	fmt.Printf("  lfst-query logs --run-id 5 --step 2 --failed\n\n")
+
+	fmt.Printf("  # Write an HTML report for test run 5\n")
+	fmt.Printf("  lfst-query report --run-id 5 --format html --output run5.html\n\n")
+
+	fmt.Printf("  # Tell what happened in run 5, step by step: operations, checks and file changes\n")
+	fmt.Printf("  lfst-query report --run-id 5 --format text\n\n")
+
+	fmt.Printf("  # Paste run 5 into a GitHub wiki page\n")
+	fmt.Printf("  lfst-query report --run-id 5 --format markdown --output run5.md\n\n")
+
+	fmt.Printf("  # Benchmark runs 8 and 12 against baseline run 5, step by step\n")
+	fmt.Printf("  lfst-query compare-runs --runs 5,8,12\n\n")
+
+	fmt.Printf("  # Compare the median push time of every benchmark, then look at benchmark 2 in full\n")
+	fmt.Printf("  lfst-query benchmarks --operation push\n")
+	fmt.Printf("  lfst-query benchmarks --id 2\n\n")
+
+	fmt.Printf("  # Show how much of run 5 is inherently serial\n")
+	fmt.Printf("  lfst-query critical-path --run-id 5\n\n")
+
+	fmt.Printf("  # See which operations of run 5 ran one after another, then chart them for a wiki page\n")
+	fmt.Printf("  lfst-query timeline --run-id 5 --width 100\n")
+	fmt.Printf("  lfst-query timeline --run-id 5 --format svg --output run5-timeline.svg\n\n")
+
+	fmt.Printf("  # Find the bottlenecks of run 5, then the biggest pushes of every run\n")
+	fmt.Printf("  lfst-query top --run-id 5 --by duration --limit 20\n")
+	fmt.Printf("  lfst-query top --by bytes --operation push --limit 10\n\n")
+
+	fmt.Printf("  # Compare the servers over every run of scenario 6, as a spreadsheet or for the evaluation page\n")
+	fmt.Printf("  lfst-query scoreboard --scenario 6\n")
+	fmt.Printf("  lfst-query scoreboard --scenario 6 --format csv > scoreboard.csv\n")
+	fmt.Printf("  lfst-query scoreboard --scenario 6 --format markdown > scoreboard.md\n\n")
+
+	fmt.Printf("  # Compare the servers over the runs on SSDs with git-lfs 3.4.1, tagged with lfst-run tag --env\n")
+	fmt.Printf("  lfst-query scoreboard --tag disk=ssd --tag git-lfs=3.4.1\n\n")
+
+	fmt.Printf("  # Chart the steps of run 5, then the push throughput of each server in scenario 6\n")
+	fmt.Printf("  lfst-query chart --run-id 5 --metric duration --output run5.png\n")
+	fmt.Printf("  lfst-query chart --scenario 6 --metric push --output push.svg\n\n")
+
+	fmt.Printf("  # Find the most memory-hungry pushes, then look at the operations of run 5 in detail\n")
+	fmt.Printf("  lfst-query resources --operation push\n")
+	fmt.Printf("  lfst-query operations --run-id 5 --resources\n\n")
+
+	fmt.Printf("  # Show how repository and server storage grew during run 5\n")
+	fmt.Printf("  lfst-query sizes --run-id 5\n\n")
+
+	fmt.Printf("  # Show the latest locking API result of every server type and protocol\n")
+	fmt.Printf("  lfst-query locks\n\n")
+
+	fmt.Printf("  # Show the batch requests of run 5's second step, with each object's actions and hrefs\n")
+	fmt.Printf("  lfst-query batch --run-id 5 --step 2 --objects\n\n")
+
+	fmt.Printf("  # Archive run 5, or move it to another machine's database\n")
+	fmt.Printf("  lfst-query export --run-id 5 --format jsonl > run5.jsonl\n")
+	fmt.Printf("  lfst-import --run run5.jsonl\n\n")
+
+	fmt.Printf("  # Show the latest evaluation status in the project README\n")
+	fmt.Printf("  lfst-run tag 12 campaign=2025-q3\n")
+	fmt.Printf("  lfst-query badge --campaign 2025-q3 --output badge.svg\n")
+	fmt.Printf("  lfst-query badge --campaign 2025-q3 --format markdown > docs/status.md\n\n")
+
+	fmt.Printf("  # Count, then remove, the checksums stored twice by older versions\n")
+	fmt.Printf("  lfst-query dedupe --dry-run\n")
+	fmt.Printf("  lfst-query dedupe\n\n")
+
+	fmt.Printf("  # Compare run 5 with run 3 of the database copied from the laptop\n")
+	fmt.Printf("  lfst-query --attach laptop.db compare-runs --runs 5,%d\n\n", 3+database.FederationOffset)
+
+	fmt.Printf("For command-specific help:\n")
+	fmt.Printf("  lfst-query COMMAND --help\n\n")
+}
@@ -0,0 +1,1024 @@
+// Package runcmd is lfst-run, which the lfst command also runs as lfst run
+package runcmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mslinn/git-lfs-test/pkg/completion"
+	"github.com/mslinn/git-lfs-test/pkg/config"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/environment"
+	"github.com/mslinn/git-lfs-test/pkg/i18n"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/scenario"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/spf13/pflag"
+)
+
+var version string // Set by Command
+
+// commands are the subcommands of lfst-run. Each defines its flags on the flag set it is
+// given and returns the function that runs it, so the flags can be listed without running it.
+var commands = map[string]func(fs *pflag.FlagSet) func(db *database.DB, cfg *config.Config, args []string, debug bool){
+	"create":   createCommand,
+	"list":     listCommand,
+	"show":     showCommand,
+	"complete": completeCommand,
+	"fail":     failCommand,
+	"update":   updateCommand,
+	"tag":      tagCommand,
+	"purge":    purgeCommand,
+	"doctor":   doctorCommand,
+}
+
+// Commands returns the flags of each subcommand of lfst-run, for completion
+func Commands() map[string]*pflag.FlagSet {
+	return completion.FlagSets(commands)
+}
+
+// Main runs lfst-run with args, the command line after the program name
+func Main(v string, args []string) {
+	run := Command(v, pflag.CommandLine)
+	pflag.CommandLine.Parse(args)
+	run(pflag.Args())
+}
+
+// Command defines the flags of lfst-run on fs and returns the function that runs it
+// with the arguments left once fs is parsed; lfst run registers them on its subcommand
+func Command(v string, fs *pflag.FlagSet) func(args []string) {
+	version = v
+
+	// Define global flags
+	var (
+		showVersion bool
+		showHelp    bool
+		quiet       bool
+		noColor     bool
+		debug       bool
+		dbPath      string
+	)
+
+	fs.BoolVarP(&showVersion, "version", "V", false, "Show version and exit")
+	fs.BoolVarP(&showHelp, "help", "h", false, "Show this help message")
+	fs.BoolVarP(&quiet, "quiet", "q", false, "Print errors only")
+	fs.BoolVar(&noColor, "no-color", false, "Plain output without colors (automatic when not a terminal)")
+	fs.BoolVarP(&debug, "debug", "d", false, "Enable debug output")
+	fs.BoolVarP(&debug, "verbose", "v", false, "Enable verbose output (alias for --debug)")
+	var logFile, logFormat string
+	fs.StringVar(&logFile, "log-file", "", "Also append every message, debug included, to this file")
+	fs.StringVar(&logFormat, "log-format", "", "Format of the log file: text or json (default text)")
+	fs.StringVar(&dbPath, "db", "", "Path to SQLite database (default from config)")
+	var lang string
+	fs.StringVar(&lang, "lang", "", "Language of the output: en or de (default from $LFST_LANG, config or locale)")
+
+	// Stop parsing at first non-flag argument (the subcommand)
+	fs.SetInterspersed(false)
+
+	return func(args []string) {
+		// Handle version
+		if showVersion {
+			fmt.Printf("lfst-run version %s\n", version)
+			os.Exit(0)
+		}
+
+		// Get subcommand
+		if len(args) == 0 || showHelp {
+			printHelp()
+			os.Exit(0)
+		}
+
+		if err := term.Setup(quiet, noColor); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := log.Setup(debug, logFile, logFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		subcommand := args[0]
+
+		// Load configuration
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := i18n.Setup(lang, cfg.Language); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Use config database if not overridden
+		if dbPath == "" {
+			if dbPath, err = cfg.GetDatabasePath(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Validate database (creates directory if needed)
+		if err := cfg.ValidateDatabase(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error validating database: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Open database
+		db, err := database.Open(dbPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		// Execute subcommand
+		command, ok := commands[subcommand]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown subcommand '%s'\n\n", subcommand)
+			printUsage()
+			os.Exit(1)
+		}
+		command(pflag.NewFlagSet(subcommand, pflag.ExitOnError))(db, cfg, args[1:], debug)
+	}
+}
+
+func createCommand(fs *pflag.FlagSet) func(db *database.DB, _ *config.Config, args []string, debug bool) {
+	scenarioID := fs.Int("scenario", 0, "Scenario ID (required)")
+	serverType := fs.String("server", "", "Server type: lfs-test-server, giftless, rudolfs, bare (required)")
+	protocol := fs.String("protocol", "", "Protocol: http, https, ssh, local (required)")
+	gitServer := fs.String("git-server", "bare", "Git server: bare, github")
+	notes := fs.String("notes", "", "Optional notes about this test run")
+
+	return func(db *database.DB, _ *config.Config, args []string, debug bool) {
+		fs.Parse(args)
+
+		// Validate required flags
+		if *scenarioID == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --scenario is required\n")
+			os.Exit(1)
+		}
+		if *serverType == "" {
+			fmt.Fprintf(os.Stderr, "Error: --server is required\n")
+			os.Exit(1)
+		}
+		if *protocol == "" {
+			fmt.Fprintf(os.Stderr, "Error: --protocol is required\n")
+			os.Exit(1)
+		}
+
+		// Validate server type
+		validServers := map[string]bool{
+			"lfs-test-server": true,
+			"giftless":        true,
+			"rudolfs":         true,
+			"bare":            true,
+		}
+		if !validServers[*serverType] {
+			fmt.Fprintf(os.Stderr, "Error: invalid server type '%s'\n", *serverType)
+			fmt.Fprintf(os.Stderr, "Valid types: lfs-test-server, giftless, rudolfs, bare\n")
+			os.Exit(1)
+		}
+
+		// Validate protocol
+		validProtocols := map[string]bool{
+			"http":  true,
+			"https": true,
+			"ssh":   true,
+			"local": true,
+		}
+		if !validProtocols[*protocol] {
+			fmt.Fprintf(os.Stderr, "Error: invalid protocol '%s'\n", *protocol)
+			fmt.Fprintf(os.Stderr, "Valid protocols: http, https, ssh, local\n")
+			os.Exit(1)
+		}
+
+		// Create test run
+		run := &database.TestRun{
+			ScenarioID: *scenarioID,
+			ServerType: *serverType,
+			Protocol:   *protocol,
+			GitServer:  *gitServer,
+			StartedAt:  time.Now(),
+			Status:     "running",
+			Notes:      *notes,
+		}
+
+		err := db.CreateTestRun(run)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating test run: %v\n", err)
+			os.Exit(1)
+		}
+
+		// The run's commands run here, as those of the scripts calling lfst-run create do
+		env := environment.Collect(".")
+		env.RunID = run.ID
+		if err := db.SaveEnvironment(env); err != nil {
+			log.Warnf("failed to record the environment of run %d: %v\n", run.ID, err)
+		}
+
+		fmt.Printf("Created test run ID: %d\n", run.ID)
+		log.Debugf("  Scenario: %d\n", *scenarioID)
+		log.Debugf("  Server: %s\n", *serverType)
+		log.Debugf("  Protocol: %s\n", *protocol)
+		log.Debugf("  Git Server: %s\n", *gitServer)
+		log.Debugf("  Status: running\n")
+		if *notes != "" {
+			log.Debugf("  Notes: %s\n", *notes)
+		}
+	}
+}
+
+func listCommand(fs *pflag.FlagSet) func(db *database.DB, _ *config.Config, args []string, debug bool) {
+	status := fs.String("status", "", "Filter by status: running, completed, failed, cancelled, timed-out, aborted")
+	limit := fs.Int("limit", 20, "Maximum number of runs to display")
+	watch := fs.Bool("watch", false, "Refresh the table until interrupted, highlighting runs whose status changed")
+	interval := fs.Duration("interval", 5*time.Second, "Time between refreshes with --watch")
+
+	return func(db *database.DB, _ *config.Config, args []string, debug bool) {
+		fs.Parse(args)
+
+		if *interval <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: --interval must be positive\n")
+			os.Exit(1)
+		}
+		if *watch {
+			watchRuns(db, *status, *limit, *interval)
+			return
+		}
+
+		runs, err := listRuns(db, *status, *limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing test runs: %v\n", err)
+			os.Exit(1)
+		}
+		if len(runs) == 0 {
+			fmt.Println(i18n.T("No test runs found"))
+			return
+		}
+		writeRuns(os.Stdout, runs, nil)
+
+		log.Debugf("\nTotal runs: %d\n", len(runs))
+	}
+}
+
+// listRuns returns the latest limit runs, only those with status if it is set
+func listRuns(db *database.DB, status string, limit int) ([]*database.TestRun, error) {
+	runs, err := db.ListTestRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	// Filter by status if specified
+	if status != "" {
+		filtered := make([]*database.TestRun, 0)
+		for _, run := range runs {
+			if run.Status == status {
+				filtered = append(filtered, run)
+			}
+		}
+		runs = filtered
+	}
+
+	// Apply limit
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// writeRuns writes runs as a table. The rows of the runs in changed, which maps their
+// IDs to their previous status, are highlighted and show that status too.
+func writeRuns(out io.Writer, runs []*database.TestRun, changed map[int64]string) {
+	var table bytes.Buffer
+	w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	var header, dashes []string
+	for _, column := range []string{"ID", "Scenario", "Server", "Protocol", "Git", "Status", "Started", "Duration", "Notes"} {
+		header = append(header, i18n.T(column))
+		dashes = append(dashes, strings.Repeat("-", utf8.RuneCountInString(i18n.T(column))))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	fmt.Fprintln(w, strings.Join(dashes, "\t"))
+
+	for _, run := range runs {
+		duration := "-"
+		if run.CompletedAt != nil {
+			d := run.CompletedAt.Sub(run.StartedAt)
+			duration = fmt.Sprintf("%.1fs", d.Seconds())
+		} else {
+			d := time.Since(run.StartedAt)
+			duration = fmt.Sprintf("%.1fs*", d.Seconds())
+		}
+
+		notes := run.Notes
+		if len(notes) > 30 {
+			notes = notes[:27] + "..."
+		}
+
+		status := i18n.T(run.Status)
+		if previous, ok := changed[run.ID]; ok {
+			status = i18n.T("%s (was %s)", status, i18n.T(previous))
+		}
+
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			run.ID,
+			run.ScenarioID,
+			run.ServerType,
+			run.Protocol,
+			run.GitServer,
+			status,
+			run.StartedAt.Format("15:04:05"),
+			duration,
+			notes,
+		)
+	}
+	w.Flush()
+
+	// Highlight whole rows after aligning them, as escape sequences would count as width
+	lines := strings.SplitAfter(table.String(), "\n")
+	for i, line := range lines {
+		if row := i - 2; row >= 0 && row < len(runs) {
+			if _, ok := changed[runs[row].ID]; ok {
+				line = term.Highlight(strings.TrimSuffix(line, "\n")) + "\n"
+			}
+		}
+		fmt.Fprint(out, line)
+	}
+}
+
+// clearScreen moves the cursor to the top left corner of the terminal and clears it
+const clearScreen = "\033[H\033[2J"
+
+// watchRuns prints the runs table every interval until interrupted, redrawing it in place
+// on a terminal. Runs whose status changed since the previous refresh are highlighted.
+func watchRuns(db *database.DB, status string, limit int, interval time.Duration) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	inPlace := term.IsTerminal(os.Stdout)
+	seen := make(map[int64]string) // The status of each run at the previous refresh
+	for refresh := 0; ; refresh++ {
+		runs, err := listRuns(db, status, limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing test runs: %v\n", err)
+			os.Exit(1)
+		}
+		changed := make(map[int64]string)
+		for _, run := range runs {
+			if previous, ok := seen[run.ID]; ok && previous != run.Status {
+				changed[run.ID] = previous
+			}
+			seen[run.ID] = run.Status
+		}
+
+		if inPlace {
+			fmt.Print(clearScreen)
+		} else if refresh > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s\n\n", i18n.T("Every %s, updated %s (Ctrl-C to stop)", interval, time.Now().Format("15:04:05")))
+		if len(runs) == 0 {
+			fmt.Println(i18n.T("No test runs found"))
+		} else {
+			writeRuns(os.Stdout, runs, changed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func showCommand(_ *pflag.FlagSet) func(db *database.DB, _ *config.Config, args []string, debug bool) {
+	return func(db *database.DB, _ *config.Config, args []string, debug bool) {
+		if len(args) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: run ID required\n")
+			fmt.Fprintf(os.Stderr, "Usage: lfst-run show <RUN_ID>\n")
+			os.Exit(1)
+		}
+
+		var runID int64
+		if _, err := fmt.Sscanf(args[0], "%d", &runID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid run ID '%s'\n", args[0])
+			os.Exit(1)
+		}
+
+		run, err := db.GetTestRun(runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: test run %d not found: %v\n", runID, err)
+			os.Exit(1)
+		}
+
+		fmt.Println(i18n.T("Test Run %d:", run.ID))
+		fmt.Printf("  %-13s %d\n", i18n.T("Scenario ID:"), run.ScenarioID)
+		fmt.Printf("  %-13s %s\n", i18n.T("Server Type:"), run.ServerType)
+		fmt.Printf("  %-13s %s\n", i18n.T("Protocol:"), run.Protocol)
+		fmt.Printf("  %-13s %s\n", i18n.T("Git Server:"), run.GitServer)
+		fmt.Printf("  %-13s %s\n", i18n.T("Status:"), i18n.T(run.Status))
+		fmt.Printf("  %-13s %s\n", i18n.T("Started:"), run.StartedAt.Format("2006-01-02 15:04:05"))
+
+		if run.CompletedAt != nil {
+			fmt.Printf("  %-13s %s\n", i18n.T("Completed:"), run.CompletedAt.Format("2006-01-02 15:04:05"))
+			duration := run.CompletedAt.Sub(run.StartedAt)
+			fmt.Printf("  %-13s %.2fs\n", i18n.T("Duration:"), duration.Seconds())
+		} else {
+			duration := time.Since(run.StartedAt)
+			fmt.Printf("  %-13s %.2fs\n", i18n.T("Running for:"), duration.Seconds())
+		}
+
+		if run.WorkDir != "" {
+			fmt.Printf("  %-13s %s\n", i18n.T("Directory:"), run.WorkDir)
+		}
+		if run.NetworkProfile != "" {
+			fmt.Printf("  %-13s %s\n", i18n.T("Network:"), run.NetworkProfile)
+		}
+		if run.Notes != "" {
+			fmt.Printf("  %-13s %s\n", i18n.T("Notes:"), run.Notes)
+		}
+		if env, err := db.GetEnvironment(run.ID); err == nil && env != nil {
+			fmt.Printf("  %-13s %s\n", i18n.T("Environment:"), env.Summary())
+		}
+		if tags, err := db.ListRunTags(run.ID); err == nil && len(tags) > 0 {
+			fmt.Printf("  %-13s %s\n", i18n.T("Tags:"), formatTags(tags))
+		}
+
+		if summary, err := db.GetTimingSummary(run.ID); err == nil && summary != "" {
+			fmt.Printf("\n%s\n%s", i18n.T("Where the time went:"), summary)
+		}
+	}
+}
+
+func completeCommand(fs *pflag.FlagSet) func(db *database.DB, _ *config.Config, args []string, debug bool) {
+	notes := fs.String("notes", "", "Optional completion notes")
+
+	return func(db *database.DB, _ *config.Config, args []string, debug bool) {
+		if len(args) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: run ID required\n")
+			fmt.Fprintf(os.Stderr, "Usage: lfst-run complete <RUN_ID> [--notes \"message\"]\n")
+			os.Exit(1)
+		}
+
+		fs.Parse(args[1:])
+
+		var runID int64
+		if _, err := fmt.Sscanf(args[0], "%d", &runID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid run ID '%s'\n", args[0])
+			os.Exit(1)
+		}
+
+		// Get existing run
+		run, err := db.GetTestRun(runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: test run %d not found: %v\n", runID, err)
+			os.Exit(1)
+		}
+
+		// Update status
+		now := time.Now()
+		run.CompletedAt = &now
+		run.Status = "completed"
+		if *notes != "" {
+			if run.Notes != "" {
+				run.Notes += " | " + *notes
+			} else {
+				run.Notes = *notes
+			}
+		}
+
+		if err := db.UpdateTestRun(run); err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating test run: %v\n", err)
+			os.Exit(1)
+		}
+
+		duration := now.Sub(run.StartedAt)
+		fmt.Printf("%s Test run %d marked as completed (%.2fs)\n", term.OK(), runID, duration.Seconds())
+	}
+}
+
+func failCommand(fs *pflag.FlagSet) func(db *database.DB, _ *config.Config, args []string, debug bool) {
+	notes := fs.String("notes", "", "Optional failure notes")
+
+	return func(db *database.DB, _ *config.Config, args []string, debug bool) {
+		if len(args) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: run ID required\n")
+			fmt.Fprintf(os.Stderr, "Usage: lfst-run fail <RUN_ID> [--notes \"error message\"]\n")
+			os.Exit(1)
+		}
+
+		fs.Parse(args[1:])
+
+		var runID int64
+		if _, err := fmt.Sscanf(args[0], "%d", &runID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid run ID '%s'\n", args[0])
+			os.Exit(1)
+		}
+
+		// Get existing run
+		run, err := db.GetTestRun(runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: test run %d not found: %v\n", runID, err)
+			os.Exit(1)
+		}
+
+		// Update status
+		now := time.Now()
+		run.CompletedAt = &now
+		run.Status = "failed"
+		if *notes != "" {
+			if run.Notes != "" {
+				run.Notes += " | " + *notes
+			} else {
+				run.Notes = *notes
+			}
+		}
+
+		if err := db.UpdateTestRun(run); err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating test run: %v\n", err)
+			os.Exit(1)
+		}
+
+		duration := now.Sub(run.StartedAt)
+		fmt.Printf("%s Test run %d marked as failed (%.2fs)\n", term.Fail(), runID, duration.Seconds())
+	}
+}
+
+func updateCommand(fs *pflag.FlagSet) func(db *database.DB, _ *config.Config, args []string, debug bool) {
+	notes := fs.String("notes", "", "Update notes")
+	status := fs.String("status", "", "Update status: running, completed, failed")
+
+	return func(db *database.DB, _ *config.Config, args []string, debug bool) {
+		if len(args) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: run ID required\n")
+			fmt.Fprintf(os.Stderr, "Usage: lfst-run update <RUN_ID> [--notes \"message\"] [--status STATUS]\n")
+			os.Exit(1)
+		}
+
+		fs.Parse(args[1:])
+
+		var runID int64
+		if _, err := fmt.Sscanf(args[0], "%d", &runID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid run ID '%s'\n", args[0])
+			os.Exit(1)
+		}
+
+		// Get existing run
+		run, err := db.GetTestRun(runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: test run %d not found: %v\n", runID, err)
+			os.Exit(1)
+		}
+
+		// Update fields
+		updated := false
+		if *notes != "" {
+			if run.Notes != "" {
+				run.Notes += " | " + *notes
+			} else {
+				run.Notes = *notes
+			}
+			updated = true
+		}
+
+		if *status != "" {
+			validStatus := map[string]bool{
+				"running":   true,
+				"completed": true,
+				"failed":    true,
+			}
+			if !validStatus[*status] {
+				fmt.Fprintf(os.Stderr, "Error: invalid status '%s'\n", *status)
+				fmt.Fprintf(os.Stderr, "Valid status: running, completed, failed\n")
+				os.Exit(1)
+			}
+			run.Status = *status
+			if *status != "running" && run.CompletedAt == nil {
+				now := time.Now()
+				run.CompletedAt = &now
+			}
+			updated = true
+		}
+
+		if !updated {
+			fmt.Fprintf(os.Stderr, "Error: nothing to update (use --notes or --status)\n")
+			os.Exit(1)
+		}
+
+		if err := db.UpdateTestRun(run); err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating test run: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s Test run %d updated\n", term.OK(), runID)
+	}
+}
+
+func tagCommand(fs *pflag.FlagSet) func(db *database.DB, _ *config.Config, args []string, debug bool) {
+	operationID := fs.Int64("operation", 0, "Tag this operation of the run instead of the run")
+	env := fs.Bool("env", false, "Add tags describing this machine: git and git-lfs versions, kernel, disk type and so on")
+	deletes := fs.StringArray("delete", nil, "Remove the tag with this key (repeatable)")
+
+	return func(db *database.DB, _ *config.Config, args []string, debug bool) {
+		if len(args) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: run ID required\n")
+			fmt.Fprintf(os.Stderr, "Usage: lfst-run tag <RUN_ID> [KEY=VALUE...] [--operation ID] [--env] [--delete KEY]\n")
+			os.Exit(1)
+		}
+
+		fs.Parse(args[1:])
+
+		var runID int64
+		if _, err := fmt.Sscanf(args[0], "%d", &runID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid run ID '%s'\n", args[0])
+			os.Exit(1)
+		}
+
+		run, err := db.GetTestRun(runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: test run %d not found: %v\n", runID, err)
+			os.Exit(1)
+		}
+
+		var tags []database.Tag
+		for _, arg := range fs.Args() {
+			tag, err := database.ParseTag(arg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			tags = append(tags, tag)
+		}
+		if *env {
+			// The disk and filesystem are those the run's repositories were on
+			dir := run.WorkDir
+			if dir == "" {
+				dir = "."
+			}
+			tags = append(environment.Tags(environment.Collect(dir)), tags...)
+		}
+
+		target := fmt.Sprintf("test run %d", runID)
+		set, remove, list := db.SetRunTag, db.DeleteRunTag, db.ListRunTags
+		id := runID
+		if *operationID != 0 {
+			ops, err := db.ListOperations(runID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error querying operations: %v\n", err)
+				os.Exit(1)
+			}
+			if !slices.ContainsFunc(ops, func(op *database.Operation) bool { return op.ID == *operationID }) {
+				fmt.Fprintf(os.Stderr, "Error: operation %d is not an operation of test run %d\n", *operationID, runID)
+				os.Exit(1)
+			}
+			target = fmt.Sprintf("operation %d", *operationID)
+			set, remove, list = db.SetOperationTag, db.DeleteOperationTag, db.ListOperationTags
+			id = *operationID
+		}
+
+		// Without changes, list the tags
+		if len(tags) == 0 && len(*deletes) == 0 {
+			existing, err := list(id)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if len(existing) == 0 {
+				fmt.Printf("No tags on %s\n", target)
+				return
+			}
+			for _, tag := range existing {
+				fmt.Println(tag)
+			}
+			return
+		}
+
+		for _, key := range *deletes {
+			deleted, err := remove(id, key)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if !deleted {
+				fmt.Fprintf(os.Stderr, "%s %s has no tag '%s'\n", term.Warn(), target, key)
+			}
+		}
+		for _, tag := range tags {
+			if err := set(id, tag); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			log.Debugf("Tagged %s with %s\n", target, tag)
+		}
+
+		current, err := list(id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s Tags of %s: %s\n", term.OK(), target, formatTags(current))
+	}
+}
+
+// formatTags lists tags as key=value separated by spaces, or "none"
+func formatTags(tags []database.Tag) string {
+	if len(tags) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = tag.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+func purgeCommand(fs *pflag.FlagSet) func(db *database.DB, cfg *config.Config, args []string, debug bool) {
+	olderThan := fs.String("older-than", "", "Delete runs started longer ago than this, e.g. 30d, 2w, 1y, 36h")
+	status := fs.String("status", "", "Only delete runs with this status (default: all but running)")
+	dryRun := fs.Bool("dry-run", false, "List the runs that would be deleted without deleting them")
+
+	return func(db *database.DB, cfg *config.Config, args []string, debug bool) {
+		fs.Parse(args)
+
+		// Maximum age by status; --older-than overrides the retention policy
+		policy := make(map[string]time.Duration)
+		if *olderThan != "" {
+			age, err := config.ParseAge(*olderThan)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --older-than: %v\n", err)
+				os.Exit(1)
+			}
+			statuses := config.RetentionStatuses
+			if *status != "" {
+				statuses = []string{*status}
+			}
+			for _, s := range statuses {
+				policy[s] = age
+			}
+		} else {
+			if *status != "" {
+				fmt.Fprintf(os.Stderr, "Error: --status requires --older-than\n")
+				os.Exit(1)
+			}
+			var err error
+			if policy, err = cfg.GetRetention(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if len(policy) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: no retention policy configured; use --older-than or set one, e.g. lfst-config set retention.failed 30d\n")
+				os.Exit(1)
+			}
+		}
+
+		runs, err := db.ListTestRuns()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing test runs: %v\n", err)
+			os.Exit(1)
+		}
+
+		now := time.Now()
+		var purge []*database.TestRun
+		var ids []int64
+		for _, run := range runs {
+			if age, ok := policy[run.Status]; ok && now.Sub(run.StartedAt) > age {
+				purge = append(purge, run)
+				ids = append(ids, run.ID)
+			}
+		}
+
+		if len(purge) == 0 {
+			fmt.Println("No test runs to purge")
+			return
+		}
+
+		if *dryRun || debug {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tScenario\tStatus\tStarted")
+			fmt.Fprintln(w, "--\t--------\t------\t-------")
+			for _, run := range purge {
+				fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", run.ID, run.ScenarioID, run.Status, run.StartedAt.Format("2006-01-02 15:04:05"))
+			}
+			w.Flush()
+		}
+		if *dryRun {
+			fmt.Printf("\n%d test runs would be purged\n", len(purge))
+			return
+		}
+
+		deleted, err := db.DeleteTestRuns(ids)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error purging test runs: %v\n", err)
+			os.Exit(1)
+		}
+		if err := db.Vacuum(); err != nil {
+			log.Warnf("%v\n", err)
+		}
+
+		fmt.Printf("%s Purged %d test runs (%d rows)\n", term.OK(), len(purge), deleted)
+	}
+}
+
+func doctorCommand(fs *pflag.FlagSet) func(db *database.DB, _ *config.Config, args []string, debug bool) {
+	dryRun := fs.Bool("dry-run", false, "Report orphaned runs without marking them aborted")
+
+	return func(db *database.DB, _ *config.Config, args []string, debug bool) {
+		fs.Parse(args)
+
+		runs, err := db.ListTestRuns()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing test runs: %v\n", err)
+			os.Exit(1)
+		}
+
+		var running, aborted int
+		for _, run := range runs {
+			if run.Status != "running" {
+				continue
+			}
+			running++
+
+			switch {
+			case run.PID <= 0:
+				fmt.Printf("Run %d: no process recorded; left as running (mark it with lfst-run fail %d)\n", run.ID, run.ID)
+				continue
+			case scenario.ProcessAlive(run.PID):
+				log.Debugf("Run %d: process %d is alive\n", run.ID, run.PID)
+				continue
+			}
+
+			if *dryRun {
+				fmt.Printf("Run %d: process %d is gone; would mark it aborted\n", run.ID, run.PID)
+				aborted++
+				continue
+			}
+
+			// The run ended when it last did something
+			ended := run.StartedAt
+			if ops, err := db.ListOperations(run.ID); err == nil {
+				for _, op := range ops {
+					if end := op.StartedAt.Add(time.Duration(op.DurationMs) * time.Millisecond); end.After(ended) {
+						ended = end
+					}
+				}
+			}
+
+			note := fmt.Sprintf("Aborted: process %d exited without finishing the run (found by lfst-run doctor)", run.PID)
+			if run.Notes != "" {
+				note = run.Notes + " | " + note
+			}
+			run.Status = "aborted"
+			run.PID = 0
+			run.CompletedAt = &ended
+			run.Notes = note
+			if err := db.UpdateTestRun(run); err != nil {
+				fmt.Fprintf(os.Stderr, "Error updating run %d: %v\n", run.ID, err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s Run %d marked as aborted; its process is gone\n", term.OK(), run.ID)
+			aborted++
+		}
+
+		switch {
+		case running == 0:
+			fmt.Println("No running test runs")
+		case aborted == 0:
+			fmt.Printf("%s All %d running test runs have a live process\n", term.OK(), running)
+		}
+	}
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: lfst-run [OPTIONS] COMMAND [ARGS...]\n\n")
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	fmt.Fprintf(os.Stderr, "  create    Create a new test run\n")
+	fmt.Fprintf(os.Stderr, "  list      List test runs\n")
+	fmt.Fprintf(os.Stderr, "  show      Show details of a test run\n")
+	fmt.Fprintf(os.Stderr, "  complete  Mark a test run as completed\n")
+	fmt.Fprintf(os.Stderr, "  fail      Mark a test run as failed\n")
+	fmt.Fprintf(os.Stderr, "  update    Update test run notes or status\n")
+	fmt.Fprintf(os.Stderr, "  tag       Show, add or remove key/value tags of a test run or operation\n")
+	fmt.Fprintf(os.Stderr, "  purge     Delete old test runs and their results\n")
+	fmt.Fprintf(os.Stderr, "  doctor    Mark running test runs whose process is gone as aborted\n")
+}
+
+func printHelp() {
+	fmt.Printf("lfst-run - Manage Git LFS test run lifecycle\n\n")
+	fmt.Printf("Version: %s\n\n", version)
+	fmt.Printf("DESCRIPTION:\n")
+	fmt.Printf("  Create and manage test run records in the database. Each test run\n")
+	fmt.Printf("  represents one execution of a Git LFS test scenario.\n\n")
+
+	fmt.Printf("USAGE:\n")
+	fmt.Printf("  lfst-run [OPTIONS] COMMAND [ARGS...]\n\n")
+
+	fmt.Printf("COMMANDS:\n")
+	fmt.Printf("  create    Create a new test run\n")
+	fmt.Printf("  list      List test runs\n")
+	fmt.Printf("  show      Show details of a test run\n")
+	fmt.Printf("  complete  Mark a test run as completed\n")
+	fmt.Printf("  fail      Mark a test run as failed\n")
+	fmt.Printf("  update    Update test run notes or status\n")
+	fmt.Printf("  tag       Show, add or remove key/value tags of a test run or operation\n")
+	fmt.Printf("  purge     Delete old test runs and their results\n")
+	fmt.Printf("  doctor    Mark running test runs whose process is gone as aborted\n\n")
+
+	fmt.Printf("PURGE OPTIONS:\n")
+	fmt.Printf("  --older-than AGE   Delete runs started longer ago than AGE, e.g. 30d, 2w, 1y, 36h\n")
+	fmt.Printf("  --status STATUS    Only delete runs with STATUS (default: all but running)\n")
+	fmt.Printf("  --dry-run          List the runs that would be deleted without deleting them\n")
+	fmt.Printf("  Without --older-than, purge applies the retention policy of the config file,\n")
+	fmt.Printf("  set with e.g. lfst-config set retention.failed 30d. Running runs are never\n")
+	fmt.Printf("  purged. Each run's operations, checksums, sizes and other results are deleted\n")
+	fmt.Printf("  with it in one transaction, then the SQLite file is compacted.\n\n")
+
+	fmt.Printf("LIST OPTIONS:\n")
+	fmt.Printf("  --status STATUS    Only list runs with STATUS\n")
+	fmt.Printf("  --limit N          List at most N runs (default 20)\n")
+	fmt.Printf("  --watch            Refresh the list until Ctrl-C, in place on a terminal. Runs\n")
+	fmt.Printf("                     whose status changed since the previous refresh are\n")
+	fmt.Printf("                     highlighted and show their previous status.\n")
+	fmt.Printf("  --interval TIME    Time between refreshes with --watch, e.g. 2s, 1m (default 5s)\n\n")
+
+	fmt.Printf("TAG OPTIONS:\n")
+	fmt.Printf("  lfst-run tag RUN_ID [KEY=VALUE...] sets tags, replacing those with the same key,\n")
+	fmt.Printf("  and lists the tags when given none. Tags record what the results depend on,\n")
+	fmt.Printf("  e.g. disk=ssd or cache=cold; lfst-query top, scoreboard and chart select runs\n")
+	fmt.Printf("  with --tag KEY=VALUE.\n")
+	fmt.Printf("  --operation ID     Tag operation ID of the run instead of the run\n")
+	fmt.Printf("  --env              Also tag with arch, disk (ssd or hdd), filesystem, git, git-lfs,\n")
+	fmt.Printf("                     kernel and os, of this machine and the run's directory\n")
+	fmt.Printf("  --delete KEY       Remove the tag with KEY (repeatable)\n\n")
+
+	fmt.Printf("DOCTOR OPTIONS:\n")
+	fmt.Printf("  --dry-run          Report orphaned runs without marking them aborted\n")
+	fmt.Printf("  A run left running by a crash or a killed shell stays running forever, and\n")
+	fmt.Printf("  lfst-scenario --cancel all then tries to stop it. doctor checks the process\n")
+	fmt.Printf("  recorded for each running run and marks runs whose process is gone as\n")
+	fmt.Printf("  aborted, ending them at their last operation. Aborted runs can still be\n")
+	fmt.Printf("  resumed with lfst-scenario --resume. Runs without a recorded process, made\n")
+	fmt.Printf("  with lfst-run create, are left alone.\n\n")
+
+	fmt.Printf("GLOBAL OPTIONS:\n")
+	fmt.Printf("  -h, --help         Show this help message\n")
+	fmt.Printf("  -V, --version      Show version\n")
+	fmt.Printf("  -d, --debug        Enable debug output\n")
+	fmt.Printf("  -v, --verbose      Enable verbose output (alias for --debug)\n")
+	fmt.Printf("  -q, --quiet        Print errors only\n")
+	fmt.Printf("  --no-color         Plain output without colors (automatic when not a terminal)\n")
+	fmt.Printf("  --log-file PATH    Also append every message, debug included, to PATH\n")
+	fmt.Printf("  --log-format FMT   Format of the log file: text or json (default text)\n")
+	fmt.Printf("  --db PATH          Path to SQLite database\n")
+	fmt.Printf("  --lang LANG        Language of list and show output: en or de (default from\n")
+	fmt.Printf("                     $LFST_LANG, the language config setting, or the locale)\n\n")
+
+	fmt.Printf("EXAMPLES:\n")
+	fmt.Printf("  # Create a new test run for scenario 1\n")
+	fmt.Printf("  lfst-run create --scenario 1 --server lfs-test-server --protocol http\n\n")
+
+	fmt.Printf("  # List all running test runs\n")
+	fmt.Printf("  lfst-run list --status running\n\n")
+
+	fmt.Printf("  # Monitor a long evaluation, refreshing every 10 seconds\n")
+	fmt.Printf("  lfst-run list --watch --interval 10s\n\n")
+
+	fmt.Printf("  # Show details of test run 5\n")
+	fmt.Printf("  lfst-run show 5\n\n")
+
+	fmt.Printf("  # Mark test run 5 as completed\n")
+	fmt.Printf("  lfst-run complete 5 --notes \"All tests passed\"\n\n")
+
+	fmt.Printf("  # Mark test run 6 as failed\n")
+	fmt.Printf("  lfst-run fail 6 --notes \"Push operation failed\"\n\n")
+
+	fmt.Printf("  # Record the environment of run 5, and that its server cache was cold\n")
+	fmt.Printf("  lfst-run tag 5 --env cache=cold\n\n")
+
+	fmt.Printf("  # Show the tags of run 5\n")
+	fmt.Printf("  lfst-run tag 5\n\n")
+
+	fmt.Printf("  # Delete failed runs older than 30 days\n")
+	fmt.Printf("  lfst-run purge --older-than 30d --status failed\n\n")
+
+	fmt.Printf("  # Show what the configured retention policy would delete\n")
+	fmt.Printf("  lfst-run purge --dry-run\n\n")
+
+	fmt.Printf("  # Mark runs left running by a crash as aborted\n")
+	fmt.Printf("  lfst-run doctor\n\n")
+
+	fmt.Printf("For command-specific help:\n")
+	fmt.Printf("  lfst-run COMMAND --help\n\n")
+}
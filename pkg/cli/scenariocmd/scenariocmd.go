@@ -0,0 +1,1807 @@
+// Package scenariocmd is lfst-scenario, which the lfst command also runs as lfst scenario
+package scenariocmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/config"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/netem"
+	"github.com/mslinn/git-lfs-test/pkg/notify"
+	"github.com/mslinn/git-lfs-test/pkg/progress"
+	"github.com/mslinn/git-lfs-test/pkg/report"
+	"github.com/mslinn/git-lfs-test/pkg/scenario"
+	"github.com/mslinn/git-lfs-test/pkg/serverlog"
+	"github.com/mslinn/git-lfs-test/pkg/storage"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+	"github.com/spf13/pflag"
+)
+
+var version string // Set by Command
+
+// Predefined scenarios based on gitScenarios.html
+var scenarios = map[int]*scenario.Scenario{
+	1:  {ID: 1, Name: "Bare repo - local", ServerType: "bare", Protocol: "local", GitServer: "bare"},
+	2:  {ID: 2, Name: "Bare repo - SSH", ServerType: "bare", Protocol: "ssh", GitServer: "bare"},
+	6:  {ID: 6, Name: "LFS Test Server - HTTP", ServerType: "lfs-test-server", Protocol: "http", GitServer: "bare", ServerURL: "http://gojira:8079"},
+	7:  {ID: 7, Name: "LFS Test Server - HTTP/GitHub", ServerType: "lfs-test-server", Protocol: "http", GitServer: "github", ServerURL: "http://gojira:8079", RepoName: "mslinn/lfs-eval-test"},
+	8:  {ID: 8, Name: "Giftless - local", ServerType: "giftless", Protocol: "local", GitServer: "bare"},
+	9:  {ID: 9, Name: "Giftless - SSH", ServerType: "giftless", Protocol: "ssh", GitServer: "bare"},
+	13: {ID: 13, Name: "Rudolfs - local", ServerType: "rudolfs", Protocol: "local", GitServer: "bare"},
+	14: {ID: 14, Name: "Rudolfs - SSH", ServerType: "rudolfs", Protocol: "ssh", GitServer: "bare"},
+	// Gitea serves git and LFS from the server set with 'lfst-config set gitea_url'
+	15: {ID: 15, Name: "Gitea - HTTP", ServerType: "gitea", Protocol: "http", GitServer: "gitea", RepoName: "lfs-eval-test"},
+	// GitHub stores the LFS objects too, authenticated as the account gh is logged in to
+	16: {ID: 16, Name: "GitHub - HTTPS", ServerType: "github", Protocol: "https", GitServer: "github", RepoName: "lfs-eval-github"},
+	// Bitbucket Cloud and Azure Repos store the LFS objects in the workspace or project set with lfst-config
+	17: {ID: 17, Name: "Bitbucket - HTTPS", ServerType: "bitbucket", Protocol: "https", GitServer: "bitbucket", RepoName: "lfs-eval-bitbucket"},
+	18: {ID: 18, Name: "Azure DevOps - HTTPS", ServerType: "azure-devops", Protocol: "https", GitServer: "azure-devops", RepoName: "lfs-eval-azure"},
+}
+
+// ServerURLs returns the URLs of the LFS servers of the predefined scenarios, sorted
+func ServerURLs() []string {
+	var urls []string
+	for _, scen := range scenarios {
+		if scen.ServerURL != "" && !slices.Contains(urls, scen.ServerURL) {
+			urls = append(urls, scen.ServerURL)
+		}
+	}
+	slices.Sort(urls)
+	return urls
+}
+
+// Main runs lfst-scenario with args, the command line after the program name
+func Main(v string, args []string) {
+	run := Command(v, pflag.CommandLine)
+	pflag.CommandLine.Parse(args)
+	run(pflag.Args())
+}
+
+// Command defines the flags of lfst-scenario on fs and returns the function that runs it
+// with the arguments left once fs is parsed; lfst scenario registers them on its subcommand
+func Command(v string, fs *pflag.FlagSet) func(args []string) {
+	version = v
+
+	// Define flags
+	var (
+		showVersion bool
+		showHelp    bool
+		quiet       bool
+		noColor     bool
+		debug       bool
+		force       bool
+		dbPath      string
+		workDir     string
+		listOnly    bool
+		cancelArg   string
+		offline     bool
+		matrixArg   string
+		resumeID    int64
+		mini        bool
+		corpus      string
+		workers     int
+		iface       string
+		serverStore string
+		s3URL       string
+		s3Endpoint  string
+		lenient     bool
+		clients     int
+		clientSize  string
+		locking     bool
+		largeFile   string
+		largeStop   time.Duration
+		cadence     int
+		cadenceSize string
+		lfsProxy    bool
+		gitDaemon   bool
+		maxDuration time.Duration
+		pipeline    []string
+		listSteps   bool
+		track       []string
+		exclude     []string
+		store       string
+		noCache     bool
+		assertions  []string
+		allowDiffs  []string
+		adapter     string
+		agent       string
+		nice        int
+		ioClass     string
+		cpus        float64
+		memory      string
+		noInterfere bool
+		snapshots   string
+		rerunStep   int
+		service     string
+		repeat      int
+		netemArg    string
+		sampling    time.Duration
+		showProg    bool
+		noProgress  bool
+		junit       string
+		profile     string
+	)
+
+	fs.BoolVarP(&showVersion, "version", "V", false, "Show version and exit")
+	fs.BoolVarP(&showHelp, "help", "h", false, "Show this help message")
+	fs.BoolVarP(&quiet, "quiet", "q", false, "Print errors only")
+	fs.BoolVar(&noColor, "no-color", false, "Plain output without colors (automatic when not a terminal)")
+	fs.BoolVar(&showProg, "progress", false, "Show the progress of copies, pushes, pulls and clones even when not on a terminal")
+	fs.BoolVar(&noProgress, "no-progress", false, "Do not show the progress of copies, pushes, pulls and clones")
+	fs.BoolVarP(&debug, "debug", "d", false, "Enable debug output")
+	fs.BoolVarP(&debug, "verbose", "v", false, "Enable verbose output (alias for --debug)")
+	var logFile, logFormat string
+	fs.StringVar(&logFile, "log-file", "", "Also append every message, debug included, to this file")
+	fs.StringVar(&logFormat, "log-format", "", "Format of the log file: text or json (default text)")
+	fs.BoolVarP(&force, "force", "f", false, "Force recreation of existing repositories")
+	fs.StringVar(&profile, "profile", "", "Apply this profile of the config, e.g. wan (default: $LFS_PROFILE)")
+	fs.StringVar(&dbPath, "db", "", "Path to SQLite database (default from config)")
+	fs.StringVar(&workDir, "work-dir", "", "Working directory for test execution (default from config)")
+	fs.BoolVar(&listOnly, "list", false, "List available scenarios and exit")
+	fs.StringVar(&cancelArg, "cancel", "", "Cancel a running test: run ID or 'all'")
+	fs.BoolVar(&offline, "offline", false, "Never contact GitHub or other external services (default from config)")
+	fs.StringVar(&matrixArg, "matrix", "", "Run several scenarios in sequence: comma-separated IDs or 'all'")
+	fs.IntVar(&repeat, "repeat", 0, "Run the scenario N times and report mean, median, stddev, min and max per operation type")
+	fs.BoolVar(&mini, "mini", false, "Use a generated ~5MB corpus instead of the real test data (default scenario: 1)")
+	fs.StringVar(&corpus, "corpus", "", "Copy the named corpus under the test data, e.g. small or large, described by its manifest.yaml")
+	fs.IntVar(&workers, "workers", 0, "Files to checksum and verify concurrently (default: one per CPU)")
+	fs.StringVar(&iface, "interface", "", "Network interface whose traffic is recorded per step (default: default route's)")
+	fs.StringVar(&netemArg, "netem", "", "Shape pushes, pulls and clones with tc/netem: a preset or e.g. rtt=50ms,rate=100mbit; 'none' ignores the config (Linux, root)")
+	fs.StringVar(&serverStore, "server-storage", "", "LFS server storage directory to measure after each step (PATH or HOST:/PATH)")
+	fs.StringVar(&service, "server-service", "", "Record restarts and OOM kills of the LFS server: [HOST:]systemd:UNIT or [HOST:]docker:CONTAINER")
+	fs.StringVar(&s3URL, "s3", "", "Check pushed LFS objects in the server's S3 bucket (s3://BUCKET/PREFIX)")
+	fs.StringVar(&s3Endpoint, "s3-endpoint", "", "Endpoint URL of an S3-compatible service such as MinIO")
+	fs.BoolVar(&lenient, "lenient", false, "Record failed verifications but keep running the scenario")
+	fs.StringArrayVar(&assertions, "assert", nil, "Check after every step, e.g. 'operations.push.duration_ms < 5m' (repeatable)")
+	fs.StringArrayVar(&allowDiffs, "allow-diff", nil, "Checksum difference steps 4 and 6 expect: '[STEP] [CHANGE] PATTERN', e.g. '6 modified *.log' (repeatable)")
+	fs.StringVar(&adapter, "transfer-adapter", "", "Force git-lfs to use one transfer adapter: basic, ssh, multipart or standalone")
+	fs.StringVar(&agent, "transfer-agent", "", "Command line of the custom transfer agent of the multipart and standalone adapters")
+	fs.IntVar(&clients, "clients", 0, "Add step 8: N clients push and pull concurrently against the same server")
+	fs.StringVar(&clientSize, "client-size", "10MB", "Size of the LFS file each concurrent client pushes")
+	fs.BoolVar(&locking, "locking", false, "Add step 9: test the server's LFS file locking API across both clones")
+	fs.StringVar(&largeFile, "large-file", "", "Add step 10: push one sparse file of this size, e.g. 5GB, interrupting the first upload")
+	fs.DurationVar(&largeStop, "large-file-interrupt", scenario.DefaultLargeFileInterrupt, "How long the first upload of --large-file runs before it is interrupted")
+	fs.IntVar(&cadence, "cadence", 0, "Add step 11: modify a small LFS file N times, committing and pushing after each change")
+	fs.StringVar(&cadenceSize, "cadence-size", "64KB", "Size of the file each --cadence commit writes")
+	fs.BoolVar(&lfsProxy, "lfs-proxy", false, "Record every LFS Batch API request and response through a local proxy")
+	fs.BoolVar(&gitDaemon, "git-daemon", false, "Serve the bare repository of scenarios with a bare git server through git daemon")
+	fs.DurationVar(&maxDuration, "max-duration", 0, "Stop a run that takes longer than this, e.g. 2h, and mark it timed-out")
+	fs.StringSliceVar(&pipeline, "pipeline", nil, "Steps to run, comma-separated; NAME*N repeats a step (default from config, else the standard steps)")
+	fs.BoolVar(&listSteps, "list-steps", false, "List the steps a pipeline can use and exit")
+	fs.StringSliceVar(&track, "track", nil, "File patterns to track with LFS, comma-separated, e.g. '*.psd,*.onnx' (default from config, else the standard types)")
+	fs.StringSliceVar(&exclude, "checksum-exclude", nil, "Patterns of files left out of checksums, comma-separated, e.g. '.DS_Store,*.swp' (default from config, else stray OS and editor files)")
+	fs.StringVar(&store, "checksum-store", checksum.StoreDatabase, "Where checksums of steps go: database, manifest (files in the run's directory) or both")
+	fs.BoolVar(&noCache, "no-cache", false, "Read every file when checksumming, rather than trusting the run's cache for unchanged files")
+	fs.IntVar(&nice, "nice", 0, "Run git and git-lfs with this niceness, 1-19 (lower priority)")
+	fs.StringVar(&ioClass, "ionice", "", "Run git and git-lfs with this I/O class: idle, or best-effort[:LEVEL] with LEVEL 0-7 (Linux)")
+	fs.Float64Var(&cpus, "cpus", 0, "Limit each git command to this many CPUs, e.g. 1.5 (Linux, systemd-run cgroup)")
+	fs.StringVar(&memory, "memory", "", "Limit each git command's memory, e.g. 2GB (Linux, systemd-run cgroup)")
+	fs.DurationVar(&sampling, "sample-resources", 0, "Sample the CPU, memory and disk I/O of every git command at this interval (default 250ms when given alone)")
+	fs.Lookup("sample-resources").NoOptDefVal = "250ms"
+	fs.BoolVar(&noInterfere, "no-interference-check", false, "Do not look for antivirus scanners and file indexers that distort timings")
+	fs.Int64Var(&resumeID, "resume", 0, "Resume an interrupted run, skipping its completed steps")
+	fs.StringVar(&snapshots, "snapshots", "", "Snapshot repo1 and repo2 after every step: auto, reflink or tar")
+	fs.Lookup("snapshots").NoOptDefVal = scenario.SnapshotAuto
+	fs.IntVar(&rerunStep, "rerun-step", 0, "With --resume, run step N again from the snapshot of the step before it")
+	fs.StringVar(&junit, "junit", "", "Write the steps and verifications of the runs to this file as JUnit XML, for CI servers")
+	var detailArg string
+	fs.StringVar(&detailArg, "detail", "", "Show detailed repository contents for a run ID")
+
+	return func(args []string) {
+		// Handle version
+		if showVersion {
+			fmt.Printf("lfst-scenario version %s\n", version)
+			os.Exit(0)
+		}
+
+		// Handle help
+		if showHelp {
+			printHelp(fs)
+			os.Exit(0)
+		}
+
+		if err := term.Setup(quiet, noColor); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		switch {
+		case showProg && noProgress:
+			fmt.Fprintf(os.Stderr, "Error: --progress and --no-progress cannot be combined\n")
+			os.Exit(1)
+		case showProg:
+			progress.Setup(progress.On)
+		case noProgress:
+			progress.Setup(progress.Off)
+		}
+		if err := log.Setup(debug, logFile, logFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Load configuration early for defaults; --profile selects a profile instead of $LFS_PROFILE
+		if profile == "" {
+			profile = os.Getenv(config.ProfileEnv)
+		}
+		cfg, err := config.LoadProfile(profile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		if cfg.Profile != "" {
+			log.Debugf("Using profile %s of the config\n", cfg.Profile)
+		}
+		if cfg.Offline {
+			offline = true
+		}
+
+		// Handle list
+		if listOnly {
+			listScenarios(offline)
+			os.Exit(0)
+		}
+		if listSteps {
+			listPipelineSteps()
+			os.Exit(0)
+		}
+
+		// Use config values if not overridden
+		if dbPath == "" {
+			if dbPath, err = cfg.GetDatabasePath(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if workDir == "" {
+			workDir = cfg.GetWorkDir()
+		}
+
+		// Runs that end are announced through the hooks of the config; offline, only the command
+		hooks := notify.Hooks{Command: cfg.NotifyCommand}
+		if cfg.NotifyURL != "" {
+			if offline {
+				log.Warnf("offline mode is enabled, so notify_url is not used\n")
+			} else if hooks.URL, err = cfg.Secret("notify_url"); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Handle cancel
+		if cancelArg != "" {
+			handleCancel(cancelArg, dbPath, workDir, hooks)
+			os.Exit(0)
+		}
+
+		// Handle detail
+		if detailArg != "" {
+			handleDetail(detailArg, dbPath, workDir)
+			os.Exit(0)
+		}
+
+		// gh, aws and git, which runs gh as its credential helper, take their credentials from the environment
+		if err := cfg.ExportSecrets(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		opts := &runOptions{debug: debug, force: force, offline: offline, workers: workers, iface: iface, serverStorage: serverStore, lenient: lenient}
+		opts.noInterfere = noInterfere
+		if junit != "" {
+			if info, err := os.Stat(filepath.Dir(junit)); err != nil || !info.IsDir() {
+				fmt.Fprintf(os.Stderr, "Error: the directory of --junit %s does not exist\n", junit)
+				os.Exit(1)
+			}
+		}
+		opts.junit = junit
+		opts.notify = hooks
+		if snapshots != "" && !slices.Contains(scenario.SnapshotMethods, snapshots) {
+			fmt.Fprintf(os.Stderr, "Error: invalid --snapshots '%s' (use %s)\n", snapshots, strings.Join(scenario.SnapshotMethods, ", "))
+			os.Exit(1)
+		}
+		opts.snapshots = snapshots
+		if rerunStep < 0 || (rerunStep > 0 && resumeID == 0) {
+			fmt.Fprintf(os.Stderr, "Error: --rerun-step needs a step number and --resume RUN_ID\n")
+			os.Exit(1)
+		}
+
+		if repeat == 1 || repeat < 0 {
+			fmt.Fprintf(os.Stderr, "Error: --repeat needs at least 2 runs\n")
+			os.Exit(1)
+		}
+		if repeat > 0 && (resumeID != 0 || matrixArg != "") {
+			fmt.Fprintf(os.Stderr, "Error: --repeat cannot be combined with --resume or --matrix\n")
+			os.Exit(1)
+		}
+
+		if clients == 1 || clients < 0 {
+			fmt.Fprintf(os.Stderr, "Error: --clients needs at least 2 clients\n")
+			os.Exit(1)
+		}
+		opts.clients = clients
+		opts.locking = locking
+		if largeFile != "" {
+			if opts.largeFile, err = testdata.ParseSize(largeFile); err != nil || opts.largeFile == 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --large-file '%s'\n", largeFile)
+				os.Exit(1)
+			}
+		}
+		if largeStop <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: --large-file-interrupt must be positive\n")
+			os.Exit(1)
+		}
+		opts.largeStop = largeStop
+		if cadence < 0 {
+			fmt.Fprintf(os.Stderr, "Error: --cadence must be positive\n")
+			os.Exit(1)
+		}
+		opts.cadence = cadence
+		if opts.cadenceSize, err = testdata.ParseSize(cadenceSize); err != nil || opts.cadenceSize == 0 {
+			fmt.Fprintf(os.Stderr, "Error: invalid --cadence-size '%s'\n", cadenceSize)
+			os.Exit(1)
+		}
+		opts.lfsProxy = lfsProxy
+		opts.gitDaemon = gitDaemon
+		opts.sshGitHost = cfg.SSHGitHost
+		opts.sshGitDir = cfg.SSHGitDir
+		if maxDuration < 0 {
+			fmt.Fprintf(os.Stderr, "Error: --max-duration must be positive\n")
+			os.Exit(1)
+		}
+		if maxDuration == 0 {
+			if maxDuration, err = cfg.GetMaxDuration(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		opts.maxDuration = maxDuration
+		if sampling < 0 {
+			fmt.Fprintf(os.Stderr, "Error: --sample-resources must be positive\n")
+			os.Exit(1)
+		}
+		opts.sampling = sampling
+		if len(pipeline) == 0 {
+			pipeline = cfg.Pipeline
+		}
+		if len(pipeline) > 0 {
+			if _, err := scenario.ParsePipeline(pipeline); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		opts.pipeline = pipeline
+		if len(track) == 0 {
+			track = cfg.TrackPatterns
+		}
+		if err := scenario.ValidateTrackPatterns(track); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.trackPatterns = track
+		if len(exclude) == 0 {
+			exclude = cfg.GetChecksumExclude()
+		}
+		if err := (checksum.Filter{Exclude: exclude}).Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.checksumExclude = exclude
+		if !slices.Contains(checksum.StoreModes, store) {
+			fmt.Fprintf(os.Stderr, "Error: invalid --checksum-store '%s' (use %s)\n", store, strings.Join(checksum.StoreModes, ", "))
+			os.Exit(1)
+		}
+		opts.checksumStore = store
+		opts.noCache = noCache
+		if _, err := scenario.ParseAssertions(assertions); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.assertions = assertions
+		if _, err := scenario.ParseAllowedDifferences(allowDiffs); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.allowDiffs = allowDiffs
+		if err := scenario.ValidateTransferAdapter(adapter, agent); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.transferAdapter, opts.transferAgent = adapter, agent
+		if opts.limits, err = parseLimits(nice, ioClass, cpus, memory); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if opts.network, opts.scenarioNetwork, err = parseNetwork(netemArg, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if opts.clientSize, err = testdata.ParseSize(clientSize); err != nil || opts.clientSize == 0 {
+			fmt.Fprintf(os.Stderr, "Error: invalid --client-size '%s'\n", clientSize)
+			os.Exit(1)
+		}
+
+		if service != "" {
+			source, err := serverlog.ParseSource(service)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := source.CheckTools(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --server-service: %v\n", err)
+				os.Exit(1)
+			}
+			opts.serverService = source
+		}
+
+		if s3URL != "" {
+			probe, err := storage.ParseS3URL(s3URL)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := probe.CheckCLI(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --s3 needs the aws CLI: %v\n", err)
+				os.Exit(1)
+			}
+			probe.Endpoint = s3Endpoint
+			opts.s3 = probe
+		}
+
+		if corpus != "" && mini {
+			fmt.Fprintf(os.Stderr, "Error: --corpus cannot be used with --mini\n")
+			os.Exit(1)
+		}
+		opts.corpus = corpus
+		opts.testDataCache = cfg.GetTestDataCache()
+		opts.checksumConflict = cfg.GetChecksumConflict()
+
+		// Generate the miniature corpus; it is deterministic, so resumed runs see identical files
+		if mini {
+			opts.testDataPath = filepath.Join(workDir, "mini-data")
+			if err := testdata.GenerateMiniCorpus(opts.testDataPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error generating mini corpus: %v\n", err)
+				os.Exit(1)
+			}
+			log.Debugf("Generated mini corpus in %s\n", opts.testDataPath)
+		}
+
+		// From here on, SIGINT and SIGTERM cancel the run instead of killing the process
+		ctx := signalContext()
+
+		// Handle resume
+		if resumeID != 0 {
+			handleResume(ctx, resumeID, rerunStep, cfg, dbPath, workDir, opts)
+			os.Exit(0)
+		}
+
+		// Handle matrix
+		if matrixArg != "" {
+			os.Exit(runMatrix(ctx, matrixArg, cfg, dbPath, workDir, opts))
+		}
+
+		// Get scenario ID
+		if len(args) == 0 && mini {
+			args = []string{"1"}
+		}
+		if len(args) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: scenario ID required\n\n")
+			printUsage(fs)
+			os.Exit(1)
+		}
+
+		scenarioID, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid scenario ID '%s'\n", args[0])
+			os.Exit(1)
+		}
+
+		// Get scenario
+		scen, ok := scenarios[scenarioID]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: scenario %d not found (use --list to see available scenarios)\n", scenarioID)
+			os.Exit(1)
+		}
+
+		// Handle repeat
+		if repeat > 0 {
+			os.Exit(runBenchmark(ctx, scen, repeat, cfg, dbPath, workDir, opts))
+		}
+
+		// Validate database (creates directory if needed)
+		if err := cfg.ValidateDatabase(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error validating database: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Open database
+		db, err := database.Open(dbPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		// Create and run scenario
+		runner := opts.newRunner(scen, db, workDir)
+		err = runner.Execute(ctx)
+		opts.reportRuns(db, runner.RunID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n%s Scenario %d completed successfully\n", term.OK(), scenarioID)
+		fmt.Printf("  Run ID: %d\n", runner.RunID)
+		fmt.Printf("  View results: lfst-run show %d\n", runner.RunID)
+		printTimingSummary(db, runner.RunID)
+	}
+}
+
+// printTimingSummary prints where the time of a completed run went
+func printTimingSummary(db *database.DB, runID int64) {
+	summary, err := db.GetTimingSummary(runID)
+	if err != nil || summary == "" {
+		return
+	}
+	fmt.Printf("\nWhere the time went:\n%s", summary)
+}
+
+// reportRuns reports the runs that ended to CI: as JUnit XML if --junit is set, and as
+// annotations and a job summary in GitHub Actions. Runs that never started have the ID 0
+// and are left out.
+func (o *runOptions) reportRuns(db *database.DB, runIDs ...int64) {
+	runIDs = slices.DeleteFunc(runIDs, func(id int64) bool { return id == 0 })
+	if o.junit != "" {
+		o.writeJUnit(db, runIDs)
+	}
+	if report.InGitHubActions() {
+		reportGitHub(db, runIDs)
+	}
+}
+
+// writeJUnit writes the JUnit XML report of the runs to --junit
+func (o *runOptions) writeJUnit(db *database.DB, runIDs []int64) {
+	suites, err := report.BuildJUnit(db, runIDs)
+	if err == nil {
+		var f *os.File
+		if f, err = os.Create(o.junit); err == nil {
+			err = report.RenderJUnit(f, suites)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the JUnit report %s: %v\n", o.junit, err)
+		return
+	}
+	log.Debugf("Wrote the JUnit report of %d run(s) to %s\n", len(runIDs), o.junit)
+}
+
+// reportGitHub annotates the GitHub Actions job with the failures of the runs, on standard
+// error so --quiet keeps them, and appends their step timings to the job summary
+func reportGitHub(db *database.DB, runIDs []int64) {
+	for _, runID := range runIDs {
+		if err := report.RenderGitHubAnnotations(os.Stderr, db, runID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error annotating run %d: %v\n", runID, err)
+		}
+	}
+
+	path := os.Getenv(report.GitHubSummaryEnv)
+	if path == "" || len(runIDs) == 0 {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the job summary: %v\n", err)
+		return
+	}
+	defer f.Close()
+	for _, runID := range runIDs {
+		if err := report.RenderGitHubSummary(f, db, runID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing the job summary of run %d: %v\n", runID, err)
+			return
+		}
+	}
+}
+
+// runOptions holds the command-line settings shared by every runner this command creates
+type runOptions struct {
+	debug            bool
+	force            bool
+	offline          bool
+	testDataPath     string // Set by --mini
+	corpus           string // Set by --corpus; empty copies the test data root itself
+	testDataCache    string // test_data_cache of the config
+	workers          int
+	iface            string // Set by --interface
+	serverStorage    string // Set by --server-storage
+	lenient          bool
+	s3               *storage.S3       // Set by --s3; the object layout follows each scenario's server type
+	clients          int               // Set by --clients; 0 skips the concurrent step
+	clientSize       int64             // Set by --client-size
+	locking          bool              // Set by --locking
+	largeFile        int64             // Set by --large-file; 0 skips the large file step
+	largeStop        time.Duration     // Set by --large-file-interrupt
+	cadence          int               // Set by --cadence; 0 skips the cadence step
+	cadenceSize      int64             // Set by --cadence-size
+	lfsProxy         bool              // Set by --lfs-proxy
+	gitDaemon        bool              // Set by --git-daemon
+	sshGitHost       string            // From the config; empty keeps the SSH scenarios' repository local
+	sshGitDir        string            // From the config
+	maxDuration      time.Duration     // Set by --max-duration; 0 means no limit
+	sampling         time.Duration     // Set by --sample-resources; 0 takes no samples
+	pipeline         []string          // Set by --pipeline or the config; empty runs the standard steps
+	trackPatterns    []string          // Set by --track or the config; empty keeps each scenario's patterns
+	checksumExclude  []string          // Set by --checksum-exclude or the config
+	checksumStore    string            // Set by --checksum-store
+	checksumConflict string            // From checksum_conflict
+	noCache          bool              // Set by --no-cache
+	assertions       []string          // Set by --assert
+	allowDiffs       []string          // Set by --allow-diff
+	transferAdapter  string            // Set by --transfer-adapter; empty lets git-lfs choose
+	transferAgent    string            // Set by --transfer-agent
+	limits           *timing.Limits    // Set by --nice, --ionice, --cpus and --memory; nil means none
+	noInterfere      bool              // Set by --no-interference-check
+	snapshots        string            // Set by --snapshots; empty takes none
+	serverService    *serverlog.Source // Set by --server-service
+	notify           notify.Hooks      // From the config
+	junit            string            // Set by --junit; empty writes no JUnit report
+	// network is set by --netem and shapes every scenario; nil uses scenarioNetwork
+	network *netem.Profile
+	// scenarioNetwork holds the profiles of the config's 'network' by scenario ID
+	scenarioNetwork map[int]*netem.Profile
+}
+
+// parseNetwork reads the network profile of --netem, or else the profiles the config
+// sets for scenarios. 'none' leaves the network of every scenario alone.
+func parseNetwork(netemArg string, cfg *config.Config) (*netem.Profile, map[int]*netem.Profile, error) {
+	if netemArg == "none" {
+		return nil, nil, nil
+	}
+	if netemArg != "" {
+		profile, err := netem.Parse(netemArg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --netem: %w", err)
+		}
+		return profile, nil, nil
+	}
+
+	byScenario := make(map[int]*netem.Profile)
+	for key := range cfg.Network {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("network.%s: invalid scenario ID", key)
+		}
+		if byScenario[id], err = cfg.GetNetwork(id); err != nil {
+			return nil, nil, err
+		}
+	}
+	return nil, byScenario, nil
+}
+
+// parseLimits builds the resource limits of git commands from the command-line flags,
+// and checks that the programs applying them are installed
+func parseLimits(nice int, ioClass string, cpus float64, memory string) (*timing.Limits, error) {
+	limits := &timing.Limits{Nice: nice, CPUQuota: cpus}
+
+	class, level, hasLevel := strings.Cut(ioClass, ":")
+	limits.IOClass = class
+	if hasLevel {
+		n, err := strconv.Atoi(level)
+		if err != nil || class != "best-effort" {
+			return nil, fmt.Errorf("invalid --ionice '%s' (use idle or best-effort[:LEVEL])", ioClass)
+		}
+		limits.IOLevel = n
+	} else if class == "best-effort" {
+		limits.IOLevel = 7 // Lowest best-effort priority
+	}
+
+	if memory != "" {
+		size, err := testdata.ParseSize(memory)
+		if err != nil || size == 0 {
+			return nil, fmt.Errorf("invalid --memory '%s'", memory)
+		}
+		limits.MemoryMax = size
+	}
+
+	if limits.IsZero() {
+		return nil, nil
+	}
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+	if err := limits.CheckTools(); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// newRunner creates a scenario runner configured from the options
+func (o *runOptions) newRunner(scen *scenario.Scenario, db *database.DB, workDir string) *scenario.Runner {
+	if len(o.trackPatterns) > 0 {
+		custom := *scen // Leave the scenario definition alone
+		custom.TrackPatterns = o.trackPatterns
+		scen = &custom
+	}
+	runner := scenario.NewRunner(scen, db, workDir, o.debug, o.force)
+	runner.Offline = o.offline
+	runner.TestDataPath = o.testDataPath
+	runner.Corpus = o.corpus
+	runner.TestDataCache = o.testDataCache
+	runner.Workers = o.workers
+	runner.Interface = o.iface
+	runner.ServerStorage = o.serverStorage
+	runner.Lenient = o.lenient
+	runner.Version = version
+	runner.Clients = o.clients
+	runner.ClientFileSize = o.clientSize
+	runner.Locking = o.locking
+	runner.LargeFileSize = o.largeFile
+	runner.LargeFileInterrupt = o.largeStop
+	runner.CadenceCommits = o.cadence
+	runner.CadenceFileSize = o.cadenceSize
+	runner.LFSProxy = o.lfsProxy
+	runner.GitDaemon = o.gitDaemon
+	runner.SSHGitHost = o.sshGitHost
+	runner.SSHGitDir = o.sshGitDir
+	runner.MaxDuration = o.maxDuration
+	runner.ResourceSampling = o.sampling
+	runner.Pipeline = o.pipeline
+	runner.Limits = o.limits
+	runner.SkipInterferenceCheck = o.noInterfere
+	runner.Snapshots = o.snapshots
+	runner.ServerService = o.serverService
+	runner.ChecksumStore = o.checksumStore
+	runner.ChecksumConflict = o.checksumConflict
+	runner.NoChecksumCache = o.noCache
+	runner.Assertions = o.assertions
+	runner.AllowedDifferences = o.allowDiffs
+	runner.TransferAdapter = o.transferAdapter
+	runner.TransferAgent = o.transferAgent
+	runner.Notify = o.notify
+	runner.Network = o.network
+	if runner.Network == nil {
+		runner.Network = o.scenarioNetwork[scen.ID]
+	}
+	if len(o.checksumExclude) > 0 {
+		runner.ChecksumExclude = o.checksumExclude
+	}
+	if o.s3 != nil {
+		probe := *o.s3
+		probe.Layout = storage.LayoutFor(scen.ServerType)
+		runner.Storage = &probe
+	}
+	return runner
+}
+
+// handleResume resumes a run, or reruns one of its steps if step is not 0
+func handleResume(ctx context.Context, runID int64, step int, cfg *config.Config, dbPath, workDir string, opts *runOptions) {
+	if err := cfg.ValidateDatabase(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error validating database: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	run, err := db.GetTestRun(runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: run %d not found\n", runID)
+		os.Exit(1)
+	}
+
+	scen, ok := scenarios[run.ScenarioID]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: run %d used scenario %d, which is no longer defined\n", runID, run.ScenarioID)
+		os.Exit(1)
+	}
+
+	runner := opts.newRunner(scen, db, workDir)
+	if step != 0 {
+		err = runner.Rerun(ctx, runID, step)
+	} else {
+		err = runner.Resume(ctx, runID)
+	}
+	opts.reportRuns(db, runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		os.Exit(1)
+	}
+
+	if step != 0 {
+		fmt.Printf("\n%s Step %d of run %d (scenario %d) rerun successfully\n", term.OK(), step, runID, scen.ID)
+	} else {
+		fmt.Printf("\n%s Run %d (scenario %d) resumed and completed successfully\n", term.OK(), runID, scen.ID)
+	}
+	fmt.Printf("  View results: lfst-run show %d\n", runID)
+	printTimingSummary(db, runID)
+}
+
+func handleDetail(detailArg, dbPath, workDir string) {
+	// Parse run ID
+	runID, err := strconv.ParseInt(detailArg, 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid run ID '%s'\n", detailArg)
+		os.Exit(1)
+	}
+
+	// Open database
+	db, err := database.Open(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	// Get run info
+	run, err := db.GetTestRun(runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: run %d not found\n", runID)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Repository Details for Run %d\n", runID)
+	fmt.Printf("  Scenario: %d\n", run.ScenarioID)
+	fmt.Printf("  Status: %s\n", run.Status)
+	fmt.Printf("  Started: %s\n", run.StartedAt.Format("2006-01-02 15:04:05"))
+	fmt.Println()
+
+	// Check if repositories exist
+	repo1Dir, repo2Dir := scenario.RunRepoDirs(run, workDir)
+
+	repos := []struct {
+		name string
+		path string
+	}{
+		{"First Repository (repo1)", repo1Dir},
+		{"Second Repository (repo2)", repo2Dir},
+	}
+
+	for _, repo := range repos {
+		if _, err := os.Stat(repo.path); os.IsNotExist(err) {
+			fmt.Printf("%s: Not found (may have been cleaned up)\n", repo.name)
+			fmt.Println()
+			continue
+		}
+
+		fmt.Printf("=== %s ===\n", repo.name)
+		fmt.Printf("Location: %s\n\n", repo.path)
+
+		// Show repository details
+		if err := showRepositoryDetails(repo.path); err != nil {
+			fmt.Printf("Error: %v\n\n", err)
+		}
+	}
+}
+
+func showRepositoryDetails(repoDir string) error {
+	// Get LFS tracked files
+	lfsResult := timing.Run("git", []string{"-C", repoDir, "lfs", "ls-files", "-n"}, nil)
+	lfsFiles := make(map[string]bool)
+	if lfsResult.Error == nil && lfsResult.ExitCode == 0 {
+		scanner := bufio.NewScanner(strings.NewReader(lfsResult.Stdout))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" {
+				lfsFiles[line] = true
+			}
+		}
+	}
+
+	// Get git status to find untracked and ignored files
+	statusResult := timing.Run("git", []string{"-C", repoDir, "status", "--porcelain", "--ignored"}, nil)
+	untrackedFiles := make(map[string]bool)
+	ignoredFiles := make(map[string]bool)
+	if statusResult.Error == nil && statusResult.ExitCode == 0 {
+		scanner := bufio.NewScanner(strings.NewReader(statusResult.Stdout))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if len(line) < 3 {
+				continue
+			}
+			status := line[0:2]
+			fileName := strings.TrimSpace(line[3:])
+
+			if strings.HasPrefix(status, "?") {
+				untrackedFiles[fileName] = true
+			} else if strings.HasPrefix(status, "!") {
+				ignoredFiles[fileName] = true
+			}
+		}
+	}
+
+	// Get all files in the repository (excluding .git)
+	type FileInfo struct {
+		Name    string
+		Size    int64
+		Storage string
+	}
+	var files []FileInfo
+
+	err := filepath.Walk(repoDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip .git directory
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		// Skip directories
+		if info.IsDir() {
+			return nil
+		}
+
+		// Get relative path
+		relPath, err := filepath.Rel(repoDir, path)
+		if err != nil {
+			return err
+		}
+
+		// Determine storage type
+		storageType := "Git (regular)"
+		if lfsFiles[relPath] {
+			storageType = "LFS (tracked)"
+		} else if untrackedFiles[relPath] {
+			storageType = "Untracked"
+		} else if ignoredFiles[relPath] {
+			storageType = "Ignored"
+		}
+
+		files = append(files, FileInfo{
+			Name:    relPath,
+			Size:    info.Size(),
+			Storage: storageType,
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	// Print file listing
+	fmt.Printf("%-50s %12s  %s\n", "File", "Size", "Storage")
+	fmt.Printf("%-50s %12s  %s\n", strings.Repeat("-", 50), strings.Repeat("-", 12), strings.Repeat("-", 20))
+
+	totalSize := int64(0)
+	lfsCount := 0
+	gitCount := 0
+	untrackedCount := 0
+	ignoredCount := 0
+
+	for _, f := range files {
+		// Format size
+		sizeStr := formatSize(f.Size)
+		fmt.Printf("%-50s %12s  %s\n", f.Name, sizeStr, f.Storage)
+
+		totalSize += f.Size
+		switch f.Storage {
+		case "LFS (tracked)":
+			lfsCount++
+		case "Git (regular)":
+			gitCount++
+		case "Untracked":
+			untrackedCount++
+		case "Ignored":
+			ignoredCount++
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("Summary:\n")
+	fmt.Printf("  Total files: %d (%s)\n", len(files), formatSize(totalSize))
+	fmt.Printf("  LFS tracked: %d\n", lfsCount)
+	fmt.Printf("  Git regular: %d\n", gitCount)
+	fmt.Printf("  Untracked:   %d\n", untrackedCount)
+	fmt.Printf("  Ignored:     %d\n", ignoredCount)
+	fmt.Println()
+
+	return nil
+}
+
+func formatSize(bytes int64) string {
+	const (
+		KB = 1024
+		MB = 1024 * KB
+		GB = 1024 * MB
+	)
+
+	switch {
+	case bytes >= GB:
+		return fmt.Sprintf("%.2f GB", float64(bytes)/float64(GB))
+	case bytes >= MB:
+		return fmt.Sprintf("%.2f MB", float64(bytes)/float64(MB))
+	case bytes >= KB:
+		return fmt.Sprintf("%.2f KB", float64(bytes)/float64(KB))
+	default:
+		return fmt.Sprintf("%d B", bytes)
+	}
+}
+
+func handleCancel(cancelArg, dbPath, workDir string, hooks notify.Hooks) {
+	// Open database
+	db, err := database.Open(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	// Get runs to cancel
+	var runsToCanccel []*database.TestRun
+
+	if cancelArg == "all" {
+		// Get all running tests
+		allRuns, err := db.GetAllTestRuns()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting test runs: %v\n", err)
+			os.Exit(1)
+		}
+
+		for _, run := range allRuns {
+			if run.Status == "running" {
+				runsToCanccel = append(runsToCanccel, run)
+			}
+		}
+
+		if len(runsToCanccel) == 0 {
+			fmt.Println("No running tests to cancel")
+			return
+		}
+	} else {
+		// Parse run ID
+		runID, err := strconv.ParseInt(cancelArg, 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid run ID '%s'\n", cancelArg)
+			os.Exit(1)
+		}
+
+		// Get specific run
+		run, err := db.GetTestRun(runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: run %d not found\n", runID)
+			os.Exit(1)
+		}
+
+		if run.Status != "running" {
+			fmt.Printf("Run %d is not running (status: %s)\n", runID, run.Status)
+			return
+		}
+
+		runsToCanccel = append(runsToCanccel, run)
+	}
+
+	// Cancel each run
+	for _, run := range runsToCanccel {
+		fmt.Printf("Cancelling run %d (PID %d)...\n", run.ID, run.PID)
+
+		// Try to terminate the process
+		if run.PID > 0 {
+			process, err := os.FindProcess(run.PID)
+			if err == nil {
+				// Send SIGTERM for graceful shutdown
+				err = process.Signal(syscall.SIGTERM)
+				if err == nil {
+					fmt.Printf("  Sent SIGTERM to process %d\n", run.PID)
+
+					// The run stops its command in flight, records itself as cancelled and cleans up
+					if waitForExit(run.PID, cancelGracePeriod) {
+						if current, err := db.GetTestRun(run.ID); err == nil && current.Status != "running" {
+							fmt.Printf("  %s Run %d stopped and marked itself as %s\n", term.OK(), run.ID, current.Status)
+							continue
+						}
+					} else {
+						// Process still running, send SIGKILL
+						process.Kill()
+						fmt.Printf("  Sent SIGKILL to process %d\n", run.PID)
+					}
+				} else {
+					fmt.Printf("  Process %d not found (may have already exited)\n", run.PID)
+				}
+			}
+		}
+
+		// Clean up working directories, unless completed steps make the run resumable
+		repo1Dir, repo2Dir := scenario.RunRepoDirs(run, workDir)
+
+		if hasCompletedSteps(db, run.ID) {
+			fmt.Printf("  Kept %s and %s; resume with: lfst-scenario --resume %d\n", repo1Dir, repo2Dir, run.ID)
+		} else if run.WorkDir != "" {
+			removeWorkDir(run.WorkDir)
+		} else if other, err := scenario.SharedDirRun(db, run); err != nil {
+			// Runs made before per-run directories all work in WORK_DIR/repo1 and repo2
+			fmt.Printf("  Warning: kept %s and %s, as the runs working in them cannot be listed: %v\n", repo1Dir, repo2Dir, err)
+		} else if other != 0 {
+			fmt.Printf("  Kept %s and %s; run %d is still running in them\n", repo1Dir, repo2Dir, other)
+		} else {
+			removeWorkDir(repo1Dir)
+			removeWorkDir(repo2Dir)
+		}
+
+		// Mark run as cancelled in database
+		run.Status = "cancelled"
+		run.PID = 0
+		completedNow := time.Now()
+		run.CompletedAt = &completedNow
+		run.Notes += " | Cancelled by user"
+
+		if err := db.UpdateTestRun(run); err != nil {
+			fmt.Printf("  Warning: failed to update run status: %v\n", err)
+		} else {
+			fmt.Printf("  %s Run %d marked as cancelled\n", term.OK(), run.ID)
+			notifyCancelled(db, hooks, run.ID)
+		}
+	}
+
+	fmt.Printf("\nCancelled %d test run(s)\n", len(runsToCanccel))
+}
+
+// notifyCancelled tells hooks that --cancel ended run runID, whose process could not
+func notifyCancelled(db *database.DB, hooks notify.Hooks, runID int64) {
+	if !hooks.Enabled() {
+		return
+	}
+	payload, err := notify.Build(db, runID)
+	if err == nil {
+		err = hooks.Send(payload)
+	}
+	if err != nil {
+		fmt.Printf("  Warning: failed to notify the end of run %d: %v\n", runID, err)
+	}
+}
+
+// cancelGracePeriod is how long --cancel waits for a run to stop after SIGTERM before
+// killing it
+const cancelGracePeriod = 30 * time.Second
+
+// waitForExit waits up to timeout for the process pid to exit, and reports whether it did
+func waitForExit(pid int, timeout time.Duration) bool {
+	for stop := time.Now().Add(timeout); time.Now().Before(stop); time.Sleep(200 * time.Millisecond) {
+		if !scenario.ProcessAlive(pid) {
+			return true
+		}
+	}
+	return !scenario.ProcessAlive(pid)
+}
+
+// signalContext returns a context that the first SIGINT or SIGTERM cancels, so the run in
+// progress stops its command in flight, records itself as cancelled and cleans up; a second
+// signal ends the process at once
+func signalContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		fmt.Fprintf(os.Stderr, "\nReceived %s, cancelling the run (repeat to quit at once)\n", sig)
+		cancel()
+	}()
+	return ctx
+}
+
+// hasCompletedSteps reports whether any step of a run completed
+func hasCompletedSteps(db *database.DB, runID int64) bool {
+	results, err := db.ListStepResults(runID)
+	if err != nil {
+		return false
+	}
+	for _, sr := range results {
+		if sr.Status == "completed" {
+			return true
+		}
+	}
+	return false
+}
+
+// removeWorkDir removes a working directory if it exists, reporting the outcome
+func removeWorkDir(dir string) {
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		fmt.Printf("  Warning: failed to remove %s: %v\n", dir, err)
+	} else {
+		fmt.Printf("  Removed %s\n", dir)
+	}
+}
+
+func listScenarios(offline bool) {
+	fmt.Println("Available scenarios:")
+	fmt.Println()
+	fmt.Println("ID  Server             Protocol  Git Server    Description")
+	fmt.Println("--  ------             --------  ------------  -----------")
+
+	// Print in order
+	for _, id := range scenarioIDs() {
+		scen := scenarios[id]
+		name := scen.Name
+		if offline && scen.UsesExternalServices() {
+			name += " (unavailable offline)"
+		}
+		fmt.Printf("%-3d %-18s %-9s %-13s %s\n",
+			scen.ID,
+			scen.ServerType,
+			scen.Protocol,
+			scen.GitServer,
+			name,
+		)
+	}
+
+	fmt.Println()
+	fmt.Println("Note: Only scenarios 1, 2, 6-9, and 13-18 are currently implemented.")
+	fmt.Println("      Additional scenarios require specific server configurations.")
+}
+
+// listPipelineSteps prints the registered steps and the default pipeline
+func listPipelineSteps() {
+	fmt.Println("Pipeline steps:")
+	fmt.Println()
+	for _, step := range scenario.RegisteredSteps() {
+		fmt.Printf("  %-13s %s\n", step.Name, step.Description)
+		if len(step.Requires) > 0 {
+			fmt.Printf("  %-13s (after %s)\n", "", strings.Join(step.Requires, ", "))
+		}
+	}
+	fmt.Println()
+	fmt.Printf("Default pipeline: %s\n", strings.Join(scenario.DefaultPipeline, ","))
+}
+
+// scenarioIDs returns the IDs of all predefined scenarios in ascending order
+func scenarioIDs() []int {
+	var ids []int
+	for id := range scenarios {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// matrixResult is the outcome of one scenario in a matrix run
+type matrixResult struct {
+	scenario *scenario.Scenario
+	runID    int64
+	duration time.Duration
+	status   string // 'completed', 'failed', 'timed-out', 'skipped'
+	err      error
+}
+
+// parseMatrix converts "all" or a comma-separated list of IDs into scenarios
+func parseMatrix(matrixArg string) ([]*scenario.Scenario, error) {
+	var ids []int
+	if matrixArg == "all" {
+		ids = scenarioIDs()
+	} else {
+		for _, field := range strings.Split(matrixArg, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				return nil, fmt.Errorf("invalid scenario ID '%s' in --matrix", field)
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	var result []*scenario.Scenario
+	for _, id := range ids {
+		scen, ok := scenarios[id]
+		if !ok {
+			return nil, fmt.Errorf("scenario %d not found (use --list to see available scenarios)", id)
+		}
+		result = append(result, scen)
+	}
+	return result, nil
+}
+
+// runMatrix runs several scenarios in sequence, prints a summary, and returns the exit status
+func runMatrix(ctx context.Context, matrixArg string, cfg *config.Config, dbPath, workDir string, opts *runOptions) int {
+	matrix, err := parseMatrix(matrixArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if err := cfg.ValidateDatabase(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error validating database: %v\n", err)
+		return 1
+	}
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	var results []*matrixResult
+	for i, scen := range matrix {
+		result := &matrixResult{scenario: scen}
+		results = append(results, result)
+
+		// Running "all" offline should not count GitHub scenarios as failures
+		if opts.offline && matrixArg == "all" && scen.UsesExternalServices() {
+			result.status = "skipped"
+			fmt.Printf("[%d/%d] Skipping scenario %d (%s): needs GitHub, offline mode is enabled\n",
+				i+1, len(matrix), scen.ID, scen.Name)
+			continue
+		}
+
+		fmt.Printf("[%d/%d] Running scenario %d (%s)\n", i+1, len(matrix), scen.ID, scen.Name)
+
+		runner := opts.newRunner(scen, db, workDir)
+		start := time.Now()
+		result.err = runner.Execute(ctx)
+		result.duration = time.Since(start)
+		result.runID = runner.RunID
+
+		switch {
+		case errors.Is(result.err, scenario.ErrCancelled):
+			result.status = "cancelled"
+			fmt.Fprintf(os.Stderr, "  %s Scenario %d cancelled: %v\n", term.Fail(), scen.ID, result.err)
+		case errors.Is(result.err, scenario.ErrTimedOut):
+			result.status = "timed-out"
+			fmt.Fprintf(os.Stderr, "  %s Scenario %d timed out: %v\n", term.Fail(), scen.ID, result.err)
+		case result.err != nil:
+			result.status = "failed"
+			fmt.Fprintf(os.Stderr, "  %s Scenario %d failed: %v\n", term.Fail(), scen.ID, result.err)
+		default:
+			result.status = "completed"
+			fmt.Printf("  %s Scenario %d completed in %s\n", term.OK(), scen.ID, result.duration.Round(time.Millisecond))
+		}
+		if ctx.Err() != nil {
+			break // The remaining scenarios are not started
+		}
+	}
+
+	var runIDs []int64
+	for _, result := range results {
+		runIDs = append(runIDs, result.runID)
+	}
+	opts.reportRuns(db, runIDs...)
+
+	return printMatrixSummary(results)
+}
+
+// runBenchmark runs a scenario several times as a benchmark, stores the statistics of the
+// operation timings of the completed runs, prints them, and returns the exit status
+func runBenchmark(ctx context.Context, scen *scenario.Scenario, iterations int, cfg *config.Config, dbPath, workDir string, opts *runOptions) int {
+	if err := cfg.ValidateDatabase(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error validating database: %v\n", err)
+		return 1
+	}
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	bench := &database.Benchmark{ScenarioID: scen.ID, Iterations: iterations, StartedAt: time.Now()}
+	if err := db.CreateBenchmark(bench); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	var completed, runIDs []int64
+	failed := 0
+	defer func() { opts.reportRuns(db, runIDs...) }()
+	for i := 1; i <= iterations; i++ {
+		fmt.Printf("[%d/%d] Running scenario %d (%s)\n", i, iterations, scen.ID, scen.Name)
+
+		runner := opts.newRunner(scen, db, workDir)
+		if i > 1 && scen.UsesHostedGit() {
+			runner.Force = true // The hosted repository of the previous run is still there
+		}
+		start := time.Now()
+		err := runner.Execute(ctx)
+		runIDs = append(runIDs, runner.RunID)
+		if runner.RunID > 0 {
+			if err := db.AddBenchmarkRun(bench.ID, runner.RunID, i); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+		}
+		if err != nil && runner.RunID == 0 {
+			// Nothing ran, so the next iteration would fail the same way
+			fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+			return 1
+		}
+		if errors.Is(err, scenario.ErrCancelled) {
+			failed++
+			fmt.Fprintf(os.Stderr, "  %s Run %d cancelled; the statistics cover the runs before it: %v\n", term.Fail(), runner.RunID, err)
+			break
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "  %s Run %d failed and is left out of the statistics: %v\n", term.Fail(), runner.RunID, err)
+			continue
+		}
+		completed = append(completed, runner.RunID)
+		fmt.Printf("  %s Run %d completed in %s\n", term.OK(), runner.RunID, time.Since(start).Round(time.Millisecond))
+	}
+
+	stats, err := report.BenchmarkStats(db, completed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := db.CompleteBenchmark(bench.ID, stats); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("\nBenchmark %d: scenario %d, %d of %d run(s) completed\n\n", bench.ID, scen.ID, len(completed), iterations)
+	if len(stats) > 0 {
+		report.RenderBenchmarkStats(os.Stdout, stats)
+	} else {
+		fmt.Printf("No run completed an operation, so there are no statistics\n")
+	}
+	fmt.Printf("\n  View again: lfst-query benchmarks --id %d\n", bench.ID)
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printMatrixSummary prints the matrix results and returns 1 if any scenario failed
+func printMatrixSummary(results []*matrixResult) int {
+	fmt.Println()
+	fmt.Println("Matrix summary:")
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tScenario\tRun ID\tDuration\tStatus\tError")
+	fmt.Fprintln(w, "--\t--------\t------\t--------\t------\t-----")
+
+	var total time.Duration
+	failed := 0
+	for _, r := range results {
+		runID := "-"
+		if r.runID > 0 {
+			runID = strconv.FormatInt(r.runID, 10)
+		}
+		duration := "-"
+		if r.duration > 0 {
+			duration = r.duration.Round(time.Millisecond).String()
+		}
+		errMsg := ""
+		if r.err != nil {
+			failed++
+			errMsg = strings.SplitN(r.err.Error(), "\n", 2)[0]
+		}
+		total += r.duration
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			r.scenario.ID, r.scenario.Name, runID, duration, r.status, errMsg)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d scenario(s), %d failed, total time %s\n", len(results), failed, total.Round(time.Millisecond))
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printUsage(fs *pflag.FlagSet) {
+	fmt.Fprintf(os.Stderr, "Usage: lfst-scenario [OPTIONS] SCENARIO_ID\n\n")
+	fmt.Fprintf(os.Stderr, "Run a complete Git LFS test scenario (all 7 steps, or a custom --pipeline)\n\n")
+	fs.PrintDefaults()
+}
+
+func printHelp(fs *pflag.FlagSet) {
+	fmt.Printf("lfst-scenario - Execute complete Git LFS test scenarios\n\n")
+	fmt.Printf("Version: %s\n\n", version)
+	fmt.Printf("DESCRIPTION:\n")
+	fmt.Printf("  Executes a complete 7-step Git LFS evaluation scenario:\n")
+	fmt.Printf("    1. Setup repository, configure LFS, copy initial files (~1.3GB)\n")
+	fmt.Printf("    2. Add, commit, and push with timing measurements\n")
+	fmt.Printf("    3. Modify, delete, and rename files\n")
+	fmt.Printf("    4. Clone to second machine and verify checksums\n")
+	fmt.Printf("    5. Make changes on second machine\n")
+	fmt.Printf("    6. Pull changes back to first machine\n")
+	fmt.Printf("    7. Untrack files from LFS\n")
+	fmt.Printf("  After a successful run, the wall-clock time of each step with its share of the total,\n")
+	fmt.Printf("  and the %d slowest operations, are printed and stored with the run (see: lfst-run show).\n", report.SlowestOperations)
+	fmt.Printf("  With --clients N, step 8 has N clients clone, commit and push at the same time,\n")
+	fmt.Printf("  retrying pushes that lose the race, then pull; contention and throughput are recorded.\n")
+	fmt.Printf("  With --locking, step 9 locks a file from the second clone and checks from the first\n")
+	fmt.Printf("  that the lock is listed and enforced; servers without the locking API are recorded.\n")
+	fmt.Printf("  With --large-file SIZE, step 10 commits one sparse file of that size in the second clone,\n")
+	fmt.Printf("  stops its first upload after --large-file-interrupt, and pushes again. The server must end\n")
+	fmt.Printf("  up with the whole object; warnings record a server that took it in one request rather\n")
+	fmt.Printf("  than with a multipart or chunked adapter, and a second push that sent the file again\n")
+	fmt.Printf("  instead of resuming, judged from the network traffic (see: lfst-query stats).\n")
+	fmt.Printf("  With --cadence N, step 11 rewrites one small LFS file N times in the second clone, with\n")
+	fmt.Printf("  an add, commit and push after each change, as CI jobs that commit often do. The time\n")
+	fmt.Printf("  per commit and per push, the bytes sent per commit and, with --lfs-proxy, the Batch API\n")
+	fmt.Printf("  requests per commit are recorded (see: lfst-query stats).\n")
+	fmt.Printf("  With --lfs-proxy, git-lfs talks to scenarios with an HTTP(S) LFS server through a local\n")
+	fmt.Printf("  proxy that records each Batch API request: objects, actions, hrefs and status codes.\n")
+	fmt.Printf("  Scenarios with a bare git server push to, clone and pull from a bare repository that step 1\n")
+	fmt.Printf("  creates in the run's directory: as a path, over SSH to localhost for SSH scenarios, or with\n")
+	fmt.Printf("  --git-daemon through git daemon on a free local port. Without an LFS server of their own,\n")
+	fmt.Printf("  LFS objects are stored in the bare repository.\n")
+	fmt.Printf("  With --max-duration, a run that is still going when the time is up stops the command in\n")
+	fmt.Printf("  flight, is marked timed-out with the step it reached, and its working directories are removed.\n")
+	fmt.Printf("  In a matrix, every scenario gets the full duration. It defaults to max_duration of the config.\n")
+	fmt.Printf("  --profile NAME applies a profile of the config file, such as lan, wan or github, which\n")
+	fmt.Printf("  bundles settings like work_dir, database, server URLs, track_patterns and max_duration\n")
+	fmt.Printf("  of a recurring setup; $LFS_PROFILE selects one for every command. Environment variables\n")
+	fmt.Printf("  and flags still override it (see: lfst-config --help).\n")
+	fmt.Printf("  Ctrl-C, or the SIGTERM that --cancel sends, stops the git command in flight; the run is\n")
+	fmt.Printf("  marked cancelled with the step it reached, network shaping and servers started for it\n")
+	fmt.Printf("  are stopped, and its working directories are removed unless completed steps make it\n")
+	fmt.Printf("  resumable. A matrix or --repeat starts no further runs. A second signal quits at once.\n")
+	fmt.Printf("  When a run completes, fails, is cancelled or times out, the notify_url and notify_command\n")
+	fmt.Printf("  of the config receive a JSON payload with the run ID, scenario, duration and failed step\n")
+	fmt.Printf("  (see: lfst-config --help).\n")
+	fmt.Printf("  With --junit FILE, the runs are also written to FILE as JUnit XML once they end, for\n")
+	fmt.Printf("  Jenkins, GitHub Actions and other CI servers: a test suite per run, with a test case per\n")
+	fmt.Printf("  step and per verification. Failed steps and failed verifications of error severity are\n")
+	fmt.Printf("  failures; cancelled and timed-out steps are errors; failed warnings pass with their message.\n")
+	fmt.Printf("  When GITHUB_ACTIONS is true, the steps that did not complete and the failed verifications,\n")
+	fmt.Printf("  such as checksum mismatches, are also printed as ::error and ::warning annotations on\n")
+	fmt.Printf("  stderr, and a table of the step timings of each run is appended to $GITHUB_STEP_SUMMARY.\n")
+	fmt.Printf("  With --repeat N, the scenario runs N times as a benchmark, since the timings of a single\n")
+	fmt.Printf("  run, network-heavy pushes especially, are too noisy to compare servers. The mean, median,\n")
+	fmt.Printf("  standard deviation, minimum and maximum of each operation type's total time per run are\n")
+	fmt.Printf("  stored with the benchmark and printed; failed runs are left out of them. Later runs of a\n")
+	fmt.Printf("  scenario with a hosted git server recreate its repository, as --force does.\n")
+	fmt.Printf("  With --pipeline (or 'pipeline' in the config file), the run is made of the named steps\n")
+	fmt.Printf("  in the given order instead of the standard ones, numbered by position; NAME*N runs a\n")
+	fmt.Printf("  step N times. Besides the standard steps there are 'churn', which commits a rewritten\n")
+	fmt.Printf("  LFS file, and 'gc', which runs git gc and git lfs prune. See --list-steps.\n")
+	fmt.Printf("  With --track (or 'track_patterns' in the config file), the repository tracks the given\n")
+	fmt.Printf("  patterns with LFS instead of *.pdf, *.mov, *.avi, *.ogg, *.m4v and *.zip; use it with\n")
+	fmt.Printf("  test data made of your own file types. Concurrent clients push files with the first\n")
+	fmt.Printf("  *.EXT pattern's extension.\n")
+	fmt.Printf("  The test data root may hold named corpora, such as small, medium and large, each a\n")
+	fmt.Printf("  directory with a %s that lists the files of its v1/, v2/ and optional v3/\n", testdata.ManifestName)
+	fmt.Printf("  subdirectories; --corpus NAME selects one. Step 1 copies v1 and step 3 updates the files\n")
+	fmt.Printf("  with v2, then commits that and updates them with v3 if the corpus has one, before the\n")
+	fmt.Printf("  deletions and renames. A corpus may have its own %s. Without --corpus, the root\n", scenario.FixtureName)
+	fmt.Printf("  itself holds the standard 7 files, unless it has a %s. Runs are tagged with their\n", testdata.ManifestName)
+	fmt.Printf("  corpus, as corpus=NAME, and resumed runs use the corpus they started with.\n")
+	fmt.Printf("  Checksums leave out the files matching --checksum-exclude (or 'checksum_exclude' in the\n")
+	fmt.Printf("  config file), by default those operating systems and editors leave behind, such as\n")
+	fmt.Printf("  .DS_Store, Thumbs.db and *.swp, so they are not reported as added files. A pattern\n")
+	fmt.Printf("  matches a file's path or name, or one of its directories.\n")
+	fmt.Printf("  With --checksum-store manifest, the checksums of each step are written to\n")
+	fmt.Printf("  WORK_DIR/run-ID/%s/step-N.json instead of the database, and compared from there;\n", checksum.ManifestDirName)
+	fmt.Printf("  'both' writes them to both. Clients that cannot reach the shared database keep their\n")
+	fmt.Printf("  results locally and import the manifests later with lfst-import --manifests. Resume\n")
+	fmt.Printf("  such a run with the same --checksum-store.\n")
+	fmt.Printf("  Steps that checksum a repository again take the checksums of the files whose size and\n")
+	fmt.Printf("  modification time are unchanged from WORK_DIR/run-ID/%s, so large files that\n", checksum.CacheFileName)
+	fmt.Printf("  did not change are not read on every step; a resumed run keeps using it. --no-cache\n")
+	fmt.Printf("  reads every file, for verification that trusts no timestamps.\n")
+	fmt.Printf("  Each --assert, and each entry of 'assertions' in the data set's %s, compares a\n", scenario.FixtureName)
+	fmt.Printf("  recorded value with a constant or another value after every step that recorded it,\n")
+	fmt.Printf("  and is recorded as the verification 'assert: EXPRESSION'; a failed one fails the step\n")
+	fmt.Printf("  unless --lenient. Values: operations.NAME.FIELD (count, failed, duration_ms of the\n")
+	fmt.Printf("  longest, total_ms, bytes), sizes.LOCATION[.files] (client-lfs, server, ...),\n")
+	fmt.Printf("  steps.N.FIELD (duration_ms, rx_bytes, tx_bytes), diff(A,B).FIELD (count, added,\n")
+	fmt.Printf("  deleted, modified, renamed) and verifications.failed. Constants may be sizes (1GB)\n")
+	fmt.Printf("  or durations (5m, compared as milliseconds); operators are < <= > >= == !=.\n")
+	fmt.Printf("  Step 4 requires the clone to match the last step that changed the first repository,\n")
+	fmt.Printf("  and step 6 the pulled first repository to match the second client's step 5; any other\n")
+	fmt.Printf("  difference fails the step unless --lenient. Each --allow-diff, and each entry of\n")
+	fmt.Printf("  'allowed_differences' in %s, is a difference these checks expect and ignore,\n", scenario.FixtureName)
+	fmt.Printf("  written '[STEP] [CHANGE] PATTERN': STEP limits it to the check of that step, CHANGE to\n")
+	fmt.Printf("  added, deleted, modified, size-changed or renamed, and PATTERN matches a path or name.\n")
+	fmt.Printf("  With --nice and --ionice, git and git-lfs run at a lower CPU and I/O priority, so an\n")
+	fmt.Printf("  evaluation on a shared machine does not starve other workloads. --cpus and --memory run\n")
+	fmt.Printf("  each git command in a systemd-run scope with a cgroup CPU quota and memory limit, for\n")
+	fmt.Printf("  reproducible limited-resources comparisons. The limits are recorded in the run notes\n")
+	fmt.Printf("  and in %s.\n", scenario.RunMetadataName)
+	fmt.Printf("  With --sample-resources, the process tree of every git command (git-lfs included) is\n")
+	fmt.Printf("  sampled while it runs, 4 times a second by default: the CPU time, peak CPU use, peak\n")
+	fmt.Printf("  and average resident memory, and bytes read and written are recorded with each\n")
+	fmt.Printf("  operation (see: lfst-query operations --resources, lfst-query resources). Sampling\n")
+	fmt.Printf("  reads /proc on Linux; elsewhere only the totals are recorded.\n")
+	fmt.Printf("  Copying the test data, pushes, pulls and clones show their progress on standard error:\n")
+	fmt.Printf("  on a terminal a line with the bytes moved, the rate and the time left, redrawn in place.\n")
+	fmt.Printf("  With --progress, a plain line is printed every %s when standard error is not a\n", progress.PlainInterval)
+	fmt.Printf("  terminal, e.g. in a CI log; --no-progress, and --quiet unless --progress is given,\n")
+	fmt.Printf("  turn it off. The bytes come from rsync, git and git-lfs, which report the objects of\n")
+	fmt.Printf("  each phase of a transfer in turn.\n")
+	fmt.Printf("  Every push, pull and clone records the git-lfs transfer adapters that moved its objects,\n")
+	fmt.Printf("  read from git-lfs's trace (see: lfst-query operations). --transfer-adapter forces one, so\n")
+	fmt.Printf("  servers that offer faster adapters can be compared on equal terms: basic (HTTP(S) only,\n")
+	fmt.Printf("  also for SSH remotes), ssh (git-lfs-transfer, git-lfs 3.0+, SSH scenarios only),\n")
+	fmt.Printf("  multipart (offers the server multipart-basic, run by --transfer-agent) or standalone\n")
+	fmt.Printf("  (--transfer-agent moves the objects without a server, e.g. lfs-folderstore). A step\n")
+	fmt.Printf("  whose operations used another adapter, because the server chose one it supports, gets\n")
+	fmt.Printf("  a 'transfer-adapter' warning.\n")
+	fmt.Printf("  With --netem (or 'network.SCENARIO' in the config file), the steps that push, clone\n")
+	fmt.Printf("  and pull run under emulated WAN conditions: tc/netem adds half the round-trip time and\n")
+	fmt.Printf("  caps the bandwidth in each direction, optionally dropping packets. The interface shaped\n")
+	fmt.Printf("  is --interface, else lo when the LFS server is on this machine, else the default route's;\n")
+	fmt.Printf("  all traffic of that interface is shaped while such a step runs. Presets: %s.\n", strings.Join(netem.PresetNames(), ", "))
+	fmt.Printf("  Shaping needs Linux, tc and ip (iproute2), and root or passwordless sudo. The profile is\n")
+	fmt.Printf("  recorded with the run (see: lfst-run show) and reapplied when the run is resumed. A run\n")
+	fmt.Printf("  that is killed leaves the shaping in place until the next shaped run; remove it with\n")
+	fmt.Printf("  sudo tc qdisc del dev IFACE root.\n")
+	fmt.Printf("  Every step records an 'interference' verification: a warning if antivirus scanners or\n")
+	fmt.Printf("  file indexers are running (including Windows Defender under WSL), if other processes\n")
+	fmt.Printf("  opened files in the work directory during the step, or if small file operations in\n")
+	fmt.Printf("  the work directory are slow enough to suggest on-access scanning. Such timings are\n")
+	fmt.Printf("  often wildly unrepresentative; exclude the work directory from scanning.\n")
+	fmt.Printf("  With --server-service, the restarts, stops and out-of-memory kills of the LFS server's\n")
+	fmt.Printf("  systemd unit or Docker container are read after every step, over SSH if a host is given,\n")
+	fmt.Printf("  and recorded as a 'server-events' warning; operations they overlap, and the error of a\n")
+	fmt.Printf("  failed step, name them as the likely cause (see: lfst-query operations, lfst-query stats).\n")
+	fmt.Printf("  With --snapshots, repo1 and repo2 are saved after every completed step in\n")
+	fmt.Printf("  WORK_DIR/%s/RUN_ID/step-N, as copy-on-write clones where the file system supports\n", scenario.SnapshotsDir)
+	fmt.Printf("  them (btrfs, XFS, ZFS 2.2+, overlayfs on one of those) or else as tar archives.\n")
+	fmt.Printf("  --resume RUN_ID --rerun-step N then restores the snapshot of step N-1 and runs step N\n")
+	fmt.Printf("  again, without rerunning the steps before it; only the local repositories are restored.\n\n")
+
+	fmt.Printf("USAGE:\n")
+	fmt.Printf("  lfst-scenario [OPTIONS] SCENARIO_ID\n\n")
+
+	fmt.Printf("OPTIONS:\n")
+	fs.PrintDefaults()
+
+	fmt.Printf("\nEXAMPLES:\n")
+	fmt.Printf("  # List available scenarios\n")
+	fmt.Printf("  lfst-scenario --list\n\n")
+
+	fmt.Printf("  # Run scenario 6 (LFS Test Server - HTTP)\n")
+	fmt.Printf("  lfst-scenario 6\n\n")
+
+	fmt.Printf("  # Run scenario 15 against a Gitea server (set gitea_url and gitea_token first)\n")
+	fmt.Printf("  lfst-scenario 15\n\n")
+
+	fmt.Printf("  # Store the LFS objects on GitHub itself (gh auth login first; uses the account's LFS quota)\n")
+	fmt.Printf("  lfst-scenario 16\n\n")
+
+	fmt.Printf("  # Compare Bitbucket and Azure DevOps (set bitbucket_* and azure_devops_* first)\n")
+	fmt.Printf("  lfst-scenario 17 && lfst-scenario 18\n\n")
+
+	fmt.Printf("  # Benchmark scenario 6 over five runs, then compare its medians with scenario 13's\n")
+	fmt.Printf("  lfst-scenario --repeat 5 6 && lfst-scenario --repeat 5 13\n")
+	fmt.Printf("  lfst-query benchmarks\n\n")
+
+	fmt.Printf("  # Run with debug output\n")
+	fmt.Printf("  lfst-scenario -d 6\n\n")
+
+	fmt.Printf("  # Run in an air-gapped lab; scenarios that need GitHub fail immediately\n")
+	fmt.Printf("  lfst-scenario --offline 6\n\n")
+
+	fmt.Printf("  # Sanity-check an installation in seconds with a tiny generated corpus\n")
+	fmt.Printf("  lfst-scenario --mini\n\n")
+
+	fmt.Printf("  # Resume run 12 after a crash or failure, skipping completed steps\n")
+	fmt.Printf("  lfst-scenario --resume 12\n\n")
+
+	fmt.Printf("  # Keep snapshots of the repositories, then run step 5 of that run again after a fix\n")
+	fmt.Printf("  lfst-scenario --snapshots 6\n")
+	fmt.Printf("  lfst-scenario --resume 12 --rerun-step 5\n\n")
+
+	fmt.Printf("  # Keep the checksums of an offline laptop in manifest files\n")
+	fmt.Printf("  lfst-scenario --checksum-store manifest 1\n\n")
+
+	fmt.Printf("  # Read every file at every step, e.g. on a file system with unreliable timestamps\n")
+	fmt.Printf("  lfst-scenario --no-cache 6\n\n")
+
+	fmt.Printf("  # Require pushes under five minutes and an identical clone\n")
+	fmt.Printf("  lfst-scenario --assert 'operations.push.duration_ms < 5m' --assert 'diff(3,4).count == 0' 6\n\n")
+
+	fmt.Printf("  # Let the pull of step 6 bring in a changed build log, and nothing else\n")
+	fmt.Printf("  lfst-scenario --allow-diff '6 modified build.log' 6\n\n")
+
+	fmt.Printf("  # Compare Giftless with and without multipart transfers\n")
+	fmt.Printf("  lfst-scenario --transfer-adapter basic 9\n")
+	fmt.Printf("  lfst-scenario --transfer-adapter multipart --transfer-agent 'lfs-multipart-agent --verbose' 9\n\n")
+
+	fmt.Printf("  # Keep going after a failed verification; failures are recorded in the database\n")
+	fmt.Printf("  lfst-scenario --lenient 6\n\n")
+
+	fmt.Printf("  # Check that Rudolfs stored every pushed object in its MinIO bucket\n")
+	fmt.Printf("  lfst-scenario --s3 s3://lfs --s3-endpoint http://gojira:9000 13\n\n")
+
+	fmt.Printf("  # Find out whether failed pushes coincide with Rudolfs restarting or running out of memory\n")
+	fmt.Printf("  lfst-scenario --server-service gojira:docker:rudolfs 13\n\n")
+
+	fmt.Printf("  # Add a step in which 8 clients push 50MB each to the same server at once\n")
+	fmt.Printf("  lfst-scenario --clients 8 --client-size 50MB 6\n\n")
+
+	fmt.Printf("  # Find out which servers support git lfs lock (see: lfst-query locks)\n")
+	fmt.Printf("  lfst-scenario --locking --matrix all\n\n")
+
+	fmt.Printf("  # Find out whether Giftless resumes an 8GB upload interrupted after 30 seconds\n")
+	fmt.Printf("  lfst-scenario --large-file 8GB --large-file-interrupt 30s --transfer-adapter multipart --transfer-agent lfs-multipart-agent 9\n\n")
+
+	fmt.Printf("  # Measure the overhead of 50 small commits, each pushed, and the batch requests they cost\n")
+	fmt.Printf("  lfst-scenario --cadence 50 --lfs-proxy 6\n\n")
+
+	fmt.Printf("  # Record what the LFS server answers to every batch request (see: lfst-query batch)\n")
+	fmt.Printf("  lfst-scenario --lfs-proxy 6\n\n")
+
+	fmt.Printf("  # Push, clone and pull over git's own protocol through git daemon\n")
+	fmt.Printf("  lfst-scenario --git-daemon 1\n\n")
+
+	fmt.Printf("  # Skip untracking, add 10 commits of a rewritten LFS file, then clone and collect garbage\n")
+	fmt.Printf("  lfst-scenario --pipeline setup,push,modify,churn*10,clone,gc 6\n\n")
+
+	fmt.Printf("  # Evaluate Photoshop documents and ONNX models from your own test data\n")
+	fmt.Printf("  LFS_TEST_DATA=/data/assets lfst-scenario --track '*.psd,*.onnx' 6\n\n")
+
+	fmt.Printf("  # Run scenario 6 with the large corpus, then compare the servers on it\n")
+	fmt.Printf("  lfst-scenario --corpus large 6\n")
+	fmt.Printf("  lfst-query scoreboard --tag corpus=large\n\n")
+
+	fmt.Printf("  # Stay out of the way of other workloads on a shared build machine\n")
+	fmt.Printf("  lfst-scenario --nice 19 --ionice idle 6\n\n")
+
+	fmt.Printf("  # Find out which server makes git-lfs use the most memory\n")
+	fmt.Printf("  lfst-scenario --sample-resources --matrix 6,13\n")
+	fmt.Printf("  lfst-query resources --operation push\n\n")
+
+	fmt.Printf("  # Compare how Rudolfs and LFS Test Server cope with a 50ms, 100Mbit/s WAN link\n")
+	fmt.Printf("  sudo lfst-scenario --netem rtt=50ms,rate=100mbit --matrix 6,13\n\n")
+
+	fmt.Printf("  # Show how far the copy and the pushes have got in a CI log\n")
+	fmt.Printf("  lfst-scenario --progress 6 2>&1 | tee scenario.log\n\n")
+
+	fmt.Printf("  # Compare servers on a client limited to 2 CPUs and 4GB of memory\n")
+	fmt.Printf("  lfst-scenario --cpus 2 --memory 4GB --matrix 6,13\n\n")
+
+	fmt.Printf("  # Run every scenario unattended; exit status is 1 if any failed\n")
+	fmt.Printf("  lfst-scenario --matrix all\n\n")
+
+	fmt.Printf("  # Run every scenario unattended, giving up on any that takes more than 2 hours\n")
+	fmt.Printf("  lfst-scenario --matrix all --max-duration 2h\n\n")
+	fmt.Printf("  # Run every scenario in a CI job that shows the failed steps as test results\n")
+	fmt.Printf("  lfst-scenario --matrix all --junit results.xml\n\n")
+
+	fmt.Printf("  # Run a subset of scenarios in sequence\n")
+	fmt.Printf("  lfst-scenario --matrix 1,6,13\n\n")
+
+	fmt.Printf("  # Run scenario 6 with the work directory, servers and time limit of the wan profile\n")
+	fmt.Printf("  lfst-scenario --profile wan 6\n\n")
+
+	fmt.Printf("  # Use custom work directory\n")
+	fmt.Printf("  lfst-scenario --work-dir /mnt/o/lfs_test 6\n\n")
+
+	fmt.Printf("NOTES:\n")
+	fmt.Printf("  - Requires ~2.4GB of test data (set LFS_TEST_DATA environment variable)\n")
+	fmt.Printf("  - Work directory should have at least 5GB free space\n")
+	fmt.Printf("  - For remote scenarios, requires passwordless SSH to gojira\n")
+	fmt.Printf("  - Offline mode (--offline, config 'offline', or LFS_OFFLINE) never uses gh/GitHub\n")
+	fmt.Printf("  - Each run creates a test_run record in the database\n")
+	fmt.Printf("  - All operations are timed with millisecond precision\n")
+	fmt.Printf("  - Checksums are computed and stored for each step\n")
+	fmt.Printf("  - Resume a run with the pipeline and tracking patterns it started with\n")
+	fmt.Printf("  - --server-service needs journalctl or docker on the server, and server and client clocks in sync\n")
+	fmt.Printf("  - Tar snapshots copy both repositories after every step; mind the free space\n")
+	fmt.Printf("  - --ionice needs ionice (util-linux); --cpus and --memory need a systemd user session\n\n")
+}
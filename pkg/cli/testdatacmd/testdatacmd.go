@@ -0,0 +1,627 @@
+// Package testdatacmd is lfst-testdata, which the lfst command also runs as lfst testdata
+package testdatacmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mslinn/git-lfs-test/pkg/config"
+	"github.com/mslinn/git-lfs-test/pkg/download"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+	"github.com/mslinn/git-lfs-test/pkg/workerpool"
+	"github.com/spf13/pflag"
+)
+
+var version string // Set by Command
+
+// Step represents a test data step directory
+type Step struct {
+	Name      string
+	Downloads []download.FileDownload
+	Readme    string
+	GitIgnore string
+}
+
+// steps defines the test data step directories and their downloads.
+// The upstream sources publish no digests, so SHA256 is left empty; those files are
+// verified against the SHA256SUMS manifest recorded when they were first downloaded.
+var steps = []Step{
+	{
+		Name: "step1",
+		GitIgnore: `.cksum_output
+`,
+		Readme: "This is README.md for step 1\n",
+		Downloads: []download.FileDownload{
+			{
+				URL:      "https://download.blender.org/peach/bigbuckbunny_movies/BigBuckBunny_640x360.m4v",
+				FileName: "video1.m4v",
+			},
+			{
+				URL:      "https://download.blender.org/peach/bigbuckbunny_movies/big_buck_bunny_480p_h264.mov",
+				FileName: "video2.mov",
+			},
+			{
+				URL:      "https://download.blender.org/peach/bigbuckbunny_movies/big_buck_bunny_480p_stereo.avi",
+				FileName: "video3.avi",
+			},
+			{
+				URL:      "https://download.blender.org/peach/bigbuckbunny_movies/big_buck_bunny_720p_stereo.ogg",
+				FileName: "video4.ogg",
+			},
+			{
+				URL:      "https://mattmahoney.net/dc/enwik9.zip",
+				FileName: "zip1.zip",
+			},
+			{
+				URL:      "https://www.gutenberg.org/cache/epub/feeds/rdf-files.tar.zip",
+				FileName: "zip2.zip",
+			},
+			{
+				URL:      "https://files.testfile.org/PDF/100MB-TESTFILE.ORG.pdf",
+				FileName: "pdf1.pdf",
+			},
+		},
+	},
+	{
+		Name:   "step2",
+		Readme: "This is README.md for step 2\n",
+		Downloads: []download.FileDownload{
+			{
+				URL:      "http://ipv4.download.thinkbroadband.com/200MB.zip",
+				FileName: "zip1.zip",
+			},
+			{
+				URL:      "https://download.blender.org/peach/bigbuckbunny_movies/big_buck_bunny_720p_h264.mov",
+				FileName: "video2.mov",
+			},
+			{
+				URL:      "https://download.blender.org/peach/bigbuckbunny_movies/big_buck_bunny_720p_stereo.avi",
+				FileName: "video3.avi",
+			},
+			{
+				URL:      "https://files.testfile.org/PDF/200MB-TESTFILE.ORG.pdf",
+				FileName: "pdf1.pdf",
+			},
+		},
+	},
+	{
+		Name:      "step3",
+		Readme:    "This is README.md for step 3\n",
+		Downloads: []download.FileDownload{},
+	},
+}
+
+// Commands returns the flags of each subcommand of lfst-testdata, for completion
+func Commands() map[string]*pflag.FlagSet {
+	generate := pflag.NewFlagSet("generate", pflag.ContinueOnError)
+	generateCommand(generate, "")
+	verify := pflag.NewFlagSet("verify", pflag.ContinueOnError)
+	verifyCommand(verify, "")
+	return map[string]*pflag.FlagSet{"generate": generate, "verify": verify}
+}
+
+// Main runs lfst-testdata with args, the command line after the program name
+func Main(v string, args []string) {
+	run := Command(v, pflag.CommandLine)
+	pflag.CommandLine.Parse(args)
+	run(pflag.Args())
+}
+
+// Command defines the flags of lfst-testdata on fs and returns the function that runs it
+// with the arguments left once fs is parsed; lfst testdata registers them on its subcommand
+func Command(v string, fs *pflag.FlagSet) func(args []string) {
+	version = v
+
+	// Define flags
+	var (
+		showVersion bool
+		showHelp    bool
+		quiet       bool
+		noColor     bool
+		debug       bool
+		destPath    string
+	)
+
+	fs.BoolVarP(&showVersion, "version", "V", false, "Show version and exit")
+	fs.BoolVarP(&showHelp, "help", "h", false, "Show this help message")
+	fs.BoolVarP(&quiet, "quiet", "q", false, "Print errors only")
+	fs.BoolVar(&noColor, "no-color", false, "Plain output without colors (automatic when not a terminal)")
+	fs.BoolVarP(&debug, "debug", "d", false, "Enable debug output")
+	var logFile, logFormat string
+	fs.StringVar(&logFile, "log-file", "", "Also append every message, debug included, to this file")
+	fs.StringVar(&logFormat, "log-format", "", "Format of the log file: text or json (default text)")
+	fs.StringVar(&destPath, "dest", "", "Destination directory (default: from config or $work/git/git_lfs_test_data)")
+
+	// Stop parsing at the first non-flag argument (the optional subcommand)
+	fs.SetInterspersed(false)
+
+	return func(args []string) {
+		// Handle version
+		if showVersion {
+			fmt.Printf("lfst-testdata version %s\n", version)
+			os.Exit(0)
+		}
+
+		// Handle help
+		if showHelp {
+			printHelp()
+			os.Exit(0)
+		}
+
+		if err := term.Setup(quiet, noColor); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := log.Setup(debug, logFile, logFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(args) > 0 {
+			switch args[0] {
+			case "generate":
+				generateCommand(pflag.NewFlagSet("generate", pflag.ExitOnError), destPath)(args[1:], debug)
+				return
+			case "verify":
+				os.Exit(verifyCommand(pflag.NewFlagSet("verify", pflag.ExitOnError), destPath)(args[1:], debug))
+			default:
+				fmt.Fprintf(os.Stderr, "Error: unknown subcommand '%s' (see lfst-testdata --help)\n", args[0])
+				os.Exit(1)
+			}
+		}
+
+		// Check dependencies
+		if err := checkDependencies(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Determine destination directory
+		destPath = resolveDest(destPath)
+
+		log.Debugf("Destination directory: %s\n", destPath)
+
+		// Download files for each step
+		for _, step := range steps {
+			stepDir := filepath.Join(destPath, step.Name)
+
+			log.Debugf("\nProcessing %s/\n", step.Name)
+
+			// Create step directory
+			if err := os.MkdirAll(stepDir, 0755); err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating directory %s: %v\n", stepDir, err)
+				os.Exit(1)
+			}
+
+			// Write .gitignore if specified
+			if step.GitIgnore != "" {
+				gitignorePath := filepath.Join(stepDir, ".gitignore")
+				if err := os.WriteFile(gitignorePath, []byte(step.GitIgnore), 0644); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing .gitignore: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			// Write README.md
+			readmePath := filepath.Join(stepDir, "README.md")
+			if err := os.WriteFile(readmePath, []byte(step.Readme), 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing README.md: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Download files
+			for _, dl := range step.Downloads {
+				destFile := filepath.Join(stepDir, dl.FileName)
+				_, err := download.DownloadVerified(dl, destFile, debug)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error downloading %s: %v\n", dl.FileName, err)
+					os.Exit(1)
+				}
+			}
+
+			// Record digests of newly downloaded files so later runs can detect corruption
+			if err := recordManifest(stepDir, step); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Print summary
+		fmt.Printf("\nDownload complete.\n")
+		fmt.Printf("Here are lists of the initial set of downloaded files and sizes,\n")
+		fmt.Printf("ordered by name. The last line in each listing shows the total\n")
+		fmt.Printf("size of the files.\n\n")
+		fmt.Printf("Some files might be deleted by each step; those are not shown here.\n\n")
+
+		// Show disk usage for each step
+		for _, step := range steps {
+			stepDir := filepath.Join(destPath, step.Name)
+			fmt.Printf("\n%s:\n", step.Name)
+			showDiskUsage(stepDir)
+		}
+	}
+}
+
+// resolveDest returns the destination directory from --dest, the config file, or $work, exiting if none is set
+func resolveDest(destPath string) string {
+	if destPath != "" {
+		return destPath
+	}
+
+	cfg, err := config.Load()
+	if err == nil && cfg.TestDataPath != "" {
+		return cfg.GetTestDataPath()
+	}
+	if workDir := os.Getenv("work"); workDir != "" {
+		return filepath.Join(workDir, "git", "git_lfs_test_data")
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: destination directory not specified.\n")
+	fmt.Fprintf(os.Stderr, "Use --dest flag, set LFS_TEST_DATA environment variable,\n")
+	fmt.Fprintf(os.Stderr, "or define test_data in ~/.lfs-test-config\n")
+	os.Exit(1)
+	return ""
+}
+
+// generateCommand writes a deterministic synthetic corpus instead of downloading test data
+func generateCommand(fs *pflag.FlagSet, destPath string) func(args []string, debug bool) {
+	dest := fs.String("dest", destPath, "Destination directory (default: from config)")
+	size := fs.String("size", "1GB", "Total size of all files (e.g. 500MB, 2GB)")
+	files := fs.Int("files", 10, "Number of files")
+	distribution := fs.String("distribution", "fixed", "File size distribution: fixed, uniform, or lognormal")
+	compressible := fs.String("compressible", "0%", "Percentage of each file that compresses well (e.g. 30%)")
+	seed := fs.Int64("seed", 1, "Random seed; the same options and seed always produce identical files")
+	large := fs.String("large", "", "Write a single sparse file of this size instead (e.g. 8GB)")
+
+	return func(args []string, debug bool) {
+		fs.Parse(args)
+
+		if *large != "" {
+			generateLarge(resolveDest(*dest), *large, *seed)
+			return
+		}
+
+		totalSize, err := testdata.ParseSize(*size)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fraction, err := testdata.ParsePercent(*compressible)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		dir := resolveDest(*dest)
+		log.Debugf("Generating %d files (%s, %s distribution, %.0f%% compressible, seed %d) in %s\n", *files, testdata.FormatSize(totalSize), *distribution, fraction*100, *seed, dir)
+
+		generated, err := testdata.GenerateCorpus(dir, testdata.GenerateOptions{
+			TotalSize:    totalSize,
+			Files:        *files,
+			Distribution: *distribution,
+			Compressible: fraction,
+			Seed:         *seed,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating test data: %v\n", err)
+			os.Exit(1)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "FILE\tSIZE\n")
+		fmt.Fprintf(w, "----\t----\n")
+		for _, f := range generated {
+			fmt.Fprintf(w, "%s\t%s\n", f.Name, testdata.FormatSize(f.Size))
+		}
+		w.Flush()
+
+		fmt.Printf("\nGenerated %d files (%s) in %s\n", len(generated), testdata.FormatSize(totalSize), dir)
+	}
+}
+
+// generateLarge writes the single sparse file of the large file profile
+func generateLarge(dir, size string, seed int64) {
+	largeSize, err := testdata.ParseSize(size)
+	if err != nil || largeSize == 0 {
+		fmt.Fprintf(os.Stderr, "Error: invalid --large '%s'\n", size)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+
+	path := filepath.Join(dir, "large.bin")
+	log.Debugf("Writing the %s sparse file %s (seed %d)\n", testdata.FormatSize(largeSize), path, seed)
+	if err := testdata.WriteSparseFile(path, largeSize, seed); err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating test data: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Generated %s (%s, sparse)\n", path, testdata.FormatSize(largeSize))
+}
+
+// recordManifest adds the digests of downloaded files missing from a step's SHA256SUMS manifest
+func recordManifest(stepDir string, step Step) error {
+	manifestPath := filepath.Join(stepDir, download.ManifestName)
+	manifest, err := download.ReadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, dl := range step.Downloads {
+		if manifest[dl.FileName] != "" {
+			continue
+		}
+		digest := dl.SHA256
+		if digest == "" {
+			if digest, err = download.FileSHA256(filepath.Join(stepDir, dl.FileName)); err != nil {
+				return err
+			}
+		}
+		manifest[dl.FileName] = strings.ToLower(digest)
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return download.WriteManifest(manifestPath, manifest)
+}
+
+// verifyEntry is the verification state of one downloaded file
+type verifyEntry struct {
+	step     Step
+	dl       download.FileDownload
+	path     string
+	expected string // Embedded digest, else the manifest digest
+	actual   string
+	status   string // 'ok', 'missing', 'corrupt', 'unverified', 'repaired', 'failed'
+	err      error
+}
+
+// verifyCommand checks downloaded files against their expected SHA-256 digests and
+// re-downloads missing or corrupted ones. Its function returns the process exit status.
+func verifyCommand(fs *pflag.FlagSet, destPath string) func(args []string, debug bool) int {
+	dest := fs.String("dest", destPath, "Test data directory (default: from config)")
+	noRepair := fs.Bool("no-repair", false, "Report problems without re-downloading files")
+	workers := fs.Int("workers", 0, "Files to hash concurrently (default: one per CPU)")
+
+	return func(args []string, debug bool) int {
+		fs.Parse(args)
+
+		dir := resolveDest(*dest)
+		manifests := make(map[string]download.Manifest)
+
+		var entries []*verifyEntry
+		for _, step := range steps {
+			manifest, err := download.ReadManifest(filepath.Join(dir, step.Name, download.ManifestName))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			manifests[step.Name] = manifest
+
+			for _, dl := range step.Downloads {
+				expected := strings.ToLower(dl.SHA256)
+				if expected == "" {
+					expected = manifest[dl.FileName]
+				}
+				entries = append(entries, &verifyEntry{
+					step:     step,
+					dl:       dl,
+					path:     filepath.Join(dir, step.Name, dl.FileName),
+					expected: expected,
+				})
+			}
+		}
+
+		opts := workerpool.Options{Workers: *workers}
+		if debug {
+			opts.Progress = os.Stdout
+		}
+		hashEntries(entries, opts)
+
+		// Re-download missing and corrupted files one at a time
+		if !*noRepair {
+			for _, e := range entries {
+				if e.status != "missing" && e.status != "corrupt" {
+					continue
+				}
+				os.Remove(e.path)
+				if _, err := download.DownloadVerified(e.dl, e.path, debug); err != nil {
+					e.status, e.err = "failed", err
+					continue
+				}
+				hashEntries([]*verifyEntry{e}, workerpool.Options{})
+				if e.status == "ok" || e.status == "unverified" {
+					e.status = "repaired"
+				} else {
+					e.status = "failed"
+				}
+			}
+		}
+
+		// Files without a known digest are trusted on first verification
+		for _, e := range entries {
+			if (e.status == "unverified" || e.status == "repaired") && e.actual != "" {
+				manifests[e.step.Name][e.dl.FileName] = e.actual
+			}
+		}
+		for _, step := range steps {
+			if len(manifests[step.Name]) == 0 {
+				continue
+			}
+			if err := download.WriteManifest(filepath.Join(dir, step.Name, download.ManifestName), manifests[step.Name]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+		}
+
+		// Print results
+		problems := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "FILE\tSTATUS\tSHA-256\n")
+		fmt.Fprintf(w, "----\t------\t-------\n")
+		for _, e := range entries {
+			detail := e.actual
+			switch e.status {
+			case "missing", "corrupt", "failed":
+				problems++
+				if e.err != nil {
+					detail = e.err.Error()
+				} else if e.status == "corrupt" {
+					detail = fmt.Sprintf("expected %s, got %s", e.expected, e.actual)
+				}
+			}
+			fmt.Fprintf(w, "%s/%s\t%s\t%s\n", e.step.Name, e.dl.FileName, e.status, detail)
+		}
+		w.Flush()
+
+		if problems > 0 {
+			fmt.Printf("\n%d of %d files failed verification\n", problems, len(entries))
+			return 1
+		}
+		fmt.Printf("\nAll %d files verified\n", len(entries))
+		return 0
+	}
+}
+
+// hashEntries computes the digest of each entry's file in parallel and sets its status
+func hashEntries(entries []*verifyEntry, opts workerpool.Options) {
+	var totalBytes int64
+	for _, e := range entries {
+		if info, err := os.Stat(e.path); err == nil {
+			totalBytes += info.Size()
+		}
+	}
+
+	progress := workerpool.NewProgress(opts.Progress, "Verifying", len(entries), totalBytes)
+	workerpool.Run(len(entries), opts, func(i int) error {
+		e := entries[i]
+		info, err := os.Stat(e.path)
+		if err != nil {
+			e.status, e.actual = "missing", ""
+			progress.Add(0)
+			return nil
+		}
+
+		digest, err := download.FileSHA256(e.path)
+		switch {
+		case err != nil:
+			e.status, e.err = "failed", err
+		case e.expected == "":
+			e.status, e.actual = "unverified", digest
+		case digest != e.expected:
+			e.status, e.actual = "corrupt", digest
+		default:
+			e.status, e.actual = "ok", digest
+		}
+		progress.Add(info.Size())
+		return nil
+	})
+	if len(entries) > 1 {
+		progress.Finish()
+	}
+}
+
+func printHelp() {
+	fmt.Printf("lfst-testdata - Download Git LFS test data files\n\n")
+	fmt.Printf("Version: %s\n\n", version)
+	fmt.Printf("DESCRIPTION:\n")
+	fmt.Printf("  Downloads test data files for Git LFS evaluation. Creates three step\n")
+	fmt.Printf("  directories (step1, step2, step3) with test files of various sizes.\n")
+	fmt.Printf("  Files are downloaded from Big Buck Bunny, Project Gutenberg, and other\n")
+	fmt.Printf("  public sources. Total download size is approximately 2.5 GB.\n\n")
+
+	fmt.Printf("  The generate command instead writes a deterministic synthetic corpus of a\n")
+	fmt.Printf("  chosen size, for offline use or reproducible, size-controlled benchmarks.\n")
+	fmt.Printf("  With --large it writes a single file of that size instead, sparse so that it\n")
+	fmt.Printf("  takes little disk space, for testing how servers take very large objects.\n\n")
+
+	fmt.Printf("USAGE:\n")
+	fmt.Printf("  lfst-testdata [OPTIONS]\n")
+	fmt.Printf("  lfst-testdata [OPTIONS] generate [GENERATE OPTIONS]\n")
+	fmt.Printf("  lfst-testdata [OPTIONS] verify [VERIFY OPTIONS]\n\n")
+
+	fmt.Printf("OPTIONS:\n")
+	fmt.Printf("  -h, --help         Show this help message\n")
+	fmt.Printf("  -V, --version      Show version\n")
+	fmt.Printf("  -d, --debug        Enable debug output\n")
+	fmt.Printf("  --log-file PATH    Also append every message, debug included, to PATH\n")
+	fmt.Printf("  --log-format FMT   Format of the log file: text or json (default text)\n")
+	fmt.Printf("  --dest PATH        Destination directory (default: from config)\n\n")
+
+	fmt.Printf("GENERATE OPTIONS:\n")
+	fmt.Printf("  --size SIZE            Total size of all files (default: 1GB)\n")
+	fmt.Printf("  --files N              Number of files (default: 10)\n")
+	fmt.Printf("  --distribution NAME    File sizes: fixed, uniform, or lognormal (default: fixed)\n")
+	fmt.Printf("  --compressible PCT     Percentage of each file that compresses well (default: 0%%)\n")
+	fmt.Printf("  --seed N               Random seed (default: 1)\n")
+	fmt.Printf("  --large SIZE           Write only large.bin, a sparse file of SIZE (e.g. 8GB)\n")
+	fmt.Printf("  --dest PATH            Destination directory\n\n")
+
+	fmt.Printf("VERIFY OPTIONS:\n")
+	fmt.Printf("  Checks every downloaded file against its SHA-256 digest and re-downloads\n")
+	fmt.Printf("  missing or corrupted files. Digests are recorded in SHA256SUMS in each step\n")
+	fmt.Printf("  directory when files are first downloaded. Exit status is 1 if any file is bad.\n")
+	fmt.Printf("  --no-repair            Report problems without re-downloading\n")
+	fmt.Printf("  --workers N            Files to hash concurrently (default: one per CPU)\n")
+	fmt.Printf("  --dest PATH            Test data directory\n\n")
+
+	fmt.Printf("CONFIGURATION:\n")
+	fmt.Printf("  The destination directory is determined in this order:\n")
+	fmt.Printf("  1. --dest flag\n")
+	fmt.Printf("  2. test_data from ~/.lfs-test-config\n")
+	fmt.Printf("  3. $work/git/git_lfs_test_data (if $work is set)\n\n")
+
+	fmt.Printf("EXAMPLES:\n")
+	fmt.Printf("  # Download to default location\n")
+	fmt.Printf("  lfst-testdata\n\n")
+
+	fmt.Printf("  # Download to specific directory\n")
+	fmt.Printf("  lfst-testdata --dest /tmp/lfs-test-data\n\n")
+
+	fmt.Printf("  # Download with debug output\n")
+	fmt.Printf("  lfst-testdata --debug\n\n")
+
+	fmt.Printf("  # Check downloaded files and re-download corrupted ones\n")
+	fmt.Printf("  lfst-testdata verify\n\n")
+
+	fmt.Printf("  # Generate 50 files totalling 2GB with realistic size spread\n")
+	fmt.Printf("  lfst-testdata generate --size 2GB --files 50 --distribution lognormal --compressible 30%%\n\n")
+
+	fmt.Printf("  # Write one 8GB sparse file for large upload tests\n")
+	fmt.Printf("  lfst-testdata generate --large 8GB --dest /tmp/lfs-large\n\n")
+
+	fmt.Printf("DOCUMENTATION:\n")
+	fmt.Printf("  https://www.mslinn.com/git/5600-git-lfs-evaluation.html#git_lfs_test_data\n\n")
+}
+
+func checkDependencies() error {
+	// Check for curl (used as fallback in download package)
+	if _, err := exec.LookPath("curl"); err != nil {
+		return fmt.Errorf("curl is required but not found in PATH")
+	}
+	return nil
+}
+
+func showDiskUsage(dir string) {
+	// Use du command to show disk usage (similar to original bash script)
+	cmd := exec.Command("du", "-ah", dir)
+	output, err := cmd.Output()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running du: %v\n", err)
+		return
+	}
+
+	// Filter output to remove leading "./" from paths
+	lines := string(output)
+	fmt.Print(lines)
+}
@@ -0,0 +1,376 @@
+// Package completion writes the shell completion scripts of lfst. The commands and flags
+// of each lfst-* tool are read from the flag sets they are registered on, so the scripts
+// follow the tools; scenario and run IDs are looked up by the scripts when they complete.
+package completion
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/pflag"
+)
+
+// Shells that completion scripts can be written for
+var Shells = []string{"bash", "zsh", "fish"}
+
+// Kinds of values the scripts look up with 'lfst completion --list KIND'
+const (
+	Runs      = "runs"
+	Scenarios = "scenarios"
+)
+
+// Kinds are the values of 'lfst completion --list'
+var Kinds = []string{Runs, Scenarios}
+
+// RunFlags take a run ID, ScenarioFlags a scenario ID, in any tool
+var (
+	RunFlags      = []string{"--run-id", "--resume", "--cancel"}
+	ScenarioFlags = []string{"--scenario"}
+)
+
+// Command is a subcommand of lfst, i.e. an lfst-* tool
+type Command struct {
+	Name        string
+	Description string
+	Commands    []string // The tool's own commands, e.g. stats for lfst query
+	Flags       []string // Long flags, e.g. --run-id
+	// Positional is the kind of value of the tool's arguments, after its command if it has
+	// commands: Runs, Scenarios or empty
+	Positional string
+}
+
+// Value is a scenario or run ID with a description
+type Value struct {
+	Name        string
+	Description string
+}
+
+// scenarioLine is a row of lfst-scenario --list, e.g. "6   lfs-test-server    http ..."
+var scenarioLine = regexp.MustCompile(`^(\d+)\s+(.*)$`)
+
+// Flags returns the long flags registered on fs, sorted, e.g. --run-id
+func Flags(fs *pflag.FlagSet) []string {
+	var flags []string
+	fs.VisitAll(func(f *pflag.Flag) {
+		if !f.Hidden {
+			flags = append(flags, "--"+f.Name)
+		}
+	})
+	sort.Strings(flags)
+	return flags
+}
+
+// FlagSets returns the flags of each of commands, which define them on the flag set they
+// are given and return the function that runs them, without running any
+func FlagSets[F any](commands map[string]func(fs *pflag.FlagSet) F) map[string]*pflag.FlagSet {
+	flagSets := make(map[string]*pflag.FlagSet, len(commands))
+	for name, command := range commands {
+		fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+		command(fs)
+		flagSets[name] = fs
+	}
+	return flagSets
+}
+
+// ParseScenarioList returns the scenarios that lfst-scenario --list prints
+func ParseScenarioList(output string) []Value {
+	var values []Value
+	for _, line := range strings.Split(output, "\n") {
+		if m := scenarioLine.FindStringSubmatch(line); m != nil {
+			values = append(values, Value{Name: m[1], Description: strings.Join(strings.Fields(m[2]), " ")})
+		}
+	}
+	return values
+}
+
+// WriteValues writes values one per line, each name followed by a tab and its
+// description, as the scripts expect from 'lfst completion --list'
+func WriteValues(w io.Writer, values []Value) error {
+	for _, v := range values {
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", v.Name, v.Description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagValues returns the kind of value a flag takes, Runs or Scenarios, or "" for others
+func flagValues(flag string) string {
+	switch {
+	case slices.Contains(RunFlags, flag):
+		return Runs
+	case slices.Contains(ScenarioFlags, flag):
+		return Scenarios
+	}
+	return ""
+}
+
+// script is what the templates are executed with
+type script struct {
+	Commands      []Command
+	GlobalFlags   []string
+	RunFlags      []string
+	ScenarioFlags []string
+}
+
+// Write writes the completion script of shell for lfst with the given commands;
+// globalFlags are the options of lfst itself, before the command
+func Write(w io.Writer, shell string, commands []Command, globalFlags []string) error {
+	tmpl, ok := templates[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell '%s' (use %s)", shell, strings.Join(Shells, ", "))
+	}
+	return tmpl.Execute(w, script{
+		Commands:      commands,
+		GlobalFlags:   globalFlags,
+		RunFlags:      RunFlags,
+		ScenarioFlags: ScenarioFlags,
+	})
+}
+
+var funcs = template.FuncMap{
+	"join": strings.Join,
+	"names": func(commands []Command) string {
+		names := make([]string, len(commands))
+		for i, c := range commands {
+			names[i] = c.Name
+		}
+		return strings.Join(names, " ")
+	},
+	// zshDescribe quotes "NAME:DESCRIPTION" for _describe, whose separator is a colon
+	"zshDescribe": func(name, description string) string {
+		return "'" + name + ":" + strings.NewReplacer(":", `\:`, "'", `'\''`).Replace(description) + "'"
+	},
+	"fishQuote": func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+	},
+	"trimDashes": func(flag string) string { return strings.TrimPrefix(flag, "--") },
+	"flagValues": flagValues,
+	"pipes":      func(flags []string) string { return strings.Join(flags, "|") },
+}
+
+var templates = map[string]*template.Template{
+	"bash": template.Must(template.New("bash").Funcs(funcs).Parse(bashScript)),
+	"zsh":  template.Must(template.New("zsh").Funcs(funcs).Parse(zshScript)),
+	"fish": template.Must(template.New("fish").Funcs(funcs).Parse(fishScript)),
+}
+
+const bashScript = `# bash completion for lfst
+# Load it with: source <(lfst completion bash)
+
+_lfst_values() {
+    lfst completion --list "$1" 2>/dev/null | cut -f1
+}
+
+_lfst() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local sub="" i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case "${COMP_WORDS[i]}" in
+            --log-file|--log-format) ((i++)) ;;
+            -*) ;;
+            *) sub="${COMP_WORDS[i]}"; break ;;
+        esac
+    done
+
+    if [[ -z $sub ]]; then
+        COMPREPLY=($(compgen -W "{{names .Commands}} {{join .GlobalFlags " "}}" -- "$cur"))
+        return
+    fi
+
+    case "$prev" in
+        {{pipes .RunFlags}})
+            COMPREPLY=($(compgen -W "$(_lfst_values runs)" -- "$cur"))
+            return ;;
+        {{pipes .ScenarioFlags}})
+            COMPREPLY=($(compgen -W "$(_lfst_values scenarios)" -- "$cur"))
+            return ;;
+    esac
+
+    local commands="" flags="" positional=""
+    case "$sub" in
+{{- range .Commands}}
+        {{.Name}})
+            commands="{{join .Commands " "}}"
+            flags="{{join .Flags " "}}"
+            positional="{{.Positional}}" ;;
+{{- end}}
+    esac
+
+    if [[ $cur == -* ]]; then
+        COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+        return
+    fi
+
+    local command=""
+    for ((i++; i < COMP_CWORD; i++)); do
+        if [[ " $commands " == *" ${COMP_WORDS[i]} "* ]]; then
+            command="${COMP_WORDS[i]}"
+            break
+        fi
+    done
+    if [[ -n $commands && -z $command ]]; then
+        COMPREPLY=($(compgen -W "$commands" -- "$cur"))
+    elif [[ -n $positional ]]; then
+        COMPREPLY=($(compgen -W "$(_lfst_values "$positional")" -- "$cur"))
+    fi
+}
+
+complete -F _lfst lfst
+`
+
+const zshScript = `#compdef lfst
+# zsh completion for lfst
+# Load it with: source <(lfst completion zsh)
+# or save it as _lfst in a directory of $fpath
+
+_lfst_values() {
+    local -a values
+    local line
+    for line in "${(@f)$(lfst completion --list $1 2>/dev/null)}"; do
+        [[ -n $line ]] && values+=("${${line//:/\\:}/$'\t'/:}")
+    done
+    _describe -t $1 $1 values
+}
+
+_lfst() {
+    local -a subcommands
+    subcommands=(
+{{- range .Commands}}
+        {{zshDescribe .Name .Description}}
+{{- end}}
+    )
+
+    local sub="" i
+    for ((i = 2; i < CURRENT; i++)); do
+        case "${words[i]}" in
+            --log-file|--log-format) ((i++)) ;;
+            -*) ;;
+            *) sub="${words[i]}"; break ;;
+        esac
+    done
+
+    if [[ -z $sub ]]; then
+        if [[ ${words[CURRENT]} == -* ]]; then
+            compadd -- {{join .GlobalFlags " "}}
+        else
+            _describe -t commands 'lfst command' subcommands
+        fi
+        return
+    fi
+
+    case "${words[CURRENT-1]}" in
+        {{pipes .RunFlags}}) _lfst_values runs; return ;;
+        {{pipes .ScenarioFlags}}) _lfst_values scenarios; return ;;
+    esac
+
+    local -a commands flags
+    local positional=""
+    case "$sub" in
+{{- range .Commands}}
+        {{.Name}})
+            commands=({{join .Commands " "}})
+            flags=({{join .Flags " "}})
+            positional="{{.Positional}}" ;;
+{{- end}}
+    esac
+
+    if [[ ${words[CURRENT]} == -* ]]; then
+        compadd -- $flags
+        return
+    fi
+
+    local command=""
+    for ((i++; i < CURRENT; i++)); do
+        if (( ${commands[(Ie)${words[i]}]} )); then
+            command="${words[i]}"
+            break
+        fi
+    done
+    if (( ${#commands} )) && [[ -z $command ]]; then
+        compadd -- $commands
+    elif [[ -n $positional ]]; then
+        _lfst_values $positional
+    fi
+}
+
+if [[ $zsh_eval_context[-1] == loadautofunc ]]; then
+    _lfst "$@"
+else
+    compdef _lfst lfst
+fi
+`
+
+const fishScript = `# fish completion for lfst
+# Load it with: lfst completion fish | source
+# or save it as ~/.config/fish/completions/lfst.fish
+
+# __lfst_subcommand prints the lfst command being completed, e.g. query
+function __lfst_subcommand
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    set -l skip 0
+    for token in $tokens
+        if test $skip = 1
+            set skip 0
+            continue
+        end
+        switch $token
+            case --log-file --log-format
+                set skip 1
+            case '-*'
+            case '*'
+                echo $token
+                return 0
+        end
+    end
+    return 1
+end
+
+# __lfst_using succeeds when the command being completed is $argv[1]
+function __lfst_using
+    set -l sub (__lfst_subcommand)
+    and test "$sub" = $argv[1]
+end
+
+# __lfst_needs_command succeeds when none of the commands $argv[2..] of $argv[1] is given yet
+function __lfst_needs_command
+    __lfst_using $argv[1]; or return 1
+    set -l tokens (commandline -opc)
+    for token in $tokens
+        contains -- $token $argv[2..-1]; and return 1
+    end
+    return 0
+end
+
+complete -c lfst -f
+{{- range .GlobalFlags}}
+complete -c lfst -n 'not __lfst_subcommand >/dev/null' -l {{trimDashes .}}
+{{- end}}
+{{- range .Commands}}
+complete -c lfst -n 'not __lfst_subcommand >/dev/null' -a {{.Name}} -d {{fishQuote .Description}}
+{{- end}}
+{{- range .Commands}}
+{{- $command := .}}
+{{- range $flag := .Flags}}
+{{- with flagValues $flag}}
+complete -c lfst -n '__lfst_using {{$command.Name}}' -l {{trimDashes $flag}} -x -a '(lfst completion --list {{.}} 2>/dev/null)'
+{{- else}}
+complete -c lfst -n '__lfst_using {{$command.Name}}' -l {{trimDashes $flag}}
+{{- end}}
+{{- end}}
+{{- if .Commands}}
+complete -c lfst -n '__lfst_needs_command {{.Name}} {{join .Commands " "}}' -a '{{join .Commands " "}}'
+{{- if .Positional}}
+complete -c lfst -n '__lfst_using {{.Name}}; and not __lfst_needs_command {{.Name}} {{join .Commands " "}}' -a '(lfst completion --list {{.Positional}} 2>/dev/null)'
+{{- end}}
+{{- else if .Positional}}
+complete -c lfst -n '__lfst_using {{.Name}}' -a '(lfst completion --list {{.Positional}} 2>/dev/null)'
+{{- end}}
+{{- end}}
+`
@@ -0,0 +1,93 @@
+package completion
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestFlags(t *testing.T) {
+	fs := pflag.NewFlagSet("stats", pflag.ContinueOnError)
+	fs.Int64("run-id", 0, "Test run ID")
+	fs.StringP("format", "f", "table", "Output format")
+	fs.Bool("old", false, "Replaced by --format")
+	fs.MarkHidden("old")
+	if got := strings.Join(Flags(fs), " "); got != "--format --run-id" {
+		t.Errorf("flags = %s", got)
+	}
+}
+
+func TestFlagSets(t *testing.T) {
+	ran := false
+	commands := map[string]func(fs *pflag.FlagSet) func(){
+		"stats": func(fs *pflag.FlagSet) func() {
+			fs.Int64("run-id", 0, "Test run ID")
+			return func() { ran = true }
+		},
+		"show": func(fs *pflag.FlagSet) func() { return func() { ran = true } },
+	}
+	flagSets := FlagSets(commands)
+	if ran {
+		t.Error("FlagSets ran a command")
+	}
+	if got := strings.Join(Flags(flagSets["stats"]), " "); got != "--run-id" {
+		t.Errorf("stats flags = %s", got)
+	}
+	if got := Flags(flagSets["show"]); len(got) != 0 {
+		t.Errorf("show flags = %v", got)
+	}
+}
+
+func TestParseScenarioList(t *testing.T) {
+	output := `Available scenarios:
+
+ID  Server             Protocol  Git Server    Description
+--  ------             --------  ------------  -----------
+1   bare               local     bare          Bare repo - local
+13  rudolfs            https     bare          Rudolfs - HTTPS
+
+Note: Only scenarios 1, 2, 6-9, and 13-18 are currently implemented.
+`
+	values := ParseScenarioList(output)
+	want := []Value{{"1", "bare local bare Bare repo - local"}, {"13", "rudolfs https bare Rudolfs - HTTPS"}}
+	if !slices.Equal(values, want) {
+		t.Errorf("ParseScenarioList = %+v, want %+v", values, want)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteValues(&buf, values[:1]); err != nil || buf.String() != "1\tbare local bare Bare repo - local\n" {
+		t.Errorf("WriteValues wrote %q, %v", buf.String(), err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	commands := []Command{
+		{Name: "query", Description: "Query: show results", Commands: []string{"stats"}, Flags: []string{"--run-id", "--step"}},
+		{Name: "scenario", Description: "Run's scenarios", Flags: []string{"--resume"}, Positional: Scenarios},
+	}
+	want := map[string][]string{
+		"bash": {`complete -F _lfst lfst`, `--run-id|--resume|--cancel)`, `commands="stats"`, `positional="scenarios"`},
+		"zsh":  {`#compdef lfst`, `'query:Query\: show results'`, `'scenario:Run'\''s scenarios'`, `flags=(--run-id --step)`},
+		"fish": {`-a scenario -d 'Run\'s scenarios'`, `-l run-id -x -a '(lfst completion --list runs 2>/dev/null)'`,
+			`-l step` + "\n", `__lfst_using scenario' -a '(lfst completion --list scenarios 2>/dev/null)'`},
+	}
+
+	for _, shell := range Shells {
+		var buf bytes.Buffer
+		if err := Write(&buf, shell, commands, []string{"--help", "--quiet"}); err != nil {
+			t.Fatalf("Write(%s) failed: %v", shell, err)
+		}
+		for _, s := range want[shell] {
+			if !strings.Contains(buf.String(), s) {
+				t.Errorf("%s script lacks %q", shell, s)
+			}
+		}
+	}
+
+	if err := Write(&bytes.Buffer{}, "tcsh", commands, nil); err == nil {
+		t.Error("Write should reject an unsupported shell")
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/secret"
+)
+
+func TestKeysHaveFields(t *testing.T) {
+	kinds := map[Kind]reflect.Kind{KindString: reflect.String, KindBool: reflect.Bool, KindList: reflect.Slice, KindDuration: reflect.String}
+	seen := make(map[string]bool)
+	for _, k := range Keys {
+		if seen[k.Name] {
+			t.Errorf("key %s is listed twice", k.Name)
+		}
+		seen[k.Name] = true
+		if k.Description == "" {
+			t.Errorf("key %s has no description", k.Name)
+		}
+		field, err := (&Config{}).field(k.Name)
+		if err != nil {
+			t.Errorf("key %s: %v", k.Name, err)
+		} else if got := field.Kind(); got != kinds[k.Kind] {
+			t.Errorf("key %s of kind %s has a field of kind %s", k.Name, k.Kind, got)
+		}
+	}
+	if _, err := (&Config{}).field("no_such_key"); err == nil {
+		t.Error("field of an unknown key should fail")
+	}
+}
+
+func TestSetAndGet(t *testing.T) {
+	cfg := DefaultConfig()
+	for _, tt := range []struct{ key, value, want string }{
+		{"gitea_url", "http://gojira:3000", "http://gojira:3000"},
+		{"offline", "1", "true"},
+		{"db_driver", "sqlite", "sqlite3"},
+		{"track_patterns", " *.psd, ,*.onnx", "*.psd,*.onnx"},
+		{"max_duration", "90m", "90m"},
+		{"ssh_git_dir", "", ""},
+	} {
+		if err := cfg.Set(tt.key, tt.value); err != nil {
+			t.Errorf("Set(%s, %q) failed: %v", tt.key, tt.value, err)
+			continue
+		}
+		if got, err := cfg.Get(tt.key); err != nil || got != tt.want {
+			t.Errorf("Get(%s) = %q, %v; want %q", tt.key, got, err, tt.want)
+		}
+	}
+	if !cfg.Offline || len(cfg.TrackPatterns) != 2 {
+		t.Errorf("Set did not store typed values: offline %v, track_patterns %v", cfg.Offline, cfg.TrackPatterns)
+	}
+	if got, _ := cfg.Get("checksum_exclude"); got == "" {
+		t.Error("Get(checksum_exclude) should return the default patterns when none are set")
+	}
+
+	for _, bad := range [][2]string{
+		{"offline", "maybe"},
+		{"db_driver", "mysql"},
+		{"language", "xx"},
+		{"ssh_git_dir", "relative/dir"},
+		{"notify_url", "ftp://example.com"},
+		{"max_duration", "soon"},
+		{"checksum_exclude", "[unclosed"},
+		{"no_such_key", "1"},
+	} {
+		if err := cfg.Set(bad[0], bad[1]); err == nil {
+			t.Errorf("Set(%s, %q) should fail", bad[0], bad[1])
+		}
+	}
+}
+
+func TestSetValidator(t *testing.T) {
+	defer delete(validators, "pipeline")
+	SetValidator("pipeline", func(steps []string) error {
+		if steps[0] != "setup" {
+			return errors.New("pipelines start with setup")
+		}
+		return nil
+	})
+
+	cfg := DefaultConfig()
+	if err := cfg.Set("pipeline", "push,clone"); err == nil || !strings.Contains(err.Error(), "setup") {
+		t.Errorf("Set(pipeline) error = %v, want the validator's", err)
+	}
+	if err := cfg.Set("pipeline", "setup,push"); err != nil || len(cfg.Pipeline) != 2 {
+		t.Errorf("Set(pipeline) = %v, pipeline %v", err, cfg.Pipeline)
+	}
+}
+
+func TestApplyEnvAndDisplay(t *testing.T) {
+	env := map[string]string{"LFS_WORK_DIR": "/env/work", "LFS_AUTO_REMOTE": "yes", "LFS_GITEA_TOKEN": "secret1234"}
+	cfg := DefaultConfig()
+	cfg.applyEnv(func(name string) string { return env[name] })
+	if cfg.WorkDir != "/env/work" || cfg.AutoRemote || cfg.GiteaToken != "secret1234" {
+		t.Errorf("applyEnv gave work_dir %s, auto_remote %v, gitea_token %s", cfg.WorkDir, cfg.AutoRemote, cfg.GiteaToken)
+	}
+
+	if got := LookupKey("gitea_token").Display("secret1234"); got != "******1234" {
+		t.Errorf("Display of a secret = %q", got)
+	}
+	if got := LookupKey("db_dsn").Display("postgres://lfst:pw@db/lfst"); strings.Contains(got, "pw") {
+		t.Errorf("Display of db_dsn shows the password: %q", got)
+	}
+	if got := LookupKey("gitea_url").Display("http://gojira:3000"); got != "http://gojira:3000" {
+		t.Errorf("Display of a plain value = %q", got)
+	}
+}
+
+func TestSecretReferences(t *testing.T) {
+	t.Cleanup(secret.Reset)
+	t.Setenv("LFST_TEST_GITEA_TOKEN", "gitea-0123456789")
+
+	cfg := DefaultConfig()
+	if err := cfg.Set("notify_url", "env:LFST_TEST_HOOK"); err != nil {
+		t.Errorf("Set should accept a reference in place of a URL: %v", err)
+	}
+	if err := cfg.Set("gitea_token", "keychain:lfst"); err == nil {
+		t.Error("Set should reject a malformed reference")
+	}
+	if got := LookupKey("gitea_token").Display("env:LFST_TEST_GITEA_TOKEN"); got != "env:LFST_TEST_GITEA_TOKEN" {
+		t.Errorf("Display of a reference = %q", got)
+	}
+
+	cfg = DefaultConfig()
+	cfg.GiteaToken = "env:LFST_TEST_GITEA_TOKEN"
+	cfg.BitbucketToken = "bitbucket-literal"
+	cfg.AzureDevOpsToken = "env:LFST_TEST_UNSET"
+	cfg.registerSecrets()
+	if got := secret.Redact("bitbucket-literal"); got != secret.Mask {
+		t.Errorf("a literal secret is not redacted: %q", got)
+	}
+
+	token, err := cfg.Secret("gitea_token")
+	if err != nil || token != "gitea-0123456789" || cfg.Reference("gitea_token") != "env:LFST_TEST_GITEA_TOKEN" {
+		t.Errorf("gitea_token = %q, %v from %q", token, err, cfg.Reference("gitea_token"))
+	}
+	if cfg.GiteaToken != "env:LFST_TEST_GITEA_TOKEN" {
+		t.Errorf("looking up gitea_token changed the config to %q", cfg.GiteaToken)
+	}
+	if cfg.Reference("bitbucket_token") != "" {
+		t.Errorf("a literal secret has reference %q", cfg.Reference("bitbucket_token"))
+	}
+	if got := secret.Redact("gitea-0123456789"); got != secret.Mask {
+		t.Errorf("a looked-up secret is not redacted: %q", got)
+	}
+
+	// A secret that cannot be found fails only where it is needed
+	if _, err := cfg.Secret("azure_devops_token"); err == nil || !strings.Contains(err.Error(), "azure_devops_token") {
+		t.Errorf("Secret error = %v, want one naming azure_devops_token", err)
+	}
+	if _, err := cfg.Secret("bitbucket_token"); err != nil {
+		t.Errorf("Secret of a literal failed: %v", err)
+	}
+}
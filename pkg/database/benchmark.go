@@ -0,0 +1,165 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CreateBenchmark records the start of a benchmark
+func (db *DB) CreateBenchmark(b *Benchmark) error {
+	id, err := db.insert(nil, `
+		INSERT INTO benchmarks (scenario_id, iterations, started_at)
+		VALUES (?, ?, ?)`,
+		b.ScenarioID, b.Iterations, b.StartedAt.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create benchmark: %w", err)
+	}
+
+	b.ID = id
+	return nil
+}
+
+// AddBenchmarkRun records that a run is the given iteration of a benchmark, counting from 1
+func (db *DB) AddBenchmarkRun(benchmarkID, runID int64, iteration int) error {
+	_, err := db.exec(`
+		INSERT INTO benchmark_runs (benchmark_id, run_id, iteration)
+		VALUES (?, ?, ?)`,
+		benchmarkID, runID, iteration,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add run %d to benchmark %d: %w", runID, benchmarkID, err)
+	}
+	return nil
+}
+
+// CompleteBenchmark marks a benchmark completed and replaces its statistics
+func (db *DB) CompleteBenchmark(benchmarkID int64, stats []*BenchmarkStat) error {
+	return db.write(func(tx querier) error {
+		if _, err := tx.Exec(db.dialect.rebind(`DELETE FROM benchmark_stats WHERE benchmark_id = ?`), benchmarkID); err != nil {
+			return fmt.Errorf("failed to delete benchmark statistics: %w", err)
+		}
+		for _, s := range stats {
+			id, err := db.insert(tx, `
+				INSERT INTO benchmark_stats (benchmark_id, operation, samples, mean_ms, median_ms, stddev_ms, min_ms, max_ms)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				benchmarkID, s.Operation, s.Samples, s.MeanMs, s.MedianMs, s.StdDevMs, s.MinMs, s.MaxMs,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create benchmark statistics: %w", err)
+			}
+			s.ID = id
+			s.BenchmarkID = benchmarkID
+		}
+
+		_, err := tx.Exec(db.dialect.rebind(`UPDATE benchmarks SET completed_at = ? WHERE id = ?`),
+			time.Now().Format(time.RFC3339), benchmarkID)
+		if err != nil {
+			return fmt.Errorf("failed to complete benchmark: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetBenchmark retrieves a benchmark with its runs
+func (db *DB) GetBenchmark(id int64) (*Benchmark, error) {
+	benchmarks, err := db.queryBenchmarks(`
+		SELECT id, scenario_id, iterations, started_at, completed_at
+		FROM benchmarks WHERE id = ?`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(benchmarks) == 0 {
+		return nil, fmt.Errorf("failed to get benchmark: benchmark %d not found", id)
+	}
+	return benchmarks[0], nil
+}
+
+// ListBenchmarks lists all benchmarks with their runs, newest first
+func (db *DB) ListBenchmarks() ([]*Benchmark, error) {
+	return db.queryBenchmarks(`
+		SELECT id, scenario_id, iterations, started_at, completed_at
+		FROM benchmarks ORDER BY started_at DESC, id DESC`,
+	)
+}
+
+// queryBenchmarks runs a query selecting benchmarks and adds their runs
+func (db *DB) queryBenchmarks(query string, args ...interface{}) ([]*Benchmark, error) {
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list benchmarks: %w", err)
+	}
+
+	var benchmarks []*Benchmark
+	for rows.Next() {
+		var b Benchmark
+		var startedAt string
+		var completedAt sql.NullString
+
+		if err := rows.Scan(&b.ID, &b.ScenarioID, &b.Iterations, &startedAt, &completedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan benchmark: %w", err)
+		}
+
+		b.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+		if completedAt.Valid {
+			t, _ := time.Parse(time.RFC3339, completedAt.String)
+			b.CompletedAt = &t
+		}
+		benchmarks = append(benchmarks, &b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list benchmarks: %w", err)
+	}
+
+	for _, b := range benchmarks {
+		if b.RunIDs, err = db.listBenchmarkRuns(b.ID); err != nil {
+			return nil, err
+		}
+	}
+	return benchmarks, nil
+}
+
+// listBenchmarkRuns returns the runs of a benchmark in iteration order
+func (db *DB) listBenchmarkRuns(benchmarkID int64) ([]int64, error) {
+	rows, err := db.query(`SELECT run_id FROM benchmark_runs WHERE benchmark_id = ? ORDER BY iteration`, benchmarkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list benchmark runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runIDs []int64
+	for rows.Next() {
+		var runID int64
+		if err := rows.Scan(&runID); err != nil {
+			return nil, fmt.Errorf("failed to scan benchmark run: %w", err)
+		}
+		runIDs = append(runIDs, runID)
+	}
+	return runIDs, rows.Err()
+}
+
+// ListBenchmarkStats lists the statistics of a benchmark by operation type
+func (db *DB) ListBenchmarkStats(benchmarkID int64) ([]*BenchmarkStat, error) {
+	rows, err := db.query(`
+		SELECT id, benchmark_id, operation, samples, mean_ms, median_ms, stddev_ms, min_ms, max_ms
+		FROM benchmark_stats WHERE benchmark_id = ? ORDER BY id`, benchmarkID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list benchmark statistics: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []*BenchmarkStat
+	for rows.Next() {
+		var s BenchmarkStat
+		if err := rows.Scan(&s.ID, &s.BenchmarkID, &s.Operation, &s.Samples, &s.MeanMs, &s.MedianMs, &s.StdDevMs, &s.MinMs, &s.MaxMs); err != nil {
+			return nil, fmt.Errorf("failed to scan benchmark statistics: %w", err)
+		}
+		stats = append(stats, &s)
+	}
+	return stats, rows.Err()
+}
@@ -1,54 +1,116 @@
 package database
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mslinn/git-lfs-test/pkg/secret"
 )
 
-// DB wraps the SQLite database connection
+// DB wraps the connection to an SQLite database file or a PostgreSQL server
 type DB struct {
-	conn *sql.DB
+	conn    *sql.DB
+	dialect dialect
+	enc     *encryption // Set when the database file is encrypted
+	tempDir string      // Removed on Close; set for a federation
+	writer  *writer     // Runs the writes of an SQLite database; nil for PostgreSQL
+	ctx     context.Context
+
+	checksumsUnique bool // Set when the unique index of checksums exists
 }
 
-// Open opens or creates a SQLite database and initializes the schema
+// Open opens or creates a database and initializes the schema. A postgres:// URL
+// connects to a PostgreSQL server shared by several machines; anything else is the
+// path of an SQLite file. An encrypted SQLite database, or any SQLite database while
+// KeyEnv is set, is decrypted into a private directory and encrypted again by Close.
 func Open(path string) (*DB, error) {
-	conn, err := sql.Open("sqlite3", path)
+	if IsPostgresURL(path) {
+		return OpenDriver(DriverPostgres, path)
+	}
+
+	encrypted, err := IsEncrypted(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	// Enable WAL mode for better concurrency
-	// WAL allows multiple readers while one writer is active
-	if _, err := conn.Exec("PRAGMA journal_mode=WAL"); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
+	var enc *encryption
+	if encrypted || os.Getenv(KeyEnv) != "" {
+		if enc, path, err = openEncrypted(path); err != nil {
+			return nil, err
+		}
 	}
 
-	// Set busy timeout to 5 seconds
-	// If database is locked, retry for up to 5 seconds before failing
-	if _, err := conn.Exec("PRAGMA busy_timeout=5000"); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to set busy timeout: %w", err)
+	db, err := open(path)
+	if err != nil {
+		if enc != nil {
+			enc.release()
+		}
+		return nil, err
+	}
+	db.enc = enc
+	return db, nil
+}
+
+// OpenDriver opens a database with a driver from Drivers: the DSN of DriverPostgres is
+// a connection URL or keyword/value string, that of DriverSQLite a file path
+func OpenDriver(driver, dsn string) (*DB, error) {
+	switch driver {
+	case DriverSQLite, "":
+		if IsPostgresURL(dsn) {
+			return nil, fmt.Errorf("'%s' is a PostgreSQL URL; use the %s driver", dsn, DriverPostgres)
+		}
+		return Open(dsn)
+	case DriverPostgres:
+	default:
+		return nil, fmt.Errorf("unknown database driver '%s' (use %s)", driver, strings.Join(Drivers, " or "))
+	}
+
+	if os.Getenv(KeyEnv) != "" {
+		return nil, fmt.Errorf("%s encrypts SQLite database files; secure a PostgreSQL server with its own access control and TLS", KeyEnv)
 	}
 
-	// Enable foreign keys
-	if _, err := conn.Exec("PRAGMA foreign_keys = ON"); err != nil {
+	conn, err := sql.Open(DriverPostgres, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := conn.Ping(); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	return setup(conn, postgresDialect{})
+}
+
+// open opens a plain SQLite database file
+func open(path string) (*DB, error) {
+	conn, err := sql.Open(DriverSQLite, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	return setup(conn, sqliteDialect{})
+}
 
-	// Create schema
-	if _, err := conn.Exec(schema); err != nil {
+// setup creates the schema of a new connection and migrates existing databases
+func setup(conn *sql.DB, d dialect) (*DB, error) {
+	if err := d.prepare(conn); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to create schema: %w", err)
+		return nil, err
 	}
 
 	// Run migrations for existing databases
-	db := &DB{conn: conn}
+	db := &DB{conn: conn, dialect: d}
+	if _, ok := d.(sqliteDialect); ok {
+		db.writer = startWriter(conn)
+	}
 	if err := db.runMigrations(); err != nil {
+		db.stopWriter()
 		conn.Close()
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
@@ -56,28 +118,43 @@ func Open(path string) (*DB, error) {
 	return db, nil
 }
 
-// Close closes the database connection
+// Close closes the database connection once the queued writes are done, first writing an
+// encrypted database back to its file
 func (db *DB) Close() error {
-	return db.conn.Close()
+	db.stopWriter()
+	if db.tempDir != "" {
+		defer os.RemoveAll(db.tempDir)
+	}
+	if db.enc == nil {
+		return db.conn.Close()
+	}
+
+	// Closing the last connection folds the write-ahead log into the database file.
+	// If the file cannot be encrypted, the decrypted copy is kept so no results are lost.
+	err := db.conn.Close()
+	if err == nil {
+		err = db.enc.save()
+	}
+	if err != nil {
+		db.enc.lock.Close()
+		return fmt.Errorf("%w (the decrypted database was kept in %s and is used the next time the database is opened)",
+			err, db.enc.plainDir)
+	}
+	return db.enc.release()
 }
 
 // CreateTestRun creates a new test run record
 func (db *DB) CreateTestRun(run *TestRun) error {
-	result, err := db.conn.Exec(`
-		INSERT INTO test_runs (scenario_id, server_type, protocol, git_server, pid, started_at, status, notes)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+	id, err := db.insert(nil, `
+		INSERT INTO test_runs (scenario_id, server_type, protocol, git_server, pid, started_at, status, notes, work_dir, network_profile)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		run.ScenarioID, run.ServerType, run.Protocol, run.GitServer, run.PID,
-		run.StartedAt.Format(time.RFC3339), run.Status, run.Notes,
+		run.StartedAt.Format(time.RFC3339), run.Status, secret.Redact(run.Notes), run.WorkDir, run.NetworkProfile,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create test run: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
-
 	run.ID = id
 	return nil
 }
@@ -90,11 +167,11 @@ func (db *DB) UpdateTestRun(run *TestRun) error {
 		completedAt = &t
 	}
 
-	_, err := db.conn.Exec(`
+	_, err := db.exec(`
 		UPDATE test_runs
-		SET pid = ?, completed_at = ?, status = ?, notes = ?
+		SET pid = ?, completed_at = ?, status = ?, notes = ?, work_dir = ?
 		WHERE id = ?`,
-		run.PID, completedAt, run.Status, run.Notes, run.ID,
+		run.PID, completedAt, run.Status, secret.Redact(run.Notes), run.WorkDir, run.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update test run: %w", err)
@@ -109,12 +186,12 @@ func (db *DB) GetTestRun(id int64) (*TestRun, error) {
 	var startedAt string
 	var completedAt *string
 
-	err := db.conn.QueryRow(`
-		SELECT id, scenario_id, server_type, protocol, git_server, pid, started_at, completed_at, status, notes
+	err := db.queryRow(`
+		SELECT id, scenario_id, server_type, protocol, git_server, pid, started_at, completed_at, status, notes, COALESCE(work_dir, ''), COALESCE(network_profile, '')
 		FROM test_runs WHERE id = ?`, id,
 	).Scan(
 		&run.ID, &run.ScenarioID, &run.ServerType, &run.Protocol, &run.GitServer, &run.PID,
-		&startedAt, &completedAt, &run.Status, &run.Notes,
+		&startedAt, &completedAt, &run.Status, &run.Notes, &run.WorkDir, &run.NetworkProfile,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get test run: %w", err)
@@ -131,19 +208,31 @@ func (db *DB) GetTestRun(id int64) (*TestRun, error) {
 
 // ListTestRuns lists all test runs, optionally filtered by scenario ID (0 = all)
 func (db *DB) ListTestRuns(scenarioID ...int) ([]*TestRun, error) {
-	var query string
-	var args []interface{}
-
 	if len(scenarioID) > 0 && scenarioID[0] > 0 {
-		query = `SELECT id, scenario_id, server_type, protocol, git_server, pid, started_at, completed_at, status, notes
-			FROM test_runs WHERE scenario_id = ? ORDER BY started_at DESC`
-		args = append(args, scenarioID[0])
-	} else {
-		query = `SELECT id, scenario_id, server_type, protocol, git_server, pid, started_at, completed_at, status, notes
-			FROM test_runs ORDER BY started_at DESC`
+		return db.queryTestRuns(`SELECT `+testRunColumns+`
+			FROM test_runs r WHERE scenario_id = ? ORDER BY started_at DESC`, scenarioID[0])
 	}
+	return db.queryTestRuns(`SELECT ` + testRunColumns + ` FROM test_runs r ORDER BY started_at DESC`)
+}
+
+// ListTaggedRuns lists the test runs that carry every tag, newest first; without tags it
+// lists all of them
+func (db *DB) ListTaggedRuns(tags []Tag) ([]*TestRun, error) {
+	if len(tags) == 0 {
+		return db.ListTestRuns()
+	}
+	tagFilter, args := runTagFilter(tags)
+	return db.queryTestRuns(`SELECT `+testRunColumns+`
+		FROM test_runs r WHERE `+tagFilter+` ORDER BY started_at DESC`, args...)
+}
 
-	rows, err := db.conn.Query(query, args...)
+// testRunColumns are the columns queryTestRuns scans, of test_runs aliased r
+const testRunColumns = `r.id, r.scenario_id, r.server_type, r.protocol, r.git_server, r.pid, r.started_at,
+	r.completed_at, r.status, r.notes, COALESCE(r.work_dir, ''), COALESCE(r.network_profile, '')`
+
+// queryTestRuns runs a test run SELECT of testRunColumns and scans the resulting rows
+func (db *DB) queryTestRuns(query string, args ...interface{}) ([]*TestRun, error) {
+	rows, err := db.query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list test runs: %w", err)
 	}
@@ -157,7 +246,7 @@ func (db *DB) ListTestRuns(scenarioID ...int) ([]*TestRun, error) {
 
 		err := rows.Scan(
 			&run.ID, &run.ScenarioID, &run.ServerType, &run.Protocol, &run.GitServer, &run.PID,
-			&startedAt, &completedAt, &run.Status, &run.Notes,
+			&startedAt, &completedAt, &run.Status, &run.Notes, &run.WorkDir, &run.NetworkProfile,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan test run: %w", err)
@@ -172,36 +261,58 @@ func (db *DB) ListTestRuns(scenarioID ...int) ([]*TestRun, error) {
 		runs = append(runs, &run)
 	}
 
-	return runs, nil
+	return runs, rows.Err()
 }
 
-// CreateOperation creates a new operation record
+// CreateOperation creates a new operation record.
+// Unless set by the caller, the operation is linked to the run's most recent
+// checksum snapshot; the following snapshot is linked by CreateSnapshot.
 func (db *DB) CreateOperation(op *Operation) error {
-	result, err := db.conn.Exec(`
-		INSERT INTO operations (run_id, step_number, operation, started_at, duration_ms, file_count, total_bytes, status, error)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	if op.SnapshotBeforeID == nil {
+		var latest sql.NullInt64
+		err := db.queryRow(`SELECT MAX(id) FROM snapshots WHERE run_id = ?`, op.RunID).Scan(&latest)
+		if err != nil {
+			return fmt.Errorf("failed to find latest snapshot: %w", err)
+		}
+		if latest.Valid {
+			op.SnapshotBeforeID = &latest.Int64
+		}
+	}
+
+	// Operations whose resources were not sampled leave those columns NULL
+	resources := make([]interface{}, 6)
+	if r := op.Resources; r != nil {
+		resources = []interface{}{r.CPUMs, r.PeakCPUPercent, r.PeakRSSBytes, r.AvgRSSBytes, r.ReadBytes, r.WriteBytes}
+	}
+
+	args := []interface{}{
 		op.RunID, op.StepNumber, op.Operation,
 		op.StartedAt.Format(time.RFC3339), op.DurationMs,
-		op.FileCount, op.TotalBytes, op.Status, op.Error,
+		op.FileCount, op.TotalBytes, op.Status, secret.Redact(op.Error), op.ErrorKind,
+		op.SnapshotBeforeID, op.SnapshotAfterID, op.Repo, op.TransferAdapter,
+	}
+	id, err := db.insert(nil, `
+		INSERT INTO operations (run_id, step_number, operation, started_at, duration_ms, file_count, total_bytes, status, error,
+			error_kind, snapshot_before_id, snapshot_after_id, repo, transfer_adapter,
+			cpu_ms, peak_cpu_pct, peak_rss_bytes, avg_rss_bytes, read_bytes, write_bytes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		append(args, resources...)...,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create operation: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
-
 	op.ID = id
 	return nil
 }
 
 // ListOperations lists all operations for a test run
 func (db *DB) ListOperations(runID int64) ([]*Operation, error) {
-	rows, err := db.conn.Query(`
-		SELECT id, run_id, step_number, operation, started_at, duration_ms, file_count, total_bytes, status, error
-		FROM operations WHERE run_id = ? ORDER BY step_number, started_at`, runID,
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, operation, started_at, duration_ms, file_count, total_bytes, status, error,
+			error_kind, snapshot_before_id, snapshot_after_id, repo, server_event, transfer_adapter,
+			cpu_ms, peak_cpu_pct, peak_rss_bytes, avg_rss_bytes, read_bytes, write_bytes
+		FROM operations WHERE run_id = ? ORDER BY step_number, started_at, id`, runID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list operations: %w", err)
@@ -212,16 +323,35 @@ func (db *DB) ListOperations(runID int64) ([]*Operation, error) {
 	for rows.Next() {
 		var op Operation
 		var startedAt string
+		var errorMsg, errorKind, repo, serverEvent, adapter sql.NullString
+		var cpuMs, peakRSS, avgRSS, readBytes, writeBytes sql.NullInt64
+		var peakCPU sql.NullFloat64
 
 		err := rows.Scan(
 			&op.ID, &op.RunID, &op.StepNumber, &op.Operation,
 			&startedAt, &op.DurationMs, &op.FileCount, &op.TotalBytes,
-			&op.Status, &op.Error,
+			&op.Status, &errorMsg, &errorKind, &op.SnapshotBeforeID, &op.SnapshotAfterID, &repo, &serverEvent, &adapter,
+			&cpuMs, &peakCPU, &peakRSS, &avgRSS, &readBytes, &writeBytes,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan operation: %w", err)
 		}
 
+		op.Error = errorMsg.String
+		op.ErrorKind = errorKind.String
+		op.Repo = repo.String
+		op.ServerEvent = serverEvent.String
+		op.TransferAdapter = adapter.String
+		if cpuMs.Valid {
+			op.Resources = &ResourceUsage{
+				CPUMs:          cpuMs.Int64,
+				PeakCPUPercent: peakCPU.Float64,
+				PeakRSSBytes:   peakRSS.Int64,
+				AvgRSSBytes:    avgRSS.Int64,
+				ReadBytes:      readBytes.Int64,
+				WriteBytes:     writeBytes.Int64,
+			}
+		}
 		op.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
 		ops = append(ops, &op)
 	}
@@ -229,147 +359,1334 @@ func (db *DB) ListOperations(runID int64) ([]*Operation, error) {
 	return ops, nil
 }
 
-// CreateChecksum creates a new checksum record
-func (db *DB) CreateChecksum(cs *Checksum) error {
-	result, err := db.conn.Exec(`
-		INSERT INTO checksums (run_id, step_number, file_path, crc32, size_bytes, computed_at)
-		VALUES (?, ?, ?, ?, ?, ?)`,
-		cs.RunID, cs.StepNumber, cs.FilePath, cs.CRC32, cs.SizeBytes,
-		cs.ComputedAt.Format(time.RFC3339),
+// SetOperationServerEvent records the server events that happened during an operation
+func (db *DB) SetOperationServerEvent(operationID int64, description string) error {
+	if _, err := db.exec(`UPDATE operations SET server_event = ? WHERE id = ?`, description, operationID); err != nil {
+		return fmt.Errorf("failed to annotate operation: %w", err)
+	}
+	return nil
+}
+
+// LatestOperationID returns the ID of the most recent operation of a run matching the filter, or 0 if none.
+// If repo is non-empty only operations on that repository match; if opType is non-empty only operations
+// of that type match; if otherRepo is true the repository must differ from repo instead.
+func (db *DB) LatestOperationID(runID int64, repo, opType string, otherRepo bool) (int64, error) {
+	query := `SELECT MAX(id) FROM operations WHERE run_id = ? AND status = 'success'`
+	args := []interface{}{runID}
+
+	switch {
+	case otherRepo:
+		query += ` AND repo IS NOT NULL AND repo != '' AND repo != ?`
+		args = append(args, repo)
+	case repo != "":
+		query += ` AND repo = ?`
+		args = append(args, repo)
+	}
+	if opType != "" {
+		query += ` AND operation = ?`
+		args = append(args, opType)
+	}
+
+	var id sql.NullInt64
+	if err := db.queryRow(query, args...).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to find latest operation: %w", err)
+	}
+	return id.Int64, nil
+}
+
+// SaveOperationLog stores the output of an operation's command, gzip-compressed
+func (db *DB) SaveOperationLog(l *OperationLog) error {
+	// Commands and their output may carry credentials, e.g. in URLs
+	stdout, err := compress(secret.Redact(l.Stdout))
+	if err != nil {
+		return err
+	}
+	stderr, err := compress(secret.Redact(l.Stderr))
+	if err != nil {
+		return err
+	}
+
+	_, err = db.exec(`
+		INSERT INTO operation_logs (operation_id, command, exit_code, stdout, stderr) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (operation_id) DO UPDATE SET
+			command = excluded.command, exit_code = excluded.exit_code, stdout = excluded.stdout, stderr = excluded.stderr`,
+		l.OperationID, secret.Redact(l.Command), l.ExitCode, stdout, stderr,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create checksum: %w", err)
+		return fmt.Errorf("failed to save operation log: %w", err)
 	}
+	return nil
+}
 
-	id, err := result.LastInsertId()
+// ListOperationLogs lists the command output of a test run's operations by operation ID;
+// stepNumber 0 means all steps
+func (db *DB) ListOperationLogs(runID int64, stepNumber int) (map[int64]*OperationLog, error) {
+	rows, err := db.query(`
+		SELECT l.operation_id, l.command, l.exit_code, l.stdout, l.stderr
+		FROM operation_logs l JOIN operations o ON o.id = l.operation_id
+		WHERE o.run_id = ? AND (? = 0 OR o.step_number = ?)`, runID, stepNumber, stepNumber,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
+		return nil, fmt.Errorf("failed to list operation logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := make(map[int64]*OperationLog)
+	for rows.Next() {
+		var l OperationLog
+		var stdout, stderr []byte
+		if err := rows.Scan(&l.OperationID, &l.Command, &l.ExitCode, &stdout, &stderr); err != nil {
+			return nil, fmt.Errorf("failed to scan operation log: %w", err)
+		}
+		if l.Stdout, err = decompress(stdout); err != nil {
+			return nil, err
+		}
+		if l.Stderr, err = decompress(stderr); err != nil {
+			return nil, err
+		}
+		logs[l.OperationID] = &l
+	}
+
+	return logs, nil
+}
+
+// ListOperationCommands lists the command lines of a test run's operations by operation
+// ID, without their output; stepNumber 0 means all steps
+func (db *DB) ListOperationCommands(runID int64, stepNumber int) (map[int64]string, error) {
+	rows, err := db.query(`
+		SELECT l.operation_id, l.command
+		FROM operation_logs l JOIN operations o ON o.id = l.operation_id
+		WHERE o.run_id = ? AND (? = 0 OR o.step_number = ?)`, runID, stepNumber, stepNumber,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list operation commands: %w", err)
+	}
+	defer rows.Close()
+
+	commands := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var command string
+		if err := rows.Scan(&id, &command); err != nil {
+			return nil, fmt.Errorf("failed to scan operation command: %w", err)
+		}
+		commands[id] = command
+	}
+
+	return commands, rows.Err()
+}
+
+// compress gzips command output; empty output is stored as NULL
+func compress(text string) ([]byte, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(text)); err != nil {
+		return nil, fmt.Errorf("failed to compress output: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress output: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress reverses compress
+func decompress(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress output: %w", err)
+	}
+	text, err := io.ReadAll(zr)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress output: %w", err)
+	}
+	return string(text), nil
+}
+
+// AddOperationDependency records that operationID depended on dependsOnID
+func (db *DB) AddOperationDependency(dep *OperationDependency) error {
+	_, err := db.exec(`
+		INSERT INTO operation_dependencies (operation_id, depends_on_id, kind) VALUES (?, ?, ?)
+		ON CONFLICT DO NOTHING`,
+		dep.OperationID, dep.DependsOnID, dep.Kind,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add operation dependency: %w", err)
 	}
 
-	cs.ID = id
 	return nil
 }
 
-// ListChecksums lists all checksums for a test run and step
-func (db *DB) ListChecksums(runID int64, stepNumber int) ([]*Checksum, error) {
-	rows, err := db.conn.Query(`
-		SELECT id, run_id, step_number, file_path, crc32, size_bytes, computed_at
-		FROM checksums WHERE run_id = ? AND step_number = ? ORDER BY file_path`, runID, stepNumber,
+// ListOperationDependencies lists the dependencies between the operations of a run
+func (db *DB) ListOperationDependencies(runID int64) ([]*OperationDependency, error) {
+	rows, err := db.query(`
+		SELECT d.operation_id, d.depends_on_id, d.kind
+		FROM operation_dependencies d JOIN operations o ON o.id = d.operation_id
+		WHERE o.run_id = ? ORDER BY d.operation_id, d.depends_on_id`, runID,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list checksums: %w", err)
+		return nil, fmt.Errorf("failed to list operation dependencies: %w", err)
 	}
 	defer rows.Close()
 
-	var checksums []*Checksum
+	var deps []*OperationDependency
 	for rows.Next() {
-		var cs Checksum
-		var computedAt string
+		var dep OperationDependency
+		if err := rows.Scan(&dep.OperationID, &dep.DependsOnID, &dep.Kind); err != nil {
+			return nil, fmt.Errorf("failed to scan operation dependency: %w", err)
+		}
+		deps = append(deps, &dep)
+	}
 
-		err := rows.Scan(
-			&cs.ID, &cs.RunID, &cs.StepNumber, &cs.FilePath,
-			&cs.CRC32, &cs.SizeBytes, &computedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan checksum: %w", err)
+	return deps, nil
+}
+
+// SaveStepResult inserts or replaces the result of a step
+func (db *DB) SaveStepResult(sr *StepResult) error {
+	var completedAt *string
+	if sr.CompletedAt != nil {
+		t := sr.CompletedAt.Format(time.RFC3339)
+		completedAt = &t
+	}
+
+	_, err := db.exec(`
+		INSERT INTO step_results (run_id, step_number, step_name, status, started_at, completed_at, duration_ms, error,
+			interface, rx_bytes, tx_bytes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (run_id, step_number) DO UPDATE SET
+			step_name = excluded.step_name, status = excluded.status, started_at = excluded.started_at, completed_at = excluded.completed_at,
+			duration_ms = excluded.duration_ms, error = excluded.error,
+			interface = excluded.interface, rx_bytes = excluded.rx_bytes, tx_bytes = excluded.tx_bytes`,
+		sr.RunID, sr.StepNumber, sr.Name, sr.Status, sr.StartedAt.Format(time.RFC3339), completedAt, sr.DurationMs, secret.Redact(sr.Error),
+		sr.Interface, sr.RxBytes, sr.TxBytes,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save step result: %w", err)
+	}
+
+	return nil
+}
+
+// ListStepResults lists the step results of a test run in step order
+func (db *DB) ListStepResults(runID int64) ([]*StepResult, error) {
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, step_name, status, started_at, completed_at, duration_ms, error,
+			interface, rx_bytes, tx_bytes
+		FROM step_results WHERE run_id = ? ORDER BY step_number`, runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list step results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*StepResult
+	for rows.Next() {
+		var sr StepResult
+		var startedAt string
+		var name, completedAt, errorMsg, iface sql.NullString
+		var rxBytes, txBytes sql.NullInt64
+
+		if err := rows.Scan(&sr.ID, &sr.RunID, &sr.StepNumber, &name, &sr.Status, &startedAt, &completedAt, &sr.DurationMs, &errorMsg,
+			&iface, &rxBytes, &txBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan step result: %w", err)
 		}
 
-		cs.ComputedAt, _ = time.Parse(time.RFC3339, computedAt)
-		checksums = append(checksums, &cs)
+		sr.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+		if completedAt.Valid {
+			t, _ := time.Parse(time.RFC3339, completedAt.String)
+			sr.CompletedAt = &t
+		}
+		sr.Name = name.String
+		sr.Error = errorMsg.String
+		sr.Interface = iface.String
+		if rxBytes.Valid {
+			sr.RxBytes = &rxBytes.Int64
+		}
+		if txBytes.Valid {
+			sr.TxBytes = &txBytes.Int64
+		}
+		results = append(results, &sr)
 	}
 
-	return checksums, nil
+	return results, nil
 }
 
-// CreateRepositorySize creates a new repository size record
-func (db *DB) CreateRepositorySize(rs *RepositorySize) error {
-	result, err := db.conn.Exec(`
-		INSERT INTO repository_sizes (run_id, step_number, location, size_bytes, file_count, measured_at)
-		VALUES (?, ?, ?, ?, ?, ?)`,
-		rs.RunID, rs.StepNumber, rs.Location, rs.SizeBytes, rs.FileCount,
-		rs.MeasuredAt.Format(time.RFC3339),
+// ResetStepChecksums discards the checksums and snapshots of a step so it can be re-run
+func (db *DB) ResetStepChecksums(runID int64, stepNumber int) error {
+	statements := []string{
+		`UPDATE operations SET snapshot_before_id = NULL
+			WHERE snapshot_before_id IN (SELECT id FROM snapshots WHERE run_id = ? AND step_number = ?)`,
+		`UPDATE operations SET snapshot_after_id = NULL
+			WHERE snapshot_after_id IN (SELECT id FROM snapshots WHERE run_id = ? AND step_number = ?)`,
+		`DELETE FROM checksums WHERE run_id = ? AND step_number = ?`,
+		`DELETE FROM snapshots WHERE run_id = ? AND step_number = ?`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.exec(stmt, runID, stepNumber); err != nil {
+			return fmt.Errorf("failed to reset checksums for step %d: %w", stepNumber, err)
+		}
+	}
+
+	return nil
+}
+
+// CreateVerification records the outcome of a verification
+func (db *DB) CreateVerification(v *Verification) error {
+	id, err := db.insert(nil, `
+		INSERT INTO verifications (run_id, step_number, name, severity, status, message, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		v.RunID, v.StepNumber, v.Name, v.Severity, v.Status, secret.Redact(v.Message), v.CheckedAt.Format(time.RFC3339),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create repository size: %w", err)
+		return fmt.Errorf("failed to create verification: %w", err)
 	}
 
-	id, err := result.LastInsertId()
+	v.ID = id
+	return nil
+}
+
+// ListVerifications lists the verifications of a test run in the order they were made
+func (db *DB) ListVerifications(runID int64) ([]*Verification, error) {
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, name, severity, status, message, checked_at
+		FROM verifications WHERE run_id = ? ORDER BY step_number, id`, runID,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
+		return nil, fmt.Errorf("failed to list verifications: %w", err)
 	}
+	defer rows.Close()
 
-	rs.ID = id
+	var verifications []*Verification
+	for rows.Next() {
+		var v Verification
+		var message sql.NullString
+		var checkedAt string
+
+		if err := rows.Scan(&v.ID, &v.RunID, &v.StepNumber, &v.Name, &v.Severity, &v.Status, &message, &checkedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan verification: %w", err)
+		}
+
+		v.Message = message.String
+		v.CheckedAt, _ = time.Parse(time.RFC3339, checkedAt)
+		verifications = append(verifications, &v)
+	}
+
+	return verifications, nil
+}
+
+// DeleteVerifications removes the verifications of a step, e.g. before it is re-run
+func (db *DB) DeleteVerifications(runID int64, stepNumber int) error {
+	if _, err := db.exec(`DELETE FROM verifications WHERE run_id = ? AND step_number = ?`, runID, stepNumber); err != nil {
+		return fmt.Errorf("failed to delete verifications: %w", err)
+	}
 	return nil
 }
 
-// ListRepositorySizes lists all repository sizes for a test run
-func (db *DB) ListRepositorySizes(runID int64) ([]*RepositorySize, error) {
-	rows, err := db.conn.Query(`
-		SELECT id, run_id, step_number, location, size_bytes, file_count, measured_at
-		FROM repository_sizes WHERE run_id = ? ORDER BY step_number, location`, runID,
+// CreateServerEvent records a restart, stop or out-of-memory kill of the server
+func (db *DB) CreateServerEvent(e *ServerEvent) error {
+	id, err := db.insert(nil, `
+		INSERT INTO server_events (run_id, step_number, occurred_at, kind, message)
+		VALUES (?, ?, ?, ?, ?)`,
+		e.RunID, e.StepNumber, e.OccurredAt.Format(time.RFC3339), e.Kind, e.Message,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list repository sizes: %w", err)
+		return fmt.Errorf("failed to create server event: %w", err)
+	}
+
+	e.ID = id
+	return nil
+}
+
+// ListServerEvents lists the server events of a test run in the order they happened
+func (db *DB) ListServerEvents(runID int64) ([]*ServerEvent, error) {
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, occurred_at, kind, message
+		FROM server_events WHERE run_id = ? ORDER BY occurred_at, id`, runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list server events: %w", err)
 	}
 	defer rows.Close()
 
-	var sizes []*RepositorySize
+	var events []*ServerEvent
 	for rows.Next() {
-		var rs RepositorySize
-		var measuredAt string
+		var e ServerEvent
+		var message sql.NullString
+		var occurredAt string
 
-		err := rows.Scan(
-			&rs.ID, &rs.RunID, &rs.StepNumber, &rs.Location,
-			&rs.SizeBytes, &rs.FileCount, &measuredAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan repository size: %w", err)
+		if err := rows.Scan(&e.ID, &e.RunID, &e.StepNumber, &occurredAt, &e.Kind, &message); err != nil {
+			return nil, fmt.Errorf("failed to scan server event: %w", err)
 		}
 
-		rs.MeasuredAt, _ = time.Parse(time.RFC3339, measuredAt)
-		sizes = append(sizes, &rs)
+		e.Message = message.String
+		e.OccurredAt, _ = time.Parse(time.RFC3339, occurredAt)
+		events = append(events, &e)
 	}
 
-	return sizes, nil
+	return events, rows.Err()
 }
 
-// GetChecksumsByRunAndStep retrieves all checksums for a specific run and step
-func (db *DB) GetChecksumsByRunAndStep(runID int64, stepNumber int) ([]*Checksum, error) {
-	return db.ListChecksums(runID, stepNumber)
+// DeleteServerEvents removes the server events of a step, e.g. before it is re-run
+func (db *DB) DeleteServerEvents(runID int64, stepNumber int) error {
+	if _, err := db.exec(`DELETE FROM server_events WHERE run_id = ? AND step_number = ?`, runID, stepNumber); err != nil {
+		return fmt.Errorf("failed to delete server events: %w", err)
+	}
+	return nil
 }
 
-// Rows wraps sql.Rows for use in query commands
-type Rows = sql.Rows
+// CreateStorageVerification records the outcome of checking one object in the storage backend
+func (db *DB) CreateStorageVerification(v *StorageVerification) error {
+	id, err := db.insert(nil, `
+		INSERT INTO storage_verification (run_id, step_number, backend, object_key, oid, expected_size, actual_size, status, message, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		v.RunID, v.StepNumber, v.Backend, v.ObjectKey, v.OID, v.ExpectedSize, v.ActualSize, v.Status, v.Message,
+		v.CheckedAt.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create storage verification: %w", err)
+	}
 
-// QueryRaw executes a raw SQL query and returns rows
-func (db *DB) QueryRaw(query string, args ...interface{}) (*sql.Rows, error) {
-	return db.conn.Query(query, args...)
+	v.ID = id
+	return nil
 }
 
-// QueryRowRaw executes a raw SQL query and returns a single row
-func (db *DB) QueryRowRaw(query string, args ...interface{}) *sql.Row {
-	return db.conn.QueryRow(query, args...)
+// ListStorageVerifications lists the storage checks of a test run by step and OID
+func (db *DB) ListStorageVerifications(runID int64) ([]*StorageVerification, error) {
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, backend, object_key, oid, expected_size, actual_size, status, message, checked_at
+		FROM storage_verification WHERE run_id = ? ORDER BY step_number, oid`, runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage verifications: %w", err)
+	}
+	defer rows.Close()
+
+	var verifications []*StorageVerification
+	for rows.Next() {
+		var v StorageVerification
+		var actualSize sql.NullInt64
+		var message sql.NullString
+		var checkedAt string
+
+		if err := rows.Scan(&v.ID, &v.RunID, &v.StepNumber, &v.Backend, &v.ObjectKey, &v.OID, &v.ExpectedSize,
+			&actualSize, &v.Status, &message, &checkedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan storage verification: %w", err)
+		}
+
+		if actualSize.Valid {
+			v.ActualSize = &actualSize.Int64
+		}
+		v.Message = message.String
+		v.CheckedAt, _ = time.Parse(time.RFC3339, checkedAt)
+		verifications = append(verifications, &v)
+	}
+
+	return verifications, nil
 }
 
-// runMigrations applies database schema migrations for existing databases
-func (db *DB) runMigrations() error {
-	// Check if pid column exists in test_runs table
-	var pidExists bool
-	err := db.conn.QueryRow(`
-		SELECT COUNT(*) > 0
-		FROM pragma_table_info('test_runs')
-		WHERE name = 'pid'
-	`).Scan(&pidExists)
+// DeleteStorageVerifications removes the storage checks of a step, e.g. before it is re-run
+func (db *DB) DeleteStorageVerifications(runID int64, stepNumber int) error {
+	if _, err := db.exec(`DELETE FROM storage_verification WHERE run_id = ? AND step_number = ?`, runID, stepNumber); err != nil {
+		return fmt.Errorf("failed to delete storage verifications: %w", err)
+	}
+	return nil
+}
+
+// CreateClientResult records the outcome of one client of a concurrent step
+func (db *DB) CreateClientResult(c *ClientResult) error {
+	id, err := db.insert(nil, `
+		INSERT INTO client_results (run_id, step_number, client, repo_dir, started_at, duration_ms, bytes,
+			push_attempts, rejected_pushes, lock_errors, status, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.RunID, c.StepNumber, c.Client, c.RepoDir, c.StartedAt.Format(time.RFC3339), c.DurationMs, c.Bytes,
+		c.PushAttempts, c.RejectedPushes, c.LockErrors, c.Status, c.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create client result: %w", err)
+	}
+
+	c.ID = id
+	return nil
+}
+
+// ListClientResults lists the client results of a test run by step and client
+func (db *DB) ListClientResults(runID int64) ([]*ClientResult, error) {
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, client, repo_dir, started_at, duration_ms, bytes,
+			push_attempts, rejected_pushes, lock_errors, status, error
+		FROM client_results WHERE run_id = ? ORDER BY step_number, client`, runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list client results: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []*ClientResult
+	for rows.Next() {
+		var c ClientResult
+		var startedAt string
+		var errorMsg sql.NullString
+
+		if err := rows.Scan(&c.ID, &c.RunID, &c.StepNumber, &c.Client, &c.RepoDir, &startedAt, &c.DurationMs, &c.Bytes,
+			&c.PushAttempts, &c.RejectedPushes, &c.LockErrors, &c.Status, &errorMsg); err != nil {
+			return nil, fmt.Errorf("failed to scan client result: %w", err)
+		}
+
+		c.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+		c.Error = errorMsg.String
+		clients = append(clients, &c)
+	}
+
+	return clients, nil
+}
+
+// CreateConcurrencyResult records the aggregate outcome of a concurrent step
+func (db *DB) CreateConcurrencyResult(c *ConcurrencyResult) error {
+	id, err := db.insert(nil, `
+		INSERT INTO concurrency_results (run_id, step_number, clients, failed_clients, total_bytes, wall_ms,
+			push_attempts, rejected_pushes, lock_errors, recorded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.RunID, c.StepNumber, c.Clients, c.FailedClients, c.TotalBytes, c.WallMs,
+		c.PushAttempts, c.RejectedPushes, c.LockErrors, c.RecordedAt.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create concurrency result: %w", err)
+	}
+
+	c.ID = id
+	return nil
+}
+
+// ListConcurrencyResults lists the concurrent steps of a test run
+func (db *DB) ListConcurrencyResults(runID int64) ([]*ConcurrencyResult, error) {
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, clients, failed_clients, total_bytes, wall_ms,
+			push_attempts, rejected_pushes, lock_errors, recorded_at
+		FROM concurrency_results WHERE run_id = ? ORDER BY step_number`, runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list concurrency results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*ConcurrencyResult
+	for rows.Next() {
+		var c ConcurrencyResult
+		var recordedAt string
+
+		if err := rows.Scan(&c.ID, &c.RunID, &c.StepNumber, &c.Clients, &c.FailedClients, &c.TotalBytes, &c.WallMs,
+			&c.PushAttempts, &c.RejectedPushes, &c.LockErrors, &recordedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan concurrency result: %w", err)
+		}
+
+		c.RecordedAt, _ = time.Parse(time.RFC3339, recordedAt)
+		results = append(results, &c)
+	}
+
+	return results, nil
+}
+
+// DeleteConcurrencyResults removes the client and aggregate results of a step, e.g. before it is re-run
+func (db *DB) DeleteConcurrencyResults(runID int64, stepNumber int) error {
+	if _, err := db.exec(`DELETE FROM client_results WHERE run_id = ? AND step_number = ?`, runID, stepNumber); err != nil {
+		return fmt.Errorf("failed to delete client results: %w", err)
+	}
+	if _, err := db.exec(`DELETE FROM concurrency_results WHERE run_id = ? AND step_number = ?`, runID, stepNumber); err != nil {
+		return fmt.Errorf("failed to delete concurrency results: %w", err)
+	}
+	return nil
+}
+
+// CreateLockResult records the outcome of a locking API test
+func (db *DB) CreateLockResult(l *LockResult) error {
+	id, err := db.insert(nil, `
+		INSERT INTO lock_results (run_id, step_number, server_type, protocol, supported, path, visible, enforced, released, message, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		l.RunID, l.StepNumber, l.ServerType, l.Protocol, l.Supported, l.Path, l.Visible, l.Enforced, l.Released, l.Message,
+		l.CheckedAt.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create lock result: %w", err)
+	}
+
+	l.ID = id
+	return nil
+}
+
+// ListLockResults lists the locking API tests of a run, or of all runs if runID is 0, oldest first
+func (db *DB) ListLockResults(runID int64) ([]*LockResult, error) {
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, server_type, protocol, supported, path, visible, enforced, released, message, checked_at
+		FROM lock_results WHERE ? = 0 OR run_id = ? ORDER BY checked_at, id`, runID, runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lock results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*LockResult
+	for rows.Next() {
+		var l LockResult
+		var path, message sql.NullString
+		var checkedAt string
+
+		if err := rows.Scan(&l.ID, &l.RunID, &l.StepNumber, &l.ServerType, &l.Protocol, &l.Supported, &path,
+			&l.Visible, &l.Enforced, &l.Released, &message, &checkedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan lock result: %w", err)
+		}
+
+		l.Path = path.String
+		l.Message = message.String
+		l.CheckedAt, _ = time.Parse(time.RFC3339, checkedAt)
+		results = append(results, &l)
+	}
+
+	return results, nil
+}
+
+// DeleteLockResults removes the locking API tests of a step, e.g. before it is re-run
+func (db *DB) DeleteLockResults(runID int64, stepNumber int) error {
+	if _, err := db.exec(`DELETE FROM lock_results WHERE run_id = ? AND step_number = ?`, runID, stepNumber); err != nil {
+		return fmt.Errorf("failed to delete lock results: %w", err)
+	}
+	return nil
+}
+
+// CreateLargeFileResult records the outcome of the large file upload test
+func (db *DB) CreateLargeFileResult(l *LargeFileResult) error {
+	id, err := db.insert(nil, `
+		INSERT INTO large_file_results (run_id, step_number, path, size_bytes, interrupted, first_ms, resume_ms,
+			resume_sent_bytes, resumed, adapter, status, message, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		l.RunID, l.StepNumber, l.Path, l.SizeBytes, l.Interrupted, l.FirstMs, l.ResumeMs,
+		l.ResumeSentBytes, l.Resumed, l.Adapter, l.Status, l.Message, l.CheckedAt.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create large file result: %w", err)
+	}
+
+	l.ID = id
+	return nil
+}
+
+// ListLargeFileResults lists the large file upload tests of a run, or of all runs if runID is 0, oldest first
+func (db *DB) ListLargeFileResults(runID int64) ([]*LargeFileResult, error) {
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, path, size_bytes, interrupted, first_ms, resume_ms, resume_sent_bytes, resumed,
+			adapter, status, message, checked_at
+		FROM large_file_results WHERE ? = 0 OR run_id = ? ORDER BY checked_at, id`, runID, runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list large file results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*LargeFileResult
+	for rows.Next() {
+		var l LargeFileResult
+		var adapter, message sql.NullString
+		var checkedAt string
+
+		if err := rows.Scan(&l.ID, &l.RunID, &l.StepNumber, &l.Path, &l.SizeBytes, &l.Interrupted, &l.FirstMs, &l.ResumeMs,
+			&l.ResumeSentBytes, &l.Resumed, &adapter, &l.Status, &message, &checkedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan large file result: %w", err)
+		}
+
+		l.Adapter = adapter.String
+		l.Message = message.String
+		l.CheckedAt, _ = time.Parse(time.RFC3339, checkedAt)
+		results = append(results, &l)
+	}
+
+	return results, rows.Err()
+}
+
+// DeleteLargeFileResults removes the large file upload tests of a step, e.g. before it is re-run
+func (db *DB) DeleteLargeFileResults(runID int64, stepNumber int) error {
+	if _, err := db.exec(`DELETE FROM large_file_results WHERE run_id = ? AND step_number = ?`, runID, stepNumber); err != nil {
+		return fmt.Errorf("failed to delete large file results: %w", err)
+	}
+	return nil
+}
+
+// CreateCadenceResult records the outcome of the commit cadence test
+func (db *DB) CreateCadenceResult(c *CadenceResult) error {
+	id, err := db.insert(nil, `
+		INSERT INTO cadence_results (run_id, step_number, path, commits, file_size, total_ms, mean_commit_ms,
+			median_commit_ms, max_commit_ms, mean_push_ms, batch_requests, sent_bytes, status, message, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.RunID, c.StepNumber, c.Path, c.Commits, c.FileSize, c.TotalMs, c.MeanCommitMs,
+		c.MedianCommitMs, c.MaxCommitMs, c.MeanPushMs, c.BatchRequests, c.SentBytes, c.Status, c.Message,
+		c.CheckedAt.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create cadence result: %w", err)
+	}
+
+	c.ID = id
+	return nil
+}
+
+// ListCadenceResults lists the commit cadence tests of a run, or of all runs if runID is 0, oldest first
+func (db *DB) ListCadenceResults(runID int64) ([]*CadenceResult, error) {
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, path, commits, file_size, total_ms, mean_commit_ms, median_commit_ms,
+			max_commit_ms, mean_push_ms, batch_requests, sent_bytes, status, message, checked_at
+		FROM cadence_results WHERE ? = 0 OR run_id = ? ORDER BY checked_at, id`, runID, runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cadence results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*CadenceResult
+	for rows.Next() {
+		var c CadenceResult
+		var message sql.NullString
+		var checkedAt string
+
+		if err := rows.Scan(&c.ID, &c.RunID, &c.StepNumber, &c.Path, &c.Commits, &c.FileSize, &c.TotalMs, &c.MeanCommitMs,
+			&c.MedianCommitMs, &c.MaxCommitMs, &c.MeanPushMs, &c.BatchRequests, &c.SentBytes, &c.Status, &message,
+			&checkedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cadence result: %w", err)
+		}
+
+		c.Message = message.String
+		c.CheckedAt, _ = time.Parse(time.RFC3339, checkedAt)
+		results = append(results, &c)
+	}
+
+	return results, rows.Err()
+}
+
+// DeleteCadenceResults removes the commit cadence tests of a step, e.g. before it is re-run
+func (db *DB) DeleteCadenceResults(runID int64, stepNumber int) error {
+	if _, err := db.exec(`DELETE FROM cadence_results WHERE run_id = ? AND step_number = ?`, runID, stepNumber); err != nil {
+		return fmt.Errorf("failed to delete cadence results: %w", err)
+	}
+	return nil
+}
+
+// CreateLFSBatchRequest records a Batch API request together with its objects
+func (db *DB) CreateLFSBatchRequest(b *LFSBatchRequest) error {
+	var id int64
+	err := db.write(func(tx querier) error {
+		var err error
+		id, err = db.insert(tx, `
+			INSERT INTO lfs_batch_requests (run_id, step_number, operation, transfers, ref, status_code, transfer, message, duration_ms, requested_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			b.RunID, b.StepNumber, b.Operation, strings.Join(b.Transfers, ","), b.Ref, b.StatusCode, b.Transfer, b.Message,
+			b.DurationMs, b.RequestedAt.Format(time.RFC3339),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create LFS batch request: %w", err)
+		}
+
+		for _, obj := range b.Objects {
+			obj.ID, err = db.insert(tx, `
+				INSERT INTO lfs_batch_objects (request_id, oid, size_bytes, actions, href, verify_href, expires_in, error_code, error_message)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				id, obj.OID, obj.Size, strings.Join(obj.Actions, ","), obj.Href, obj.VerifyHref, obj.ExpiresIn,
+				obj.ErrorCode, obj.ErrorMessage,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create LFS batch object: %w", err)
+			}
+			obj.RequestID = id
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	b.ID = id
+	return nil
+}
+
+// ListLFSBatchRequests lists the Batch API requests of a run with their objects, in the order they were made
+func (db *DB) ListLFSBatchRequests(runID int64) ([]*LFSBatchRequest, error) {
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, operation, transfers, ref, status_code, transfer, message, duration_ms, requested_at
+		FROM lfs_batch_requests WHERE run_id = ? ORDER BY id`, runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list LFS batch requests: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []*LFSBatchRequest
+	byID := make(map[int64]*LFSBatchRequest)
+	for rows.Next() {
+		var b LFSBatchRequest
+		var transfers, ref, transfer, message sql.NullString
+		var requestedAt string
+
+		if err := rows.Scan(&b.ID, &b.RunID, &b.StepNumber, &b.Operation, &transfers, &ref, &b.StatusCode,
+			&transfer, &message, &b.DurationMs, &requestedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan LFS batch request: %w", err)
+		}
+
+		b.Transfers = splitList(transfers.String)
+		b.Ref = ref.String
+		b.Transfer = transfer.String
+		b.Message = message.String
+		b.RequestedAt, _ = time.Parse(time.RFC3339, requestedAt)
+		requests = append(requests, &b)
+		byID[b.ID] = &b
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list LFS batch requests: %w", err)
+	}
+
+	objRows, err := db.query(`
+		SELECT o.id, o.request_id, o.oid, o.size_bytes, o.actions, o.href, o.verify_href, o.expires_in, o.error_code, o.error_message
+		FROM lfs_batch_objects o JOIN lfs_batch_requests b ON b.id = o.request_id
+		WHERE b.run_id = ? ORDER BY o.id`, runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list LFS batch objects: %w", err)
+	}
+	defer objRows.Close()
+
+	for objRows.Next() {
+		var obj LFSBatchObject
+		var actions, href, verifyHref, errorMessage sql.NullString
+		var expiresIn, errorCode sql.NullInt64
+
+		if err := objRows.Scan(&obj.ID, &obj.RequestID, &obj.OID, &obj.Size, &actions, &href, &verifyHref,
+			&expiresIn, &errorCode, &errorMessage); err != nil {
+			return nil, fmt.Errorf("failed to scan LFS batch object: %w", err)
+		}
+
+		obj.Actions = splitList(actions.String)
+		obj.Href = href.String
+		obj.VerifyHref = verifyHref.String
+		obj.ExpiresIn = int(expiresIn.Int64)
+		obj.ErrorCode = int(errorCode.Int64)
+		obj.ErrorMessage = errorMessage.String
+		if b := byID[obj.RequestID]; b != nil {
+			b.Objects = append(b.Objects, &obj)
+		}
+	}
+
+	return requests, nil
+}
+
+// DeleteLFSBatchRequests removes the Batch API requests of a step, e.g. before it is re-run
+func (db *DB) DeleteLFSBatchRequests(runID int64, stepNumber int) error {
+	if _, err := db.exec(`
+		DELETE FROM lfs_batch_objects WHERE request_id IN
+			(SELECT id FROM lfs_batch_requests WHERE run_id = ? AND step_number = ?)`, runID, stepNumber); err != nil {
+		return fmt.Errorf("failed to delete LFS batch objects: %w", err)
+	}
+	if _, err := db.exec(`DELETE FROM lfs_batch_requests WHERE run_id = ? AND step_number = ?`, runID, stepNumber); err != nil {
+		return fmt.Errorf("failed to delete LFS batch requests: %w", err)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated column value; an empty value has no elements
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// ErrDuplicateChecksum is returned by CreateChecksum for a file whose checksum the step
+// already has
+var ErrDuplicateChecksum = errors.New("checksum is already stored")
+
+// CreateChecksum creates a new checksum record
+func (db *DB) CreateChecksum(cs *Checksum) error {
+	return db.write(func(q querier) error {
+		return db.createChecksum(q, cs)
+	})
+}
+
+// createChecksum creates a checksum record within a write
+func (db *DB) createChecksum(q querier, cs *Checksum) error {
+	var snapshotID *int64
+	if cs.SnapshotID != 0 {
+		snapshotID = &cs.SnapshotID
+	}
+
+	id, err := db.insert(q, `
+		INSERT INTO checksums (run_id, step_number, snapshot_id, file_path, crc32, size_bytes, computed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		cs.RunID, cs.StepNumber, snapshotID, cs.FilePath, cs.CRC32, cs.SizeBytes,
+		cs.ComputedAt.Format(time.RFC3339),
+	)
+	if db.dialect.isUniqueViolation(err) {
+		return fmt.Errorf("%s of step %d of run %d: %w", cs.FilePath, cs.StepNumber, cs.RunID, ErrDuplicateChecksum)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create checksum: %w", err)
+	}
+
+	cs.ID = id
+	return nil
+}
+
+// UpsertChecksum creates a checksum record, or replaces the one the step already has for
+// the file, e.g. when a step is run again
+func (db *DB) UpsertChecksum(cs *Checksum) error {
+	return db.write(func(q querier) error {
+		return db.upsertChecksum(q, cs)
+	})
+}
+
+// upsertChecksum creates a checksum record, or updates in place the one the step already
+// has for the file, within a write. Updating rather than inserting keeps the row's ID and
+// needs no unique index, so the duplicates of databases that lack it are updated too.
+func (db *DB) upsertChecksum(q querier, cs *Checksum) error {
+	if cs.StepNumber == 0 {
+		return db.createChecksum(q, cs)
+	}
+	var snapshotID *int64
+	if cs.SnapshotID != 0 {
+		snapshotID = &cs.SnapshotID
+	}
+
+	err := q.QueryRow(db.dialect.rebind(`
+		UPDATE checksums SET snapshot_id = ?, crc32 = ?, size_bytes = ?, computed_at = ?
+		WHERE run_id = ? AND step_number = ? AND file_path = ?
+		RETURNING id`),
+		snapshotID, cs.CRC32, cs.SizeBytes, cs.ComputedAt.Format(time.RFC3339),
+		cs.RunID, cs.StepNumber, cs.FilePath,
+	).Scan(&cs.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db.createChecksum(q, cs)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store checksum: %w", err)
+	}
+	return nil
+}
+
+// ErrStepHasChecksums is returned by StoreSnapshot for a step that has checksums already,
+// unless they are to be replaced
+var ErrStepHasChecksums = errors.New("checksums are not replaced")
+
+// StoreSnapshot creates a snapshot of a run together with the checksums that each hands
+// to its store function, in one transaction, so an error partway through stores none of
+// them. With replace, the checksums replace those the step has, of files the snapshot
+// lacks included; without, a step that has checksums is refused with ErrStepHasChecksums.
+// Labelled snapshots, of step 0, never replace anything. The checksums are given the
+// run, step and ID of the snapshot.
+func (db *DB) StoreSnapshot(s *Snapshot, replace bool, each func(store func(cs *Checksum) error) error) error {
+	return db.write(func(q querier) error {
+		if !replace && s.StepNumber > 0 {
+			var n int
+			err := q.QueryRow(db.dialect.rebind(`SELECT COUNT(*) FROM checksums WHERE run_id = ? AND step_number = ?`),
+				s.RunID, s.StepNumber).Scan(&n)
+			if err != nil {
+				return fmt.Errorf("failed to count checksums: %w", err)
+			}
+			if n > 0 {
+				return fmt.Errorf("step %d of run %d already has %d checksums: %w", s.StepNumber, s.RunID, n, ErrStepHasChecksums)
+			}
+		}
+		if err := db.createSnapshot(q, s); err != nil {
+			return err
+		}
+
+		err := each(func(cs *Checksum) error {
+			cs.RunID, cs.StepNumber, cs.SnapshotID = s.RunID, s.StepNumber, s.ID
+			if replace {
+				return db.upsertChecksum(q, cs)
+			}
+			return db.createChecksum(q, cs)
+		})
+		if err != nil || !replace || s.StepNumber == 0 {
+			return err
+		}
+		_, err = q.Exec(db.dialect.rebind(`
+			DELETE FROM checksums WHERE run_id = ? AND step_number = ? AND (snapshot_id IS NULL OR snapshot_id <> ?)`),
+			s.RunID, s.StepNumber, s.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to delete replaced checksums: %w", err)
+		}
+		return nil
+	})
+}
+
+// ChecksumsUnique reports whether the database stores one checksum per file and step.
+// It does not while it holds duplicates stored before it did; DedupeChecksums removes them.
+func (db *DB) ChecksumsUnique() bool {
+	return db.checksumsUnique
+}
+
+// DedupeChecksums removes the checksums that a file has in a step besides the one stored
+// last, and returns how many it removed, or with dryRun how many it would remove. Once
+// none are left, the database keeps each file's checksums of a step unique.
+func (db *DB) DedupeChecksums(dryRun bool) (int64, error) {
+	const duplicates = `FROM checksums WHERE step_number > 0 AND id NOT IN (
+		SELECT MAX(id) FROM checksums WHERE step_number > 0 GROUP BY run_id, step_number, file_path)`
+
+	if dryRun {
+		var n int64
+		if err := db.queryRow(`SELECT COUNT(*) ` + duplicates).Scan(&n); err != nil {
+			return 0, fmt.Errorf("failed to count duplicate checksums: %w", err)
+		}
+		return n, nil
+	}
+
+	result, err := db.exec(`DELETE ` + duplicates)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete duplicate checksums: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete duplicate checksums: %w", err)
+	}
+	if err := db.createChecksumIndex(); err != nil {
+		return n, fmt.Errorf("failed to create unique checksum index: %w", err)
+	}
+	db.checksumsUnique = true
+	return n, nil
+}
+
+// ListChecksums lists all checksums for a test run and step
+func (db *DB) ListChecksums(runID int64, stepNumber int) ([]*Checksum, error) {
+	return db.queryChecksums(`
+		SELECT id, run_id, step_number, snapshot_id, file_path, crc32, size_bytes, computed_at
+		FROM checksums WHERE run_id = ? AND step_number = ? ORDER BY file_path`, runID, stepNumber,
+	)
+}
+
+// ListChecksumsBySnapshot lists all checksums belonging to a snapshot
+func (db *DB) ListChecksumsBySnapshot(snapshotID int64) ([]*Checksum, error) {
+	return db.queryChecksums(`
+		SELECT id, run_id, step_number, snapshot_id, file_path, crc32, size_bytes, computed_at
+		FROM checksums WHERE snapshot_id = ? ORDER BY file_path`, snapshotID,
+	)
+}
+
+// queryChecksums runs a checksum SELECT and scans the resulting rows
+func (db *DB) queryChecksums(query string, args ...interface{}) ([]*Checksum, error) {
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checksums: %w", err)
+	}
+	defer rows.Close()
+
+	var checksums []*Checksum
+	for rows.Next() {
+		var cs Checksum
+		var snapshotID sql.NullInt64
+		var computedAt string
+
+		err := rows.Scan(
+			&cs.ID, &cs.RunID, &cs.StepNumber, &snapshotID, &cs.FilePath,
+			&cs.CRC32, &cs.SizeBytes, &computedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan checksum: %w", err)
+		}
+
+		cs.SnapshotID = snapshotID.Int64
+		cs.ComputedAt, _ = time.Parse(time.RFC3339, computedAt)
+		checksums = append(checksums, &cs)
+	}
+
+	return checksums, nil
+}
+
+// CreateSnapshot creates a new checksum snapshot record
+func (db *DB) CreateSnapshot(s *Snapshot) error {
+	return db.write(func(q querier) error {
+		return db.createSnapshot(q, s)
+	})
+}
+
+// createSnapshot creates a snapshot record within a write
+func (db *DB) createSnapshot(q querier, s *Snapshot) error {
+	var label *string
+	if s.Label != "" {
+		label = &s.Label
+	}
+
+	id, err := db.insert(q, `
+		INSERT INTO snapshots (run_id, step_number, label, created_at)
+		VALUES (?, ?, ?, ?)`,
+		s.RunID, s.StepNumber, label, s.CreatedAt.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	s.ID = id
+
+	// Link operations performed since the previous snapshot to this one
+	_, err = q.Exec(db.dialect.rebind(`
+		UPDATE operations SET snapshot_after_id = ?
+		WHERE run_id = ? AND snapshot_after_id IS NULL`), s.ID, s.RunID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to link operations to snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// GetSnapshot retrieves a snapshot by ID
+func (db *DB) GetSnapshot(id int64) (*Snapshot, error) {
+	snapshots, err := db.querySnapshots(`
+		SELECT id, run_id, step_number, label, created_at
+		FROM snapshots WHERE id = ?`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("snapshot %d not found", id)
+	}
+
+	return snapshots[0], nil
+}
+
+// GetSnapshotByLabel retrieves the most recent snapshot with the given label in a run
+func (db *DB) GetSnapshotByLabel(runID int64, label string) (*Snapshot, error) {
+	snapshots, err := db.querySnapshots(`
+		SELECT id, run_id, step_number, label, created_at
+		FROM snapshots WHERE run_id = ? AND label = ? ORDER BY id DESC LIMIT 1`, runID, label,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no snapshot labelled '%s' in run %d", label, runID)
+	}
+
+	return snapshots[0], nil
+}
+
+// ListSnapshots lists all snapshots for a test run in creation order
+func (db *DB) ListSnapshots(runID int64) ([]*Snapshot, error) {
+	return db.querySnapshots(`
+		SELECT id, run_id, step_number, label, created_at
+		FROM snapshots WHERE run_id = ? ORDER BY id`, runID,
+	)
+}
+
+// querySnapshots runs a snapshot SELECT and scans the resulting rows
+func (db *DB) querySnapshots(query string, args ...interface{}) ([]*Snapshot, error) {
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []*Snapshot
+	for rows.Next() {
+		var s Snapshot
+		var label sql.NullString
+		var createdAt string
+
+		if err := rows.Scan(&s.ID, &s.RunID, &s.StepNumber, &label, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+
+		s.Label = label.String
+		s.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		snapshots = append(snapshots, &s)
+	}
+
+	return snapshots, nil
+}
+
+// CreateRepositorySize creates a new repository size record
+func (db *DB) CreateRepositorySize(rs *RepositorySize) error {
+	id, err := db.insert(nil, `
+		INSERT INTO repository_sizes (run_id, step_number, location, size_bytes, file_count, measured_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		rs.RunID, rs.StepNumber, rs.Location, rs.SizeBytes, rs.FileCount,
+		rs.MeasuredAt.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create repository size: %w", err)
+	}
+
+	rs.ID = id
+	return nil
+}
+
+// DeleteRepositorySizes removes the size measurements of a step, e.g. before it is re-run
+func (db *DB) DeleteRepositorySizes(runID int64, stepNumber int) error {
+	if _, err := db.exec(`DELETE FROM repository_sizes WHERE run_id = ? AND step_number = ?`, runID, stepNumber); err != nil {
+		return fmt.Errorf("failed to delete repository sizes: %w", err)
+	}
+	return nil
+}
+
+// ListRepositorySizes lists all repository sizes for a test run
+func (db *DB) ListRepositorySizes(runID int64) ([]*RepositorySize, error) {
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, location, size_bytes, file_count, measured_at
+		FROM repository_sizes WHERE run_id = ? ORDER BY step_number, location`, runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository sizes: %w", err)
+	}
+	defer rows.Close()
+
+	var sizes []*RepositorySize
+	for rows.Next() {
+		var rs RepositorySize
+		var measuredAt string
+
+		err := rows.Scan(
+			&rs.ID, &rs.RunID, &rs.StepNumber, &rs.Location,
+			&rs.SizeBytes, &rs.FileCount, &measuredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan repository size: %w", err)
+		}
+
+		rs.MeasuredAt, _ = time.Parse(time.RFC3339, measuredAt)
+		sizes = append(sizes, &rs)
+	}
+
+	return sizes, nil
+}
+
+// GetChecksumsByRunAndStep retrieves all checksums for a specific run and step
+func (db *DB) GetChecksumsByRunAndStep(runID int64, stepNumber int) ([]*Checksum, error) {
+	return db.ListChecksums(runID, stepNumber)
+}
+
+// Rows wraps sql.Rows for use in query commands
+type Rows = sql.Rows
+
+// QueryRaw executes a raw SQL query and returns rows
+func (db *DB) QueryRaw(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.query(query, args...)
+}
+
+// QueryRowRaw executes a raw SQL query and returns a single row
+func (db *DB) QueryRowRaw(query string, args ...interface{}) *sql.Row {
+	return db.queryRow(query, args...)
+}
+
+// runMigrations applies database schema migrations for existing databases
+func (db *DB) runMigrations() error {
+	if err := db.addColumnIfMissing("test_runs", "pid", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("test_runs", "work_dir", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("test_runs", "network_profile", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("test_runs", "timing_summary", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("checksums", "snapshot_id", "INTEGER REFERENCES snapshots(id)"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("operations", "snapshot_before_id", "INTEGER REFERENCES snapshots(id)"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("operations", "snapshot_after_id", "INTEGER REFERENCES snapshots(id)"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("operations", "repo", "TEXT"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("step_results", "interface", "TEXT"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("step_results", "rx_bytes", "INTEGER"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("step_results", "tx_bytes", "INTEGER"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("step_results", "step_name", "TEXT"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("operations", "server_event", "TEXT"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("operations", "error_kind", "TEXT"); err != nil {
+		return err
+	}
+
+	if err := db.addColumnIfMissing("operations", "transfer_adapter", "TEXT"); err != nil {
+		return err
+	}
+
+	// Resource usage of the command of an operation, when sampled
+	for _, column := range []struct{ name, definition string }{
+		{"cpu_ms", "INTEGER"},
+		{"peak_cpu_pct", "REAL"},
+		{"peak_rss_bytes", "INTEGER"},
+		{"avg_rss_bytes", "INTEGER"},
+		{"read_bytes", "INTEGER"},
+		{"write_bytes", "INTEGER"},
+	} {
+		if err := db.addColumnIfMissing("operations", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	// Indexes on migrated columns must be created after the columns exist
+	if _, err := db.exec(`CREATE INDEX IF NOT EXISTS idx_checksums_snapshot ON checksums(snapshot_id)`); err != nil {
+		return fmt.Errorf("failed to create snapshot index: %w", err)
+	}
+
+	// A database holding duplicate checksums from before the index works on without it,
+	// storing duplicates as it did, until DedupeChecksums removes them
+	db.checksumsUnique = db.createChecksumIndex() == nil
+
+	return nil
+}
+
+// createChecksumIndex makes the checksum of a file unique within a step. Labelled
+// snapshots, whose checksums have step 0, are told apart by their snapshot instead.
+func (db *DB) createChecksumIndex() error {
+	_, err := db.exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_checksums_step_file
+		ON checksums(run_id, step_number, file_path) WHERE step_number > 0`)
+	return err
+}
+
+// addColumnIfMissing adds a column to a table unless it already exists
+func (db *DB) addColumnIfMissing(table, column, definition string) error {
+	var count int
+	err := db.queryRow(db.dialect.columnQuery(), table, column).Scan(&count)
 
 	if err != nil {
-		return fmt.Errorf("failed to check for pid column: %w", err)
+		return fmt.Errorf("failed to check for %s column: %w", column, err)
 	}
 
-	// Add pid column if it doesn't exist
-	if !pidExists {
-		_, err := db.conn.Exec(`ALTER TABLE test_runs ADD COLUMN pid INTEGER DEFAULT 0`)
+	if count == 0 {
+		_, err := db.exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, db.dialect.ddl(definition)))
 		if err != nil {
-			return fmt.Errorf("failed to add pid column: %w", err)
+			return fmt.Errorf("failed to add %s column: %w", column, err)
 		}
 	}
 
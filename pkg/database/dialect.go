@@ -0,0 +1,247 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Database drivers
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
+// Drivers lists the supported database drivers
+var Drivers = []string{DriverSQLite, DriverPostgres}
+
+// dialect hides the differences between the SQL of the database drivers.
+// Queries are written for SQLite, with ? placeholders; the dialect adapts them.
+type dialect interface {
+	// prepare configures a new connection and creates the schema
+	prepare(conn *sql.DB) error
+	// rebind rewrites the ? placeholders of a query for the driver
+	rebind(query string) string
+	// args converts query arguments the driver cannot store as they are
+	args(args []interface{}) []interface{}
+	// insert runs an INSERT and returns the ID of the new row
+	insert(q querier, query string, args ...interface{}) (int64, error)
+	// columnQuery returns a query that counts the columns named $2 of table $1
+	columnQuery() string
+	// ddl rewrites column definitions for the driver
+	ddl(definition string) string
+	// isUniqueViolation reports whether err is the violation of a unique index
+	isUniqueViolation(err error) bool
+}
+
+// querier is implemented by *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// sqliteDialect is the dialect of local SQLite database files
+type sqliteDialect struct{}
+
+func (sqliteDialect) prepare(conn *sql.DB) error {
+	// Enable WAL mode for better concurrency
+	// WAL allows multiple readers while one writer is active
+	if _, err := conn.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		return fmt.Errorf("failed to enable WAL mode: %w", err)
+	}
+
+	// Set busy timeout to 5 seconds
+	// If another process holds the lock, retry for up to 5 seconds before failing;
+	// writers of this process take turns (see writer.go)
+	if _, err := conn.Exec("PRAGMA busy_timeout=5000"); err != nil {
+		return fmt.Errorf("failed to set busy timeout: %w", err)
+	}
+
+	// Enable foreign keys
+	if _, err := conn.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		return fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+
+	if _, err := conn.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+	return nil
+}
+
+func (sqliteDialect) rebind(query string) string { return query }
+
+func (sqliteDialect) args(args []interface{}) []interface{} { return args }
+
+func (sqliteDialect) insert(q querier, query string, args ...interface{}) (int64, error) {
+	result, err := q.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return id, nil
+}
+
+func (sqliteDialect) columnQuery() string {
+	return `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+}
+
+func (sqliteDialect) ddl(definition string) string { return definition }
+
+func (sqliteDialect) isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// postgresDialect is the dialect of a shared PostgreSQL server
+type postgresDialect struct{}
+
+// postgresTypes maps SQLite column types to PostgreSQL ones; IDs and sizes need 64 bits
+var postgresTypes = strings.NewReplacer(
+	"INTEGER PRIMARY KEY AUTOINCREMENT", "BIGSERIAL PRIMARY KEY",
+	"INTEGER", "BIGINT",
+	"BLOB", "BYTEA",
+	"REAL", "DOUBLE PRECISION",
+)
+
+func (d postgresDialect) prepare(conn *sql.DB) error {
+	// Several machines may open the database at once; only one creates the schema
+	tx, err := conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(4242)`); err != nil {
+		return fmt.Errorf("failed to lock schema: %w", err)
+	}
+	if _, err := tx.Exec(d.ddl(schema)); err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+	return nil
+}
+
+func (postgresDialect) rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (postgresDialect) args(args []interface{}) []interface{} {
+	// Flags are stored in BIGINT columns, as in SQLite
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		converted[i] = arg
+		if flag, ok := arg.(bool); ok {
+			converted[i] = 0
+			if flag {
+				converted[i] = 1
+			}
+		}
+	}
+	return converted
+}
+
+func (postgresDialect) insert(q querier, query string, args ...interface{}) (int64, error) {
+	var id int64
+	if err := q.QueryRow(query+" RETURNING id", args...).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (postgresDialect) columnQuery() string {
+	return `SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`
+}
+
+func (postgresDialect) ddl(definition string) string {
+	return postgresTypes.Replace(definition)
+}
+
+func (postgresDialect) isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" // unique_violation
+}
+
+// exec runs a statement written with ? placeholders, as a write of its own
+func (db *DB) exec(query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := db.write(func(q querier) error {
+		var err error
+		result, err = q.Exec(db.dialect.rebind(query), db.dialect.args(args)...)
+		return err
+	})
+	return result, err
+}
+
+// query runs a query written with ? placeholders
+func (db *DB) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.conn.QueryContext(db.context(), db.dialect.rebind(query), db.dialect.args(args)...)
+}
+
+// queryRow runs a single-row query written with ? placeholders
+func (db *DB) queryRow(query string, args ...interface{}) *sql.Row {
+	return db.conn.QueryRowContext(db.context(), db.dialect.rebind(query), db.dialect.args(args)...)
+}
+
+// insert runs an INSERT written with ? placeholders and returns the new row's ID: on q
+// within a write, or as a write of its own if q is nil
+func (db *DB) insert(q querier, query string, args ...interface{}) (int64, error) {
+	if q != nil {
+		return db.dialect.insert(q, db.dialect.rebind(query), db.dialect.args(args)...)
+	}
+	var id int64
+	err := db.write(func(q querier) error {
+		var err error
+		id, err = db.dialect.insert(q, db.dialect.rebind(query), db.dialect.args(args)...)
+		return err
+	})
+	return id, err
+}
+
+// Driver returns the database driver, DriverSQLite or DriverPostgres
+func (db *DB) Driver() string {
+	if _, ok := db.dialect.(postgresDialect); ok {
+		return DriverPostgres
+	}
+	return DriverSQLite
+}
+
+// Redact hides the password of a PostgreSQL URL, so it can be displayed
+func Redact(location string) string {
+	if !IsPostgresURL(location) {
+		return location
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return "postgres://..."
+	}
+	return u.Redacted()
+}
+
+// IsPostgresURL reports whether a database location is a PostgreSQL connection URL
+// rather than the path of an SQLite file
+func IsPostgresURL(location string) bool {
+	return strings.HasPrefix(location, "postgres://") || strings.HasPrefix(location, "postgresql://")
+}
@@ -0,0 +1,292 @@
+package database
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mslinn/git-lfs-test/pkg/secret"
+)
+
+// KeyEnv holds the passphrase of an encrypted database. The value "keychain" reads
+// the passphrase from the system keychain instead.
+const KeyEnv = "LFS_TEST_DB_KEY"
+
+// KeychainKey is the KeyEnv value that reads the passphrase from the system keychain
+const KeychainKey = "keychain"
+
+// Keychain entry holding the passphrase: service "lfst", account "database"
+const (
+	keychainService = "lfst"
+	keychainAccount = "database"
+)
+
+// encryptedMagic starts every encrypted database file. It is followed by the key
+// derivation salt, the AES-GCM nonce, and the encrypted SQLite file.
+const encryptedMagic = "LFSTENC1"
+
+const saltSize = 16
+
+// plainName is the name of the decrypted copy in its private directory
+const plainName = "lfs-test.db"
+
+// errLocked is returned by lockFile when another process holds the lock
+var errLocked = errors.New("locked")
+
+// keyIterations is the PBKDF2 work factor; tests lower it
+var keyIterations = 600000
+
+// encryption holds the state of a database opened from an encrypted file.
+// SQLite works on a decrypted copy in a private directory, which Close encrypts
+// back over the original file and removes.
+type encryption struct {
+	path       string   // Encrypted database file
+	passphrase string   // Key derivation input
+	plainDir   string   // Private directory holding the decrypted copy
+	lock       *os.File // Keeps other processes from opening the same file
+}
+
+// IsEncrypted reports whether the file at path is an encrypted database.
+// A missing file is not encrypted.
+func IsEncrypted(path string) (bool, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer file.Close()
+
+	header := make([]byte, len(encryptedMagic))
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false, nil // Shorter than the header: empty or plain SQLite
+	}
+	return string(header) == encryptedMagic, nil
+}
+
+// LookupKey returns the database passphrase from KeyEnv or, if that is unset or
+// KeychainKey, from the system keychain. It returns "" if neither has one.
+func LookupKey() (string, error) {
+	if key := os.Getenv(KeyEnv); key != "" && key != KeychainKey {
+		secret.Register(key)
+		return key, nil
+	}
+
+	key, err := keychainPassphrase()
+	if err != nil {
+		if os.Getenv(KeyEnv) == KeychainKey {
+			return "", err
+		}
+		return "", nil
+	}
+	secret.Register(key)
+	return key, nil
+}
+
+// keychainPassphrase reads the passphrase from the macOS keychain or, elsewhere,
+// the Secret Service (GNOME Keyring, KWallet) through secret-tool
+func keychainPassphrase() (string, error) {
+	key, err := secret.Keychain(keychainService, keychainAccount)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the database key from the keychain: %w", err)
+	}
+	return key, nil
+}
+
+// openEncrypted decrypts the database at path into a private directory, locking path
+// so no other process opens it until Close writes it back
+func openEncrypted(path string) (*encryption, string, error) {
+	passphrase, err := LookupKey()
+	if err != nil {
+		return nil, "", err
+	}
+	if passphrase == "" {
+		return nil, "", fmt.Errorf("database %s is encrypted: set %s, or store the key with: "+
+			"secret-tool store --label lfst service %s account %s", path, KeyEnv, keychainService, keychainAccount)
+	}
+
+	lock, err := lockFile(path + ".lock")
+	if errors.Is(err, errLocked) {
+		return nil, "", fmt.Errorf("encrypted database %s is in use by another process", path)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create lock file: %w", err)
+	}
+
+	// A new database, or a plain one that is encrypted for the first time on Close.
+	// Decrypting an encrypted one also checks the passphrase.
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		lock.Close()
+		return nil, "", fmt.Errorf("failed to read database: %w", err)
+	}
+	if bytes.HasPrefix(data, []byte(encryptedMagic)) {
+		if data, err = decrypt(data, passphrase); err != nil {
+			lock.Close()
+			return nil, "", fmt.Errorf("failed to decrypt %s: %w", path, err)
+		}
+	}
+
+	enc := &encryption{path: path, passphrase: passphrase, lock: lock}
+
+	// A process that exited without closing the database left its decrypted copy, which
+	// is newer than the encrypted file; carry on with it so no results are lost
+	prev, _ := io.ReadAll(lock)
+	if len(prev) > 0 {
+		plainPath := filepath.Join(string(prev), plainName)
+		if _, err := os.Stat(plainPath); err == nil {
+			enc.plainDir = string(prev)
+			return enc, plainPath, nil
+		}
+	}
+
+	// Anything else left in the private directory, or in the one the lock file names, is
+	// stale: the lock shows that no other process is using it
+	dir, err := plainDirFor(path)
+	if err != nil {
+		enc.release()
+		return nil, "", err
+	}
+	if len(prev) > 0 && string(prev) != dir {
+		os.RemoveAll(string(prev))
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		enc.release()
+		return nil, "", fmt.Errorf("failed to remove the stale decrypted database: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err == nil {
+		err = os.Mkdir(dir, 0700)
+	}
+	if err != nil {
+		enc.release()
+		return nil, "", fmt.Errorf("failed to create directory for the decrypted database: %w", err)
+	}
+	enc.plainDir = dir
+	plainPath := filepath.Join(enc.plainDir, plainName)
+	if err := os.WriteFile(plainPath, data, 0600); err != nil {
+		enc.release()
+		return nil, "", fmt.Errorf("failed to write decrypted database: %w", err)
+	}
+
+	// The lock file names the decrypted copy until Close encrypts it
+	if err := lock.Truncate(0); err == nil {
+		_, err = lock.WriteAt([]byte(enc.plainDir), 0)
+	}
+	if err != nil {
+		enc.release()
+		return nil, "", fmt.Errorf("failed to write lock file: %w", err)
+	}
+
+	return enc, plainPath, nil
+}
+
+// plainDirFor returns the private directory of the decrypted copy of the database at path:
+// under $XDG_RUNTIME_DIR, which is private to the user and usually kept in memory, or
+// else next to the encrypted file, so the copy is never left in a shared temp directory
+func plainDirFor(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve database path: %w", err)
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		sum := sha256.Sum256([]byte(abs))
+		return filepath.Join(runtimeDir, "lfst", fmt.Sprintf("db-%x", sum[:8])), nil
+	}
+	return filepath.Join(filepath.Dir(abs), "."+filepath.Base(abs)+".plain"), nil
+}
+
+// save encrypts the decrypted copy over the original file, replacing it atomically
+func (enc *encryption) save() error {
+	data, err := os.ReadFile(filepath.Join(enc.plainDir, plainName))
+	if err != nil {
+		return fmt.Errorf("failed to read decrypted database: %w", err)
+	}
+
+	sealed, err := encrypt(data, enc.passphrase)
+	if err != nil {
+		return err
+	}
+
+	tmp := enc.path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0600); err != nil {
+		return fmt.Errorf("failed to write encrypted database: %w", err)
+	}
+	if err := os.Rename(tmp, enc.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace encrypted database: %w", err)
+	}
+	return nil
+}
+
+// release removes the decrypted copy and unlocks the encrypted file
+func (enc *encryption) release() error {
+	var err error
+	if enc.plainDir != "" {
+		err = os.RemoveAll(enc.plainDir)
+	}
+	return errors.Join(err, enc.lock.Truncate(0), enc.lock.Close())
+}
+
+// newGCM derives the AES-256-GCM cipher for a passphrase and salt
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, keyIterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt seals data with a key derived from passphrase and a fresh salt
+func encrypt(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte(encryptedMagic), salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, []byte(encryptedMagic)), nil
+}
+
+// decrypt opens data sealed by encrypt
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	data = bytes.TrimPrefix(data, []byte(encryptedMagic))
+	if len(data) < saltSize {
+		return nil, fmt.Errorf("file is truncated")
+	}
+	gcm, err := newGCM(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltSize:]
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("file is truncated")
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(encryptedMagic))
+	if err != nil {
+		return nil, fmt.Errorf("wrong key or damaged file")
+	}
+	return plain, nil
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedDatabase(t *testing.T) {
+	defer func(saved int) { keyIterations = saved }(keyIterations)
+	keyIterations = 1000
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	t.Setenv(KeyEnv, "correct horse")
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	run := createTestRun(t, db)
+	run.Notes = "internal-host.example.com"
+	if err := db.UpdateTestRun(run); err != nil {
+		t.Fatalf("UpdateTestRun failed: %v", err)
+	}
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("a second Open of an open encrypted database: err = %v", err)
+	}
+	plainDir := db.enc.plainDir
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte(encryptedMagic)) || bytes.Contains(data, []byte("internal-host")) {
+		t.Error("the database file should be encrypted")
+	}
+	if _, err := os.Stat(plainDir); !os.IsNotExist(err) {
+		t.Errorf("the decrypted copy in %s should be removed", plainDir)
+	}
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopening failed: %v", err)
+	}
+	got, err := db.GetTestRun(run.ID)
+	db.Close()
+	if err != nil || got.Notes != run.Notes {
+		t.Errorf("reopened run = %+v, %v; want notes %q", got, err, run.Notes)
+	}
+
+	t.Setenv(KeyEnv, "wrong")
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("Open with the wrong key: err = %v", err)
+	}
+}
+
+func TestEncryptExistingDatabase(t *testing.T) {
+	defer func(saved int) { keyIterations = saved }(keyIterations)
+	keyIterations = 1000
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	run := createTestRun(t, db)
+	db.Close()
+
+	if encrypted, _ := IsEncrypted(path); encrypted {
+		t.Fatal("a database opened without a key should not be encrypted")
+	}
+
+	// Setting a key encrypts an existing plain database the next time it is closed
+	t.Setenv(KeyEnv, "secret")
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open with a key failed: %v", err)
+	}
+	if _, err := db.GetTestRun(run.ID); err != nil {
+		t.Errorf("the plain database's run is missing: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if encrypted, _ := IsEncrypted(path); !encrypted {
+		t.Error("the database should be encrypted after closing")
+	}
+}
+
+func TestEncryptedDatabasePlainDir(t *testing.T) {
+	defer func(saved int) { keyIterations = saved }(keyIterations)
+	keyIterations = 1000
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.db")
+	t.Setenv(KeyEnv, "secret")
+
+	// Without XDG_RUNTIME_DIR the copy is kept next to the database, never in the shared
+	// temp directory, and anything stale in its directory is removed
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	stale := filepath.Join(dir, ".test.db.plain", "stale")
+	if err := os.MkdirAll(stale, 0700); err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if want := filepath.Join(dir, ".test.db.plain"); db.enc.plainDir != want {
+		t.Errorf("plainDir = %s, want %s", db.enc.plainDir, want)
+	}
+	if info, err := os.Stat(db.enc.plainDir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("plainDir should have mode 0700: %v, %v", info, err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("the stale %s should be removed", stale)
+	}
+	db.Close()
+
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+	if !strings.HasPrefix(db.enc.plainDir, filepath.Join(runtimeDir, "lfst")+string(filepath.Separator)) {
+		t.Errorf("plainDir = %s, want one in %s", db.enc.plainDir, runtimeDir)
+	}
+}
+
+func TestEncryptedDatabaseRecoversAfterExit(t *testing.T) {
+	defer func(saved int) { keyIterations = saved }(keyIterations)
+	keyIterations = 1000
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	t.Setenv(KeyEnv, "secret")
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	db.Close()
+
+	// A process that exits without Close leaves its decrypted copy and releases the lock
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	run := createTestRun(t, db)
+	plainDir := db.enc.plainDir
+	db.conn.Close()
+	db.enc.lock.Close()
+
+	t.Setenv(KeyEnv, "wrong")
+	if _, err := Open(path); err == nil {
+		t.Fatal("Open with the wrong key should fail")
+	}
+	if _, err := os.Stat(plainDir); err != nil {
+		t.Fatalf("a failed Open should keep the decrypted copy: %v", err)
+	}
+
+	t.Setenv(KeyEnv, "secret")
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, err := db.GetTestRun(run.ID); err != nil {
+		t.Errorf("the run written before the exit was lost: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(plainDir); !os.IsNotExist(err) {
+		t.Errorf("the recovered copy in %s should be removed by Close", plainDir)
+	}
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.GetTestRun(run.ID); err != nil {
+		t.Errorf("the recovered run was not encrypted back: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SaveEnvironment records the environment of a run, replacing any recorded before
+func (db *DB) SaveEnvironment(env *Environment) error {
+	_, err := db.exec(`
+		INSERT INTO environments (run_id, git_version, git_lfs_version, os, arch, kernel, cpu_model, cpu_count,
+			memory_bytes, filesystem, disk, collected_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (run_id) DO UPDATE SET
+			git_version = excluded.git_version, git_lfs_version = excluded.git_lfs_version, os = excluded.os,
+			arch = excluded.arch, kernel = excluded.kernel, cpu_model = excluded.cpu_model, cpu_count = excluded.cpu_count,
+			memory_bytes = excluded.memory_bytes, filesystem = excluded.filesystem, disk = excluded.disk,
+			collected_at = excluded.collected_at`,
+		env.RunID, env.GitVersion, env.GitLFSVersion, env.OS, env.Arch, env.Kernel, env.CPUModel, env.CPUs,
+		env.MemoryBytes, env.Filesystem, env.Disk, env.CollectedAt.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save environment of run %d: %w", env.RunID, err)
+	}
+	return nil
+}
+
+// GetEnvironment returns the environment of a run, or nil if none was recorded, as for
+// runs made before environments were
+func (db *DB) GetEnvironment(runID int64) (*Environment, error) {
+	var env Environment
+	var collectedAt string
+	err := db.queryRow(`
+		SELECT id, run_id, git_version, git_lfs_version, os, arch, kernel, cpu_model, cpu_count, memory_bytes,
+			filesystem, disk, collected_at
+		FROM environments WHERE run_id = ?`, runID,
+	).Scan(&env.ID, &env.RunID, &env.GitVersion, &env.GitLFSVersion, &env.OS, &env.Arch, &env.Kernel, &env.CPUModel,
+		&env.CPUs, &env.MemoryBytes, &env.Filesystem, &env.Disk, &collectedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get environment of run %d: %w", runID, err)
+	}
+
+	env.CollectedAt, _ = time.Parse(time.RFC3339, collectedAt)
+	return &env, nil
+}
+
+// Summary describes the environment on one line, e.g.
+// "git 2.43.0, git-lfs 3.4.1, linux 6.8.0-45-generic amd64, Intel Core i7 (8 CPUs), 32.0 GB RAM, ext4 on ssd"
+func (env *Environment) Summary() string {
+	var parts []string
+	if env.GitVersion != "" {
+		parts = append(parts, "git "+env.GitVersion)
+	}
+	if env.GitLFSVersion != "" {
+		parts = append(parts, "git-lfs "+env.GitLFSVersion)
+	}
+	if system := strings.Join(nonEmpty(env.OS, env.Kernel, env.Arch), " "); system != "" {
+		parts = append(parts, system)
+	}
+	cpus := fmt.Sprintf("%d CPUs", env.CPUs)
+	if env.CPUs == 1 {
+		cpus = "1 CPU"
+	}
+	switch {
+	case env.CPUModel != "" && env.CPUs > 0:
+		parts = append(parts, fmt.Sprintf("%s (%s)", env.CPUModel, cpus))
+	case env.CPUModel != "":
+		parts = append(parts, env.CPUModel)
+	case env.CPUs > 0:
+		parts = append(parts, cpus)
+	}
+	if env.MemoryBytes > 0 {
+		parts = append(parts, fmt.Sprintf("%.1f GB RAM", float64(env.MemoryBytes)/1024/1024/1024))
+	}
+	if storage := strings.Join(nonEmpty(env.Filesystem, env.Disk), " on "); storage != "" {
+		parts = append(parts, storage)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// nonEmpty returns the strings that are not empty
+func nonEmpty(values ...string) []string {
+	var kept []string
+	for _, v := range values {
+		if v != "" {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
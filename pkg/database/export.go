@@ -0,0 +1,378 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ExportFormat identifies the first line of a run exported by ExportRun
+const ExportFormat = "lfst-run"
+
+// exportVersion is the version of the export format
+const exportVersion = 1
+
+// exportHeader is the first line of an export
+type exportHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	RunID   int64  `json:"run_id"`
+}
+
+// exportRow is every other line of an export: one row of a table
+type exportRow struct {
+	Table string                 `json:"table"`
+	Row   map[string]interface{} `json:"row"`
+}
+
+// catalogColumn describes a column of the schema
+type catalogColumn struct {
+	name string
+	typ  string // Declared type, e.g. INTEGER, TEXT or BLOB
+	ref  string // Table the column references, or ""
+}
+
+// catalogTable describes a table of the schema
+type catalogTable struct {
+	name    string
+	columns []catalogColumn
+	autoID  bool // Rows are identified by an id assigned by the database
+}
+
+// column returns the column called name, or nil
+func (t *catalogTable) column(name string) *catalogColumn {
+	for i := range t.columns {
+		if t.columns[i].name == name {
+			return &t.columns[i]
+		}
+	}
+	return nil
+}
+
+var (
+	catalogOnce   sync.Once
+	catalogTables []*catalogTable
+	catalogErr    error
+)
+
+// schemaCatalog describes the tables of the schema, each after the tables it references.
+// It is read from a private in-memory SQLite database, so it is the same for every driver.
+func schemaCatalog() ([]*catalogTable, error) {
+	catalogOnce.Do(func() {
+		conn, err := sql.Open(DriverSQLite, ":memory:")
+		if err != nil {
+			catalogErr = err
+			return
+		}
+		conn.SetMaxOpenConns(1) // Each connection would have its own in-memory database
+		db, err := setup(conn, sqliteDialect{})
+		if err != nil {
+			catalogErr = err
+			return
+		}
+		defer db.Close()
+		catalogTables, catalogErr = db.readCatalog()
+	})
+	return catalogTables, catalogErr
+}
+
+// readCatalog reads the tables of an SQLite database and sorts them so referenced tables come first
+func (db *DB) readCatalog() ([]*catalogTable, error) {
+	names, err := db.tables()
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*catalogTable)
+	for _, name := range names {
+		t := &catalogTable{name: name}
+		rows, err := db.query(`SELECT name, type, pk FROM pragma_table_info(?) ORDER BY cid`, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the columns of %s: %w", name, err)
+		}
+		for rows.Next() {
+			var c catalogColumn
+			var pk int
+			if err := rows.Scan(&c.name, &c.typ, &pk); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if pk > 0 && c.name == "id" {
+				t.autoID = true
+			}
+			t.columns = append(t.columns, c)
+		}
+		rows.Close()
+
+		rows, err = db.query(`SELECT "from", "table" FROM pragma_foreign_key_list(?)`, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the foreign keys of %s: %w", name, err)
+		}
+		for rows.Next() {
+			var from, to string
+			if err := rows.Scan(&from, &to); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if c := t.column(from); c != nil {
+				c.ref = to
+			}
+		}
+		rows.Close()
+		byName[name] = t
+	}
+
+	// Depth-first, so every table follows the tables it references
+	var ordered []*catalogTable
+	done := make(map[string]bool)
+	var visit func(t *catalogTable)
+	visit = func(t *catalogTable) {
+		if done[t.name] {
+			return
+		}
+		done[t.name] = true
+		for _, c := range t.columns {
+			if parent := byName[c.ref]; parent != nil {
+				visit(parent)
+			}
+		}
+		ordered = append(ordered, t)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		visit(byName[name])
+	}
+	return ordered, nil
+}
+
+// runFilter returns the WHERE clause selecting the rows of a table that belong to a run,
+// with the run ID as its only parameter, or "" if the table does not hold run results
+func runFilter(t *catalogTable, byName map[string]*catalogTable) string {
+	if t.name == "test_runs" {
+		return "id = ?"
+	}
+	if linksRuns(t, byName) {
+		return ""
+	}
+	if t.column("run_id") != nil {
+		return "run_id = ?"
+	}
+	for _, c := range t.columns {
+		if parent := byName[c.ref]; parent != nil && parent.column("run_id") != nil {
+			return fmt.Sprintf("%s IN (SELECT id FROM %s WHERE run_id = ?)", c.name, parent.name)
+		}
+	}
+	return ""
+}
+
+// linksRuns reports whether a table links runs to rows that belong to no single run, such
+// as benchmark_runs, which ties the runs of a benchmark to it
+func linksRuns(t *catalogTable, byName map[string]*catalogTable) bool {
+	for _, c := range t.columns {
+		if parent := byName[c.ref]; parent != nil && parent.name != "test_runs" && runFilter(parent, byName) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportRun writes a run and all its results (operations, command output, checksums,
+// sizes, verifications and so on) to w as JSON lines, for ImportRun to read
+func (db *DB) ExportRun(runID int64, w io.Writer) error {
+	if _, err := db.GetTestRun(runID); err != nil {
+		return err
+	}
+	tables, err := schemaCatalog()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*catalogTable)
+	for _, t := range tables {
+		byName[t.name] = t
+	}
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(exportHeader{Format: ExportFormat, Version: exportVersion, RunID: runID}); err != nil {
+		return err
+	}
+
+	for _, t := range tables {
+		filter := runFilter(t, byName)
+		if filter == "" {
+			continue
+		}
+		names := make([]string, len(t.columns))
+		for i, c := range t.columns {
+			names[i] = c.name
+		}
+		rows, err := db.query(fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s",
+			strings.Join(names, ", "), t.name, filter, names[0]), runID)
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", t.name, err)
+		}
+
+		values := make([]interface{}, len(names))
+		pointers := make([]interface{}, len(names))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		for rows.Next() {
+			if err := rows.Scan(pointers...); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to export %s: %w", t.name, err)
+			}
+			row := make(map[string]interface{}, len(names))
+			for i, c := range t.columns {
+				value := values[i]
+				if b, ok := value.([]byte); ok && c.typ != "BLOB" {
+					value = string(b)
+				}
+				row[c.name] = value
+			}
+			if err := enc.Encode(exportRow{Table: t.name, Row: row}); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// ImportRun reads a run written by ExportRun and adds it to the database with new IDs.
+// It returns the ID of the imported run.
+func (db *DB) ImportRun(r io.Reader) (int64, error) {
+	tables, err := schemaCatalog()
+	if err != nil {
+		return 0, err
+	}
+	byName := make(map[string]*catalogTable)
+	for _, t := range tables {
+		byName[t.name] = t
+	}
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var header exportHeader
+	if err := dec.Decode(&header); err != nil || header.Format != ExportFormat {
+		return 0, fmt.Errorf("not an exported run")
+	}
+	if header.Version > exportVersion {
+		return 0, fmt.Errorf("the run was exported in format version %d; this version reads up to %d", header.Version, exportVersion)
+	}
+
+	rows := make(map[string][]map[string]interface{})
+	for line := 2; ; line++ {
+		var row exportRow
+		err := dec.Decode(&row)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		t := byName[row.Table]
+		if t == nil {
+			return 0, fmt.Errorf("line %d: unknown table '%s' (exported by a newer version?)", line, row.Table)
+		}
+		for name := range row.Row {
+			if t.column(name) == nil {
+				return 0, fmt.Errorf("line %d: unknown column '%s' of %s (exported by a newer version?)", line, name, row.Table)
+			}
+		}
+		rows[row.Table] = append(rows[row.Table], row.Row)
+	}
+	if len(rows["test_runs"]) != 1 {
+		return 0, fmt.Errorf("the export holds %d runs, want 1", len(rows["test_runs"]))
+	}
+
+	// New IDs by table and exported ID
+	ids := make(map[string]map[int64]int64)
+	err = db.write(func(tx querier) error {
+		for _, t := range tables {
+			ids[t.name] = make(map[int64]int64)
+			for _, row := range rows[t.name] {
+				var names []string
+				var args []interface{}
+				var oldID int64
+				for _, c := range t.columns {
+					raw, ok := row[c.name]
+					if !ok {
+						continue
+					}
+					value, err := importValue(c, raw, ids)
+					if err != nil {
+						return fmt.Errorf("%s: %w", t.name, err)
+					}
+					if t.autoID && c.name == "id" {
+						oldID, _ = value.(int64)
+						continue
+					}
+					names = append(names, c.name)
+					args = append(args, value)
+				}
+
+				query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", t.name,
+					strings.Join(names, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", "))
+				if !t.autoID {
+					if _, err := tx.Exec(db.dialect.rebind(query), db.dialect.args(args)...); err != nil {
+						return fmt.Errorf("failed to import %s: %w", t.name, err)
+					}
+					continue
+				}
+				id, err := db.insert(tx, query, args...)
+				if err != nil {
+					return fmt.Errorf("failed to import %s: %w", t.name, err)
+				}
+				ids[t.name][oldID] = id
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return ids["test_runs"][header.RunID], nil
+}
+
+// importValue converts a value read from JSON to the type of its column, and an exported
+// ID in a column that references another table to the ID the row was imported with
+func importValue(c catalogColumn, raw interface{}, ids map[string]map[int64]int64) (interface{}, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case json.Number:
+		if !strings.Contains(c.typ, "INT") {
+			return v.Float64()
+		}
+		n, err := v.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", c.name, err)
+		}
+		if c.ref == "" {
+			return n, nil
+		}
+		id, ok := ids[c.ref][n]
+		if !ok {
+			return nil, fmt.Errorf("column %s references %s %d, which is not in the export", c.name, c.ref, n)
+		}
+		return id, nil
+	case string:
+		if c.typ == "BLOB" {
+			return base64.StdEncoding.DecodeString(v)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("column %s has an unexpected value %v", c.name, raw)
+	}
+}
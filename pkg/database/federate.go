@@ -0,0 +1,174 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FederationOffset separates the IDs of federated databases: the IDs of the Nth database,
+// counting from 0, are offset by N times FederationOffset, so run 12 of the second
+// database is run 1000000012 of the federation
+const FederationOffset int64 = 1_000_000_000
+
+// Federate opens a temporary SQLite database holding the results of every database at
+// locations, so results stranded in the databases of several machines or campaigns can
+// be queried as one. Each location is anything Open accepts. The IDs of the first
+// database are kept; those of the others are offset by FederationOffset. Changes to the
+// federation are not written back; Close removes it.
+func Federate(locations []string) (*DB, error) {
+	dir, err := os.MkdirTemp("", "lfst-federation-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create federation directory: %w", err)
+	}
+
+	db, err := open(filepath.Join(dir, "federation.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	db.tempDir = dir
+
+	// Rows are copied table by table, before the rows they reference may exist
+	db.conn.SetMaxOpenConns(1)
+	if _, err := db.conn.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to disable foreign keys: %w", err)
+	}
+
+	for i, location := range locations {
+		if !IsPostgresURL(location) {
+			if _, err := os.Stat(location); err != nil {
+				db.Close()
+				return nil, fmt.Errorf("no database at %s", location)
+			}
+		}
+		src, err := Open(location)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open %s: %w", Redact(location), err)
+		}
+		err = db.copyFrom(src, int64(i)*FederationOffset)
+		src.Close()
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to federate %s: %w", Redact(location), err)
+		}
+	}
+	return db, nil
+}
+
+// copyFrom copies every row of src, adding offset to the IDs and to the columns that reference them
+func (db *DB) copyFrom(src *DB, offset int64) error {
+	tables, err := db.tables()
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]map[string]bool)
+	for _, table := range tables {
+		if ids[table], err = db.idColumns(table); err != nil {
+			return err
+		}
+	}
+
+	// The transaction holds the only connection
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range tables {
+		rows, err := src.query("SELECT * FROM " + table)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", table, err)
+		}
+		columns, err := rows.Columns()
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table,
+			strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		for rows.Next() {
+			if err := rows.Scan(pointers...); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to read %s: %w", table, err)
+			}
+			for i, column := range columns {
+				if id, ok := values[i].(int64); ok && ids[table][column] {
+					values[i] = id + offset
+				}
+			}
+			if _, err := tx.Exec(insert, values...); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to copy %s: %w", table, err)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// tables returns the names of the tables of the schema
+func (db *DB) tables() ([]string, error) {
+	rows, err := db.query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// idColumns returns the columns of a table holding IDs: its integer primary key and its foreign keys
+func (db *DB) idColumns(table string) (map[string]bool, error) {
+	ids := make(map[string]bool)
+
+	rows, err := db.query(`SELECT name FROM pragma_table_info(?) WHERE pk > 0 AND type = 'INTEGER'`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the columns of %s: %w", table, err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids[name] = true
+	}
+	rows.Close()
+
+	rows, err = db.query(`SELECT "from" FROM pragma_foreign_key_list(?)`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the foreign keys of %s: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		ids[name] = true
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package database
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile opens path, creating it if needed, and takes an exclusive lock on it that
+// is released when the file is closed or the process exits. It returns errLocked if
+// another process holds the lock.
+func lockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errLocked
+		}
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package database
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// errorSharingViolation is returned by CreateFile when another handle denies sharing
+const errorSharingViolation syscall.Errno = 32
+
+// lockFile opens path, creating it if needed, without sharing it, so other processes
+// cannot open it until the file is closed or the process exits. It returns errLocked if
+// another process has it open.
+func lockFile(path string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil,
+		syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if errors.Is(err, errorSharingViolation) {
+		return nil, errLocked
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(handle), path), nil
+}
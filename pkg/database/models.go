@@ -12,8 +12,12 @@ type TestRun struct {
 	PID         int    // Process ID of the running test
 	StartedAt   time.Time
 	CompletedAt *time.Time
-	Status      string // 'running', 'completed', 'failed', 'cancelled'
+	Status      string // 'running', 'completed', 'failed', 'cancelled', 'timed-out', 'aborted'
 	Notes       string
+	WorkDir     string // Directory holding the run's repositories; empty for runs made before per-run directories
+	// NetworkProfile is the tc/netem shaping applied to the transfer steps, e.g. "rtt=50ms,rate=100mbit";
+	// empty means the network was not shaped
+	NetworkProfile string
 }
 
 // Operation represents a timed Git/LFS operation
@@ -28,6 +32,74 @@ type Operation struct {
 	TotalBytes *int64
 	Status     string // 'success', 'failed'
 	Error      string
+	ErrorKind  string // Why a failed operation failed, when known, e.g. 'lfs-storage-quota'
+	// Checksum snapshots taken immediately before and after this operation
+	SnapshotBeforeID *int64
+	SnapshotAfterID  *int64
+	Repo             string // Repository directory the operation acted on; empty for hosting operations
+	// ServerEvent describes the server restarts and out-of-memory kills during the operation,
+	// e.g. "oom at 14:02:03: Out of memory: Killed process 812 (rudolfs)"; empty if none
+	ServerEvent string
+	// TransferAdapter names the git-lfs transfer adapters that moved the operation's objects,
+	// comma-separated, e.g. 'basic', 'ssh' or 'multipart-basic'; empty if it moved none
+	TransferAdapter string
+	// Resources the command of the operation used, when sampled; nil otherwise
+	Resources *ResourceUsage
+}
+
+// ResourceUsage is the CPU time, memory and disk I/O of an operation's command and its
+// child processes, such as git-lfs started by git
+type ResourceUsage struct {
+	CPUMs          int64   // User and system CPU time
+	PeakCPUPercent float64 // Highest CPU use between two samples; 100 is one full CPU
+	PeakRSSBytes   int64   // Highest resident memory at once
+	AvgRSSBytes    int64   // Mean resident memory
+	ReadBytes      int64   // Bytes read from storage
+	WriteBytes     int64   // Bytes written to storage
+}
+
+// OperationLog holds the complete output of the command of an operation
+type OperationLog struct {
+	OperationID int64
+	Command     string // Command line, e.g. 'git -C /tmp/lfst/repo push --progress origin main'
+	ExitCode    int
+	Stdout      string
+	Stderr      string
+}
+
+// OperationDependency records that an operation could not start before another finished
+type OperationDependency struct {
+	OperationID int64
+	DependsOnID int64
+	Kind        string // 'sequence' (same repository) or 'data' (e.g. clone of a push)
+}
+
+// StepResult records the progress of one scenario step, so interrupted runs can resume
+type StepResult struct {
+	ID          int64
+	RunID       int64
+	StepNumber  int
+	Name        string // Pipeline step name, e.g. 'setup' or 'churn'
+	Status      string // 'running', 'completed', 'failed'
+	StartedAt   time.Time
+	CompletedAt *time.Time
+	DurationMs  int64
+	Error       string
+	// Network traffic on Interface during the step, from the client's interface counters
+	Interface string
+	RxBytes   *int64
+	TxBytes   *int64
+}
+
+// Snapshot groups a set of checksums taken at one point in a run.
+// Step snapshots have a StepNumber and no Label; named snapshots
+// (e.g. "pre-migration", "after-gc") have a Label and StepNumber 0.
+type Snapshot struct {
+	ID         int64
+	RunID      int64
+	StepNumber int
+	Label      string
+	CreatedAt  time.Time
 }
 
 // Checksum represents a file CRC32 checksum
@@ -35,19 +107,280 @@ type Checksum struct {
 	ID         int64
 	RunID      int64
 	StepNumber int
+	SnapshotID int64 // 0 for checksums recorded before snapshots existed
 	FilePath   string
 	CRC32      string
 	SizeBytes  int64
 	ComputedAt time.Time
 }
 
+// ServerEvent records a restart, stop or out-of-memory kill of the LFS server during a step
+type ServerEvent struct {
+	ID         int64
+	RunID      int64
+	StepNumber int
+	OccurredAt time.Time
+	Kind       string // 'start', 'stop' or 'oom'
+	Message    string // e.g. 'Out of memory: Killed process 812 (rudolfs)'
+}
+
+// Verification records the outcome of one check made during a step, such as
+// "files are LFS pointers" or "LFS objects are larger than git objects"
+type Verification struct {
+	ID         int64
+	RunID      int64
+	StepNumber int
+	Name       string // e.g. 'lfs-pointers', 'repository-sizes'
+	Severity   string // 'error' or 'warning'
+	Status     string // 'passed' or 'failed'
+	Message    string // Failure details
+	CheckedAt  time.Time
+}
+
+// StorageVerification records whether an LFS object pushed during a step is present,
+// with the right size, in the server's storage backend (e.g. an S3 bucket)
+type StorageVerification struct {
+	ID           int64
+	RunID        int64
+	StepNumber   int
+	Backend      string // e.g. 's3://bucket/prefix'
+	ObjectKey    string // Key of the object in the backend
+	OID          string
+	ExpectedSize int64
+	ActualSize   *int64 // nil if the object is missing or could not be checked
+	Status       string // 'ok', 'missing', 'size-mismatch' or 'error'
+	Message      string
+	CheckedAt    time.Time
+}
+
+// ServerObjects records how many LFS objects the server holds after a push, compared with
+// the objects referenced in the pushed history. Identical content under several names must
+// be stored once.
+type ServerObjects struct {
+	ID              int64
+	RunID           int64
+	StepNumber      int
+	Location        string // Object store, e.g. 'gojira:/srv/lfs', or the LFS endpoint for 'api'
+	Method          string // 'filesystem', 'ssh' or 'api'
+	ReferencedFiles int    // Paths referencing LFS objects in history; identical content counts once per path
+	ExpectedObjects int    // Unique objects referenced in history
+	ExpectedBytes   int64  // Their summed size
+	StoredObjects   int    // Referenced objects found on the server
+	StoredCopies    *int   // Files holding them; nil for 'api', which cannot tell
+	StoredBytes     *int64 // Summed size of those files; nil for 'api'
+	OtherFiles      *int   // Files in the store holding no referenced object; nil for 'api'
+	Status          string // 'passed' or 'failed'
+	Message         string
+	CheckedAt       time.Time
+}
+
+// ClientResult records one simulated client of a concurrent multi-client step
+type ClientResult struct {
+	ID             int64
+	RunID          int64
+	StepNumber     int
+	Client         int    // 1-based client number
+	RepoDir        string // The client's clone
+	StartedAt      time.Time
+	DurationMs     int64 // From clone until the client's commit was pushed (or it gave up)
+	Bytes          int64 // Size of the LFS object the client pushed
+	PushAttempts   int
+	RejectedPushes int    // Pushes refused because another client updated the branch first
+	LockErrors     int    // Failures caused by a ref, index or database lock
+	Status         string // 'completed' or 'failed'
+	Error          string
+}
+
+// ConcurrencyResult summarizes a concurrent multi-client step
+type ConcurrencyResult struct {
+	ID             int64
+	RunID          int64
+	StepNumber     int
+	Clients        int
+	FailedClients  int
+	TotalBytes     int64 // LFS bytes pushed by the clients that completed
+	WallMs         int64 // From the first clone until the last client finished
+	PushAttempts   int
+	RejectedPushes int
+	LockErrors     int
+	RecordedAt     time.Time
+}
+
+// ThroughputMBps returns the aggregate push throughput of all clients in MB/s
+func (c *ConcurrencyResult) ThroughputMBps() float64 {
+	if c.WallMs <= 0 {
+		return 0
+	}
+	return float64(c.TotalBytes) / 1024 / 1024 / (float64(c.WallMs) / 1000)
+}
+
+// LockResult records how the LFS server of a run handled the file locking API
+type LockResult struct {
+	ID         int64
+	RunID      int64
+	StepNumber int
+	ServerType string
+	Protocol   string
+	Supported  bool   // The server implements the locking API
+	Path       string // File that was locked
+	Visible    bool   // The second clone listed the first clone's lock
+	Enforced   bool   // The second clone could not take the same lock
+	Released   bool   // The lock was gone after unlocking
+	Message    string
+	CheckedAt  time.Time
+}
+
+// LargeFileResult records how the LFS server took a very large file whose first upload was
+// deliberately interrupted
+type LargeFileResult struct {
+	ID          int64
+	RunID       int64
+	StepNumber  int
+	Path        string // File pushed
+	SizeBytes   int64
+	Interrupted bool  // The first upload was stopped before it finished
+	FirstMs     int64 // How long the first upload ran
+	ResumeMs    int64 // Duration of the push that completed the upload
+	// ResumeSentBytes is what the completing push sent over the network, when known
+	ResumeSentBytes *int64
+	// Resumed reports whether the completing push sent less than the whole file again;
+	// nil when unknown
+	Resumed   *bool
+	Adapter   string // Transfer adapter of the completing push, e.g. 'basic' or 'multipart-basic'
+	Status    string // 'completed' or 'failed'
+	Message   string
+	CheckedAt time.Time
+}
+
+// CadenceResult records a burst of small commits, each pushed on its own, as CI systems
+// that commit frequently produce
+type CadenceResult struct {
+	ID             int64
+	RunID          int64
+	StepNumber     int
+	Path           string // LFS file modified by every commit
+	Commits        int    // Commits made and pushed
+	FileSize       int64  // Size of the file each commit writes
+	TotalMs        int64  // Wall-clock time of all commits
+	MeanCommitMs   int64  // Mean time of one modification, add, commit and push
+	MedianCommitMs int64
+	MaxCommitMs    int64
+	MeanPushMs     int64 // Mean time of one push
+	// BatchRequests counts the Batch API requests of the pushes; nil without the LFS proxy
+	BatchRequests *int
+	// SentBytes is what the pushes sent over the network, when known
+	SentBytes *int64
+	Status    string // 'completed' or 'failed'
+	Message   string
+	CheckedAt time.Time
+}
+
+// RequestsPerCommit returns the Batch API requests each commit cost, or 0 when unknown
+func (c *CadenceResult) RequestsPerCommit() float64 {
+	if c.BatchRequests == nil || c.Commits == 0 {
+		return 0
+	}
+	return float64(*c.BatchRequests) / float64(c.Commits)
+}
+
+// BytesPerCommit returns what each commit sent over the network, or 0 when unknown
+func (c *CadenceResult) BytesPerCommit() int64 {
+	if c.SentBytes == nil || c.Commits == 0 {
+		return 0
+	}
+	return *c.SentBytes / int64(c.Commits)
+}
+
+// LFSBatchRequest records one Git LFS Batch API request and the server's response
+type LFSBatchRequest struct {
+	ID          int64
+	RunID       int64
+	StepNumber  int
+	Operation   string   // 'upload' or 'download'
+	Transfers   []string // Transfer adapters offered by the client
+	Ref         string   // Ref the objects belong to, if the client sent one
+	StatusCode  int
+	Transfer    string // Transfer adapter chosen by the server
+	Message     string // Error message of a failed request
+	DurationMs  int64
+	RequestedAt time.Time
+	Objects     []*LFSBatchObject
+}
+
+// TotalBytes returns the summed size of the requested objects
+func (b *LFSBatchRequest) TotalBytes() int64 {
+	var total int64
+	for _, obj := range b.Objects {
+		total += obj.Size
+	}
+	return total
+}
+
+// LFSBatchObject is one object of an LFS Batch API request, with the server's answer for it
+type LFSBatchObject struct {
+	ID           int64
+	RequestID    int64
+	OID          string
+	Size         int64
+	Actions      []string // Actions the server returned, e.g. 'upload', 'verify'; none if it has the object
+	Href         string   // URL of the upload or download action
+	VerifyHref   string   // URL of the verify action
+	ExpiresIn    int      // Seconds the actions stay valid
+	ErrorCode    int      // Per-object error, e.g. 404 for a missing object
+	ErrorMessage string
+}
+
+// Environment describes the machine a run ran on, recorded when the run is created,
+// without which its timings cannot be compared with those of other machines. Facts that
+// could not be found are empty or 0.
+type Environment struct {
+	ID            int64
+	RunID         int64
+	GitVersion    string
+	GitLFSVersion string
+	OS            string // 'linux', 'darwin', ...
+	Arch          string // 'amd64', 'arm64', ...
+	Kernel        string // Kernel release, e.g. '6.8.0-45-generic'
+	CPUModel      string
+	CPUs          int
+	MemoryBytes   int64  // Physical memory
+	Filesystem    string // Type of the filesystem holding the run's directory, e.g. 'ext4'
+	Disk          string // 'ssd' or 'hdd', the disk holding that filesystem
+	CollectedAt   time.Time
+}
+
 // RepositorySize represents storage metrics
 type RepositorySize struct {
 	ID         int64
 	RunID      int64
 	StepNumber int
-	Location   string // 'client-git', 'client-lfs', 'server-git', 'server-lfs'
+	Location   string // 'client-git', 'client-lfs', 'client2-git', 'client2-lfs', 'server'
 	SizeBytes  int64
 	FileCount  *int
 	MeasuredAt time.Time
 }
+
+// Benchmark groups the runs of one scenario repeated with lfst-scenario --repeat, whose
+// operation timings are summarized in BenchmarkStats
+type Benchmark struct {
+	ID          int64
+	ScenarioID  int
+	Iterations  int // Runs requested
+	StartedAt   time.Time
+	CompletedAt *time.Time
+	RunIDs      []int64 // Runs made so far, in iteration order
+}
+
+// BenchmarkStat summarizes the time one operation type took in the runs of a benchmark.
+// Each sample is the total time of the operation type in one run.
+type BenchmarkStat struct {
+	ID          int64
+	BenchmarkID int64
+	Operation   string
+	Samples     int // Runs that performed the operation
+	MeanMs      float64
+	MedianMs    float64
+	StdDevMs    float64 // Sample standard deviation; 0 for a single sample
+	MinMs       int64
+	MaxMs       int64
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"fmt"
+)
+
+// DeleteTestRuns deletes runs and all their results (operations, command output, checksums,
+// sizes, verifications and so on) in one transaction, so a failure leaves every run whole.
+// It returns the number of rows deleted.
+func (db *DB) DeleteTestRuns(runIDs []int64) (int64, error) {
+	tables, err := schemaCatalog()
+	if err != nil {
+		return 0, err
+	}
+	byName := make(map[string]*catalogTable)
+	for _, t := range tables {
+		byName[t.name] = t
+	}
+
+	// Children before the tables they reference, test_runs last
+	var deleted int64
+	err = db.write(func(tx querier) error {
+		for _, runID := range runIDs {
+			for i := len(tables) - 1; i >= 0; i-- {
+				t := tables[i]
+				filter := runFilter(t, byName)
+				if linksRuns(t, byName) && t.column("run_id") != nil {
+					filter = "run_id = ?" // The run leaves, what it is linked to stays
+				}
+				if filter == "" {
+					continue
+				}
+				result, err := tx.Exec(db.dialect.rebind(fmt.Sprintf("DELETE FROM %s WHERE %s", t.name, filter)), runID)
+				if err != nil {
+					return fmt.Errorf("failed to delete %s of run %d: %w", t.name, runID, err)
+				}
+				if n, err := result.RowsAffected(); err == nil {
+					deleted += n
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// Vacuum returns the space freed by deleted rows to the operating system
+func (db *DB) Vacuum() error {
+	// SQLite cannot vacuum within a transaction
+	err := db.writeDirect(func(q querier) error {
+		_, err := q.Exec("VACUUM")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	return nil
+}
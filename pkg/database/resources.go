@@ -0,0 +1,56 @@
+package database
+
+import "fmt"
+
+// ResourceSummary aggregates the sampled resource usage of one operation type on one
+// server type over every run
+type ResourceSummary struct {
+	ServerType    string
+	Operation     string
+	Operations    int     // Sampled operations
+	MaxPeakRSS    int64   // Highest peak memory of any of them
+	AvgPeakRSS    int64   // Mean of their peak memory
+	AvgCPUMs      int64   // Mean CPU time
+	MaxPeakCPU    float64 // Highest peak CPU use, in percent of one CPU
+	AvgReadBytes  int64   // Mean bytes read from storage
+	AvgWriteBytes int64   // Mean bytes written to storage
+}
+
+// ResourceUsageByServer summarizes the sampled resource usage of successful operations by
+// server type and operation type, most memory-hungry first. A non-empty operation
+// restricts the summary to that operation type.
+func (db *DB) ResourceUsageByServer(operation string) ([]*ResourceSummary, error) {
+	query := `
+		SELECT r.server_type, o.operation, COUNT(*), MAX(o.peak_rss_bytes), AVG(o.peak_rss_bytes),
+			AVG(o.cpu_ms), MAX(o.peak_cpu_pct), AVG(o.read_bytes), AVG(o.write_bytes)
+		FROM operations o JOIN test_runs r ON r.id = o.run_id
+		WHERE o.cpu_ms IS NOT NULL AND o.status = 'success'`
+	var args []interface{}
+	if operation != "" {
+		query += ` AND o.operation = ?`
+		args = append(args, operation)
+	}
+	query += ` GROUP BY r.server_type, o.operation ORDER BY MAX(o.peak_rss_bytes) DESC, r.server_type, o.operation`
+
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize resource usage: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []*ResourceSummary
+	for rows.Next() {
+		var s ResourceSummary
+		var avgPeakRSS, avgCPU, avgRead, avgWrite float64
+		if err := rows.Scan(&s.ServerType, &s.Operation, &s.Operations, &s.MaxPeakRSS, &avgPeakRSS,
+			&avgCPU, &s.MaxPeakCPU, &avgRead, &avgWrite); err != nil {
+			return nil, fmt.Errorf("failed to scan resource usage: %w", err)
+		}
+		s.AvgPeakRSS = int64(avgPeakRSS)
+		s.AvgCPUMs = int64(avgCPU)
+		s.AvgReadBytes = int64(avgRead)
+		s.AvgWriteBytes = int64(avgWrite)
+		summaries = append(summaries, &s)
+	}
+	return summaries, rows.Err()
+}
@@ -11,7 +11,20 @@ CREATE TABLE IF NOT EXISTS test_runs (
     started_at TEXT NOT NULL,
     completed_at TEXT,
     status TEXT NOT NULL,
-    notes TEXT
+    notes TEXT,
+    work_dir TEXT DEFAULT '',
+    network_profile TEXT DEFAULT '',
+    timing_summary TEXT DEFAULT ''
+);
+
+-- Tables are created before the tables that refer to them, as PostgreSQL requires
+CREATE TABLE IF NOT EXISTS snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL DEFAULT 0,
+    label TEXT,
+    created_at TEXT NOT NULL,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
 );
 
 CREATE TABLE IF NOT EXISTS operations (
@@ -25,18 +38,52 @@ CREATE TABLE IF NOT EXISTS operations (
     total_bytes INTEGER,
     status TEXT NOT NULL,
     error TEXT,
-    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+    error_kind TEXT,
+    snapshot_before_id INTEGER,
+    snapshot_after_id INTEGER,
+    repo TEXT,
+    server_event TEXT,
+    transfer_adapter TEXT,
+    cpu_ms INTEGER,
+    peak_cpu_pct REAL,
+    peak_rss_bytes INTEGER,
+    avg_rss_bytes INTEGER,
+    read_bytes INTEGER,
+    write_bytes INTEGER,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id),
+    FOREIGN KEY (snapshot_before_id) REFERENCES snapshots(id),
+    FOREIGN KEY (snapshot_after_id) REFERENCES snapshots(id)
+);
+
+CREATE TABLE IF NOT EXISTS operation_dependencies (
+    operation_id INTEGER NOT NULL,
+    depends_on_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    PRIMARY KEY (operation_id, depends_on_id),
+    FOREIGN KEY (operation_id) REFERENCES operations(id),
+    FOREIGN KEY (depends_on_id) REFERENCES operations(id)
+);
+
+CREATE TABLE IF NOT EXISTS operation_logs (
+    operation_id INTEGER PRIMARY KEY,
+    command TEXT NOT NULL,
+    exit_code INTEGER NOT NULL,
+    stdout BLOB,
+    stderr BLOB,
+    FOREIGN KEY (operation_id) REFERENCES operations(id)
 );
 
 CREATE TABLE IF NOT EXISTS checksums (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     run_id INTEGER NOT NULL,
     step_number INTEGER NOT NULL,
+    snapshot_id INTEGER,
     file_path TEXT NOT NULL,
     crc32 TEXT NOT NULL,
     size_bytes INTEGER NOT NULL,
     computed_at TEXT NOT NULL,
-    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+    FOREIGN KEY (run_id) REFERENCES test_runs(id),
+    FOREIGN KEY (snapshot_id) REFERENCES snapshots(id)
 );
 
 CREATE TABLE IF NOT EXISTS repository_sizes (
@@ -50,8 +97,281 @@ CREATE TABLE IF NOT EXISTS repository_sizes (
     FOREIGN KEY (run_id) REFERENCES test_runs(id)
 );
 
+CREATE TABLE IF NOT EXISTS step_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL,
+    step_name TEXT,
+    status TEXT NOT NULL,
+    started_at TEXT NOT NULL,
+    completed_at TEXT,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    interface TEXT,
+    rx_bytes INTEGER,
+    tx_bytes INTEGER,
+    UNIQUE (run_id, step_number),
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS verifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    status TEXT NOT NULL,
+    message TEXT,
+    checked_at TEXT NOT NULL,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS storage_verification (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL,
+    backend TEXT NOT NULL,
+    object_key TEXT NOT NULL,
+    oid TEXT NOT NULL,
+    expected_size INTEGER NOT NULL,
+    actual_size INTEGER,
+    status TEXT NOT NULL,
+    message TEXT,
+    checked_at TEXT NOT NULL,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS server_objects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL,
+    location TEXT NOT NULL,
+    method TEXT NOT NULL,
+    referenced_files INTEGER NOT NULL,
+    expected_objects INTEGER NOT NULL,
+    expected_bytes INTEGER NOT NULL,
+    stored_objects INTEGER NOT NULL,
+    stored_copies INTEGER,
+    stored_bytes INTEGER,
+    other_files INTEGER,
+    status TEXT NOT NULL,
+    message TEXT,
+    checked_at TEXT NOT NULL,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS client_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL,
+    client INTEGER NOT NULL,
+    repo_dir TEXT NOT NULL,
+    started_at TEXT NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    bytes INTEGER NOT NULL,
+    push_attempts INTEGER NOT NULL,
+    rejected_pushes INTEGER NOT NULL,
+    lock_errors INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS concurrency_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL,
+    clients INTEGER NOT NULL,
+    failed_clients INTEGER NOT NULL,
+    total_bytes INTEGER NOT NULL,
+    wall_ms INTEGER NOT NULL,
+    push_attempts INTEGER NOT NULL,
+    rejected_pushes INTEGER NOT NULL,
+    lock_errors INTEGER NOT NULL,
+    recorded_at TEXT NOT NULL,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS lock_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL,
+    server_type TEXT NOT NULL,
+    protocol TEXT NOT NULL,
+    supported INTEGER NOT NULL,
+    path TEXT,
+    visible INTEGER NOT NULL DEFAULT 0,
+    enforced INTEGER NOT NULL DEFAULT 0,
+    released INTEGER NOT NULL DEFAULT 0,
+    message TEXT,
+    checked_at TEXT NOT NULL,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS large_file_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    interrupted INTEGER NOT NULL DEFAULT 0,
+    first_ms INTEGER NOT NULL,
+    resume_ms INTEGER NOT NULL,
+    resume_sent_bytes INTEGER,
+    resumed INTEGER,
+    adapter TEXT,
+    status TEXT NOT NULL,
+    message TEXT,
+    checked_at TEXT NOT NULL,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS cadence_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    commits INTEGER NOT NULL,
+    file_size INTEGER NOT NULL,
+    total_ms INTEGER NOT NULL,
+    mean_commit_ms INTEGER NOT NULL,
+    median_commit_ms INTEGER NOT NULL,
+    max_commit_ms INTEGER NOT NULL,
+    mean_push_ms INTEGER NOT NULL,
+    batch_requests INTEGER,
+    sent_bytes INTEGER,
+    status TEXT NOT NULL,
+    message TEXT,
+    checked_at TEXT NOT NULL,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+-- The machine each run ran on
+CREATE TABLE IF NOT EXISTS environments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL UNIQUE,
+    git_version TEXT NOT NULL DEFAULT '',
+    git_lfs_version TEXT NOT NULL DEFAULT '',
+    os TEXT NOT NULL DEFAULT '',
+    arch TEXT NOT NULL DEFAULT '',
+    kernel TEXT NOT NULL DEFAULT '',
+    cpu_model TEXT NOT NULL DEFAULT '',
+    cpu_count INTEGER NOT NULL DEFAULT 0,
+    memory_bytes INTEGER NOT NULL DEFAULT 0,
+    filesystem TEXT NOT NULL DEFAULT '',
+    disk TEXT NOT NULL DEFAULT '',
+    collected_at TEXT NOT NULL,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+-- Key/value tags of runs and operations, e.g. git-lfs=3.4.1 or disk=ssd, so results stay
+-- interpretable long after the run
+CREATE TABLE IF NOT EXISTS run_tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    UNIQUE (run_id, key),
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS operation_tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    operation_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    UNIQUE (operation_id, key),
+    FOREIGN KEY (operation_id) REFERENCES operations(id)
+);
+
+CREATE TABLE IF NOT EXISTS lfs_batch_requests (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL,
+    operation TEXT NOT NULL,
+    transfers TEXT,
+    ref TEXT,
+    status_code INTEGER NOT NULL,
+    transfer TEXT,
+    message TEXT,
+    duration_ms INTEGER NOT NULL,
+    requested_at TEXT NOT NULL,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS lfs_batch_objects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    request_id INTEGER NOT NULL,
+    oid TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    actions TEXT,
+    href TEXT,
+    verify_href TEXT,
+    expires_in INTEGER,
+    error_code INTEGER,
+    error_message TEXT,
+    FOREIGN KEY (request_id) REFERENCES lfs_batch_requests(id)
+);
+
+CREATE TABLE IF NOT EXISTS server_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL,
+    occurred_at TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    message TEXT,
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+-- Runs of lfst-scenario --repeat, and the statistics of their operation timings
+CREATE TABLE IF NOT EXISTS benchmarks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    scenario_id INTEGER NOT NULL,
+    iterations INTEGER NOT NULL,
+    started_at TEXT NOT NULL,
+    completed_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS benchmark_runs (
+    benchmark_id INTEGER NOT NULL,
+    run_id INTEGER NOT NULL,
+    iteration INTEGER NOT NULL,
+    PRIMARY KEY (benchmark_id, iteration),
+    FOREIGN KEY (benchmark_id) REFERENCES benchmarks(id),
+    FOREIGN KEY (run_id) REFERENCES test_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS benchmark_stats (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    benchmark_id INTEGER NOT NULL,
+    operation TEXT NOT NULL,
+    samples INTEGER NOT NULL,
+    mean_ms REAL NOT NULL,
+    median_ms REAL NOT NULL,
+    stddev_ms REAL NOT NULL,
+    min_ms INTEGER NOT NULL,
+    max_ms INTEGER NOT NULL,
+    FOREIGN KEY (benchmark_id) REFERENCES benchmarks(id)
+);
+
 CREATE INDEX IF NOT EXISTS idx_operations_run ON operations(run_id);
 CREATE INDEX IF NOT EXISTS idx_checksums_run ON checksums(run_id);
 CREATE INDEX IF NOT EXISTS idx_repo_sizes_run ON repository_sizes(run_id);
 CREATE INDEX IF NOT EXISTS idx_test_runs_scenario ON test_runs(scenario_id);
+CREATE INDEX IF NOT EXISTS idx_snapshots_run ON snapshots(run_id);
+CREATE INDEX IF NOT EXISTS idx_verifications_run ON verifications(run_id);
+CREATE INDEX IF NOT EXISTS idx_storage_verification_run ON storage_verification(run_id);
+CREATE INDEX IF NOT EXISTS idx_server_objects_run ON server_objects(run_id);
+CREATE INDEX IF NOT EXISTS idx_client_results_run ON client_results(run_id);
+CREATE INDEX IF NOT EXISTS idx_concurrency_results_run ON concurrency_results(run_id);
+CREATE INDEX IF NOT EXISTS idx_lock_results_run ON lock_results(run_id);
+CREATE INDEX IF NOT EXISTS idx_large_file_results_run ON large_file_results(run_id);
+CREATE INDEX IF NOT EXISTS idx_cadence_results_run ON cadence_results(run_id);
+CREATE INDEX IF NOT EXISTS idx_run_tags_key ON run_tags(key, value);
+CREATE INDEX IF NOT EXISTS idx_operation_tags_key ON operation_tags(key, value);
+CREATE INDEX IF NOT EXISTS idx_lfs_batch_requests_run ON lfs_batch_requests(run_id);
+CREATE INDEX IF NOT EXISTS idx_lfs_batch_objects_request ON lfs_batch_objects(request_id);
+CREATE INDEX IF NOT EXISTS idx_server_events_run ON server_events(run_id);
+CREATE INDEX IF NOT EXISTS idx_benchmark_runs_run ON benchmark_runs(run_id);
+CREATE INDEX IF NOT EXISTS idx_benchmark_stats_benchmark ON benchmark_stats(benchmark_id);
 `
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ScoreboardRow aggregates the finished runs of one server type and protocol. Running and
+// cancelled runs say nothing about the servers, so they are left out.
+type ScoreboardRow struct {
+	ServerType    string
+	Protocol      string
+	Runs          int     // Finished runs
+	FailedRuns    int     // Runs that failed, timed out or were aborted
+	AvgDurationMs int64   // Mean wall-clock time of the completed runs, start to end; 0 if none completed
+	PushMBps      float64 // Throughput of successful pushes; 0 if none recorded their bytes
+	PullMBps      float64 // Throughput of successful clones and pulls; 0 if none recorded their bytes
+	FailedChecks  int     // Verifications and storage verifications that failed
+
+	// Wall-clock time of the completed runs AvgDurationMs is computed from
+	durationMs    int64
+	completedRuns int64
+
+	// Bytes and time of the transfers PushMBps and PullMBps are computed from
+	pushBytes, pushMs int64
+	pullBytes, pullMs int64
+}
+
+// FailureRate is the fraction of the runs that did not complete
+func (row *ScoreboardRow) FailureRate() float64 {
+	if row.Runs == 0 {
+		return 0
+	}
+	return float64(row.FailedRuns) / float64(row.Runs)
+}
+
+// finishedRuns selects the runs a scoreboard aggregates
+const finishedRuns = `r.status NOT IN ('running', 'cancelled')`
+
+// Scoreboard compares server types and protocols over all their finished runs, or those
+// of one scenario if scenarioID is not 0, most reliable first and then fastest. Only runs
+// carrying every tag of tags are compared.
+func (db *DB) Scoreboard(scenarioID int, tags []Tag) ([]*ScoreboardRow, error) {
+	filter := finishedRuns
+	var args []interface{}
+	if scenarioID != 0 {
+		filter += ` AND r.scenario_id = ?`
+		args = append(args, scenarioID)
+	}
+	if len(tags) > 0 {
+		tagFilter, tagArgs := runTagFilter(tags)
+		filter += ` AND ` + tagFilter
+		args = append(args, tagArgs...)
+	}
+
+	type key struct{ serverType, protocol string }
+	rows := make(map[key]*ScoreboardRow)
+	var scoreboard []*ScoreboardRow
+
+	// The runs of each server type and protocol come first; the other queries fill them in
+	err := db.scoreboardQuery(`
+		SELECT r.server_type, r.protocol, COUNT(*), SUM(CASE WHEN r.status = 'completed' THEN 0 ELSE 1 END)
+		FROM test_runs r WHERE `+filter+` GROUP BY r.server_type, r.protocol`, args,
+		func(scan func(...interface{}) error) error {
+			var row ScoreboardRow
+			if err := scan(&row.ServerType, &row.Protocol, &row.Runs, &row.FailedRuns); err != nil {
+				return err
+			}
+			rows[key{row.ServerType, row.Protocol}] = &row
+			scoreboard = append(scoreboard, &row)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	// Timestamps are subtracted here, as each database driver does date arithmetic its own way;
+	// they are stored to the second
+	err = db.scoreboardQuery(`
+		SELECT r.server_type, r.protocol, r.started_at, r.completed_at
+		FROM test_runs r WHERE `+filter+` AND r.status = 'completed' AND r.completed_at IS NOT NULL`, args,
+		func(scan func(...interface{}) error) error {
+			var k key
+			var startedAt, completedAt string
+			if err := scan(&k.serverType, &k.protocol, &startedAt, &completedAt); err != nil {
+				return err
+			}
+			started, _ := time.Parse(time.RFC3339, startedAt)
+			completed, _ := time.Parse(time.RFC3339, completedAt)
+			row := rows[k]
+			row.durationMs += completed.Sub(started).Milliseconds()
+			row.completedRuns++
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.scoreboardQuery(`
+		SELECT r.server_type, r.protocol, o.operation, SUM(o.total_bytes), SUM(o.duration_ms)
+		FROM operations o JOIN test_runs r ON r.id = o.run_id
+		WHERE `+filter+` AND o.status = 'success' AND o.total_bytes IS NOT NULL AND o.duration_ms > 0
+			AND o.operation IN ('push', 'pull', 'clone')
+		GROUP BY r.server_type, r.protocol, o.operation`, args,
+		func(scan func(...interface{}) error) error {
+			var k key
+			var operation string
+			var bytes, ms int64
+			if err := scan(&k.serverType, &k.protocol, &operation, &bytes, &ms); err != nil {
+				return err
+			}
+			row := rows[k]
+			if operation == "push" {
+				row.pushBytes += bytes
+				row.pushMs += ms
+			} else {
+				row.pullBytes += bytes
+				row.pullMs += ms
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range []string{
+		`verifications v JOIN test_runs r ON r.id = v.run_id WHERE v.status = 'failed'`,
+		`storage_verification v JOIN test_runs r ON r.id = v.run_id WHERE v.status <> 'ok'`,
+	} {
+		err = db.scoreboardQuery(`SELECT r.server_type, r.protocol, COUNT(*) FROM `+table+` AND `+filter+`
+			GROUP BY r.server_type, r.protocol`, args,
+			func(scan func(...interface{}) error) error {
+				var k key
+				var n int
+				if err := scan(&k.serverType, &k.protocol, &n); err != nil {
+					return err
+				}
+				rows[k].FailedChecks += n
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, row := range scoreboard {
+		if row.completedRuns > 0 {
+			row.AvgDurationMs = row.durationMs / row.completedRuns
+		}
+		row.PushMBps = mbps(row.pushBytes, row.pushMs)
+		row.PullMBps = mbps(row.pullBytes, row.pullMs)
+	}
+	sort.SliceStable(scoreboard, func(i, j int) bool {
+		a, b := scoreboard[i], scoreboard[j]
+		if a.FailureRate() != b.FailureRate() {
+			return a.FailureRate() < b.FailureRate()
+		}
+		if (a.AvgDurationMs == 0) != (b.AvgDurationMs == 0) {
+			return b.AvgDurationMs == 0 // Rows without a completed run last
+		}
+		if a.AvgDurationMs != b.AvgDurationMs {
+			return a.AvgDurationMs < b.AvgDurationMs
+		}
+		return a.ServerType+a.Protocol < b.ServerType+b.Protocol
+	})
+	return scoreboard, nil
+}
+
+// scoreboardQuery runs a scoreboard query and passes each row to f
+func (db *DB) scoreboardQuery(query string, args []interface{}, f func(scan func(...interface{}) error) error) error {
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to build scoreboard: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := f(rows.Scan); err != nil {
+			return fmt.Errorf("failed to scan scoreboard: %w", err)
+		}
+	}
+	return rows.Err()
+}
+
+// mbps is the throughput of bytes transferred in ms milliseconds, in megabytes per second
+func mbps(bytes, ms int64) float64 {
+	if ms == 0 {
+		return 0
+	}
+	return float64(bytes) / 1024 / 1024 / (float64(ms) / 1000)
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CreateServerObjects records the outcome of counting the LFS objects on the server
+func (db *DB) CreateServerObjects(o *ServerObjects) error {
+	id, err := db.insert(nil, `
+		INSERT INTO server_objects (run_id, step_number, location, method, referenced_files, expected_objects, expected_bytes,
+			stored_objects, stored_copies, stored_bytes, other_files, status, message, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		o.RunID, o.StepNumber, o.Location, o.Method, o.ReferencedFiles, o.ExpectedObjects, o.ExpectedBytes,
+		o.StoredObjects, o.StoredCopies, o.StoredBytes, o.OtherFiles, o.Status, o.Message, o.CheckedAt.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create server objects: %w", err)
+	}
+
+	o.ID = id
+	return nil
+}
+
+// ListServerObjects lists the server object counts of a test run by step
+func (db *DB) ListServerObjects(runID int64) ([]*ServerObjects, error) {
+	rows, err := db.query(`
+		SELECT id, run_id, step_number, location, method, referenced_files, expected_objects, expected_bytes,
+			stored_objects, stored_copies, stored_bytes, other_files, status, message, checked_at
+		FROM server_objects WHERE run_id = ? ORDER BY step_number, id`, runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list server objects: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*ServerObjects
+	for rows.Next() {
+		var o ServerObjects
+		var message sql.NullString
+		var checkedAt string
+
+		if err := rows.Scan(&o.ID, &o.RunID, &o.StepNumber, &o.Location, &o.Method, &o.ReferencedFiles, &o.ExpectedObjects,
+			&o.ExpectedBytes, &o.StoredObjects, &o.StoredCopies, &o.StoredBytes, &o.OtherFiles, &o.Status, &message,
+			&checkedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan server objects: %w", err)
+		}
+
+		o.Message = message.String
+		o.CheckedAt, _ = time.Parse(time.RFC3339, checkedAt)
+		results = append(results, &o)
+	}
+
+	return results, rows.Err()
+}
+
+// DeleteServerObjects removes the server object counts of a step, e.g. before it is re-run
+func (db *DB) DeleteServerObjects(runID int64, stepNumber int) error {
+	if _, err := db.exec(`DELETE FROM server_objects WHERE run_id = ? AND step_number = ?`, runID, stepNumber); err != nil {
+		return fmt.Errorf("failed to delete server objects: %w", err)
+	}
+	return nil
+}
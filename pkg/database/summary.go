@@ -0,0 +1,21 @@
+package database
+
+import "fmt"
+
+// SetTimingSummary stores the rendered breakdown of where the time of a completed run went
+func (db *DB) SetTimingSummary(runID int64, summary string) error {
+	if _, err := db.exec(`UPDATE test_runs SET timing_summary = ? WHERE id = ?`, summary, runID); err != nil {
+		return fmt.Errorf("failed to store timing summary: %w", err)
+	}
+	return nil
+}
+
+// GetTimingSummary returns the timing summary stored for a run; empty if it has none
+func (db *DB) GetTimingSummary(runID int64) (string, error) {
+	var summary string
+	err := db.queryRow(`SELECT COALESCE(timing_summary, '') FROM test_runs WHERE id = ?`, runID).Scan(&summary)
+	if err != nil {
+		return "", fmt.Errorf("failed to get timing summary: %w", err)
+	}
+	return summary, nil
+}
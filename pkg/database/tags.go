@@ -0,0 +1,147 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CampaignTag is the key of the tag that puts a run in a campaign, e.g. campaign=2025-q3
+const CampaignTag = "campaign"
+
+// Tag is a key/value annotation of a run or an operation, such as git-lfs=3.4.1 or disk=ssd
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// String returns the tag as key=value
+func (t Tag) String() string {
+	return t.Key + "=" + t.Value
+}
+
+// ParseTag parses a tag written as key=value. The value may be empty, the key may not,
+// and the key may not contain whitespace.
+func ParseTag(s string) (Tag, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return Tag{}, fmt.Errorf("invalid tag '%s' (use key=value)", s)
+	}
+	if err := checkTagKey(key); err != nil {
+		return Tag{}, err
+	}
+	return Tag{Key: key, Value: value}, nil
+}
+
+// checkTagKey rejects keys that could not be written back as key=value
+func checkTagKey(key string) error {
+	if key == "" || strings.ContainsAny(key, "= \t\n") {
+		return fmt.Errorf("invalid tag key '%s' (it must be non-empty, without '=' or whitespace)", key)
+	}
+	return nil
+}
+
+// SetRunTag tags a run, replacing the value of a tag with the same key
+func (db *DB) SetRunTag(runID int64, tag Tag) error {
+	if err := checkTagKey(tag.Key); err != nil {
+		return err
+	}
+	_, err := db.exec(`
+		INSERT INTO run_tags (run_id, key, value) VALUES (?, ?, ?)
+		ON CONFLICT (run_id, key) DO UPDATE SET value = excluded.value`,
+		runID, tag.Key, tag.Value,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to tag run %d: %w", runID, err)
+	}
+	return nil
+}
+
+// DeleteRunTag removes the tag with a key from a run, reporting whether the run had it
+func (db *DB) DeleteRunTag(runID int64, key string) (bool, error) {
+	result, err := db.exec(`DELETE FROM run_tags WHERE run_id = ? AND key = ?`, runID, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete tag '%s' of run %d: %w", key, runID, err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ListRunTags lists the tags of a run in key order
+func (db *DB) ListRunTags(runID int64) ([]Tag, error) {
+	return db.listTags(`SELECT key, value FROM run_tags WHERE run_id = ? ORDER BY key`, runID)
+}
+
+// SetOperationTag tags an operation, replacing the value of a tag with the same key
+func (db *DB) SetOperationTag(operationID int64, tag Tag) error {
+	if err := checkTagKey(tag.Key); err != nil {
+		return err
+	}
+	_, err := db.exec(`
+		INSERT INTO operation_tags (operation_id, key, value) VALUES (?, ?, ?)
+		ON CONFLICT (operation_id, key) DO UPDATE SET value = excluded.value`,
+		operationID, tag.Key, tag.Value,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to tag operation %d: %w", operationID, err)
+	}
+	return nil
+}
+
+// DeleteOperationTag removes the tag with a key from an operation, reporting whether the
+// operation had it
+func (db *DB) DeleteOperationTag(operationID int64, key string) (bool, error) {
+	result, err := db.exec(`DELETE FROM operation_tags WHERE operation_id = ? AND key = ?`, operationID, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete tag '%s' of operation %d: %w", key, operationID, err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ListOperationTags lists the tags of an operation in key order
+func (db *DB) ListOperationTags(operationID int64) ([]Tag, error) {
+	return db.listTags(`SELECT key, value FROM operation_tags WHERE operation_id = ? ORDER BY key`, operationID)
+}
+
+func (db *DB) listTags(query string, id int64) ([]Tag, error) {
+	rows, err := db.query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.Key, &t.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// runTagFilter returns the condition selecting the runs, aliased r, that carry every tag,
+// and its arguments
+func runTagFilter(tags []Tag) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, t := range tags {
+		conditions = append(conditions, `r.id IN (SELECT run_id FROM run_tags WHERE key = ? AND value = ?)`)
+		args = append(args, t.Key, t.Value)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// operationTagFilter returns the condition selecting the operations, aliased o, that carry
+// every tag themselves or through their run, and its arguments
+func operationTagFilter(tags []Tag) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, t := range tags {
+		conditions = append(conditions, `(o.run_id IN (SELECT run_id FROM run_tags WHERE key = ? AND value = ?)
+			OR o.id IN (SELECT operation_id FROM operation_tags WHERE key = ? AND value = ?))`)
+		args = append(args, t.Key, t.Value, t.Key, t.Value)
+	}
+	return strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Orders of TopOperations
+const (
+	TopByDuration = "duration" // Slowest first
+	TopByBytes    = "bytes"    // Most bytes first; operations that moved no counted bytes are left out
+)
+
+// TopOrders lists the orders of TopOperations
+var TopOrders = []string{TopByDuration, TopByBytes}
+
+// TopOperation is an operation with the run and step it belongs to, so operations of
+// different runs can be told apart
+type TopOperation struct {
+	Operation
+	StepName   string
+	ScenarioID int
+	ServerType string
+	Protocol   string
+	GitServer  string
+}
+
+// TopOptions select the operations TopOperations ranks
+type TopOptions struct {
+	RunID     int64  // Only the operations of this run; 0 ranks those of every run
+	Operation string // Only this operation type, e.g. push, if set
+	By        string // TopByDuration or TopByBytes
+	Limit     int    // Most operations returned
+	Tags      []Tag  // Only operations carrying every tag, themselves or through their run
+}
+
+// TopOperations returns the slowest operations, or those that moved the most bytes, to
+// find the bottlenecks of a run or of every run
+func (db *DB) TopOperations(opts TopOptions) ([]*TopOperation, error) {
+	query := `
+		SELECT o.id, o.run_id, o.step_number, o.operation, o.started_at, o.duration_ms, o.file_count, o.total_bytes,
+			o.status, o.error, o.transfer_adapter, s.step_name, r.scenario_id, r.server_type, r.protocol, r.git_server
+		FROM operations o
+		JOIN test_runs r ON r.id = o.run_id
+		LEFT JOIN step_results s ON s.run_id = o.run_id AND s.step_number = o.step_number
+		WHERE 1 = 1`
+	var args []interface{}
+	if opts.RunID != 0 {
+		query += ` AND o.run_id = ?`
+		args = append(args, opts.RunID)
+	}
+	if opts.Operation != "" {
+		query += ` AND o.operation = ?`
+		args = append(args, opts.Operation)
+	}
+	if len(opts.Tags) > 0 {
+		filter, tagArgs := operationTagFilter(opts.Tags)
+		query += ` AND ` + filter
+		args = append(args, tagArgs...)
+	}
+	switch opts.By {
+	case TopByDuration:
+		query += ` ORDER BY o.duration_ms DESC, o.id`
+	case TopByBytes:
+		query += ` AND o.total_bytes IS NOT NULL ORDER BY o.total_bytes DESC, o.duration_ms DESC, o.id`
+	default:
+		return nil, fmt.Errorf("invalid order '%s' (use %s or %s)", opts.By, TopByDuration, TopByBytes)
+	}
+	query += ` LIMIT ?`
+	args = append(args, opts.Limit)
+
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank operations: %w", err)
+	}
+	defer rows.Close()
+
+	var ops []*TopOperation
+	for rows.Next() {
+		var op TopOperation
+		var startedAt string
+		var errorMsg, adapter, stepName sql.NullString
+		err := rows.Scan(&op.ID, &op.RunID, &op.StepNumber, &op.Operation.Operation, &startedAt, &op.DurationMs,
+			&op.FileCount, &op.TotalBytes, &op.Status, &errorMsg, &adapter, &stepName,
+			&op.ScenarioID, &op.ServerType, &op.Protocol, &op.GitServer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan operation: %w", err)
+		}
+		op.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+		op.Error = errorMsg.String
+		op.TransferAdapter = adapter.String
+		op.StepName = stepName.String
+		ops = append(ops, &op)
+	}
+	return ops, rows.Err()
+}
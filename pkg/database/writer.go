@@ -0,0 +1,295 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Writes to an SQLite database go through a single writer goroutine per DB, which runs
+// them one after the other and commits whatever queued up meanwhile in one transaction.
+// Goroutines of the same process (parallel clients, samplers, recorders) therefore never
+// compete for SQLite's write lock and never wait out the busy timeout, and a burst of
+// writes costs one commit instead of one each. PostgreSQL serializes writers itself, so
+// there each write is its own transaction.
+
+// maxBatch is the most writes committed in one transaction
+const maxBatch = 64
+
+// ErrClosed is returned by writes to a closed database
+var ErrClosed = errors.New("database is closed")
+
+// States of a writeJob
+const (
+	jobPending   int32 = iota // Queued
+	jobRunning                // Taken by the writer; the caller waits for its outcome
+	jobCancelled              // Given up by the caller before the writer took it
+)
+
+// writeJob is one write: fn runs its statements on the transaction it is given
+type writeJob struct {
+	ctx    context.Context
+	fn     func(q querier) error
+	direct bool // Runs outside a transaction, like VACUUM must
+	state  atomic.Int32
+	done   chan error
+}
+
+// writer runs the writes of a DB
+type writer struct {
+	conn    *sql.DB
+	jobs    chan *writeJob
+	quit    chan struct{}
+	stopped chan struct{}
+	mu      sync.RWMutex // Held for writing by stop, so no job is queued after the last one ran
+	closed  bool
+	batches atomic.Int64 // Transactions committed, for tests
+}
+
+// startWriter starts the writer goroutine of conn
+func startWriter(conn *sql.DB) *writer {
+	w := &writer{
+		conn:    conn,
+		jobs:    make(chan *writeJob, maxBatch),
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// stop runs the writes still queued and ends the writer goroutine
+func (w *writer) stop() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.quit)
+	}
+	w.mu.Unlock()
+	<-w.stopped
+}
+
+// queue hands a job to the writer goroutine
+func (w *writer) queue(job *writeJob) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return ErrClosed
+	}
+	select {
+	case w.jobs <- job:
+		return nil
+	case <-job.ctx.Done():
+		return job.ctx.Err()
+	}
+}
+
+func (w *writer) run() {
+	defer close(w.stopped)
+	for {
+		select {
+		case job := <-w.jobs:
+			w.runBatch(job)
+		case <-w.quit:
+			for {
+				select {
+				case job := <-w.jobs:
+					w.runBatch(job)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// runBatch runs first and the writes queued behind it, up to maxBatch of them. A write
+// that must run outside a transaction ends the batch and runs on its own.
+func (w *writer) runBatch(first *writeJob) {
+	batch := []*writeJob{first}
+	var direct *writeJob
+	if first.direct {
+		batch, direct = nil, first
+	}
+	for direct == nil && len(batch) < maxBatch {
+		select {
+		case job := <-w.jobs:
+			if job.direct {
+				direct = job
+			} else {
+				batch = append(batch, job)
+			}
+			continue
+		default:
+		}
+		break
+	}
+
+	if len(batch) > 0 {
+		w.commit(batch)
+	}
+	if direct != nil && direct.start() {
+		direct.done <- direct.fn(w.conn)
+	}
+}
+
+// commit runs the writes of batch in one transaction, each within a savepoint, so a write
+// that fails is undone without undoing the others. Callers learn the outcome of their
+// write once it is committed.
+func (w *writer) commit(batch []*writeJob) {
+	tx, err := w.conn.Begin()
+	if err != nil {
+		for _, job := range batch {
+			if job.start() {
+				job.done <- fmt.Errorf("failed to begin transaction: %w", err)
+			}
+		}
+		return
+	}
+
+	var ran []*writeJob
+	var results []error
+	var abort error // Set when the transaction itself failed
+	for _, job := range batch {
+		if !job.start() {
+			continue
+		}
+		if abort != nil {
+			job.done <- abort
+			continue
+		}
+		ran = append(ran, job)
+		if _, err := tx.Exec("SAVEPOINT write"); err != nil {
+			abort = fmt.Errorf("failed to begin write: %w", err)
+			results = append(results, abort)
+			continue
+		}
+		err := job.fn(tx)
+		if err != nil {
+			if _, rollbackErr := tx.Exec("ROLLBACK TO write"); rollbackErr != nil {
+				abort = fmt.Errorf("failed to undo a write (%v): %w", err, rollbackErr)
+			}
+		}
+		if abort == nil {
+			if _, releaseErr := tx.Exec("RELEASE write"); releaseErr != nil {
+				abort = fmt.Errorf("failed to end write: %w", releaseErr)
+			}
+		}
+		results = append(results, err)
+	}
+
+	if abort != nil {
+		tx.Rollback()
+		for _, job := range ran {
+			job.done <- abort
+		}
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		for _, job := range ran {
+			job.done <- fmt.Errorf("failed to commit: %w", err)
+		}
+		return
+	}
+	w.batches.Add(1)
+	for i, job := range ran {
+		job.done <- results[i]
+	}
+}
+
+// start claims a job for the writer, unless its caller gave up on it. A job whose context
+// ended while it was queued is answered with the context's error.
+func (job *writeJob) start() bool {
+	if !job.state.CompareAndSwap(jobPending, jobRunning) {
+		return false
+	}
+	if err := job.ctx.Err(); err != nil {
+		job.done <- err
+		return false
+	}
+	return true
+}
+
+// stopWriter runs the queued writes and stops the writer, if db has one
+func (db *DB) stopWriter() {
+	if db.writer != nil {
+		db.writer.stop()
+	}
+}
+
+// write runs fn, which writes with the querier it is given, in the writer's next
+// transaction, and returns its error once the transaction is committed. The statements
+// of fn are committed or undone together. When the context of db ends before the write
+// started, it is dropped and the context's error returned. fn runs on the writer
+// goroutine, so it must not write through db itself.
+func (db *DB) write(fn func(q querier) error) error {
+	return db.submit(&writeJob{fn: fn})
+}
+
+// writeDirect runs fn outside of any transaction, for statements SQLite refuses within one
+func (db *DB) writeDirect(fn func(q querier) error) error {
+	return db.submit(&writeJob{fn: fn, direct: true})
+}
+
+// submit queues a job for the writer and waits for its outcome
+func (db *DB) submit(job *writeJob) error {
+	job.ctx = db.context()
+	if db.writer == nil {
+		return db.writeNow(job)
+	}
+	job.done = make(chan error, 1)
+	if err := db.writer.queue(job); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-job.done:
+		return err
+	case <-job.ctx.Done():
+		if job.state.CompareAndSwap(jobPending, jobCancelled) {
+			return job.ctx.Err()
+		}
+		return <-job.done // Already running; its outcome stands
+	}
+}
+
+// writeNow runs a job in a transaction of its own, on databases without a writer
+func (db *DB) writeNow(job *writeJob) error {
+	if err := job.ctx.Err(); err != nil {
+		return err
+	}
+	if job.direct {
+		return job.fn(db.conn)
+	}
+	tx, err := db.conn.BeginTx(job.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := job.fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// WithContext returns a view of db whose reads and writes end with ctx: queued writes are
+// dropped and running queries stopped. Close the original DB, not the view.
+func (db *DB) WithContext(ctx context.Context) *DB {
+	view := *db
+	view.ctx = ctx
+	return &view
+}
+
+// context returns the context of db's reads and writes
+func (db *DB) context() context.Context {
+	if db.ctx != nil {
+		return db.ctx
+	}
+	return context.Background()
+}
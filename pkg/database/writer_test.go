@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countVerifications returns the number of verifications of a run
+func countVerifications(t *testing.T, db *DB, runID int64) int {
+	t.Helper()
+	verifications, err := db.ListVerifications(runID)
+	if err != nil {
+		t.Fatalf("ListVerifications failed: %v", err)
+	}
+	return len(verifications)
+}
+
+// verification returns a passed verification of a run
+func verification(runID int64, name string) *Verification {
+	return &Verification{RunID: runID, StepNumber: 1, Name: name, Severity: "error", Status: "passed", CheckedAt: time.Now()}
+}
+
+// blockWriter occupies the writer until the returned function is called, so the writes
+// made meanwhile queue up behind it
+func blockWriter(t *testing.T, db *DB) func() {
+	t.Helper()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go db.write(func(q querier) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	return func() { close(release) }
+}
+
+// waitQueued waits until n writes are queued
+func waitQueued(t *testing.T, db *DB, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(db.writer.jobs) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d writes queued, want %d", len(db.writer.jobs), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	db := openTestDB(t)
+	run := createTestRun(t, db)
+
+	const writers, writes = 20, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*writes)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				if err := db.CreateVerification(verification(run.ID, fmt.Sprintf("check-%d-%d", i, j))); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent write failed: %v", err)
+	}
+	if n := countVerifications(t, db, run.ID); n != writers*writes {
+		t.Errorf("%d verifications written, want %d", n, writers*writes)
+	}
+}
+
+func TestWriteBatch(t *testing.T) {
+	db := openTestDB(t)
+	run := createTestRun(t, db)
+
+	release := blockWriter(t, db)
+	before := db.writer.batches.Load()
+	results := make([]error, 3)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i == 1 {
+				// Fails after writing, so its first statement must be undone as well
+				results[i] = db.write(func(q querier) error {
+					if _, err := db.insert(q, `INSERT INTO verifications (run_id, step_number, name, severity, status, checked_at)
+						VALUES (?, 1, 'undone', 'error', 'passed', '')`, run.ID); err != nil {
+						return err
+					}
+					_, err := q.Exec(`INSERT INTO no_such_table VALUES (1)`)
+					return err
+				})
+				return
+			}
+			results[i] = db.CreateVerification(verification(run.ID, fmt.Sprintf("check-%d", i)))
+		}(i)
+		waitQueued(t, db, i+1)
+	}
+	release()
+	wg.Wait()
+
+	if results[0] != nil || results[2] != nil || results[1] == nil {
+		t.Errorf("results = %v; want only the second write to fail", results)
+	}
+	if n := countVerifications(t, db, run.ID); n != 2 {
+		t.Errorf("%d verifications written, want the 2 of the writes that succeeded", n)
+	}
+	// The blocking write and the three queued behind it
+	if batches := db.writer.batches.Load() - before; batches != 2 {
+		t.Errorf("%d transactions committed, want 2", batches)
+	}
+}
+
+func TestWriteContext(t *testing.T) {
+	db := openTestDB(t)
+	run := createTestRun(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := db.WithContext(ctx).CreateVerification(verification(run.ID, "cancelled")); !errors.Is(err, context.Canceled) {
+		t.Errorf("write with a cancelled context returned %v", err)
+	}
+
+	// A queued write is dropped when its context ends before the writer gets to it
+	release := blockWriter(t, db)
+	ctx, cancel = context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- db.WithContext(ctx).CreateVerification(verification(run.ID, "queued")) }()
+	waitQueued(t, db, 1)
+	cancel()
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("cancelled queued write returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled write still waiting for the writer")
+	}
+	release()
+
+	if err := db.CreateVerification(verification(run.ID, "after")); err != nil {
+		t.Fatalf("write after cancellation failed: %v", err)
+	}
+	if n := countVerifications(t, db, run.ID); n != 1 {
+		t.Errorf("%d verifications written, want only the last", n)
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	run := createTestRun(t, db)
+	db.Close()
+	if err := db.CreateVerification(verification(run.ID, "late")); !errors.Is(err, ErrClosed) {
+		t.Errorf("write after Close returned %v, want ErrClosed", err)
+	}
+}
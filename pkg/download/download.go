@@ -7,6 +7,9 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
 )
 
 // FileDownload describes a file to download
@@ -15,6 +18,7 @@ type FileDownload struct {
 	FileName  string // Target filename to save as
 	URLDir    string // URL directory (for display purposes)
 	ShortName string // Short name for display
+	SHA256    string // Expected SHA-256 digest (hex); empty if the source publishes none
 }
 
 // DownloadFile downloads a file from a URL with retry logic
@@ -22,15 +26,11 @@ type FileDownload struct {
 func DownloadFile(url, destPath string, debug bool) (bool, error) {
 	// Check if file already exists
 	if _, err := os.Stat(destPath); err == nil {
-		if debug {
-			fmt.Printf("  %s already exists\n", filepath.Base(destPath))
-		}
+		log.Debugf("  %s already exists\n", filepath.Base(destPath))
 		return true, nil
 	}
 
-	if debug {
-		fmt.Printf("  Downloading %s\n", filepath.Base(destPath))
-	}
+	log.Debugf("  Downloading %s\n", filepath.Base(destPath))
 
 	// Create parent directory if needed
 	dir := filepath.Dir(destPath)
@@ -52,12 +52,18 @@ func DownloadFile(url, destPath string, debug bool) (bool, error) {
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		if attempt > 1 {
-			if debug {
-				fmt.Printf("  Retry %d/%d for %s\n", attempt-1, maxRetries-1, filepath.Base(destPath))
-			}
+			log.Debugf("  Retry %d/%d for %s\n", attempt-1, maxRetries-1, filepath.Base(destPath))
 			time.Sleep(time.Second * time.Duration(attempt))
 		}
 
+		// Discard any partial content from a failed attempt
+		if err := out.Truncate(0); err != nil {
+			return false, fmt.Errorf("failed to truncate file: %w", err)
+		}
+		if _, err := out.Seek(0, io.SeekStart); err != nil {
+			return false, fmt.Errorf("failed to rewind file: %w", err)
+		}
+
 		// Make HTTP request
 		client := &http.Client{
 			Timeout: 30 * time.Minute, // Long timeout for large files
@@ -89,9 +95,8 @@ func DownloadFile(url, destPath string, debug bool) (bool, error) {
 			return false, fmt.Errorf("failed to rename downloaded file: %w", err)
 		}
 
-		if debug {
-			info, _ := os.Stat(destPath)
-			fmt.Printf("  ✓ Downloaded %s (%s)\n", filepath.Base(destPath), formatSize(info.Size()))
+		if info, err := os.Stat(destPath); err == nil {
+			log.Debugf("  %s Downloaded %s (%s)\n", term.OK(), filepath.Base(destPath), formatSize(info.Size()))
 		}
 
 		return false, nil
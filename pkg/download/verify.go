@@ -0,0 +1,107 @@
+package download
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+)
+
+// ManifestName is the name of the SHA-256 manifest written to each download directory
+const ManifestName = "SHA256SUMS"
+
+// Manifest maps file names to their SHA-256 digests (hex)
+type Manifest map[string]string
+
+// FileSHA256 returns the SHA-256 digest (hex) of a file
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", filepath.Base(path), err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DownloadVerified downloads fd like DownloadFile and, when fd.SHA256 is set, checks the
+// digest of the result. A file that does not match is removed and an error is returned.
+func DownloadVerified(fd FileDownload, destPath string, debug bool) (bool, error) {
+	existed, err := DownloadFile(fd.URL, destPath, debug)
+	if err != nil || fd.SHA256 == "" {
+		return existed, err
+	}
+
+	digest, err := FileSHA256(destPath)
+	if err != nil {
+		return existed, err
+	}
+	if !strings.EqualFold(digest, fd.SHA256) {
+		os.Remove(destPath)
+		return existed, fmt.Errorf("SHA-256 mismatch for %s: expected %s, got %s", filepath.Base(destPath), fd.SHA256, digest)
+	}
+
+	log.Debugf("  %s Verified SHA-256 of %s\n", term.OK(), filepath.Base(destPath))
+	return existed, nil
+}
+
+// ReadManifest reads a manifest in sha256sum format ("DIGEST  NAME" per line).
+// A missing manifest is returned as an empty one.
+func ReadManifest(path string) (Manifest, error) {
+	m := make(Manifest)
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		digest, name, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok {
+			continue
+		}
+		// sha256sum marks binary mode with '*' before the name
+		m[strings.TrimPrefix(strings.TrimSpace(name), "*")] = strings.ToLower(digest)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+// WriteManifest writes a manifest in sha256sum format, sorted by name
+func WriteManifest(path string, m Manifest) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&sb, "%s  %s\n", m[name], name)
+	}
+
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
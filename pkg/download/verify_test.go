@@ -0,0 +1,63 @@
+package download
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ManifestName)
+
+	m, err := ReadManifest(path)
+	if err != nil || len(m) != 0 {
+		t.Fatalf("Missing manifest should read as empty, got %v, %v", m, err)
+	}
+
+	m = Manifest{"zip1.zip": "aa11", "pdf1.pdf": "bb22"}
+	if err := WriteManifest(path, m); err != nil {
+		t.Fatalf("WriteManifest failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "bb22  pdf1.pdf\naa11  zip1.zip\n" {
+		t.Errorf("Unexpected manifest content:\n%s", content)
+	}
+
+	// sha256sum binary-mode entries are accepted too
+	os.WriteFile(path, []byte("CC33 *video1.m4v\n"), 0644)
+	m, err = ReadManifest(path)
+	if err != nil || m["video1.m4v"] != "cc33" {
+		t.Errorf("ReadManifest = %v, %v; want video1.m4v -> cc33", m, err)
+	}
+}
+
+func TestDownloadVerified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	good := filepath.Join(tmpDir, "good.bin")
+	src := filepath.Join(tmpDir, "src.bin")
+	os.WriteFile(src, []byte("test content"), 0644)
+	digest, err := FileSHA256(src)
+	if err != nil {
+		t.Fatalf("FileSHA256 failed: %v", err)
+	}
+
+	if _, err := DownloadVerified(FileDownload{URL: server.URL, SHA256: digest}, good, false); err != nil {
+		t.Errorf("Download with matching digest failed: %v", err)
+	}
+
+	bad := filepath.Join(tmpDir, "bad.bin")
+	if _, err := DownloadVerified(FileDownload{URL: server.URL, SHA256: "0000"}, bad, false); err == nil {
+		t.Error("Download with mismatched digest should fail")
+	}
+	if _, err := os.Stat(bad); !os.IsNotExist(err) {
+		t.Error("Mismatched download should be removed")
+	}
+}
@@ -0,0 +1,219 @@
+// Package environment collects facts about the machine a test runs on, such as the
+// git-lfs version, kernel and disk type, so results stay interpretable months later
+package environment
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/preflight"
+)
+
+// Linux sources of mounted filesystems, the CPU model and the physical memory
+const (
+	procMounts  = "/proc/mounts"
+	procCPUInfo = "/proc/cpuinfo"
+	procMemInfo = "/proc/meminfo"
+)
+
+// Mount is a mounted filesystem
+type Mount struct {
+	Device string
+	Dir    string
+	Type   string
+}
+
+// Collect describes the machine and the filesystem holding dir, for a run to record.
+// Facts that cannot be found, such as the disk type outside Linux, are left empty.
+func Collect(dir string) *database.Environment {
+	env := &database.Environment{
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		CPUs:        runtime.NumCPU(),
+		CollectedAt: time.Now(),
+	}
+	if output, err := exec.Command("git", "--version").Output(); err == nil {
+		env.GitVersion = strings.TrimPrefix(strings.TrimSpace(string(output)), "git version ")
+	}
+	if output, err := exec.Command("git", "lfs", "version").Output(); err == nil {
+		env.GitLFSVersion, _ = preflight.ParseGitLFSVersion(string(output))
+	}
+	if output, err := exec.Command("uname", "-r").Output(); err == nil {
+		env.Kernel = strings.TrimSpace(string(output))
+	}
+	env.CPUModel = cpuModel()
+	env.MemoryBytes = memoryBytes()
+	if m := mountOf(dir); m != nil {
+		env.Filesystem = m.Type
+		env.Disk = diskType(m.Device)
+	}
+	return env
+}
+
+// Tags returns the facts of an environment that tell results apart as tags, in key order:
+// arch, disk, filesystem, git, git-lfs, kernel and os. Empty facts are left out.
+func Tags(env *database.Environment) []database.Tag {
+	var tags []database.Tag
+	for _, tag := range []database.Tag{
+		{Key: "arch", Value: env.Arch},
+		{Key: "disk", Value: env.Disk},
+		{Key: "filesystem", Value: env.Filesystem},
+		{Key: "git", Value: env.GitVersion},
+		{Key: "git-lfs", Value: env.GitLFSVersion},
+		{Key: "kernel", Value: env.Kernel},
+		{Key: "os", Value: env.OS},
+	} {
+		if tag.Value != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// cpuModel returns the model name of the CPU, or "" if it cannot be found
+func cpuModel() string {
+	if f, err := os.Open(procCPUInfo); err == nil {
+		defer f.Close()
+		return parseCPUModel(f)
+	}
+	if output, err := exec.Command("sysctl", "-n", "machdep.cpu.brand_string").Output(); err == nil {
+		return strings.TrimSpace(string(output)) // macOS
+	}
+	return ""
+}
+
+// parseCPUModel returns the model of the first CPU in the format of /proc/cpuinfo: its
+// model name on x86, its Model or Hardware on ARM
+func parseCPUModel(r io.Reader) string {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, seen := fields[key]; !seen {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	for _, key := range []string{"model name", "Model", "Hardware"} {
+		if fields[key] != "" {
+			return fields[key]
+		}
+	}
+	return ""
+}
+
+// memoryBytes returns the physical memory, or 0 if it cannot be found
+func memoryBytes() int64 {
+	if f, err := os.Open(procMemInfo); err == nil {
+		defer f.Close()
+		return parseMemTotal(f)
+	}
+	if output, err := exec.Command("sysctl", "-n", "hw.memsize").Output(); err == nil {
+		n, _ := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64) // macOS
+		return n
+	}
+	return 0
+}
+
+// parseMemTotal returns the MemTotal of the format of /proc/meminfo in bytes
+func parseMemTotal(r io.Reader) int64 {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, _ := strconv.ParseInt(fields[1], 10, 64)
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+// mountOf returns the filesystem holding dir, or nil if it cannot be found
+func mountOf(dir string) *Mount {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	f, err := os.Open(procMounts)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	mounts, err := parseMounts(f)
+	if err != nil {
+		return nil
+	}
+	return FindMount(mounts, dir)
+}
+
+// parseMounts parses the format of /proc/mounts
+func parseMounts(r io.Reader) ([]Mount, error) {
+	var mounts []Mount
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		// Spaces in mount points are escaped as \040
+		mounts = append(mounts, Mount{Device: fields[0], Dir: strings.ReplaceAll(fields[1], `\040`, " "), Type: fields[2]})
+	}
+	return mounts, scanner.Err()
+}
+
+// FindMount returns the mount holding the absolute path dir: the one with the longest
+// mount point containing it, mounted last if several share it. It returns nil if none does.
+func FindMount(mounts []Mount, dir string) *Mount {
+	var found *Mount
+	for i := range mounts {
+		m := &mounts[i]
+		if dir != m.Dir && !strings.HasPrefix(dir, strings.TrimSuffix(m.Dir, "/")+"/") {
+			continue
+		}
+		if found == nil || len(m.Dir) >= len(found.Dir) {
+			found = m
+		}
+	}
+	return found
+}
+
+// diskType returns ssd or hdd for a block device such as /dev/sda1, from the rotational
+// flag of its disk, or "" if the device is not a local disk
+func diskType(device string) string {
+	if !strings.HasPrefix(device, "/dev/") {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(device); err == nil {
+		device = resolved // e.g. /dev/mapper/root, a link to /dev/dm-0
+	}
+	// A partition's directory in /sys/class/block sits in that of its disk
+	block, err := filepath.EvalSymlinks(filepath.Join("/sys/class/block", filepath.Base(device)))
+	if err != nil {
+		return ""
+	}
+	for _, dir := range []string{block, filepath.Dir(block)} {
+		data, err := os.ReadFile(filepath.Join(dir, "queue", "rotational"))
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(string(data)) == "1" {
+			return "hdd"
+		}
+		return "ssd"
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package environment
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleMounts = `sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0
+/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+/dev/sda1 /data xfs rw,relatime 0 0
+tmpfs /data/tmp tmpfs rw,nosuid,nodev 0 0
+/dev/sdb1 /mnt/my\040disk btrfs rw,relatime 0 0
+`
+
+func TestFindMount(t *testing.T) {
+	mounts, err := parseMounts(strings.NewReader(sampleMounts))
+	if err != nil {
+		t.Fatalf("parseMounts failed: %v", err)
+	}
+	if len(mounts) != 5 {
+		t.Fatalf("Expected 5 mounts, got %d", len(mounts))
+	}
+
+	tests := []struct {
+		dir, device, fsType string
+	}{
+		{"/home/mslinn/work", "/dev/nvme0n1p2", "ext4"},
+		{"/data", "/dev/sda1", "xfs"},
+		{"/data/runs/5", "/dev/sda1", "xfs"},
+		{"/data/tmp/x", "tmpfs", "tmpfs"},
+		{"/datastore", "/dev/nvme0n1p2", "ext4"},
+		{"/mnt/my disk/repo", "/dev/sdb1", "btrfs"},
+	}
+	for _, tt := range tests {
+		m := FindMount(mounts, tt.dir)
+		if m == nil || m.Device != tt.device || m.Type != tt.fsType {
+			t.Errorf("FindMount(%s) = %+v, want %s %s", tt.dir, m, tt.device, tt.fsType)
+		}
+	}
+	if m := FindMount(mounts[:1], "/home"); m != nil {
+		t.Errorf("FindMount outside every mount = %+v, want nil", m)
+	}
+}
+
+const sampleCPUInfo = `processor	: 0
+vendor_id	: GenuineIntel
+model name	: Intel(R) Core(TM) i7-8700 CPU @ 3.20GHz
+cpu MHz		: 800.024
+
+processor	: 1
+model name	: Intel(R) Core(TM) i7-8700 CPU @ 3.20GHz
+`
+
+const sampleARMCPUInfo = `processor	: 0
+BogoMIPS	: 108.00
+CPU part	: 0xd08
+
+Hardware	: BCM2835
+Model		: Raspberry Pi 4 Model B Rev 1.4
+`
+
+func TestParseCPUModel(t *testing.T) {
+	if got := parseCPUModel(strings.NewReader(sampleCPUInfo)); got != "Intel(R) Core(TM) i7-8700 CPU @ 3.20GHz" {
+		t.Errorf("parseCPUModel of x86 = %q", got)
+	}
+	if got := parseCPUModel(strings.NewReader(sampleARMCPUInfo)); got != "Raspberry Pi 4 Model B Rev 1.4" {
+		t.Errorf("parseCPUModel of ARM = %q", got)
+	}
+}
+
+func TestParseMemTotal(t *testing.T) {
+	meminfo := "MemTotal:       32594980 kB\nMemFree:         1234567 kB\n"
+	if got := parseMemTotal(strings.NewReader(meminfo)); got != 32594980*1024 {
+		t.Errorf("parseMemTotal = %d, want %d", got, 32594980*1024)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	env := Collect(t.TempDir())
+	if env.OS == "" || env.Arch == "" || env.CPUs == 0 {
+		t.Errorf("Collect = %+v, want at least the OS, architecture and CPU count", env)
+	}
+
+	tags := Tags(env)
+	keys := make(map[string]string)
+	for i, tag := range tags {
+		if i > 0 && tags[i-1].Key >= tag.Key {
+			t.Errorf("Tags are not in key order: %v", tags)
+		}
+		keys[tag.Key] = tag.Value
+	}
+	if keys["os"] != env.OS || keys["arch"] != env.Arch {
+		t.Errorf("Tags = %v, want at least os and arch", tags)
+	}
+}
@@ -0,0 +1,51 @@
+package git
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/log"
+)
+
+// adapterTrace matches git-lfs tracing a transfer adapter as it starts, e.g.
+// `xfer: adapter "basic" Begin() with 8 workers`
+var adapterTrace = regexp.MustCompile(`xfer: adapter "([^"]+)" Begin\(\)`)
+
+// ParseAdapters returns the transfer adapters that started in git-lfs trace output, in the
+// order they were first used, e.g. [basic] or [ssh]
+func ParseAdapters(trace string) []string {
+	var adapters []string
+	for _, m := range adapterTrace.FindAllStringSubmatch(trace, -1) {
+		if !slices.Contains(adapters, m[1]) {
+			adapters = append(adapters, m[1])
+		}
+	}
+	return adapters
+}
+
+// traceAdapters makes git and git-lfs write their traces to a temporary file, whose adapter
+// lines tell which transfer adapters moved the objects of a command. It returns the
+// environment variable to add to the command, and the function that reads the adapters,
+// comma-separated, and removes the file. A GIT_TRACE set by the user is left alone.
+func traceAdapters() (string, func() string) {
+	if os.Getenv("GIT_TRACE") != "" {
+		return "", func() string { return "" }
+	}
+	f, err := os.CreateTemp("", "lfst-trace-*.log")
+	if err != nil {
+		log.Warnf("cannot trace transfer adapters: %v\n", err)
+		return "", func() string { return "" }
+	}
+	f.Close()
+
+	return "GIT_TRACE=" + f.Name(), func() string {
+		defer os.Remove(f.Name())
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			return ""
+		}
+		return strings.Join(ParseAdapters(string(data)), ",")
+	}
+}
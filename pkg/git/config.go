@@ -0,0 +1,76 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// ExportConfig passes git config settings to every git command this process starts, through
+// GIT_CONFIG_COUNT (git 2.31+), so credentials never reach a repository's .git/config.
+// Exporting a key again replaces its earlier value.
+func ExportConfig(settings map[string]string) {
+	count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+
+	index := make(map[string]int)
+	for i := 0; i < count; i++ {
+		index[os.Getenv(fmt.Sprintf("GIT_CONFIG_KEY_%d", i))] = i
+	}
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		i, ok := index[key]
+		if !ok {
+			i = count
+			count++
+			os.Setenv(fmt.Sprintf("GIT_CONFIG_KEY_%d", i), key)
+		}
+		os.Setenv(fmt.Sprintf("GIT_CONFIG_VALUE_%d", i), settings[key])
+	}
+	os.Setenv("GIT_CONFIG_COUNT", strconv.Itoa(count))
+}
+
+// UnexportConfig stops passing settings exported by ExportConfig to git commands
+func UnexportConfig(keys ...string) {
+	count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+
+	remove := make(map[string]bool)
+	for _, key := range keys {
+		remove[key] = true
+	}
+
+	// Move the remaining settings down over the removed ones
+	kept := 0
+	for i := 0; i < count; i++ {
+		key := os.Getenv(fmt.Sprintf("GIT_CONFIG_KEY_%d", i))
+		if remove[key] {
+			continue
+		}
+		os.Setenv(fmt.Sprintf("GIT_CONFIG_KEY_%d", kept), key)
+		os.Setenv(fmt.Sprintf("GIT_CONFIG_VALUE_%d", kept), os.Getenv(fmt.Sprintf("GIT_CONFIG_VALUE_%d", i)))
+		kept++
+	}
+	for i := kept; i < count; i++ {
+		os.Unsetenv(fmt.Sprintf("GIT_CONFIG_KEY_%d", i))
+		os.Unsetenv(fmt.Sprintf("GIT_CONFIG_VALUE_%d", i))
+	}
+	os.Setenv("GIT_CONFIG_COUNT", strconv.Itoa(kept))
+}
+
+// SetConfig sets a git config value in a repository
+func (ctx *Context) SetConfig(repoDir, key, value string) error {
+	result := ctx.run("git", []string{"-C", repoDir, "config", key, value}, nil)
+	if result.Error != nil {
+		return fmt.Errorf("git config failed: %w", result.Error)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("git config %s failed (exit %d): %s", key, result.ExitCode, result.Stderr)
+	}
+	return nil
+}
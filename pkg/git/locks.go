@@ -0,0 +1,97 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+)
+
+// Lock is a file lock held on the LFS server, as listed by git lfs locks --json
+type Lock struct {
+	ID    string `json:"id"`
+	Path  string `json:"path"`
+	Owner struct {
+		Name string `json:"name"`
+	} `json:"owner"`
+	LockedAt string `json:"locked_at"`
+}
+
+// LFSLock locks a file on the LFS server of a repository's remote
+func (ctx *Context) LFSLock(repoDir, path string) error {
+	log.Debugf("[Step %d] Locking %s\n", ctx.StepNumber, path)
+
+	result := ctx.run("git", []string{"-C", repoDir, "lfs", "lock", path}, nil)
+
+	if err := ctx.recordRepoOperation(repoDir, "lfs-lock", fmt.Sprintf("git lfs lock %s", path), result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
+	}
+
+	if result.Error != nil {
+		return fmt.Errorf("git lfs lock failed: %w", result.Error)
+	}
+
+	if result.ExitCode != 0 {
+		return fmt.Errorf("git lfs lock failed (exit %d): %s", result.ExitCode, result.Stderr)
+	}
+
+	log.Debugf("  %s Locked %s in %dms\n", term.OK(), path, result.DurationMs)
+
+	return nil
+}
+
+// LFSUnlock releases a lock; force releases a lock held by someone else
+func (ctx *Context) LFSUnlock(repoDir, path string, force bool) error {
+	log.Debugf("[Step %d] Unlocking %s\n", ctx.StepNumber, path)
+
+	args := []string{"-C", repoDir, "lfs", "unlock", path}
+	if force {
+		args = append(args, "--force")
+	}
+	result := ctx.run("git", args, nil)
+
+	if err := ctx.recordRepoOperation(repoDir, "lfs-unlock", fmt.Sprintf("git lfs unlock %s", path), result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
+	}
+
+	if result.Error != nil {
+		return fmt.Errorf("git lfs unlock failed: %w", result.Error)
+	}
+
+	if result.ExitCode != 0 {
+		return fmt.Errorf("git lfs unlock failed (exit %d): %s", result.ExitCode, result.Stderr)
+	}
+
+	log.Debugf("  %s Unlocked %s in %dms\n", term.OK(), path, result.DurationMs)
+
+	return nil
+}
+
+// LFSLocks lists the locks the LFS server holds for a repository
+func (ctx *Context) LFSLocks(repoDir string) ([]Lock, error) {
+	log.Debugf("[Step %d] Listing locks\n", ctx.StepNumber)
+
+	result := ctx.run("git", []string{"-C", repoDir, "lfs", "locks", "--json"}, nil)
+
+	if err := ctx.recordRepoOperation(repoDir, "lfs-locks", "git lfs locks", result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
+	}
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("git lfs locks failed: %w", result.Error)
+	}
+
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("git lfs locks failed (exit %d): %s", result.ExitCode, result.Stderr)
+	}
+
+	var locks []Lock
+	if err := json.Unmarshal([]byte(result.Stdout), &locks); err != nil {
+		return nil, fmt.Errorf("failed to parse git lfs locks output: %w", err)
+	}
+
+	log.Debugf("  %s %d lock(s) listed in %dms\n", term.OK(), len(locks), result.DurationMs)
+
+	return locks, nil
+}
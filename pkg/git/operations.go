@@ -8,6 +8,9 @@ import (
 	"time"
 
 	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/githost"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
 	"github.com/mslinn/git-lfs-test/pkg/timing"
 )
 
@@ -18,10 +21,38 @@ type Context struct {
 	StepNumber int
 	Debug      bool
 	WorkDir    string // Working directory for operations
+	// Exec holds the options every command runs with, such as the context, deadline and
+	// resource limits of the run
+	Exec timing.Options
 }
 
-// recordOperation records a git operation in the database
+// run executes a git command with the options of the context, adding the working
+// directory, timeout, progress and environment of opts, which may be nil
+func (ctx *Context) run(command string, args []string, opts *timing.Options) *timing.Result {
+	options := ctx.Exec
+	if opts != nil {
+		options.Dir = opts.Dir
+		options.Timeout = opts.Timeout
+		options.Progress = opts.Progress
+		options.Env = append(append([]string(nil), options.Env...), opts.Env...)
+	}
+	return timing.Run(command, args, &options)
+}
+
+// recordOperation records an operation that does not act on a local repository
 func (ctx *Context) recordOperation(opType, command string, result *timing.Result) error {
+	return ctx.recordRepoOperation("", opType, command, result)
+}
+
+// recordRepoOperation records a git operation on repoDir in the database,
+// together with the operations it depended on
+func (ctx *Context) recordRepoOperation(repoDir, opType, command string, result *timing.Result) error {
+	return ctx.recordTransferOperation(repoDir, opType, command, "", result)
+}
+
+// recordTransferOperation records a git operation on repoDir that moved LFS objects with
+// the transfer adapters adapter, e.g. "basic"; empty if it moved none or they are unknown
+func (ctx *Context) recordTransferOperation(repoDir, opType, command, adapter string, result *timing.Result) error {
 	if ctx.DB == nil {
 		return nil // Skip if no database
 	}
@@ -35,27 +66,117 @@ func (ctx *Context) recordOperation(opType, command string, result *timing.Resul
 		status = "failed"
 		errorMsg = fmt.Sprintf("exit code %d: %s", result.ExitCode, result.Stderr)
 	}
+	errorKind := ""
+	if status == "failed" {
+		errorKind = githost.QuotaErrorKind(opType, result.Stderr)
+	}
 
 	op := &database.Operation{
-		RunID:       ctx.RunID,
-		StepNumber:  ctx.StepNumber,
-		Operation:   opType,
-		StartedAt:   time.Now().Add(-time.Duration(result.DurationMs) * time.Millisecond),
-		DurationMs:  result.DurationMs,
-		FileCount:   nil, // TODO: extract from output
-		TotalBytes:  nil, // TODO: extract from output
-		Status:      status,
-		Error:       errorMsg,
-	}
-
-	return ctx.DB.CreateOperation(op)
+		RunID:           ctx.RunID,
+		StepNumber:      ctx.StepNumber,
+		Operation:       opType,
+		StartedAt:       time.Now().Add(-time.Duration(result.DurationMs) * time.Millisecond),
+		DurationMs:      result.DurationMs,
+		Status:          status,
+		Error:           errorMsg,
+		ErrorKind:       errorKind,
+		Repo:            repoDir,
+		TransferAdapter: adapter,
+	}
+
+	if u := result.Usage; u != nil {
+		op.Resources = &database.ResourceUsage{
+			CPUMs:          u.CPUMs,
+			PeakCPUPercent: u.PeakCPUPercent,
+			PeakRSSBytes:   u.PeakRSSBytes,
+			AvgRSSBytes:    u.AvgRSSBytes,
+			ReadBytes:      u.ReadBytes,
+			WriteBytes:     u.WriteBytes,
+		}
+	}
+
+	// git and git-lfs report progress on stderr and commit summaries on stdout
+	transfer := timing.ParseTransfer(result.Stderr + "\n" + result.Stdout)
+	if n, ok := transfer.FileCount(); ok {
+		op.FileCount = &n
+	}
+	if n, ok := transfer.TotalBytes(); ok {
+		op.TotalBytes = &n
+	}
+
+	// Dependencies must be found before the operation itself is recorded
+	deps, err := ctx.findDependencies(repoDir, opType)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.DB.CreateOperation(op); err != nil {
+		return err
+	}
+
+	opLog := &database.OperationLog{
+		OperationID: op.ID,
+		Command:     strings.Join(append([]string{result.Command}, result.Args...), " "),
+		ExitCode:    result.ExitCode,
+		Stdout:      result.Stdout,
+		Stderr:      result.Stderr,
+	}
+	if err := ctx.DB.SaveOperationLog(opLog); err != nil {
+		return err
+	}
+
+	for _, dep := range deps {
+		dep.OperationID = op.ID
+		if err := ctx.DB.AddOperationDependency(dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dataDependencies maps an operation type to the operation types in other repositories
+// whose results it consumes, in order of preference (a clone reads what was last pushed,
+// or committed when cloning a local working repository directly)
+var dataDependencies = map[string][]string{
+	"clone": {"push", "commit"},
+	"pull":  {"push", "commit"},
+}
+
+// findDependencies returns the operations that an operation of opType on repoDir must follow:
+// the previous operation on the same repository and, for clones and pulls, the operation
+// in another repository that produced the data they fetch
+func (ctx *Context) findDependencies(repoDir, opType string) ([]*database.OperationDependency, error) {
+	if repoDir == "" {
+		return nil, nil
+	}
+
+	var deps []*database.OperationDependency
+
+	prev, err := ctx.DB.LatestOperationID(ctx.RunID, repoDir, "", false)
+	if err != nil {
+		return nil, err
+	}
+	if prev != 0 {
+		deps = append(deps, &database.OperationDependency{DependsOnID: prev, Kind: "sequence"})
+	}
+
+	for _, source := range dataDependencies[opType] {
+		id, err := ctx.DB.LatestOperationID(ctx.RunID, repoDir, source, true)
+		if err != nil {
+			return nil, err
+		}
+		if id != 0 {
+			deps = append(deps, &database.OperationDependency{DependsOnID: id, Kind: "data"})
+			break
+		}
+	}
+
+	return deps, nil
 }
 
 // Clone clones a git repository
 func (ctx *Context) Clone(url, destDir string) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Cloning %s to %s\n", ctx.StepNumber, url, destDir)
-	}
+	log.Debugf("[Step %d] Cloning %s to %s\n", ctx.StepNumber, url, destDir)
 
 	// Remove destination if it exists
 	if err := os.RemoveAll(destDir); err != nil {
@@ -69,11 +190,11 @@ func (ctx *Context) Clone(url, destDir string) error {
 	}
 
 	// Run git clone
-	result := timing.Run("git", []string{"clone", url, destDir}, nil)
-	if err := ctx.recordOperation("clone", fmt.Sprintf("git clone %s", url), result); err != nil {
-		if ctx.Debug {
-			fmt.Printf("  Warning: failed to record operation: %v\n", err)
-		}
+	opts, done := transferOptions("Cloning " + filepath.Base(destDir))
+	result := ctx.run("git", []string{"clone", "--progress", url, destDir}, opts)
+	adapter := done()
+	if err := ctx.recordTransferOperation(destDir, "clone", fmt.Sprintf("git clone %s", url), adapter, result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
 	}
 
 	if result.Error != nil {
@@ -81,21 +202,20 @@ func (ctx *Context) Clone(url, destDir string) error {
 	}
 
 	if result.ExitCode != 0 {
+		if quotaErr := githost.NewQuotaError("clone", result.Stderr); quotaErr != nil {
+			return fmt.Errorf("git clone failed: %w", quotaErr)
+		}
 		return fmt.Errorf("git clone failed (exit %d): %s", result.ExitCode, result.Stderr)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Cloned in %dms\n", result.DurationMs)
-	}
+	log.Debugf("  %s Cloned in %dms\n", term.OK(), result.DurationMs)
 
 	return nil
 }
 
 // InitRepo initializes a new git repository
 func (ctx *Context) InitRepo(dir string, bare bool) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Initializing git repository in %s (bare=%v)\n", ctx.StepNumber, dir, bare)
-	}
+	log.Debugf("[Step %d] Initializing git repository in %s (bare=%v)\n", ctx.StepNumber, dir, bare)
 
 	// Create directory
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -109,11 +229,9 @@ func (ctx *Context) InitRepo(dir string, bare bool) error {
 	}
 	args = append(args, dir)
 
-	result := timing.Run("git", args, nil)
-	if err := ctx.recordOperation("init", fmt.Sprintf("git init %s", dir), result); err != nil {
-		if ctx.Debug {
-			fmt.Printf("  Warning: failed to record operation: %v\n", err)
-		}
+	result := ctx.run("git", args, nil)
+	if err := ctx.recordRepoOperation(dir, "init", fmt.Sprintf("git init %s", dir), result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
 	}
 
 	if result.Error != nil {
@@ -124,26 +242,20 @@ func (ctx *Context) InitRepo(dir string, bare bool) error {
 		return fmt.Errorf("git init failed (exit %d): %s", result.ExitCode, result.Stderr)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Initialized in %dms\n", result.DurationMs)
-	}
+	log.Debugf("  %s Initialized in %dms\n", term.OK(), result.DurationMs)
 
 	return nil
 }
 
 // Add stages files for commit
 func (ctx *Context) Add(repoDir string, paths ...string) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Adding files: %v\n", ctx.StepNumber, paths)
-	}
+	log.Debugf("[Step %d] Adding files: %v\n", ctx.StepNumber, paths)
 
 	args := append([]string{"-C", repoDir, "add"}, paths...)
-	result := timing.Run("git", args, nil)
+	result := ctx.run("git", args, nil)
 
-	if err := ctx.recordOperation("add", fmt.Sprintf("git add %s", strings.Join(paths, " ")), result); err != nil {
-		if ctx.Debug {
-			fmt.Printf("  Warning: failed to record operation: %v\n", err)
-		}
+	if err := ctx.recordRepoOperation(repoDir, "add", fmt.Sprintf("git add %s", strings.Join(paths, " ")), result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
 	}
 
 	if result.Error != nil {
@@ -154,25 +266,19 @@ func (ctx *Context) Add(repoDir string, paths ...string) error {
 		return fmt.Errorf("git add failed (exit %d): %s", result.ExitCode, result.Stderr)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Added in %dms\n", result.DurationMs)
-	}
+	log.Debugf("  %s Added in %dms\n", term.OK(), result.DurationMs)
 
 	return nil
 }
 
 // Commit creates a commit
 func (ctx *Context) Commit(repoDir, message string) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Committing: %s\n", ctx.StepNumber, message)
-	}
+	log.Debugf("[Step %d] Committing: %s\n", ctx.StepNumber, message)
 
-	result := timing.Run("git", []string{"-C", repoDir, "commit", "-m", message}, nil)
+	result := ctx.run("git", []string{"-C", repoDir, "commit", "-m", message}, nil)
 
-	if err := ctx.recordOperation("commit", "git commit", result); err != nil {
-		if ctx.Debug {
-			fmt.Printf("  Warning: failed to record operation: %v\n", err)
-		}
+	if err := ctx.recordRepoOperation(repoDir, "commit", "git commit", result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
 	}
 
 	if result.Error != nil {
@@ -183,25 +289,21 @@ func (ctx *Context) Commit(repoDir, message string) error {
 		return fmt.Errorf("git commit failed (exit %d): %s", result.ExitCode, result.Stderr)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Committed in %dms\n", result.DurationMs)
-	}
+	log.Debugf("  %s Committed in %dms\n", term.OK(), result.DurationMs)
 
 	return nil
 }
 
 // Push pushes commits to remote
 func (ctx *Context) Push(repoDir, remote, branch string) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Pushing to %s/%s\n", ctx.StepNumber, remote, branch)
-	}
+	log.Debugf("[Step %d] Pushing to %s/%s\n", ctx.StepNumber, remote, branch)
 
-	result := timing.Run("git", []string{"-C", repoDir, "push", remote, branch}, nil)
+	opts, done := transferOptions("Pushing " + filepath.Base(repoDir))
+	result := ctx.run("git", []string{"-C", repoDir, "push", "--progress", remote, branch}, opts)
+	adapter := done()
 
-	if err := ctx.recordOperation("push", fmt.Sprintf("git push %s %s", remote, branch), result); err != nil {
-		if ctx.Debug {
-			fmt.Printf("  Warning: failed to record operation: %v\n", err)
-		}
+	if err := ctx.recordTransferOperation(repoDir, "push", fmt.Sprintf("git push %s %s", remote, branch), adapter, result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
 	}
 
 	if result.Error != nil {
@@ -209,28 +311,27 @@ func (ctx *Context) Push(repoDir, remote, branch string) error {
 	}
 
 	if result.ExitCode != 0 {
+		if quotaErr := githost.NewQuotaError("push", result.Stderr); quotaErr != nil {
+			return fmt.Errorf("git push failed: %w", quotaErr)
+		}
 		return fmt.Errorf("git push failed (exit %d): %s", result.ExitCode, result.Stderr)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Pushed in %dms\n", result.DurationMs)
-	}
+	log.Debugf("  %s Pushed in %dms\n", term.OK(), result.DurationMs)
 
 	return nil
 }
 
 // Pull pulls commits from remote
 func (ctx *Context) Pull(repoDir string) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Pulling changes\n", ctx.StepNumber)
-	}
+	log.Debugf("[Step %d] Pulling changes\n", ctx.StepNumber)
 
-	result := timing.Run("git", []string{"-C", repoDir, "pull"}, nil)
+	opts, done := transferOptions("Pulling " + filepath.Base(repoDir))
+	result := ctx.run("git", []string{"-C", repoDir, "pull", "--progress"}, opts)
+	adapter := done()
 
-	if err := ctx.recordOperation("pull", "git pull", result); err != nil {
-		if ctx.Debug {
-			fmt.Printf("  Warning: failed to record operation: %v\n", err)
-		}
+	if err := ctx.recordTransferOperation(repoDir, "pull", "git pull", adapter, result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
 	}
 
 	if result.Error != nil {
@@ -238,46 +339,41 @@ func (ctx *Context) Pull(repoDir string) error {
 	}
 
 	if result.ExitCode != 0 {
+		if quotaErr := githost.NewQuotaError("pull", result.Stderr); quotaErr != nil {
+			return fmt.Errorf("git pull failed: %w", quotaErr)
+		}
 		return fmt.Errorf("git pull failed (exit %d): %s", result.ExitCode, result.Stderr)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Pulled in %dms\n", result.DurationMs)
-	}
+	log.Debugf("  %s Pulled in %dms\n", term.OK(), result.DurationMs)
 
 	return nil
 }
 
 // ConfigUser sets git user configuration for a repository
 func (ctx *Context) ConfigUser(repoDir, name, email string) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Configuring git user: %s <%s>\n", ctx.StepNumber, name, email)
-	}
+	log.Debugf("[Step %d] Configuring git user: %s <%s>\n", ctx.StepNumber, name, email)
 
 	// Set user.name
-	result1 := timing.Run("git", []string{"-C", repoDir, "config", "user.name", name}, nil)
+	result1 := ctx.run("git", []string{"-C", repoDir, "config", "user.name", name}, nil)
 	if result1.Error != nil || result1.ExitCode != 0 {
 		return fmt.Errorf("failed to set user.name: %v", result1.Error)
 	}
 
 	// Set user.email
-	result2 := timing.Run("git", []string{"-C", repoDir, "config", "user.email", email}, nil)
+	result2 := ctx.run("git", []string{"-C", repoDir, "config", "user.email", email}, nil)
 	if result2.Error != nil || result2.ExitCode != 0 {
 		return fmt.Errorf("failed to set user.email: %v", result2.Error)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Configured user\n")
-	}
+	log.Debugf("  %s Configured user\n", term.OK())
 
 	return nil
 }
 
 // ConfigureLFSURL sets the LFS server URL in .lfsconfig
 func (ctx *Context) ConfigureLFSURL(repoDir, url string) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Configuring LFS URL: %s\n", ctx.StepNumber, url)
-	}
+	log.Debugf("[Step %d] Configuring LFS URL: %s\n", ctx.StepNumber, url)
 
 	lfsConfigPath := filepath.Join(repoDir, ".lfsconfig")
 	content := fmt.Sprintf("[lfs]\n\turl = %s\n", url)
@@ -286,78 +382,53 @@ func (ctx *Context) ConfigureLFSURL(repoDir, url string) error {
 		return fmt.Errorf("failed to write .lfsconfig: %w", err)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Created .lfsconfig\n")
-	}
+	log.Debugf("  %s Created .lfsconfig\n", term.OK())
 
 	return nil
 }
 
-// CreateGitHubRepo creates a private GitHub repository using gh CLI
-// Returns the clone URL for the created repository
-func (ctx *Context) CreateGitHubRepo(repoName string, force bool) (string, error) {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Creating GitHub repository: %s\n", ctx.StepNumber, repoName)
+// Record stores a timed command that ran outside this package as an operation of the current step
+func (ctx *Context) Record(opType, command string, result *timing.Result) {
+	if err := ctx.recordOperation(opType, command, result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
 	}
+}
 
-	// Check if gh CLI is available
-	checkResult := timing.Run("gh", []string{"--version"}, nil)
-	if checkResult.Error != nil || checkResult.ExitCode != 0 {
-		return "", fmt.Errorf("gh CLI not available - install with: sudo apt install gh")
-	}
+// CreateHostedRepo creates a private repository on a git host
+// Returns the clone URL for the created repository
+func (ctx *Context) CreateHostedRepo(host githost.GitHost, repoName string, force bool) (string, error) {
+	log.Debugf("[Step %d] Creating %s repository: %s\n", ctx.StepNumber, host.Name(), repoName)
 
 	// Delete existing repo if force flag is set
 	if force {
-		if ctx.Debug {
-			fmt.Printf("  Checking if repo already exists...\n")
-		}
-		deleteResult := timing.Run("gh", []string{"repo", "delete", repoName, "--yes"}, nil)
-		if deleteResult.ExitCode == 0 && ctx.Debug {
-			fmt.Printf("  ✓ Deleted existing repository\n")
+		log.Debugf("  Checking if repo already exists...\n")
+		if exists, err := host.RepoExists(repoName); err == nil && exists {
+			if err := host.DeleteRepo(repoName); err != nil {
+				return "", fmt.Errorf("failed to delete existing repository: %w", err)
+			}
+			log.Debugf("  %s Deleted existing repository\n", term.OK())
 		}
 	}
 
-	// Create private repository
-	args := []string{"repo", "create", repoName, "--private"}
-	result := timing.Run("gh", args, nil)
-
-	if err := ctx.recordOperation("gh-create-repo", fmt.Sprintf("gh repo create %s", repoName), result); err != nil {
-		if ctx.Debug {
-			fmt.Printf("  Warning: failed to record operation: %v\n", err)
-		}
+	cloneURL, err := host.CreateRepo(repoName)
+	if err != nil {
+		return "", err
 	}
 
-	if result.Error != nil {
-		return "", fmt.Errorf("gh repo create failed: %w", result.Error)
-	}
-
-	if result.ExitCode != 0 {
-		return "", fmt.Errorf("gh repo create failed (exit %d): %s", result.ExitCode, result.Stderr)
-	}
-
-	// Get the clone URL
-	cloneURL := fmt.Sprintf("https://github.com/%s.git", repoName)
-
-	if ctx.Debug {
-		fmt.Printf("  ✓ Created GitHub repository in %dms\n", result.DurationMs)
-		fmt.Printf("  Clone URL: %s\n", cloneURL)
-	}
+	log.Debugf("  %s Created %s repository\n", term.OK(), host.Name())
+	log.Debugf("  Clone URL: %s\n", cloneURL)
 
 	return cloneURL, nil
 }
 
 // AddRemote adds a git remote to a repository
 func (ctx *Context) AddRemote(repoDir, remoteName, url string) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Adding remote '%s': %s\n", ctx.StepNumber, remoteName, url)
-	}
+	log.Debugf("[Step %d] Adding remote '%s': %s\n", ctx.StepNumber, remoteName, url)
 
-	result := timing.Run("git", []string{"-C", repoDir, "remote", "add", remoteName, url}, nil)
+	result := ctx.run("git", []string{"-C", repoDir, "remote", "add", remoteName, url}, nil)
 
-	if err := ctx.recordOperation("add-remote", fmt.Sprintf("git remote add %s", remoteName), result); err != nil {
-		if ctx.Debug {
-			fmt.Printf("  Warning: failed to record operation: %v\n", err)
-		}
+	if err := ctx.recordRepoOperation(repoDir, "add-remote", fmt.Sprintf("git remote add %s", remoteName), result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
 	}
 
 	if result.Error != nil {
@@ -368,25 +439,19 @@ func (ctx *Context) AddRemote(repoDir, remoteName, url string) error {
 		return fmt.Errorf("git remote add failed (exit %d): %s", result.ExitCode, result.Stderr)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Added remote in %dms\n", result.DurationMs)
-	}
+	log.Debugf("  %s Added remote in %dms\n", term.OK(), result.DurationMs)
 
 	return nil
 }
 
 // LFSInstall installs git-lfs hooks in a repository
 func (ctx *Context) LFSInstall(repoDir string) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Installing git-lfs hooks\n", ctx.StepNumber)
-	}
+	log.Debugf("[Step %d] Installing git-lfs hooks\n", ctx.StepNumber)
 
-	result := timing.Run("git", []string{"-C", repoDir, "lfs", "install"}, nil)
+	result := ctx.run("git", []string{"-C", repoDir, "lfs", "install"}, nil)
 
-	if err := ctx.recordOperation("lfs-install", "git lfs install", result); err != nil {
-		if ctx.Debug {
-			fmt.Printf("  Warning: failed to record operation: %v\n", err)
-		}
+	if err := ctx.recordRepoOperation(repoDir, "lfs-install", "git lfs install", result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
 	}
 
 	if result.Error != nil {
@@ -397,25 +462,19 @@ func (ctx *Context) LFSInstall(repoDir string) error {
 		return fmt.Errorf("git lfs install failed (exit %d): %s", result.ExitCode, result.Stderr)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Installed git-lfs in %dms\n", result.DurationMs)
-	}
+	log.Debugf("  %s Installed git-lfs in %dms\n", term.OK(), result.DurationMs)
 
 	return nil
 }
 
 // LFSTrack adds a pattern to git-lfs tracking
 func (ctx *Context) LFSTrack(repoDir, pattern string) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Tracking pattern with git-lfs: %s\n", ctx.StepNumber, pattern)
-	}
+	log.Debugf("[Step %d] Tracking pattern with git-lfs: %s\n", ctx.StepNumber, pattern)
 
-	result := timing.Run("git", []string{"-C", repoDir, "lfs", "track", pattern}, nil)
+	result := ctx.run("git", []string{"-C", repoDir, "lfs", "track", pattern}, nil)
 
-	if err := ctx.recordOperation("lfs-track", fmt.Sprintf("git lfs track %s", pattern), result); err != nil {
-		if ctx.Debug {
-			fmt.Printf("  Warning: failed to record operation: %v\n", err)
-		}
+	if err := ctx.recordRepoOperation(repoDir, "lfs-track", fmt.Sprintf("git lfs track %s", pattern), result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
 	}
 
 	if result.Error != nil {
@@ -426,25 +485,19 @@ func (ctx *Context) LFSTrack(repoDir, pattern string) error {
 		return fmt.Errorf("git lfs track failed (exit %d): %s", result.ExitCode, result.Stderr)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Tracked %s in %dms\n", pattern, result.DurationMs)
-	}
+	log.Debugf("  %s Tracked %s in %dms\n", term.OK(), pattern, result.DurationMs)
 
 	return nil
 }
 
 // LFSUntrack removes a pattern from git-lfs tracking
 func (ctx *Context) LFSUntrack(repoDir, pattern string) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Untracking pattern from git-lfs: %s\n", ctx.StepNumber, pattern)
-	}
+	log.Debugf("[Step %d] Untracking pattern from git-lfs: %s\n", ctx.StepNumber, pattern)
 
-	result := timing.Run("git", []string{"-C", repoDir, "lfs", "untrack", pattern}, nil)
+	result := ctx.run("git", []string{"-C", repoDir, "lfs", "untrack", pattern}, nil)
 
-	if err := ctx.recordOperation("lfs-untrack", fmt.Sprintf("git lfs untrack %s", pattern), result); err != nil {
-		if ctx.Debug {
-			fmt.Printf("  Warning: failed to record operation: %v\n", err)
-		}
+	if err := ctx.recordRepoOperation(repoDir, "lfs-untrack", fmt.Sprintf("git lfs untrack %s", pattern), result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
 	}
 
 	if result.Error != nil {
@@ -455,26 +508,20 @@ func (ctx *Context) LFSUntrack(repoDir, pattern string) error {
 		return fmt.Errorf("git lfs untrack failed (exit %d): %s", result.ExitCode, result.Stderr)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Untracked %s in %dms\n", pattern, result.DurationMs)
-	}
+	log.Debugf("  %s Untracked %s in %dms\n", term.OK(), pattern, result.DurationMs)
 
 	return nil
 }
 
 // LFSMigrate migrates files out of LFS back to regular git
 func (ctx *Context) LFSMigrate(repoDir string) error {
-	if ctx.Debug {
-		fmt.Printf("[Step %d] Migrating files out of LFS\n", ctx.StepNumber)
-	}
+	log.Debugf("[Step %d] Migrating files out of LFS\n", ctx.StepNumber)
 
 	// Use git lfs migrate export to move files out of LFS
-	result := timing.Run("git", []string{"-C", repoDir, "lfs", "migrate", "export", "--include=*", "--everything"}, nil)
+	result := ctx.run("git", []string{"-C", repoDir, "lfs", "migrate", "export", "--include=*", "--everything"}, nil)
 
-	if err := ctx.recordOperation("lfs-migrate", "git lfs migrate export", result); err != nil {
-		if ctx.Debug {
-			fmt.Printf("  Warning: failed to record operation: %v\n", err)
-		}
+	if err := ctx.recordRepoOperation(repoDir, "lfs-migrate", "git lfs migrate export", result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
 	}
 
 	if result.Error != nil || result.ExitCode != 0 {
@@ -488,9 +535,115 @@ func (ctx *Context) LFSMigrate(repoDir string) error {
 		return fmt.Errorf("git lfs migrate export failed (exit %d): %s", result.ExitCode, stderr)
 	}
 
-	if ctx.Debug {
-		fmt.Printf("  ✓ Migrated files in %dms\n", result.DurationMs)
+	log.Debugf("  %s Migrated files in %dms\n", term.OK(), result.DurationMs)
+
+	return nil
+}
+
+// GC runs git gc in a repository
+func (ctx *Context) GC(repoDir string) error {
+	log.Debugf("[Step %d] Running git gc\n", ctx.StepNumber)
+
+	result := ctx.run("git", []string{"-C", repoDir, "gc", "--quiet"}, nil)
+
+	if err := ctx.recordRepoOperation(repoDir, "gc", "git gc", result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
+	}
+
+	if result.Error != nil {
+		return fmt.Errorf("git gc failed: %w", result.Error)
+	}
+
+	if result.ExitCode != 0 {
+		return fmt.Errorf("git gc failed (exit %d): %s", result.ExitCode, result.Stderr)
+	}
+
+	log.Debugf("  %s Collected garbage in %dms\n", term.OK(), result.DurationMs)
+
+	return nil
+}
+
+// LFSPrune deletes local LFS objects that are no longer referenced
+func (ctx *Context) LFSPrune(repoDir string) error {
+	log.Debugf("[Step %d] Pruning LFS objects\n", ctx.StepNumber)
+
+	result := ctx.run("git", []string{"-C", repoDir, "lfs", "prune"}, nil)
+
+	if err := ctx.recordRepoOperation(repoDir, "lfs-prune", "git lfs prune", result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
+	}
+
+	if result.Error != nil {
+		return fmt.Errorf("git lfs prune failed: %w", result.Error)
+	}
+
+	if result.ExitCode != 0 {
+		return fmt.Errorf("git lfs prune failed (exit %d): %s", result.ExitCode, result.Stderr)
+	}
+
+	log.Debugf("  %s Pruned LFS objects in %dms\n", term.OK(), result.DurationMs)
+
+	return nil
+}
+
+// LFSPush uploads the LFS objects of branch that remote lacks, without pushing any commits,
+// and stops the upload after interruptAfter if it is still running; 0 lets it finish.
+// It reports whether the upload was interrupted, which is not an error.
+func (ctx *Context) LFSPush(repoDir, remote, branch string, interruptAfter time.Duration) (bool, error) {
+	log.Debugf("[Step %d] Pushing LFS objects to %s/%s\n", ctx.StepNumber, remote, branch)
+
+	// git-lfs runs directly rather than under git, so stopping it stops the upload itself
+	opts, done := transferOptions("Uploading " + filepath.Base(repoDir))
+	opts.Dir = repoDir
+	opts.Timeout = interruptAfter
+	result := ctx.run("git-lfs", []string{"push", remote, branch}, opts)
+	adapter := done()
+
+	interrupted := interruptAfter > 0 && !result.Success() && result.DurationMs >= interruptAfter.Milliseconds()
+	opType := "lfs-push"
+	if interrupted {
+		opType = "lfs-push-interrupted"
+	}
+	if err := ctx.recordTransferOperation(repoDir, opType, fmt.Sprintf("git lfs push %s %s", remote, branch), adapter, result); err != nil {
+		log.Warnf("failed to record operation: %v\n", err)
+	}
+
+	if interrupted {
+		log.Debugf("  %s Interrupted the upload after %dms\n", term.OK(), result.DurationMs)
+		return true, nil
+	}
+	if result.Error != nil {
+		return false, fmt.Errorf("git lfs push failed: %w", result.Error)
+	}
+	if result.ExitCode != 0 {
+		return false, fmt.Errorf("git lfs push failed (exit %d): %s", result.ExitCode, result.Stderr)
+	}
+
+	log.Debugf("  %s Pushed LFS objects in %dms\n", term.OK(), result.DurationMs)
+
+	return false, nil
+}
+
+// CurrentBranch returns the branch checked out in a repository
+func (ctx *Context) CurrentBranch(repoDir string) (string, error) {
+	result := ctx.run("git", []string{"-C", repoDir, "symbolic-ref", "--short", "HEAD"}, nil)
+	if result.Error != nil {
+		return "", fmt.Errorf("git symbolic-ref failed: %w", result.Error)
 	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("failed to get current branch (exit %d): %s", result.ExitCode, result.Stderr)
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
 
+// SetHead points HEAD of a (typically bare) repository at branch, so clones check it out
+func (ctx *Context) SetHead(repoDir, branch string) error {
+	result := ctx.run("git", []string{"-C", repoDir, "symbolic-ref", "HEAD", "refs/heads/" + branch}, nil)
+	if result.Error != nil {
+		return fmt.Errorf("git symbolic-ref failed: %w", result.Error)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to set HEAD to %s (exit %d): %s", branch, result.ExitCode, result.Stderr)
+	}
 	return nil
 }
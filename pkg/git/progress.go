@@ -0,0 +1,29 @@
+package git
+
+import (
+	"github.com/mslinn/git-lfs-test/pkg/progress"
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// transferOptions returns the options of a git command that transfers data, showing its
+// progress as label when progress is shown, and the function to call when it has finished,
+// which returns the transfer adapters the command used (see traceAdapters)
+func transferOptions(label string) (*timing.Options, func() string) {
+	opts := &timing.Options{}
+	trace, adapters := traceAdapters()
+	if trace != "" {
+		opts.Env = append(opts.Env, trace)
+	}
+
+	bar := progress.Start(label, 0)
+	if bar == nil {
+		return opts, adapters
+	}
+	// git-lfs reports its progress only to a terminal unless forced
+	opts.Progress = bar.Output()
+	opts.Env = append(opts.Env, "GIT_LFS_FORCE_PROGRESS=1")
+	return opts, func() string {
+		bar.Finish()
+		return adapters()
+	}
+}
@@ -0,0 +1,187 @@
+package githost
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// azureAPIVersion is the Azure DevOps REST API version requested
+const azureAPIVersion = "api-version=7.1"
+
+// AzureDevOps manages repositories in an Azure DevOps project through its REST API.
+// Azure Repos serves LFS itself at <clone URL>/info/lfs, which git-lfs finds from the remote.
+type AzureDevOps struct {
+	URL      string // Project URL, e.g. "https://dev.azure.com/ORG/PROJECT"
+	Token    string // Personal access token with the Code (read, write & manage) scope
+	Recorder Recorder
+	Client   *http.Client // nil uses a client with a 30 second timeout
+}
+
+// NewAzureDevOps creates an Azure DevOps host that reports API calls to recorder
+func NewAzureDevOps(projectURL, token string, recorder Recorder) *AzureDevOps {
+	return &AzureDevOps{URL: projectURL, Token: token, Recorder: recorder}
+}
+
+// Name returns "azure-devops"
+func (a *AzureDevOps) Name() string {
+	return "azure-devops"
+}
+
+// baseURL returns the project URL without a trailing slash
+func (a *AzureDevOps) baseURL() string {
+	return strings.TrimRight(a.URL, "/")
+}
+
+// request calls the project's git API at path, records it under opType, and decodes a
+// successful JSON response into out. It returns the HTTP status; only transport failures
+// and missing settings are errors.
+func (a *AzureDevOps) request(opType, method, path string, body, out any) (int, error) {
+	if a.URL == "" {
+		return 0, fmt.Errorf("Azure DevOps project not configured - set it with: lfst-config set azure_devops_url https://dev.azure.com/ORG/PROJECT")
+	}
+	if a.Token == "" {
+		return 0, fmt.Errorf("Azure DevOps token not configured - set it with: lfst-config set azure_devops_token TOKEN")
+	}
+
+	path = "/_apis/git/repositories" + path
+	return doAPI(a.Client, a.Recorder, apiCall{
+		Service: "Azure DevOps",
+		OpType:  opType,
+		Method:  method,
+		URL:     a.baseURL() + path + "?" + azureAPIVersion,
+		Path:    path,
+		Auth:    basicAuth("", a.Token),
+		Body:    body,
+		Out:     out,
+	})
+}
+
+// azureRepo is the part of a repository the API returns that AzureDevOps uses
+type azureRepo struct {
+	ID        string `json:"id"`
+	RemoteURL string `json:"remoteUrl"`
+	Size      int64  `json:"size"`
+}
+
+// lookup returns a repository, or nil if it does not exist
+func (a *AzureDevOps) lookup(name string) (*azureRepo, error) {
+	var repo azureRepo
+	status, err := a.request("", http.MethodGet, "/"+url.PathEscape(name), nil, &repo)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case http.StatusOK:
+		return &repo, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, apiError("Azure DevOps", "look up repository "+name, status, "")
+	}
+}
+
+// CreateRepo creates a repository in the project. Azure Repos are as private as their
+// project, so a repository cannot be made private on its own.
+func (a *AzureDevOps) CreateRepo(name string) (string, error) {
+	var created azureRepo
+	status, err := a.request("azure-create-repo", http.MethodPost, "", map[string]any{"name": name}, &created)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusCreated {
+		return "", apiError("Azure DevOps", "create repository "+name, status, conflictHint(status))
+	}
+	return a.CloneURL(name)
+}
+
+// DeleteRepo deletes a repository, which the API addresses by ID
+func (a *AzureDevOps) DeleteRepo(name string) error {
+	repo, err := a.lookup(name)
+	if err != nil {
+		return err
+	}
+	if repo == nil {
+		return fmt.Errorf("failed to delete repository %s: it does not exist", name)
+	}
+
+	status, err := a.request("azure-delete-repo", http.MethodDelete, "/"+url.PathEscape(repo.ID), nil, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNoContent {
+		return apiError("Azure DevOps", "delete repository "+name, status, "")
+	}
+	return nil
+}
+
+// RepoExists reports whether a repository exists
+func (a *AzureDevOps) RepoExists(name string) (bool, error) {
+	repo, err := a.lookup(name)
+	return repo != nil, err
+}
+
+// CloneURL returns the HTTPS clone URL of a repository
+func (a *AzureDevOps) CloneURL(name string) (string, error) {
+	if a.URL == "" {
+		return "", fmt.Errorf("Azure DevOps project not configured - set it with: lfst-config set azure_devops_url https://dev.azure.com/ORG/PROJECT")
+	}
+	return a.baseURL() + "/_git/" + url.PathEscape(name), nil
+}
+
+// LFSURL returns the LFS endpoint Azure Repos serves for a repository
+func (a *AzureDevOps) LFSURL(name string) (string, error) {
+	cloneURL, err := a.CloneURL(name)
+	if err != nil {
+		return "", err
+	}
+	return cloneURL + "/info/lfs", nil
+}
+
+// QuotaInfo returns the combined size of the project's repositories. Azure Repos has
+// no storage quota, so LimitBytes is always zero.
+func (a *AzureDevOps) QuotaInfo() (*Quota, error) {
+	var repos struct {
+		Value []azureRepo `json:"value"`
+	}
+	status, err := a.request("azure-quota", http.MethodGet, "", nil, &repos)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, apiError("Azure DevOps", "list repositories", status, "")
+	}
+
+	quota := &Quota{}
+	for _, repo := range repos.Value {
+		quota.UsedBytes += repo.Size
+	}
+	return quota, nil
+}
+
+// organizationURL returns the URL of the project's organization: https://dev.azure.com/ORG,
+// or https://ORG.visualstudio.com for organizations with an old-style URL
+func (a *AzureDevOps) organizationURL() string {
+	u, err := url.Parse(a.baseURL())
+	if err != nil || u.Host == "" {
+		return a.baseURL()
+	}
+	org := ""
+	if u.Host == "dev.azure.com" {
+		org, _, _ = strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+		org = "/" + org
+	}
+	return u.Scheme + "://" + u.Host + org
+}
+
+// GitConfig sends the token with every git and git-lfs request to the organization,
+// whose LFS object URLs lie outside the project
+func (a *AzureDevOps) GitConfig() map[string]string {
+	if a.URL == "" || a.Token == "" {
+		return nil
+	}
+	return map[string]string{
+		"http." + a.organizationURL() + "/.extraHeader": "Authorization: " + basicAuth("", a.Token),
+	}
+}
@@ -0,0 +1,151 @@
+package githost
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// newAzureServer starts a fake Azure DevOps API for project lab/proj that stores repositories by ID
+func newAzureServer(t *testing.T) (*httptest.Server, map[string]string) {
+	repos := make(map[string]string) // ID -> name
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /lab/proj/_apis/git/repositories", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, name := range repos {
+			if name == req.Name {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+		}
+		repos["id-"+req.Name] = req.Name
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"id": "id-" + req.Name})
+	})
+	mux.HandleFunc("GET /lab/proj/_apis/git/repositories/{repo}", func(w http.ResponseWriter, r *http.Request) {
+		for id, name := range repos {
+			if name == r.PathValue("repo") {
+				json.NewEncoder(w).Encode(map[string]any{"id": id, "size": 1})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("DELETE /lab/proj/_apis/git/repositories/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := repos[r.PathValue("id")]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(repos, r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /lab/proj/_apis/git/repositories", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"count": 2, "value": [{"size": 2}, {"size": 3}]}`))
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != basicAuth("", "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("api-version") == "" {
+			t.Errorf("%s %s has no api-version", r.Method, r.URL.Path)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, repos
+}
+
+func TestAzureRepoLifecycle(t *testing.T) {
+	server, repos := newAzureServer(t)
+
+	var ops []string
+	a := NewAzureDevOps(server.URL+"/lab/proj/", "secret", func(opType, command string, result *timing.Result) {
+		ops = append(ops, opType)
+	})
+
+	url, err := a.CreateRepo("lfs-eval-azure")
+	if err != nil {
+		t.Fatalf("CreateRepo failed: %v", err)
+	}
+	if url != server.URL+"/lab/proj/_git/lfs-eval-azure" {
+		t.Errorf("CreateRepo URL = %s", url)
+	}
+	if repos["id-lfs-eval-azure"] != "lfs-eval-azure" {
+		t.Error("CreateRepo should create the repository")
+	}
+
+	_, err = a.CreateRepo("lfs-eval-azure")
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("CreateRepo of an existing repository: err = %v", err)
+	}
+
+	if exists, err := a.RepoExists("lfs-eval-azure"); err != nil || !exists {
+		t.Errorf("RepoExists = %v, %v; want true", exists, err)
+	}
+	if err := a.DeleteRepo("lfs-eval-azure"); err != nil {
+		t.Fatalf("DeleteRepo failed: %v", err)
+	}
+	if exists, err := a.RepoExists("lfs-eval-azure"); err != nil || exists {
+		t.Errorf("RepoExists after delete = %v, %v; want false", exists, err)
+	}
+	if err := a.DeleteRepo("lfs-eval-azure"); err == nil {
+		t.Error("DeleteRepo of a missing repository should fail")
+	}
+
+	want := []string{"azure-create-repo", "azure-create-repo", "azure-delete-repo"}
+	if strings.Join(ops, ",") != strings.Join(want, ",") {
+		t.Errorf("recorded operations = %v, want %v", ops, want)
+	}
+}
+
+func TestAzureQuotaInfo(t *testing.T) {
+	server, _ := newAzureServer(t)
+	a := NewAzureDevOps(server.URL+"/lab/proj", "secret", nil)
+
+	quota, err := a.QuotaInfo()
+	if err != nil {
+		t.Fatalf("QuotaInfo failed: %v", err)
+	}
+	if quota.UsedBytes != 5 || quota.LimitBytes != 0 {
+		t.Errorf("QuotaInfo = %+v, want 5 bytes used and no limit", quota)
+	}
+}
+
+func TestAzureURLs(t *testing.T) {
+	a := NewAzureDevOps("https://dev.azure.com/lab/proj/", "secret", nil)
+
+	lfs, _ := a.LFSURL("lfs-eval-azure")
+	if lfs != "https://dev.azure.com/lab/proj/_git/lfs-eval-azure/info/lfs" {
+		t.Errorf("LFSURL = %s", lfs)
+	}
+
+	// The header covers the whole organization, where LFS objects are stored
+	header := "Authorization: " + basicAuth("", "secret")
+	if config := a.GitConfig(); config["http.https://dev.azure.com/lab/.extraHeader"] != header {
+		t.Errorf("GitConfig = %v", config)
+	}
+	a.URL = "https://lab.visualstudio.com/proj"
+	if config := a.GitConfig(); config["http.https://lab.visualstudio.com/.extraHeader"] != header {
+		t.Errorf("GitConfig of an old-style URL = %v", config)
+	}
+}
+
+func TestAzureNotConfigured(t *testing.T) {
+	a := NewAzureDevOps("", "", nil)
+	if _, err := a.CreateRepo("repo"); err == nil || !strings.Contains(err.Error(), "azure_devops_url") {
+		t.Errorf("CreateRepo without a URL: err = %v", err)
+	}
+	if a.GitConfig() != nil {
+		t.Error("GitConfig should be empty without a URL and token")
+	}
+}
@@ -0,0 +1,220 @@
+package githost
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Bitbucket Cloud addresses, which tests replace with a fake server
+const (
+	BitbucketAPIURL = "https://api.bitbucket.org/2.0"
+	BitbucketURL    = "https://bitbucket.org"
+)
+
+// bitbucketGitUser is the user name git sends with a Bitbucket API token
+const bitbucketGitUser = "x-bitbucket-api-token-auth"
+
+// Bitbucket manages repositories in a Bitbucket Cloud workspace through its REST API.
+// Bitbucket serves LFS itself at <clone URL>/info/lfs, which git-lfs finds from the remote.
+type Bitbucket struct {
+	User      string // Atlassian account email the API token belongs to
+	Token     string // API token with repository read, write and delete scopes
+	Workspace string // Workspace that owns unqualified repository names
+	Recorder  Recorder
+	Client    *http.Client // nil uses a client with a 30 second timeout
+	APIURL    string       // Empty means BitbucketAPIURL
+	WebURL    string       // Empty means BitbucketURL
+}
+
+// NewBitbucket creates a Bitbucket host that reports API calls to recorder
+func NewBitbucket(user, token, workspace string, recorder Recorder) *Bitbucket {
+	return &Bitbucket{User: user, Token: token, Workspace: workspace, Recorder: recorder}
+}
+
+// Name returns "bitbucket"
+func (b *Bitbucket) Name() string {
+	return "bitbucket"
+}
+
+// apiURL returns the API URL without a trailing slash
+func (b *Bitbucket) apiURL() string {
+	if b.APIURL == "" {
+		return BitbucketAPIURL
+	}
+	return strings.TrimRight(b.APIURL, "/")
+}
+
+// webURL returns the URL repositories are cloned from, without a trailing slash
+func (b *Bitbucket) webURL() string {
+	if b.WebURL == "" {
+		return BitbucketURL
+	}
+	return strings.TrimRight(b.WebURL, "/")
+}
+
+// request calls the API at path, which may carry a query, records it under opType, and
+// decodes a successful JSON response into out. It returns the HTTP status; only transport
+// failures and missing settings are errors.
+func (b *Bitbucket) request(opType, method, path string, body, out any) (int, error) {
+	if b.User == "" || b.Token == "" {
+		return 0, fmt.Errorf("Bitbucket credentials not configured - create an API token with repository scopes, " +
+			"then: lfst-config set bitbucket_user EMAIL; lfst-config set bitbucket_token TOKEN")
+	}
+
+	return doAPI(b.Client, b.Recorder, apiCall{
+		Service: "Bitbucket",
+		OpType:  opType,
+		Method:  method,
+		URL:     b.apiURL() + path,
+		Path:    path,
+		Auth:    basicAuth(b.User, b.Token),
+		Body:    body,
+		Out:     out,
+	})
+}
+
+// fullName qualifies a repository name with the workspace
+func (b *Bitbucket) fullName(name string) (string, error) {
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+	if b.Workspace == "" {
+		return "", fmt.Errorf("Bitbucket workspace not configured - set it with: lfst-config set bitbucket_workspace WORKSPACE")
+	}
+	return b.Workspace + "/" + name, nil
+}
+
+// repoPath returns the API path of a repository. Bitbucket addresses repositories by
+// slug, which is the lowercase name.
+func (b *Bitbucket) repoPath(name string) (string, error) {
+	fullName, err := b.fullName(name)
+	if err != nil {
+		return "", err
+	}
+	workspace, slug, _ := strings.Cut(fullName, "/")
+	return "/repositories/" + url.PathEscape(workspace) + "/" + url.PathEscape(strings.ToLower(slug)), nil
+}
+
+// CreateRepo creates a private repository in the workspace
+func (b *Bitbucket) CreateRepo(name string) (string, error) {
+	path, err := b.repoPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	request := map[string]any{"scm": "git", "is_private": true}
+	status, err := b.request("bitbucket-create-repo", http.MethodPost, path, request, nil)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK && status != http.StatusCreated {
+		hint := ""
+		if status == http.StatusBadRequest {
+			// Bitbucket answers a name that is taken with a plain 400
+			hint = " (the repository may already exist; use --force to recreate it)"
+		}
+		return "", apiError("Bitbucket", "create repository "+name, status, hint)
+	}
+	return b.CloneURL(name)
+}
+
+// DeleteRepo deletes a repository
+func (b *Bitbucket) DeleteRepo(name string) error {
+	path, err := b.repoPath(name)
+	if err != nil {
+		return err
+	}
+
+	status, err := b.request("bitbucket-delete-repo", http.MethodDelete, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNoContent {
+		return apiError("Bitbucket", "delete repository "+name, status, "")
+	}
+	return nil
+}
+
+// RepoExists reports whether a repository exists
+func (b *Bitbucket) RepoExists(name string) (bool, error) {
+	path, err := b.repoPath(name)
+	if err != nil {
+		return false, err
+	}
+
+	status, err := b.request("", http.MethodGet, path, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	switch status {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, apiError("Bitbucket", "look up repository "+name, status, "")
+	}
+}
+
+// CloneURL returns the HTTPS clone URL of a repository
+func (b *Bitbucket) CloneURL(name string) (string, error) {
+	fullName, err := b.fullName(name)
+	if err != nil {
+		return "", err
+	}
+	workspace, slug, _ := strings.Cut(fullName, "/")
+	return fmt.Sprintf("%s/%s/%s.git", b.webURL(), workspace, strings.ToLower(slug)), nil
+}
+
+// LFSURL returns the LFS endpoint Bitbucket serves for a repository
+func (b *Bitbucket) LFSURL(name string) (string, error) {
+	cloneURL, err := b.CloneURL(name)
+	if err != nil {
+		return "", err
+	}
+	return cloneURL + "/info/lfs", nil
+}
+
+// QuotaInfo returns the combined size of the workspace's repositories. The API does not
+// report the plan's LFS storage limit, so LimitBytes is always zero.
+func (b *Bitbucket) QuotaInfo() (*Quota, error) {
+	if b.Workspace == "" {
+		return nil, fmt.Errorf("Bitbucket workspace not configured - set it with: lfst-config set bitbucket_workspace WORKSPACE")
+	}
+	quota := &Quota{}
+
+	// Bitbucket pages results and links to the next page
+	path := "/repositories/" + url.PathEscape(b.Workspace) + "?pagelen=100&fields=next,values.size"
+	for path != "" {
+		var page struct {
+			Next   string `json:"next"`
+			Values []struct {
+				Size int64 `json:"size"`
+			} `json:"values"`
+		}
+		status, err := b.request("bitbucket-quota", http.MethodGet, path, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		if status != http.StatusOK {
+			return nil, apiError("Bitbucket", "list repositories", status, "")
+		}
+		for _, repo := range page.Values {
+			quota.UsedBytes += repo.Size
+		}
+		path = strings.TrimPrefix(page.Next, b.apiURL())
+	}
+	return quota, nil
+}
+
+// GitConfig sends the API token with every git and git-lfs request to Bitbucket
+func (b *Bitbucket) GitConfig() map[string]string {
+	if b.Token == "" {
+		return nil
+	}
+	return map[string]string{
+		"http." + b.webURL() + "/.extraHeader": "Authorization: " + basicAuth(bitbucketGitUser, b.Token),
+	}
+}
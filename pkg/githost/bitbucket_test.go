@@ -0,0 +1,146 @@
+package githost
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// newBitbucketServer starts a fake Bitbucket API with workspace "lab" that stores repository slugs
+func newBitbucketServer(t *testing.T) (*httptest.Server, map[string]bool) {
+	repos := make(map[string]bool)
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /repositories/{workspace}/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			SCM       string `json:"scm"`
+			IsPrivate bool   `json:"is_private"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.SCM != "git" || !req.IsPrivate {
+			t.Errorf("create request = %+v, want a private git repository", req)
+		}
+		key := r.PathValue("workspace") + "/" + r.PathValue("slug")
+		if repos[key] {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		repos[key] = true
+		w.Write([]byte("{}"))
+	})
+	mux.HandleFunc("GET /repositories/{workspace}/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		if !repos[r.PathValue("workspace")+"/"+r.PathValue("slug")] {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	mux.HandleFunc("DELETE /repositories/{workspace}/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		delete(repos, r.PathValue("workspace")+"/"+r.PathValue("slug"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /repositories/{workspace}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`{"values": [{"size": 3}]}`))
+			return
+		}
+		next := "http://" + r.Host + "/repositories/lab?pagelen=100&page=2"
+		json.NewEncoder(w).Encode(map[string]any{"next": next, "values": []map[string]int{{"size": 1}, {"size": 2}}})
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != basicAuth("me@example.com", "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, repos
+}
+
+func TestBitbucketRepoLifecycle(t *testing.T) {
+	server, repos := newBitbucketServer(t)
+
+	var ops []string
+	b := NewBitbucket("me@example.com", "secret", "lab", func(opType, command string, result *timing.Result) {
+		ops = append(ops, opType)
+	})
+	b.APIURL = server.URL
+	b.WebURL = "https://bitbucket.example"
+
+	url, err := b.CreateRepo("LFS-Eval-Test")
+	if err != nil {
+		t.Fatalf("CreateRepo failed: %v", err)
+	}
+	if url != "https://bitbucket.example/lab/lfs-eval-test.git" {
+		t.Errorf("CreateRepo URL = %s", url)
+	}
+	if !repos["lab/lfs-eval-test"] {
+		t.Error("CreateRepo should create the repository under its lowercase slug")
+	}
+
+	_, err = b.CreateRepo("lfs-eval-test")
+	if err == nil || !strings.Contains(err.Error(), "already exist") {
+		t.Errorf("CreateRepo of an existing repository: err = %v", err)
+	}
+
+	if exists, err := b.RepoExists("lfs-eval-test"); err != nil || !exists {
+		t.Errorf("RepoExists = %v, %v; want true", exists, err)
+	}
+	if err := b.DeleteRepo("lab/lfs-eval-test"); err != nil {
+		t.Fatalf("DeleteRepo failed: %v", err)
+	}
+	if exists, err := b.RepoExists("lfs-eval-test"); err != nil || exists {
+		t.Errorf("RepoExists after delete = %v, %v; want false", exists, err)
+	}
+
+	want := []string{"bitbucket-create-repo", "bitbucket-create-repo", "bitbucket-delete-repo"}
+	if strings.Join(ops, ",") != strings.Join(want, ",") {
+		t.Errorf("recorded operations = %v, want %v", ops, want)
+	}
+}
+
+func TestBitbucketQuotaInfo(t *testing.T) {
+	server, _ := newBitbucketServer(t)
+	b := NewBitbucket("me@example.com", "secret", "lab", nil)
+	b.APIURL = server.URL
+
+	quota, err := b.QuotaInfo()
+	if err != nil {
+		t.Fatalf("QuotaInfo failed: %v", err)
+	}
+	if quota.UsedBytes != 6 || quota.LimitBytes != 0 {
+		t.Errorf("QuotaInfo = %+v, want 6 bytes used over both pages and no limit", quota)
+	}
+}
+
+func TestBitbucketURLs(t *testing.T) {
+	b := NewBitbucket("me@example.com", "secret", "lab", nil)
+
+	lfs, _ := b.LFSURL("other/Repo")
+	if lfs != "https://bitbucket.org/other/repo.git/info/lfs" {
+		t.Errorf("LFSURL = %s", lfs)
+	}
+
+	config := b.GitConfig()
+	want := "Authorization: " + basicAuth(bitbucketGitUser, "secret")
+	if config["http.https://bitbucket.org/.extraHeader"] != want {
+		t.Errorf("GitConfig = %v", config)
+	}
+}
+
+func TestBitbucketNotConfigured(t *testing.T) {
+	b := NewBitbucket("", "", "", nil)
+	if _, err := b.CreateRepo("lab/repo"); err == nil || !strings.Contains(err.Error(), "bitbucket_token") {
+		t.Errorf("CreateRepo without credentials: err = %v", err)
+	}
+	if _, err := b.CloneURL("repo"); err == nil || !strings.Contains(err.Error(), "bitbucket_workspace") {
+		t.Errorf("CloneURL without a workspace: err = %v", err)
+	}
+	if b.GitConfig() != nil {
+		t.Error("GitConfig should be empty without a token")
+	}
+}
@@ -0,0 +1,235 @@
+package githost
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Gitea manages repositories on a Gitea server through its REST API.
+// Gitea serves LFS itself at <clone URL>/info/lfs, which git-lfs finds from the remote.
+type Gitea struct {
+	URL      string // Base URL of the server, e.g. "http://gojira:3000"
+	Token    string // Access token with repository read/write scope
+	Owner    string // User or organization that owns unqualified repository names (token user if empty)
+	Recorder Recorder
+	Client   *http.Client // nil uses a client with a 30 second timeout
+
+	login string // Token user, looked up on first use
+}
+
+// NewGitea creates a Gitea host that reports API calls to recorder
+func NewGitea(baseURL, token string, recorder Recorder) *Gitea {
+	return &Gitea{URL: baseURL, Token: token, Recorder: recorder}
+}
+
+// Name returns "gitea"
+func (g *Gitea) Name() string {
+	return "gitea"
+}
+
+// baseURL returns the server URL without a trailing slash
+func (g *Gitea) baseURL() string {
+	return strings.TrimRight(g.URL, "/")
+}
+
+// request calls the API, records it under opType, and decodes a successful JSON response into out.
+// It returns the HTTP status; only transport failures and missing settings are errors.
+func (g *Gitea) request(opType, method, path string, body, out any) (int, error) {
+	if g.URL == "" {
+		return 0, fmt.Errorf("Gitea URL not configured - set it with: lfst-config set gitea_url http://HOST:3000")
+	}
+	if g.Token == "" {
+		return 0, fmt.Errorf("Gitea token not configured - set it with: lfst-config set gitea_token TOKEN")
+	}
+
+	return doAPI(g.Client, g.Recorder, apiCall{
+		Service: "Gitea",
+		OpType:  opType,
+		Method:  method,
+		URL:     g.baseURL() + "/api/v1" + path,
+		Path:    path,
+		Auth:    "token " + g.Token,
+		Body:    body,
+		Out:     out,
+	})
+}
+
+// user returns the login of the token's user
+func (g *Gitea) user() (string, error) {
+	if g.login == "" {
+		var user struct {
+			Login string `json:"login"`
+		}
+		status, err := g.request("", http.MethodGet, "/user", nil, &user)
+		if err != nil {
+			return "", err
+		}
+		if status != http.StatusOK {
+			return "", apiError("Gitea", "get Gitea user", status, "")
+		}
+		g.login = user.Login
+	}
+	return g.login, nil
+}
+
+// fullName qualifies a repository name with its owner
+func (g *Gitea) fullName(name string) (string, error) {
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+	if g.Owner == "" {
+		login, err := g.user()
+		if err != nil {
+			return "", err
+		}
+		g.Owner = login
+	}
+	return g.Owner + "/" + name, nil
+}
+
+// repoPath returns the API path of a repository
+func (g *Gitea) repoPath(name string) (string, error) {
+	fullName, err := g.fullName(name)
+	if err != nil {
+		return "", err
+	}
+	owner, repo, _ := strings.Cut(fullName, "/")
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo), nil
+}
+
+// CreateRepo creates a private repository, under an organization if the owner is not the token user
+func (g *Gitea) CreateRepo(name string) (string, error) {
+	fullName, err := g.fullName(name)
+	if err != nil {
+		return "", err
+	}
+	login, err := g.user()
+	if err != nil {
+		return "", err
+	}
+
+	owner, repo, _ := strings.Cut(fullName, "/")
+	path := "/user/repos"
+	if owner != login {
+		path = "/orgs/" + url.PathEscape(owner) + "/repos"
+	}
+
+	var created struct {
+		CloneURL string `json:"clone_url"`
+	}
+	request := map[string]any{"name": repo, "private": true}
+	status, err := g.request("gitea-create-repo", http.MethodPost, path, request, &created)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusCreated {
+		return "", apiError("Gitea", "create repository "+fullName, status, conflictHint(status))
+	}
+
+	if created.CloneURL != "" {
+		return created.CloneURL, nil
+	}
+	return g.CloneURL(fullName)
+}
+
+// conflictHint explains the status Gitea returns for a repository that already exists
+func conflictHint(status int) string {
+	if status == http.StatusConflict {
+		return " (repository already exists; use --force to recreate it)"
+	}
+	return ""
+}
+
+// DeleteRepo deletes a repository; the token needs admin rights on it
+func (g *Gitea) DeleteRepo(name string) error {
+	path, err := g.repoPath(name)
+	if err != nil {
+		return err
+	}
+
+	status, err := g.request("gitea-delete-repo", http.MethodDelete, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNoContent {
+		return apiError("Gitea", "delete repository "+name, status, "")
+	}
+	return nil
+}
+
+// RepoExists reports whether a repository exists
+func (g *Gitea) RepoExists(name string) (bool, error) {
+	path, err := g.repoPath(name)
+	if err != nil {
+		return false, err
+	}
+
+	status, err := g.request("", http.MethodGet, path, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	switch status {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, apiError("Gitea", "look up repository "+name, status, "")
+	}
+}
+
+// CloneURL returns the HTTP(S) clone URL of a repository
+func (g *Gitea) CloneURL(name string) (string, error) {
+	fullName, err := g.fullName(name)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s.git", g.baseURL(), fullName), nil
+}
+
+// LFSURL returns the LFS endpoint Gitea serves for a repository
+func (g *Gitea) LFSURL(name string) (string, error) {
+	cloneURL, err := g.CloneURL(name)
+	if err != nil {
+		return "", err
+	}
+	return cloneURL + "/info/lfs", nil
+}
+
+// QuotaInfo returns the combined size of the token user's repositories.
+// Gitea has no account quota, so LimitBytes is always zero.
+func (g *Gitea) QuotaInfo() (*Quota, error) {
+	quota := &Quota{Plan: "self-hosted"}
+
+	// Gitea reports sizes in kilobytes, a page at a time
+	for page := 1; ; page++ {
+		var repos []struct {
+			Size int64 `json:"size"`
+		}
+		status, err := g.request("gitea-quota", http.MethodGet, fmt.Sprintf("/user/repos?limit=50&page=%d", page), nil, &repos)
+		if err != nil {
+			return nil, err
+		}
+		if status != http.StatusOK {
+			return nil, apiError("Gitea", "list repositories", status, "")
+		}
+		if len(repos) == 0 {
+			return quota, nil
+		}
+		for _, repo := range repos {
+			quota.UsedBytes += repo.Size * 1024
+		}
+	}
+}
+
+// GitConfig sends the token with every git and git-lfs request to this server
+func (g *Gitea) GitConfig() map[string]string {
+	if g.URL == "" || g.Token == "" {
+		return nil
+	}
+	return map[string]string{
+		"http." + g.baseURL() + "/.extraHeader": "Authorization: token " + g.Token,
+	}
+}
@@ -0,0 +1,145 @@
+package githost
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// newGiteaServer starts a fake Gitea API for user "tester" that stores repository names
+func newGiteaServer(t *testing.T) (*httptest.Server, map[string]bool) {
+	repos := make(map[string]bool)
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/v1/user", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"login": "tester"})
+	})
+	mux.HandleFunc("POST /api/v1/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Name    string `json:"name"`
+			Private bool   `json:"private"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Private {
+			t.Error("repositories should be created private")
+		}
+		if repos["tester/"+req.Name] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		repos["tester/"+req.Name] = true
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"clone_url": "http://" + r.Host + "/tester/" + req.Name + ".git"})
+	})
+	mux.HandleFunc("GET /api/v1/repos/{owner}/{repo}", func(w http.ResponseWriter, r *http.Request) {
+		if !repos[r.PathValue("owner")+"/"+r.PathValue("repo")] {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	mux.HandleFunc("DELETE /api/v1/repos/{owner}/{repo}", func(w http.ResponseWriter, r *http.Request) {
+		delete(repos, r.PathValue("owner")+"/"+r.PathValue("repo"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/v1/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			w.Write([]byte("[]"))
+			return
+		}
+		w.Write([]byte(`[{"size": 2}, {"size": 3}]`))
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, repos
+}
+
+func TestGiteaRepoLifecycle(t *testing.T) {
+	server, repos := newGiteaServer(t)
+
+	var ops []string
+	g := NewGitea(server.URL+"/", "secret", func(opType, command string, result *timing.Result) {
+		ops = append(ops, opType)
+	})
+
+	url, err := g.CreateRepo("lfs-eval-test")
+	if err != nil {
+		t.Fatalf("CreateRepo failed: %v", err)
+	}
+	if url != server.URL+"/tester/lfs-eval-test.git" {
+		t.Errorf("CreateRepo URL = %s", url)
+	}
+	if !repos["tester/lfs-eval-test"] {
+		t.Error("CreateRepo should create the repository for the token user")
+	}
+
+	_, err = g.CreateRepo("lfs-eval-test")
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("CreateRepo of an existing repository: err = %v", err)
+	}
+
+	if exists, err := g.RepoExists("lfs-eval-test"); err != nil || !exists {
+		t.Errorf("RepoExists = %v, %v; want true", exists, err)
+	}
+	if err := g.DeleteRepo("tester/lfs-eval-test"); err != nil {
+		t.Fatalf("DeleteRepo failed: %v", err)
+	}
+	if exists, err := g.RepoExists("lfs-eval-test"); err != nil || exists {
+		t.Errorf("RepoExists after delete = %v, %v; want false", exists, err)
+	}
+
+	want := []string{"gitea-create-repo", "gitea-create-repo", "gitea-delete-repo"}
+	if strings.Join(ops, ",") != strings.Join(want, ",") {
+		t.Errorf("recorded operations = %v, want %v", ops, want)
+	}
+}
+
+func TestGiteaQuotaInfo(t *testing.T) {
+	server, _ := newGiteaServer(t)
+	g := NewGitea(server.URL, "secret", nil)
+
+	quota, err := g.QuotaInfo()
+	if err != nil {
+		t.Fatalf("QuotaInfo failed: %v", err)
+	}
+	if quota.UsedBytes != 5*1024 || quota.LimitBytes != 0 {
+		t.Errorf("QuotaInfo = %+v, want 5 KiB used and no limit", quota)
+	}
+}
+
+func TestGiteaURLs(t *testing.T) {
+	g := &Gitea{URL: "http://gojira:3000/", Token: "secret", Owner: "lab"}
+
+	clone, _ := g.CloneURL("lfs-eval-test")
+	if clone != "http://gojira:3000/lab/lfs-eval-test.git" {
+		t.Errorf("CloneURL = %s", clone)
+	}
+	lfs, _ := g.LFSURL("other/repo")
+	if lfs != "http://gojira:3000/other/repo.git/info/lfs" {
+		t.Errorf("LFSURL = %s", lfs)
+	}
+
+	config := g.GitConfig()
+	if config["http.http://gojira:3000/.extraHeader"] != "Authorization: token secret" {
+		t.Errorf("GitConfig = %v", config)
+	}
+}
+
+func TestGiteaNotConfigured(t *testing.T) {
+	g := NewGitea("", "", nil)
+	if _, err := g.CreateRepo("repo"); err == nil || !strings.Contains(err.Error(), "gitea_url") {
+		t.Errorf("CreateRepo without a URL: err = %v", err)
+	}
+	if g.GitConfig() != nil {
+		t.Error("GitConfig should be empty without a URL and token")
+	}
+}
@@ -0,0 +1,101 @@
+package githost
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// GitHost is a hosting service that stores the git side of a test repository
+type GitHost interface {
+	// Name returns the host kind, e.g. "github"
+	Name() string
+	// CreateRepo creates a private repository and returns its clone URL
+	CreateRepo(name string) (string, error)
+	// DeleteRepo deletes a repository
+	DeleteRepo(name string) error
+	// RepoExists reports whether a repository exists
+	RepoExists(name string) (bool, error)
+	// CloneURL returns the URL used to clone a repository
+	CloneURL(name string) (string, error)
+	// QuotaInfo returns storage usage and limits for the account
+	QuotaInfo() (*Quota, error)
+}
+
+// Quota describes the storage available on a hosting account.
+// Zero values mean the host did not report that figure.
+type Quota struct {
+	Plan       string
+	UsedBytes  int64
+	LimitBytes int64
+}
+
+// Recorder receives each timed host command so callers can store it as an operation
+type Recorder func(opType, command string, result *timing.Result)
+
+// controlPlanePrefixes start the operation types recorded for hosting service calls
+var controlPlanePrefixes = []string{"gh-", "gitea-", "bitbucket-", "azure-"}
+
+// IsControlPlane reports whether an operation type is a call to a hosting service's API,
+// such as creating a repository on GitHub, rather than a git or LFS data transfer.
+// Reports keep the two apart, so a slow GitHub API does not look like a slow LFS server.
+func IsControlPlane(opType string) bool {
+	for _, prefix := range controlPlanePrefixes {
+		if strings.HasPrefix(opType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Factory creates a GitHost that reports its commands to recorder (which may be nil).
+// It fails if the settings of the host, such as its credentials, cannot be read.
+type Factory func(recorder Recorder) (GitHost, error)
+
+var registry = map[string]Factory{
+	"github": func(recorder Recorder) (GitHost, error) { return NewGitHub(recorder), nil },
+	"gitea":  func(recorder Recorder) (GitHost, error) { return NewGitea("", "", recorder), nil },
+	"mock":   func(recorder Recorder) (GitHost, error) { return NewMock(), nil },
+
+	// Cloud hosts configured through their REST APIs
+	"bitbucket":    func(recorder Recorder) (GitHost, error) { return NewBitbucket("", "", "", recorder), nil },
+	"azure-devops": func(recorder Recorder) (GitHost, error) { return NewAzureDevOps("", "", recorder), nil },
+}
+
+// Register adds a host kind to the registry, replacing any existing entry
+func Register(kind string, factory Factory) {
+	registry[kind] = factory
+}
+
+// New creates the GitHost registered for kind
+func New(kind string, recorder Recorder) (GitHost, error) {
+	factory, ok := registry[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown git host '%s' (available: %v)", kind, Kinds())
+	}
+	return factory(recorder)
+}
+
+// Kinds returns the registered host kinds in sorted order
+func Kinds() []string {
+	var kinds []string
+	for kind := range registry {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// LFSEndpoint is implemented by hosts that serve LFS for their repositories themselves
+type LFSEndpoint interface {
+	// LFSURL returns the LFS API endpoint of a repository
+	LFSURL(name string) (string, error)
+}
+
+// Authenticator is implemented by hosts whose git and LFS traffic needs credentials
+type Authenticator interface {
+	// GitConfig returns git config settings that authenticate requests to the host
+	GitConfig() map[string]string
+}
@@ -0,0 +1,166 @@
+package githost
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+func TestNew(t *testing.T) {
+	host, err := New("github", nil)
+	if err != nil {
+		t.Fatalf("New(github) failed: %v", err)
+	}
+	if host.Name() != "github" {
+		t.Errorf("Name = %s, want github", host.Name())
+	}
+
+	if _, err := New("sourceforge", nil); err == nil {
+		t.Error("New should reject an unknown host kind")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	mock := NewMock()
+	Register("test-host", func(recorder Recorder) (GitHost, error) { return mock, nil })
+	defer delete(registry, "test-host")
+
+	host, err := New("test-host", nil)
+	if err != nil {
+		t.Fatalf("New(test-host) failed: %v", err)
+	}
+	if host != GitHost(mock) {
+		t.Error("New should return the host built by the registered factory")
+	}
+}
+
+func TestGitHubCloneURL(t *testing.T) {
+	g := &GitHub{Owner: "mslinn"}
+
+	tests := map[string]string{
+		"scenario3":           "https://github.com/mslinn/scenario3.git",
+		"other/lfs-eval-test": "https://github.com/other/lfs-eval-test.git",
+	}
+	for name, want := range tests {
+		got, err := g.CloneURL(name)
+		if err != nil {
+			t.Fatalf("CloneURL(%s) failed: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("CloneURL(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestMock(t *testing.T) {
+	m := NewMock()
+
+	url, err := m.CreateRepo("repo")
+	if err != nil {
+		t.Fatalf("CreateRepo failed: %v", err)
+	}
+	if url != "mock://repo" {
+		t.Errorf("CreateRepo URL = %s, want mock://repo", url)
+	}
+	if _, err := m.CreateRepo("repo"); err == nil {
+		t.Error("CreateRepo should fail for an existing repository")
+	}
+
+	if exists, _ := m.RepoExists("repo"); !exists {
+		t.Error("RepoExists should report the created repository")
+	}
+	if err := m.DeleteRepo("repo"); err != nil {
+		t.Errorf("DeleteRepo failed: %v", err)
+	}
+	if exists, _ := m.RepoExists("repo"); exists {
+		t.Error("RepoExists should not report a deleted repository")
+	}
+
+	if len(m.Calls) != 5 || m.Calls[0] != "CreateRepo repo" {
+		t.Errorf("Calls = %v", m.Calls)
+	}
+
+	m.Err = errors.New("service unavailable")
+	if _, err := m.QuotaInfo(); err == nil {
+		t.Error("QuotaInfo should return the injected error")
+	}
+}
+
+func TestIsControlPlane(t *testing.T) {
+	for opType, want := range map[string]bool{
+		GitHubCreateRepo:    true,
+		GitHubRepoView:      true,
+		"gitea-delete-repo": true,
+		"bitbucket-quota":   true,
+		"azure-create-repo": true,
+		"push":              false,
+		"lfs-track":         false,
+		"clone":             false,
+	} {
+		if got := IsControlPlane(opType); got != want {
+			t.Errorf("IsControlPlane(%s) = %v, want %v", opType, got, want)
+		}
+	}
+}
+
+func TestGitHubRecordsEveryCall(t *testing.T) {
+	// A fake gh that knows one user and no repositories
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"case \"$1 $2\" in\n" +
+		"  'api user') echo tester ;;\n" +
+		"  'repo view') exit 1 ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var ops []string
+	g := NewGitHub(func(opType, command string, result *timing.Result) {
+		ops = append(ops, opType)
+	})
+
+	if exists, err := g.RepoExists("lfs-eval-test"); err != nil || exists {
+		t.Errorf("RepoExists = %v, %v; want false", exists, err)
+	}
+	if _, err := g.CreateRepo("lfs-eval-test"); err != nil {
+		t.Fatalf("CreateRepo failed: %v", err)
+	}
+	if err := g.DeleteRepo("lfs-eval-test"); err != nil {
+		t.Fatalf("DeleteRepo failed: %v", err)
+	}
+
+	want := []string{GitHubUser, GitHubRepoView, GitHubAuthStatus, GitHubCreateRepo, GitHubDeleteRepo}
+	if strings.Join(ops, ",") != strings.Join(want, ",") {
+		t.Errorf("recorded operations = %v, want %v", ops, want)
+	}
+}
+
+func TestGitHubAuth(t *testing.T) {
+	// A fake gh that is not logged in
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"case \"$1 $2\" in\n" +
+		"  'auth status') echo 'You are not logged into any GitHub hosts.' >&2; exit 1 ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	g := NewGitHub(nil)
+	_, err := g.CreateRepo("owner/lfs-eval-test")
+	if err == nil || !strings.Contains(err.Error(), "gh auth login") {
+		t.Errorf("CreateRepo without a login returned %v, want advice to log in", err)
+	}
+
+	var auth Authenticator = g
+	if helper := auth.GitConfig()["credential.https://github.com.helper"]; helper != "!gh auth git-credential" {
+		t.Errorf("credential helper = %q", helper)
+	}
+}
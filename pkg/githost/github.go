@@ -0,0 +1,183 @@
+package githost
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// Operation types of the gh commands GitHub records; IsControlPlane reports them
+const (
+	GitHubCreateRepo = "gh-create-repo" // gh repo create
+	GitHubDeleteRepo = "gh-delete-repo" // gh repo delete
+	GitHubRepoView   = "gh-repo-view"   // gh repo view, which checks whether a repository exists
+	GitHubUser       = "gh-api-user"    // gh api user, which looks up the authenticated account
+	GitHubQuota      = "gh-quota"       // gh api user, which reads the plan and disk usage
+	GitHubAuthStatus = "gh-auth-status" // gh auth status, which checks the login
+)
+
+// gitHubCredentialHelper makes git and git-lfs authenticate HTTPS requests to GitHub
+// with the token gh is logged in with, as 'gh auth setup-git' would
+const gitHubCredentialHelper = "!gh auth git-credential"
+
+// GitHub manages repositories on GitHub through the gh CLI
+type GitHub struct {
+	Owner    string // Account that owns unqualified repository names (looked up if empty)
+	Recorder Recorder
+}
+
+// NewGitHub creates a GitHub host that reports gh commands to recorder
+func NewGitHub(recorder Recorder) *GitHub {
+	return &GitHub{Recorder: recorder}
+}
+
+// Name returns "github"
+func (g *GitHub) Name() string {
+	return "github"
+}
+
+// run executes gh, records it under opType, and returns an error for failures
+func (g *GitHub) run(opType string, args ...string) (*timing.Result, error) {
+	result := timing.Run("gh", args, nil)
+	if g.Recorder != nil && opType != "" {
+		g.Recorder(opType, "gh "+strings.Join(args, " "), result)
+	}
+
+	if result.Error != nil {
+		return result, fmt.Errorf("gh %s failed: %w", args[0], result.Error)
+	}
+	if result.ExitCode != 0 {
+		return result, fmt.Errorf("gh %s failed (exit %d): %s", strings.Join(args[:2], " "), result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	return result, nil
+}
+
+// CheckCLI returns an error if gh is not installed
+func (g *GitHub) CheckCLI() error {
+	result := timing.Run("gh", []string{"--version"}, nil)
+	if result.Error != nil || result.ExitCode != 0 {
+		return fmt.Errorf("gh CLI not available - install with: sudo apt install gh")
+	}
+	return nil
+}
+
+// CheckAuth returns an error that says how to log in if gh is not logged in to GitHub
+func (g *GitHub) CheckAuth() error {
+	if _, err := g.run(GitHubAuthStatus, "auth", "status", "--hostname", "github.com"); err != nil {
+		return fmt.Errorf("gh is not logged in to GitHub: %w\n\n"+
+			"Log in with: gh auth login --hostname github.com --git-protocol https --scopes repo,delete_repo\n"+
+			"(delete_repo lets --force recreate the test repository)", err)
+	}
+	return nil
+}
+
+// GitConfig makes git and git-lfs push to and fetch from GitHub over HTTPS with the
+// credentials of gh, so neither prompts for a password
+func (g *GitHub) GitConfig() map[string]string {
+	return map[string]string{
+		"credential.https://github.com.helper": gitHubCredentialHelper,
+	}
+}
+
+// fullName qualifies a repository name with its owner
+func (g *GitHub) fullName(name string) (string, error) {
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+	if g.Owner == "" {
+		result, err := g.run(GitHubUser, "api", "user", "-q", ".login")
+		if err != nil {
+			return "", fmt.Errorf("failed to get GitHub user: %w", err)
+		}
+		g.Owner = strings.TrimSpace(result.Stdout)
+	}
+	return g.Owner + "/" + name, nil
+}
+
+// CreateRepo creates a private repository and returns its clone URL
+func (g *GitHub) CreateRepo(name string) (string, error) {
+	if err := g.CheckCLI(); err != nil {
+		return "", err
+	}
+	if err := g.CheckAuth(); err != nil {
+		return "", err
+	}
+
+	fullName, err := g.fullName(name)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := g.run(GitHubCreateRepo, "repo", "create", fullName, "--private"); err != nil {
+		return "", err
+	}
+
+	return g.CloneURL(fullName)
+}
+
+// DeleteRepo deletes a repository; gh needs the delete_repo scope for this
+func (g *GitHub) DeleteRepo(name string) error {
+	fullName, err := g.fullName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = g.run(GitHubDeleteRepo, "repo", "delete", fullName, "--yes")
+	return err
+}
+
+// RepoExists reports whether a repository exists
+func (g *GitHub) RepoExists(name string) (bool, error) {
+	fullName, err := g.fullName(name)
+	if err != nil {
+		return false, err
+	}
+
+	// A missing repository makes gh fail, so the exit code is the answer rather than an error
+	args := []string{"repo", "view", fullName, "--json", "name"}
+	result := timing.Run("gh", args, nil)
+	if g.Recorder != nil {
+		g.Recorder(GitHubRepoView, "gh "+strings.Join(args, " "), result)
+	}
+	if result.Error != nil && result.ExitCode < 0 {
+		return false, fmt.Errorf("gh repo view failed: %w", result.Error)
+	}
+	return result.ExitCode == 0, nil
+}
+
+// CloneURL returns the HTTPS clone URL of a repository
+func (g *GitHub) CloneURL(name string) (string, error) {
+	fullName, err := g.fullName(name)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://github.com/%s.git", fullName), nil
+}
+
+// QuotaInfo returns the plan and disk usage of the authenticated account
+func (g *GitHub) QuotaInfo() (*Quota, error) {
+	result, err := g.run(GitHubQuota, "api", "user")
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub reports sizes in kilobytes
+	var user struct {
+		DiskUsage int64 `json:"disk_usage"`
+		Plan      struct {
+			Name  string `json:"name"`
+			Space int64  `json:"space"`
+		} `json:"plan"`
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), &user); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub user info: %w", err)
+	}
+
+	return &Quota{
+		Plan:       user.Plan.Name,
+		UsedBytes:  user.DiskUsage * 1024,
+		LimitBytes: user.Plan.Space * 1024,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package githost
+
+import "fmt"
+
+// Mock is an in-memory GitHost for tests; it never contacts a real service
+type Mock struct {
+	Repos map[string]bool
+	Calls []string // Method calls in order, e.g. "CreateRepo foo"
+	Quota Quota
+	Err   error // Returned by every method when set
+}
+
+// NewMock creates an empty mock host
+func NewMock() *Mock {
+	return &Mock{Repos: make(map[string]bool)}
+}
+
+// Name returns "mock"
+func (m *Mock) Name() string {
+	return "mock"
+}
+
+// CreateRepo records the repository and returns its mock clone URL
+func (m *Mock) CreateRepo(name string) (string, error) {
+	m.Calls = append(m.Calls, "CreateRepo "+name)
+	if m.Err != nil {
+		return "", m.Err
+	}
+	if m.Repos[name] {
+		return "", fmt.Errorf("repository '%s' already exists", name)
+	}
+	m.Repos[name] = true
+	return m.CloneURL(name)
+}
+
+// DeleteRepo forgets the repository
+func (m *Mock) DeleteRepo(name string) error {
+	m.Calls = append(m.Calls, "DeleteRepo "+name)
+	if m.Err != nil {
+		return m.Err
+	}
+	if !m.Repos[name] {
+		return fmt.Errorf("repository '%s' not found", name)
+	}
+	delete(m.Repos, name)
+	return nil
+}
+
+// RepoExists reports whether the repository was created
+func (m *Mock) RepoExists(name string) (bool, error) {
+	m.Calls = append(m.Calls, "RepoExists "+name)
+	return m.Repos[name], m.Err
+}
+
+// CloneURL returns a mock:// URL for the repository
+func (m *Mock) CloneURL(name string) (string, error) {
+	return "mock://" + name, m.Err
+}
+
+// QuotaInfo returns the configured Quota
+func (m *Mock) QuotaInfo() (*Quota, error) {
+	m.Calls = append(m.Calls, "QuotaInfo")
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	quota := m.Quota
+	return &quota, nil
+}
@@ -0,0 +1,79 @@
+package githost
+
+import (
+	"slices"
+	"strings"
+)
+
+// Kinds of quota errors, recorded with failed operations so an exhausted hosting quota
+// is not reported as a failing server
+const (
+	QuotaStorage   = "lfs-storage-quota"   // The account's LFS storage is used up, so uploads fail
+	QuotaBandwidth = "lfs-bandwidth-quota" // The account's LFS bandwidth is used up, so downloads fail
+)
+
+// quotaMessages identify git-lfs errors caused by an exhausted LFS quota. GitHub words
+// them the same way for storage and bandwidth, so the direction of the transfer tells
+// which one ran out.
+var quotaMessages = []string{
+	"over its data quota",
+	"exceeded its lfs budget",
+	"purchase more data packs",
+	"lfs quota exceeded",
+}
+
+// uploadOperations are the operation types that send LFS objects to the server
+var uploadOperations = []string{"push", "lfs-push", "lfs-migrate"}
+
+// QuotaErrorKind returns QuotaStorage or QuotaBandwidth when the output of a failed
+// operation of opType says the host's LFS quota is used up, and "" otherwise
+func QuotaErrorKind(opType, output string) string {
+	output = strings.ToLower(output)
+	for _, message := range quotaMessages {
+		if strings.Contains(output, message) {
+			if slices.Contains(uploadOperations, opType) {
+				return QuotaStorage
+			}
+			return QuotaBandwidth
+		}
+	}
+	return ""
+}
+
+// QuotaError is returned for git and git-lfs commands that failed because the host's LFS
+// quota is used up
+type QuotaError struct {
+	Kind    string // QuotaStorage or QuotaBandwidth
+	Message string // The host's explanation
+}
+
+func (e *QuotaError) Error() string {
+	what := "LFS bandwidth quota exceeded"
+	if e.Kind == QuotaStorage {
+		what = "LFS storage quota exceeded"
+	}
+	return what + ": " + e.Message
+}
+
+// NewQuotaError returns a QuotaError when the output of a failed operation of opType
+// reports an exhausted quota, and nil otherwise
+func NewQuotaError(opType, output string) *QuotaError {
+	kind := QuotaErrorKind(opType, output)
+	if kind == "" {
+		return nil
+	}
+	return &QuotaError{Kind: kind, Message: quotaLine(output)}
+}
+
+// quotaLine returns the line of output that reports the quota
+func quotaLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		lower := strings.ToLower(line)
+		for _, message := range quotaMessages {
+			if strings.Contains(lower, message) {
+				return strings.TrimSpace(line)
+			}
+		}
+	}
+	return strings.TrimSpace(output)
+}
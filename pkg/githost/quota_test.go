@@ -0,0 +1,43 @@
+package githost
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestQuotaErrorKind(t *testing.T) {
+	const overQuota = "Uploading LFS objects:   0% (0/3), 0 B | 0 B/s, done.\n" +
+		"batch response: This repository is over its data quota. Account responsible for LFS bandwidth should purchase more data packs to restore access.\n" +
+		"error: failed to push some refs to 'https://github.com/owner/lfs-eval-test.git'\n"
+	const overBudget = "Downloading video.mov (120 MB)\n" +
+		"Error downloading object: video.mov (5a4e1f2): Smudge error: batch response: This repository exceeded its LFS budget. The account responsible for the budget should increase it to restore access.\n"
+
+	tests := []struct {
+		opType, output, want string
+	}{
+		{"push", overQuota, QuotaStorage},
+		{"clone", overQuota, QuotaBandwidth},
+		{"pull", overBudget, QuotaBandwidth},
+		{"push", "error: failed to push some refs\n ! [rejected] main -> main (fetch first)\n", ""},
+		{"clone", "fatal: repository 'https://github.com/owner/x.git/' not found\n", ""},
+	}
+	for _, tt := range tests {
+		if got := QuotaErrorKind(tt.opType, tt.output); got != tt.want {
+			t.Errorf("QuotaErrorKind(%s, %.30q) = %q, want %q", tt.opType, tt.output, got, tt.want)
+		}
+	}
+
+	err := fmt.Errorf("git push failed: %w", NewQuotaError("push", overQuota))
+	var quotaErr *QuotaError
+	if !errors.As(err, &quotaErr) || quotaErr.Kind != QuotaStorage {
+		t.Fatalf("errors.As found %+v in %v", quotaErr, err)
+	}
+	if want := "LFS storage quota exceeded: batch response: This repository is over its data quota. " +
+		"Account responsible for LFS bandwidth should purchase more data packs to restore access."; quotaErr.Error() != want {
+		t.Errorf("Error() = %s", quotaErr.Error())
+	}
+	if NewQuotaError("push", "fatal: unable to access\n") != nil {
+		t.Error("NewQuotaError reported a quota for an unrelated failure")
+	}
+}
@@ -0,0 +1,93 @@
+package githost
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// apiCall is one request to the REST API of a hosting service
+type apiCall struct {
+	Service string // Name of the service in error messages, e.g. "Gitea"
+	OpType  string // Operation type the call is recorded as; empty records nothing
+	Method  string
+	URL     string // Full URL of the request
+	Path    string // Path recorded as the command, e.g. "/user/repos"
+	Auth    string // Authorization header
+	Body    any    // Encoded as JSON; nil sends no body
+	Out     any    // Decoded from a successful JSON response; nil ignores it
+}
+
+// doAPI makes an API call with client (nil uses one with a 30 second timeout), records it
+// with recorder, and returns the HTTP status; only transport and decoding failures are errors
+func doAPI(client *http.Client, recorder Recorder, call apiCall) (int, error) {
+	var reader io.Reader
+	if call.Body != nil {
+		data, err := json.Marshal(call.Body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(call.Method, call.URL, reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", call.Auth)
+	req.Header.Set("Accept", "application/json")
+	if call.Body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	// Record API calls like commands, with the HTTP status as the exit code of failures
+	result := &timing.Result{Command: call.Method, Args: []string{req.URL.String()}}
+	start := time.Now()
+	resp, err := client.Do(req)
+	var data []byte
+	if err == nil {
+		data, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.Stdout = string(data)
+	if err != nil {
+		result.Error = err
+		result.ExitCode = -1
+	} else if resp.StatusCode >= 300 {
+		result.ExitCode = resp.StatusCode
+	}
+	if recorder != nil && call.OpType != "" {
+		recorder(call.OpType, call.Method+" "+call.Path, result)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("%s API %s %s failed: %w", call.Service, call.Method, call.Path, err)
+	}
+	if call.Out != nil && resp.StatusCode < 300 {
+		if err := json.Unmarshal(data, call.Out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to parse %s response: %w", call.Service, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// apiError describes an unexpected API status, followed by an optional hint
+func apiError(service, action string, status int, hint string) error {
+	return fmt.Errorf("failed to %s: %s returned HTTP %d%s", action, service, status, hint)
+}
+
+// basicAuth returns the Authorization header of HTTP basic authentication
+func basicAuth(user, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
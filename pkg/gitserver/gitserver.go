@@ -0,0 +1,345 @@
+// Package gitserver runs a bare repository that stands in for the git server of
+// scenarios without a hosted one. Clients reach it as a local path, through git daemon,
+// or over SSH; over SSH the repository may also live on another host.
+package gitserver
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/sshutil"
+)
+
+// Ways of exposing the bare repository to clients
+const (
+	ModePath   = "path"   // Clients use the directory itself
+	ModeDaemon = "daemon" // git daemon serves it on a local port (git://)
+	ModeSSH    = "ssh"    // Clients reach it over SSH (ssh://HOST/DIR)
+)
+
+// Modes lists the valid values of Server.Mode
+var Modes = []string{ModePath, ModeDaemon, ModeSSH}
+
+// DefaultSSHHost is the host clients connect to in ModeSSH when Host is empty
+const DefaultSSHHost = "localhost"
+
+// DefaultRemoteDir is the directory on a remote host that holds the bare repositories
+const DefaultRemoteDir = "/tmp/lfst-git"
+
+// Server is a bare repository exposed to clients in one of the Modes
+type Server struct {
+	Dir  string // Absolute path of the bare repository
+	Mode string // One of Modes
+	Host string // SSH host in ModeSSH; empty means DefaultSSHHost
+	Port int    // Port git daemon listens on in ModeDaemon, set by Start
+
+	remote bool // Dir is on Host rather than on this machine
+	daemon *exec.Cmd
+	exited chan error // Receives the result of the daemon's Wait
+	stderr bytes.Buffer
+}
+
+// New returns a server for the bare repository dir; Create makes the repository
+// and Start exposes it
+func New(dir, mode string) (*Server, error) {
+	if mode == "" {
+		mode = ModePath
+	}
+	if !slices.Contains(Modes, mode) {
+		return nil, fmt.Errorf("unknown git server mode '%s' (use %s)", mode, strings.Join(Modes, ", "))
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	return &Server{Dir: abs, Mode: mode}, nil
+}
+
+// NewRemote returns a server for the bare repository dir on host, which clients reach
+// over SSH. Create makes the repository there, and Start checks that it can.
+func NewRemote(host, dir string) (*Server, error) {
+	if host == "" {
+		return nil, fmt.Errorf("no SSH host given for the bare repository %s", dir)
+	}
+	if !path.IsAbs(dir) {
+		return nil, fmt.Errorf("the bare repository on %s needs an absolute path, not %s", host, dir)
+	}
+	return &Server{Dir: path.Clean(dir), Mode: ModeSSH, Host: host, remote: true}, nil
+}
+
+// Remote reports whether the repository is on another host, made with NewRemote
+func (s *Server) Remote() bool {
+	return s.remote
+}
+
+// Create (re)creates the bare repository, with HEAD on branch so clones check it out
+func (s *Server) Create(branch string) error {
+	if s.remote {
+		return s.runRemote(createScript(s.Dir, branch))
+	}
+	if err := os.RemoveAll(s.Dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", s.Dir, err)
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", s.Dir, err)
+	}
+	if err := runGit("init", "--bare", s.Dir); err != nil {
+		return err
+	}
+	if branch != "" {
+		if err := runGit("-C", s.Dir, "symbolic-ref", "HEAD", "refs/heads/"+branch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove deletes the bare repository
+func (s *Server) Remove() error {
+	if s.remote {
+		return s.runRemote("rm -rf " + sshutil.Quote(s.Dir))
+	}
+	if err := os.RemoveAll(s.Dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", s.Dir, err)
+	}
+	return nil
+}
+
+// Start exposes the repository: in ModeDaemon it starts git daemon on a free local port,
+// in ModeSSH it checks that the host accepts a non-interactive login, and for a remote
+// repository also that the repository's parent directory is writable there. The
+// repository need not exist yet.
+func (s *Server) Start() error {
+	switch s.Mode {
+	case ModeDaemon:
+		return s.startDaemon()
+	case ModeSSH:
+		if err := s.checkSSH(); err != nil {
+			return err
+		}
+		if s.remote {
+			return s.checkRemoteDir()
+		}
+	}
+	return nil
+}
+
+// CheckLFS verifies that clients can store LFS objects with the repository when no LFS
+// server is configured. Locally they can always write to its directory; a remote host
+// needs git-lfs-transfer, which serves git-lfs's SSH transfer protocol.
+func (s *Server) CheckLFS() error {
+	if !s.remote {
+		return nil
+	}
+	if output, err := s.ssh("command -v git-lfs-transfer").CombinedOutput(); err != nil {
+		return fmt.Errorf("%s cannot store LFS objects over SSH: git-lfs-transfer is not installed there (%v: %s)\n\n"+
+			"Without an LFS server, git-lfs pushes objects over SSH to git-lfs-transfer on %s. Either:\n"+
+			"  - install git-lfs-transfer on %s and make sure it is in the PATH of non-interactive logins\n"+
+			"  - or run a scenario with an LFS server instead",
+			s.Host, err, strings.TrimSpace(string(output)), s.Host, s.Host)
+	}
+	return nil
+}
+
+// Stop stops git daemon, if Start started it; the repository is left in place
+func (s *Server) Stop() error {
+	if s.daemon == nil {
+		return nil
+	}
+	cmd := s.daemon
+	s.daemon = nil
+	if err := cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("failed to stop git daemon: %w", err)
+	}
+	<-s.exited // Reports the kill
+	return nil
+}
+
+// URL returns the URL clients clone and push to
+func (s *Server) URL() string {
+	switch s.Mode {
+	case ModeDaemon:
+		return fmt.Sprintf("git://127.0.0.1:%d/%s", s.Port, filepath.Base(s.Dir))
+	case ModeSSH:
+		return "ssh://" + s.host() + filepath.ToSlash(s.Dir)
+	}
+	return s.Dir
+}
+
+// LFSURL returns the lfs.url clients need for LFS objects, or "" when git-lfs finds
+// them from URL by itself. git daemon has no LFS API, and a plain SSH login has none
+// unless git-lfs-transfer is installed, so then the objects go to the repository's
+// directory directly, which is on this machine. A remote repository always relies on
+// git-lfs-transfer (see CheckLFS).
+func (s *Server) LFSURL() string {
+	if s.remote {
+		return ""
+	}
+	switch s.Mode {
+	case ModeDaemon:
+		return fileURL(s.Dir)
+	case ModeSSH:
+		if _, err := exec.LookPath("git-lfs-transfer"); err != nil {
+			return fileURL(s.Dir)
+		}
+	}
+	return ""
+}
+
+// SSHCommand returns the value for core.sshCommand that applies the configured SSH
+// options of the host (see sshutil), or "" when there are none
+func (s *Server) SSHCommand() string {
+	if s.Mode != ModeSSH {
+		return ""
+	}
+	if shell := sshutil.RsyncShell(s.host()); shell != "ssh" {
+		return shell
+	}
+	return ""
+}
+
+// host returns the SSH host clients connect to
+func (s *Server) host() string {
+	if s.Host != "" {
+		return s.Host
+	}
+	return DefaultSSHHost
+}
+
+// startDaemon serves the repository's parent directory with git daemon, allowing
+// pushes, and waits until it accepts connections
+func (s *Server) startDaemon() error {
+	// git daemon would run git-daemon as a child that outlives killing it, so run that directly
+	execPath, err := exec.Command("git", "--exec-path").Output()
+	if err != nil {
+		return fmt.Errorf("git is not installed or not in PATH: %w", err)
+	}
+	program := filepath.Join(strings.TrimSpace(string(execPath)), "git-daemon")
+
+	// Ask the kernel for a free port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to find a free port for git daemon: %w", err)
+	}
+	s.Port = listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	base := filepath.Dir(s.Dir)
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", base, err)
+	}
+	s.stderr.Reset()
+	cmd := exec.Command(program, "--reuseaddr", "--export-all", "--enable=receive-pack",
+		"--listen=127.0.0.1", fmt.Sprintf("--port=%d", s.Port), "--base-path="+base, base)
+	cmd.Stderr = &s.stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start git daemon: %w", err)
+	}
+	s.exited = make(chan error, 1)
+	go func() { s.exited <- cmd.Wait() }()
+
+	address := fmt.Sprintf("127.0.0.1:%d", s.Port)
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-s.exited:
+			return fmt.Errorf("git daemon exited (%v): %s", err, strings.TrimSpace(s.stderr.String()))
+		default:
+		}
+		if conn, err := net.DialTimeout("tcp", address, time.Second); err == nil {
+			conn.Close()
+			s.daemon = cmd
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	cmd.Process.Kill()
+	<-s.exited
+	return fmt.Errorf("git daemon did not listen on %s within 10s: %s", address, strings.TrimSpace(s.stderr.String()))
+}
+
+// checkSSH verifies that clients can log in to the host without a password prompt
+func (s *Server) checkSSH() error {
+	host := s.host()
+	output, err := s.ssh("git --version").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cannot reach the bare repository over SSH at %s: %v: %s\n\n"+
+			"The SSH scenarios push to %s over ssh://%s. Check that:\n"+
+			"  - an SSH server is running on %s\n"+
+			"  - you can log in without a password (e.g. ssh-copy-id %s)\n"+
+			"  - git is installed there\n"+
+			"  - the SSH options for %s are right (lfst-config show lists ssh_hosts)",
+			host, err, strings.TrimSpace(string(output)), s.Dir, host, host, host, host)
+	}
+	return nil
+}
+
+// checkRemoteDir verifies that the SSH user can create the repository on the host
+func (s *Server) checkRemoteDir() error {
+	parent := path.Dir(s.Dir)
+	script := fmt.Sprintf("mkdir -p %s && test -w %s", sshutil.Quote(parent), sshutil.Quote(parent))
+	if output, err := s.ssh(script).CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot create the bare repository on %s: %s is not writable (%v: %s)\n\n"+
+			"Give the SSH user write access to %s on %s, or choose another directory for the\n"+
+			"repositories of SSH scenarios (lfst-config set ssh_git_dir DIR)",
+			s.Host, parent, err, strings.TrimSpace(string(output)), parent, s.Host)
+	}
+	return nil
+}
+
+// ssh returns the command that runs script on the SSH host, failing rather than
+// prompting for a password
+func (s *Server) ssh(script string) *exec.Cmd {
+	args := sshutil.Args(s.host(), "-o", "BatchMode=yes", "-o", "ConnectTimeout=10")
+	return exec.Command("ssh", append(args, script)...)
+}
+
+// runRemote runs a shell command on the host of a remote repository
+func (s *Server) runRemote(script string) error {
+	output, err := s.ssh(script).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("'%s' failed on %s: %v: %s", script, s.Host, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// createScript returns the shell command that creates a bare repository in dir with HEAD
+// on branch, and the directory git-lfs-transfer keeps its objects in. The repository is
+// group-writable so that other members of the SSH user's group can push to it too.
+func createScript(dir, branch string) string {
+	commands := []string{
+		"rm -rf " + sshutil.Quote(dir),
+		"mkdir -p " + sshutil.Quote(dir),
+		"git init --quiet --bare --shared=group " + sshutil.Quote(dir),
+	}
+	if branch != "" {
+		commands = append(commands, "git -C "+sshutil.Quote(dir)+" symbolic-ref HEAD "+sshutil.Quote("refs/heads/"+branch))
+	}
+	commands = append(commands,
+		"mkdir -p "+sshutil.Quote(path.Join(dir, "lfs", "objects")),
+		"chmod -R ug+rwX "+sshutil.Quote(dir))
+	return strings.Join(commands, " && ")
+}
+
+// runGit runs a git command that needs no timing
+func runGit(args ...string) error {
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// fileURL returns the file:// URL of a local directory
+func fileURL(dir string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(dir)}).String()
+}
@@ -0,0 +1,160 @@
+package gitserver
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// git runs a git command for a test, failing it on error
+func git(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v: %s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// pushAndClone commits a file in a new repository, pushes it to the server and clones
+// the server again, returning the clone's directory
+func pushAndClone(t *testing.T, s *Server) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	if err := s.Create("trunk"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer s.Stop()
+
+	work := filepath.Join(t.TempDir(), "work")
+	git(t, "init", "-b", "trunk", work)
+	git(t, "-C", work, "config", "user.name", "Test")
+	git(t, "-C", work, "config", "user.email", "test@example.com")
+	if err := os.WriteFile(filepath.Join(work, "a.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, "-C", work, "add", "a.txt")
+	git(t, "-C", work, "commit", "-m", "First")
+	git(t, "-C", work, "remote", "add", "origin", s.URL())
+	git(t, "-C", work, "push", "origin", "trunk")
+
+	clone := filepath.Join(t.TempDir(), "clone")
+	git(t, "clone", s.URL(), clone)
+	return clone
+}
+
+func TestPathServer(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "server.git"), "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if s.URL() != s.Dir || s.LFSURL() != "" {
+		t.Errorf("URL = %s, LFSURL = %s; want the directory and nothing", s.URL(), s.LFSURL())
+	}
+
+	clone := pushAndClone(t, s)
+	if data, err := os.ReadFile(filepath.Join(clone, "a.txt")); err != nil || string(data) != "hello\n" {
+		t.Errorf("clone has a.txt = %q, %v", data, err)
+	}
+	if branch := git(t, "-C", clone, "symbolic-ref", "--short", "HEAD"); branch != "trunk" {
+		t.Errorf("clone checked out %s, want trunk", branch)
+	}
+}
+
+func TestDaemonServer(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "server.git"), ModeDaemon)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	clone := pushAndClone(t, s)
+	if _, err := os.Stat(filepath.Join(clone, "a.txt")); err != nil {
+		t.Errorf("clone through git daemon lacks a.txt: %v", err)
+	}
+	if !strings.HasPrefix(s.URL(), "git://127.0.0.1:") || !strings.HasPrefix(s.LFSURL(), "file:///") {
+		t.Errorf("URL = %s, LFSURL = %s", s.URL(), s.LFSURL())
+	}
+	if s.daemon != nil {
+		t.Error("git daemon still running after Stop")
+	}
+}
+
+func TestSSHURL(t *testing.T) {
+	s, err := New("/srv/run-3/server.git", ModeSSH)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if got, want := s.URL(), "ssh://localhost/srv/run-3/server.git"; got != want {
+		t.Errorf("URL = %s, want %s", got, want)
+	}
+	s.Host = "git@gojira"
+	if got, want := s.URL(), "ssh://git@gojira/srv/run-3/server.git"; got != want {
+		t.Errorf("URL = %s, want %s", got, want)
+	}
+
+	if _, err := New("/srv/server.git", "http"); err == nil {
+		t.Error("New accepted an unknown mode")
+	}
+}
+
+func TestRemoteServer(t *testing.T) {
+	s, err := NewRemote("gojira", "/srv/lfst git/run-3/server.git/")
+	if err != nil {
+		t.Fatalf("NewRemote failed: %v", err)
+	}
+	if !s.Remote() || s.Mode != ModeSSH || s.Dir != "/srv/lfst git/run-3/server.git" {
+		t.Errorf("server = %+v", s)
+	}
+	if got, want := s.URL(), "ssh://gojira/srv/lfst git/run-3/server.git"; got != want {
+		t.Errorf("URL = %s, want %s", got, want)
+	}
+	if s.LFSURL() != "" {
+		t.Errorf("LFSURL = %s; a remote repository relies on git-lfs-transfer", s.LFSURL())
+	}
+
+	if _, err := NewRemote("gojira", "lfst/server.git"); err == nil {
+		t.Error("NewRemote accepted a relative directory")
+	}
+	if _, err := NewRemote("", "/srv/server.git"); err == nil {
+		t.Error("NewRemote accepted an empty host")
+	}
+}
+
+func TestCreateScript(t *testing.T) {
+	got := createScript("/srv/lfst git/server.git", "main")
+	want := "rm -rf '/srv/lfst git/server.git' && mkdir -p '/srv/lfst git/server.git' && " +
+		"git init --quiet --bare --shared=group '/srv/lfst git/server.git' && " +
+		"git -C '/srv/lfst git/server.git' symbolic-ref HEAD refs/heads/main && " +
+		"mkdir -p '/srv/lfst git/server.git/lfs/objects' && chmod -R ug+rwX '/srv/lfst git/server.git'"
+	if got != want {
+		t.Errorf("createScript =\n%s\nwant\n%s", got, want)
+	}
+
+	// The script must work in the login shell of the host, so run it in one here
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := filepath.Join(t.TempDir(), "it's", "server.git")
+	if output, err := exec.Command("sh", "-c", createScript(dir, "trunk")).CombinedOutput(); err != nil {
+		t.Fatalf("script failed: %v: %s", err, output)
+	}
+	if head := git(t, "-C", dir, "symbolic-ref", "HEAD"); head != "refs/heads/trunk" {
+		t.Errorf("HEAD = %s, want refs/heads/trunk", head)
+	}
+	if shared := git(t, "-C", dir, "config", "core.sharedRepository"); shared != "1" && shared != "group" {
+		t.Errorf("core.sharedRepository = %s, want group", shared)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "lfs", "objects")); err != nil || info.Mode().Perm()&0060 != 0060 {
+		t.Errorf("LFS store not group-writable: %v, %v", info, err)
+	}
+}
@@ -0,0 +1,154 @@
+package i18n
+
+// de translates messages into German
+var de = map[string]string{
+	// Run, step and operation statuses, check severities and kinds of file changes
+	"running":   "läuft",
+	"completed": "abgeschlossen",
+	"failed":    "fehlgeschlagen",
+	"cancelled": "abgebrochen",
+	"timed-out": "Zeitlimit überschritten",
+	"aborted":   "abgestürzt",
+	"skipped":   "übersprungen",
+	"success":   "erfolgreich",
+	"error":     "Fehler",
+	"warning":   "Warnung",
+	"added":     "hinzugefügt",
+	"deleted":   "gelöscht",
+	"modified":  "geändert",
+	"renamed":   "umbenannt",
+
+	// Table headings and labels
+	"ID":                   "ID",
+	"Scenario":             "Szenario",
+	"Server":               "Server",
+	"Protocol":             "Protokoll",
+	"Git":                  "Git",
+	"Git server":           "Git-Server",
+	"Status":               "Status",
+	"Started":              "Gestartet",
+	"Completed":            "Beendet",
+	"Duration":             "Dauer",
+	"Notes":                "Notizen",
+	"Environment":          "Umgebung",
+	"Step":                 "Schritt",
+	"Operation":            "Operation",
+	"Operations":           "Operationen",
+	"Failed":               "Fehlgeschlagen",
+	"Hosting API":          "Hosting-API",
+	"Checksums":            "Prüfsummen",
+	"Files":                "Dateien",
+	"Bytes":                "Bytes",
+	"Error":                "Fehler",
+	"File":                 "Datei",
+	"Change":               "Änderung",
+	"Old CRC32":            "Alte CRC32",
+	"New CRC32":            "Neue CRC32",
+	"Old Size":             "Alte Größe",
+	"New Size":             "Neue Größe",
+	"Run":                  "Lauf",
+	"Date":                 "Datum",
+	"Operation time":       "Operationsdauer",
+	"Total operation time": "Gesamte Operationsdauer",
+	"Name":                 "Name",
+	"Share":                "Anteil",
+	"Total":                "Gesamt",
+	"Size":                 "Größe",
+	"Slowest operations:":  "Langsamste Operationen:",
+	"Where the time went:": "Wohin die Zeit ging:",
+	"Hosting API time":     "Zeit in der Hosting-API",
+	"Scenario ID:":         "Szenario-ID:",
+	"Server Type:":         "Servertyp:",
+	"Protocol:":            "Protokoll:",
+	"Git Server:":          "Git-Server:",
+	"Status:":              "Status:",
+	"Started:":             "Gestartet:",
+	"Completed:":           "Beendet:",
+	"Duration:":            "Dauer:",
+	"Running for:":         "Läuft seit:",
+	"Directory:":           "Verzeichnis:",
+	"Network:":             "Netzwerk:",
+	"Notes:":               "Notizen:",
+	"Environment:":         "Umgebung:",
+	"Tags:":                "Tags:",
+
+	// Report sections
+	"Git LFS Test Run %d": "Git-LFS-Testlauf %d",
+	"Test Run %d:":        "Testlauf %d:",
+	"Narrative":           "Ablauf",
+	"Step Timings":        "Dauer der Schritte",
+	"Critical Path":       "Kritischer Pfad",
+	"Checksum Changes":    "Geänderte Prüfsummen",
+	"Repository Sizes":    "Repository-Größen",
+	"Generated %s":        "Erstellt am %s",
+	"Step %d":             "Schritt %d",
+	"Step %d %s":          "Schritt %d %s",
+
+	"Nothing recorded yet.":                    "Noch nichts aufgezeichnet.",
+	"No steps recorded.":                       "Keine Schritte aufgezeichnet.",
+	"No operations recorded.":                  "Keine Operationen aufgezeichnet.",
+	"No operation dependencies recorded.":      "Keine Abhängigkeiten zwischen Operationen aufgezeichnet.",
+	"Fewer than two steps recorded checksums.": "Weniger als zwei Schritte haben Prüfsummen aufgezeichnet.",
+	"No repository sizes recorded.":            "Keine Repository-Größen aufgezeichnet.",
+	"No test runs found":                       "Keine Testläufe gefunden",
+
+	// Watching the list of runs
+	"%s (was %s)":                           "%s (vorher %s)",
+	"Every %s, updated %s (Ctrl-C to stop)": "Alle %s, aktualisiert um %s (Strg-C beendet)",
+
+	"%s of the total, in calls such as creating the repository":                                     "%s der Gesamtzeit, in Aufrufen wie dem Anlegen des Repositorys",
+	"%s of %s operation time (%.1f%%) is inherently serial; %s could in principle overlap with it.": "%s von %s Operationsdauer (%.1f%%) sind zwingend seriell; %s könnten sich im Prinzip damit überschneiden.",
+
+	// Narrative
+	": completed in %s":               "; abgeschlossen in %s",
+	": failed after %s":               "; fehlgeschlagen nach %s",
+	"error: %s":                       "Fehler: %s",
+	"check %s failed (%s)":            "Prüfung %s fehlgeschlagen (%s)",
+	"verified %s":                     "geprüft: %s",
+	"%d files":                        "%d Dateien",
+	"%s failed after %s":              "%s fehlgeschlagen nach %s",
+	"%s in %s":                        "%s in %s",
+	"; server: %s":                    "; Server: %s",
+	"%d added":                        "%d hinzugefügt",
+	"%d deleted":                      "%d gelöscht",
+	"%d modified":                     "%d geändert",
+	"%d renamed":                      "%d umbenannt",
+	"no files changed since step %d":  "keine Dateien seit Schritt %d geändert",
+	"files changed since step %d: %s": "seit Schritt %d geänderte Dateien: %s",
+	"Run %s":                          "Lauf %s",
+	" at %s, after %s":                " am %s, nach %s",
+	"Run %d of scenario %d against %s (%s), started %s": "Lauf %d von Szenario %d gegen %s (%s), gestartet %s",
+
+	// Causes of failed operations
+	"LFS storage quota of the host exceeded":   "LFS-Speicherkontingent des Hosts überschritten",
+	"LFS bandwidth quota of the host exceeded": "LFS-Bandbreitenkontingent des Hosts überschritten",
+
+	// Timeline
+	"Start": "Beginn",
+	"Timeline of run %d: %d operations over %s": "Zeitachse von Lauf %d: %d Operationen in %s",
+	"Busy: %s of %s (%.1f%%), idle %s":          "Beschäftigt: %s von %s (%.1f %%), untätig %s",
+	"Most operations running at once: %d":       "Höchstens gleichzeitig laufende Operationen: %d",
+
+	// Scoreboard
+	"Server scoreboard":                "Server-Vergleich",
+	"Server scoreboard of scenario %d": "Server-Vergleich für Szenario %d",
+	"No finished runs found":           "Keine beendeten Läufe gefunden",
+	"Runs":                             "Läufe",
+	"Failure rate":                     "Fehlerquote",
+	"Avg duration":                     "Mittlere Dauer",
+	"Push MB/s":                        "Push MB/s",
+	"Pull MB/s":                        "Pull MB/s",
+	"Failed checks":                    "Fehlgeschlagene Prüfungen",
+
+	// Charts
+	"Step durations of run %d":                 "Dauer der Schritte von Lauf %d",
+	"Bytes transferred in each step of run %d": "Übertragene Bytes je Schritt von Lauf %d",
+	"Mean duration of completed runs":          "Mittlere Dauer abgeschlossener Läufe",
+	"Push throughput":                          "Push-Durchsatz",
+	"Clone and pull throughput":                "Clone- und Pull-Durchsatz",
+
+	// Badge
+	"no runs":              "keine Läufe",
+	"%d passed":            "%d bestanden",
+	"%d passed, %d failed": "%d bestanden, %d fehlgeschlagen",
+}
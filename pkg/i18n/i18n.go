@@ -0,0 +1,80 @@
+// Package i18n translates user-facing messages. Messages are looked up by their English
+// text, so English needs no catalog and untranslated messages fall back to English.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Languages are the languages messages can be shown in
+var Languages = []string{"en", "de"}
+
+// catalogs holds the translations of each language other than English, by English text
+var catalogs = map[string]map[string]string{
+	"de": de,
+}
+
+var current = "en"
+
+// Setup selects the language of messages: lang, e.g. from a --lang flag, if given, then
+// $LFST_LANG, then configured, then the locale ($LC_ALL, $LC_MESSAGES, $LANG). An
+// unsupported language given explicitly is an error; an unsupported locale means English.
+func Setup(lang, configured string) error {
+	for _, explicit := range []string{lang, os.Getenv("LFST_LANG"), configured} {
+		if explicit != "" {
+			return Set(explicit)
+		}
+	}
+	current = fromLocale()
+	return nil
+}
+
+// Set selects the language of messages, e.g. de or de_DE.UTF-8
+func Set(lang string) error {
+	code := normalize(lang)
+	if !slices.Contains(Languages, code) {
+		return fmt.Errorf("unsupported language '%s' (supported: %s)", lang, strings.Join(Languages, ", "))
+	}
+	current = code
+	return nil
+}
+
+// Language returns the code of the selected language, e.g. en
+func Language() string {
+	return current
+}
+
+// fromLocale returns the language of the first locale variable set, or en
+func fromLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			if code := normalize(locale); slices.Contains(Languages, code) {
+				return code
+			}
+			return "en"
+		}
+	}
+	return "en"
+}
+
+// normalize reduces a locale like de_DE.UTF-8 or de-AT to its language code
+func normalize(locale string) string {
+	code, _, _ := strings.Cut(locale, ".")
+	code, _, _ = strings.Cut(code, "_")
+	code, _, _ = strings.Cut(code, "-")
+	return strings.ToLower(code)
+}
+
+// T translates message into the selected language and, with args, formats it like fmt.Sprintf
+func T(message string, args ...interface{}) string {
+	if translated, ok := catalogs[current][message]; ok {
+		message = translated
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
@@ -0,0 +1,73 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	defer Set("en")
+
+	tests := []struct {
+		name              string
+		flag, env, config string
+		lcAll, lang       string
+		want              string
+		wantErr           bool
+	}{
+		{name: "locale", lang: "de_DE.UTF-8", want: "de"},
+		{name: "LC_ALL over LANG", lcAll: "C", lang: "de_DE.UTF-8", want: "en"},
+		{name: "unsupported locale", lang: "fr_FR.UTF-8", want: "en"},
+		{name: "config over locale", config: "en", lang: "de_DE.UTF-8", want: "en"},
+		{name: "LFST_LANG over config", env: "de", config: "en", want: "de"},
+		{name: "flag over all", flag: "en", env: "de", config: "de", lang: "de_DE", want: "en"},
+		{name: "region", flag: "de-AT", want: "de"},
+		{name: "unsupported flag", flag: "fr", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LFST_LANG", tt.env)
+			t.Setenv("LC_ALL", tt.lcAll)
+			t.Setenv("LC_MESSAGES", "")
+			t.Setenv("LANG", tt.lang)
+			Set("en")
+
+			err := Setup(tt.flag, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Setup error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && Language() != tt.want {
+				t.Errorf("Language() = %s, want %s", Language(), tt.want)
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	defer Set("en")
+
+	Set("en")
+	if got := T("Step %d", 3); got != "Step 3" {
+		t.Errorf("T = %q, want Step 3", got)
+	}
+	Set("de")
+	if got := T("Step %d", 3); got != "Schritt 3" {
+		t.Errorf("T = %q, want Schritt 3", got)
+	}
+	if got := T("not in the catalog"); got != "not in the catalog" {
+		t.Errorf("T of an untranslated message = %q", got)
+	}
+}
+
+// Every translation must take the same arguments as its English message
+func TestCatalogVerbs(t *testing.T) {
+	verb := regexp.MustCompile(`%[-+# 0]*[0-9.]*[a-zA-Z%]`)
+	for lang, catalog := range catalogs {
+		for message, translated := range catalog {
+			if want, got := verb.FindAllString(message, -1), verb.FindAllString(translated, -1); !slices.Equal(want, got) {
+				t.Errorf("%s: %q has verbs %v, its translation %q has %v", lang, message, want, translated, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package interference
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// procDir is the Linux source of process names, parents and open files
+const procDir = "/proc"
+
+// KnownScanners maps the process names of antivirus scanners and file indexers, which
+// open files as they are written and slow git and git-lfs down, to what they are.
+// Linux truncates process names to 15 characters, so both forms are listed.
+var KnownScanners = map[string]string{
+	"MsMpEng.exe":            "Microsoft Defender",
+	"MsSense.exe":            "Microsoft Defender for Endpoint",
+	"NisSrv.exe":             "Microsoft Defender network inspection",
+	"SearchIndexer.exe":      "Windows Search indexer",
+	"SearchProtocolHost.exe": "Windows Search indexer",
+	"wdavdaemon":             "Microsoft Defender for Linux",
+	"clamd":                  "ClamAV daemon",
+	"clamonacc":              "ClamAV on-access scanner",
+	"falcon-sensor":          "CrowdStrike Falcon",
+	"sophos-spl":             "Sophos",
+	"savd":                   "Sophos",
+	"tracker-miner-fs":       "GNOME Tracker indexer",
+	"tracker-miner-f":        "GNOME Tracker indexer",
+	"tracker-extract":        "GNOME Tracker indexer",
+	"baloo_file":             "KDE Baloo indexer",
+	"baloo_file_extractor":   "KDE Baloo indexer",
+	"baloo_file_extr":        "KDE Baloo indexer",
+	"updatedb":               "locate database update",
+	"updatedb.plocate":       "locate database update",
+	"updatedb.plocat":        "locate database update",
+	"mds":                    "Spotlight indexer",
+	"mds_stores":             "Spotlight indexer",
+	"mdworker":               "Spotlight indexer",
+	"mdworker_shared":        "Spotlight indexer",
+}
+
+// SlowFileOp is the average time to create, close, reopen and delete a small file above
+// which an on-access scanner is probably inspecting the files; a local disk takes well
+// under a tenth of it
+const SlowFileOp = time.Millisecond
+
+// Process is a process that can distort timings
+type Process struct {
+	PID  int
+	Name string
+	What string // What the process is, from KnownScanners; empty for unknown processes
+}
+
+func (p Process) String() string {
+	var details []string
+	if p.PID != 0 { // Windows processes seen from WSL have none
+		details = append(details, fmt.Sprintf("pid %d", p.PID))
+	}
+	if p.What != "" {
+		details = append(details, p.What)
+	}
+	if len(details) == 0 {
+		return p.Name
+	}
+	return fmt.Sprintf("%s (%s)", p.Name, strings.Join(details, ", "))
+}
+
+// Report is what Check found
+type Report struct {
+	Scanners   []Process     // Known scanners and indexers that are running
+	Touching   []Process     // Other processes that had files in the directory open
+	FileOpTime time.Duration // Average time of a create/close/open/close/delete cycle; 0 if not measured
+}
+
+// Warnings describes the likely sources of interference, or nothing if there are none
+func (r *Report) Warnings() []string {
+	var warnings []string
+	if len(r.Scanners) > 0 {
+		warnings = append(warnings, "file scanners running: "+joinProcesses(r.Scanners))
+	}
+	if len(r.Touching) > 0 {
+		warnings = append(warnings, "other processes had files in the work directory open: "+joinProcesses(r.Touching))
+	}
+	if r.FileOpTime > SlowFileOp {
+		warnings = append(warnings, fmt.Sprintf("creating and opening a small file takes %s, over %s; "+
+			"an on-access scanner is probably inspecting the files", r.FileOpTime.Round(time.Microsecond), SlowFileOp))
+	}
+	return warnings
+}
+
+// joinProcesses lists processes for a warning
+func joinProcesses(procs []Process) string {
+	names := make([]string, len(procs))
+	for i, p := range procs {
+		names[i] = p.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// Check looks for known scanners, for other processes with files in dir open, and
+// measures how long small file operations take in dir. Sources that the platform does
+// not expose are skipped.
+func Check(dir string) (*Report, error) {
+	report := &Report{}
+
+	var err error
+	if report.Scanners, err = Scanners(); err != nil {
+		return nil, err
+	}
+	if report.Touching, err = ProcessesTouching(dir); err != nil {
+		return nil, err
+	}
+	if report.FileOpTime, err = ProbeFileOps(dir, 50); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Scanners returns the known scanners and indexers that are running: from /proc on
+// Linux, from ps on macOS, and also from tasklist.exe under WSL, where Windows Defender
+// scans the files of /mnt/c
+func Scanners() ([]Process, error) {
+	var procs []Process
+	var err error
+	switch {
+	case isDir(procDir):
+		procs, err = listProc()
+	case runtime.GOOS == "darwin":
+		procs, err = listPS()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, lookErr := exec.LookPath("tasklist.exe"); lookErr == nil {
+		out, err := exec.Command("tasklist.exe", "/FO", "CSV", "/NH").Output()
+		if err == nil {
+			procs = append(procs, parseTasklist(strings.NewReader(string(out)))...)
+		}
+	}
+
+	var scanners []Process
+	seen := make(map[string]bool)
+	for _, p := range procs {
+		what, ok := KnownScanners[p.Name]
+		if !ok || seen[p.Name] {
+			continue
+		}
+		seen[p.Name] = true
+		p.What = what
+		scanners = append(scanners, p)
+	}
+	sort.Slice(scanners, func(i, j int) bool { return scanners[i].Name < scanners[j].Name })
+	return scanners, nil
+}
+
+// listProc returns every process in /proc with its name
+func listProc() ([]Process, error) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	var procs []Process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		name, err := os.ReadFile(filepath.Join(procDir, entry.Name(), "comm"))
+		if err != nil {
+			continue // Exited
+		}
+		procs = append(procs, Process{PID: pid, Name: strings.TrimSpace(string(name))})
+	}
+	return procs, nil
+}
+
+// listPS returns every process that ps lists, with the name of its executable
+func listPS() ([]Process, error) {
+	out, err := exec.Command("ps", "-axco", "pid=,comm=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	var procs []Process
+	for _, line := range strings.Split(string(out), "\n") {
+		pid, name, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if n, err := strconv.Atoi(pid); ok && err == nil {
+			procs = append(procs, Process{PID: n, Name: strings.TrimSpace(name)})
+		}
+	}
+	return procs, nil
+}
+
+// parseTasklist parses the CSV output of tasklist.exe /FO CSV /NH:
+// "Image Name","PID","Session Name","Session#","Mem Usage". Windows PIDs mean nothing
+// to Linux, so the processes get PID 0.
+func parseTasklist(r io.Reader) []Process {
+	reader := csv.NewReader(bufio.NewReader(r))
+	reader.FieldsPerRecord = -1
+	var procs []Process
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			break
+		}
+		if len(record) > 0 && record[0] != "" {
+			procs = append(procs, Process{Name: record[0]})
+		}
+	}
+	return procs
+}
+
+// ProcessesTouching returns the processes other than this one and its descendants
+// that have a file in dir open. It needs /proc, and only sees the processes of the
+// same user unless run as root; elsewhere it returns nothing.
+func ProcessesTouching(dir string) ([]Process, error) {
+	if !isDir(procDir) {
+		return nil, nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	procs, err := listProc()
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[int]int)
+	for _, p := range procs {
+		parents[p.PID] = parentPID(p.PID)
+	}
+
+	self := os.Getpid()
+	var touching []Process
+	for _, p := range procs {
+		if isDescendant(p.PID, self, parents) || !hasOpenFileIn(p.PID, dir) {
+			continue
+		}
+		p.What = KnownScanners[p.Name]
+		touching = append(touching, p)
+	}
+	return touching, nil
+}
+
+// parentPID returns the parent of a process from /proc/PID/stat, or 0
+func parentPID(pid int) int {
+	data, err := os.ReadFile(filepath.Join(procDir, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0
+	}
+	// "PID (comm) STATE PPID ..."; comm may contain spaces and parentheses
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	if len(fields) < 2 {
+		return 0
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	return ppid
+}
+
+// isDescendant reports whether pid is ancestor or one of its descendants
+func isDescendant(pid, ancestor int, parents map[int]int) bool {
+	for depth := 0; pid > 1 && depth < 64; depth++ {
+		if pid == ancestor {
+			return true
+		}
+		pid = parents[pid]
+	}
+	return false
+}
+
+// hasOpenFileIn reports whether a process has a file in dir open, or dir as its
+// working directory
+func hasOpenFileIn(pid int, dir string) bool {
+	base := filepath.Join(procDir, strconv.Itoa(pid))
+	if cwd, err := os.Readlink(filepath.Join(base, "cwd")); err == nil && within(cwd, dir) {
+		return true
+	}
+	fds, err := os.ReadDir(filepath.Join(base, "fd"))
+	if err != nil {
+		return false // Exited, or another user's process
+	}
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(base, "fd", fd.Name()))
+		if err == nil && within(target, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// within reports whether path is dir or inside it
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// ProbeFileOps returns the average time to create, write, close, reopen, close and
+// delete n small files in dir
+func ProbeFileOps(dir string, n int) (time.Duration, error) {
+	probeDir, err := os.MkdirTemp(dir, ".lfst-probe-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create probe directory: %w", err)
+	}
+	defer os.RemoveAll(probeDir)
+
+	data := []byte("lfst file operation probe\n")
+	start := time.Now()
+	for i := range n {
+		path := filepath.Join(probeDir, fmt.Sprintf("probe%03d.bin", i))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return 0, fmt.Errorf("failed to write probe file: %w", err)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open probe file: %w", err)
+		}
+		file.Close()
+		if err := os.Remove(path); err != nil {
+			return 0, fmt.Errorf("failed to remove probe file: %w", err)
+		}
+	}
+	return time.Since(start) / time.Duration(n), nil
+}
+
+// isDir reports whether path is a directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// Monitor samples the processes touching a directory in the background, since
+// scanners hold files open only briefly
+type Monitor struct {
+	dir  string
+	stop chan struct{}
+	done chan struct{}
+
+	mu   sync.Mutex
+	seen map[int]Process
+}
+
+// Watch starts sampling the processes touching dir every interval until Stop.
+// It returns nil where processes cannot be inspected.
+func Watch(dir string, interval time.Duration) *Monitor {
+	if !isDir(procDir) {
+		return nil
+	}
+	m := &Monitor{dir: dir, stop: make(chan struct{}), done: make(chan struct{}), seen: make(map[int]Process)}
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			m.sample()
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return m
+}
+
+// sample records the processes touching the directory now
+func (m *Monitor) sample() {
+	procs, err := ProcessesTouching(m.dir)
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range procs {
+		m.seen[p.PID] = p
+	}
+}
+
+// Drain returns the processes seen since the last Drain, by PID
+func (m *Monitor) Drain() []Process {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	procs := make([]Process, 0, len(m.seen))
+	for _, p := range m.seen {
+		procs = append(procs, p)
+	}
+	m.seen = make(map[int]Process)
+	sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+	return procs
+}
+
+// Stop ends the sampling
+func (m *Monitor) Stop() {
+	if m == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+}
@@ -0,0 +1,117 @@
+package interference
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTasklist(t *testing.T) {
+	out := `"System Idle Process","0","Services","0","8 K"
+"MsMpEng.exe","4120","Services","0","215,340 K"
+"explorer.exe","7312","Console","1","98,120 K"
+`
+	procs := parseTasklist(strings.NewReader(out))
+	if len(procs) != 3 || procs[1].Name != "MsMpEng.exe" || procs[1].PID != 0 {
+		t.Errorf("parseTasklist = %+v", procs)
+	}
+}
+
+func TestReportWarnings(t *testing.T) {
+	report := &Report{FileOpTime: 100 * time.Microsecond}
+	if warnings := report.Warnings(); len(warnings) != 0 {
+		t.Errorf("a clean report has warnings: %v", warnings)
+	}
+
+	report = &Report{
+		Scanners:   []Process{{Name: "MsMpEng.exe", What: KnownScanners["MsMpEng.exe"]}},
+		Touching:   []Process{{PID: 42, Name: "baloo_file"}},
+		FileOpTime: 5 * time.Millisecond,
+	}
+	warnings := report.Warnings()
+	if len(warnings) != 3 {
+		t.Fatalf("Warnings() = %v, want 3", warnings)
+	}
+	if !strings.Contains(warnings[0], "MsMpEng.exe (Microsoft Defender)") {
+		t.Errorf("scanner warning = %s", warnings[0])
+	}
+	if !strings.Contains(warnings[1], "baloo_file (pid 42)") {
+		t.Errorf("touching warning = %s", warnings[1])
+	}
+	if !strings.Contains(warnings[2], "5ms") {
+		t.Errorf("file operation warning = %s", warnings[2])
+	}
+}
+
+func TestProbeFileOps(t *testing.T) {
+	dir := t.TempDir()
+	elapsed, err := ProbeFileOps(dir, 10)
+	if err != nil {
+		t.Fatalf("ProbeFileOps failed: %v", err)
+	}
+	if elapsed <= 0 {
+		t.Errorf("ProbeFileOps = %s, want a positive time", elapsed)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("ProbeFileOps left %d entries behind", len(entries))
+	}
+}
+
+func TestProcessesTouching(t *testing.T) {
+	if !isDir(procDir) {
+		t.Skip("needs /proc")
+	}
+	dir := t.TempDir()
+	file := filepath.Join(dir, "held.bin")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// This process and its children are the test itself
+	held, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Close()
+	child := exec.Command("sleep", "10")
+	child.Dir = dir
+	if err := child.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	defer child.Process.Kill()
+
+	procs, err := ProcessesTouching(dir)
+	if err != nil {
+		t.Fatalf("ProcessesTouching failed: %v", err)
+	}
+	if len(procs) != 0 {
+		t.Errorf("ProcessesTouching should skip this process and its children, got %v", procs)
+	}
+
+	// A process that is not a descendant, as a scanner would be
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	orphan := exec.Command("sh", "-c", `(exec 3<"$1"; sleep 10) & echo $! > "$2"`, "sh", file, pidFile)
+	if err := orphan.Run(); err != nil {
+		t.Skipf("cannot start a detached process: %v", err)
+	}
+	data, _ := os.ReadFile(pidFile)
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	defer exec.Command("kill", strconv.Itoa(pid)).Run()
+
+	monitor := Watch(dir, 10*time.Millisecond)
+	defer monitor.Stop()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, p := range monitor.Drain() {
+			if p.PID == pid {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Monitor did not see the detached process %d holding a file open", pid)
+}
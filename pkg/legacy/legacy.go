@@ -0,0 +1,387 @@
+// Package legacy imports the results of the original bash evaluation scripts (the
+// gitScenarios.html workflow), so measurements made before this framework stay
+// comparable with new runs.
+//
+// The scripts left one directory per scenario run with text files per step, named
+// after the step, e.g. step2-timing.txt and step2-checksums.txt:
+//
+//   - Timing files hold each command, as echoed by the script ("$ git add .") or traced
+//     by bash -x ("+ git add ."), followed by the output of bash's time keyword
+//     ("real 0m3.241s", also in the -p form "real 3.24") or of GNU time ("0:03.24elapsed").
+//     Lines such as "Step 3" or "=== Step 3 ===" switch to another step within a file.
+//   - Checksum files hold one file per line as printed by crc32: "CRC32 PATH", or
+//     "CRC32 SIZE PATH" where the script recorded sizes too.
+package legacy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/scenario"
+)
+
+// Timing is one timed command of a legacy timing file
+type Timing struct {
+	Step      int    // Step the command belongs to; 0 means the step of the file
+	Command   string // Command line, e.g. "git add ."
+	Operation string // Operation name as recorded by lfst-scenario, e.g. "add" or "lfs-track"
+	Duration  time.Duration
+}
+
+var (
+	stepPattern     = regexp.MustCompile(`(?i)\bstep[-_ ]?(\d+)`)
+	stepLinePattern = regexp.MustCompile(`(?i)^[#=\-* ]*step[-_ ]?(\d+)\b([^a-z].*)?$`)
+	realPattern     = regexp.MustCompile(`^real\s+(?:(\d+)m)?([\d.]+)s?$`)
+	gnuTimePattern  = regexp.MustCompile(`\b(?:(\d+):)?(\d+):([\d.]+)elapsed\b`)
+	checksumPattern = regexp.MustCompile(`^([0-9a-fA-F]{8})\s+(?:(\d+)\s+)?(.+)$`)
+)
+
+// ParseTimings reads the timed commands of a timing file. A timing without a command
+// before it is an error, because it could not be attributed to an operation.
+func ParseTimings(r io.Reader) ([]*Timing, error) {
+	var timings []*Timing
+	step := 0
+	command := ""
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if m := stepLinePattern.FindStringSubmatch(line); m != nil {
+			step, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if rest, ok := cutCommand(line); ok {
+			command = rest
+			continue
+		}
+
+		duration, ok, err := parseDuration(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if !ok {
+			continue // Command output, user and sys times
+		}
+		if command == "" {
+			return nil, fmt.Errorf("line %d: timing without a command before it", lineNum)
+		}
+		timings = append(timings, &Timing{Step: step, Command: command, Operation: OperationName(command), Duration: duration})
+		command = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read timings: %w", err)
+	}
+	return timings, nil
+}
+
+// cutCommand returns the command of a line echoed by a script ("$ ") or traced by bash -x ("+ ")
+func cutCommand(line string) (string, bool) {
+	for _, prompt := range []string{"$ ", "+ ", "++ "} {
+		if rest, ok := strings.CutPrefix(line, prompt); ok {
+			rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), "time "))
+			return rest, rest != ""
+		}
+	}
+	return "", false
+}
+
+// parseDuration reads the elapsed time of a line written by bash's time or GNU time
+func parseDuration(line string) (time.Duration, bool, error) {
+	if m := realPattern.FindStringSubmatch(line); m != nil {
+		minutes, _ := strconv.Atoi("0" + m[1])
+		seconds, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid time '%s'", line)
+		}
+		return secondsToDuration(float64(minutes)*60 + seconds), true, nil
+	}
+	if m := gnuTimePattern.FindStringSubmatch(line); m != nil {
+		hours, _ := strconv.Atoi("0" + m[1])
+		minutes, _ := strconv.Atoi(m[2])
+		seconds, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid time '%s'", line)
+		}
+		return secondsToDuration(float64(hours*3600+minutes*60) + seconds), true, nil
+	}
+	return 0, false, nil
+}
+
+// secondsToDuration converts seconds to a duration, to the millisecond the scripts recorded
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Round(seconds*1000)) * time.Millisecond
+}
+
+// OperationName maps a command line to the operation name lfst-scenario records for it:
+// "git lfs track '*.pdf'" is lfs-track, "git -C repo2 push origin" is push, and commands
+// other than git are named after their program
+func OperationName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+	if filepath.Base(fields[0]) != "git" {
+		return filepath.Base(fields[0])
+	}
+
+	// Skip git's own options, some of which take a value
+	args := fields[1:]
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		if args[0] == "-C" || args[0] == "-c" {
+			args = args[1:]
+		}
+		args = args[1:]
+	}
+	switch {
+	case len(args) == 0:
+		return "git"
+	case args[0] == "lfs" && len(args) > 1:
+		return "lfs-" + args[1]
+	case args[0] == "remote" && len(args) > 1 && args[1] == "add":
+		return "add-remote"
+	default:
+		return args[0]
+	}
+}
+
+// ParseChecksums reads the files of a checksum file; paths lose a leading "./"
+func ParseChecksums(r io.Reader) ([]*checksum.FileChecksum, error) {
+	var checksums []*checksum.FileChecksum
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := checksumPattern.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: expected 'CRC32 [SIZE] PATH', got '%s'", lineNum, line)
+		}
+		crc, err := strconv.ParseUint(m[1], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid CRC32 '%s'", lineNum, m[1])
+		}
+		var size int64
+		if m[2] != "" {
+			size, _ = strconv.ParseInt(m[2], 10, 64)
+		}
+		checksums = append(checksums, &checksum.FileChecksum{
+			Path:      strings.TrimPrefix(m[3], "./"),
+			CRC32:     uint32(crc),
+			SizeBytes: size,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+	return checksums, nil
+}
+
+// Options describe the run the legacy results came from, which the files do not record
+type Options struct {
+	ScenarioID int
+	ServerType string
+	Protocol   string
+	GitServer  string
+	// StartedAt is when the run started; zero means the modification time of the
+	// oldest file
+	StartedAt time.Time
+}
+
+// Result holds the legacy results of a directory
+type Result struct {
+	Run       *database.TestRun // Not stored yet when returned by Read
+	Timings   map[int][]*Timing // By step
+	Checksums map[int][]*checksum.FileChecksum
+	Skipped   []string // Files that are neither timings nor checksums, or have no step
+}
+
+// Steps returns the steps with timings or checksums, in order
+func (r *Result) Steps() []int {
+	seen := make(map[int]bool)
+	for step := range r.Timings {
+		seen[step] = true
+	}
+	for step := range r.Checksums {
+		seen[step] = true
+	}
+	steps := make([]int, 0, len(seen))
+	for step := range seen {
+		steps = append(steps, step)
+	}
+	sort.Ints(steps)
+	return steps
+}
+
+// Read parses the legacy result files in dir without storing anything
+func Read(dir string, opts Options) (*Result, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	result := &Result{
+		Timings:   make(map[int][]*Timing),
+		Checksums: make(map[int][]*checksum.FileChecksum),
+	}
+	var oldest time.Time
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		if info, err := entry.Info(); err == nil && (oldest.IsZero() || info.ModTime().Before(oldest)) {
+			oldest = info.ModTime()
+		}
+
+		step := 0
+		if m := stepPattern.FindStringSubmatch(name); m != nil {
+			step, _ = strconv.Atoi(m[1])
+		}
+		lower := strings.ToLower(name)
+		switch {
+		case strings.Contains(lower, "checksum") || strings.Contains(lower, "crc"):
+			if step <= 0 {
+				result.Skipped = append(result.Skipped, name)
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			checksums, err := ParseChecksums(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			result.Checksums[step] = append(result.Checksums[step], checksums...)
+		case strings.Contains(lower, "tim"):
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			timings, err := ParseTimings(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			for _, timing := range timings {
+				if timing.Step == 0 {
+					timing.Step = step
+				}
+				if timing.Step <= 0 {
+					return nil, fmt.Errorf("%s: '%s' belongs to no step; name the file after its step or add a 'Step N' line", name, timing.Command)
+				}
+				result.Timings[timing.Step] = append(result.Timings[timing.Step], timing)
+			}
+		default:
+			result.Skipped = append(result.Skipped, name)
+		}
+	}
+	if len(result.Timings) == 0 && len(result.Checksums) == 0 {
+		return nil, fmt.Errorf("no timing or checksum files found in %s", dir)
+	}
+
+	startedAt := opts.StartedAt
+	if startedAt.IsZero() {
+		startedAt = oldest
+	}
+	gitServer := opts.GitServer
+	if gitServer == "" {
+		gitServer = "bare"
+	}
+	result.Run = &database.TestRun{
+		ScenarioID: opts.ScenarioID,
+		ServerType: opts.ServerType,
+		Protocol:   opts.Protocol,
+		GitServer:  gitServer,
+		StartedAt:  startedAt,
+		Status:     "completed",
+		Notes:      fmt.Sprintf("Imported from legacy bash-script results in %s", dir),
+	}
+	return result, nil
+}
+
+// Import stores the legacy results in dir as a completed run with its operations, step
+// results and checksums. Operations get start times one after the other from the start
+// of the run, as the scripts ran them. If storing fails, nothing is left behind.
+func Import(db *database.DB, dir string, opts Options) (*Result, error) {
+	result, err := Read(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := store(db, result); err != nil {
+		if result.Run.ID != 0 {
+			db.DeleteTestRuns([]int64{result.Run.ID})
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// store writes a parsed result to the database
+func store(db *database.DB, result *Result) error {
+	run := result.Run
+	if err := db.CreateTestRun(run); err != nil {
+		return err
+	}
+
+	at := run.StartedAt
+	for _, step := range result.Steps() {
+		stepStart := at
+		for _, timing := range result.Timings[step] {
+			op := &database.Operation{
+				RunID:      run.ID,
+				StepNumber: step,
+				Operation:  timing.Operation,
+				StartedAt:  at,
+				DurationMs: timing.Duration.Milliseconds(),
+				Status:     "success",
+			}
+			if err := db.CreateOperation(op); err != nil {
+				return err
+			}
+			if err := db.SaveOperationLog(&database.OperationLog{OperationID: op.ID, Command: timing.Command}); err != nil {
+				return err
+			}
+			at = at.Add(timing.Duration)
+		}
+
+		if checksums := result.Checksums[step]; len(checksums) > 0 {
+			if err := checksum.StoreChecksums(db, run.ID, step, checksums, checksum.ConflictReplace); err != nil {
+				return fmt.Errorf("failed to store the checksums of step %d: %w", step, err)
+			}
+		}
+
+		completedAt := at
+		name := ""
+		if step >= 1 && step <= len(scenario.DefaultPipeline) {
+			name = scenario.DefaultPipeline[step-1]
+		}
+		if err := db.SaveStepResult(&database.StepResult{
+			RunID:       run.ID,
+			StepNumber:  step,
+			Name:        name,
+			Status:      "completed",
+			StartedAt:   stepStart,
+			CompletedAt: &completedAt,
+			DurationMs:  completedAt.Sub(stepStart).Milliseconds(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	run.CompletedAt = &at
+	return db.UpdateTestRun(run)
+}
@@ -0,0 +1,160 @@
+package legacy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestParseTimings(t *testing.T) {
+	input := `=== Step 2 ===
+$ time git add .
+
+real	0m3.241s
+user	0m1.203s
+sys	0m0.512s
++ git commit -m 'Initial commit'
+[master (root-commit) 1a2b3c4] Initial commit
+real 0.57
+Step 3: modifications
+$ git push origin master
+0.50user 0.10system 1:02.50elapsed 96%CPU (0avgtext+0avgdata 5120maxresident)k
+`
+	timings, err := ParseTimings(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseTimings failed: %v", err)
+	}
+	want := []Timing{
+		{Step: 2, Command: "git add .", Operation: "add", Duration: 3241 * time.Millisecond},
+		{Step: 2, Command: "git commit -m 'Initial commit'", Operation: "commit", Duration: 570 * time.Millisecond},
+		{Step: 3, Command: "git push origin master", Operation: "push", Duration: 62500 * time.Millisecond},
+	}
+	if len(timings) != len(want) {
+		t.Fatalf("got %d timings, want %d", len(timings), len(want))
+	}
+	for i, timing := range timings {
+		if *timing != want[i] {
+			t.Errorf("timing %d = %+v, want %+v", i, *timing, want[i])
+		}
+	}
+
+	if _, err := ParseTimings(strings.NewReader("real 0m1.000s\n")); err == nil {
+		t.Error("expected an error for a timing without a command")
+	}
+}
+
+func TestOperationName(t *testing.T) {
+	tests := map[string]string{
+		"git lfs track '*.pdf'":          "lfs-track",
+		"git -C repo2 push origin main":  "push",
+		"git -c user.name=x commit -m x": "commit",
+		"git remote add origin ../srv":   "add-remote",
+		"/usr/bin/git clone url repo2":   "clone",
+		"rsync -a v1/ repo1/":            "rsync",
+	}
+	for command, want := range tests {
+		if got := OperationName(command); got != want {
+			t.Errorf("OperationName(%q) = %s, want %s", command, got, want)
+		}
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	input := "# crc32 output\n5a4e1f2b\t./pdf1.pdf\n0000abcd 1048576 video 1.mov\n"
+	checksums, err := ParseChecksums(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseChecksums failed: %v", err)
+	}
+	if len(checksums) != 2 {
+		t.Fatalf("got %d checksums, want 2", len(checksums))
+	}
+	if c := checksums[0]; c.Path != "pdf1.pdf" || c.CRC32 != 0x5a4e1f2b || c.SizeBytes != 0 {
+		t.Errorf("first checksum = %+v", c)
+	}
+	if c := checksums[1]; c.Path != "video 1.mov" || c.CRC32 != 0xabcd || c.SizeBytes != 1048576 {
+		t.Errorf("second checksum = %+v", c)
+	}
+
+	if _, err := ParseChecksums(strings.NewReader("not a checksum\n")); err == nil {
+		t.Error("expected an error for a malformed line")
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"step1-checksums.txt": "11111111 100 a.pdf\n22222222 200 b.zip\n",
+		"step2-timing.txt":    "$ git add .\nreal 0m2.000s\n$ git commit -m v1\nreal 0m1.000s\n",
+		"step2-checksums.txt": "11111111 100 a.pdf\n22222222 200 b.zip\n",
+		"notes.txt":           "Ran on gojira\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	started := time.Date(2023, 5, 1, 14, 0, 0, 0, time.UTC)
+	result, err := Import(db, dir, Options{ScenarioID: 6, ServerType: "lfs-test-server", Protocol: "http", StartedAt: started})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "notes.txt" {
+		t.Errorf("Skipped = %v, want [notes.txt]", result.Skipped)
+	}
+
+	run, err := db.GetTestRun(result.Run.ID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if run.Status != "completed" || run.GitServer != "bare" || !run.StartedAt.Equal(started) {
+		t.Errorf("run = %+v", run)
+	}
+	if run.CompletedAt == nil || run.CompletedAt.Sub(started) != 3*time.Second {
+		t.Errorf("CompletedAt = %v, want 3s after the start", run.CompletedAt)
+	}
+
+	ops, err := db.ListOperations(run.ID)
+	if err != nil {
+		t.Fatalf("ListOperations failed: %v", err)
+	}
+	if len(ops) != 2 || ops[0].Operation != "add" || ops[0].DurationMs != 2000 || ops[1].Operation != "commit" {
+		t.Fatalf("operations = %+v", ops)
+	}
+	if !ops[1].StartedAt.Equal(started.Add(2 * time.Second)) {
+		t.Errorf("commit started at %v, want right after add", ops[1].StartedAt)
+	}
+	logs, err := db.ListOperationLogs(run.ID, 2)
+	if err != nil {
+		t.Fatalf("ListOperationLogs failed: %v", err)
+	}
+	if logs[ops[0].ID] == nil || logs[ops[0].ID].Command != "git add ." {
+		t.Errorf("log of add = %+v", logs[ops[0].ID])
+	}
+
+	checksums, err := db.ListChecksums(run.ID, 1)
+	if err != nil {
+		t.Fatalf("ListChecksums failed: %v", err)
+	}
+	if len(checksums) != 2 || checksums[0].CRC32 != "11111111" {
+		t.Errorf("step 1 checksums = %+v", checksums)
+	}
+
+	steps, err := db.ListStepResults(run.ID)
+	if err != nil {
+		t.Fatalf("ListStepResults failed: %v", err)
+	}
+	if len(steps) != 2 || steps[0].Name != "setup" || steps[1].Name != "push" || steps[1].DurationMs != 3000 {
+		t.Errorf("step results = %+v", steps)
+	}
+}
@@ -0,0 +1,245 @@
+package lfsproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Proxy forwards Git LFS API requests from git-lfs to an LFS server and reports every
+// Batch API exchange to a Recorder. Point lfs.url at URL to route git-lfs through it.
+// Transfers go straight to the hrefs the server returns, so only the API passes through.
+type Proxy struct {
+	URL      string // Base URL of the proxy, to configure as lfs.url
+	Upstream *url.URL
+	Recorder Recorder
+
+	server   *http.Server
+	listener net.Listener
+	proxy    *httputil.ReverseProxy
+}
+
+// Recorder receives each Batch API exchange once the response has been relayed
+type Recorder func(exchange *Exchange)
+
+// Exchange is one Batch API request and the server's response
+type Exchange struct {
+	Operation  string   // 'upload' or 'download'
+	Transfers  []string // Transfer adapters offered by the client
+	Ref        string   // Ref the objects belong to, if the client sent one
+	Objects    []*Object
+	StatusCode int
+	Transfer   string // Transfer adapter chosen by the server
+	Message    string // Error message of a failed request
+	StartedAt  time.Time
+	Duration   time.Duration
+}
+
+// Object is one requested object with the server's answer for it
+type Object struct {
+	OID          string
+	Size         int64
+	Actions      []string // Action names in the response, e.g. 'upload', 'verify'
+	Href         string   // URL of the upload or download action
+	VerifyHref   string   // URL of the verify action
+	ExpiresIn    int      // Seconds the actions stay valid
+	ErrorCode    int
+	ErrorMessage string
+}
+
+// Batch API request and response bodies, reduced to what an Exchange records
+type batchRequest struct {
+	Operation string   `json:"operation"`
+	Transfers []string `json:"transfers"`
+	Ref       *struct {
+		Name string `json:"name"`
+	} `json:"ref"`
+	Objects []struct {
+		OID  string `json:"oid"`
+		Size int64  `json:"size"`
+	} `json:"objects"`
+}
+
+type batchResponse struct {
+	Transfer string `json:"transfer"`
+	Message  string `json:"message"`
+	Objects  []struct {
+		OID     string `json:"oid"`
+		Size    int64  `json:"size"`
+		Actions map[string]struct {
+			Href      string `json:"href"`
+			ExpiresIn int    `json:"expires_in"`
+		} `json:"actions"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"objects"`
+}
+
+// actionOrder lists Batch API actions in the order a transfer uses them
+var actionOrder = []string{"download", "upload", "verify"}
+
+// Start listens on a free local port and proxies to the LFS server at upstream,
+// e.g. "http://gojira:8079". Call Close when done.
+func Start(upstream string, recorder Recorder) (*Proxy, error) {
+	target, err := url.Parse(upstream)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("LFS proxy needs an http(s) server URL, got '%s'", upstream)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start LFS proxy: %w", err)
+	}
+
+	p := &Proxy{
+		URL:      "http://" + listener.Addr().String(),
+		Upstream: target,
+		Recorder: recorder,
+		listener: listener,
+	}
+	p.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			// Let the transport decompress responses, so batch responses can be parsed
+			pr.Out.Header.Del("Accept-Encoding")
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			w.Header().Set("Content-Type", "application/vnd.git-lfs+json")
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]string{"message": "LFS proxy: " + err.Error()})
+		},
+	}
+	p.server = &http.Server{Handler: http.HandlerFunc(p.handle)}
+
+	go p.server.Serve(listener)
+	return p, nil
+}
+
+// Close stops the proxy
+func (p *Proxy) Close() error {
+	if err := p.server.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// isBatch reports whether req is a Batch API request (POST <lfs.url>/objects/batch)
+func isBatch(req *http.Request) bool {
+	return req.Method == http.MethodPost && strings.HasSuffix(strings.TrimSuffix(req.URL.Path, "/"), "/objects/batch")
+}
+
+// handle relays a request, capturing Batch API requests and responses for the Recorder
+func (p *Proxy) handle(w http.ResponseWriter, req *http.Request) {
+	if !isBatch(req) || p.Recorder == nil {
+		p.proxy.ServeHTTP(w, req)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	capture := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+	start := time.Now()
+	p.proxy.ServeHTTP(capture, req)
+
+	exchange := parseExchange(body, capture.body.Bytes(), capture.status)
+	exchange.StartedAt = start
+	exchange.Duration = time.Since(start)
+	p.Recorder(exchange)
+}
+
+// parseExchange builds an Exchange from the request and response bodies. Bodies that
+// are not Batch API JSON leave their fields empty, and the response's text becomes the message.
+func parseExchange(reqBody, respBody []byte, status int) *Exchange {
+	exchange := &Exchange{StatusCode: status}
+
+	var request batchRequest
+	if json.Unmarshal(reqBody, &request) == nil {
+		exchange.Operation = request.Operation
+		exchange.Transfers = request.Transfers
+		if request.Ref != nil {
+			exchange.Ref = request.Ref.Name
+		}
+		for _, obj := range request.Objects {
+			exchange.Objects = append(exchange.Objects, &Object{OID: obj.OID, Size: obj.Size})
+		}
+	}
+
+	var response batchResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		if status >= 300 {
+			exchange.Message = strings.TrimSpace(string(respBody))
+		}
+		return exchange
+	}
+	exchange.Transfer = response.Transfer
+	exchange.Message = response.Message
+
+	byOID := make(map[string]*Object)
+	for _, obj := range exchange.Objects {
+		byOID[obj.OID] = obj
+	}
+	for _, answer := range response.Objects {
+		obj := byOID[answer.OID]
+		if obj == nil {
+			// The server answered for an object that was not requested
+			obj = &Object{OID: answer.OID, Size: answer.Size}
+			exchange.Objects = append(exchange.Objects, obj)
+		}
+		for _, name := range actionOrder {
+			action, ok := answer.Actions[name]
+			if !ok {
+				continue
+			}
+			obj.Actions = append(obj.Actions, name)
+			obj.ExpiresIn = action.ExpiresIn
+			if name == "verify" {
+				obj.VerifyHref = action.Href
+			} else {
+				obj.Href = action.Href
+			}
+		}
+		if answer.Error != nil {
+			obj.ErrorCode = answer.Error.Code
+			obj.ErrorMessage = answer.Error.Message
+		}
+	}
+	return exchange
+}
+
+// captureWriter relays a response while keeping its status and body. It drops
+// Content-Length, so the client sees the end of the response only when the handler
+// returns, after the exchange has been recorded.
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *captureWriter) WriteHeader(status int) {
+	c.status = status
+	c.Header().Del("Content-Length")
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureWriter) Write(data []byte) (int, error) {
+	if c.body.Len() == 0 {
+		c.Header().Del("Content-Length")
+	}
+	c.body.Write(data)
+	return c.ResponseWriter.Write(data)
+}
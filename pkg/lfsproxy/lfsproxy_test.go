@@ -0,0 +1,131 @@
+package lfsproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/mockserver"
+)
+
+// startProxy starts a proxy in front of a mock LFS server and collects its exchanges
+func startProxy(t *testing.T) (*mockserver.Server, *Proxy, func() []*Exchange) {
+	server := mockserver.New()
+	t.Cleanup(server.Close)
+
+	var mu sync.Mutex
+	var exchanges []*Exchange
+	proxy, err := Start(server.LFSURL(), func(e *Exchange) {
+		mu.Lock()
+		defer mu.Unlock()
+		exchanges = append(exchanges, e)
+	})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { proxy.Close() })
+
+	return server, proxy, func() []*Exchange {
+		mu.Lock()
+		defer mu.Unlock()
+		return exchanges
+	}
+}
+
+// batch sends a Batch API request through the proxy
+func batch(t *testing.T, proxy *Proxy, operation string, objects ...mockserver.ObjectSpec) *mockserver.BatchResponse {
+	body, _ := json.Marshal(map[string]any{
+		"operation": operation,
+		"transfers": []string{"basic"},
+		"ref":       map[string]string{"name": "refs/heads/main"},
+		"objects":   objects,
+	})
+	resp, err := http.Post(proxy.URL+"/objects/batch", mockserver.MediaType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("batch request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var batchResp mockserver.BatchResponse
+	json.NewDecoder(resp.Body).Decode(&batchResp)
+	return &batchResp
+}
+
+func TestProxyRecordsBatchExchanges(t *testing.T) {
+	server, proxy, exchanges := startProxy(t)
+	stored := server.AddObject([]byte("already on the server"))
+	missing := mockserver.OID([]byte("not uploaded yet"))
+
+	resp := batch(t, proxy, "upload",
+		mockserver.ObjectSpec{OID: stored, Size: 21},
+		mockserver.ObjectSpec{OID: missing, Size: 16})
+	if len(resp.Objects) != 2 {
+		t.Fatalf("the client should get the server's response through the proxy, got %+v", resp)
+	}
+	batch(t, proxy, "download", mockserver.ObjectSpec{OID: missing, Size: 16})
+
+	got := exchanges()
+	if len(got) != 2 {
+		t.Fatalf("recorded %d exchanges, want 2", len(got))
+	}
+
+	upload := got[0]
+	if upload.Operation != "upload" || upload.StatusCode != http.StatusOK || upload.Transfer != "basic" ||
+		upload.Ref != "refs/heads/main" || strings.Join(upload.Transfers, ",") != "basic" {
+		t.Errorf("upload exchange = %+v", upload)
+	}
+	if len(upload.Objects) != 2 {
+		t.Fatalf("upload objects = %+v", upload.Objects)
+	}
+	if obj := upload.Objects[0]; obj.OID != stored || len(obj.Actions) != 0 {
+		t.Errorf("an object the server has needs no actions, got %+v", obj)
+	}
+	obj := upload.Objects[1]
+	if strings.Join(obj.Actions, ",") != "upload,verify" || obj.Size != 16 || obj.ExpiresIn != 3600 ||
+		!strings.HasSuffix(obj.Href, "/objects/"+missing) || !strings.HasSuffix(obj.VerifyHref, "/verify") {
+		t.Errorf("missing object = %+v", obj)
+	}
+
+	download := got[1].Objects[0]
+	if download.ErrorCode != http.StatusNotFound || download.ErrorMessage == "" {
+		t.Errorf("download of a missing object = %+v", download)
+	}
+}
+
+func TestProxyRecordsFailures(t *testing.T) {
+	server, proxy, exchanges := startProxy(t)
+	server.FailNext(mockserver.Batch, http.StatusServiceUnavailable, 1)
+
+	batch(t, proxy, "download", mockserver.ObjectSpec{OID: mockserver.OID([]byte("x")), Size: 1})
+
+	got := exchanges()
+	if len(got) != 1 || got[0].StatusCode != http.StatusServiceUnavailable || !strings.Contains(got[0].Message, "injected") {
+		t.Errorf("failed exchange = %+v", got)
+	}
+}
+
+func TestProxyPassesOtherRequests(t *testing.T) {
+	server, proxy, exchanges := startProxy(t)
+	oid := server.AddObject([]byte("content"))
+
+	resp, err := http.Get(proxy.URL + "/objects/" + oid)
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("download status = %d", resp.StatusCode)
+	}
+	if len(exchanges()) != 0 {
+		t.Error("only Batch API requests should be recorded")
+	}
+}
+
+func TestStartRejectsNonHTTP(t *testing.T) {
+	if _, err := Start("ssh://gojira/repo.git", nil); err == nil {
+		t.Error("Start should reject a non-HTTP server URL")
+	}
+}
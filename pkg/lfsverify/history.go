@@ -0,0 +1,354 @@
+package lfsverify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// HistoryCounts compares the LFS objects referenced anywhere in a repository's history
+// with the objects in its local store and on its LFS server
+type HistoryCounts struct {
+	Referenced int // Unique OIDs referenced by any commit (git lfs ls-files --all)
+	Local      int // Referenced objects present in .git/lfs/objects
+	Server     int // Referenced objects the server has; -1 if the server could not be asked
+
+	// Files counts the paths referencing the objects, so identical content under several
+	// names counts once per name
+	Files int
+	Bytes int64            // Summed size of the referenced objects, each counted once
+	Sizes map[string]int64 // Size of each referenced object by OID
+
+	MissingLocally  []string // Referenced OIDs that are not in the local store, sorted
+	MissingOnServer []string // Referenced OIDs the server does not have, sorted
+	Endpoint        string   // LFS endpoint of the server; empty if unknown
+	ServerError     error    // Why the server could not be asked
+}
+
+// historyObject is an LFS object referenced by a commit
+type historyObject struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+	Name string `json:"name"`
+}
+
+// batchChunk is the number of objects asked about in one Batch API request
+const batchChunk = 100
+
+// CountHistoryObjects counts the LFS objects referenced in the history of repoDir, those in
+// its local store, and those its remote's LFS server reports having. Failing to ask the
+// server is not an error; it leaves Server at -1 and sets ServerError.
+func CountHistoryObjects(repoDir string) (*HistoryCounts, error) {
+	objects, files, err := historyObjects(repoDir)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := &HistoryCounts{Referenced: len(objects), Files: files, Server: -1, Sizes: make(map[string]int64)}
+	for _, obj := range objects {
+		counts.Bytes += obj.Size
+		counts.Sizes[obj.OID] = obj.Size
+		if lfsObjectExists(repoDir, obj.OID) {
+			counts.Local++
+		} else {
+			counts.MissingLocally = append(counts.MissingLocally, obj.OID)
+		}
+	}
+
+	counts.Endpoint, err = lfsEndpoint(repoDir)
+	if err != nil {
+		counts.ServerError = err
+		return counts, nil
+	}
+	has, err := serverHas(repoDir, counts.Endpoint, objects)
+	if err != nil {
+		counts.ServerError = err
+		return counts, nil
+	}
+	counts.Server = 0
+	for _, obj := range objects {
+		if has[obj.OID] {
+			counts.Server++
+		} else {
+			counts.MissingOnServer = append(counts.MissingOnServer, obj.OID)
+		}
+	}
+	return counts, nil
+}
+
+// Check reports objects the server is missing, which after a partial or interrupted push
+// leaves history that cannot be checked out, and, if requireLocal, objects missing from the
+// local store. Clones fetch only the objects of the checked-out commit, so only the
+// repository that created the history is expected to hold every object.
+func (c *HistoryCounts) Check(requireLocal bool) error {
+	var problems []string
+	if requireLocal && len(c.MissingLocally) > 0 {
+		problems = append(problems, fmt.Sprintf("%d of %d LFS objects referenced in history are missing locally: %s",
+			len(c.MissingLocally), c.Referenced, abbreviateOIDs(c.MissingLocally)))
+	}
+	if len(c.MissingOnServer) > 0 {
+		problems = append(problems, fmt.Sprintf("%d of %d LFS objects referenced in history are missing on the server: %s",
+			len(c.MissingOnServer), c.Referenced, abbreviateOIDs(c.MissingOnServer)))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// String summarizes the counts, e.g. "12 referenced in history, 12 local, 11 on the server"
+func (c *HistoryCounts) String() string {
+	server := fmt.Sprintf("%d on the server", c.Server)
+	if c.Server < 0 {
+		server = "server not asked"
+	}
+	return fmt.Sprintf("%d referenced in history, %d local, %s", c.Referenced, c.Local, server)
+}
+
+// abbreviateOIDs lists the first OIDs, shortened to 12 characters
+func abbreviateOIDs(oids []string) string {
+	const shown = 5
+	var short []string
+	for i, oid := range oids {
+		if i == shown {
+			short = append(short, fmt.Sprintf("and %d more", len(oids)-shown))
+			break
+		}
+		if len(oid) > 12 {
+			oid = oid[:12]
+		}
+		short = append(short, oid)
+	}
+	return strings.Join(short, ", ")
+}
+
+// historyObjects returns the unique LFS objects referenced by any commit of repoDir, sorted
+// by OID, and the number of distinct paths referencing them
+func historyObjects(repoDir string) ([]historyObject, int, error) {
+	result := timing.Run("git", []string{"-C", repoDir, "lfs", "ls-files", "--all", "--json"}, nil)
+	if result.Error != nil || result.ExitCode != 0 {
+		return nil, 0, fmt.Errorf("git lfs ls-files --all failed: %v %s", result.Error, strings.TrimSpace(result.Stderr))
+	}
+	return parseHistoryObjects(result.Stdout)
+}
+
+// parseHistoryObjects parses the output of git lfs ls-files --all --json
+func parseHistoryObjects(output string) ([]historyObject, int, error) {
+
+	var listing struct {
+		Files []historyObject `json:"files"`
+	}
+	if strings.TrimSpace(output) != "" {
+		if err := json.Unmarshal([]byte(output), &listing); err != nil {
+			return nil, 0, fmt.Errorf("failed to parse git lfs ls-files output: %w", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	paths := make(map[historyObject]bool)
+	var objects []historyObject
+	for _, obj := range listing.Files {
+		paths[obj] = true
+		if !seen[obj.OID] {
+			seen[obj.OID] = true
+			objects = append(objects, obj)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].OID < objects[j].OID })
+	return objects, len(paths), nil
+}
+
+// lfsEndpoint returns the LFS endpoint of repoDir's remote as git lfs env reports it
+func lfsEndpoint(repoDir string) (string, error) {
+	result := timing.Run("git", []string{"-C", repoDir, "lfs", "env"}, nil)
+	if result.Error != nil || result.ExitCode != 0 {
+		return "", fmt.Errorf("git lfs env failed: %v", result.Error)
+	}
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if endpoint, ok := strings.CutPrefix(strings.TrimSpace(line), "Endpoint="); ok {
+			endpoint, _, _ = strings.Cut(endpoint, " ")
+			if endpoint != "" {
+				return endpoint, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("the repository has no LFS endpoint")
+}
+
+// serverHas returns the OIDs among objects that the LFS server at endpoint, that of
+// repoDir's remote, has. Local (file://) remotes are looked up in their object directory;
+// HTTP servers are asked with Batch API download requests.
+func serverHas(repoDir, endpoint string, objects []historyObject) (map[string]bool, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LFS endpoint '%s': %w", endpoint, err)
+	}
+
+	has := make(map[string]bool)
+	switch u.Scheme {
+	case "file":
+		for _, obj := range objects {
+			for _, dir := range []string{"lfs", filepath.Join(".git", "lfs")} {
+				if len(obj.OID) < 4 {
+					continue
+				}
+				if _, err := os.Stat(filepath.Join(u.Path, dir, "objects", obj.OID[0:2], obj.OID[2:4], obj.OID)); err == nil {
+					has[obj.OID] = true
+				}
+			}
+		}
+	case "http", "https":
+		for start := 0; start < len(objects); start += batchChunk {
+			end := min(start+batchChunk, len(objects))
+			if err := batchDownload(repoDir, endpoint, objects[start:end], has); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("cannot ask an LFS server at %s", endpoint)
+	}
+	return has, nil
+}
+
+// batchDownload asks the LFS server at endpoint for download actions for objects and
+// marks those it offers to send in has. It sends the http.extraHeader git configuration
+// for the endpoint, and the credentials of git's credential helpers if the server asks.
+func batchDownload(repoDir, endpoint string, objects []historyObject, has map[string]bool) error {
+	type object struct {
+		OID  string `json:"oid"`
+		Size int64  `json:"size"`
+	}
+	request := struct {
+		Operation string   `json:"operation"`
+		Transfers []string `json:"transfers"`
+		Objects   []object `json:"objects"`
+	}{Operation: "download", Transfers: []string{"basic"}}
+	for _, obj := range objects {
+		request.Objects = append(request.Objects, object{OID: obj.OID, Size: obj.Size})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	batchURL := strings.TrimSuffix(endpoint, "/") + "/objects/batch"
+	client := &http.Client{Timeout: time.Minute}
+	var user, password string
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, batchURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/vnd.git-lfs+json")
+		req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+		for _, header := range extraHeaders(repoDir, endpoint) {
+			if name, value, ok := strings.Cut(header, ":"); ok {
+				req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+			}
+		}
+		if user != "" || password != "" {
+			req.SetBasicAuth(user, password)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("batch request to %s failed: %w", batchURL, err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			resp.Body.Close()
+			if user, password, err = credentials(repoDir, endpoint); err != nil {
+				return err
+			}
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("batch request to %s failed: %s", batchURL, resp.Status)
+		}
+
+		var response struct {
+			Objects []struct {
+				OID     string                     `json:"oid"`
+				Actions map[string]json.RawMessage `json:"actions"`
+				Error   *struct {
+					Code int `json:"code"`
+				} `json:"error"`
+			} `json:"objects"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return fmt.Errorf("failed to parse batch response of %s: %w", batchURL, err)
+		}
+		for _, obj := range response.Objects {
+			if obj.Error == nil && obj.Actions["download"] != nil {
+				has[obj.OID] = true
+			}
+		}
+		return nil
+	}
+}
+
+// extraHeaders returns the http.extraHeader values git applies to requests to endpoint
+func extraHeaders(repoDir, endpoint string) []string {
+	result := timing.Run("git", []string{"-C", repoDir, "config", "--get-urlmatch", "http.extraheader", endpoint}, nil)
+	if result.Error != nil || result.ExitCode != 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(result.Stdout), "\n")
+}
+
+// credentials asks git's credential helpers for the user name and password of endpoint
+func credentials(repoDir, endpoint string) (string, string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+	input := fmt.Sprintf("protocol=%s\nhost=%s\npath=%s\n\n", u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/"))
+	cmd := exec.Command("git", "-C", repoDir, "credential", "fill")
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("the LFS server at %s requires credentials, and git has none", endpoint)
+	}
+
+	var user, password string
+	for _, line := range strings.Split(string(out), "\n") {
+		if value, ok := strings.CutPrefix(line, "username="); ok {
+			user = value
+		}
+		if value, ok := strings.CutPrefix(line, "password="); ok {
+			password = value
+		}
+	}
+	return user, password, nil
+}
+
+// VerifyHistoryObjects counts the LFS objects of repoDir's history, locally and on the
+// server, and returns the counts with the result of their Check
+func VerifyHistoryObjects(repoDir string, requireLocal bool) (*HistoryCounts, error) {
+	log.Debugf("  Counting LFS objects referenced in history...\n")
+	counts, err := CountHistoryObjects(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	if counts.ServerError != nil {
+		log.Debugf("    Could not ask the server: %v\n", counts.ServerError)
+	}
+	if err := counts.Check(requireLocal); err != nil {
+		return counts, err
+	}
+	log.Debugf("    %s LFS objects: %s\n", term.OK(), counts)
+	return counts, nil
+}
@@ -2,14 +2,21 @@ package lfsverify
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
 	"github.com/mslinn/git-lfs-test/pkg/timing"
+	"github.com/mslinn/git-lfs-test/pkg/workerpool"
 )
 
 // VerificationResult contains the results of LFS verification
@@ -29,18 +36,14 @@ type VerificationResult struct {
 func VerifyLFSStatus(repoDir string, expectedFiles []string, debug bool) (*VerificationResult, error) {
 	result := &VerificationResult{}
 
-	if debug {
-		fmt.Println("  Verifying LFS status...")
-	}
+	log.Debugf("  Verifying LFS status...\n")
 
 	// Check if LFS is installed in the repo
 	gitDir := filepath.Join(repoDir, ".git")
 	lfsDir := filepath.Join(gitDir, "lfs")
 	if _, err := os.Stat(lfsDir); err == nil {
 		result.IsLFSEnabled = true
-		if debug {
-			fmt.Println("    ✓ LFS is enabled in repository")
-		}
+		log.Debugf("    %s LFS is enabled in repository\n", term.OK())
 	} else {
 		result.Errors = append(result.Errors, "LFS not enabled in repository")
 		return result, fmt.Errorf("LFS not enabled in repository")
@@ -52,9 +55,7 @@ func VerifyLFSStatus(repoDir string, expectedFiles []string, debug bool) (*Verif
 		result.Errors = append(result.Errors, fmt.Sprintf("Failed to get LFS tracked files: %v", err))
 	} else {
 		result.TrackedFiles = trackedFiles
-		if debug {
-			fmt.Printf("    ✓ %d files tracked by LFS\n", len(trackedFiles))
-		}
+		log.Debugf("    %s %d files tracked by LFS\n", term.OK(), len(trackedFiles))
 	}
 
 	// Count and measure LFS objects
@@ -64,9 +65,7 @@ func VerifyLFSStatus(repoDir string, expectedFiles []string, debug bool) (*Verif
 	} else {
 		result.LFSObjectCount = objectCount
 		result.LFSObjectsSize = objectSize
-		if debug {
-			fmt.Printf("    ✓ %d LFS objects (%.2f MB)\n", objectCount, float64(objectSize)/1024/1024)
-		}
+		log.Debugf("    %s %d LFS objects (%.2f MB)\n", term.OK(), objectCount, float64(objectSize)/1024/1024)
 	}
 
 	// Measure git objects size
@@ -75,9 +74,7 @@ func VerifyLFSStatus(repoDir string, expectedFiles []string, debug bool) (*Verif
 		result.Errors = append(result.Errors, fmt.Sprintf("Failed to measure git objects: %v", err))
 	} else {
 		result.GitObjectsSize = gitObjectSize
-		if debug {
-			fmt.Printf("    ✓ Git objects size: %.2f MB\n", float64(gitObjectSize)/1024/1024)
-		}
+		log.Debugf("    %s Git objects size: %.2f MB\n", term.OK(), float64(gitObjectSize)/1024/1024)
 	}
 
 	// Verify expected files are LFS pointers
@@ -86,9 +83,7 @@ func VerifyLFSStatus(repoDir string, expectedFiles []string, debug bool) (*Verif
 		result.PointerFiles = pointers
 		result.NonPointerFiles = nonPointers
 
-		if debug {
-			fmt.Printf("    ✓ %d/%d files are LFS pointers\n", len(pointers), len(expectedFiles))
-		}
+		log.Debugf("    %s %d/%d files are LFS pointers\n", term.OK(), len(pointers), len(expectedFiles))
 
 		if len(nonPointers) > 0 {
 			result.Errors = append(result.Errors, fmt.Sprintf("%d files are not LFS pointers: %v", len(nonPointers), nonPointers))
@@ -294,9 +289,7 @@ func lfsObjectExists(repoDir, oid string) bool {
 // Uses git lfs ls-files to check what's actually tracked, since working directory
 // files are always expanded (not pointers)
 func VerifyLFSPointers(repoDir string, files []string, debug bool) error {
-	if debug {
-		fmt.Printf("  Verifying %d files are tracked by LFS...\n", len(files))
-	}
+	log.Debugf("  Verifying %d files are tracked by LFS...\n", len(files))
 
 	// Get list of LFS-tracked files from git
 	trackedFiles, err := getLFSTrackedFiles(repoDir)
@@ -323,13 +316,114 @@ func VerifyLFSPointers(repoDir string, files []string, debug bool) error {
 			len(files), len(notTracked), notTracked)
 	}
 
-	if debug {
-		fmt.Printf("    ✓ All %d files are tracked by LFS\n", len(files))
+	log.Debugf("    %s All %d files are tracked by LFS\n", term.OK(), len(files))
+
+	return nil
+}
+
+// VerifyLFSContent recomputes the SHA-256 of every LFS-tracked working file and checks
+// that it matches the OID in its pointer. Files are hashed in parallel by a worker pool.
+func VerifyLFSContent(repoDir string, opts workerpool.Options, debug bool) error {
+	log.Debugf("  Verifying content of LFS files against pointer OIDs...\n")
+
+	oids, err := getLFSTrackedOIDs(repoDir)
+	if err != nil {
+		return fmt.Errorf("failed to get LFS tracked files: %w", err)
+	}
+
+	mismatched, err := verifyOIDs(repoDir, oids, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(mismatched) > 0 {
+		return fmt.Errorf("%d of %d LFS files do not match their pointer OIDs: %v",
+			len(mismatched), len(oids), mismatched)
 	}
 
+	log.Debugf("    %s All %d LFS files match their pointer OIDs\n", term.OK(), len(oids))
+
 	return nil
 }
 
+// getLFSTrackedOIDs maps each file tracked by LFS to its full OID using git lfs ls-files --long
+func getLFSTrackedOIDs(repoDir string) (map[string]string, error) {
+	result := timing.Run("git", []string{"-C", repoDir, "lfs", "ls-files", "--long"}, nil)
+	if result.Error != nil || result.ExitCode != 0 {
+		return nil, fmt.Errorf("git lfs ls-files failed: %v", result.Error)
+	}
+
+	// Lines look like "<oid> * path" ('*' if the object is present locally, '-' if not)
+	oids := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(result.Stdout))
+	for scanner.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 3)
+		if len(fields) == 3 {
+			oids[fields[2]] = fields[0]
+		}
+	}
+
+	return oids, nil
+}
+
+// verifyOIDs hashes the working files named in oids and returns those whose SHA-256
+// differs from the expected OID, in sorted order. Deleted files are skipped.
+func verifyOIDs(repoDir string, oids map[string]string, opts workerpool.Options) ([]string, error) {
+	var files []string
+	var totalBytes int64
+	for file := range oids {
+		info, err := os.Stat(filepath.Join(repoDir, file))
+		if err != nil {
+			continue
+		}
+		files = append(files, file)
+		totalBytes += info.Size()
+	}
+	sort.Strings(files)
+
+	matches := make([]bool, len(files))
+	progress := workerpool.NewProgress(opts.Progress, "Verifying", len(files), totalBytes)
+	err := workerpool.Run(len(files), opts, func(i int) error {
+		oid, size, err := hashFile(filepath.Join(repoDir, files[i]))
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %w", files[i], err)
+		}
+		matches[i] = oid == oids[files[i]]
+		progress.Add(size)
+		return nil
+	})
+	if len(files) > 0 {
+		progress.Finish()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatched []string
+	for i, file := range files {
+		if !matches[i] {
+			mismatched = append(mismatched, file)
+		}
+	}
+	return mismatched, nil
+}
+
+// hashFile returns the SHA-256 (LFS OID) and size of a file
+func hashFile(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
 // VerifyLFSObjects verifies that LFS objects exist for tracked files
 func VerifyLFSObjects(repoDir string, expectedCount int, debug bool) error {
 	gitDir := filepath.Join(repoDir, ".git")
@@ -338,18 +432,14 @@ func VerifyLFSObjects(repoDir string, expectedCount int, debug bool) error {
 		return fmt.Errorf("failed to count LFS objects: %w", err)
 	}
 
-	if debug {
-		fmt.Printf("  Verifying LFS objects...\n")
-		fmt.Printf("    Found %d LFS objects (%.2f MB)\n", count, float64(size)/1024/1024)
-	}
+	log.Debugf("  Verifying LFS objects...\n")
+	log.Debugf("    Found %d LFS objects (%.2f MB)\n", count, float64(size)/1024/1024)
 
 	if count < expectedCount {
 		return fmt.Errorf("expected at least %d LFS objects, found %d", expectedCount, count)
 	}
 
-	if debug {
-		fmt.Printf("    ✓ LFS objects exist (%d >= %d expected)\n", count, expectedCount)
-	}
+	log.Debugf("    %s LFS objects exist (%d >= %d expected)\n", term.OK(), count, expectedCount)
 
 	return nil
 }
@@ -357,17 +447,13 @@ func VerifyLFSObjects(repoDir string, expectedCount int, debug bool) error {
 // VerifyNotLFSPointers verifies that files are NOT tracked by LFS (after untracking)
 // Uses git lfs ls-files to verify files are no longer tracked
 func VerifyNotLFSPointers(repoDir string, files []string, debug bool) error {
-	if debug {
-		fmt.Printf("  Verifying %d files are NOT tracked by LFS...\n", len(files))
-	}
+	log.Debugf("  Verifying %d files are NOT tracked by LFS...\n", len(files))
 
 	// Get list of LFS-tracked files from git
 	trackedFiles, err := getLFSTrackedFiles(repoDir)
 	if err != nil {
 		// If git lfs ls-files fails or returns empty, that's expected after untracking
-		if debug {
-			fmt.Printf("    ✓ No files tracked by LFS (successfully migrated out)\n")
-		}
+		log.Debugf("    %s No files tracked by LFS (successfully migrated out)\n", term.OK())
 		return nil
 	}
 
@@ -390,9 +476,7 @@ func VerifyNotLFSPointers(repoDir string, files []string, debug bool) error {
 			len(stillTracked), stillTracked)
 	}
 
-	if debug {
-		fmt.Printf("    ✓ No files tracked by LFS (successfully migrated out)\n")
-	}
+	log.Debugf("    %s No files tracked by LFS (successfully migrated out)\n", term.OK())
 
 	return nil
 }
@@ -453,11 +537,9 @@ func VerifyRepositorySizes(repoDir string, debug bool) error {
 		return fmt.Errorf("failed to measure LFS objects: %w", err)
 	}
 
-	if debug {
-		fmt.Printf("  Repository size comparison:\n")
-		fmt.Printf("    Git objects: %.2f MB\n", float64(gitObjectsSize)/1024/1024)
-		fmt.Printf("    LFS objects: %.2f MB\n", float64(lfsObjectsSize)/1024/1024)
-	}
+	log.Debugf("  Repository size comparison:\n")
+	log.Debugf("    Git objects: %.2f MB\n", float64(gitObjectsSize)/1024/1024)
+	log.Debugf("    LFS objects: %.2f MB\n", float64(lfsObjectsSize)/1024/1024)
 
 	// LFS objects should be significantly larger than git objects
 	// If git objects are larger, LFS probably isn't working
@@ -466,9 +548,7 @@ func VerifyRepositorySizes(repoDir string, debug bool) error {
 			float64(gitObjectsSize)/1024/1024, float64(lfsObjectsSize)/1024/1024)
 	}
 
-	if debug {
-		fmt.Printf("    ✓ Repository sizes are correct (LFS objects > git objects)\n")
-	}
+	log.Debugf("    %s Repository sizes are correct (LFS objects > git objects)\n", term.OK())
 
 	return nil
 }
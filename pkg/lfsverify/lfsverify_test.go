@@ -0,0 +1,124 @@
+package lfsverify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/workerpool"
+)
+
+func oidOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestVerifyOIDs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"good.bin":        "expected content",
+		"sub/corrupt.bin": "corrupted content",
+		"sub/another.bin": "more content",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	oids := map[string]string{
+		"good.bin":        oidOf("expected content"),
+		"sub/corrupt.bin": oidOf("original content"),
+		"sub/another.bin": oidOf("more content"),
+		"deleted.bin":     oidOf("gone"),
+	}
+
+	mismatched, err := verifyOIDs(dir, oids, workerpool.Options{Workers: 2})
+	if err != nil {
+		t.Fatalf("verifyOIDs failed: %v", err)
+	}
+	if len(mismatched) != 1 || mismatched[0] != "sub/corrupt.bin" {
+		t.Errorf("Mismatched = %v, want [sub/corrupt.bin]", mismatched)
+	}
+}
+
+func TestBatchDownload(t *testing.T) {
+	stored, lost := oidOf("stored"), oidOf("lost")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/repo.git/info/lfs/objects/batch" || req.Header.Get("X-Token") != "secret" {
+			http.Error(w, "unexpected request", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.git-lfs+json")
+		fmt.Fprintf(w, `{"transfer":"basic","objects":[
+			{"oid":"%s","size":6,"actions":{"download":{"href":"http://example.com/%s"}}},
+			{"oid":"%s","size":4,"error":{"code":404,"message":"Object does not exist"}}]}`, stored, stored, lost)
+	}))
+	defer server.Close()
+
+	// The header reaches the request from the repository's git configuration, as for git-lfs
+	repo := t.TempDir()
+	endpoint := server.URL + "/repo.git/info/lfs"
+	for _, args := range [][]string{{"init", "-q"}, {"config", "http." + server.URL + ".extraHeader", "X-Token: secret"}} {
+		if out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Skipf("git %v failed: %v %s", args, err, out)
+		}
+	}
+
+	has := make(map[string]bool)
+	objects := []historyObject{{OID: stored, Size: 6}, {OID: lost, Size: 4}}
+	if err := batchDownload(repo, endpoint, objects, has); err != nil {
+		t.Fatalf("batchDownload failed: %v", err)
+	}
+	if !has[stored] || has[lost] {
+		t.Errorf("batchDownload found %v, want only %s", has, stored)
+	}
+}
+
+func TestParseHistoryObjects(t *testing.T) {
+	a, b := oidOf("a"), oidOf("b")
+	output := `{"files": [
+		{"name": "v1/a.bin", "size": 1, "oid": "` + a + `"},
+		{"name": "copy/a.bin", "size": 1, "oid": "` + a + `"},
+		{"name": "v1/a.bin", "size": 1, "oid": "` + a + `"},
+		{"name": "b.bin", "size": 1, "oid": "` + b + `"}]}`
+
+	objects, files, err := parseHistoryObjects(output)
+	if err != nil {
+		t.Fatalf("parseHistoryObjects failed: %v", err)
+	}
+	if len(objects) != 2 || files != 3 {
+		t.Errorf("parseHistoryObjects = %d objects, %d files; want 2 objects for 3 files", len(objects), files)
+	}
+
+	if objects, files, err := parseHistoryObjects(""); err != nil || len(objects) != 0 || files != 0 {
+		t.Errorf("parseHistoryObjects of no output = %v, %d, %v", objects, files, err)
+	}
+}
+
+func TestHistoryCountsCheck(t *testing.T) {
+	counts := &HistoryCounts{Referenced: 3, Local: 2, Server: 3, MissingLocally: []string{oidOf("old")}}
+	if err := counts.Check(false); err != nil {
+		t.Errorf("Check(false) = %v; a clone need not hold the objects of old commits", err)
+	}
+	if err := counts.Check(true); err == nil || !strings.Contains(err.Error(), "missing locally: "+oidOf("old")[:12]) {
+		t.Errorf("Check(true) = %v", err)
+	}
+
+	counts = &HistoryCounts{Referenced: 3, Local: 3, Server: 1, MissingOnServer: []string{oidOf("a"), oidOf("b")}}
+	if err := counts.Check(false); err == nil || !strings.Contains(err.Error(), "2 of 3 LFS objects referenced in history are missing on the server") {
+		t.Errorf("Check = %v", err)
+	}
+	if counts.String() != "3 referenced in history, 3 local, 1 on the server" {
+		t.Errorf("String = %s", counts)
+	}
+}
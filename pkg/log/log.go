@@ -0,0 +1,185 @@
+// Package log is the leveled log shared by the lfst commands. Messages go to standard
+// output as plain text, debug messages only with --debug, and every message, debug
+// included, can also be written to a log file as text or JSON records tagged with the
+// run ID and step, so long runs can be audited after the fact.
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/secret"
+)
+
+// FileEnv and FormatEnv set the log file and its format when the flags do not;
+// lfst sets them for --log-file and --log-format before running a subcommand
+const (
+	FileEnv   = "LFST_LOG_FILE"
+	FormatEnv = "LFST_LOG_FORMAT"
+)
+
+// Log file formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Level is the severity of a message
+type Level = slog.Level
+
+// Levels, from the most verbose
+const (
+	LevelDebug = slog.LevelDebug
+	LevelInfo  = slog.LevelInfo
+	LevelWarn  = slog.LevelWarn
+	LevelError = slog.LevelError
+)
+
+var (
+	mu      sync.Mutex
+	debug   bool
+	file    *os.File
+	handler slog.Handler // Writes the log file; nil without one
+
+	runID atomic.Int64
+	step  atomic.Int32
+
+	timeNow = time.Now // Time of a record; tests fix it
+)
+
+// Setup applies the --debug, --log-file and --log-format flags; FileEnv and FormatEnv
+// stand in for the last two. The log file is appended to. Call it once, after parsing
+// flags and after term.Setup; Close closes the log file.
+func Setup(debugFlag bool, path, format string) error {
+	if path == "" {
+		path = os.Getenv(FileEnv)
+	}
+	if format == "" {
+		format = os.Getenv(FormatEnv)
+	}
+	if format == "" {
+		format = FormatText
+	}
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("unknown log format '%s' (use %s or %s)", format, FormatText, FormatJSON)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	debug = debugFlag
+	if file != nil {
+		file.Close()
+		file, handler = nil, nil
+	}
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	file = f
+	handler = newHandler(f, format)
+	return nil
+}
+
+// newHandler returns the handler that writes every level to w in format
+func newHandler(w io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: LevelDebug}
+	if format == FormatJSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// Close closes the log file
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	handler = nil
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file = nil
+	return err
+}
+
+// Debugging reports whether debug messages are printed
+func Debugging() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return debug
+}
+
+// SetRun tags the following messages with a test run; 0 removes the tag
+func SetRun(id int64) {
+	runID.Store(id)
+}
+
+// SetStep tags the following messages with the step being run; 0 removes the tag
+func SetStep(n int) {
+	step.Store(int32(n))
+}
+
+// Debugf prints a message with --debug, like fmt.Printf
+func Debugf(format string, args ...any) {
+	logf(LevelDebug, format, args...)
+}
+
+// Infof prints a message, like fmt.Printf
+func Infof(format string, args ...any) {
+	logf(LevelInfo, format, args...)
+}
+
+// Warnf prints a message prefixed with "Warning: ", like fmt.Printf
+func Warnf(format string, args ...any) {
+	logf(LevelWarn, "Warning: "+format, args...)
+}
+
+// Errorf prints a message prefixed with "Error: " to standard error, like fmt.Printf
+func Errorf(format string, args ...any) {
+	logf(LevelError, "Error: "+format, args...)
+}
+
+// logf prints a message on the console at its level and records it in the log file.
+// The console gets the text as formatted, blank lines and indentation included; the
+// log file gets it trimmed, without the level prefix. Both get it without the secrets
+// registered with package secret.
+func logf(level Level, format string, args ...any) {
+	text := secret.Redact(fmt.Sprintf(format, args...))
+
+	mu.Lock()
+	defer mu.Unlock()
+	switch {
+	case level >= LevelError:
+		fmt.Fprint(os.Stderr, text)
+	case level > LevelDebug || debug:
+		fmt.Fprint(os.Stdout, text)
+	}
+
+	if handler == nil {
+		return
+	}
+	msg := strings.TrimSpace(text)
+	msg = strings.TrimPrefix(strings.TrimPrefix(msg, "Warning: "), "Error: ")
+	if msg == "" {
+		return
+	}
+	record := slog.NewRecord(timeNow(), level, msg, 0)
+	if id := runID.Load(); id != 0 {
+		record.AddAttrs(slog.Int64("run_id", id))
+	}
+	if n := step.Load(); n != 0 {
+		record.AddAttrs(slog.Int("step", int(n)))
+	}
+	handler.Handle(context.Background(), record)
+}
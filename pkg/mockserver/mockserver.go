@@ -0,0 +1,312 @@
+package mockserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MediaType is the content type of Git LFS batch API requests and responses
+const MediaType = "application/vnd.git-lfs+json"
+
+// Endpoint identifies one part of the LFS API for latency and failure injection
+type Endpoint string
+
+const (
+	Batch    Endpoint = "batch"
+	Upload   Endpoint = "upload"
+	Download Endpoint = "download"
+	Verify   Endpoint = "verify"
+)
+
+// Server is an in-process Git LFS server implementing the batch API
+// with basic transfers. Point lfs.url at LFSURL() to use it from git-lfs.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	objects  map[string][]byte
+	latency  map[Endpoint]time.Duration
+	failures map[Endpoint]*failure
+	counts   map[Endpoint]int
+}
+
+// failure makes the next count requests to an endpoint return status
+type failure struct {
+	status int
+	count  int
+}
+
+// ObjectSpec identifies an LFS object in batch requests
+type ObjectSpec struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// BatchRequest is the body of a batch API request
+type BatchRequest struct {
+	Operation string       `json:"operation"` // 'upload' or 'download'
+	Transfers []string     `json:"transfers,omitempty"`
+	Objects   []ObjectSpec `json:"objects"`
+}
+
+// Action is a transfer the client should perform for an object
+type Action struct {
+	Href      string            `json:"href"`
+	Header    map[string]string `json:"header,omitempty"`
+	ExpiresIn int               `json:"expires_in,omitempty"`
+}
+
+// ObjectError reports why an object cannot be transferred
+type ObjectError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ObjectResponse describes the actions for one object in a batch response
+type ObjectResponse struct {
+	OID           string             `json:"oid"`
+	Size          int64              `json:"size"`
+	Authenticated bool               `json:"authenticated,omitempty"`
+	Actions       map[string]*Action `json:"actions,omitempty"`
+	Error         *ObjectError       `json:"error,omitempty"`
+}
+
+// BatchResponse is the body of a batch API response
+type BatchResponse struct {
+	Transfer string            `json:"transfer"`
+	Objects  []*ObjectResponse `json:"objects"`
+}
+
+// New starts a mock LFS server; call Close when done
+func New() *Server {
+	s := &Server{
+		objects:  make(map[string][]byte),
+		latency:  make(map[Endpoint]time.Duration),
+		failures: make(map[Endpoint]*failure),
+		counts:   make(map[Endpoint]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// LFSURL returns the URL to configure as lfs.url
+func (s *Server) LFSURL() string {
+	return s.URL + "/lfs"
+}
+
+// SetLatency delays every request to an endpoint by d
+func (s *Server) SetLatency(e Endpoint, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency[e] = d
+}
+
+// FailNext makes the next count requests to an endpoint fail with an HTTP status
+func (s *Server) FailNext(e Endpoint, status, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[e] = &failure{status: status, count: count}
+}
+
+// Requests returns how many requests an endpoint has received
+func (s *Server) Requests(e Endpoint) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[e]
+}
+
+// ObjectCount returns the number of stored objects
+func (s *Server) ObjectCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.objects)
+}
+
+// HasObject reports whether an object is stored
+func (s *Server) HasObject(oid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.objects[oid]
+	return ok
+}
+
+// AddObject stores content directly and returns its OID
+func (s *Server) AddObject(content []byte) string {
+	oid := OID(content)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[oid] = append([]byte(nil), content...)
+	return oid
+}
+
+// OID returns the LFS object ID (SHA-256) of content
+func OID(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// begin counts a request, applies latency, and reports an injected failure status (0 if none)
+func (s *Server) begin(e Endpoint) int {
+	s.mu.Lock()
+	s.counts[e]++
+	delay := s.latency[e]
+	status := 0
+	if f := s.failures[e]; f != nil && f.count > 0 {
+		f.count--
+		status = f.status
+	}
+	s.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return status
+}
+
+// handle routes requests: POST .../objects/batch, PUT/GET .../objects/{oid},
+// and POST .../objects/{oid}/verify
+func (s *Server) handle(w http.ResponseWriter, req *http.Request) {
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	idx := strings.LastIndex(path, "/objects/")
+	if idx < 0 {
+		http.NotFound(w, req)
+		return
+	}
+	rest := path[idx+len("/objects/"):]
+
+	switch {
+	case rest == "batch" && req.Method == http.MethodPost:
+		s.serve(w, Batch, func() { s.handleBatch(w, req, path[:idx]) })
+	case strings.HasSuffix(rest, "/verify") && req.Method == http.MethodPost:
+		s.serve(w, Verify, func() { s.handleVerify(w, req, strings.TrimSuffix(rest, "/verify")) })
+	case req.Method == http.MethodPut:
+		s.serve(w, Upload, func() { s.handleUpload(w, req, rest) })
+	case req.Method == http.MethodGet:
+		s.serve(w, Download, func() { s.handleDownload(w, rest) })
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serve runs handler unless a failure has been injected for the endpoint
+func (s *Server) serve(w http.ResponseWriter, e Endpoint, handler func()) {
+	if status := s.begin(e); status != 0 {
+		writeError(w, status, fmt.Sprintf("injected %s failure", e))
+		return
+	}
+	handler()
+}
+
+func (s *Server) handleBatch(w http.ResponseWriter, req *http.Request, base string) {
+	var batch BatchRequest
+	if err := json.NewDecoder(req.Body).Decode(&batch); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid batch request: %v", err))
+		return
+	}
+	if batch.Operation != "upload" && batch.Operation != "download" {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("unsupported operation '%s'", batch.Operation))
+		return
+	}
+
+	objectsURL := s.URL + base + "/objects/"
+	resp := &BatchResponse{Transfer: "basic"}
+
+	s.mu.Lock()
+	for _, obj := range batch.Objects {
+		or := &ObjectResponse{OID: obj.OID, Size: obj.Size, Authenticated: true}
+		content, stored := s.objects[obj.OID]
+
+		switch {
+		case batch.Operation == "download" && !stored:
+			or.Error = &ObjectError{Code: http.StatusNotFound, Message: "object does not exist"}
+		case batch.Operation == "download":
+			or.Size = int64(len(content))
+			or.Actions = map[string]*Action{"download": {Href: objectsURL + obj.OID, ExpiresIn: 3600}}
+		case !stored:
+			// Objects already on the server need no upload actions
+			or.Actions = map[string]*Action{
+				"upload": {Href: objectsURL + obj.OID, ExpiresIn: 3600},
+				"verify": {Href: objectsURL + obj.OID + "/verify", ExpiresIn: 3600},
+			}
+		}
+		resp.Objects = append(resp.Objects, or)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, req *http.Request, oid string) {
+	content, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("failed to read upload: %v", err))
+		return
+	}
+	if OID(content) != oid {
+		writeError(w, http.StatusUnprocessableEntity, "content does not match oid")
+		return
+	}
+
+	s.mu.Lock()
+	s.objects[oid] = content
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleDownload(w http.ResponseWriter, oid string) {
+	s.mu.Lock()
+	content, ok := s.objects[oid]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "object does not exist")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(content)))
+	io.Copy(w, bytes.NewReader(content))
+}
+
+func (s *Server) handleVerify(w http.ResponseWriter, req *http.Request, oid string) {
+	var spec ObjectSpec
+	if err := json.NewDecoder(req.Body).Decode(&spec); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid verify request: %v", err))
+		return
+	}
+
+	s.mu.Lock()
+	content, ok := s.objects[oid]
+	s.mu.Unlock()
+
+	switch {
+	case !ok:
+		writeError(w, http.StatusNotFound, "object does not exist")
+	case int64(len(content)) != spec.Size:
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("size mismatch: stored %d, expected %d", len(content), spec.Size))
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// writeJSON writes v as an LFS JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", MediaType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an LFS error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
@@ -0,0 +1,153 @@
+package mockserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// batch posts a batch request to the server and decodes the response
+func batch(t *testing.T, s *Server, operation string, objects ...ObjectSpec) (*BatchResponse, int) {
+	t.Helper()
+
+	body, _ := json.Marshal(&BatchRequest{Operation: operation, Transfers: []string{"basic"}, Objects: objects})
+	resp, err := http.Post(s.LFSURL()+"/objects/batch", MediaType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Batch request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode
+	}
+
+	var br BatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&br); err != nil {
+		t.Fatalf("Failed to decode batch response: %v", err)
+	}
+	return &br, resp.StatusCode
+}
+
+func TestUploadVerifyDownload(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	content := []byte("large binary content")
+	spec := ObjectSpec{OID: OID(content), Size: int64(len(content))}
+
+	// Upload: batch, PUT, verify
+	br, _ := batch(t, s, "upload", spec)
+	upload := br.Objects[0].Actions["upload"]
+	verify := br.Objects[0].Actions["verify"]
+	if upload == nil || verify == nil {
+		t.Fatalf("Expected upload and verify actions, got %+v", br.Objects[0])
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, upload.Href, bytes.NewReader(content))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Upload failed: %v %v", err, resp)
+	}
+	resp.Body.Close()
+
+	body, _ := json.Marshal(spec)
+	resp, err = http.Post(verify.Href, MediaType, bytes.NewReader(body))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Verify failed: %v %v", err, resp)
+	}
+	resp.Body.Close()
+
+	if !s.HasObject(spec.OID) {
+		t.Error("Server should store the uploaded object")
+	}
+
+	// A second upload batch needs no actions
+	br, _ = batch(t, s, "upload", spec)
+	if br.Objects[0].Actions != nil {
+		t.Errorf("Stored object should have no upload actions, got %+v", br.Objects[0].Actions)
+	}
+
+	// Download: batch, GET
+	br, _ = batch(t, s, "download", spec)
+	download := br.Objects[0].Actions["download"]
+	if download == nil {
+		t.Fatalf("Expected download action, got %+v", br.Objects[0])
+	}
+	resp, err = http.Get(download.Href)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !bytes.Equal(got, content) {
+		t.Errorf("Downloaded %q, want %q", got, content)
+	}
+
+	if s.Requests(Batch) != 3 || s.Requests(Upload) != 1 || s.Requests(Download) != 1 || s.Requests(Verify) != 1 {
+		t.Errorf("Unexpected request counts: batch=%d upload=%d download=%d verify=%d",
+			s.Requests(Batch), s.Requests(Upload), s.Requests(Download), s.Requests(Verify))
+	}
+}
+
+func TestUploadRejectsWrongContent(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	oid := OID([]byte("expected"))
+	req, _ := http.NewRequest(http.MethodPut, s.LFSURL()+"/objects/"+oid, bytes.NewReader([]byte("corrupted")))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Status = %d, want %d", resp.StatusCode, http.StatusUnprocessableEntity)
+	}
+	if s.ObjectCount() != 0 {
+		t.Error("Corrupted upload should not be stored")
+	}
+}
+
+func TestDownloadMissingObject(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	br, _ := batch(t, s, "download", ObjectSpec{OID: OID([]byte("missing")), Size: 7})
+	if br.Objects[0].Error == nil || br.Objects[0].Error.Code != http.StatusNotFound {
+		t.Errorf("Expected a 404 object error, got %+v", br.Objects[0])
+	}
+}
+
+func TestFailureInjection(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	oid := s.AddObject([]byte("content"))
+	s.FailNext(Batch, http.StatusServiceUnavailable, 2)
+
+	for i := 0; i < 2; i++ {
+		if _, status := batch(t, s, "download", ObjectSpec{OID: oid, Size: 7}); status != http.StatusServiceUnavailable {
+			t.Errorf("Request %d status = %d, want 503", i+1, status)
+		}
+	}
+	if _, status := batch(t, s, "download", ObjectSpec{OID: oid, Size: 7}); status != http.StatusOK {
+		t.Errorf("Request after injected failures status = %d, want 200", status)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	s.SetLatency(Batch, 50*time.Millisecond)
+
+	start := time.Now()
+	batch(t, s, "upload", ObjectSpec{OID: OID([]byte("x")), Size: 1})
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Batch returned after %s, want at least 50ms", elapsed)
+	}
+}
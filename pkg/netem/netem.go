@@ -0,0 +1,165 @@
+package netem
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profile describes the network conditions the Linux tc/netem queueing discipline
+// emulates, so a server can be evaluated as if it were across a WAN
+type Profile struct {
+	// RTT is the round-trip latency added to the traffic; each direction gets half of it
+	RTT time.Duration
+	// Rate caps the bandwidth in each direction, in bits per second; 0 means no cap
+	Rate int64
+	// Loss is the percentage of packets dropped in each direction, e.g. 0.1
+	Loss float64
+}
+
+// Presets are the named profiles Parse accepts besides a specification
+var Presets = map[string]string{
+	"lan":              "rtt=1ms,rate=1gbit",
+	"broadband":        "rtt=30ms,rate=50mbit",
+	"wan":              "rtt=50ms,rate=100mbit",
+	"intercontinental": "rtt=150ms,rate=50mbit,loss=0.1%",
+}
+
+// PresetNames returns the names of the presets, sorted
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rateUnits are the tc bandwidth units in bits per second, largest first
+var rateUnits = []struct {
+	suffix string
+	bits   int64
+}{
+	{"gbit", 1_000_000_000},
+	{"mbit", 1_000_000},
+	{"kbit", 1_000},
+	{"bit", 1},
+}
+
+// Parse reads a preset name or a specification of comma-separated settings, e.g.
+// "rtt=50ms,rate=100mbit" or "rtt=150ms,rate=50mbit,loss=0.1%". A specification
+// needs rtt or rate.
+func Parse(spec string) (*Profile, error) {
+	spec = strings.TrimSpace(spec)
+	if preset, ok := Presets[strings.ToLower(spec)]; ok {
+		spec = preset
+	}
+	if spec == "" {
+		return nil, fmt.Errorf("empty network profile")
+	}
+
+	p := &Profile{}
+	for _, setting := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(setting), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid network setting '%s' (use KEY=VALUE, or a preset: %s)", setting, strings.Join(PresetNames(), ", "))
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "rtt":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid rtt '%s' (use a duration such as 50ms)", value)
+			}
+			p.RTT = d
+		case "rate":
+			rate, err := parseRate(value)
+			if err != nil {
+				return nil, err
+			}
+			p.Rate = rate
+		case "loss":
+			loss, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil || loss < 0 || loss > 100 {
+				return nil, fmt.Errorf("invalid loss '%s' (use a percentage such as 0.1%%)", value)
+			}
+			p.Loss = loss
+		default:
+			return nil, fmt.Errorf("unknown network setting '%s' (use rtt, rate or loss)", key)
+		}
+	}
+	if p.RTT == 0 && p.Rate == 0 {
+		return nil, fmt.Errorf("network profile '%s' sets neither rtt nor rate", spec)
+	}
+	return p, nil
+}
+
+// parseRate reads a bandwidth in tc units, e.g. 100mbit, 1gbit or 512kbit
+func parseRate(value string) (int64, error) {
+	lower := strings.ToLower(value)
+	for _, unit := range rateUnits {
+		if number, ok := strings.CutSuffix(lower, unit.suffix); ok {
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil || n <= 0 {
+				break
+			}
+			return int64(n * float64(unit.bits)), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid rate '%s' (use a bandwidth such as 100mbit, 1gbit or 512kbit)", value)
+}
+
+// formatRate writes a bandwidth in the largest tc unit that represents it exactly
+func formatRate(bits int64) string {
+	for _, unit := range rateUnits {
+		if bits%unit.bits == 0 {
+			return fmt.Sprintf("%d%s", bits/unit.bits, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dbit", bits)
+}
+
+// String returns the specification of p, which Parse reads back; it is recorded with runs
+func (p *Profile) String() string {
+	var parts []string
+	if p.RTT > 0 {
+		parts = append(parts, "rtt="+p.RTT.String())
+	}
+	if p.Rate > 0 {
+		parts = append(parts, "rate="+formatRate(p.Rate))
+	}
+	if p.Loss > 0 {
+		parts = append(parts, "loss="+strconv.FormatFloat(p.Loss, 'f', -1, 64)+"%")
+	}
+	return strings.Join(parts, ",")
+}
+
+// netemArgs returns the netem parameters that shape one direction of the traffic with
+// the given one-way delay
+func (p *Profile) netemArgs(delay time.Duration) []string {
+	var args []string
+	if delay > 0 {
+		args = append(args, "delay", fmt.Sprintf("%dus", delay.Microseconds()))
+	}
+	if p.Rate > 0 {
+		args = append(args, "rate", formatRate(p.Rate))
+	}
+	if p.Loss > 0 {
+		args = append(args, "loss", strconv.FormatFloat(p.Loss, 'f', -1, 64)+"%")
+	}
+	return append(args, "limit", strconv.Itoa(p.limit()))
+}
+
+// limit returns the queue length in packets. netem holds every delayed packet in its
+// queue, and its default of 1000 packets drops most of a fast link's traffic in flight,
+// so the queue holds twice the bandwidth-delay product of full-size packets.
+func (p *Profile) limit() int {
+	rate := p.Rate
+	if rate == 0 {
+		rate = 10_000_000_000 // No cap: allow for a 10 Gbit/s link
+	}
+	packets := int(float64(rate) / 8 * p.RTT.Seconds() / 1500 * 2)
+	return max(packets, 1000)
+}
@@ -0,0 +1,81 @@
+package netem
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	p, err := Parse("rtt=50ms, rate=100mbit")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if p.RTT != 50*time.Millisecond || p.Rate != 100_000_000 || p.Loss != 0 {
+		t.Errorf("Parse = %+v", p)
+	}
+	if p.String() != "rtt=50ms,rate=100mbit" {
+		t.Errorf("String = %s", p)
+	}
+
+	p, err = Parse("rate=1.5mbit,loss=0.1%")
+	if err != nil || p.Rate != 1_500_000 || p.Loss != 0.1 {
+		t.Errorf("Parse = %+v, %v", p, err)
+	}
+	if p.String() != "rate=1500kbit,loss=0.1%" {
+		t.Errorf("String = %s", p)
+	}
+
+	// Presets resolve to their specification
+	p, err = Parse("WAN")
+	if err != nil || p.String() != Presets["wan"] {
+		t.Errorf("Parse of a preset = %v, %v", p, err)
+	}
+
+	for _, spec := range []string{"", "slow", "rtt=fast", "rate=100mb", "rate=0mbit", "loss=150%", "jitter=5ms", "loss=1%"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) should fail", spec)
+		}
+	}
+}
+
+func TestShaperCommands(t *testing.T) {
+	p, _ := Parse("rtt=50ms,rate=100mbit")
+
+	s := NewShaper("eth0", p)
+	var lines []string
+	for _, command := range s.applyCommands() {
+		lines = append(lines, strings.Join(command, " "))
+	}
+	want := []string{
+		"ip link add ifb-lfst type ifb",
+		"ip link set dev ifb-lfst up",
+		"tc qdisc add dev eth0 handle ffff: ingress",
+		"tc filter add dev eth0 parent ffff: protocol all u32 match u32 0 0 action mirred egress redirect dev ifb-lfst",
+		"tc qdisc add dev ifb-lfst root netem delay 25000us rate 100mbit limit 1000",
+		"tc qdisc add dev eth0 root netem delay 25000us rate 100mbit limit 1000",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("applyCommands =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+	if len(s.removeCommands()) != 3 {
+		t.Errorf("removeCommands = %v", s.removeCommands())
+	}
+
+	// The loopback interface is shaped once, on egress
+	lo := NewShaper("lo", p)
+	if commands := lo.applyCommands(); len(commands) != 1 || !strings.HasPrefix(strings.Join(commands[0], " "), "tc qdisc add dev lo root netem delay 25000us") {
+		t.Errorf("applyCommands of lo = %v", commands)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	// 1 Gbit/s over 150 ms holds 12500 full-size packets in flight
+	p := &Profile{RTT: 150 * time.Millisecond, Rate: 1_000_000_000}
+	if got := p.limit(); got != 25000 {
+		t.Errorf("limit = %d, want 25000", got)
+	}
+	if got := (&Profile{Rate: 1_000_000}).limit(); got != 1000 {
+		t.Errorf("limit without delay = %d, want the netem default of 1000", got)
+	}
+}
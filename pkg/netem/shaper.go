@@ -0,0 +1,126 @@
+package netem
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ifbDevice is the intermediate functional block that incoming traffic is redirected
+// through, so netem can shape it like outgoing traffic
+const ifbDevice = "ifb-lfst"
+
+// Shaper applies a Profile to the traffic of a network interface with tc. Outgoing
+// traffic is shaped on the interface itself and incoming traffic on ifbDevice, each with
+// half the round trip. Traffic to this machine through the loopback interface crosses it
+// once in each direction, so there only the interface is shaped, and its rate cap is
+// shared by both directions.
+type Shaper struct {
+	Interface string
+	Profile   *Profile
+
+	sudo bool // Run tc and ip through sudo; set by CheckTools when not root
+}
+
+// NewShaper creates a shaper of the traffic of iface
+func NewShaper(iface string, p *Profile) *Shaper {
+	return &Shaper{Interface: iface, Profile: p}
+}
+
+// loopback reports whether the shaped interface is the loopback interface
+func (s *Shaper) loopback() bool {
+	return s.Interface == "lo"
+}
+
+// CheckTools reports why the shaper cannot run: netem needs Linux, tc and ip (iproute2),
+// and root, or sudo without a password
+func (s *Shaper) CheckTools() error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("network shaping needs Linux tc/netem, not available on %s", runtime.GOOS)
+	}
+	for _, tool := range []string{"tc", "ip"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("%s is needed for network shaping but was not found in PATH\n\nInstall with: apt-get install iproute2", tool)
+		}
+	}
+	if os.Geteuid() == 0 {
+		return nil
+	}
+	if _, err := exec.LookPath("sudo"); err != nil {
+		return fmt.Errorf("network shaping needs root, and sudo was not found in PATH")
+	}
+	if err := exec.Command("sudo", "-n", "true").Run(); err != nil {
+		return fmt.Errorf("network shaping needs root or sudo without a password")
+	}
+	s.sudo = true
+	return nil
+}
+
+// Apply shapes the interface's traffic, replacing whatever shaping an interrupted run left
+func (s *Shaper) Apply() error {
+	s.run(s.removeCommands(), false)
+	if err := s.run(s.applyCommands(), true); err != nil {
+		s.run(s.removeCommands(), false)
+		return fmt.Errorf("failed to shape %s with %s: %w", s.Interface, s.Profile, err)
+	}
+	return nil
+}
+
+// Remove takes the shaping off the interface
+func (s *Shaper) Remove() error {
+	if err := s.run(s.removeCommands(), true); err != nil {
+		return fmt.Errorf("failed to remove the network shaping of %s: %w", s.Interface, err)
+	}
+	return nil
+}
+
+// applyCommands returns the tc and ip commands that shape the interface
+func (s *Shaper) applyCommands() [][]string {
+	if s.loopback() {
+		// Both directions cross lo's egress, each taking half the round trip
+		return [][]string{
+			append([]string{"tc", "qdisc", "add", "dev", s.Interface, "root", "netem"}, s.Profile.netemArgs(s.Profile.RTT/2)...),
+		}
+	}
+
+	half := s.Profile.RTT / 2
+	return [][]string{
+		{"ip", "link", "add", ifbDevice, "type", "ifb"},
+		{"ip", "link", "set", "dev", ifbDevice, "up"},
+		{"tc", "qdisc", "add", "dev", s.Interface, "handle", "ffff:", "ingress"},
+		{"tc", "filter", "add", "dev", s.Interface, "parent", "ffff:", "protocol", "all", "u32",
+			"match", "u32", "0", "0", "action", "mirred", "egress", "redirect", "dev", ifbDevice},
+		append([]string{"tc", "qdisc", "add", "dev", ifbDevice, "root", "netem"}, s.Profile.netemArgs(half)...),
+		append([]string{"tc", "qdisc", "add", "dev", s.Interface, "root", "netem"}, s.Profile.netemArgs(half)...),
+	}
+}
+
+// removeCommands returns the tc and ip commands that undo applyCommands
+func (s *Shaper) removeCommands() [][]string {
+	if s.loopback() {
+		return [][]string{{"tc", "qdisc", "del", "dev", s.Interface, "root"}}
+	}
+	return [][]string{
+		{"tc", "qdisc", "del", "dev", s.Interface, "root"},
+		{"tc", "qdisc", "del", "dev", s.Interface, "ingress"},
+		{"ip", "link", "del", ifbDevice},
+	}
+}
+
+// run executes commands in order. With stopOnError it stops at the first failure and
+// returns it; otherwise it runs them all and ignores failures, as when removing shaping
+// that may not be there.
+func (s *Shaper) run(commands [][]string, stopOnError bool) error {
+	for _, command := range commands {
+		if s.sudo {
+			command = append([]string{"sudo", "-n"}, command...)
+		}
+		out, err := exec.Command(command[0], command[1:]...).CombinedOutput()
+		if err != nil && stopOnError {
+			return fmt.Errorf("%s: %s", strings.Join(command, " "), strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package netstat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// procNetDev and procNetRoute are the Linux sources of interface counters and routes
+const (
+	procNetDev   = "/proc/net/dev"
+	procNetRoute = "/proc/net/route"
+)
+
+// Counters are the cumulative bytes received and transmitted by an interface
+type Counters struct {
+	RxBytes uint64
+	TxBytes uint64
+}
+
+// Sub returns the traffic between an earlier sample and c.
+// A counter that went backwards (interface reset) counts as zero.
+func (c Counters) Sub(earlier Counters) Counters {
+	var d Counters
+	if c.RxBytes >= earlier.RxBytes {
+		d.RxBytes = c.RxBytes - earlier.RxBytes
+	}
+	if c.TxBytes >= earlier.TxBytes {
+		d.TxBytes = c.TxBytes - earlier.TxBytes
+	}
+	return d
+}
+
+// Available reports whether interface counters can be read on this platform
+func Available() bool {
+	_, err := os.Stat(procNetDev)
+	return err == nil
+}
+
+// Read returns the current counters of an interface
+func Read(iface string) (Counters, error) {
+	file, err := os.Open(procNetDev)
+	if err != nil {
+		return Counters{}, fmt.Errorf("interface counters are not available: %w", err)
+	}
+	defer file.Close()
+
+	all, err := parseNetDev(file)
+	if err != nil {
+		return Counters{}, err
+	}
+
+	c, ok := all[iface]
+	if !ok {
+		return Counters{}, fmt.Errorf("network interface '%s' not found", iface)
+	}
+	return c, nil
+}
+
+// parseNetDev parses /proc/net/dev, which has two header lines and then one line per interface:
+// "  eth0: RX-bytes packets errs drop fifo frame compressed multicast TX-bytes packets ..."
+func parseNetDev(r io.Reader) (map[string]Counters, error) {
+	counters := make(map[string]Counters)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name, stats, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue // Header line
+		}
+
+		fields := strings.Fields(stats)
+		if len(fields) < 9 {
+			return nil, fmt.Errorf("unexpected /proc/net/dev line for %s", strings.TrimSpace(name))
+		}
+
+		rx, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RX bytes: %w", err)
+		}
+		tx, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse TX bytes: %w", err)
+		}
+		counters[strings.TrimSpace(name)] = Counters{RxBytes: rx, TxBytes: tx}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read interface counters: %w", err)
+	}
+
+	return counters, nil
+}
+
+// DefaultInterface returns the interface of the default route, which carries traffic
+// to remote LFS servers. It returns "lo" when there is no default route.
+func DefaultInterface() (string, error) {
+	file, err := os.Open(procNetRoute)
+	if err != nil {
+		return "", fmt.Errorf("routing table is not available: %w", err)
+	}
+	defer file.Close()
+
+	return parseDefaultRoute(file)
+}
+
+// parseDefaultRoute finds the interface whose destination is 00000000 in /proc/net/route
+func parseDefaultRoute(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[1] == "00000000" {
+			return fields[0], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read routing table: %w", err)
+	}
+	return "lo", nil
+}
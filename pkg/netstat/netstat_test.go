@@ -0,0 +1,61 @@
+package netstat
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  123456     100    0    0    0     0          0         0   123456     100    0    0    0     0       0          0
+  eth0: 987654321  5000    0    0    0     0          0        12 12345678   4000    0    0    0     0       0          0
+`
+
+func TestParseNetDev(t *testing.T) {
+	counters, err := parseNetDev(strings.NewReader(sampleNetDev))
+	if err != nil {
+		t.Fatalf("parseNetDev failed: %v", err)
+	}
+
+	if len(counters) != 2 {
+		t.Fatalf("Expected 2 interfaces, got %d", len(counters))
+	}
+	if got := counters["eth0"]; got.RxBytes != 987654321 || got.TxBytes != 12345678 {
+		t.Errorf("eth0 = %+v, want RX 987654321 TX 12345678", got)
+	}
+	if got := counters["lo"]; got.RxBytes != 123456 || got.TxBytes != 123456 {
+		t.Errorf("lo = %+v, want RX 123456 TX 123456", got)
+	}
+}
+
+func TestCountersSub(t *testing.T) {
+	before := Counters{RxBytes: 1000, TxBytes: 500}
+	after := Counters{RxBytes: 4000, TxBytes: 200}
+
+	d := after.Sub(before)
+	if d.RxBytes != 3000 {
+		t.Errorf("RxBytes = %d, want 3000", d.RxBytes)
+	}
+	if d.TxBytes != 0 {
+		t.Errorf("TxBytes = %d, want 0 after a counter reset", d.TxBytes)
+	}
+}
+
+func TestParseDefaultRoute(t *testing.T) {
+	routes := `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+docker0	000011AC	00000000	0001	0	0	0	0000FFFF	0	0	0
+wlan0	00000000	0101A8C0	0003	0	0	600	00000000	0	0	0
+`
+	iface, err := parseDefaultRoute(strings.NewReader(routes))
+	if err != nil {
+		t.Fatalf("parseDefaultRoute failed: %v", err)
+	}
+	if iface != "wlan0" {
+		t.Errorf("Expected wlan0, got %s", iface)
+	}
+
+	iface, _ = parseDefaultRoute(strings.NewReader("Iface\tDestination\n"))
+	if iface != "lo" {
+		t.Errorf("Expected lo without a default route, got %s", iface)
+	}
+}
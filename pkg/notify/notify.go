@@ -0,0 +1,166 @@
+// Package notify tells people that a run ended, e.g. in Slack or by email, by posting a
+// JSON payload to a webhook and by running a command with the payload on standard input
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+// Timeout is how long posting to the webhook or running the command may take
+const Timeout = 30 * time.Second
+
+// Environment variables of the notification command, besides the payload on its standard input
+const (
+	RunIDEnv  = "LFST_RUN_ID"
+	StatusEnv = "LFST_RUN_STATUS"
+)
+
+// Payload describes a run that ended
+type Payload struct {
+	RunID      int64      `json:"run_id"`
+	ScenarioID int        `json:"scenario_id"`
+	ServerType string     `json:"server_type"`
+	Protocol   string     `json:"protocol"`
+	GitServer  string     `json:"git_server"`
+	Status     string     `json:"status"` // 'completed', 'failed', 'cancelled', 'timed-out', 'aborted'
+	StartedAt  time.Time  `json:"started_at"`
+	EndedAt    *time.Time `json:"ended_at,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+	// The step that did not complete, for runs that did not complete
+	FailedStep     int    `json:"failed_step,omitempty"`
+	FailedStepName string `json:"failed_step_name,omitempty"`
+	Error          string `json:"error,omitempty"`
+	Host           string `json:"host"` // The machine that ran the scenario
+	// Text sums the rest up in one line, which Slack and compatible webhooks show
+	Text string `json:"text"`
+}
+
+// Hooks are the ways of telling that a run ended; empty ones are not used
+type Hooks struct {
+	URL     string // Webhook that receives the payload in a POST request
+	Command string // Shell command that receives the payload on standard input
+}
+
+// Enabled reports whether any hook is set
+func (h Hooks) Enabled() bool {
+	return h.URL != "" || h.Command != ""
+}
+
+// Build returns the payload of run runID, as recorded in db
+func Build(db *database.DB, runID int64) (*Payload, error) {
+	run, err := db.GetTestRun(runID)
+	if err != nil {
+		return nil, err
+	}
+	results, err := db.ListStepResults(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Payload{
+		RunID:      run.ID,
+		ScenarioID: run.ScenarioID,
+		ServerType: run.ServerType,
+		Protocol:   run.Protocol,
+		GitServer:  run.GitServer,
+		Status:     run.Status,
+		StartedAt:  run.StartedAt,
+		EndedAt:    run.CompletedAt,
+	}
+	if run.CompletedAt != nil {
+		p.DurationMs = run.CompletedAt.Sub(run.StartedAt).Milliseconds()
+	}
+	p.Host, _ = os.Hostname()
+	for _, sr := range results {
+		if sr.Status != "completed" {
+			p.FailedStep = sr.StepNumber
+			p.FailedStepName = sr.Name
+			p.Error = sr.Error
+			break
+		}
+	}
+	p.Text = summary(p)
+	return p, nil
+}
+
+// summary returns the one-line text of p
+func summary(p *Payload) string {
+	text := fmt.Sprintf("lfst run %d of scenario %d (%s via %s) %s", p.RunID, p.ScenarioID, p.ServerType, p.Protocol, p.Status)
+	if p.EndedAt != nil {
+		text += fmt.Sprintf(" after %s", (time.Duration(p.DurationMs) * time.Millisecond).Round(time.Second))
+	}
+	if p.FailedStep > 0 {
+		text += fmt.Sprintf(" in step %d (%s)", p.FailedStep, p.FailedStepName)
+	}
+	if p.Error != "" {
+		text += ": " + p.Error
+	}
+	if p.Host != "" {
+		text += " on " + p.Host
+	}
+	return text
+}
+
+// Send posts p to the webhook and runs the command. A hook that fails does not keep the
+// other from running; the errors of both are returned.
+func (h Hooks) Send(p *Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	var errs []error
+	if h.URL != "" {
+		errs = append(errs, post(h.URL, body))
+	}
+	if h.Command != "" {
+		errs = append(errs, run(h.Command, body, p))
+	}
+	return errors.Join(errs...)
+}
+
+// post sends body to url as JSON
+func post(url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid notify_url: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to post notification: %s", resp.Status)
+	}
+	return nil
+}
+
+// run runs command with sh, with body on its standard input as one line
+func run(command string, body []byte, p *Payload) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(append(body, '\n'))
+	cmd.Env = append(os.Environ(), RunIDEnv+"="+strconv.FormatInt(p.RunID, 10), StatusEnv+"="+p.Status)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("notify_command failed: %w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestBuild(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	started := time.Now().Add(-90 * time.Second)
+	ended := started.Add(90 * time.Second)
+	run := &database.TestRun{ScenarioID: 6, ServerType: "giftless", Protocol: "http", GitServer: "bare",
+		StartedAt: started, Status: "running"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("CreateTestRun failed: %v", err)
+	}
+	run.Status = "failed"
+	run.CompletedAt = &ended
+	if err := db.UpdateTestRun(run); err != nil {
+		t.Fatalf("UpdateTestRun failed: %v", err)
+	}
+	for _, sr := range []*database.StepResult{
+		{RunID: run.ID, StepNumber: 1, Name: "setup", Status: "completed", StartedAt: started},
+		{RunID: run.ID, StepNumber: 2, Name: "push", Status: "failed", StartedAt: started, Error: "git push failed"},
+	} {
+		if err := db.SaveStepResult(sr); err != nil {
+			t.Fatalf("SaveStepResult failed: %v", err)
+		}
+	}
+
+	p, err := Build(db, run.ID)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if p.RunID != run.ID || p.ScenarioID != 6 || p.Status != "failed" || p.DurationMs != 90000 {
+		t.Errorf("payload = %+v", p)
+	}
+	if p.FailedStep != 2 || p.FailedStepName != "push" || p.Error != "git push failed" {
+		t.Errorf("failed step = %d %q %q, want 2 push \"git push failed\"", p.FailedStep, p.FailedStepName, p.Error)
+	}
+	want := "scenario 6 (giftless via http) failed after 1m30s in step 2 (push): git push failed"
+	if !strings.Contains(p.Text, want) {
+		t.Errorf("Text = %q, want it to contain %q", p.Text, want)
+	}
+}
+
+func TestSend(t *testing.T) {
+	var received Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("invalid payload %s: %v", body, err)
+		}
+	}))
+	defer server.Close()
+
+	out := filepath.Join(t.TempDir(), "out")
+	hooks := Hooks{URL: server.URL, Command: "cat > " + out + "; echo $" + RunIDEnv + " $" + StatusEnv + " >> " + out}
+	if !hooks.Enabled() || (Hooks{}).Enabled() {
+		t.Error("Enabled should report whether a hook is set")
+	}
+
+	p := &Payload{RunID: 7, ScenarioID: 1, Status: "completed", Text: "done"}
+	if err := hooks.Send(p); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if received.RunID != 7 || received.Text != "done" {
+		t.Errorf("webhook received %+v", received)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("the command did not run: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 ||
+		!strings.Contains(lines[0], `"run_id":7`) || lines[1] != "7 completed" {
+		t.Errorf("the command received %q", data)
+	}
+
+	// A failing webhook does not keep the command from running
+	os.Remove(out)
+	hooks.URL = server.URL + "/\x7f"
+	if err := hooks.Send(p); err == nil {
+		t.Error("Send should fail with an invalid URL")
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("the command did not run after the webhook failed: %v", err)
+	}
+
+	hooks = Hooks{Command: "echo broken >&2; exit 3"}
+	if err := hooks.Send(p); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Send error = %v, want the command's output", err)
+	}
+}
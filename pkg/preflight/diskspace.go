@@ -0,0 +1,109 @@
+package preflight
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"syscall"
+
+	"github.com/mslinn/git-lfs-test/pkg/config"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+)
+
+// Clones is the number of clones a scenario keeps in its work directory, repo1 and repo2
+const Clones = 2
+
+// SpaceNeeds describes what a scenario writes to its work directory
+type SpaceNeeds struct {
+	Versions   [][]testdata.FileSpec // The files of every version the scenario writes: v1, v2 and v3
+	LocalStore bool                  // The server keeps its LFS objects in the work directory
+	Snapshots  int                   // Full copies of both clones taken by tar snapshots
+}
+
+// FreeSpace returns the bytes available to unprivileged users on the filesystem holding
+// path, or its nearest existing parent when path does not exist yet
+func FreeSpace(path string) (uint64, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		var st syscall.Statfs_t
+		err := syscall.Statfs(path, &st)
+		if err == nil {
+			return uint64(st.Bavail) * uint64(st.Bsize), nil
+		}
+		parent := filepath.Dir(path)
+		if !errors.Is(err, syscall.ENOENT) || parent == path {
+			return 0, fmt.Errorf("failed to get the free space of %s: %w", path, err)
+		}
+		path = parent
+	}
+}
+
+// NeededSpace estimates the disk space a scenario needs in its work directory. Each clone
+// keeps every version in its LFS store and the largest in its working tree; a local
+// server stores every version once more, and every tar snapshot copies both clones.
+func NeededSpace(needs SpaceNeeds) (int64, error) {
+	var total, largest int64
+	for _, files := range needs.Versions {
+		size, err := testdata.TotalSize(files)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+		largest = max(largest, size)
+	}
+	clones := Clones * (total + largest)
+	need := clones + clones*int64(needs.Snapshots)
+	if needs.LocalStore {
+		need += total
+	}
+	return need, nil
+}
+
+// CheckDiskSpace returns an error explaining the shortfall if the filesystem holding dir
+// has less than need bytes free
+func CheckDiskSpace(dir string, need int64) error {
+	free, err := FreeSpace(dir)
+	if err != nil {
+		return err
+	}
+	if free < uint64(need) {
+		return fmt.Errorf("not enough disk space in %s: about %s is needed for the clones, server store and snapshots of the test data, but only %s is free",
+			dir, testdata.FormatSize(need), testdata.FormatSize(int64(free)))
+	}
+	return nil
+}
+
+// checkDiskSpace checks that the work directory has room for both clones of every version
+// of the test data, unless the test data is remote: sizing it takes an SSH command per
+// file. The server store and snapshots depend on the scenario, which lfst-scenario checks.
+func checkDiskSpace(cfg *config.Config) Check {
+	const name = "disk space"
+	dir := cfg.GetWorkDir()
+	path, err := testdata.GetTestDataPath()
+	if err != nil {
+		return Check{Name: name, Status: Skip, Detail: "test data not found"}
+	}
+	if _, remote := testdata.ParseRemotePath(path); remote {
+		return Check{Name: name, Status: Skip, Detail: "test data is remote; lfst-scenario checks before step 1"}
+	}
+	corpus, err := testdata.OpenCorpus(path, "")
+	if err != nil {
+		return Check{Name: name, Status: Skip, Detail: err.Error()}
+	}
+	need, err := NeededSpace(SpaceNeeds{Versions: [][]testdata.FileSpec{corpus.Files("v1"), corpus.Files("v2"), corpus.Files("v3")}})
+	if err != nil {
+		if corpora, _ := testdata.ListCorpora(path); len(corpora) > 0 {
+			return Check{Name: name, Status: Skip, Detail: "test data holds corpora; lfst-scenario checks the space of its --corpus before step 1"}
+		}
+		return Check{Name: name, Status: Skip, Detail: err.Error()}
+	}
+	if err := CheckDiskSpace(dir, need); err != nil {
+		return Check{Name: name, Status: Fail, Detail: err.Error()}
+	}
+	free, _ := FreeSpace(dir)
+	return Check{Name: name, Status: Pass, Detail: fmt.Sprintf("%s free in %s, about %s needed for the clones",
+		testdata.FormatSize(int64(free)), dir, testdata.FormatSize(need))}
+}
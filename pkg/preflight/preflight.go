@@ -0,0 +1,300 @@
+// Package preflight checks the setup that scenarios depend on and reports it as a
+// checklist, so a misconfiguration shows up in seconds instead of 40 minutes into a run
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/config"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/githost"
+	"github.com/mslinn/git-lfs-test/pkg/sshutil"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+)
+
+// MinGitLFSVersion is the oldest git-lfs supported: the first with the SSH transfer
+// protocol that SSH scenarios and the ssh transfer adapter use
+const MinGitLFSVersion = "3.0.0"
+
+// Timeout is how long a server may take to respond
+const Timeout = 10 * time.Second
+
+// Status is the outcome of a check
+type Status string
+
+// Outcomes of checks; only Fail makes the setup unusable
+const (
+	Pass Status = "pass"
+	Warn Status = "warn" // A problem that only some scenarios run into
+	Fail Status = "fail"
+	Skip Status = "skip" // Not checked, e.g. because it does not apply
+)
+
+// Check is one item of the checklist
+type Check struct {
+	Name   string
+	Status Status
+	Detail string // What was found, or what is wrong and how to fix it
+}
+
+// Options adjust what Run checks
+type Options struct {
+	// URLs of the LFS and git servers of the scenarios, which must respond over HTTP
+	ServerURLs []string
+	// Offline skips the checks of GitHub
+	Offline bool
+}
+
+// Run checks the configuration and the tools, test data and servers it refers to
+func Run(cfg *config.Config, opts Options) []Check {
+	checks := append(checkSecrets(cfg),
+		checkDatabase(cfg),
+		checkRemoteHost(cfg),
+		checkGit(),
+		checkGitLFS(),
+		checkTestData(),
+	)
+	if dir := cfg.GetTestDataCache(); dir != "" {
+		checks = append(checks, checkTestDataCache(dir))
+	}
+	// gh checks github_token if the config has one; checkSecrets reports one that cannot be found
+	_ = cfg.ExportSecrets()
+	checks = append(checks, checkDiskSpace(cfg), checkGitHub(opts.Offline))
+	for _, url := range opts.ServerURLs {
+		checks = append(checks, CheckServer(url))
+	}
+	return checks
+}
+
+// Failed returns how many checks failed
+func Failed(checks []Check) int {
+	failed := 0
+	for _, c := range checks {
+		if c.Status == Fail {
+			failed++
+		}
+	}
+	return failed
+}
+
+// result returns a check that passed with detail, or failed with err
+func result(name, detail string, err error) Check {
+	if err != nil {
+		return Check{Name: name, Status: Fail, Detail: err.Error()}
+	}
+	return Check{Name: name, Status: Pass, Detail: detail}
+}
+
+// checkSecrets checks that the secrets the configuration refers to can be found
+func checkSecrets(cfg *config.Config) []Check {
+	var checks []Check
+	for _, k := range config.Keys {
+		if ref := cfg.Reference(k.Name); ref != "" {
+			_, err := cfg.Secret(k.Name)
+			checks = append(checks, result(k.Name, "found in "+ref, err))
+		}
+	}
+	return checks
+}
+
+func checkDatabase(cfg *config.Config) Check {
+	path, err := cfg.GetDatabasePath()
+	if err != nil {
+		return result("database", "", err)
+	}
+	return result("database", database.Redact(path), cfg.ValidateDatabase())
+}
+
+func checkRemoteHost(cfg *config.Config) Check {
+	switch {
+	case !cfg.AutoRemote:
+		return Check{Name: "remote host", Status: Skip, Detail: "auto_remote is off"}
+	case cfg.RemoteHost != "" && !cfg.IsRemoteHost():
+		return Check{Name: "remote host", Status: Pass, Detail: "this machine is " + cfg.RemoteHost}
+	case cfg.ImportURL != "":
+		// Checksums go to lfst-import --listen, so SSH is not needed
+		token, err := cfg.GetImportToken()
+		if err != nil {
+			return result("import endpoint", "", err)
+		}
+		return CheckImportURL(cfg.ImportURL, token, cfg.ImportCACert)
+	}
+	return result("remote host", cfg.RemoteHost+" is reachable over SSH", cfg.ValidateRemoteHost())
+}
+
+func checkGit() Check {
+	output, err := exec.Command("git", "--version").Output()
+	if err != nil {
+		return Check{Name: "git", Status: Fail, Detail: "git is not installed or not in PATH"}
+	}
+	return Check{Name: "git", Status: Pass, Detail: strings.TrimSpace(string(output))}
+}
+
+func checkGitLFS() Check {
+	output, err := exec.Command("git", "lfs", "version").Output()
+	if err != nil {
+		return Check{Name: "git-lfs", Status: Fail, Detail: "git-lfs is not installed or not in PATH; install it with: apt-get install git-lfs"}
+	}
+	found, err := ParseGitLFSVersion(string(output))
+	if err != nil {
+		return Check{Name: "git-lfs", Status: Fail, Detail: err.Error()}
+	}
+	if !AtLeast(found, MinGitLFSVersion) {
+		return Check{Name: "git-lfs", Status: Fail, Detail: fmt.Sprintf("git-lfs %s is older than %s", found, MinGitLFSVersion)}
+	}
+	return Check{Name: "git-lfs", Status: Pass, Detail: "git-lfs " + found}
+}
+
+// gitLFSVersion matches the version in the output of git lfs version, e.g.
+// git-lfs/3.4.1 (GitHub; linux amd64; go 1.21.5)
+var gitLFSVersion = regexp.MustCompile(`git-lfs/(\d+\.\d+\.\d+)`)
+
+// ParseGitLFSVersion returns the version in the output of git lfs version
+func ParseGitLFSVersion(output string) (string, error) {
+	m := gitLFSVersion.FindStringSubmatch(output)
+	if m == nil {
+		return "", fmt.Errorf("cannot find the version of git-lfs in %q", strings.TrimSpace(output))
+	}
+	return m[1], nil
+}
+
+// AtLeast reports whether a MAJOR.MINOR.PATCH version is min or newer
+func AtLeast(version, min string) bool {
+	v, m := strings.Split(version, "."), strings.Split(min, ".")
+	for i := range m {
+		var a int
+		if i < len(v) {
+			a, _ = strconv.Atoi(v[i])
+		}
+		b, _ := strconv.Atoi(m[i])
+		if a != b {
+			return a > b
+		}
+	}
+	return true
+}
+
+func checkTestData() Check {
+	path, err := testdata.GetTestDataPath()
+	if err != nil {
+		return Check{Name: "test data", Status: Fail, Detail: strings.SplitN(err.Error(), "\n", 2)[0]}
+	}
+
+	corpus, err := testdata.OpenCorpus(path, "")
+	if err != nil {
+		return Check{Name: "test data", Status: Fail, Detail: strings.SplitN(err.Error(), "\n", 2)[0]}
+	}
+
+	// Named corpora are listed for local test data only, as listing costs an SSH command
+	corpora, _ := testdata.ListCorpora(path)
+
+	// The data set is there if its first file is, as lfst-scenario checks before step 1
+	files := corpus.Files("v1")
+	first := files[0].SourcePath
+	if remote, ok := testdata.ParseRemotePath(first); ok {
+		if err := sshutil.Command(remote.Host, "test", "-f", remote.Path).Run(); err != nil {
+			return Check{Name: "test data", Status: Fail, Detail: fmt.Sprintf("%s is missing on %s", remote.Path, remote.Host)}
+		}
+	} else if _, err := os.Stat(first); err != nil {
+		// A root may hold only named corpora, which scenarios select with --corpus
+		if len(corpora) > 0 {
+			return Check{Name: "test data", Status: Pass, Detail: fmt.Sprintf("%s holds corpora %s", path, strings.Join(corpora, ", "))}
+		}
+		return Check{Name: "test data", Status: Fail, Detail: first + " is missing"}
+	}
+	detail := fmt.Sprintf("%s (%d files)", path, len(files))
+	if len(corpora) > 0 {
+		detail += fmt.Sprintf(", and corpora %s", strings.Join(corpora, ", "))
+	}
+	return Check{Name: "test data", Status: Pass, Detail: detail}
+}
+
+// checkTestDataCache checks that the test_data_cache directory can be written, and
+// reports what it holds
+func checkTestDataCache(dir string) Check {
+	const name = "test data cache"
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Check{Name: name, Status: Fail, Detail: err.Error()}
+	}
+	f, err := os.CreateTemp(dir, ".lfst-check-*")
+	if err != nil {
+		return Check{Name: name, Status: Fail, Detail: dir + " is not writable"}
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	files, size, err := testdata.CacheUsage(dir)
+	if err != nil {
+		return Check{Name: name, Status: Warn, Detail: err.Error()}
+	}
+	return Check{Name: name, Status: Pass, Detail: fmt.Sprintf("%s holds %d files, %s", dir, files, testdata.FormatSize(size))}
+}
+
+// checkGitHub warns rather than fails: only scenarios with GitHub need gh
+func checkGitHub(offline bool) Check {
+	if offline {
+		return Check{Name: "GitHub", Status: Skip, Detail: "offline mode is enabled"}
+	}
+	gh := &githost.GitHub{}
+	if err := gh.CheckCLI(); err != nil {
+		return Check{Name: "GitHub", Status: Warn, Detail: err.Error() + "; scenarios with GitHub need it"}
+	}
+	if err := gh.CheckAuth(); err != nil {
+		return Check{Name: "GitHub", Status: Warn, Detail: "gh is not logged in; log in with: gh auth login --hostname github.com --git-protocol https --scopes repo,delete_repo"}
+	}
+	return Check{Name: "GitHub", Status: Pass, Detail: "gh is logged in to github.com"}
+}
+
+// CheckImportURL checks that lfst-import --listen answers at url: it refuses a GET, as
+// it only accepts checksums, while the TLS handshake proves its certificate is trusted
+func CheckImportURL(url, token, caFile string) Check {
+	const name = "import endpoint"
+	if token == "" {
+		return Check{Name: name, Status: Fail, Detail: "import_url is set but import_token is not; set it with: lfst-config set import_token TOKEN"}
+	}
+	client, err := checksum.UploadClient(caFile)
+	if err != nil {
+		return Check{Name: name, Status: Fail, Detail: err.Error()}
+	}
+	client.Timeout = Timeout
+	resp, err := client.Get(url + checksum.ImportPath)
+	if err != nil {
+		return Check{Name: name, Status: Fail, Detail: fmt.Sprintf("no response: %v", err)}
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		return Check{Name: name, Status: Fail, Detail: fmt.Sprintf("%s responded %s; is it lfst-import --listen?", url, resp.Status)}
+	}
+	return Check{Name: name, Status: Pass, Detail: url + " accepts checksums over HTTPS"}
+}
+
+// CheckServer checks that a server responds over HTTP. Any response below 500 will do,
+// since the root of an LFS server is usually not found or needs credentials.
+func CheckServer(url string) Check {
+	name := "server " + url
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Check{Name: name, Status: Fail, Detail: fmt.Sprintf("invalid URL: %v", err)}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Check{Name: name, Status: Fail, Detail: fmt.Sprintf("no response: %v", err)}
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return Check{Name: name, Status: Fail, Detail: "responded " + resp.Status}
+	}
+	return Check{Name: name, Status: Pass, Detail: "responded " + resp.Status}
+}
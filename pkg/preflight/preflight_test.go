@@ -0,0 +1,129 @@
+package preflight
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+)
+
+func TestParseGitLFSVersion(t *testing.T) {
+	version, err := ParseGitLFSVersion("git-lfs/3.4.1 (GitHub; linux amd64; go 1.21.5)\n")
+	if err != nil || version != "3.4.1" {
+		t.Errorf("ParseGitLFSVersion = %q, %v; want 3.4.1", version, err)
+	}
+	if _, err := ParseGitLFSVersion("git: 'lfs' is not a git command"); err == nil {
+		t.Error("ParseGitLFSVersion should fail without a version")
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	tests := []struct {
+		version, min string
+		want         bool
+	}{
+		{"3.0.0", "3.0.0", true},
+		{"3.4.1", "3.0.0", true},
+		{"10.0.0", "3.0.0", true},
+		{"2.13.3", "3.0.0", false},
+		{"3.0", "3.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := AtLeast(tt.version, tt.min); got != tt.want {
+			t.Errorf("AtLeast(%q, %q) = %v, want %v", tt.version, tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	var files []testdata.FileSpec
+	for i, size := range []int{1000, 500} {
+		path := filepath.Join(dir, fmt.Sprintf("file%d.bin", i))
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, testdata.FileSpec{Name: filepath.Base(path), SourcePath: path})
+	}
+	// Two versions of 1500 and 1000 bytes: each clone holds both in its LFS store and
+	// the larger in its working tree
+	versions := [][]testdata.FileSpec{files, files[:1]}
+	need, err := NeededSpace(SpaceNeeds{Versions: versions})
+	if err != nil || need != Clones*(2500+1500) {
+		t.Errorf("NeededSpace = %d, %v, want %d", need, err, Clones*(2500+1500))
+	}
+	need, err = NeededSpace(SpaceNeeds{Versions: versions, LocalStore: true, Snapshots: 3})
+	if want := int64(Clones*(2500+1500)*4 + 2500); err != nil || need != want {
+		t.Errorf("NeededSpace with a local store and snapshots = %d, %v, want %d", need, err, want)
+	}
+	missing := []testdata.FileSpec{{SourcePath: filepath.Join(dir, "missing")}}
+	if _, err := NeededSpace(SpaceNeeds{Versions: [][]testdata.FileSpec{files, missing}}); err == nil {
+		t.Error("NeededSpace should fail for a missing file")
+	}
+
+	// A work directory that does not exist yet is on the filesystem of its parent
+	workDir := filepath.Join(dir, "work", "runs")
+	free, err := FreeSpace(workDir)
+	if err != nil || free == 0 {
+		t.Fatalf("FreeSpace = %d, %v", free, err)
+	}
+	if err := CheckDiskSpace(workDir, need); err != nil {
+		t.Errorf("CheckDiskSpace of %d bytes failed: %v", need, err)
+	}
+	err = CheckDiskSpace(workDir, int64(free)+1<<40)
+	if err == nil || !strings.Contains(err.Error(), "not enough disk space in "+workDir) {
+		t.Errorf("CheckDiskSpace of more than is free = %v", err)
+	}
+}
+
+func TestCheckServer(t *testing.T) {
+	status := http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	if c := CheckServer(server.URL); c.Status != Pass {
+		t.Errorf("a server that answers 404 should pass: %+v", c)
+	}
+	status = http.StatusBadGateway
+	if c := CheckServer(server.URL); c.Status != Fail {
+		t.Errorf("a server that answers 502 should fail: %+v", c)
+	}
+	server.Close()
+	if c := CheckServer(server.URL); c.Status != Fail {
+		t.Errorf("a server that is down should fail: %+v", c)
+	}
+
+	checks := []Check{{Status: Pass}, {Status: Fail}, {Status: Warn}, {Status: Fail}, {Status: Skip}}
+	if n := Failed(checks); n != 2 {
+		t.Errorf("Failed = %d, want 2", n)
+	}
+}
+
+func TestCheckImportURL(t *testing.T) {
+	server := httptest.NewTLSServer(&checksum.ImportServer{Token: "s3cr3t-token"})
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := CheckImportURL(server.URL, "s3cr3t-token", caFile); c.Status != Pass {
+		t.Errorf("lfst-import --listen should pass: %+v", c)
+	}
+	if c := CheckImportURL(server.URL, "", caFile); c.Status != Fail {
+		t.Errorf("a missing token should fail: %+v", c)
+	}
+	if c := CheckImportURL(server.URL, "s3cr3t-token", ""); c.Status != Fail {
+		t.Errorf("an untrusted certificate should fail: %+v", c)
+	}
+}
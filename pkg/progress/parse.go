@@ -0,0 +1,69 @@
+package progress
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// Report is what one progress line of git, git-lfs or rsync says
+type Report struct {
+	Phase   string // e.g. "Uploading LFS objects"; empty for rsync
+	Bytes   int64  // Bytes done so far in the phase, or of the file for rsync
+	Percent int    // Percentage done, of the objects for git and git-lfs
+}
+
+var (
+	// e.g. "Uploading LFS objects:  45% (9/20), 512 MB | 25 MB/s",
+	// "Writing objects:  40% (2/5)" and "remote: Counting objects: 100% (5/5), done."
+	gitLineRE = regexp.MustCompile(`^(?:remote:\s*)?([A-Za-z][A-Za-z ]*?):\s+(\d+)% \(\d+/\d+\)(?:, ([\d.]+) (bytes|[KMGT]?i?B))?`)
+	// e.g. "    557,056  42%   10.59MB/s    0:00:07" from rsync --progress
+	rsyncLineRE = regexp.MustCompile(`^\s*([\d,]+)\s+(\d+)%\s`)
+)
+
+// ParseLine reads a progress line of git, git-lfs or rsync
+func ParseLine(line string) (Report, bool) {
+	if m := gitLineRE.FindStringSubmatch(line); m != nil {
+		r := Report{Phase: m[1]}
+		r.Percent, _ = strconv.Atoi(m[2])
+		if m[3] != "" {
+			r.Bytes = timing.ParseBytes(m[3], m[4])
+		}
+		return r, true
+	}
+	if m := rsyncLineRE.FindStringSubmatch(line); m != nil {
+		var r Report
+		r.Bytes, _ = strconv.ParseInt(strings.ReplaceAll(m[1], ",", ""), 10, 64)
+		r.Percent, _ = strconv.Atoi(m[2])
+		return r, true
+	}
+	return Report{}, false
+}
+
+// LineWriter returns a writer that calls fn with each line written to it. Progress meters
+// rewrite their line with \r, so \r ends a line as \n does.
+func LineWriter(fn func(line string)) io.Writer {
+	return &lineWriter{fn: fn}
+}
+
+type lineWriter struct {
+	fn      func(line string)
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	for _, c := range p {
+		if c != '\r' && c != '\n' {
+			w.partial = append(w.partial, c)
+			continue
+		}
+		if len(w.partial) > 0 {
+			w.fn(string(w.partial))
+			w.partial = w.partial[:0]
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,267 @@
+// Package progress shows how far long copies and transfers have got, on standard error.
+// On a terminal a spinner line with the bytes done, the rate and the time left is redrawn
+// in place; elsewhere, e.g. in a CI log, a plain line is printed every PlainInterval.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/term"
+)
+
+// Mode selects when progress is shown
+type Mode int
+
+const (
+	Auto Mode = iota // On a terminal, unless in quiet mode
+	On               // Always; plain lines when standard error is not a terminal
+	Off              // Never
+)
+
+// PlainInterval is the time between the progress lines printed when standard error is
+// not a terminal
+const PlainInterval = 10 * time.Second
+
+// redrawInterval is the time between redraws of the progress line on a terminal
+const redrawInterval = 200 * time.Millisecond
+
+// spinner is drawn one frame per redraw
+var spinner = []string{"|", "/", "-", "\\"}
+
+var (
+	mode   atomic.Int32
+	active atomic.Bool // A bar is showing; tasks running alongside it get none of their own
+)
+
+// Setup applies the --progress and --no-progress flags. Call it after term.Setup, which
+// decides whether quiet mode is on.
+func Setup(m Mode) {
+	mode.Store(int32(m))
+}
+
+// Enabled reports whether progress is shown
+func Enabled() bool {
+	switch Mode(mode.Load()) {
+	case On:
+		return true
+	case Off:
+		return false
+	}
+	return !term.Quiet() && os.Getenv("TERM") != "dumb" && term.IsTerminal(os.Stderr)
+}
+
+// Bar shows the progress of one task. The nil *Bar that Start returns when progress is not
+// shown ignores every call, so callers need not check for it.
+type Bar struct {
+	label    string
+	out      io.Writer
+	tty      bool
+	interval time.Duration
+
+	mu         sync.Mutex
+	total      int64     // Bytes the task moves in all; 0 if unknown
+	done       int64     // Bytes moved so far in the current phase
+	percent    int       // Percentage the command last reported, -1 if none
+	phase      string    // Phase the command last reported, e.g. "Uploading LFS objects"
+	phaseStart time.Time // When the current phase started, for its rate
+	frame      int
+	printed    bool // A plain line was printed
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// Start shows the progress of the task named label, which moves total bytes (0 if
+// unknown), until Finish is called. It returns nil when progress is not shown, or when
+// another task is already showing its progress.
+func Start(label string, total int64) *Bar {
+	if !Enabled() || !active.CompareAndSwap(false, true) {
+		return nil
+	}
+	tty := term.IsTerminal(os.Stderr)
+	interval := PlainInterval
+	if tty {
+		interval = redrawInterval
+	}
+	b := newBar(label, total, os.Stderr, tty, interval)
+	go b.loop()
+	return b
+}
+
+// newBar returns a bar that draws on out
+func newBar(label string, total int64, out io.Writer, tty bool, interval time.Duration) *Bar {
+	return &Bar{
+		label:      label,
+		out:        out,
+		tty:        tty,
+		interval:   interval,
+		total:      total,
+		percent:    -1,
+		phaseStart: time.Now(),
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+}
+
+// Add counts n more bytes done
+func (b *Bar) Add(n int64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.done += n
+	b.mu.Unlock()
+}
+
+// Set sets the bytes done
+func (b *Bar) Set(done int64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.done = done
+	b.mu.Unlock()
+}
+
+// Bytes returns the bytes done
+func (b *Bar) Bytes() int64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.done
+}
+
+// Write counts the bytes written through it as done, so a copy can report its progress
+// through an io.MultiWriter
+func (b *Bar) Write(p []byte) (int, error) {
+	b.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// Update shows what a progress line of the command said. A new phase restarts the count.
+func (b *Bar) Update(r Report) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if r.Phase != b.phase {
+		b.phase = r.Phase
+		b.phaseStart = time.Now()
+	}
+	b.done = r.Bytes
+	b.percent = r.Percent
+}
+
+// Output returns a writer that shows the progress lines of git, git-lfs or rsync written
+// to it, such as the standard error of git push --progress
+func (b *Bar) Output() io.Writer {
+	if b == nil {
+		return io.Discard
+	}
+	return LineWriter(func(line string) {
+		if r, ok := ParseLine(line); ok {
+			b.Update(r)
+		}
+	})
+}
+
+// Finish stops showing the progress: the line is cleared on a terminal, and a final line
+// follows the plain lines printed elsewhere
+func (b *Bar) Finish() {
+	if b == nil {
+		return
+	}
+	close(b.stop)
+	<-b.stopped
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tty {
+		fmt.Fprint(b.out, "\r\033[K")
+	} else if b.printed {
+		fmt.Fprintf(b.out, "%s: done, %s\n", b.label, formatBytes(b.done))
+	}
+	active.Store(false)
+}
+
+// loop draws the progress every interval until Finish
+func (b *Bar) loop() {
+	defer close(b.stopped)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case now := <-ticker.C:
+			b.draw(now)
+		}
+	}
+}
+
+// draw redraws the progress line on a terminal, or prints a plain line
+func (b *Bar) draw(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tty {
+		b.frame++
+		fmt.Fprintf(b.out, "\r%s %s\033[K", spinner[b.frame%len(spinner)], b.status(now))
+		return
+	}
+	fmt.Fprintln(b.out, b.status(now))
+	b.printed = true
+}
+
+// status describes the progress at now, e.g.
+// "Copying test files: 512.0 MB of 1.3 GB (39%), 85.3 MB/s, 10s left"
+func (b *Bar) status(now time.Time) string {
+	label := b.label
+	if b.phase != "" {
+		label += " - " + b.phase
+	}
+
+	parts := []string{formatBytes(b.done)}
+	fraction := -1.0
+	switch {
+	case b.total > 0:
+		fraction = min(float64(b.done)/float64(b.total), 1)
+		parts[0] += fmt.Sprintf(" of %s (%d%%)", formatBytes(b.total), int(fraction*100))
+	case b.percent >= 0:
+		fraction = float64(b.percent) / 100
+		parts[0] += fmt.Sprintf(" (%d%%)", b.percent)
+	}
+
+	elapsed := now.Sub(b.phaseStart)
+	if elapsed >= time.Second && b.done > 0 {
+		parts = append(parts, formatBytes(int64(float64(b.done)/elapsed.Seconds()))+"/s")
+	}
+	if elapsed >= time.Second && fraction > 0 && fraction < 1 {
+		left := time.Duration(float64(elapsed) * (1 - fraction) / fraction)
+		parts = append(parts, left.Round(time.Second).String()+" left")
+	}
+	return label + ": " + strings.Join(parts, ", ")
+}
+
+// formatBytes formats a byte count with one decimal, e.g. "1.3 GB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n) / unit
+	prefix := 0
+	for value >= unit && prefix < 3 {
+		value /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "KMGT"[prefix])
+}
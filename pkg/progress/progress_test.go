@@ -0,0 +1,98 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want Report
+		ok   bool
+	}{
+		{"Uploading LFS objects:  45% (9/20), 512 MB | 25 MB/s", Report{Phase: "Uploading LFS objects", Bytes: 512000000, Percent: 45}, true},
+		{"Writing objects:  40% (2/5)", Report{Phase: "Writing objects", Percent: 40}, true},
+		{"Receiving objects: 100% (12/12), 1.50 KiB | 1.50 MiB/s, done.", Report{Phase: "Receiving objects", Bytes: 1536, Percent: 100}, true},
+		{"remote: Counting objects: 100% (5/5), done.", Report{Phase: "Counting objects", Percent: 100}, true},
+		{"    557,056  42%   10.59MB/s    0:00:07", Report{Bytes: 557056, Percent: 42}, true},
+		{"video2.mov", Report{}, false},
+		{"Enumerating objects: 5, done.", Report{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseLine(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseLine(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := LineWriter(func(line string) { lines = append(lines, line) })
+	io.WriteString(w, "Writing objects:  40% (2/5)\rWriting ob")
+	io.WriteString(w, "jects: 100% (5/5), done.\n\nTo server\n")
+
+	want := []string{"Writing objects:  40% (2/5)", "Writing objects: 100% (5/5), done.", "To server"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	b := newBar("Copying test files", 4<<30, io.Discard, false, time.Hour)
+	start := b.phaseStart
+	b.Add(1 << 30)
+
+	got := b.status(start.Add(10 * time.Second))
+	want := "Copying test files: 1.0 GB of 4.0 GB (25%), 102.4 MB/s, 30s left"
+	if got != want {
+		t.Errorf("status = %q, want %q", got, want)
+	}
+
+	b = newBar("Pushing", 0, io.Discard, false, time.Hour)
+	b.Update(Report{Phase: "Uploading LFS objects", Bytes: 5 << 20, Percent: 50})
+	got = b.status(b.phaseStart.Add(2 * time.Second))
+	want = "Pushing - Uploading LFS objects: 5.0 MB (50%), 2.5 MB/s, 2s left"
+	if got != want {
+		t.Errorf("status = %q, want %q", got, want)
+	}
+}
+
+func TestPlainLines(t *testing.T) {
+	var out bytes.Buffer
+	b := newBar("Pulling", 0, &out, false, 10*time.Millisecond)
+	active.Store(true)
+	go b.loop()
+	b.Output().Write([]byte("Downloading LFS objects:  50% (1/2), 2.0 MiB | 1 MiB/s\r"))
+	time.Sleep(50 * time.Millisecond)
+	b.Finish()
+
+	if !strings.Contains(out.String(), "Pulling - Downloading LFS objects: 2.0 MB (50%)") {
+		t.Errorf("output = %q, want a plain progress line", out.String())
+	}
+	if !strings.HasSuffix(out.String(), "Pulling: done, 2.0 MB\n") {
+		t.Errorf("output = %q, want a final line", out.String())
+	}
+	if active.Load() {
+		t.Error("Finish should let another task show its progress")
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	Setup(Off)
+	defer Setup(Auto)
+
+	b := Start("Copying", 100)
+	if b != nil {
+		t.Fatal("Start should return nil when progress is off")
+	}
+	// A nil bar ignores every call
+	b.Add(10)
+	b.Update(Report{Bytes: 5})
+	b.Output().Write([]byte("Writing objects:  40% (2/5)\n"))
+	b.Finish()
+}
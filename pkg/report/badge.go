@@ -0,0 +1,177 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/i18n"
+)
+
+// Summary is the latest evaluation status: one run per configuration, for a README badge
+// or a compact table
+type Summary struct {
+	Rows   []*SummaryRow
+	Passed int // Rows whose run completed
+	Failed int // Rows whose run failed, timed out or was aborted
+}
+
+// SummaryRow is one run of a Summary
+type SummaryRow struct {
+	Run     *database.TestRun
+	TotalMs int64 // Total operation time
+}
+
+// Passed reports whether the run of the row completed
+func (row *SummaryRow) Passed() bool {
+	return row.Run.Status == "completed"
+}
+
+// BuildSummary summarizes the given runs or, without runIDs, the latest finished run of
+// each combination of scenario, server, protocol and git server among the runs that carry
+// every tag, e.g. those of a campaign. Running and cancelled runs say nothing about the
+// servers, so they are left out of the latest runs.
+func BuildSummary(db *database.DB, runIDs []int64, tags []database.Tag) (*Summary, error) {
+	var runs []*database.TestRun
+	if len(runIDs) > 0 {
+		for _, runID := range runIDs {
+			run, err := db.GetTestRun(runID)
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, run)
+		}
+	} else {
+		all, err := db.ListTaggedRuns(tags)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, run := range all { // Newest first
+			if run.Status == "running" || run.Status == "cancelled" {
+				continue
+			}
+			key := fmt.Sprintf("%d/%s/%s/%s", run.ScenarioID, run.ServerType, run.Protocol, run.GitServer)
+			if !seen[key] {
+				seen[key] = true
+				runs = append(runs, run)
+			}
+		}
+		sort.SliceStable(runs, func(i, j int) bool {
+			a, b := runs[i], runs[j]
+			if a.ScenarioID != b.ScenarioID {
+				return a.ScenarioID < b.ScenarioID
+			}
+			return a.ServerType+a.Protocol+a.GitServer < b.ServerType+b.Protocol+b.GitServer
+		})
+	}
+
+	s := &Summary{}
+	for _, run := range runs {
+		ops, err := db.ListOperations(run.ID)
+		if err != nil {
+			return nil, err
+		}
+		row := &SummaryRow{Run: run}
+		for _, op := range ops {
+			row.TotalMs += op.DurationMs
+		}
+		s.Rows = append(s.Rows, row)
+		if row.Passed() {
+			s.Passed++
+		} else {
+			s.Failed++
+		}
+	}
+	return s, nil
+}
+
+// Message is the right-hand side of the badge, e.g. "5 passed, 1 failed"
+func (s *Summary) Message() string {
+	switch {
+	case len(s.Rows) == 0:
+		return i18n.T("no runs")
+	case s.Failed == 0:
+		return i18n.T("%d passed", s.Passed)
+	default:
+		return i18n.T("%d passed, %d failed", s.Passed, s.Failed)
+	}
+}
+
+// color is the badge color: green when every run passed, red when none did
+func (s *Summary) color() string {
+	switch {
+	case len(s.Rows) == 0:
+		return "#9f9f9f"
+	case s.Failed == 0:
+		return "#4c1"
+	case s.Passed == 0:
+		return "#e05d44"
+	default:
+		return "#dfb317"
+	}
+}
+
+// textWidth estimates the width in pixels of text in 11px Verdana, the badge font
+func textWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune("il.,:;|!' ", r):
+			width += 4
+		case strings.ContainsRune("mwMW", r):
+			width += 10
+		case r >= 'A' && r <= 'Z':
+			width += 8
+		default:
+			width += 7
+		}
+	}
+	return width
+}
+
+// RenderBadge writes the summary as an SVG badge in the style of shields.io, with label
+// on the left, e.g. "git lfs | 5 passed, 1 failed"
+func RenderBadge(w io.Writer, s *Summary, label string) error {
+	message := s.Message()
+	left := textWidth(label) + 10
+	right := textWidth(message) + 10
+	total := left + right
+
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[7]d" y="14">%[4]s</text>
+<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[8]d" y="14">%[5]s</text>
+</g>
+</svg>
+`, total, left, right, html.EscapeString(label), html.EscapeString(message), s.color(), left/2, left+right/2)
+	return err
+}
+
+// RenderMarkdownSummary writes the summary as a Markdown table for a README or wiki
+func RenderMarkdownSummary(w io.Writer, s *Summary) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s | %s |\n", i18n.T("Scenario"), i18n.T("Server"), i18n.T("Protocol"),
+		i18n.T("Git"), i18n.T("Status"), i18n.T("Operation time"), i18n.T("Run"), i18n.T("Date"))
+	fmt.Fprintf(&b, "|---:|---|---|---|---|---:|---:|---|\n")
+	for _, row := range s.Rows {
+		run := row.Run
+		status := i18n.T(run.Status)
+		if !row.Passed() {
+			status = "**" + status + "**"
+		}
+		fmt.Fprintf(&b, "| %d | %s | %s | %s | %s | %s | %d | %s |\n",
+			run.ScenarioID, run.ServerType, run.Protocol, run.GitServer, status,
+			formatMs(row.TotalMs), run.ID, run.StartedAt.Format("2006-01-02"))
+	}
+	fmt.Fprintf(&b, "\n**%s**\n", s.Message())
+	_, err := io.WriteString(w, b.String())
+	return err
+}
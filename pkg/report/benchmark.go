@@ -0,0 +1,111 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+// BenchmarkStats summarizes the operation timings of the runs of a benchmark by operation
+// type, in the order the operation types first occur. Repeated operations of a type within
+// a run are summed, so each run that performed it gives one sample; failed operations are
+// left out.
+func BenchmarkStats(db *database.DB, runIDs []int64) ([]*database.BenchmarkStat, error) {
+	var order []string
+	samples := make(map[string][]int64)
+
+	for _, runID := range runIDs {
+		ops, err := db.ListOperations(runID)
+		if err != nil {
+			return nil, err
+		}
+
+		totals := make(map[string]int64)
+		var runOrder []string
+		for _, op := range ops {
+			if op.Status != "success" {
+				continue
+			}
+			if _, seen := totals[op.Operation]; !seen {
+				runOrder = append(runOrder, op.Operation)
+			}
+			totals[op.Operation] += op.DurationMs
+		}
+		for _, name := range runOrder {
+			if samples[name] == nil {
+				order = append(order, name)
+			}
+			samples[name] = append(samples[name], totals[name])
+		}
+	}
+
+	stats := make([]*database.BenchmarkStat, 0, len(order))
+	for _, name := range order {
+		stats = append(stats, Summarize(name, samples[name]))
+	}
+	return stats, nil
+}
+
+// Summarize computes the mean, median, sample standard deviation, minimum and maximum
+// of the durations of an operation type, in milliseconds
+func Summarize(operation string, samples []int64) *database.BenchmarkStat {
+	s := &database.BenchmarkStat{Operation: operation, Samples: len(samples)}
+	if len(samples) == 0 {
+		return s
+	}
+
+	sorted := append([]int64(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	s.MinMs = sorted[0]
+	s.MaxMs = sorted[len(sorted)-1]
+
+	var sum float64
+	for _, v := range sorted {
+		sum += float64(v)
+	}
+	s.MeanMs = sum / float64(len(sorted))
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		s.MedianMs = float64(sorted[mid])
+	} else {
+		s.MedianMs = float64(sorted[mid-1]+sorted[mid]) / 2
+	}
+
+	if len(sorted) > 1 {
+		var squares float64
+		for _, v := range sorted {
+			squares += (float64(v) - s.MeanMs) * (float64(v) - s.MeanMs)
+		}
+		s.StdDevMs = math.Sqrt(squares / float64(len(sorted)-1))
+	}
+	return s
+}
+
+// RenderBenchmarkStats writes benchmark statistics as a text table. The coefficient of
+// variation (CV), the standard deviation as a percentage of the mean, shows how noisy each
+// operation type is.
+func RenderBenchmarkStats(w io.Writer, stats []*database.BenchmarkStat) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Operation\tRuns\tMean\tMedian\tStdDev\tCV\tMin\tMax")
+	fmt.Fprintln(tw, "---------\t----\t----\t------\t------\t--\t---\t---")
+	for _, s := range stats {
+		cv := "-"
+		if s.Samples > 1 && s.MeanMs > 0 {
+			cv = fmt.Sprintf("%.1f%%", s.StdDevMs/s.MeanMs*100)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Operation, s.Samples,
+			formatMsFloat(s.MeanMs), formatMsFloat(s.MedianMs), formatMsFloat(s.StdDevMs), cv,
+			formatMs(s.MinMs), formatMs(s.MaxMs))
+	}
+	return tw.Flush()
+}
+
+// formatMsFloat formats a fractional millisecond duration for display, to the millisecond
+func formatMsFloat(ms float64) string {
+	return formatMs(int64(math.Round(ms)))
+}
@@ -0,0 +1,98 @@
+package report
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestSummarize(t *testing.T) {
+	s := Summarize("push", []int64{400, 100, 300, 200})
+	if s.Samples != 4 || s.MinMs != 100 || s.MaxMs != 400 {
+		t.Errorf("Summarize = %+v", s)
+	}
+	if s.MeanMs != 250 || s.MedianMs != 250 {
+		t.Errorf("mean %v, median %v; want 250 and 250", s.MeanMs, s.MedianMs)
+	}
+	if math.Abs(s.StdDevMs-129.0994) > 0.001 {
+		t.Errorf("stddev = %v, want the sample standard deviation 129.0994", s.StdDevMs)
+	}
+
+	if s := Summarize("clone", []int64{70}); s.MedianMs != 70 || s.StdDevMs != 0 {
+		t.Errorf("Summarize of one sample = %+v", s)
+	}
+}
+
+func TestBenchmarkStats(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	b := &database.Benchmark{ScenarioID: 1, Iterations: 2, StartedAt: time.Now()}
+	if err := db.CreateBenchmark(b); err != nil {
+		t.Fatalf("CreateBenchmark failed: %v", err)
+	}
+
+	var runIDs []int64
+	for i, pushes := range [][]int64{{100, 50}, {300}} {
+		run := &database.TestRun{ScenarioID: 1, ServerType: "bare", Protocol: "local", GitServer: "bare", StartedAt: time.Now(), Status: "completed"}
+		if err := db.CreateTestRun(run); err != nil {
+			t.Fatalf("CreateTestRun failed: %v", err)
+		}
+		ops := []*database.Operation{{RunID: run.ID, StepNumber: 1, Operation: "init", DurationMs: 10, Status: "success"}}
+		for _, ms := range pushes {
+			ops = append(ops, &database.Operation{RunID: run.ID, StepNumber: 2, Operation: "push", DurationMs: ms, Status: "success"})
+		}
+		ops = append(ops, &database.Operation{RunID: run.ID, StepNumber: 2, Operation: "push", DurationMs: 9000, Status: "failed"})
+		for _, op := range ops {
+			op.StartedAt = time.Now()
+			if err := db.CreateOperation(op); err != nil {
+				t.Fatalf("CreateOperation failed: %v", err)
+			}
+		}
+		if err := db.AddBenchmarkRun(b.ID, run.ID, i+1); err != nil {
+			t.Fatalf("AddBenchmarkRun failed: %v", err)
+		}
+		runIDs = append(runIDs, run.ID)
+	}
+
+	stats, err := BenchmarkStats(db, runIDs)
+	if err != nil {
+		t.Fatalf("BenchmarkStats failed: %v", err)
+	}
+	if len(stats) != 2 || stats[0].Operation != "init" || stats[1].Operation != "push" {
+		t.Fatalf("BenchmarkStats = %+v, want init then push", stats)
+	}
+	if push := stats[1]; push.Samples != 2 || push.MinMs != 150 || push.MaxMs != 300 || push.MeanMs != 225 {
+		t.Errorf("push = %+v, want the successful pushes of each run summed", push)
+	}
+
+	// Stored statistics and runs read back in order
+	if err := db.CompleteBenchmark(b.ID, stats); err != nil {
+		t.Fatalf("CompleteBenchmark failed: %v", err)
+	}
+	got, err := db.GetBenchmark(b.ID)
+	if err != nil {
+		t.Fatalf("GetBenchmark failed: %v", err)
+	}
+	if got.CompletedAt == nil || len(got.RunIDs) != 2 || got.RunIDs[0] != runIDs[0] {
+		t.Errorf("GetBenchmark = %+v", got)
+	}
+	stored, err := db.ListBenchmarkStats(b.ID)
+	if err != nil || len(stored) != 2 || stored[1].MedianMs != 225 {
+		t.Errorf("ListBenchmarkStats = %+v, %v", stored, err)
+	}
+
+	// Purging a run unlinks it and keeps the benchmark
+	if _, err := db.DeleteTestRuns(runIDs[:1]); err != nil {
+		t.Fatalf("DeleteTestRuns failed: %v", err)
+	}
+	if got, err := db.GetBenchmark(b.ID); err != nil || len(got.RunIDs) != 1 {
+		t.Errorf("benchmark after purge = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,162 @@
+package report
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/i18n"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// Metrics charted per step of a run
+const (
+	MetricDuration = "duration" // Summed operation time of each step, or mean wall-clock duration of each server
+	MetricBytes    = "bytes"    // Bytes transferred by the operations of each step
+)
+
+// Metrics charted per server type and protocol, besides MetricDuration
+const (
+	MetricPush        = "push"         // Push throughput
+	MetricPull        = "pull"         // Clone and pull throughput
+	MetricFailureRate = "failure-rate" // Share of the runs that did not complete
+)
+
+// RunMetrics and ServerMetrics list the metrics of RunChart and ServerChart
+var (
+	RunMetrics    = []string{MetricDuration, MetricBytes}
+	ServerMetrics = []string{MetricDuration, MetricPush, MetricPull, MetricFailureRate}
+)
+
+// Chart is a bar chart of one metric, one bar per label
+type Chart struct {
+	Title  string
+	YLabel string
+	Labels []string
+	Values []float64
+}
+
+// RunChart charts a metric of each step of a run
+func RunChart(db *database.DB, runID int64, metric string) (*Chart, error) {
+	if _, err := db.GetTestRun(runID); err != nil {
+		return nil, err
+	}
+	ops, err := db.ListOperations(runID)
+	if err != nil {
+		return nil, err
+	}
+	results, err := db.ListStepResults(runID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int]string)
+	for _, sr := range results {
+		names[sr.StepNumber] = sr.Name
+	}
+
+	c := &Chart{}
+	var scale float64
+	switch metric {
+	case MetricDuration:
+		c.Title = i18n.T("Step durations of run %d", runID)
+		c.YLabel = i18n.T("Duration") + " (s)"
+		scale = 1.0 / 1000
+	case MetricBytes:
+		c.Title = i18n.T("Bytes transferred in each step of run %d", runID)
+		c.YLabel = "MB"
+		scale = 1.0 / 1024 / 1024
+	default:
+		return nil, fmt.Errorf("invalid metric '%s' of a run (use %s or %s)", metric, MetricDuration, MetricBytes)
+	}
+
+	totals := make(map[int]int64)
+	for _, op := range ops {
+		if metric == MetricDuration {
+			totals[op.StepNumber] += op.DurationMs
+		} else if op.TotalBytes != nil {
+			totals[op.StepNumber] += *op.TotalBytes
+		} else if _, ok := totals[op.StepNumber]; !ok {
+			totals[op.StepNumber] = 0
+		}
+	}
+	steps := make([]int, 0, len(totals))
+	for step := range totals {
+		steps = append(steps, step)
+	}
+	sort.Ints(steps)
+	for _, step := range steps {
+		label := i18n.T("Step %d", step)
+		if names[step] != "" {
+			label += "\n" + names[step]
+		}
+		c.Labels = append(c.Labels, label)
+		c.Values = append(c.Values, float64(totals[step])*scale)
+	}
+	return c, nil
+}
+
+// ServerChart charts a metric of each server type and protocol of a scoreboard
+func ServerChart(scoreboard []*database.ScoreboardRow, metric string) (*Chart, error) {
+	c := &Chart{}
+	var value func(*database.ScoreboardRow) float64
+	switch metric {
+	case MetricDuration:
+		c.Title = i18n.T("Mean duration of completed runs")
+		c.YLabel = i18n.T("Duration") + " (s)"
+		value = func(row *database.ScoreboardRow) float64 { return float64(row.AvgDurationMs) / 1000 }
+	case MetricPush:
+		c.Title = i18n.T("Push throughput")
+		c.YLabel = "MB/s"
+		value = func(row *database.ScoreboardRow) float64 { return row.PushMBps }
+	case MetricPull:
+		c.Title = i18n.T("Clone and pull throughput")
+		c.YLabel = "MB/s"
+		value = func(row *database.ScoreboardRow) float64 { return row.PullMBps }
+	case MetricFailureRate:
+		c.Title = i18n.T("Failure rate")
+		c.YLabel = "%"
+		value = func(row *database.ScoreboardRow) float64 { return row.FailureRate() * 100 }
+	default:
+		return nil, fmt.Errorf("invalid metric '%s' of servers (use %s, %s, %s or %s)",
+			metric, MetricDuration, MetricPush, MetricPull, MetricFailureRate)
+	}
+	for _, row := range scoreboard {
+		c.Labels = append(c.Labels, row.ServerType+"\n"+row.Protocol)
+		c.Values = append(c.Values, value(row))
+	}
+	return c, nil
+}
+
+// Save renders the chart to file in the format its extension names: .png, .svg, .pdf,
+// .jpg or .tiff. Fonts are built in, so no external tool is needed.
+func (c *Chart) Save(file string) error {
+	if len(c.Values) == 0 {
+		return fmt.Errorf("nothing to chart")
+	}
+
+	p := plot.New()
+	p.Title.Text = c.Title
+	p.Y.Label.Text = c.YLabel
+	p.Y.Min = 0
+	p.Add(plotter.NewGrid())
+	p.NominalX(c.Labels...)
+
+	bars, err := plotter.NewBarChart(plotter.Values(c.Values), vg.Points(28))
+	if err != nil {
+		return fmt.Errorf("failed to chart: %w", err)
+	}
+	bars.Color = color.RGBA{R: 0x4a, G: 0x7e, B: 0xbb, A: 0xff} // As the bars of the HTML report
+	bars.LineStyle.Width = 0
+	p.Add(bars)
+
+	// Wide enough that the labels of many bars do not overlap
+	width := vg.Length(math.Max(6, float64(len(c.Values))*0.9)) * vg.Inch
+	if err := p.Save(width, 4*vg.Inch, file); err != nil {
+		return fmt.Errorf("failed to save chart: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestCharts(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	run := &database.TestRun{ScenarioID: 6, ServerType: "giftless", Protocol: "http", GitServer: "bare", StartedAt: time.Now(), Status: "completed"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("Failed to create test run: %v", err)
+	}
+	pushed := int64(4 * 1024 * 1024)
+	for _, op := range []*database.Operation{
+		{RunID: run.ID, StepNumber: 1, Operation: "commit", DurationMs: 500},
+		{RunID: run.ID, StepNumber: 2, Operation: "push", DurationMs: 2000, TotalBytes: &pushed},
+		{RunID: run.ID, StepNumber: 2, Operation: "lfs-track", DurationMs: 500},
+	} {
+		op.StartedAt = time.Now()
+		op.Status = "success"
+		if err := db.CreateOperation(op); err != nil {
+			t.Fatalf("CreateOperation failed: %v", err)
+		}
+	}
+
+	chart, err := RunChart(db, run.ID, MetricDuration)
+	if err != nil {
+		t.Fatalf("RunChart failed: %v", err)
+	}
+	if len(chart.Values) != 2 || chart.Values[0] != 0.5 || chart.Values[1] != 2.5 {
+		t.Errorf("Step durations = %v, want [0.5 2.5]", chart.Values)
+	}
+	if chart, _ := RunChart(db, run.ID, MetricBytes); len(chart.Values) != 2 || chart.Values[0] != 0 || chart.Values[1] != 4 {
+		t.Errorf("Step bytes = %v, want [0 4]", chart.Values)
+	}
+	if _, err := RunChart(db, run.ID, MetricPush); err == nil {
+		t.Error("RunChart should reject a server metric")
+	}
+
+	file := filepath.Join(t.TempDir(), "chart.png")
+	if err := chart.Save(file); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	png, err := os.ReadFile(file)
+	if err != nil || !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Errorf("Save wrote no PNG: %v", err)
+	}
+
+	scoreboard, err := db.Scoreboard(0, nil)
+	if err != nil {
+		t.Fatalf("Scoreboard failed: %v", err)
+	}
+	chart, err = ServerChart(scoreboard, MetricPush)
+	if err != nil {
+		t.Fatalf("ServerChart failed: %v", err)
+	}
+	if len(chart.Values) != 1 || chart.Values[0] != 2 || chart.Labels[0] != "giftless\nhttp" {
+		t.Errorf("Server chart = %v %v, want giftless at 2 MB/s", chart.Labels, chart.Values)
+	}
+	if err := (&Chart{}).Save(file); err == nil {
+		t.Error("Save should refuse an empty chart")
+	}
+}
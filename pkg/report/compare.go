@@ -0,0 +1,110 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+// RunComparison aligns the operations of several runs by step and operation type
+type RunComparison struct {
+	Runs   []*database.TestRun
+	Rows   []*ComparisonRow
+	Totals []int64 // Total operation time of each run, in Runs order
+}
+
+// ComparisonRow holds the duration of one step/operation pair in each run.
+// Repeated operations of the same type within a step are summed.
+type ComparisonRow struct {
+	StepNumber int
+	Operation  string
+	DurationMs []*int64 // nil where a run did not perform the operation
+}
+
+// CompareRuns builds a side-by-side comparison of the given runs.
+// The first run is the baseline that deltas are measured against.
+func CompareRuns(db *database.DB, runIDs []int64) (*RunComparison, error) {
+	if len(runIDs) < 2 {
+		return nil, fmt.Errorf("at least two runs are required for a comparison")
+	}
+
+	type key struct {
+		step      int
+		operation string
+	}
+
+	c := &RunComparison{Totals: make([]int64, len(runIDs))}
+	rows := make(map[key]*ComparisonRow)
+
+	for i, runID := range runIDs {
+		run, err := db.GetTestRun(runID)
+		if err != nil {
+			return nil, err
+		}
+		c.Runs = append(c.Runs, run)
+
+		ops, err := db.ListOperations(runID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, op := range ops {
+			k := key{op.StepNumber, op.Operation}
+			row := rows[k]
+			if row == nil {
+				row = &ComparisonRow{
+					StepNumber: op.StepNumber,
+					Operation:  op.Operation,
+					DurationMs: make([]*int64, len(runIDs)),
+				}
+				rows[k] = row
+				c.Rows = append(c.Rows, row)
+			}
+			if row.DurationMs[i] == nil {
+				row.DurationMs[i] = new(int64)
+			}
+			*row.DurationMs[i] += op.DurationMs
+			c.Totals[i] += op.DurationMs
+		}
+	}
+
+	sort.SliceStable(c.Rows, func(i, j int) bool {
+		if c.Rows[i].StepNumber != c.Rows[j].StepNumber {
+			return c.Rows[i].StepNumber < c.Rows[j].StepNumber
+		}
+		return c.Rows[i].Operation < c.Rows[j].Operation
+	})
+
+	return c, nil
+}
+
+// Delta returns the difference between run i and the baseline run for this row.
+// ok is false if either run lacks the operation.
+func (row *ComparisonRow) Delta(i int) (deltaMs int64, percent float64, ok bool) {
+	base, other := row.DurationMs[0], row.DurationMs[i]
+	if base == nil || other == nil {
+		return 0, 0, false
+	}
+	deltaMs, percent = delta(*base, *other)
+	return deltaMs, percent, true
+}
+
+// TotalDelta returns the difference between the total time of run i and the baseline run
+func (c *RunComparison) TotalDelta(i int) (deltaMs int64, percent float64) {
+	return delta(c.Totals[0], c.Totals[i])
+}
+
+// delta computes the absolute and percentage difference from base to other
+func delta(base, other int64) (int64, float64) {
+	d := other - base
+	if base == 0 {
+		return d, 0
+	}
+	return d, float64(d) * 100 / float64(base)
+}
+
+// FormatDelta formats a duration delta such as "+120ms (+12.5%)"
+func FormatDelta(deltaMs int64, percent float64) string {
+	return fmt.Sprintf("%+dms (%+.1f%%)", deltaMs, percent)
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"sort"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+// CriticalPath is the longest chain of dependent operations in a run. Its duration is
+// the time that is inherently serial; the rest of the operation time could overlap.
+type CriticalPath struct {
+	Operations []*database.Operation // In execution order
+	DurationMs int64                 // Summed duration of the operations on the path
+	TotalMs    int64                 // Summed duration of all operations in the run
+}
+
+// ParallelizableMs returns the operation time that is not on the critical path
+func (c *CriticalPath) ParallelizableMs() int64 {
+	return c.TotalMs - c.DurationMs
+}
+
+// SerialPercent returns the share of operation time on the critical path
+func (c *CriticalPath) SerialPercent() float64 {
+	if c.TotalMs == 0 {
+		return 0
+	}
+	return float64(c.DurationMs) * 100 / float64(c.TotalMs)
+}
+
+// BuildCriticalPath loads the operations and dependencies of a run and computes its critical path
+func BuildCriticalPath(db *database.DB, runID int64) (*CriticalPath, error) {
+	ops, err := db.ListOperations(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	deps, err := db.ListOperationDependencies(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ComputeCriticalPath(ops, deps), nil
+}
+
+// ComputeCriticalPath finds the chain of dependent operations with the largest summed duration.
+// Dependencies always point to earlier operations, so processing by ID is a topological order.
+func ComputeCriticalPath(ops []*database.Operation, deps []*database.OperationDependency) *CriticalPath {
+	sorted := append([]*database.Operation(nil), ops...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	dependsOn := make(map[int64][]int64)
+	for _, dep := range deps {
+		dependsOn[dep.OperationID] = append(dependsOn[dep.OperationID], dep.DependsOnID)
+	}
+
+	cp := &CriticalPath{}
+	finish := make(map[int64]int64) // Longest path duration ending with each operation
+	prev := make(map[int64]int64)   // Predecessor of each operation on that path
+	byID := make(map[int64]*database.Operation)
+	var last int64
+
+	for _, op := range sorted {
+		byID[op.ID] = op
+		cp.TotalMs += op.DurationMs
+
+		var start int64
+		for _, id := range dependsOn[op.ID] {
+			if f, ok := finish[id]; ok && (f > start || prev[op.ID] == 0) {
+				start = f
+				prev[op.ID] = id
+			}
+		}
+		finish[op.ID] = start + op.DurationMs
+
+		if last == 0 || finish[op.ID] > finish[last] {
+			last = op.ID
+		}
+	}
+
+	if last == 0 {
+		return cp
+	}
+
+	cp.DurationMs = finish[last]
+	for id := last; id != 0; id = prev[id] {
+		cp.Operations = append([]*database.Operation{byID[id]}, cp.Operations...)
+	}
+	return cp
+}
@@ -0,0 +1,46 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestComputeCriticalPath(t *testing.T) {
+	// repo1: add(1) -> commit(2) -> push(3); a hosting call (4) depends on nothing;
+	// repo2: clone(5) depends on push(3); repo2 commit(6) follows the clone
+	ops := []*database.Operation{
+		{ID: 1, Operation: "add", DurationMs: 100},
+		{ID: 2, Operation: "commit", DurationMs: 200},
+		{ID: 3, Operation: "push", DurationMs: 1000},
+		{ID: 4, Operation: "gh-quota", DurationMs: 1500},
+		{ID: 5, Operation: "clone", DurationMs: 800},
+		{ID: 6, Operation: "commit", DurationMs: 50},
+	}
+	deps := []*database.OperationDependency{
+		{OperationID: 2, DependsOnID: 1, Kind: "sequence"},
+		{OperationID: 3, DependsOnID: 2, Kind: "sequence"},
+		{OperationID: 5, DependsOnID: 3, Kind: "data"},
+		{OperationID: 6, DependsOnID: 5, Kind: "sequence"},
+	}
+
+	cp := ComputeCriticalPath(ops, deps)
+
+	var ids []int64
+	for _, op := range cp.Operations {
+		ids = append(ids, op.ID)
+	}
+	if len(ids) != 5 || ids[0] != 1 || ids[2] != 3 || ids[4] != 6 {
+		t.Errorf("Critical path = %v, want [1 2 3 5 6]", ids)
+	}
+	if cp.DurationMs != 2150 || cp.TotalMs != 3650 || cp.ParallelizableMs() != 1500 {
+		t.Errorf("Durations: critical=%d total=%d parallel=%d", cp.DurationMs, cp.TotalMs, cp.ParallelizableMs())
+	}
+	if pct := cp.SerialPercent(); pct < 58.9 || pct > 59.0 {
+		t.Errorf("SerialPercent = %.2f, want about 58.9", pct)
+	}
+
+	if empty := ComputeCriticalPath(nil, nil); len(empty.Operations) != 0 || empty.SerialPercent() != 0 {
+		t.Errorf("Empty run should have an empty critical path, got %+v", empty)
+	}
+}
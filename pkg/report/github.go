@@ -0,0 +1,125 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/i18n"
+)
+
+// GitHubActionsEnv is set to "true" in every GitHub Actions job
+const GitHubActionsEnv = "GITHUB_ACTIONS"
+
+// GitHubSummaryEnv names the file whose Markdown GitHub Actions shows as the job summary
+const GitHubSummaryEnv = "GITHUB_STEP_SUMMARY"
+
+// InGitHubActions reports whether this runs in a GitHub Actions job
+func InGitHubActions() bool {
+	return os.Getenv(GitHubActionsEnv) == "true"
+}
+
+// runResults returns a run with its step results and verifications
+func runResults(db *database.DB, runID int64) (*database.TestRun, []*database.StepResult, []*database.Verification, error) {
+	run, err := db.GetTestRun(runID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	results, err := db.ListStepResults(runID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	verifications, err := db.ListVerifications(runID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return run, results, verifications, nil
+}
+
+// RenderGitHubAnnotations writes the workflow commands that annotate a GitHub Actions job
+// with the steps of a run that did not complete, as errors, and its failed verifications,
+// such as mismatched checksums, as errors or warnings by their severity
+func RenderGitHubAnnotations(w io.Writer, db *database.DB, runID int64) error {
+	run, results, verifications, err := runResults(db, runID)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	names := make(map[int]string)
+	for _, sr := range results {
+		names[sr.StepNumber] = sr.Name
+		if sr.Status == "completed" {
+			continue
+		}
+		message := sr.Error
+		if message == "" {
+			message = "the step never finished"
+		}
+		title := fmt.Sprintf("Run %d, scenario %d: step %d (%s) %s", run.ID, run.ScenarioID, sr.StepNumber, sr.Name, sr.Status)
+		writeWorkflowCommand(&b, "error", title, message)
+	}
+	for _, v := range verifications {
+		if v.Status != "failed" {
+			continue
+		}
+		level := "error"
+		if v.Severity == "warning" {
+			level = "warning"
+		}
+		title := fmt.Sprintf("Run %d, scenario %d: %s in step %d (%s)", run.ID, run.ScenarioID, v.Name, v.StepNumber, names[v.StepNumber])
+		writeWorkflowCommand(&b, level, title, v.Message)
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// writeWorkflowCommand writes the annotation command ::LEVEL title=TITLE::MESSAGE
+func writeWorkflowCommand(b *strings.Builder, level, title, message string) {
+	data := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	property := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+	fmt.Fprintf(b, "::%s title=%s::%s\n", level, property.Replace(title), data.Replace(message))
+}
+
+// RenderGitHubSummary writes the step timings of a run as Markdown, for the summary of a
+// GitHub Actions job
+func RenderGitHubSummary(w io.Writer, db *database.DB, runID int64) error {
+	run, results, verifications, err := runResults(db, runID)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s %d: %s %d (%s, %s) %s\n\n", i18n.T("Run"), run.ID, i18n.T("Scenario"), run.ScenarioID,
+		run.ServerType, run.Protocol, markStatus(run.Status))
+	if len(results) == 0 {
+		fmt.Fprintf(&b, "%s\n\n", i18n.T("No steps recorded."))
+	} else {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", i18n.T("Step"), i18n.T("Name"), i18n.T("Status"), i18n.T("Duration"))
+		fmt.Fprintf(&b, "|---:|---|---|---:|\n")
+		var totalMs int64
+		for _, sr := range results {
+			fmt.Fprintf(&b, "| %d | %s | %s | %s |\n", sr.StepNumber, sr.Name, markStatus(sr.Status), formatMs(sr.DurationMs))
+			totalMs += sr.DurationMs
+		}
+		fmt.Fprintf(&b, "| | **%s** | | **%s** |\n\n", i18n.T("Total"), formatMs(totalMs))
+	}
+
+	for _, v := range verifications {
+		if v.Status == "failed" {
+			fmt.Fprintf(&b, "- %s %d, %s: %s\n", i18n.T("Step"), v.StepNumber, v.Name, v.Message)
+		}
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// markStatus returns a run or step status, in bold unless it completed
+func markStatus(status string) string {
+	if status == "completed" {
+		return i18n.T(status)
+	}
+	return "**" + i18n.T(status) + "**"
+}
@@ -0,0 +1,75 @@
+package report
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestRenderGitHub(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	run := &database.TestRun{ScenarioID: 6, ServerType: "giftless", Protocol: "http", GitServer: "bare", StartedAt: time.Now(), Status: "failed"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("CreateTestRun failed: %v", err)
+	}
+	for _, sr := range []*database.StepResult{
+		{RunID: run.ID, StepNumber: 1, Name: "setup", Status: "completed", DurationMs: 1000},
+		{RunID: run.ID, StepNumber: 2, Name: "push", Status: "failed", DurationMs: 2500, Error: "100% done,\nthen rejected"},
+	} {
+		sr.StartedAt = time.Now()
+		if err := db.SaveStepResult(sr); err != nil {
+			t.Fatalf("SaveStepResult failed: %v", err)
+		}
+	}
+	for _, v := range []*database.Verification{
+		{RunID: run.ID, StepNumber: 1, Name: "lfs-pointers", Severity: "error", Status: "passed"},
+		{RunID: run.ID, StepNumber: 1, Name: "interference", Severity: "warning", Status: "failed", Message: "indexer found"},
+		{RunID: run.ID, StepNumber: 2, Name: "checksums-match", Severity: "error", Status: "failed", Message: "checksum mismatch: a.bin"},
+	} {
+		v.CheckedAt = time.Now()
+		if err := db.CreateVerification(v); err != nil {
+			t.Fatalf("CreateVerification failed: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := RenderGitHubAnnotations(&buf, db, run.ID); err != nil {
+		t.Fatalf("RenderGitHubAnnotations failed: %v", err)
+	}
+	want := "::error title=Run 1%2C scenario 6%3A step 2 (push) failed::100%25 done,%0Athen rejected\n" +
+		"::warning title=Run 1%2C scenario 6%3A interference in step 1 (setup)::indexer found\n" +
+		"::error title=Run 1%2C scenario 6%3A checksums-match in step 2 (push)::checksum mismatch: a.bin\n"
+	if buf.String() != want {
+		t.Errorf("annotations =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := RenderGitHubSummary(&buf, db, run.ID); err != nil {
+		t.Fatalf("RenderGitHubSummary failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"### Run 1: Scenario 6 (giftless, http) **failed**",
+		"| Step | Name | Status | Duration |",
+		"| 1 | setup | completed | " + formatMs(1000) + " |",
+		"| 2 | push | **failed** | " + formatMs(2500) + " |",
+		"| | **Total** | | **" + formatMs(3500) + "** |",
+		"- Step 2, checksums-match: checksum mismatch: a.bin",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("job summary lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "lfs-pointers") {
+		t.Errorf("job summary lists a passed verification:\n%s", out)
+	}
+}
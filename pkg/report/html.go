@@ -0,0 +1,227 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/i18n"
+)
+
+// barWidth is the width in pixels of the longest bar in a graph
+const barWidth = 400
+
+// bar is one row of an inline SVG bar graph
+type bar struct {
+	Label string
+	Value string
+	Width int
+	Y     int
+}
+
+// graph is an inline SVG bar graph
+type graph struct {
+	Bars   []bar
+	Height int
+}
+
+// newGraph scales values so the largest one spans barWidth pixels
+func newGraph(labels []string, values []int64, format func(int64) string) graph {
+	var max int64
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+
+	g := graph{Height: len(values)*24 + 4}
+	for i, v := range values {
+		width := 0
+		if max > 0 {
+			width = int(v * barWidth / max)
+		}
+		g.Bars = append(g.Bars, bar{Label: labels[i], Value: format(v), Width: width, Y: i*24 + 2})
+	}
+	return g
+}
+
+// formatMs formats a millisecond duration for display
+func formatMs(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// RenderHTML writes the report as a self-contained HTML page
+func RenderHTML(w io.Writer, r *Report) error {
+	var stepLabels []string
+	var stepDurations []int64
+	for _, step := range r.Steps {
+		stepLabels = append(stepLabels, i18n.T("Step %d", step.Number))
+		stepDurations = append(stepDurations, step.DurationMs)
+	}
+
+	var sizeLabels []string
+	var sizeValues []int64
+	for _, size := range r.Sizes {
+		sizeLabels = append(sizeLabels, i18n.T("Step %d %s", size.StepNumber, size.Location))
+		sizeValues = append(sizeValues, size.SizeBytes)
+	}
+
+	data := struct {
+		*Report
+		StepGraph graph
+		SizeGraph graph
+	}{
+		Report:    r,
+		StepGraph: newGraph(stepLabels, stepDurations, formatMs),
+		SizeGraph: newGraph(sizeLabels, sizeValues, checksum.FormatSize),
+	}
+
+	if err := htmlTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return nil
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms":   formatMs,
+	"size": checksum.FormatSize,
+	"t":    i18n.T,
+	"lang": i18n.Language,
+	"time": func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+	"deref": func(p *int64) int64 {
+		if p == nil {
+			return 0
+		}
+		return *p
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="{{lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{t "Git LFS Test Run %d" .Run.ID}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; }
+td.num { text-align: right; }
+.failed { color: #b00020; }
+.added { color: #1b7f3b; }
+.deleted { color: #b00020; }
+table.narrative td { border: none; padding: 2px 8px; }
+svg text { font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>{{t "Git LFS Test Run %d" .Run.ID}}</h1>
+<table>
+<tr><th>{{t "Scenario"}}</th><td>{{.Run.ScenarioID}}</td></tr>
+<tr><th>{{t "Server"}}</th><td>{{.Run.ServerType}} ({{.Run.Protocol}})</td></tr>
+<tr><th>{{t "Git server"}}</th><td>{{.Run.GitServer}}</td></tr>
+<tr><th>{{t "Status"}}</th><td>{{t .Run.Status}}</td></tr>
+<tr><th>{{t "Started"}}</th><td>{{time .Run.StartedAt}}</td></tr>
+{{- if .Run.CompletedAt}}
+<tr><th>{{t "Completed"}}</th><td>{{time .Run.CompletedAt}}</td></tr>
+{{- end}}
+<tr><th>{{t "Total operation time"}}</th><td>{{ms .TotalDurationMs}}</td></tr>
+{{- if .HostAPIDurationMs}}
+<tr><th>{{t "Hosting API time"}}</th><td>{{t "%s of the total, in calls such as creating the repository" (ms .HostAPIDurationMs)}}</td></tr>
+{{- end}}
+{{- if .Environment}}
+<tr><th>{{t "Environment"}}</th><td>{{.Environment.Summary}}</td></tr>
+{{- end}}
+{{- if .Run.Notes}}
+<tr><th>{{t "Notes"}}</th><td>{{.Run.Notes}}</td></tr>
+{{- end}}
+</table>
+
+<h2>{{t "Narrative"}}</h2>
+{{- if .Narrative}}
+{{- range .Narrative}}
+<h3{{if .Failed}} class="failed"{{end}}>{{.Heading}}</h3>
+<table class="narrative">
+{{- range .Lines}}
+<tr><td class="num">{{.When}}</td><td{{if .Failed}} class="failed"{{end}}>{{.Text}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- else}}
+<p>{{t "Nothing recorded yet."}}</p>
+{{- end}}
+
+<h2>{{t "Step Timings"}}</h2>
+{{- if .Steps}}
+<table>
+<tr><th>{{t "Step"}}</th><th>{{t "Operations"}}</th><th>{{t "Failed"}}</th><th>{{t "Duration"}}</th><th>{{t "Hosting API"}}</th><th>{{t "Checksums"}}</th></tr>
+{{- range .Steps}}
+<tr><td class="num">{{.Number}}</td><td class="num">{{.OperationCount}}</td><td class="num{{if .FailedCount}} failed{{end}}">{{.FailedCount}}</td><td class="num">{{ms .DurationMs}}</td><td class="num">{{if .HostAPIMs}}{{ms .HostAPIMs}}{{end}}</td><td class="num">{{.ChecksumCount}}</td></tr>
+{{- end}}
+</table>
+{{template "graph" .StepGraph}}
+{{- else}}
+<p>{{t "No steps recorded."}}</p>
+{{- end}}
+
+<h2>{{t "Operations"}}</h2>
+{{- if .Operations}}
+<table>
+<tr><th>{{t "Step"}}</th><th>{{t "Operation"}}</th><th>{{t "Started"}}</th><th>{{t "Duration"}}</th><th>{{t "Files"}}</th><th>{{t "Bytes"}}</th><th>{{t "Status"}}</th><th>{{t "Error"}}</th></tr>
+{{- range .Operations}}
+<tr><td class="num">{{.StepNumber}}</td><td>{{.Operation}}</td><td>{{time .StartedAt}}</td><td class="num">{{ms .DurationMs}}</td><td class="num">{{if .FileCount}}{{.FileCount}}{{end}}</td><td class="num">{{if .TotalBytes}}{{size (deref .TotalBytes)}}{{end}}</td><td{{if ne .Status "success"}} class="failed"{{end}}>{{t .Status}}</td><td>{{.Error}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>{{t "No operations recorded."}}</p>
+{{- end}}
+
+<h2>{{t "Critical Path"}}</h2>
+{{- if .Critical.Operations}}
+<p>{{t "%s of %s operation time (%.1f%%) is inherently serial; %s could in principle overlap with it." (ms .Critical.DurationMs) (ms .Critical.TotalMs) .Critical.SerialPercent (ms .Critical.ParallelizableMs)}}</p>
+<table>
+<tr><th>{{t "Step"}}</th><th>{{t "Operation"}}</th><th>{{t "Duration"}}</th></tr>
+{{- range .Critical.Operations}}
+<tr><td class="num">{{.StepNumber}}</td><td>{{.Operation}}</td><td class="num">{{ms .DurationMs}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>{{t "No operation dependencies recorded."}}</p>
+{{- end}}
+
+<h2>{{t "Checksum Changes"}}</h2>
+{{- if .Diffs}}
+{{- range .Diffs}}
+<h3>{{t "Step %d" .FromStep}} &rarr; {{t "Step %d" .ToStep}}</h3>
+<p><span class="added">{{t "%d added" .Added}}</span>, <span class="deleted">{{t "%d deleted" .Deleted}}</span>, {{t "%d modified" .Modified}}, {{t "%d renamed" .Renamed}}</p>
+{{- if .Differences}}
+<table>
+<tr><th>{{t "File"}}</th><th>{{t "Change"}}</th><th>{{t "Old CRC32"}}</th><th>{{t "New CRC32"}}</th><th>{{t "Old Size"}}</th><th>{{t "New Size"}}</th></tr>
+{{- range .Differences}}
+<tr><td>{{if .OldPath}}{{.OldPath}} &rarr; {{end}}{{.FilePath}}</td><td class="{{.ChangeType}}">{{t .ChangeType}}</td><td>{{.OldCRC32}}</td><td>{{.NewCRC32}}</td><td class="num">{{if .OldSize}}{{size .OldSize}}{{end}}</td><td class="num">{{if .NewSize}}{{size .NewSize}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- else}}
+<p>{{t "Fewer than two steps recorded checksums."}}</p>
+{{- end}}
+
+<h2>{{t "Repository Sizes"}}</h2>
+{{- if .Sizes}}
+{{template "graph" .SizeGraph}}
+{{- else}}
+<p>{{t "No repository sizes recorded."}}</p>
+{{- end}}
+
+<p><small>{{t "Generated %s" (time .GeneratedAt)}}</small></p>
+</body>
+</html>
+{{define "graph"}}<svg width="700" height="{{.Height}}" xmlns="http://www.w3.org/2000/svg">
+{{- range .Bars}}
+<text x="0" y="{{.Y}}" dy="14">{{.Label}}</text>
+<rect x="170" y="{{.Y}}" width="{{.Width}}" height="18" fill="#4a7ebb"></rect>
+<text x="{{.Width}}" y="{{.Y}}" dx="176" dy="14">{{.Value}}</text>
+{{- end}}
+</svg>{{end}}
+`))
@@ -0,0 +1,173 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+// JUnitSuites is a JUnit XML report of runs, which CI servers such as Jenkins and GitHub
+// Actions show as test results: one test suite per run, with one test case per step and
+// per verification
+type JUnitSuites struct {
+	XMLName  xml.Name      `xml:"testsuites"`
+	Name     string        `xml:"name,attr"`
+	Tests    int           `xml:"tests,attr"`
+	Failures int           `xml:"failures,attr"`
+	Errors   int           `xml:"errors,attr"`
+	Time     string        `xml:"time,attr"`
+	Suites   []*JUnitSuite `xml:"testsuite"`
+}
+
+// JUnitSuite is the test suite of one run
+type JUnitSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Time       string           `xml:"time,attr"`
+	Timestamp  string           `xml:"timestamp,attr"`
+	Hostname   string           `xml:"hostname,attr,omitempty"`
+	Properties []*JUnitProperty `xml:"properties>property,omitempty"`
+	Cases      []*JUnitCase     `xml:"testcase"`
+}
+
+// JUnitProperty describes the configuration of a run
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// JUnitCase is a step or a verification. Failed steps and verifications of error severity
+// have a failure; steps that were cancelled, timed out or never finished have an error.
+// Failed warnings pass, with their message as output.
+type JUnitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	Error     *JUnitFailure `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// JUnitFailure is the failure or error of a test case
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// BuildJUnit builds the JUnit XML report of runs
+func BuildJUnit(db *database.DB, runIDs []int64) (*JUnitSuites, error) {
+	suites := &JUnitSuites{Name: "lfst"}
+	var totalMs int64
+	for _, runID := range runIDs {
+		suite, ms, err := buildJUnitSuite(db, runID)
+		if err != nil {
+			return nil, err
+		}
+		suites.Suites = append(suites.Suites, suite)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		totalMs += ms
+	}
+	suites.Time = seconds(totalMs)
+	return suites, nil
+}
+
+// buildJUnitSuite builds the test suite of a run, and returns the duration of its steps
+func buildJUnitSuite(db *database.DB, runID int64) (*JUnitSuite, int64, error) {
+	run, results, verifications, err := runResults(db, runID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	className := fmt.Sprintf("scenario %d (%s via %s)", run.ScenarioID, run.ServerType, run.Protocol)
+	suite := &JUnitSuite{
+		Name:      fmt.Sprintf("run %d: %s", run.ID, className),
+		Timestamp: run.StartedAt.UTC().Format("2006-01-02T15:04:05"),
+		Properties: []*JUnitProperty{
+			{Name: "run_id", Value: strconv.FormatInt(run.ID, 10)},
+			{Name: "scenario_id", Value: strconv.Itoa(run.ScenarioID)},
+			{Name: "server_type", Value: run.ServerType},
+			{Name: "protocol", Value: run.Protocol},
+			{Name: "git_server", Value: run.GitServer},
+			{Name: "status", Value: run.Status},
+		},
+	}
+	suite.Hostname, _ = os.Hostname()
+	if run.NetworkProfile != "" {
+		suite.Properties = append(suite.Properties, &JUnitProperty{Name: "network", Value: run.NetworkProfile})
+	}
+
+	var totalMs int64
+	for _, sr := range results {
+		stepName := fmt.Sprintf("step %d: %s", sr.StepNumber, sr.Name)
+		c := &JUnitCase{Name: stepName, ClassName: className, Time: seconds(sr.DurationMs)}
+		switch sr.Status {
+		case "completed":
+		case "failed":
+			c.Failure = &JUnitFailure{Message: sr.Error, Type: sr.Status, Text: sr.Error}
+		case "running":
+			// The process running the step ended without recording its outcome
+			c.Error = &JUnitFailure{Message: "the step never finished", Type: "aborted"}
+		default:
+			c.Error = &JUnitFailure{Message: sr.Error, Type: sr.Status, Text: sr.Error}
+		}
+		suite.add(c)
+		totalMs += sr.DurationMs
+
+		for _, v := range verifications {
+			if v.StepNumber != sr.StepNumber {
+				continue
+			}
+			c := &JUnitCase{Name: stepName + " / " + v.Name, ClassName: className, Time: seconds(0)}
+			if v.Status == "failed" {
+				if v.Severity == "error" {
+					c.Failure = &JUnitFailure{Message: v.Message, Type: "verification", Text: v.Message}
+				} else {
+					c.SystemOut = "warning: " + v.Message
+				}
+			}
+			suite.add(c)
+		}
+	}
+	suite.Time = seconds(totalMs)
+	return suite, totalMs, nil
+}
+
+// add appends c to the suite and counts it
+func (s *JUnitSuite) add(c *JUnitCase) {
+	s.Cases = append(s.Cases, c)
+	s.Tests++
+	if c.Failure != nil {
+		s.Failures++
+	}
+	if c.Error != nil {
+		s.Errors++
+	}
+}
+
+// seconds formats a duration in milliseconds as the seconds of JUnit time attributes
+func seconds(ms int64) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', 3, 64)
+}
+
+// RenderJUnit writes the report as JUnit XML
+func RenderJUnit(w io.Writer, suites *JUnitSuites) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,87 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestBuildAndRenderJUnit(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	passed := &database.TestRun{ScenarioID: 1, ServerType: "bare", Protocol: "local", GitServer: "bare", StartedAt: time.Now(), Status: "completed"}
+	failed := &database.TestRun{ScenarioID: 6, ServerType: "giftless", Protocol: "http", GitServer: "bare", StartedAt: time.Now(), Status: "failed"}
+	for _, run := range []*database.TestRun{passed, failed} {
+		if err := db.CreateTestRun(run); err != nil {
+			t.Fatalf("CreateTestRun failed: %v", err)
+		}
+	}
+
+	for _, sr := range []*database.StepResult{
+		{RunID: passed.ID, StepNumber: 1, Name: "setup", Status: "completed", DurationMs: 1500},
+		{RunID: failed.ID, StepNumber: 1, Name: "setup", Status: "completed", DurationMs: 1000},
+		{RunID: failed.ID, StepNumber: 2, Name: "push", Status: "failed", DurationMs: 250, Error: "push <rejected>"},
+		{RunID: failed.ID, StepNumber: 3, Name: "clone", Status: "timed-out", Error: "killed"},
+	} {
+		sr.StartedAt = time.Now()
+		if err := db.SaveStepResult(sr); err != nil {
+			t.Fatalf("SaveStepResult failed: %v", err)
+		}
+	}
+	for _, v := range []*database.Verification{
+		{RunID: failed.ID, StepNumber: 1, Name: "lfs-pointers", Severity: "error", Status: "passed"},
+		{RunID: failed.ID, StepNumber: 1, Name: "interference", Severity: "warning", Status: "failed", Message: "indexer found"},
+		{RunID: failed.ID, StepNumber: 2, Name: "server-objects", Severity: "error", Status: "failed", Message: "2 missing"},
+	} {
+		v.CheckedAt = time.Now()
+		if err := db.CreateVerification(v); err != nil {
+			t.Fatalf("CreateVerification failed: %v", err)
+		}
+	}
+
+	suites, err := BuildJUnit(db, []int64{passed.ID, failed.ID})
+	if err != nil {
+		t.Fatalf("BuildJUnit failed: %v", err)
+	}
+	if suites.Tests != 7 || suites.Failures != 2 || suites.Errors != 1 || suites.Time != "2.750" {
+		t.Errorf("totals = %d tests, %d failures, %d errors in %s; want 7, 2, 1 in 2.750",
+			suites.Tests, suites.Failures, suites.Errors, suites.Time)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderJUnit(&buf, suites); err != nil {
+		t.Fatalf("RenderJUnit failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<testsuite name="run 2: scenario 6 (giftless via http)" tests="6" failures="2" errors="1" time="1.250"`,
+		`<testcase name="step 2: push" classname="scenario 6 (giftless via http)" time="0.250">`,
+		`<failure message="push &lt;rejected&gt;" type="failed">push &lt;rejected&gt;</failure>`,
+		`<error message="killed" type="timed-out">killed</error>`,
+		`<testcase name="step 2: push / server-objects"`,
+		`<system-out>warning: indexer found</system-out>`,
+		`<property name="server_type" value="giftless"></property>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("JUnit XML lacks %s:\n%s", want, out)
+		}
+	}
+
+	var parsed JUnitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatalf("the JUnit XML does not parse: %v", err)
+	}
+	if len(parsed.Suites) != 2 || len(parsed.Suites[1].Cases) != 6 {
+		t.Errorf("parsed %d suites", len(parsed.Suites))
+	}
+}
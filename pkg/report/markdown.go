@@ -0,0 +1,251 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/i18n"
+)
+
+// RenderMarkdown writes the report as GitHub-flavored Markdown, with the sections of the
+// HTML report and its bar charts as Mermaid charts, to paste into a wiki or an article
+func RenderMarkdown(w io.Writer, r *Report) error {
+	var b strings.Builder
+	run := r.Run
+	fmt.Fprintf(&b, "## %s\n\n", i18n.T("Git LFS Test Run %d", run.ID))
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| %s | %d |\n", i18n.T("Scenario"), run.ScenarioID)
+	fmt.Fprintf(&b, "| %s | %s (%s) |\n", i18n.T("Server"), mdCell(run.ServerType), mdCell(run.Protocol))
+	fmt.Fprintf(&b, "| %s | %s |\n", i18n.T("Git server"), mdCell(run.GitServer))
+	fmt.Fprintf(&b, "| %s | %s |\n", i18n.T("Status"), markStatus(run.Status))
+	fmt.Fprintf(&b, "| %s | %s |\n", i18n.T("Started"), run.StartedAt.Format("2006-01-02 15:04:05"))
+	if run.CompletedAt != nil {
+		fmt.Fprintf(&b, "| %s | %s |\n", i18n.T("Completed"), run.CompletedAt.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(&b, "| %s | %s |\n", i18n.T("Total operation time"), formatMs(r.TotalDurationMs()))
+	if ms := r.HostAPIDurationMs(); ms > 0 {
+		fmt.Fprintf(&b, "| %s | %s |\n", i18n.T("Hosting API time"),
+			i18n.T("%s of the total, in calls such as creating the repository", formatMs(ms)))
+	}
+	if r.Environment != nil {
+		fmt.Fprintf(&b, "| %s | %s |\n", i18n.T("Environment"), mdCell(r.Environment.Summary()))
+	}
+	if run.Notes != "" {
+		fmt.Fprintf(&b, "| %s | %s |\n", i18n.T("Notes"), mdCell(run.Notes))
+	}
+
+	fmt.Fprintf(&b, "\n### %s\n\n", i18n.T("Narrative"))
+	if len(r.Narrative) == 0 {
+		fmt.Fprintf(&b, "%s\n", i18n.T("Nothing recorded yet."))
+	}
+	for _, step := range r.Narrative {
+		fmt.Fprintf(&b, "**%s**\n\n", step.Heading)
+		for _, line := range step.Lines {
+			text := line.Text
+			if line.Failed {
+				text = "**" + text + "**"
+			}
+			if when := line.When(); when != "" {
+				text = "`" + when + "` " + text
+			}
+			fmt.Fprintf(&b, "- %s\n", text)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "### %s\n\n", i18n.T("Step Timings"))
+	if len(r.Steps) == 0 {
+		fmt.Fprintf(&b, "%s\n\n", i18n.T("No steps recorded."))
+	} else {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n|---:|---:|---:|---:|---:|---:|\n", i18n.T("Step"),
+			i18n.T("Operations"), i18n.T("Failed"), i18n.T("Duration"), i18n.T("Hosting API"), i18n.T("Checksums"))
+		var labels []string
+		var seconds []float64
+		for _, step := range r.Steps {
+			hostAPI := ""
+			if step.HostAPIMs > 0 {
+				hostAPI = formatMs(step.HostAPIMs)
+			}
+			fmt.Fprintf(&b, "| %d | %d | %d | %s | %s | %d |\n", step.Number, step.OperationCount, step.FailedCount,
+				formatMs(step.DurationMs), hostAPI, step.ChecksumCount)
+			labels = append(labels, i18n.T("Step %d", step.Number))
+			seconds = append(seconds, float64(step.DurationMs)/1000)
+		}
+		b.WriteString("\n")
+		writeMermaidBars(&b, i18n.T("Step Timings"), i18n.T("Duration")+" (s)", labels, seconds)
+	}
+
+	fmt.Fprintf(&b, "### %s\n\n", i18n.T("Operations"))
+	if len(r.Operations) == 0 {
+		fmt.Fprintf(&b, "%s\n\n", i18n.T("No operations recorded."))
+	} else {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s | %s |\n|---:|---|---|---:|---:|---:|---|---|\n",
+			i18n.T("Step"), i18n.T("Operation"), i18n.T("Started"), i18n.T("Duration"), i18n.T("Files"),
+			i18n.T("Bytes"), i18n.T("Status"), i18n.T("Error"))
+		for _, op := range r.Operations {
+			var files, size string
+			if op.FileCount != nil {
+				files = fmt.Sprint(*op.FileCount)
+			}
+			if op.TotalBytes != nil {
+				size = checksum.FormatSize(*op.TotalBytes)
+			}
+			status := i18n.T(op.Status)
+			if op.Status != "success" {
+				status = "**" + status + "**"
+			}
+			fmt.Fprintf(&b, "| %d | %s | %s | %s | %s | %s | %s | %s |\n", op.StepNumber, mdCell(op.Operation),
+				op.StartedAt.Format("2006-01-02 15:04:05"), formatMs(op.DurationMs), files, size, status, mdCell(op.Error))
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "### %s\n\n", i18n.T("Critical Path"))
+	if c := r.Critical; len(c.Operations) == 0 {
+		fmt.Fprintf(&b, "%s\n\n", i18n.T("No operation dependencies recorded."))
+	} else {
+		fmt.Fprintf(&b, "%s\n\n", i18n.T("%s of %s operation time (%.1f%%) is inherently serial; %s could in principle overlap with it.",
+			formatMs(c.DurationMs), formatMs(c.TotalMs), c.SerialPercent(), formatMs(c.ParallelizableMs())))
+		fmt.Fprintf(&b, "| %s | %s | %s |\n|---:|---|---:|\n", i18n.T("Step"), i18n.T("Operation"), i18n.T("Duration"))
+		for _, op := range c.Operations {
+			fmt.Fprintf(&b, "| %d | %s | %s |\n", op.StepNumber, mdCell(op.Operation), formatMs(op.DurationMs))
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "### %s\n\n", i18n.T("Checksum Changes"))
+	if len(r.Diffs) == 0 {
+		fmt.Fprintf(&b, "%s\n\n", i18n.T("Fewer than two steps recorded checksums."))
+	}
+	for _, d := range r.Diffs {
+		fmt.Fprintf(&b, "**%s → %s**: %s, %s, %s, %s\n\n", i18n.T("Step %d", d.FromStep), i18n.T("Step %d", d.ToStep),
+			i18n.T("%d added", d.Added), i18n.T("%d deleted", d.Deleted), i18n.T("%d modified", d.Modified), i18n.T("%d renamed", d.Renamed))
+		if len(d.Differences) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n|---|---|---|---|---:|---:|\n", i18n.T("File"), i18n.T("Change"),
+			i18n.T("Old CRC32"), i18n.T("New CRC32"), i18n.T("Old Size"), i18n.T("New Size"))
+		for _, diff := range d.Differences {
+			file := mdCell(diff.FilePath)
+			if diff.OldPath != "" {
+				file = mdCell(diff.OldPath) + " → " + file
+			}
+			var oldSize, newSize string
+			if diff.OldSize != 0 {
+				oldSize = checksum.FormatSize(diff.OldSize)
+			}
+			if diff.NewSize != 0 {
+				newSize = checksum.FormatSize(diff.NewSize)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", file, i18n.T(diff.ChangeType),
+				diff.OldCRC32, diff.NewCRC32, oldSize, newSize)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "### %s\n\n", i18n.T("Repository Sizes"))
+	if len(r.Sizes) == 0 {
+		fmt.Fprintf(&b, "%s\n\n", i18n.T("No repository sizes recorded."))
+	} else {
+		var labels []string
+		var megabytes []float64
+		for _, size := range r.Sizes {
+			labels = append(labels, i18n.T("Step %d %s", size.StepNumber, size.Location))
+			megabytes = append(megabytes, float64(size.SizeBytes)/1024/1024)
+		}
+		writeMermaidBars(&b, i18n.T("Repository Sizes"), i18n.T("Size")+" (MB)", labels, megabytes)
+	}
+
+	fmt.Fprintf(&b, "_%s_\n", i18n.T("Generated %s", r.GeneratedAt.Format("2006-01-02 15:04:05")))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// RenderMarkdownScoreboard writes a scoreboard as a Markdown table followed by Mermaid
+// charts of the average operation time and throughputs of each server, for an evaluation
+// article; scenarioID is the scenario the scoreboard was built for, 0 for all
+func RenderMarkdownScoreboard(w io.Writer, scoreboard []*database.ScoreboardRow, scenarioID int) error {
+	var b strings.Builder
+	if scenarioID != 0 {
+		fmt.Fprintf(&b, "## %s\n\n", i18n.T("Server scoreboard of scenario %d", scenarioID))
+	} else {
+		fmt.Fprintf(&b, "## %s\n\n", i18n.T("Server scoreboard"))
+	}
+	if len(scoreboard) == 0 {
+		fmt.Fprintf(&b, "%s\n", i18n.T("No finished runs found"))
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s | %s |\n|---|---|---:|---:|---:|---:|---:|---:|\n",
+		i18n.T("Server"), i18n.T("Protocol"), i18n.T("Runs"), i18n.T("Failure rate"), i18n.T("Avg duration"),
+		i18n.T("Push MB/s"), i18n.T("Pull MB/s"), i18n.T("Failed checks"))
+	var labels []string
+	var seconds, push, pull []float64
+	for _, row := range scoreboard {
+		failures := fmt.Sprintf("%.0f%% (%d)", row.FailureRate()*100, row.FailedRuns)
+		if row.FailedRuns > 0 {
+			failures = "**" + failures + "**"
+		}
+		var duration string
+		if row.AvgDurationMs > 0 {
+			duration = formatMs(row.AvgDurationMs)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %d | %s | %s | %s | %s | %d |\n", mdCell(row.ServerType), mdCell(row.Protocol),
+			row.Runs, failures, duration, formatMBps(row.PushMBps), formatMBps(row.PullMBps), row.FailedChecks)
+		labels = append(labels, row.ServerType+" "+row.Protocol)
+		seconds = append(seconds, float64(row.AvgDurationMs)/1000)
+		push = append(push, row.PushMBps)
+		pull = append(pull, row.PullMBps)
+	}
+	b.WriteString("\n")
+
+	writeMermaidBars(&b, i18n.T("Avg duration"), i18n.T("Duration")+" (s)", labels, seconds)
+	// Throughputs are charted only if some transfer recorded its bytes
+	for _, chart := range []struct {
+		title  string
+		values []float64
+	}{{i18n.T("Push MB/s"), push}, {i18n.T("Pull MB/s"), pull}} {
+		if slices.ContainsFunc(chart.values, func(v float64) bool { return v != 0 }) {
+			writeMermaidBars(&b, chart.title, "MB/s", labels, chart.values)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMermaidBars writes a Mermaid bar chart of values, one bar per label
+func writeMermaidBars(b *strings.Builder, title, axis string, labels []string, values []float64) {
+	quoted := make([]string, len(labels))
+	numbers := make([]string, len(values))
+	for i, label := range labels {
+		quoted[i] = mermaidString(label)
+	}
+	for i, v := range values {
+		numbers[i] = fmt.Sprintf("%.2f", v)
+	}
+	fmt.Fprintf(b, "```mermaid\nxychart-beta\n    title %s\n    x-axis [%s]\n    y-axis %s\n    bar [%s]\n```\n\n",
+		mermaidString(title), strings.Join(quoted, ", "), mermaidString(axis), strings.Join(numbers, ", "))
+}
+
+// mermaidString quotes text for a Mermaid chart, which has no escape for double quotes
+func mermaidString(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, "'") + `"`
+}
+
+// mdCell makes text safe for a Markdown table cell
+func mdCell(text string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ").Replace(text)
+}
+
+// formatMBps formats a throughput for a table; blank if none was recorded
+func formatMBps(mbps float64) string {
+	if mbps == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", mbps)
+}
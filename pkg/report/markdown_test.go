@@ -0,0 +1,83 @@
+package report
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	run := &database.TestRun{ScenarioID: 6, ServerType: "giftless", Protocol: "http", GitServer: "bare", StartedAt: time.Now(), Status: "failed"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("Failed to create test run: %v", err)
+	}
+	bytesPushed := int64(20 * 1024 * 1024)
+	for _, op := range []*database.Operation{
+		{RunID: run.ID, StepNumber: 1, Operation: "commit", DurationMs: 250, Status: "success"},
+		{RunID: run.ID, StepNumber: 2, Operation: "push", DurationMs: 2000, Status: "failed", Error: "rejected | quota", TotalBytes: &bytesPushed},
+	} {
+		op.StartedAt = time.Now()
+		if err := db.CreateOperation(op); err != nil {
+			t.Fatalf("CreateOperation failed: %v", err)
+		}
+	}
+
+	if err := db.SaveEnvironment(&database.Environment{RunID: run.ID, GitLFSVersion: "3.4.1", OS: "linux", CollectedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+
+	r, err := Build(db, run.ID)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := RenderMarkdown(&buf, r); err != nil {
+		t.Fatalf("RenderMarkdown failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"## Git LFS Test Run 1\n",
+		"| Server | giftless (http) |",
+		"| Status | **failed** |",
+		"| Environment | git-lfs 3.4.1, linux |",
+		"| 2 | push | ",
+		"| 20.0 MB | **failed** | rejected \\| quota |",
+		"```mermaid\nxychart-beta\n    title \"Step Timings\"\n    x-axis [\"Step 1\", \"Step 2\"]\n    y-axis \"Duration (s)\"\n    bar [0.25, 2.00]\n```",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown report missing %q:\n%s", want, out)
+		}
+	}
+
+	scoreboard, err := db.Scoreboard(0, nil)
+	if err != nil {
+		t.Fatalf("Scoreboard failed: %v", err)
+	}
+	buf.Reset()
+	if err := RenderMarkdownScoreboard(&buf, scoreboard, 6); err != nil {
+		t.Fatalf("RenderMarkdownScoreboard failed: %v", err)
+	}
+	out = buf.String()
+	for _, want := range []string{
+		"## Server scoreboard of scenario 6\n",
+		"| giftless | http | 1 | **100% (1)** |  |  |  | 0 |",
+		"title \"Avg duration\"",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown scoreboard missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Push MB/s\"") {
+		t.Errorf("Markdown scoreboard charts a push throughput no successful push recorded:\n%s", out)
+	}
+}
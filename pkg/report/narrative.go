@@ -0,0 +1,229 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/githost"
+	"github.com/mslinn/git-lfs-test/pkg/i18n"
+)
+
+// NarrativeStep tells what happened during one step of a run, in the order it happened
+type NarrativeStep struct {
+	Number  int
+	Heading string // e.g. "Step 2 (add): completed in 3.4s"
+	Failed  bool
+	Lines   []*NarrativeLine
+}
+
+// NarrativeLine is one event of a step
+type NarrativeLine struct {
+	Offset time.Duration // Since the run started; negative if unknown
+	Text   string
+	Failed bool
+}
+
+// When returns the offset of the line from the start of the run, e.g. "+1m2.3s"
+func (l *NarrativeLine) When() string {
+	if l.Offset < 0 {
+		return ""
+	}
+	return "+" + l.Offset.Round(100*time.Millisecond).String()
+}
+
+// buildNarrative turns the step results, operations, verifications and checksum
+// changes of a run into a chronological account, one section per step
+func buildNarrative(r *Report, results []*database.StepResult, verifications []*database.Verification) []*NarrativeStep {
+	start := r.Run.StartedAt
+	offset := func(t time.Time) time.Duration {
+		if t.IsZero() || t.Before(start) {
+			return -1
+		}
+		return t.Sub(start)
+	}
+
+	steps := make(map[int]*NarrativeStep)
+	stepFor := func(n int) *NarrativeStep {
+		if steps[n] == nil {
+			steps[n] = &NarrativeStep{Number: n, Heading: i18n.T("Step %d", n)}
+		}
+		return steps[n]
+	}
+
+	for _, sr := range results {
+		step := stepFor(sr.StepNumber)
+		if sr.Name != "" {
+			step.Heading += fmt.Sprintf(" (%s)", sr.Name)
+		}
+		switch sr.Status {
+		case "completed":
+			step.Heading += i18n.T(": completed in %s", formatMs(sr.DurationMs))
+		case "failed":
+			step.Heading += i18n.T(": failed after %s", formatMs(sr.DurationMs))
+			step.Failed = true
+		default:
+			step.Heading += ": " + i18n.T(sr.Status)
+		}
+		if sr.Error != "" {
+			step.Lines = append(step.Lines, &NarrativeLine{Offset: -1, Text: i18n.T("error: %s", sr.Error), Failed: true})
+		}
+	}
+
+	type event struct {
+		at   time.Time
+		line *NarrativeLine
+	}
+	events := make(map[int][]event)
+
+	for _, op := range r.Operations {
+		line := &NarrativeLine{Offset: offset(op.StartedAt), Text: describeOperation(op)}
+		if op.Status != "success" {
+			line.Failed = true
+			stepFor(op.StepNumber).Failed = true
+		}
+		events[op.StepNumber] = append(events[op.StepNumber], event{op.StartedAt, line})
+	}
+
+	// Checks that passed are listed together; each failure gets its own line
+	passed := make(map[int][]string)
+	seen := make(map[string]bool)
+	for _, v := range verifications {
+		if v.Status == "passed" {
+			key := fmt.Sprintf("%d %s", v.StepNumber, v.Name)
+			if !seen[key] {
+				seen[key] = true
+				passed[v.StepNumber] = append(passed[v.StepNumber], v.Name)
+			}
+			continue
+		}
+		text := i18n.T("check %s failed (%s)", v.Name, i18n.T(v.Severity))
+		if v.Message != "" {
+			text += ": " + v.Message
+		}
+		line := &NarrativeLine{Offset: offset(v.CheckedAt), Text: text, Failed: v.Severity == "error"}
+		events[v.StepNumber] = append(events[v.StepNumber], event{v.CheckedAt, line})
+	}
+
+	for n, list := range events {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].at.Before(list[j].at) })
+		step := stepFor(n)
+		for _, e := range list {
+			step.Lines = append(step.Lines, e.line)
+		}
+	}
+	for n, names := range passed {
+		step := stepFor(n)
+		step.Lines = append(step.Lines, &NarrativeLine{Offset: -1, Text: i18n.T("verified %s", strings.Join(names, ", "))})
+	}
+	for _, d := range r.Diffs {
+		step := stepFor(d.ToStep)
+		step.Lines = append(step.Lines, &NarrativeLine{Offset: -1, Text: describeDiff(d)})
+	}
+
+	var narrative []*NarrativeStep
+	for _, step := range steps {
+		narrative = append(narrative, step)
+	}
+	sort.Slice(narrative, func(i, j int) bool { return narrative[i].Number < narrative[j].Number })
+	return narrative
+}
+
+// describeOperation tells what an operation did and how long it took,
+// e.g. "push: 14 files, 120.0 MB in 2.1s (57.1 MB/s)"
+func describeOperation(op *database.Operation) string {
+	var amount []string
+	if op.FileCount != nil {
+		amount = append(amount, i18n.T("%d files", *op.FileCount))
+	}
+	if op.TotalBytes != nil {
+		amount = append(amount, checksum.FormatSize(*op.TotalBytes))
+	}
+
+	text := op.Operation
+	if op.Status != "success" {
+		text = i18n.T("%s failed after %s", text, formatMs(op.DurationMs))
+		if op.ErrorKind != "" {
+			text += ": " + describeErrorKind(op.ErrorKind)
+		} else if op.Error != "" {
+			message, _, _ := strings.Cut(op.Error, "\n")
+			text += ": " + message
+		}
+		return text + describeServerEvent(op)
+	}
+
+	if len(amount) > 0 {
+		text += ": " + strings.Join(amount, ", ")
+	}
+	text = i18n.T("%s in %s", text, formatMs(op.DurationMs))
+	if op.TotalBytes != nil && op.DurationMs > 0 {
+		text += fmt.Sprintf(" (%.1f MB/s)", float64(*op.TotalBytes)/1024/1024/(float64(op.DurationMs)/1000))
+	}
+	return text + describeServerEvent(op)
+}
+
+// describeErrorKind names the cause of a failed operation
+func describeErrorKind(kind string) string {
+	switch kind {
+	case githost.QuotaStorage:
+		return i18n.T("LFS storage quota of the host exceeded")
+	case githost.QuotaBandwidth:
+		return i18n.T("LFS bandwidth quota of the host exceeded")
+	}
+	return kind
+}
+
+// describeServerEvent tells what happened to the server during an operation, if anything
+func describeServerEvent(op *database.Operation) string {
+	if op.ServerEvent == "" {
+		return ""
+	}
+	return i18n.T("; server: %s", op.ServerEvent)
+}
+
+// describeDiff summarizes the checksum changes leading up to a step
+func describeDiff(d *StepDiff) string {
+	if len(d.Differences) == 0 {
+		return i18n.T("no files changed since step %d", d.FromStep)
+	}
+
+	var changes []string
+	for _, c := range []struct {
+		n    int
+		what string
+	}{{d.Added, "%d added"}, {d.Deleted, "%d deleted"}, {d.Modified, "%d modified"}, {d.Renamed, "%d renamed"}} {
+		if c.n > 0 {
+			changes = append(changes, i18n.T(c.what, c.n))
+		}
+	}
+	return i18n.T("files changed since step %d: %s", d.FromStep, strings.Join(changes, ", "))
+}
+
+// RenderNarrative writes the narrative of the report as plain text
+func RenderNarrative(w io.Writer, r *Report) error {
+	run := r.Run
+	fmt.Fprintln(w, i18n.T("Run %d of scenario %d against %s (%s), started %s",
+		run.ID, run.ScenarioID, run.ServerType, run.Protocol, run.StartedAt.Format("2006-01-02 15:04:05")))
+
+	for _, step := range r.Narrative {
+		fmt.Fprintf(w, "\n%s\n", step.Heading)
+		for _, line := range step.Lines {
+			fmt.Fprintf(w, "  %-9s %s\n", line.When(), line.Text)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%s", i18n.T("Run %s", i18n.T(run.Status)))
+	if run.CompletedAt != nil {
+		fmt.Fprint(w, i18n.T(" at %s, after %s", run.CompletedAt.Format("2006-01-02 15:04:05"),
+			run.CompletedAt.Sub(run.StartedAt).Round(time.Second)))
+	}
+	if run.Notes != "" {
+		fmt.Fprintf(w, "; %s", run.Notes)
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
@@ -0,0 +1,189 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/githost"
+)
+
+// Report collects everything known about a single test run
+type Report struct {
+	Run         *database.TestRun
+	Environment *database.Environment // Machine the run ran on; nil if not recorded
+	Steps       []*StepSummary
+	Operations  []*database.Operation
+	Diffs       []*StepDiff
+	Sizes       []*database.RepositorySize
+	Critical    *CriticalPath
+	Narrative   []*NarrativeStep // What happened in each step, in order
+	GeneratedAt time.Time
+}
+
+// StepSummary aggregates the operations and checksums of one step
+type StepSummary struct {
+	Number         int
+	OperationCount int
+	FailedCount    int
+	DurationMs     int64
+	// HostAPIMs is the part of DurationMs spent calling the hosting service's API,
+	// e.g. creating the repository on GitHub, rather than moving git and LFS data
+	HostAPIMs     int64
+	ChecksumCount int
+}
+
+// StepDiff summarizes checksum changes between two consecutive steps
+type StepDiff struct {
+	FromStep    int
+	ToStep      int
+	Added       int
+	Deleted     int
+	Modified    int
+	Renamed     int
+	Differences []*checksum.Difference
+}
+
+// Build gathers the report data for a run from the database
+func Build(db *database.DB, runID int64) (*Report, error) {
+	run, err := db.GetTestRun(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	ops, err := db.ListOperations(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes, err := db.ListRepositorySizes(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	deps, err := db.ListOperationDependencies(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := db.ListStepResults(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	verifications, err := db.ListVerifications(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := db.GetEnvironment(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{
+		Run:         run,
+		Environment: env,
+		Operations:  ops,
+		Sizes:       sizes,
+		Critical:    ComputeCriticalPath(ops, deps),
+		GeneratedAt: time.Now(),
+	}
+
+	steps := make(map[int]*StepSummary)
+	stepFor := func(n int) *StepSummary {
+		if steps[n] == nil {
+			steps[n] = &StepSummary{Number: n}
+		}
+		return steps[n]
+	}
+
+	for _, op := range ops {
+		step := stepFor(op.StepNumber)
+		step.OperationCount++
+		step.DurationMs += op.DurationMs
+		if githost.IsControlPlane(op.Operation) {
+			step.HostAPIMs += op.DurationMs
+		}
+		if op.Status != "success" {
+			step.FailedCount++
+		}
+	}
+
+	rows, err := db.QueryRaw("SELECT step_number, COUNT(*) FROM checksums WHERE run_id = ? AND step_number > 0 GROUP BY step_number", runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count checksums: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var n, count int
+		if err := rows.Scan(&n, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan checksum count: %w", err)
+		}
+		stepFor(n).ChecksumCount = count
+	}
+
+	for _, step := range steps {
+		r.Steps = append(r.Steps, step)
+	}
+	sort.Slice(r.Steps, func(i, j int) bool {
+		return r.Steps[i].Number < r.Steps[j].Number
+	})
+
+	// Diff each pair of consecutive steps that recorded checksums
+	var checksummed []int
+	for _, step := range r.Steps {
+		if step.ChecksumCount > 0 {
+			checksummed = append(checksummed, step.Number)
+		}
+	}
+	for i := 1; i < len(checksummed); i++ {
+		diffs, err := checksum.CompareChecksums(db, runID, checksummed[i-1], checksummed[i])
+		if err != nil {
+			return nil, err
+		}
+		r.Diffs = append(r.Diffs, summarizeDiff(checksummed[i-1], checksummed[i], diffs))
+	}
+
+	r.Narrative = buildNarrative(r, results, verifications)
+
+	return r, nil
+}
+
+// summarizeDiff counts the change types in a checksum comparison
+func summarizeDiff(from, to int, diffs []*checksum.Difference) *StepDiff {
+	sd := &StepDiff{FromStep: from, ToStep: to, Differences: diffs}
+	for _, d := range diffs {
+		switch d.ChangeType {
+		case "added":
+			sd.Added++
+		case "deleted":
+			sd.Deleted++
+		case "renamed":
+			sd.Renamed++
+		default:
+			sd.Modified++
+		}
+	}
+	return sd
+}
+
+// TotalDurationMs returns the summed duration of all operations in the run
+func (r *Report) TotalDurationMs() int64 {
+	var total int64
+	for _, step := range r.Steps {
+		total += step.DurationMs
+	}
+	return total
+}
+
+// HostAPIDurationMs returns the part of TotalDurationMs spent calling the hosting service's API
+func (r *Report) HostAPIDurationMs() int64 {
+	var total int64
+	for _, step := range r.Steps {
+		total += step.HostAPIMs
+	}
+	return total
+}
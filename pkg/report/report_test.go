@@ -0,0 +1,353 @@
+package report
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestBuildAndRenderHTML(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	run := &database.TestRun{ScenarioID: 6, ServerType: "bare", Protocol: "local", GitServer: "bare", StartedAt: time.Now(), Status: "completed"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("Failed to create test run: %v", err)
+	}
+
+	ops := []*database.Operation{
+		{RunID: run.ID, StepNumber: 1, Operation: "init", StartedAt: time.Now(), DurationMs: 40, Status: "success"},
+		{RunID: run.ID, StepNumber: 2, Operation: "commit", StartedAt: time.Now(), DurationMs: 250, Status: "success"},
+		{RunID: run.ID, StepNumber: 2, Operation: "push", StartedAt: time.Now(), DurationMs: 900, Status: "failed", Error: "<rejected>"},
+	}
+	for _, op := range ops {
+		if err := db.CreateOperation(op); err != nil {
+			t.Fatalf("CreateOperation failed: %v", err)
+		}
+	}
+
+	if err := checksum.StoreChecksums(db, run.ID, 1, []*checksum.FileChecksum{
+		{Path: "a.pdf", CRC32: 0x11111111, SizeBytes: 10},
+	}, checksum.ConflictReplace); err != nil {
+		t.Fatalf("StoreChecksums failed: %v", err)
+	}
+	if err := checksum.StoreChecksums(db, run.ID, 2, []*checksum.FileChecksum{
+		{Path: "a.pdf", CRC32: 0x22222222, SizeBytes: 12},
+		{Path: "b.zip", CRC32: 0x33333333, SizeBytes: 20},
+	}, checksum.ConflictReplace); err != nil {
+		t.Fatalf("StoreChecksums failed: %v", err)
+	}
+
+	if err := db.CreateRepositorySize(&database.RepositorySize{RunID: run.ID, StepNumber: 2, Location: "client-lfs", SizeBytes: 2048, MeasuredAt: time.Now()}); err != nil {
+		t.Fatalf("CreateRepositorySize failed: %v", err)
+	}
+
+	r, err := Build(db, run.ID)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if len(r.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %d", len(r.Steps))
+	}
+	if r.Steps[1].DurationMs != 1150 || r.Steps[1].FailedCount != 1 || r.Steps[1].ChecksumCount != 2 {
+		t.Errorf("Step 2 = %+v, want 1150ms, 1 failed, 2 checksums", r.Steps[1])
+	}
+	if r.TotalDurationMs() != 1190 {
+		t.Errorf("TotalDurationMs = %d, want 1190", r.TotalDurationMs())
+	}
+	if len(r.Diffs) != 1 || r.Diffs[0].Added != 1 || r.Diffs[0].Modified != 1 {
+		t.Errorf("Diffs = %+v, want one diff with 1 added and 1 modified", r.Diffs)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderHTML(&buf, r); err != nil {
+		t.Fatalf("RenderHTML failed: %v", err)
+	}
+	html := buf.String()
+	for _, want := range []string{"<svg", "b.zip", "client-lfs", "2.0 KB", "&lt;rejected&gt;"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML report missing %q", want)
+		}
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	durations := [][]int64{{100, 1000}, {150, 500}}
+	var runIDs []int64
+	for _, d := range durations {
+		run := &database.TestRun{ScenarioID: 6, ServerType: "bare", Protocol: "local", GitServer: "bare", StartedAt: time.Now(), Status: "completed"}
+		if err := db.CreateTestRun(run); err != nil {
+			t.Fatalf("Failed to create test run: %v", err)
+		}
+		runIDs = append(runIDs, run.ID)
+
+		for i, op := range []string{"add", "push"} {
+			if err := db.CreateOperation(&database.Operation{RunID: run.ID, StepNumber: 2, Operation: op, StartedAt: time.Now(), DurationMs: d[i], Status: "success"}); err != nil {
+				t.Fatalf("CreateOperation failed: %v", err)
+			}
+		}
+	}
+
+	// Only the second run pulled
+	if err := db.CreateOperation(&database.Operation{RunID: runIDs[1], StepNumber: 6, Operation: "pull", StartedAt: time.Now(), DurationMs: 70, Status: "success"}); err != nil {
+		t.Fatalf("CreateOperation failed: %v", err)
+	}
+
+	cmp, err := CompareRuns(db, runIDs)
+	if err != nil {
+		t.Fatalf("CompareRuns failed: %v", err)
+	}
+	if len(cmp.Rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(cmp.Rows))
+	}
+
+	push := cmp.Rows[1]
+	if push.Operation != "push" {
+		t.Fatalf("Rows[1] = %s, want push", push.Operation)
+	}
+	if d, pct, ok := push.Delta(1); !ok || d != -500 || pct != -50 {
+		t.Errorf("push delta = %d (%.1f%%, ok=%v), want -500 (-50%%)", d, pct, ok)
+	}
+	if _, _, ok := cmp.Rows[2].Delta(1); ok {
+		t.Error("pull delta should be unavailable when the baseline lacks it")
+	}
+	if d, _ := cmp.TotalDelta(1); d != -380 {
+		t.Errorf("TotalDelta = %d, want -380", d)
+	}
+	if got := FormatDelta(50, 50); got != "+50ms (+50.0%)" {
+		t.Errorf("FormatDelta = %q", got)
+	}
+
+	if _, err := CompareRuns(db, runIDs[:1]); err == nil {
+		t.Error("CompareRuns should require at least two runs")
+	}
+}
+
+func TestHostAPITime(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	run := &database.TestRun{ScenarioID: 3, ServerType: "github", Protocol: "https", GitServer: "github", StartedAt: time.Now(), Status: "completed"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("Failed to create test run: %v", err)
+	}
+	for _, op := range []*database.Operation{
+		{RunID: run.ID, StepNumber: 1, Operation: "gh-api-user", StartedAt: time.Now(), DurationMs: 300, Status: "success"},
+		{RunID: run.ID, StepNumber: 1, Operation: "gh-create-repo", StartedAt: time.Now(), DurationMs: 1200, Status: "success"},
+		{RunID: run.ID, StepNumber: 1, Operation: "init", StartedAt: time.Now(), DurationMs: 40, Status: "success"},
+		{RunID: run.ID, StepNumber: 2, Operation: "push", StartedAt: time.Now(), DurationMs: 5000, Status: "success"},
+	} {
+		if err := db.CreateOperation(op); err != nil {
+			t.Fatalf("CreateOperation failed: %v", err)
+		}
+	}
+
+	r, err := Build(db, run.ID)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if r.Steps[0].HostAPIMs != 1500 || r.Steps[1].HostAPIMs != 0 {
+		t.Errorf("HostAPIMs = %d and %d, want 1500 and 0", r.Steps[0].HostAPIMs, r.Steps[1].HostAPIMs)
+	}
+	if r.HostAPIDurationMs() != 1500 || r.TotalDurationMs() != 6540 {
+		t.Errorf("HostAPIDurationMs = %d of %d, want 1500 of 6540", r.HostAPIDurationMs(), r.TotalDurationMs())
+	}
+
+	var buf bytes.Buffer
+	if err := RenderHTML(&buf, r); err != nil {
+		t.Fatalf("RenderHTML failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Hosting API time") {
+		t.Error("HTML report should show the hosting API time")
+	}
+}
+
+func TestNarrative(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Now().Add(-time.Minute).Truncate(time.Second)
+	run := &database.TestRun{ScenarioID: 6, ServerType: "bare", Protocol: "local", GitServer: "bare", StartedAt: start, Status: "failed"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("Failed to create test run: %v", err)
+	}
+
+	files, size := 3, int64(30*1024*1024)
+	for _, op := range []*database.Operation{
+		{RunID: run.ID, StepNumber: 1, Operation: "commit", StartedAt: start.Add(2 * time.Second), DurationMs: 100, Status: "success"},
+		{RunID: run.ID, StepNumber: 1, Operation: "add", StartedAt: start.Add(time.Second), DurationMs: 1500, FileCount: &files, Status: "success"},
+		{RunID: run.ID, StepNumber: 2, Operation: "push", StartedAt: start.Add(5 * time.Second), DurationMs: 2000, TotalBytes: &size, Status: "success"},
+		{RunID: run.ID, StepNumber: 2, Operation: "pull", StartedAt: start.Add(8 * time.Second), DurationMs: 400, Status: "failed", Error: "exit code 1: fatal: bad object\nmore"},
+	} {
+		if err := db.CreateOperation(op); err != nil {
+			t.Fatalf("CreateOperation failed: %v", err)
+		}
+	}
+	for _, v := range []*database.Verification{
+		{RunID: run.ID, StepNumber: 1, Name: "lfs-pointers", Severity: "error", Status: "passed", CheckedAt: start.Add(3 * time.Second)},
+		{RunID: run.ID, StepNumber: 1, Name: "interference", Severity: "warning", Status: "passed", CheckedAt: start.Add(3 * time.Second)},
+		{RunID: run.ID, StepNumber: 2, Name: "repository-sizes", Severity: "warning", Status: "failed", Message: "LFS is smaller", CheckedAt: start.Add(7 * time.Second)},
+	} {
+		if err := db.CreateVerification(v); err != nil {
+			t.Fatalf("CreateVerification failed: %v", err)
+		}
+	}
+	completed := start.Add(4 * time.Second)
+	for _, sr := range []*database.StepResult{
+		{RunID: run.ID, StepNumber: 1, Name: "setup", Status: "completed", StartedAt: start, CompletedAt: &completed, DurationMs: 4000},
+		{RunID: run.ID, StepNumber: 2, Name: "push", Status: "failed", StartedAt: completed, DurationMs: 5000, Error: "git pull failed"},
+	} {
+		if err := db.SaveStepResult(sr); err != nil {
+			t.Fatalf("SaveStepResult failed: %v", err)
+		}
+	}
+	for step, crc := range map[int]uint32{1: 0x11111111, 2: 0x22222222} {
+		if err := checksum.StoreChecksums(db, run.ID, step, []*checksum.FileChecksum{{Path: "a.pdf", CRC32: crc, SizeBytes: 10}}, checksum.ConflictReplace); err != nil {
+			t.Fatalf("StoreChecksums failed: %v", err)
+		}
+	}
+
+	r, err := Build(db, run.ID)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(r.Narrative) != 2 {
+		t.Fatalf("Narrative has %d steps, want 2", len(r.Narrative))
+	}
+
+	var texts []string
+	for _, line := range r.Narrative[0].Lines {
+		texts = append(texts, line.When()+" "+line.Text)
+	}
+	want := []string{"+1s add: 3 files in 1.5s", "+2s commit in 100ms", " verified lfs-pointers, interference"}
+	if strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("step 1 narrative = %q, want %q", texts, want)
+	}
+	if r.Narrative[0].Heading != "Step 1 (setup): completed in 4s" || r.Narrative[0].Failed {
+		t.Errorf("step 1 heading = %q (failed %v)", r.Narrative[0].Heading, r.Narrative[0].Failed)
+	}
+
+	step2 := r.Narrative[1]
+	if !step2.Failed || step2.Heading != "Step 2 (push): failed after 5s" {
+		t.Errorf("step 2 heading = %q (failed %v)", step2.Heading, step2.Failed)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderNarrative(&buf, r); err != nil {
+		t.Fatalf("RenderNarrative failed: %v", err)
+	}
+	text := buf.String()
+	for _, want := range []string{
+		"push: 30.0 MB in 2s (15.0 MB/s)",
+		"check repository-sizes failed (warning): LFS is smaller",
+		"pull failed after 400ms: exit code 1: fatal: bad object\n",
+		"files changed since step 1: 1 modified",
+		"error: git pull failed",
+		"Run failed",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("narrative missing %q:\n%s", want, text)
+		}
+	}
+
+	buf.Reset()
+	if err := RenderHTML(&buf, r); err != nil {
+		t.Fatalf("RenderHTML failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<h3 class=\"failed\">Step 2 (push): failed after 5s</h3>") {
+		t.Error("HTML report should include the narrative")
+	}
+}
+
+func TestBuildSummary(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Now().Add(-time.Hour)
+	runs := []*database.TestRun{
+		{ScenarioID: 1, ServerType: "giftless", Protocol: "http", GitServer: "bare", Status: "failed"},
+		{ScenarioID: 1, ServerType: "giftless", Protocol: "http", GitServer: "bare", Status: "completed"}, // Latest of its configuration
+		{ScenarioID: 1, ServerType: "giftless", Protocol: "http", GitServer: "bare", Status: "cancelled"},
+		{ScenarioID: 2, ServerType: "rudolfs", Protocol: "https", GitServer: "bare", Status: "timed-out"},
+		{ScenarioID: 3, ServerType: "bare", Protocol: "local", GitServer: "bare", Status: "running"},
+	}
+	for i, run := range runs {
+		run.StartedAt = start.Add(time.Duration(i) * time.Minute)
+		if err := db.CreateTestRun(run); err != nil {
+			t.Fatalf("Failed to create test run: %v", err)
+		}
+	}
+	if err := db.CreateOperation(&database.Operation{RunID: runs[1].ID, StepNumber: 2, Operation: "push", StartedAt: time.Now(), DurationMs: 1500, Status: "success"}); err != nil {
+		t.Fatalf("CreateOperation failed: %v", err)
+	}
+
+	s, err := BuildSummary(db, nil, nil)
+	if err != nil {
+		t.Fatalf("BuildSummary failed: %v", err)
+	}
+	if len(s.Rows) != 2 || s.Rows[0].Run.ID != runs[1].ID || s.Rows[1].Run.ID != runs[3].ID {
+		t.Fatalf("BuildSummary rows = %v, want runs %d and %d", s.Rows, runs[1].ID, runs[3].ID)
+	}
+	if s.Rows[0].TotalMs != 1500 || s.Message() != "1 passed, 1 failed" {
+		t.Errorf("TotalMs = %d, Message = %q", s.Rows[0].TotalMs, s.Message())
+	}
+
+	var svg bytes.Buffer
+	if err := RenderBadge(&svg, s, "git lfs"); err != nil {
+		t.Fatalf("RenderBadge failed: %v", err)
+	}
+	if !strings.Contains(svg.String(), ">1 passed, 1 failed</text>") || !strings.HasPrefix(svg.String(), "<svg") {
+		t.Errorf("unexpected badge:\n%s", svg.String())
+	}
+
+	var md bytes.Buffer
+	if err := RenderMarkdownSummary(&md, s); err != nil {
+		t.Fatalf("RenderMarkdownSummary failed: %v", err)
+	}
+	if !strings.Contains(md.String(), "| 1 | giftless | http | bare | completed | 1.5s |") || !strings.Contains(md.String(), "**timed-out**") {
+		t.Errorf("unexpected summary:\n%s", md.String())
+	}
+
+	if s, err := BuildSummary(db, []int64{runs[0].ID}, nil); err != nil || s.Message() != "0 passed, 1 failed" {
+		t.Errorf("BuildSummary(%d) = %v, %v", runs[0].ID, s, err)
+	}
+
+	// A campaign is the runs tagged with its name
+	campaign := database.Tag{Key: database.CampaignTag, Value: "2025-q3"}
+	for _, run := range runs[:2] {
+		if err := db.SetRunTag(run.ID, campaign); err != nil {
+			t.Fatalf("SetRunTag failed: %v", err)
+		}
+	}
+	s, err = BuildSummary(db, nil, []database.Tag{campaign})
+	if err != nil {
+		t.Fatalf("BuildSummary(campaign) failed: %v", err)
+	}
+	if len(s.Rows) != 1 || s.Rows[0].Run.ID != runs[1].ID {
+		t.Errorf("BuildSummary(campaign) rows = %v, want run %d", s.Rows, runs[1].ID)
+	}
+}
@@ -0,0 +1,208 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/i18n"
+)
+
+// TimelineWidth is the default width in characters of the bars of a text timeline
+const TimelineWidth = 60
+
+// Timeline places the operations of a run on a common time axis, so that operations
+// that ran one after another although they could have overlapped stand out
+type Timeline struct {
+	RunID         int64
+	Start         time.Time      // When the first operation started
+	Span          time.Duration  // From the start of the first operation to the end of the last
+	Busy          time.Duration  // Time in which at least one operation was running
+	MaxConcurrent int            // Most operations running at once
+	Bars          []*TimelineBar // Ordered by start time
+}
+
+// TimelineBar is one operation of a Timeline
+type TimelineBar struct {
+	Operation *database.Operation
+	Offset    time.Duration // From the start of the timeline
+	Duration  time.Duration
+}
+
+// End returns the offset at which the operation finished
+func (b *TimelineBar) End() time.Duration {
+	return b.Offset + b.Duration
+}
+
+// Idle returns the time within the span in which no operation was running
+func (t *Timeline) Idle() time.Duration {
+	return t.Span - t.Busy
+}
+
+// BuildTimeline loads the operations of a run and places them on a timeline
+func BuildTimeline(db *database.DB, runID int64) (*Timeline, error) {
+	ops, err := db.ListOperations(runID)
+	if err != nil {
+		return nil, err
+	}
+	t := ComputeTimeline(ops)
+	t.RunID = runID
+	return t, nil
+}
+
+// ComputeTimeline orders operations by start time and measures how much they overlapped
+func ComputeTimeline(ops []*database.Operation) *Timeline {
+	t := &Timeline{}
+	if len(ops) == 0 {
+		return t
+	}
+
+	sorted := append([]*database.Operation(nil), ops...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].StartedAt.Equal(sorted[j].StartedAt) {
+			return sorted[i].StartedAt.Before(sorted[j].StartedAt)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	t.Start = sorted[0].StartedAt
+	var busyUntil time.Duration
+	for _, op := range sorted {
+		bar := &TimelineBar{
+			Operation: op,
+			Offset:    op.StartedAt.Sub(t.Start),
+			Duration:  time.Duration(op.DurationMs) * time.Millisecond,
+		}
+		t.Bars = append(t.Bars, bar)
+		t.Span = max(t.Span, bar.End())
+
+		// Operations are in start order, so the busy time is the union of the intervals so far
+		if bar.End() > busyUntil {
+			t.Busy += bar.End() - max(bar.Offset, busyUntil)
+			busyUntil = bar.End()
+		}
+	}
+
+	// Sweep over start and end events; an end sorts before a start at the same offset
+	type event struct {
+		at    time.Duration
+		delta int
+	}
+	events := make([]event, 0, 2*len(t.Bars))
+	for _, bar := range t.Bars {
+		events = append(events, event{bar.Offset, 1}, event{bar.End(), -1})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at != events[j].at {
+			return events[i].at < events[j].at
+		}
+		return events[i].delta < events[j].delta
+	})
+	running := 0
+	for _, e := range events {
+		running += e.delta
+		t.MaxConcurrent = max(t.MaxConcurrent, running)
+	}
+	return t
+}
+
+// columns returns the first and last column, exclusive, that a bar covers in a chart of
+// width columns. Every bar covers at least one column.
+func (t *Timeline) columns(b *TimelineBar, width int) (int, int) {
+	if t.Span <= 0 {
+		return 0, 1
+	}
+	from := int(int64(b.Offset) * int64(width) / int64(t.Span))
+	to := int((int64(b.End())*int64(width) + int64(t.Span) - 1) / int64(t.Span))
+	from = min(from, width-1)
+	return from, max(to, from+1)
+}
+
+// RenderTimelineText writes the timeline as an ASCII Gantt chart with bars width columns
+// wide: '#' marks successful operations and 'x' failed ones
+func RenderTimelineText(w io.Writer, t *Timeline, width int) error {
+	if width < 10 {
+		width = 10
+	}
+	fmt.Fprintf(w, i18n.T("Timeline of run %d: %d operations over %s")+"\n\n", t.RunID, len(t.Bars), t.Span)
+
+	end := t.Span.String()
+	scale := "0" + strings.Repeat(" ", max(width-1-len(end), 1)) + end
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t %s\n", i18n.T("Step"), i18n.T("Operation"), i18n.T("Start"), i18n.T("Duration"), scale)
+	fmt.Fprintf(tw, "----\t---------\t-----\t--------\t|%s|\n", strings.Repeat("-", width))
+	for _, b := range t.Bars {
+		mark := "#"
+		if b.Operation.Status != "success" {
+			mark = "x"
+		}
+		from, to := t.columns(b, width)
+		chart := strings.Repeat(" ", from) + strings.Repeat(mark, to-from) + strings.Repeat(" ", width-to)
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t|%s|\n", b.Operation.StepNumber, b.Operation.Operation,
+			b.Offset.Round(time.Millisecond), formatMs(b.Operation.DurationMs), chart)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if t.Span > 0 {
+		fmt.Fprintf(w, "\n"+i18n.T("Busy: %s of %s (%.1f%%), idle %s")+"\n", t.Busy, t.Span,
+			float64(t.Busy)*100/float64(t.Span), t.Idle())
+	}
+	_, err := fmt.Fprintf(w, i18n.T("Most operations running at once: %d")+"\n", t.MaxConcurrent)
+	return err
+}
+
+// Dimensions of the SVG timeline, in pixels
+const (
+	timelineLabelWidth = 220
+	timelineChartWidth = 640
+	timelineRowHeight  = 18
+	timelineMargin     = 10
+)
+
+// RenderTimelineSVG writes the timeline as a self-contained SVG Gantt chart. Each bar has
+// a tooltip with the operation's start and duration.
+func RenderTimelineSVG(w io.Writer, t *Timeline) error {
+	var b strings.Builder
+	width := timelineLabelWidth + timelineChartWidth + 2*timelineMargin
+	height := (len(t.Bars)+2)*timelineRowHeight + 2*timelineMargin
+	axisY := timelineMargin + timelineRowHeight
+	title := fmt.Sprintf(i18n.T("Timeline of run %d: %d operations over %s"), t.RunID, len(t.Bars), t.Span)
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" role="img" aria-label="%s">
+<title>%s</title>
+<g font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+`, width, height, html.EscapeString(title), html.EscapeString(title))
+
+	// Axis with the start and end of the span
+	x0 := timelineMargin + timelineLabelWidth
+	fmt.Fprintf(&b, `<text x="%d" y="%d">0</text><text x="%d" y="%d" text-anchor="end">%s</text>
+<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>
+`, x0, axisY-4, x0+timelineChartWidth, axisY-4, t.Span, x0, axisY, x0+timelineChartWidth, axisY)
+
+	for i, bar := range t.Bars {
+		op := bar.Operation
+		y := axisY + i*timelineRowHeight + 3
+		from, to := t.columns(bar, timelineChartWidth)
+		color := "#4c1"
+		if op.Status != "success" {
+			color = "#e05d44"
+		}
+		label := fmt.Sprintf("%d %s", op.StepNumber, op.Operation)
+		tip := fmt.Sprintf("%s: %s +%s", label, bar.Offset.Round(time.Millisecond), formatMs(op.DurationMs))
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text><rect x="%d" y="%d" width="%d" height="%d" fill="%s"><title>%s</title></rect>
+`, timelineMargin, y+timelineRowHeight-6, html.EscapeString(label), x0+from, y+2, to-from,
+			timelineRowHeight-4, color, html.EscapeString(tip))
+	}
+	b.WriteString("</g>\n</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,90 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestComputeTimeline(t *testing.T) {
+	start := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+	ops := []*database.Operation{
+		{ID: 3, StepNumber: 2, Operation: "push", StartedAt: start.Add(4 * time.Second), DurationMs: 4000, Status: "success"},
+		{ID: 1, StepNumber: 1, Operation: "add", StartedAt: start, DurationMs: 2000, Status: "success"},
+		{ID: 2, StepNumber: 1, Operation: "commit", StartedAt: start.Add(time.Second), DurationMs: 500, Status: "failed"},
+		{ID: 4, StepNumber: 2, Operation: "clone", StartedAt: start.Add(6 * time.Second), DurationMs: 2000, Status: "success"},
+	}
+
+	tl := ComputeTimeline(ops)
+	var ids []int64
+	for _, b := range tl.Bars {
+		ids = append(ids, b.Operation.ID)
+	}
+	if len(ids) != 4 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 || ids[3] != 4 {
+		t.Errorf("Bars ordered %v, want [1 2 3 4]", ids)
+	}
+	if tl.Span != 8*time.Second {
+		t.Errorf("Span = %s, want 8s", tl.Span)
+	}
+	// 0-2s and 4-8s are busy, 2-4s idle
+	if tl.Busy != 6*time.Second || tl.Idle() != 2*time.Second {
+		t.Errorf("Busy = %s, idle %s; want 6s and 2s", tl.Busy, tl.Idle())
+	}
+	if tl.MaxConcurrent != 2 {
+		t.Errorf("MaxConcurrent = %d, want 2", tl.MaxConcurrent)
+	}
+
+	// Operations that touch but do not overlap are not concurrent
+	serial := ComputeTimeline([]*database.Operation{
+		{ID: 1, StartedAt: start, DurationMs: 1000},
+		{ID: 2, StartedAt: start.Add(time.Second), DurationMs: 1000},
+	})
+	if serial.MaxConcurrent != 1 {
+		t.Errorf("MaxConcurrent of back-to-back operations = %d, want 1", serial.MaxConcurrent)
+	}
+
+	if empty := ComputeTimeline(nil); len(empty.Bars) != 0 || empty.Span != 0 {
+		t.Errorf("Timeline of no operations = %+v", empty)
+	}
+}
+
+func TestRenderTimeline(t *testing.T) {
+	start := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+	tl := ComputeTimeline([]*database.Operation{
+		{ID: 1, StepNumber: 1, Operation: "add", StartedAt: start, DurationMs: 5000, Status: "success"},
+		{ID: 2, StepNumber: 2, Operation: "push", StartedAt: start.Add(5 * time.Second), DurationMs: 5000, Status: "failed"},
+	})
+	tl.RunID = 7
+
+	var buf bytes.Buffer
+	if err := RenderTimelineText(&buf, tl, 10); err != nil {
+		t.Fatalf("RenderTimelineText failed: %v", err)
+	}
+	want := `Timeline of run 7: 2 operations over 10s
+
+Step  Operation  Start  Duration   0      10s
+----  ---------  -----  --------  |----------|
+1     add        0s     5s        |#####     |
+2     push       5s     5s        |     xxxxx|
+
+Busy: 10s of 10s (100.0%), idle 0s
+Most operations running at once: 1
+`
+	if buf.String() != want {
+		t.Errorf("RenderTimelineText =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := RenderTimelineSVG(&buf, tl); err != nil {
+		t.Fatalf("RenderTimelineSVG failed: %v", err)
+	}
+	svg := buf.String()
+	for _, s := range []string{"<svg ", "Timeline of run 7", `width="320"`, "2 push: 5s +5s", "#e05d44", "</svg>"} {
+		if !strings.Contains(svg, s) {
+			t.Errorf("SVG does not contain %q:\n%s", s, svg)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/i18n"
+)
+
+// SlowestOperations is the number of operations a timing summary lists
+const SlowestOperations = 5
+
+// TimingSummary shows where the time of a run went: the wall-clock time of each step and
+// the slowest individual operations
+type TimingSummary struct {
+	Steps   []*database.StepResult // Steps in order
+	TotalMs int64                  // Sum of the step durations
+	Slowest []*database.Operation  // Slowest successful operations, slowest first
+}
+
+// BuildTimingSummary collects the step durations and the slowest operations of a run
+func BuildTimingSummary(db *database.DB, runID int64) (*TimingSummary, error) {
+	steps, err := db.ListStepResults(runID)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := db.ListOperations(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &TimingSummary{Steps: steps}
+	for _, step := range steps {
+		s.TotalMs += step.DurationMs
+	}
+	for _, op := range ops {
+		if op.Status == "success" {
+			s.Slowest = append(s.Slowest, op)
+		}
+	}
+	sort.SliceStable(s.Slowest, func(i, j int) bool { return s.Slowest[i].DurationMs > s.Slowest[j].DurationMs })
+	if len(s.Slowest) > SlowestOperations {
+		s.Slowest = s.Slowest[:SlowestOperations]
+	}
+	return s, nil
+}
+
+// RenderTimingSummary writes the step durations with their share of the total, then the
+// slowest operations, as text tables
+func RenderTimingSummary(w io.Writer, s *TimingSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", i18n.T("Step"), i18n.T("Name"), i18n.T("Duration"), i18n.T("Share"))
+	fmt.Fprintln(tw, "----\t----\t--------\t-----")
+	for _, step := range s.Steps {
+		share := "-"
+		if s.TotalMs > 0 {
+			share = fmt.Sprintf("%.1f%%", float64(step.DurationMs)/float64(s.TotalMs)*100)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", step.StepNumber, step.Name, formatMs(step.DurationMs), share)
+	}
+	fmt.Fprintf(tw, "\t%s\t%s\n", i18n.T("Total"), formatMs(s.TotalMs))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(s.Slowest) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(w, "\n%s\n", i18n.T("Slowest operations:"))
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", i18n.T("Step"), i18n.T("Operation"), i18n.T("Duration"), i18n.T("Size"))
+	fmt.Fprintln(tw, "----\t---------\t--------\t----")
+	for _, op := range s.Slowest {
+		size := "-"
+		if op.TotalBytes != nil {
+			size = fmt.Sprintf("%.1f MB", float64(*op.TotalBytes)/1024/1024)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", op.StepNumber, op.Operation, formatMs(op.DurationMs), size)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestTimingSummary(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	run := &database.TestRun{ScenarioID: 6, ServerType: "bare", Protocol: "local", GitServer: "bare", StartedAt: time.Now(), Status: "completed"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("Failed to create test run: %v", err)
+	}
+	for i, ms := range []int64{142000, 610000, 48000} {
+		sr := &database.StepResult{RunID: run.ID, StepNumber: i + 1, Name: []string{"setup", "push", "modify"}[i],
+			Status: "completed", StartedAt: time.Now(), DurationMs: ms}
+		if err := db.SaveStepResult(sr); err != nil {
+			t.Fatalf("SaveStepResult failed: %v", err)
+		}
+	}
+	size := int64(1300 << 20)
+	for i, ms := range []int64{40, 600000, 900000, 30, 20, 10, 5} {
+		op := &database.Operation{RunID: run.ID, StepNumber: 2, Operation: "op", StartedAt: time.Now(), DurationMs: ms, Status: "success"}
+		switch i {
+		case 1:
+			op.Operation = "push"
+			op.TotalBytes = &size
+		case 2:
+			op.Status = "failed" // Left out
+		}
+		if err := db.CreateOperation(op); err != nil {
+			t.Fatalf("CreateOperation failed: %v", err)
+		}
+	}
+
+	s, err := BuildTimingSummary(db, run.ID)
+	if err != nil {
+		t.Fatalf("BuildTimingSummary failed: %v", err)
+	}
+	if s.TotalMs != 800000 || len(s.Steps) != 3 {
+		t.Errorf("TotalMs = %d over %d steps, want 800000 over 3", s.TotalMs, len(s.Steps))
+	}
+	if len(s.Slowest) != SlowestOperations || s.Slowest[0].Operation != "push" || s.Slowest[1].DurationMs != 40 {
+		t.Errorf("Slowest = %+v, want the push first and %d operations", s.Slowest, SlowestOperations)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderTimingSummary(&buf, s); err != nil {
+		t.Fatalf("RenderTimingSummary failed: %v", err)
+	}
+	for _, want := range []string{"push    10m10s    76.2%", "Total   13m20s", "Slowest operations:", "10m0s     1300.0 MB"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("summary lacks %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package scenario
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/git"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+)
+
+// Transfer adapters a run can force git-lfs to use with Runner.TransferAdapter
+const (
+	// AdapterBasic uses plain HTTP(S) transfers, also for SSH remotes, which then only
+	// authenticate over SSH: lfs.basictransfersonly and lfs.sshtransfer=never
+	AdapterBasic = "basic"
+	// AdapterSSH moves objects over SSH with git-lfs-transfer (git-lfs 3.0+): lfs.sshtransfer=always
+	AdapterSSH = "ssh"
+	// AdapterMultipart offers the server the multipart-basic adapter of e.g. Giftless, run
+	// by the custom transfer agent Runner.TransferAgent; the server may still choose basic
+	AdapterMultipart = "multipart"
+	// AdapterStandalone moves objects with the standalone transfer agent Runner.TransferAgent,
+	// e.g. lfs-folderstore, without asking a server: lfs.standalonetransferagent
+	AdapterStandalone = "standalone"
+)
+
+// TransferAdapters are the values of Runner.TransferAdapter
+var TransferAdapters = []string{AdapterBasic, AdapterSSH, AdapterMultipart, AdapterStandalone}
+
+// multipartAdapter is the name under which servers offer multipart transfers
+const multipartAdapter = "multipart-basic"
+
+// ValidateTransferAdapter checks that adapter is one of TransferAdapters, or empty, and
+// that adapters run by a custom agent have one
+func ValidateTransferAdapter(adapter, agent string) error {
+	if adapter == "" {
+		if agent != "" {
+			return fmt.Errorf("a transfer agent needs the %s or %s transfer adapter", AdapterMultipart, AdapterStandalone)
+		}
+		return nil
+	}
+	if !slices.Contains(TransferAdapters, adapter) {
+		return fmt.Errorf("invalid transfer adapter '%s' (use %s)", adapter, strings.Join(TransferAdapters, ", "))
+	}
+	needsAgent := adapter == AdapterMultipart || adapter == AdapterStandalone
+	if needsAgent && strings.TrimSpace(agent) == "" {
+		return fmt.Errorf("the %s transfer adapter needs a transfer agent", adapter)
+	}
+	if !needsAgent && agent != "" {
+		return fmt.Errorf("the %s transfer adapter does not use a transfer agent", adapter)
+	}
+	return nil
+}
+
+// expectedAdapter returns the name git-lfs traces for the forced adapter (see
+// git.ParseAdapters), or "" when no adapter is forced
+func (r *Runner) expectedAdapter() string {
+	switch r.TransferAdapter {
+	case AdapterMultipart:
+		return multipartAdapter
+	case AdapterStandalone:
+		fields := strings.Fields(r.TransferAgent)
+		return filepath.Base(fields[0])
+	}
+	return r.TransferAdapter
+}
+
+// adapterSettings returns the git config settings that force the runner's transfer adapter
+func (r *Runner) adapterSettings() (map[string]string, error) {
+	if err := ValidateTransferAdapter(r.TransferAdapter, r.TransferAgent); err != nil {
+		return nil, err
+	}
+
+	switch r.TransferAdapter {
+	case AdapterBasic:
+		return map[string]string{"lfs.basictransfersonly": "true", "lfs.sshtransfer": "never"}, nil
+	case AdapterSSH:
+		if r.Scenario.Protocol != "ssh" {
+			return nil, fmt.Errorf("the %s transfer adapter needs an SSH scenario, but scenario %d uses %s",
+				AdapterSSH, r.Scenario.ID, r.Scenario.Protocol)
+		}
+		return map[string]string{"lfs.sshtransfer": "always"}, nil
+	}
+
+	// Custom agents: the path is the first word of the command line, the rest its arguments
+	fields := strings.Fields(r.TransferAgent)
+	name := r.expectedAdapter()
+	settings := map[string]string{
+		"lfs.customtransfer." + name + ".path": fields[0],
+		"lfs.customtransfer." + name + ".args": strings.Join(fields[1:], " "),
+	}
+	if r.TransferAdapter == AdapterStandalone {
+		settings["lfs.standalonetransferagent"] = name
+	} else {
+		settings["lfs.sshtransfer"] = "never"
+	}
+	return settings, nil
+}
+
+// forceTransferAdapter passes the settings of the runner's transfer adapter to every git
+// command. The returned function stops passing them.
+func (r *Runner) forceTransferAdapter() (func(), error) {
+	settings, err := r.adapterSettings()
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Forcing the %s transfer adapter\n", r.expectedAdapter())
+
+	git.ExportConfig(settings)
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	return func() { git.UnexportConfig(keys...) }, nil
+}
+
+// checkTransferAdapter records as the verification transfer-adapter whether the operations
+// of step that moved LFS objects used the forced adapter; a server that does not support
+// it may have chosen another
+func (r *Runner) checkTransferAdapter(step int) {
+	ops, err := r.DB.ListOperations(r.RunID)
+	if err != nil {
+		log.Warnf("cannot check transfer adapters: %v\n", err)
+		return
+	}
+
+	expected := r.expectedAdapter()
+	checked := false
+	var others []string
+	for _, op := range ops {
+		if op.StepNumber != step || op.TransferAdapter == "" {
+			continue
+		}
+		checked = true
+		for _, adapter := range strings.Split(op.TransferAdapter, ",") {
+			if adapter != expected {
+				others = append(others, fmt.Sprintf("%s used %s", op.Operation, adapter))
+			}
+		}
+	}
+	if !checked {
+		return
+	}
+
+	var mismatch error
+	if len(others) > 0 {
+		mismatch = fmt.Errorf("the %s transfer adapter was forced, but %s", expected, strings.Join(others, ", "))
+	}
+	r.verify(step, "transfer-adapter", SeverityWarning, mismatch)
+}
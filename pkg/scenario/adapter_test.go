@@ -0,0 +1,142 @@
+package scenario
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/git"
+)
+
+func TestValidateTransferAdapter(t *testing.T) {
+	valid := [][2]string{{"", ""}, {"basic", ""}, {"ssh", ""}, {"multipart", "lfs-multipart"}, {"standalone", "lfs-folderstore /mnt/lfs"}}
+	for _, v := range valid {
+		if err := ValidateTransferAdapter(v[0], v[1]); err != nil {
+			t.Errorf("ValidateTransferAdapter(%q, %q) failed: %v", v[0], v[1], err)
+		}
+	}
+
+	invalid := [][2]string{{"tus", ""}, {"multipart", ""}, {"standalone", " "}, {"basic", "lfs-folderstore"}, {"", "lfs-folderstore"}}
+	for _, v := range invalid {
+		if err := ValidateTransferAdapter(v[0], v[1]); err == nil {
+			t.Errorf("ValidateTransferAdapter(%q, %q) should fail", v[0], v[1])
+		}
+	}
+}
+
+func TestAdapterSettings(t *testing.T) {
+	runner := newVerifyRunner(t)
+	runner.Scenario.Protocol = "local"
+
+	runner.TransferAdapter = AdapterBasic
+	settings, err := runner.adapterSettings()
+	if err != nil || settings["lfs.basictransfersonly"] != "true" || settings["lfs.sshtransfer"] != "never" {
+		t.Errorf("basic settings = %v, %v", settings, err)
+	}
+
+	runner.TransferAdapter = AdapterSSH
+	if _, err := runner.adapterSettings(); err == nil || !strings.Contains(err.Error(), "needs an SSH scenario") {
+		t.Errorf("ssh settings for a local scenario: err = %v", err)
+	}
+	runner.Scenario.Protocol = "ssh"
+	if settings, err := runner.adapterSettings(); err != nil || settings["lfs.sshtransfer"] != "always" {
+		t.Errorf("ssh settings = %v, %v", settings, err)
+	}
+
+	runner.TransferAdapter = AdapterStandalone
+	runner.TransferAgent = "/usr/local/bin/lfs-folderstore /mnt/lfs"
+	settings, err = runner.adapterSettings()
+	if err != nil {
+		t.Fatalf("standalone settings failed: %v", err)
+	}
+	want := map[string]string{
+		"lfs.standalonetransferagent":             "lfs-folderstore",
+		"lfs.customtransfer.lfs-folderstore.path": "/usr/local/bin/lfs-folderstore",
+		"lfs.customtransfer.lfs-folderstore.args": "/mnt/lfs",
+	}
+	for key, value := range want {
+		if settings[key] != value {
+			t.Errorf("standalone %s = %q, want %q", key, settings[key], value)
+		}
+	}
+
+	runner.TransferAdapter = AdapterMultipart
+	runner.TransferAgent = "lfs-multipart"
+	settings, err = runner.adapterSettings()
+	if err != nil || settings["lfs.customtransfer.multipart-basic.path"] != "lfs-multipart" || settings["lfs.standalonetransferagent"] != "" {
+		t.Errorf("multipart settings = %v, %v", settings, err)
+	}
+}
+
+func TestParseAdapters(t *testing.T) {
+	trace := `12:00:01.000000 trace git-lfs: tq: sending batch of size 3
+12:00:01.100000 trace git-lfs: xfer: adapter "multipart-basic" Begin() with 8 workers
+12:00:02.000000 trace git-lfs: xfer: adapter "basic" Begin() with 8 workers
+12:00:03.000000 trace git-lfs: xfer: adapter "multipart-basic" Begin() with 8 workers`
+	if got := git.ParseAdapters(trace); strings.Join(got, ",") != "multipart-basic,basic" {
+		t.Errorf("ParseAdapters = %v", got)
+	}
+	if got := git.ParseAdapters("trace git: built-in: git push"); len(got) != 0 {
+		t.Errorf("ParseAdapters without transfers = %v", got)
+	}
+}
+
+func TestCheckTransferAdapter(t *testing.T) {
+	runner := newVerifyRunner(t)
+	runner.TransferAdapter = AdapterMultipart
+	runner.TransferAgent = "lfs-multipart"
+
+	for _, op := range []*database.Operation{
+		{StepNumber: 2, Operation: "push", TransferAdapter: "multipart-basic"},
+		{StepNumber: 2, Operation: "commit"},
+		{StepNumber: 3, Operation: "clone", TransferAdapter: "basic"},
+	} {
+		op.RunID = runner.RunID
+		op.Status = "success"
+		op.StartedAt = time.Now()
+		if err := runner.DB.CreateOperation(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, step := range []int{1, 2, 3} {
+		runner.checkTransferAdapter(step)
+	}
+
+	verifications, err := runner.DB.ListVerifications(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListVerifications failed: %v", err)
+	}
+	if len(verifications) != 2 {
+		t.Fatalf("verifications = %+v, want steps 2 and 3 only", verifications)
+	}
+	if v := verifications[0]; v.StepNumber != 2 || v.Status != "passed" {
+		t.Errorf("step 2 verification = %+v", v)
+	}
+	if v := verifications[1]; v.Status != "failed" || v.Severity != SeverityWarning || !strings.Contains(v.Message, "clone used basic") {
+		t.Errorf("step 3 verification = %+v", v)
+	}
+}
+
+func TestTransferAdapterFailureFailsRun(t *testing.T) {
+	runner := newVerifyRunner(t)
+	runner.TransferAdapter = AdapterSSH
+	runner.Scenario.Protocol = "local"
+
+	run, err := runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if err := runner.runSteps(context.Background(), run, nil); err == nil {
+		t.Fatal("runSteps succeeded with an ssh transfer adapter on a local scenario")
+	}
+
+	run, err = runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if run.Status != "failed" || run.CompletedAt == nil || !strings.Contains(run.Notes, "Transfer adapter could not be forced before the steps ran") {
+		t.Errorf("run = %s, %v, %q; want failed before the steps ran", run.Status, run.CompletedAt, run.Notes)
+	}
+}
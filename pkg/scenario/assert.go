@@ -0,0 +1,441 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+)
+
+// Assertion is an acceptance criterion comparing a value recorded for a run with another
+// value or a constant, e.g. "operations.push.duration_ms < 5m". Values are:
+//
+//	operations.NAME.FIELD  operations named NAME so far: count, failed (how many failed),
+//	                       duration_ms (the longest), total_ms (their sum) or bytes (their sum)
+//	sizes.LOCATION[.files] latest measurement of a location such as client-lfs or server:
+//	                       its bytes, or with .files its file count
+//	steps.N.FIELD          step N: duration_ms, rx_bytes or tx_bytes
+//	diff(A,B).FIELD        checksum differences between steps A and B: count, added,
+//	                       deleted, modified or renamed
+//	verifications.failed   verifications failed so far
+//
+// Constants are numbers, sizes such as 1GB or 500M (binary units), or durations such as
+// 90s or 5m, which compare as milliseconds.
+type Assertion struct {
+	Expr  string
+	op    string
+	left  operand
+	right operand
+}
+
+// operand is a constant or a reference to recorded data
+type operand struct {
+	text  string
+	ref   []string // Path of a reference, e.g. [operations push duration_ms]; nil for a constant
+	diff  [2]int   // Steps of a diff() reference
+	value float64  // Value of a constant
+}
+
+// Comparison operators, longest first so "<=" is not taken for "<"
+var assertOperators = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// diffPattern matches a diff() reference
+var diffPattern = regexp.MustCompile(`^diff\(\s*(\d+)\s*,\s*(\d+)\s*\)\.(\w+)$`)
+
+// Fields of each kind of reference
+var (
+	operationFields = []string{"count", "failed", "duration_ms", "total_ms", "bytes"}
+	stepFields      = []string{"duration_ms", "rx_bytes", "tx_bytes"}
+	diffFields      = []string{"count", "added", "deleted", "modified", "renamed"}
+)
+
+// errNotRecorded is returned for a reference to data the run has not recorded yet
+var errNotRecorded = errors.New("not recorded yet")
+
+// ParseAssertion parses an assertion
+func ParseAssertion(expr string) (*Assertion, error) {
+	a := &Assertion{Expr: strings.TrimSpace(expr)}
+	for _, op := range assertOperators {
+		if left, right, found := strings.Cut(a.Expr, op); found {
+			a.op = op
+			var err error
+			if a.left, err = parseOperand(left); err != nil {
+				return nil, fmt.Errorf("invalid assertion '%s': %w", a.Expr, err)
+			}
+			if a.right, err = parseOperand(right); err != nil {
+				return nil, fmt.Errorf("invalid assertion '%s': %w", a.Expr, err)
+			}
+			if a.left.ref == nil && a.right.ref == nil {
+				return nil, fmt.Errorf("invalid assertion '%s': it compares no recorded value", a.Expr)
+			}
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid assertion '%s': no comparison (use one of %s)", a.Expr, strings.Join(assertOperators, " "))
+}
+
+// ParseAssertions parses a list of assertions
+func ParseAssertions(exprs []string) ([]*Assertion, error) {
+	assertions := make([]*Assertion, 0, len(exprs))
+	for _, expr := range exprs {
+		a, err := ParseAssertion(expr)
+		if err != nil {
+			return nil, err
+		}
+		assertions = append(assertions, a)
+	}
+	return assertions, nil
+}
+
+// parseOperand parses one side of a comparison
+func parseOperand(text string) (operand, error) {
+	o := operand{text: strings.TrimSpace(text)}
+	if o.text == "" {
+		return o, fmt.Errorf("missing value")
+	}
+	if strings.ContainsAny(o.text, "<>=!") {
+		return o, fmt.Errorf("only one comparison is allowed")
+	}
+
+	if match := diffPattern.FindStringSubmatch(o.text); match != nil {
+		o.diff[0], _ = strconv.Atoi(match[1])
+		o.diff[1], _ = strconv.Atoi(match[2])
+		o.ref = []string{"diff", match[3]}
+		return o, checkField(o.text, match[3], diffFields)
+	}
+	if c := o.text[0]; c >= '0' && c <= '9' || c == '.' {
+		value, err := parseConstant(o.text)
+		o.value = value
+		return o, err
+	}
+
+	o.ref = strings.Split(o.text, ".")
+	switch o.ref[0] {
+	case "operations":
+		if len(o.ref) != 3 || o.ref[1] == "" {
+			return o, fmt.Errorf("'%s' is not operations.NAME.FIELD", o.text)
+		}
+		return o, checkField(o.text, o.ref[2], operationFields)
+	case "sizes":
+		if len(o.ref) == 3 && o.ref[2] == "files" || len(o.ref) == 2 && o.ref[1] != "" {
+			return o, nil
+		}
+		return o, fmt.Errorf("'%s' is not sizes.LOCATION or sizes.LOCATION.files", o.text)
+	case "steps":
+		if len(o.ref) != 3 {
+			return o, fmt.Errorf("'%s' is not steps.N.FIELD", o.text)
+		}
+		if n, err := strconv.Atoi(o.ref[1]); err != nil || n < 1 {
+			return o, fmt.Errorf("invalid step number in '%s'", o.text)
+		}
+		return o, checkField(o.text, o.ref[2], stepFields)
+	case "verifications":
+		if len(o.ref) == 2 && o.ref[1] == "failed" {
+			return o, nil
+		}
+		return o, fmt.Errorf("'%s' is not verifications.failed", o.text)
+	}
+	return o, fmt.Errorf("unknown value '%s' (use operations, sizes, steps, diff() or verifications)", o.text)
+}
+
+// checkField reports a field that is not one of fields
+func checkField(text, field string, fields []string) error {
+	for _, f := range fields {
+		if f == field {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown field '%s' in '%s' (use %s)", field, text, strings.Join(fields, ", "))
+}
+
+// parseConstant parses a number, a duration (as milliseconds) or a size (as bytes)
+func parseConstant(text string) (float64, error) {
+	if value, err := strconv.ParseFloat(text, 64); err == nil {
+		return value, nil
+	}
+	if d, err := time.ParseDuration(text); err == nil {
+		return float64(d.Milliseconds()), nil
+	}
+	if size, err := testdata.ParseSize(text); err == nil {
+		return float64(size), nil
+	}
+	return 0, fmt.Errorf("invalid constant '%s' (use a number, a size such as 1GB or a duration such as 5m)", text)
+}
+
+// assertionData is what a run recorded up to a step, which assertions are checked against
+type assertionData struct {
+	step          int
+	operations    []*database.Operation
+	sizes         []*database.RepositorySize
+	steps         map[int]*database.StepResult
+	verifications []*database.Verification
+	checksums     checksum.Store
+	runID         int64
+}
+
+// check evaluates the assertion. It returns errNotRecorded if a value it compares has
+// not been recorded yet, and an error giving the recorded values if the comparison fails.
+func (a *Assertion) check(data *assertionData) error {
+	left, err := data.resolve(a.left)
+	if err != nil {
+		return err
+	}
+	right, err := data.resolve(a.right)
+	if err != nil {
+		return err
+	}
+
+	var ok bool
+	switch a.op {
+	case "<":
+		ok = left < right
+	case "<=":
+		ok = left <= right
+	case ">":
+		ok = left > right
+	case ">=":
+		ok = left >= right
+	case "==":
+		ok = left == right
+	case "!=":
+		ok = left != right
+	}
+	if ok {
+		return nil
+	}
+
+	// The assertion itself names the constants, so describe the recorded values
+	var values []string
+	if a.left.ref != nil {
+		values = append(values, a.left.text+" is "+formatValue(left))
+	}
+	if a.right.ref != nil {
+		values = append(values, a.right.text+" is "+formatValue(right))
+	}
+	return errors.New(strings.Join(values, ", "))
+}
+
+// formatValue formats a value without a fraction if it has none
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// resolve returns the value of an operand
+func (d *assertionData) resolve(o operand) (float64, error) {
+	if o.ref == nil {
+		return o.value, nil
+	}
+
+	switch o.ref[0] {
+	case "operations":
+		return d.operationValue(o.ref[1], o.ref[2])
+	case "sizes":
+		var latest *database.RepositorySize
+		for _, size := range d.sizes {
+			if size.Location == o.ref[1] && size.StepNumber <= d.step && (latest == nil || size.StepNumber >= latest.StepNumber) {
+				latest = size
+			}
+		}
+		switch {
+		case latest == nil:
+			return 0, fmt.Errorf("%s: %w", o.text, errNotRecorded)
+		case len(o.ref) == 3 && latest.FileCount == nil:
+			return 0, fmt.Errorf("%s: %w", o.text, errNotRecorded)
+		case len(o.ref) == 3:
+			return float64(*latest.FileCount), nil
+		}
+		return float64(latest.SizeBytes), nil
+	case "steps":
+		n, _ := strconv.Atoi(o.ref[1])
+		result := d.steps[n]
+		if result == nil || n > d.step {
+			return 0, fmt.Errorf("%s: %w", o.text, errNotRecorded)
+		}
+		var value *int64
+		switch o.ref[2] {
+		case "duration_ms":
+			value = &result.DurationMs
+		case "rx_bytes":
+			value = result.RxBytes
+		case "tx_bytes":
+			value = result.TxBytes
+		}
+		if value == nil {
+			return 0, fmt.Errorf("%s: %w", o.text, errNotRecorded)
+		}
+		return float64(*value), nil
+	case "diff":
+		return d.diffValue(o)
+	case "verifications":
+		failed := 0
+		for _, v := range d.verifications {
+			if v.Status == "failed" && v.StepNumber <= d.step {
+				failed++
+			}
+		}
+		return float64(failed), nil
+	}
+	return 0, fmt.Errorf("unknown value '%s'", o.text)
+}
+
+// operationValue aggregates a field of the operations named name
+func (d *assertionData) operationValue(name, field string) (float64, error) {
+	var count, failed int
+	var longest, total, bytes int64
+	for _, op := range d.operations {
+		if op.Operation != name || op.StepNumber > d.step {
+			continue
+		}
+		count++
+		if op.Status == "failed" {
+			failed++
+		}
+		longest = max(longest, op.DurationMs)
+		total += op.DurationMs
+		if op.TotalBytes != nil {
+			bytes += *op.TotalBytes
+		}
+	}
+
+	switch field {
+	case "count":
+		return float64(count), nil
+	case "failed":
+		return float64(failed), nil
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("operations.%s.%s: no %s operations %w", name, field, name, errNotRecorded)
+	}
+	switch field {
+	case "duration_ms":
+		return float64(longest), nil
+	case "total_ms":
+		return float64(total), nil
+	}
+	return float64(bytes), nil
+}
+
+// diffValue counts the checksum differences of a diff() reference
+func (d *assertionData) diffValue(o operand) (float64, error) {
+	if o.diff[0] > d.step || o.diff[1] > d.step {
+		return 0, fmt.Errorf("%s: %w", o.text, errNotRecorded)
+	}
+	diffs, err := checksum.CompareStored(d.checksums, d.runID, o.diff[0], o.diff[1])
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, diff := range diffs {
+		switch o.ref[1] {
+		case "count":
+			count++
+		case "modified":
+			if diff.ChangeType == "modified" || diff.ChangeType == "size-changed" {
+				count++
+			}
+		default:
+			if diff.ChangeType == o.ref[1] {
+				count++
+			}
+		}
+	}
+	return float64(count), nil
+}
+
+// resolveAssertions parses the assertions of the runner and of the fixture
+func (r *Runner) resolveAssertions() ([]*Assertion, error) {
+	if r.assertions != nil {
+		return r.assertions, nil
+	}
+
+	fixture, err := r.resolveFixture()
+	if err != nil {
+		return nil, err
+	}
+	exprs := append(append([]string(nil), r.Assertions...), fixture.Assertions...)
+	assertions, err := ParseAssertions(exprs)
+	if err != nil {
+		return nil, err
+	}
+	r.assertions = assertions
+	return assertions, nil
+}
+
+// checkAssertions checks the assertions against what the run recorded up to the step of
+// result, which is not saved yet, and records each one whose values are recorded as a
+// verification named after it
+func (r *Runner) checkAssertions(result *database.StepResult) error {
+	assertions, err := r.resolveAssertions()
+	if err != nil || len(assertions) == 0 {
+		return err
+	}
+
+	data, err := r.assertionData(result)
+	if err != nil {
+		return err
+	}
+	if r.asserted == nil {
+		r.asserted = make(map[string]bool)
+	}
+
+	var failed error
+	for _, a := range assertions {
+		checkErr := a.check(data)
+		if errors.Is(checkErr, errNotRecorded) {
+			continue
+		}
+		r.asserted[a.Expr] = true
+		if err := r.verify(result.StepNumber, "assert: "+a.Expr, SeverityError, checkErr); err != nil && failed == nil {
+			failed = fmt.Errorf("assertion '%s' failed: %w", a.Expr, err)
+		}
+	}
+	return failed
+}
+
+// assertionData gathers what the run recorded up to the step of result
+func (r *Runner) assertionData(result *database.StepResult) (*assertionData, error) {
+	store, err := checksum.NewStore(r.ChecksumStore, r.DB, r.ManifestDir(), r.ChecksumConflict)
+	if err != nil {
+		return nil, err
+	}
+	data := &assertionData{step: result.StepNumber, checksums: store, runID: r.RunID}
+
+	if data.operations, err = r.DB.ListOperations(r.RunID); err != nil {
+		return nil, err
+	}
+	if data.sizes, err = r.DB.ListRepositorySizes(r.RunID); err != nil {
+		return nil, err
+	}
+	if data.verifications, err = r.DB.ListVerifications(r.RunID); err != nil {
+		return nil, err
+	}
+	results, err := r.DB.ListStepResults(r.RunID)
+	if err != nil {
+		return nil, err
+	}
+	data.steps = make(map[int]*database.StepResult)
+	for _, step := range results {
+		data.steps[step.StepNumber] = step
+	}
+	data.steps[result.StepNumber] = result
+	return data, nil
+}
+
+// warnUncheckedAssertions records a warning for each assertion that was never checked
+// because a value it compares was never recorded, such as operations of a misspelled name
+func (r *Runner) warnUncheckedAssertions() {
+	step := r.step()
+	for _, a := range r.assertions {
+		if r.asserted[a.Expr] {
+			continue
+		}
+		r.verify(step, "assert: "+a.Expr, SeverityWarning, fmt.Errorf("never checked: no step recorded the values it compares"))
+	}
+}
@@ -0,0 +1,136 @@
+package scenario
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestParseAssertion(t *testing.T) {
+	valid := map[string]float64{
+		"operations.push.duration_ms < 5m":             300000,
+		"sizes.client-lfs >= 1GB":                      1 << 30,
+		"diff(3, 4).count == 0":                        0,
+		"operations.lfs-track.failed != 1":             1,
+		"steps.2.rx_bytes <= 500M":                     500 << 20,
+		"verifications.failed > 0.5":                   0.5,
+		"sizes.server.files == sizes.client-lfs.files": 0,
+	}
+	for expr, want := range valid {
+		a, err := ParseAssertion(expr)
+		if err != nil {
+			t.Errorf("ParseAssertion(%q) failed: %v", expr, err)
+			continue
+		}
+		if a.right.ref == nil && a.right.value != want {
+			t.Errorf("ParseAssertion(%q) constant = %v, want %v", expr, a.right.value, want)
+		}
+	}
+
+	invalid := []string{
+		"operations.push.duration_ms",
+		"operations.push.speed < 1",
+		"operations.push < 1",
+		"sizes.client-lfs.bytes > 0",
+		"steps.x.duration_ms < 1",
+		"diff(3,4).moved == 0",
+		"1 < 2",
+		"commits.count > 0",
+		"operations.push.count < 1 < 2",
+		"operations.push.count < 1 parsec",
+	}
+	for _, expr := range invalid {
+		if _, err := ParseAssertion(expr); err == nil {
+			t.Errorf("ParseAssertion(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCheckAssertions(t *testing.T) {
+	runner := newVerifyRunner(t)
+	runner.Lenient = true
+	runner.Scenario.Fixture = &Fixture{Assertions: []string{
+		"operations.push.duration_ms < 2s",
+		"diff(1,2).count == 0",
+		"sizes.client-lfs >= 1KB",
+		"operations.pull.count == 0",
+		"operations.prune.total_ms < 1s",
+	}}
+	runner.Assertions = []string{"steps.2.duration_ms < 1m"}
+
+	bytes := int64(4096)
+	for _, op := range []*database.Operation{
+		{RunID: runner.RunID, StepNumber: 2, Operation: "push", DurationMs: 1500, Status: "success", TotalBytes: &bytes},
+		{RunID: runner.RunID, StepNumber: 2, Operation: "push", DurationMs: 2500, Status: "success"},
+	} {
+		op.StartedAt = time.Now()
+		if err := runner.DB.CreateOperation(op); err != nil {
+			t.Fatalf("CreateOperation failed: %v", err)
+		}
+	}
+	runner.saveSize(2, "client-lfs", 4096, 1)
+	for step, crc := range map[int]uint32{1: 1, 2: 2} {
+		if err := checksum.StoreChecksums(runner.DB, runner.RunID, step, []*checksum.FileChecksum{{Path: "a.pdf", CRC32: crc, SizeBytes: 10}}, checksum.ConflictReplace); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// After step 1 only the pull count is known
+	if err := runner.checkAssertions(&database.StepResult{RunID: runner.RunID, StepNumber: 1, DurationMs: 10}); err != nil {
+		t.Errorf("step 1 assertions failed: %v", err)
+	}
+	if len(runner.asserted) != 1 || !runner.asserted["operations.pull.count == 0"] {
+		t.Errorf("checked after step 1: %v", runner.asserted)
+	}
+
+	if err := runner.checkAssertions(&database.StepResult{RunID: runner.RunID, StepNumber: 2, DurationMs: 30000}); err != nil {
+		t.Errorf("lenient step 2 assertions returned %v", err)
+	}
+	runner.warnUncheckedAssertions()
+
+	verifications, err := runner.DB.ListVerifications(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListVerifications failed: %v", err)
+	}
+	got := make(map[string]string)
+	for _, v := range verifications {
+		if v.StepNumber == 2 || v.Severity == SeverityWarning {
+			got[v.Name] = v.Status + " " + v.Message
+		}
+	}
+	want := map[string]string{
+		"assert: operations.push.duration_ms < 2s": "failed operations.push.duration_ms is 2500",
+		"assert: diff(1,2).count == 0":             "failed diff(1,2).count is 1",
+		"assert: sizes.client-lfs >= 1KB":          "passed ",
+		"assert: operations.pull.count == 0":       "passed ",
+		"assert: steps.2.duration_ms < 1m":         "passed ",
+	}
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%s = %q, want %q", name, got[name], status)
+		}
+	}
+	if unchecked := got["assert: operations.prune.total_ms < 1s"]; !strings.HasPrefix(unchecked, "failed never checked") {
+		t.Errorf("unchecked assertion = %q, want a warning", unchecked)
+	}
+
+	// Without leniency a failed assertion fails the step
+	runner.Lenient = false
+	if err := runner.checkAssertions(&database.StepResult{RunID: runner.RunID, StepNumber: 2}); err == nil || !strings.Contains(err.Error(), "push.duration_ms") {
+		t.Errorf("strict step 2 assertions returned %v", err)
+	}
+}
+
+func TestAssertionNotRecorded(t *testing.T) {
+	a, err := ParseAssertion("diff(3,5).added == 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.check(&assertionData{step: 4}); !errors.Is(err, errNotRecorded) {
+		t.Errorf("diff of a later step = %v, want errNotRecorded", err)
+	}
+}
@@ -0,0 +1,159 @@
+package scenario
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/git"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/report"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+)
+
+// CadenceStep is the step number of the commit cadence test in DefaultPipeline, which
+// runs when Runner.CadenceCommits is set
+const CadenceStep = 11
+
+// DefaultCadenceFileSize is the size of the file each commit of the cadence step writes
+const DefaultCadenceFileSize = 64 * 1024
+
+// cadenceFileName returns the name of the file the cadence step modifies; like the
+// concurrent clients' files, it has the extension of a tracked pattern if there is one
+func (r *Runner) cadenceFileName() string {
+	ext, _ := r.clientFileExt()
+	return "cadence" + ext
+}
+
+// Step11_Cadence: Modify one small LFS file CadenceCommits times in the second clone,
+// adding, committing and pushing after every modification
+func (r *Runner) Step11_Cadence() error {
+	ctx := &git.Context{
+		DB:         r.DB,
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		Debug:      r.Debug,
+		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
+	}
+
+	if err := r.configureUser(ctx, r.Repo2Dir); err != nil {
+		return err
+	}
+	// Start from what the server has, which earlier optional steps may have added to
+	if err := ctx.Pull(r.Repo2Dir); err != nil {
+		return err
+	}
+	branch, err := ctx.CurrentBranch(r.Repo2Dir)
+	if err != nil {
+		return err
+	}
+
+	name := r.cadenceFileName()
+	if _, tracked := r.clientFileExt(); !tracked {
+		if err := trackLocally(r.Repo2Dir, name); err != nil {
+			return err
+		}
+	}
+	size := r.CadenceFileSize
+	if size <= 0 {
+		size = DefaultCadenceFileSize
+	}
+
+	result := &database.CadenceResult{
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		Path:       name,
+		FileSize:   size,
+		Status:     "completed",
+	}
+	commitErr := r.commitAtCadence(ctx, result, branch)
+	if commitErr != nil {
+		result.Status = "failed"
+		result.Message = commitErr.Error()
+	}
+	result.CheckedAt = time.Now()
+	if err := r.DB.CreateCadenceResult(result); err != nil {
+		return err
+	}
+	if commitErr != nil {
+		return commitErr
+	}
+
+	// Every version of the file must have reached the server
+	return r.verifyHistoryObjects(r.step(), r.Repo2Dir, true)
+}
+
+// commitAtCadence makes and pushes the commits of the cadence step one at a time, timing
+// each, and counts what the server was asked and sent for them
+func (r *Runner) commitAtCadence(ctx *git.Context, result *database.CadenceResult, branch string) error {
+	path := filepath.Join(r.Repo2Dir, result.Path)
+	var commitMs, pushMs []int64
+
+	log.Debugf("Committing %s %d times, pushing after each commit...\n", result.Path, r.CadenceCommits)
+	before, sampled := r.sampleNetwork()
+	start := time.Now()
+	for i := 1; i <= r.CadenceCommits; i++ {
+		commitStart := time.Now()
+		seed := r.RunID*1000000 + int64(r.step())*1000 + int64(i)
+		if err := writeRandomFile(path, result.FileSize, seed); err != nil {
+			return fmt.Errorf("failed to write %s: %w", result.Path, err)
+		}
+		if err := ctx.Add(r.Repo2Dir, result.Path); err != nil {
+			return err
+		}
+		if err := ctx.Commit(r.Repo2Dir, fmt.Sprintf("Update %s (%d of %d)", result.Path, i, r.CadenceCommits)); err != nil {
+			return err
+		}
+		pushStart := time.Now()
+		if err := ctx.Push(r.Repo2Dir, "origin", branch); err != nil {
+			return err
+		}
+		pushMs = append(pushMs, time.Since(pushStart).Milliseconds())
+		commitMs = append(commitMs, time.Since(commitStart).Milliseconds())
+		result.Commits = i
+	}
+	result.TotalMs = time.Since(start).Milliseconds()
+
+	commits := report.Summarize("commit", commitMs)
+	result.MeanCommitMs = int64(commits.MeanMs)
+	result.MedianCommitMs = int64(commits.MedianMs)
+	result.MaxCommitMs = commits.MaxMs
+	result.MeanPushMs = int64(report.Summarize("push", pushMs).MeanMs)
+
+	// Traffic on the network interface only tells about servers reached over the network
+	if sampled && r.Scenario.Protocol != "local" {
+		if after, ok := r.sampleNetwork(); ok {
+			sent := int64(after.Sub(before).TxBytes)
+			result.SentBytes = &sent
+		}
+	}
+	if r.LFSProxy {
+		requests, err := r.uploadRequests(result.StepNumber)
+		if err != nil {
+			return err
+		}
+		result.BatchRequests = &requests
+	}
+
+	log.Debugf("  %s %d commits of %s in %dms, %dms per commit\n", term.OK(), result.Commits,
+		testdata.FormatSize(result.FileSize), result.TotalMs, result.MeanCommitMs)
+	return nil
+}
+
+// uploadRequests counts the upload Batch API requests the LFS proxy recorded for step
+func (r *Runner) uploadRequests(step int) (int, error) {
+	requests, err := r.DB.ListLFSBatchRequests(r.RunID)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, request := range requests {
+		if request.StepNumber == step && request.Operation == "upload" {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,40 @@
+package scenario
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestUploadRequests(t *testing.T) {
+	runner := newVerifyRunner(t)
+	for _, b := range []*database.LFSBatchRequest{
+		{StepNumber: 11, Operation: "download", StatusCode: 200},
+		{StepNumber: 11, Operation: "upload", StatusCode: 200},
+		{StepNumber: 11, Operation: "upload", StatusCode: 200},
+		{StepNumber: 11, Operation: "upload", StatusCode: 503},
+		{StepNumber: 2, Operation: "upload", StatusCode: 200},
+	} {
+		b.RunID = runner.RunID
+		b.RequestedAt = time.Now()
+		if err := runner.DB.CreateLFSBatchRequest(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := runner.uploadRequests(11)
+	if err != nil {
+		t.Fatalf("uploadRequests failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("uploadRequests(11) = %d, want 3 (failed requests count too)", count)
+	}
+}
+
+func TestCadenceFileName(t *testing.T) {
+	runner := NewRunner(&Scenario{TrackPatterns: []string{"*.psd"}}, nil, t.TempDir(), false, false)
+	if got := runner.cadenceFileName(); got != "cadence.psd" {
+		t.Errorf("cadenceFileName() = %q, want cadence.psd", got)
+	}
+}
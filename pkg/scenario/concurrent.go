@@ -0,0 +1,330 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/git"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+)
+
+// ConcurrentStep is the step number of the concurrent multi-client step in DefaultPipeline,
+// which runs after the seven standard steps when Runner.Clients is at least 2
+const ConcurrentStep = 8
+
+// DefaultClientFileSize is the size of the LFS file each concurrent client pushes
+const DefaultClientFileSize = 10 * 1024 * 1024
+
+// maxPushAttempts bounds how often a client retries a push that lost a race
+const maxPushAttempts = 10
+
+// Push failure classes
+const (
+	pushRejected = "rejected" // Another client updated the branch first
+	pushLocked   = "lock"     // A ref, index or database lock was held
+)
+
+// lockMessages identify failures caused by a lock held by another process
+var lockMessages = []string{
+	"cannot lock ref",
+	"unable to lock",
+	"failed to lock",
+	"could not lock config file",
+	"index.lock",
+	"database is locked",
+}
+
+// rejectMessages identify pushes refused because the remote branch moved on
+var rejectMessages = []string{
+	"non-fast-forward",
+	"fetch first",
+	"[rejected]",
+}
+
+// classifyPushError returns pushLocked, pushRejected, or "" for any other failure.
+// Lock messages win, since a lock failure also makes git report rejected refs.
+func classifyPushError(err error) string {
+	msg := strings.ToLower(err.Error())
+	for _, m := range lockMessages {
+		if strings.Contains(msg, m) {
+			return pushLocked
+		}
+	}
+	for _, m := range rejectMessages {
+		if strings.Contains(msg, m) {
+			return pushRejected
+		}
+	}
+	return ""
+}
+
+// clientFileName returns the name of the LFS file pushed by a client (1-based). It has
+// the extension of the first "*.ext" pattern the scenario tracks, so it is stored in LFS;
+// without one it gets untrackedClientExt, which each client tracks locally.
+func (r *Runner) clientFileName(client int) string {
+	ext, _ := r.clientFileExt()
+	return fmt.Sprintf("client%02d%s", client, ext)
+}
+
+// untrackedClientExt is the extension of client files when no pattern gives one
+const untrackedClientExt = ".bin"
+
+// clientFileExt returns the extension of the concurrent clients' files, and whether one
+// of the scenario's patterns tracks it
+func (r *Runner) clientFileExt() (string, bool) {
+	for _, pattern := range r.Scenario.Patterns() {
+		if ext, ok := strings.CutPrefix(pattern, "*."); ok && ext != "" && !strings.ContainsAny(ext, "*?[/") {
+			return "." + ext, true
+		}
+	}
+	return untrackedClientExt, false
+}
+
+// clientRepoDir returns the clone of a client; clients follow repo1 and repo2
+func (r *Runner) clientRepoDir(client int) string {
+	return filepath.Join(r.RunDir, fmt.Sprintf("repo%d", client+2))
+}
+
+// Step8_ConcurrentClients: Push and pull from several clients at once
+func (r *Runner) Step8_ConcurrentClients() error {
+	ctx := &git.Context{
+		DB:         r.DB,
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		Debug:      r.Debug,
+		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
+	}
+
+	cloneURL, branch, err := r.concurrentRemote(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Starting %d concurrent clients against %s...\n", r.Clients, cloneURL)
+
+	// Every client clones, commits its own file and pushes at the same time
+	results := make([]*database.ClientResult, r.Clients)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(client int) {
+			defer wg.Done()
+			results[client-1] = r.runClient(client, cloneURL, branch)
+		}(i + 1)
+	}
+	wg.Wait()
+
+	summary := &database.ConcurrencyResult{
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		Clients:    r.Clients,
+		WallMs:     time.Since(start).Milliseconds(),
+		RecordedAt: time.Now(),
+	}
+	var failures []string
+	for _, result := range results {
+		summary.PushAttempts += result.PushAttempts
+		summary.RejectedPushes += result.RejectedPushes
+		summary.LockErrors += result.LockErrors
+		if result.Status == "completed" {
+			summary.TotalBytes += result.Bytes
+		} else {
+			summary.FailedClients++
+			failures = append(failures, fmt.Sprintf("client %d: %s", result.Client, result.Error))
+		}
+		if err := r.DB.CreateClientResult(result); err != nil {
+			return err
+		}
+	}
+	if err := r.DB.CreateConcurrencyResult(summary); err != nil {
+		return err
+	}
+
+	log.Debugf("  %d of %d clients pushed %.1f MB in %dms (%.1f MB/s), %d rejected pushes, %d lock errors\n", r.Clients-summary.FailedClients, r.Clients, float64(summary.TotalBytes)/1024/1024, summary.WallMs, summary.ThroughputMBps(), summary.RejectedPushes, summary.LockErrors)
+
+	var clientErr error
+	if len(failures) > 0 {
+		clientErr = fmt.Errorf("%d of %d clients failed: %s", len(failures), r.Clients, strings.Join(failures, "; "))
+	}
+	if err := r.verify(r.step(), "concurrent-clients", SeverityError, clientErr); err != nil {
+		return err
+	}
+
+	// Afterwards every client pulls, again concurrently, and must see every pushed file
+	if err := r.verify(r.step(), "concurrent-convergence", SeverityError, r.pullClients(ctx, results)); err != nil {
+		return fmt.Errorf("clients did not converge: %w", err)
+	}
+
+	return nil
+}
+
+// concurrentRemote returns the URL and branch the clients share
+func (r *Runner) concurrentRemote(ctx *git.Context) (string, string, error) {
+	branch, err := ctx.CurrentBranch(r.Repo2Dir)
+	if err != nil {
+		return "", "", err
+	}
+	url, err := r.remoteURL()
+	return url, branch, err
+}
+
+// runClient clones the shared remote into the client's directory, commits a new LFS file,
+// and pushes it, pulling and retrying whenever another client got there first
+func (r *Runner) runClient(client int, cloneURL, branch string) *database.ClientResult {
+	ctx := &git.Context{
+		DB:         r.DB,
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		Debug:      r.Debug,
+		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
+	}
+	result := &database.ClientResult{
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		Client:     client,
+		RepoDir:    r.clientRepoDir(client),
+		StartedAt:  time.Now(),
+		Status:     "failed",
+	}
+
+	err := r.pushFromClient(ctx, result, cloneURL, branch)
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Status = "completed"
+	}
+	return result
+}
+
+// pushFromClient does the work of runClient, counting push attempts and contention in result
+func (r *Runner) pushFromClient(ctx *git.Context, result *database.ClientResult, cloneURL, branch string) error {
+	if err := ctx.Clone(cloneURL, result.RepoDir); err != nil {
+		return err
+	}
+	if err := r.configureUser(ctx, result.RepoDir); err != nil {
+		return err
+	}
+	// Rebase on pulls, so a client that lost a race keeps a linear history
+	if err := ctx.SetConfig(result.RepoDir, "pull.rebase", "true"); err != nil {
+		return err
+	}
+	// No tracked pattern covers the client files, so track them in this clone only;
+	// committing .gitattributes from every client at once would make the pushes conflict
+	if _, tracked := r.clientFileExt(); !tracked {
+		attributes := "client*" + untrackedClientExt + " filter=lfs diff=lfs merge=lfs -text\n"
+		if err := os.WriteFile(filepath.Join(result.RepoDir, ".git", "info", "attributes"), []byte(attributes), 0644); err != nil {
+			return fmt.Errorf("failed to track client files: %w", err)
+		}
+	}
+
+	name := r.clientFileName(result.Client)
+	size := r.ClientFileSize
+	if size <= 0 {
+		size = DefaultClientFileSize
+	}
+	if err := writeRandomFile(filepath.Join(result.RepoDir, name), size, r.RunID*1000+int64(result.Client)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	result.Bytes = size
+
+	if err := ctx.Add(result.RepoDir, name); err != nil {
+		return err
+	}
+	if err := ctx.Commit(result.RepoDir, fmt.Sprintf("Add %s from concurrent client %d", name, result.Client)); err != nil {
+		return err
+	}
+
+	var pushErr error
+	for result.PushAttempts < maxPushAttempts {
+		result.PushAttempts++
+		if pushErr = ctx.Push(result.RepoDir, "origin", branch); pushErr == nil {
+			return nil
+		}
+
+		switch classifyPushError(pushErr) {
+		case pushLocked:
+			result.LockErrors++
+			time.Sleep(time.Duration(result.PushAttempts*100) * time.Millisecond)
+		case pushRejected:
+			result.RejectedPushes++
+			// Bring in the other clients' commits before trying again
+			if err := ctx.Pull(result.RepoDir); err != nil {
+				if classifyPushError(err) != pushLocked {
+					return err
+				}
+				result.LockErrors++
+			}
+		default:
+			return pushErr
+		}
+	}
+	return fmt.Errorf("gave up after %d push attempts: %w", result.PushAttempts, pushErr)
+}
+
+// pullClients pulls into every client that pushed and checks that each one
+// has the files of all of them
+func (r *Runner) pullClients(ctx *git.Context, results []*database.ClientResult) error {
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	for i, result := range results {
+		if result.Status != "completed" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, repoDir string) {
+			defer wg.Done()
+			errs[i] = ctx.Pull(repoDir)
+		}(i, result.RepoDir)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		if result.Status != "completed" {
+			continue
+		}
+		if errs[i] != nil {
+			return fmt.Errorf("client %d: %w", result.Client, errs[i])
+		}
+		for _, other := range results {
+			if other.Status != "completed" {
+				continue
+			}
+			path := filepath.Join(result.RepoDir, r.clientFileName(other.Client))
+			info, err := os.Stat(path)
+			if err != nil {
+				return fmt.Errorf("client %d is missing %s", result.Client, r.clientFileName(other.Client))
+			}
+			if info.Size() != other.Bytes {
+				return fmt.Errorf("client %d has %s with %d bytes, expected %d (LFS pointer not smudged?)",
+					result.Client, r.clientFileName(other.Client), info.Size(), other.Bytes)
+			}
+		}
+	}
+
+	log.Debugf("%s All clients converged\n", term.OK())
+	return nil
+}
+
+// writeRandomFile writes size bytes of incompressible content, the same for the same seed
+func writeRandomFile(path string, size, seed int64) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(file, rand.New(rand.NewSource(seed)), size)
+	return errors.Join(err, file.Close())
+}
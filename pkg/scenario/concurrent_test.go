@@ -0,0 +1,64 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifyPushError(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"git push failed (exit 1): ! [rejected]        main -> main (fetch first)", pushRejected},
+		{"git push failed (exit 1): ! [rejected] main -> main (non-fast-forward)", pushRejected},
+		{"git push failed (exit 1): ! [remote rejected] main -> main (cannot lock ref 'refs/heads/main')", pushLocked},
+		{"git pull failed (exit 128): fatal: Unable to create '/w/repo3/.git/index.lock': File exists.", pushLocked},
+		{"git push failed (exit 1): batch response: Authentication required", ""},
+	}
+
+	for _, tt := range tests {
+		if got := classifyPushError(errors.New(tt.msg)); got != tt.want {
+			t.Errorf("classifyPushError(%q) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestConcurrentClientsEndToEnd(t *testing.T) {
+	requireGitLFS(t)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+
+	runner := newMiniRunner(t)
+	runner.Clients = 3
+	runner.ClientFileSize = 64 * 1024
+	if err := runner.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	clients, err := runner.DB.ListClientResults(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListClientResults failed: %v", err)
+	}
+	if len(clients) != 3 {
+		t.Fatalf("Expected 3 client results, got %d", len(clients))
+	}
+	for _, c := range clients {
+		if c.Status != "completed" || c.StepNumber != ConcurrentStep {
+			t.Errorf("Client %d: status %s in step %d: %s", c.Client, c.Status, c.StepNumber, c.Error)
+		}
+	}
+
+	summaries, err := runner.DB.ListConcurrencyResults(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListConcurrencyResults failed: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].TotalBytes != 3*64*1024 || summaries[0].FailedClients != 0 {
+		t.Errorf("ListConcurrencyResults = %+v, want 3 clients pushing 192 KB", summaries)
+	}
+	// Every client pushes at least once; each push that lost a race adds another attempt
+	if s := summaries[0]; s.PushAttempts < 3 {
+		t.Errorf("PushAttempts = %d, want at least one per client", s.PushAttempts)
+	}
+}
@@ -0,0 +1,144 @@
+package scenario
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mslinn/git-lfs-test/pkg/sshutil"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+	"gopkg.in/yaml.v3"
+)
+
+// FixtureName is the file in the root of a test data set that declares its fixture
+const FixtureName = "fixture.yaml"
+
+// Fixture declares the changes a scenario makes to its data set in step 3 and,
+// optionally, the exact state expected after any step. States that are not
+// declared are derived from the data set and the declared changes.
+type Fixture struct {
+	Deletions []string               `yaml:"deletions"` // Files deleted in step 3
+	Renames   map[string]string      `yaml:"renames"`   // Files renamed in step 3, old name to new name
+	Expected  map[int]*ExpectedState `yaml:"expected"`  // Step number to declared state
+	// Assertions are checked after every step (see Assertion)
+	Assertions []string `yaml:"assertions"`
+	// AllowedDifferences are differences the checksum comparisons of steps 4 and 6
+	// expect (see AllowedDifference)
+	AllowedDifferences []string `yaml:"allowed_differences"`
+}
+
+// ExpectedState is the repository state expected after a step
+type ExpectedState struct {
+	Files  []string `yaml:"files"`   // Files that must exist in the working tree
+	Absent []string `yaml:"absent"`  // Files that must not exist (deleted or renamed away)
+	LFS    []string `yaml:"lfs"`     // Files that must be tracked by LFS
+	NotLFS []string `yaml:"not_lfs"` // Files that must not be tracked by LFS
+}
+
+// DefaultFixture returns the fixture of the standard v1/v2 test data
+func DefaultFixture() *Fixture {
+	return &Fixture{
+		Deletions: []string{"video1.m4v", "video4.ogg"},
+		Renames:   map[string]string{"zip2.zip": "zip2_renamed.zip"},
+	}
+}
+
+// LoadFixture reads the fixture declared by a data set (local path or host:/path).
+// It returns nil without error if the data set declares none.
+func LoadFixture(dataPath string) (*Fixture, error) {
+	var data []byte
+	var err error
+
+	if remote, isRemote := testdata.ParseRemotePath(dataPath); isRemote {
+		path := remote.Path + "/" + FixtureName
+		// A missing file is not an error, so test for it on the remote side
+		data, err = sshutil.Command(remote.Host, fmt.Sprintf("test ! -f '%s' || cat '%s'", path, path)).Output()
+	} else {
+		data, err = os.ReadFile(filepath.Join(dataPath, FixtureName))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", FixtureName, err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var f Fixture
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FixtureName, err)
+	}
+	return &f, nil
+}
+
+// State returns the state expected after step, given the file names of the v1 and v2 data.
+// After step 2 the v1 files are in LFS; steps 3 to 6 add v2 and apply the deletions and
+// renames; after step 7 the same files are no longer in LFS.
+func (f *Fixture) State(step int, v1, v2 []string) *ExpectedState {
+	if s, ok := f.Expected[step]; ok {
+		return s
+	}
+
+	if step <= 2 {
+		return &ExpectedState{Files: v1, LFS: v1}
+	}
+
+	present := make(map[string]bool)
+	for _, name := range append(append([]string(nil), v1...), v2...) {
+		present[name] = true
+	}
+
+	var absent []string
+	for _, name := range f.Deletions {
+		delete(present, name)
+		absent = append(absent, name)
+	}
+	for oldName, newName := range f.Renames {
+		if present[oldName] {
+			delete(present, oldName)
+			present[newName] = true
+		}
+		absent = append(absent, oldName)
+	}
+
+	files := make([]string, 0, len(present))
+	for name := range present {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	sort.Strings(absent)
+
+	if step >= 7 {
+		return &ExpectedState{Files: files, Absent: absent, NotLFS: files}
+	}
+	return &ExpectedState{Files: files, Absent: absent, LFS: files}
+}
+
+// CheckFiles returns an error listing the expected files that are missing from repoDir
+// and the absent files that still exist
+func (s *ExpectedState) CheckFiles(repoDir string) error {
+	var missing, unexpected []string
+	for _, name := range s.Files {
+		if _, err := os.Stat(filepath.Join(repoDir, name)); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	for _, name := range s.Absent {
+		if _, err := os.Stat(filepath.Join(repoDir, name)); err == nil {
+			unexpected = append(unexpected, name)
+		}
+	}
+
+	switch {
+	case len(missing) > 0 && len(unexpected) > 0:
+		return fmt.Errorf("missing files %v and unexpected files %v", missing, unexpected)
+	case len(missing) > 0:
+		return fmt.Errorf("missing files %v", missing)
+	case len(unexpected) > 0:
+		return fmt.Errorf("unexpected files %v", unexpected)
+	}
+	return nil
+}
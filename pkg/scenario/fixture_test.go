@@ -0,0 +1,91 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var (
+	fixtureV1 = []string{"pdf1.pdf", "video1.m4v", "video2.mov", "video3.avi", "video4.ogg", "zip1.zip", "zip2.zip"}
+	fixtureV2 = []string{"pdf1.pdf", "video2.mov", "video3.avi", "zip1.zip"}
+)
+
+func TestDefaultFixtureStates(t *testing.T) {
+	f := DefaultFixture()
+
+	after2 := f.State(2, fixtureV1, fixtureV2)
+	if !reflect.DeepEqual(after2.LFS, fixtureV1) {
+		t.Errorf("Step 2 LFS = %v, want v1 files", after2.LFS)
+	}
+
+	// Matches the lists the scenario steps used before fixtures existed
+	modified := []string{"pdf1.pdf", "video2.mov", "video3.avi", "zip1.zip", "zip2_renamed.zip"}
+	after4 := f.State(4, fixtureV1, fixtureV2)
+	if !reflect.DeepEqual(after4.Files, modified) || !reflect.DeepEqual(after4.LFS, modified) {
+		t.Errorf("Step 4 = %+v, want files and LFS %v", after4, modified)
+	}
+	if want := []string{"video1.m4v", "video4.ogg", "zip2.zip"}; !reflect.DeepEqual(after4.Absent, want) {
+		t.Errorf("Step 4 absent = %v, want %v", after4.Absent, want)
+	}
+
+	after7 := f.State(7, fixtureV1, fixtureV2)
+	if len(after7.LFS) != 0 || !reflect.DeepEqual(after7.NotLFS, modified) {
+		t.Errorf("Step 7 = %+v, want no LFS files and not-LFS %v", after7, modified)
+	}
+}
+
+func TestLoadFixture(t *testing.T) {
+	dir := t.TempDir()
+
+	f, err := LoadFixture(dir)
+	if err != nil || f != nil {
+		t.Fatalf("LoadFixture without fixture.yaml = %v, %v; want nil, nil", f, err)
+	}
+
+	yaml := `deletions: [model.onnx]
+renames:
+  scene.psd: scene_final.psd
+expected:
+  7:
+    files: [scene_final.psd]
+    not_lfs: [scene_final.psd]
+`
+	if err := os.WriteFile(filepath.Join(dir, FixtureName), []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = LoadFixture(dir)
+	if err != nil {
+		t.Fatalf("LoadFixture failed: %v", err)
+	}
+	if !reflect.DeepEqual(f.Deletions, []string{"model.onnx"}) || f.Renames["scene.psd"] != "scene_final.psd" {
+		t.Errorf("Fixture = %+v, want the declared deletions and renames", f)
+	}
+
+	// A declared state is used as is; other steps are still derived
+	v1 := []string{"model.onnx", "scene.psd", "texture.png"}
+	if got := f.State(7, v1, nil); !reflect.DeepEqual(got.NotLFS, []string{"scene_final.psd"}) {
+		t.Errorf("Step 7 NotLFS = %v, want the declared list", got.NotLFS)
+	}
+	if got := f.State(3, v1, nil); !reflect.DeepEqual(got.Files, []string{"scene_final.psd", "texture.png"}) {
+		t.Errorf("Step 3 files = %v, want derived list", got.Files)
+	}
+}
+
+func TestCheckFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "keep.bin"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(dir, "deleted.bin"), []byte("x"), 0644)
+
+	state := &ExpectedState{Files: []string{"keep.bin"}, Absent: []string{"gone.bin"}}
+	if err := state.CheckFiles(dir); err != nil {
+		t.Errorf("CheckFiles failed: %v", err)
+	}
+
+	state = &ExpectedState{Files: []string{"keep.bin", "missing.bin"}, Absent: []string{"deleted.bin"}}
+	if err := state.CheckFiles(dir); err == nil {
+		t.Error("Expected error for missing and unexpected files")
+	}
+}
@@ -0,0 +1,53 @@
+package scenario
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/interference"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+)
+
+// interferenceInterval is how often the processes touching the work directory are sampled
+const interferenceInterval = 2 * time.Second
+
+// checkInterference looks for antivirus scanners and file indexers before the first step,
+// and measures how long small file operations take in the work directory. It returns nil
+// if the platform exposes none of it.
+func (r *Runner) checkInterference() *interference.Report {
+	if err := os.MkdirAll(r.WorkDir, 0755); err != nil {
+		return nil
+	}
+	report, err := interference.Check(r.WorkDir)
+	if err != nil {
+		log.Warnf("interference check failed: %v\n", err)
+		return nil
+	}
+	log.Debugf("Small file operations take %s in %s\n", report.FileOpTime.Round(time.Microsecond), r.WorkDir)
+	return report
+}
+
+// recordInterference records a warning verification for a step if the initial report
+// (first step only) or the monitor found something that may have slowed the step down
+func (r *Runner) recordInterference(step int, report *interference.Report, monitor *interference.Monitor) {
+	if report == nil {
+		report = &interference.Report{}
+	}
+	seen := make(map[int]bool)
+	for _, p := range report.Touching {
+		seen[p.PID] = true
+	}
+	for _, p := range monitor.Drain() {
+		if !seen[p.PID] {
+			report.Touching = append(report.Touching, p)
+		}
+	}
+
+	var err error
+	if warnings := report.Warnings(); len(warnings) > 0 {
+		err = errors.New(strings.Join(warnings, "; "))
+	}
+	r.verify(step, "interference", SeverityWarning, err)
+}
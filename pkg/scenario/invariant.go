@@ -0,0 +1,157 @@
+package scenario
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+)
+
+// changeTypes are the kinds of checksum differences (see checksum.Difference)
+var changeTypes = []string{"added", "deleted", "modified", "size-changed", "renamed"}
+
+// maxReportedDifferences is the number of unexpected differences a failed invariant names
+const maxReportedDifferences = 5
+
+// AllowedDifference is a checksum difference that the invariant checks of steps 4 and 6,
+// which require two repositories to match, expect and ignore. It is written
+// "[STEP] [CHANGE] PATTERN", e.g. "*.log", "modified build.info" or "6 added CHANGELOG.md":
+// STEP restricts it to the check of that step, CHANGE to one kind of change (added,
+// deleted, modified, size-changed or renamed), and PATTERN matches a file's path or name,
+// or one of its directories, as in checksum.Filter.
+type AllowedDifference struct {
+	Spec    string
+	Step    int    // Step whose check allows the difference; 0 for every step
+	Change  string // Kind of change; empty for any
+	Pattern string
+}
+
+// ParseAllowedDifference parses an allowed difference
+func ParseAllowedDifference(spec string) (*AllowedDifference, error) {
+	a := &AllowedDifference{Spec: strings.TrimSpace(spec)}
+	fields := strings.Fields(a.Spec)
+	if len(fields) > 1 {
+		if step, err := strconv.Atoi(fields[0]); err == nil {
+			if step < 1 {
+				return nil, fmt.Errorf("invalid allowed difference '%s': step %d", a.Spec, step)
+			}
+			a.Step = step
+			fields = fields[1:]
+		}
+	}
+	if len(fields) > 1 && slices.Contains(changeTypes, fields[0]) {
+		a.Change = fields[0]
+		fields = fields[1:]
+	}
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("invalid allowed difference '%s': use [STEP] [%s] PATTERN", a.Spec, strings.Join(changeTypes, "|"))
+	}
+	a.Pattern = fields[0]
+	if _, err := path.Match(a.Pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid allowed difference '%s': %w", a.Spec, err)
+	}
+	return a, nil
+}
+
+// ParseAllowedDifferences parses allowed differences, stopping at the first invalid one
+func ParseAllowedDifferences(specs []string) ([]*AllowedDifference, error) {
+	allowed := make([]*AllowedDifference, 0, len(specs))
+	for _, spec := range specs {
+		a, err := ParseAllowedDifference(spec)
+		if err != nil {
+			return nil, err
+		}
+		allowed = append(allowed, a)
+	}
+	return allowed, nil
+}
+
+// Allows reports whether the check of step expects the difference d. A rename is allowed
+// if the pattern matches its old or new path.
+func (a *AllowedDifference) Allows(step int, d *checksum.Difference) bool {
+	if a.Step != 0 && a.Step != step {
+		return false
+	}
+	if a.Change != "" && a.Change != d.ChangeType {
+		return false
+	}
+	filter := checksum.Filter{Include: []string{a.Pattern}}
+	return filter.Match(d.FilePath) || (d.OldPath != "" && filter.Match(d.OldPath))
+}
+
+// resolveAllowedDifferences returns the runner's allowed differences followed by those of
+// the fixture
+func (r *Runner) resolveAllowedDifferences() ([]*AllowedDifference, error) {
+	if r.allowed != nil {
+		return r.allowed, nil
+	}
+
+	fixture, err := r.resolveFixture()
+	if err != nil {
+		return nil, err
+	}
+	specs := append(append([]string(nil), r.AllowedDifferences...), fixture.AllowedDifferences...)
+	allowed, err := ParseAllowedDifferences(specs)
+	if err != nil {
+		return nil, err
+	}
+	r.allowed = allowed
+	return allowed, nil
+}
+
+// checkChecksumInvariant compares the checksums of the running step with those of step
+// source, which must match but for the allowed differences, and records the outcome as
+// the verification checksums-match
+func (r *Runner) checkChecksumInvariant(source int) error {
+	log.Debugf("Comparing checksums with step %d...\n", source)
+	diffs, err := r.compareChecksums(source)
+	if err != nil {
+		return fmt.Errorf("failed to compare checksums: %w", err)
+	}
+	allowed, err := r.resolveAllowedDifferences()
+	if err != nil {
+		return err
+	}
+
+	var unexpected []*checksum.Difference
+	for _, d := range diffs {
+		if !slices.ContainsFunc(allowed, func(a *AllowedDifference) bool { return a.Allows(r.step(), d) }) {
+			unexpected = append(unexpected, d)
+		}
+	}
+	if n := len(diffs) - len(unexpected); n > 0 {
+		log.Debugf("Ignoring %d allowed differences between step %d and step %d\n", n, source, r.step())
+	}
+
+	var mismatch error
+	if len(unexpected) > 0 {
+		mismatch = fmt.Errorf("%d differences found between step %d and step %d: %s",
+			len(unexpected), source, r.step(), describeDifferences(unexpected))
+	} else {
+		log.Debugf("%s Checksums match step %d\n", term.OK(), source)
+	}
+	return r.verify(r.step(), "checksums-match", SeverityError, mismatch)
+}
+
+// describeDifferences lists the first differences, e.g.
+// "modified video2.mov, renamed zip2.zip → zip2_renamed.zip and 3 more"
+func describeDifferences(diffs []*checksum.Difference) string {
+	var parts []string
+	for _, d := range diffs[:min(len(diffs), maxReportedDifferences)] {
+		if d.ChangeType == "renamed" {
+			parts = append(parts, fmt.Sprintf("renamed %s → %s", d.OldPath, d.FilePath))
+		} else {
+			parts = append(parts, d.ChangeType+" "+d.FilePath)
+		}
+	}
+	description := strings.Join(parts, ", ")
+	if more := len(diffs) - maxReportedDifferences; more > 0 {
+		description += fmt.Sprintf(" and %d more", more)
+	}
+	return description
+}
@@ -0,0 +1,124 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+)
+
+func TestParseAllowedDifference(t *testing.T) {
+	valid := map[string]AllowedDifference{
+		"*.log":                 {Pattern: "*.log"},
+		"modified build.info":   {Change: "modified", Pattern: "build.info"},
+		" 6 added CHANGELOG.md": {Step: 6, Change: "added", Pattern: "CHANGELOG.md"},
+		"4 docs":                {Step: 4, Pattern: "docs"},
+		"size-changed 2024":     {Change: "size-changed", Pattern: "2024"},
+		"7":                     {Pattern: "7"}, // A file named 7
+	}
+	for spec, want := range valid {
+		a, err := ParseAllowedDifference(spec)
+		if err != nil {
+			t.Errorf("ParseAllowedDifference(%q) failed: %v", spec, err)
+			continue
+		}
+		want.Spec = strings.TrimSpace(spec)
+		if *a != want {
+			t.Errorf("ParseAllowedDifference(%q) = %+v, want %+v", spec, *a, want)
+		}
+	}
+
+	for _, spec := range []string{"", "6 moved a.txt", "0 a.txt", "6 added a b", "added [a"} {
+		if _, err := ParseAllowedDifference(spec); err == nil {
+			t.Errorf("ParseAllowedDifference(%q) should fail", spec)
+		}
+	}
+}
+
+func TestAllowedDifferenceAllows(t *testing.T) {
+	modified := &checksum.Difference{FilePath: "logs/build.log", ChangeType: "modified"}
+	renamed := &checksum.Difference{FilePath: "b.zip", OldPath: "a.zip", ChangeType: "renamed"}
+
+	tests := []struct {
+		spec string
+		step int
+		diff *checksum.Difference
+		want bool
+	}{
+		{"*.log", 4, modified, true},
+		{"logs", 6, modified, true},
+		{"6 *.log", 6, modified, true},
+		{"6 *.log", 4, modified, false},
+		{"added *.log", 6, modified, false},
+		{"renamed a.zip", 6, renamed, true},
+		{"b.zip", 6, renamed, true},
+		{"*.psd", 6, renamed, false},
+	}
+	for _, tt := range tests {
+		a, err := ParseAllowedDifference(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := a.Allows(tt.step, tt.diff); got != tt.want {
+			t.Errorf("%q allows step %d %s %s = %v, want %v", tt.spec, tt.step, tt.diff.ChangeType, tt.diff.FilePath, got, tt.want)
+		}
+	}
+}
+
+func TestCheckChecksumInvariant(t *testing.T) {
+	runner := newVerifyRunner(t)
+	runner.Scenario.Fixture = &Fixture{AllowedDifferences: []string{"6 modified build.log"}}
+	runner.AllowedDifferences = []string{"added *.tmp"}
+
+	store := func(step int, files map[string]uint32) {
+		var checksums []*checksum.FileChecksum
+		for path, crc := range files {
+			checksums = append(checksums, &checksum.FileChecksum{Path: path, CRC32: crc, SizeBytes: 10})
+		}
+		if err := checksum.StoreChecksums(runner.DB, runner.RunID, step, checksums, checksum.ConflictReplace); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store(5, map[string]uint32{"a.pdf": 1, "build.log": 2})
+	store(6, map[string]uint32{"a.pdf": 1, "build.log": 3, "x.tmp": 4})
+
+	// Every difference is allowed
+	runner.currentStep.Store(6)
+	if err := runner.checkChecksumInvariant(5); err != nil {
+		t.Errorf("checkChecksumInvariant with allowed differences failed: %v", err)
+	}
+
+	// The same differences are unexpected in step 7, except the added file
+	store(7, map[string]uint32{"a.pdf": 5, "build.log": 3, "x.tmp": 4})
+	runner.currentStep.Store(7)
+	err := runner.checkChecksumInvariant(5)
+	if err == nil || !strings.Contains(err.Error(), "2 differences found between step 5 and step 7") {
+		t.Fatalf("checkChecksumInvariant = %v, want 2 differences", err)
+	}
+	for _, s := range []string{"modified a.pdf", "modified build.log"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error %q does not name %q", err, s)
+		}
+	}
+
+	verifications, err := runner.DB.ListVerifications(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListVerifications failed: %v", err)
+	}
+	if len(verifications) != 2 || verifications[0].Status != "passed" || verifications[1].Status != "failed" ||
+		verifications[1].Name != "checksums-match" {
+		t.Errorf("verifications = %+v, want a passed and a failed checksums-match", verifications)
+	}
+}
+
+func TestDescribeDifferences(t *testing.T) {
+	var diffs []*checksum.Difference
+	diffs = append(diffs, &checksum.Difference{FilePath: "b.zip", OldPath: "a.zip", ChangeType: "renamed"})
+	for _, name := range []string{"c", "d", "e", "f", "g", "h"} {
+		diffs = append(diffs, &checksum.Difference{FilePath: name, ChangeType: "deleted"})
+	}
+	want := "renamed a.zip → b.zip, deleted c, deleted d, deleted e, deleted f and 2 more"
+	if got := describeDifferences(diffs); got != want {
+		t.Errorf("describeDifferences = %q, want %q", got, want)
+	}
+}
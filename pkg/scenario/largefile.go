@@ -0,0 +1,195 @@
+package scenario
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/git"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+)
+
+// LargeFileStep is the step number of the large file upload test in DefaultPipeline,
+// which runs when Runner.LargeFileSize is set
+const LargeFileStep = 10
+
+// DefaultLargeFileInterrupt is how long the first upload of the large file runs before it
+// is stopped
+const DefaultLargeFileInterrupt = 10 * time.Second
+
+// chunkedAdapters are the transfer adapters that upload an object in several requests, so
+// an interrupted upload can continue where it stopped
+var chunkedAdapters = []string{"multipart-basic", "tus"}
+
+// resumedFraction is the share of the large file below which the push after the
+// interruption counts as resuming the upload rather than starting it again
+const resumedFraction = 0.9
+
+// largeFileName returns the name of the large file; like the concurrent clients' files, it
+// has the extension of a tracked pattern if there is one
+func (r *Runner) largeFileName() string {
+	ext, _ := r.clientFileExt()
+	return "large" + ext
+}
+
+// Step10_LargeFile: Push one very large sparse file from the second clone, interrupt the
+// upload, and push again to complete it
+func (r *Runner) Step10_LargeFile() error {
+	ctx := &git.Context{
+		DB:         r.DB,
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		Debug:      r.Debug,
+		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
+	}
+
+	if err := r.configureUser(ctx, r.Repo2Dir); err != nil {
+		return err
+	}
+	// Start from what the server has, which the concurrent clients may have added to
+	if err := ctx.Pull(r.Repo2Dir); err != nil {
+		return err
+	}
+	branch, err := ctx.CurrentBranch(r.Repo2Dir)
+	if err != nil {
+		return err
+	}
+
+	name := r.largeFileName()
+	if _, tracked := r.clientFileExt(); !tracked {
+		if err := trackLocally(r.Repo2Dir, name); err != nil {
+			return err
+		}
+	}
+	log.Debugf("Writing the %s sparse file %s...\n", testdata.FormatSize(r.LargeFileSize), name)
+	if err := testdata.WriteSparseFile(filepath.Join(r.Repo2Dir, name), r.LargeFileSize, r.RunID); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := ctx.Add(r.Repo2Dir, name); err != nil {
+		return err
+	}
+	if err := ctx.Commit(r.Repo2Dir, "Add "+name); err != nil {
+		return err
+	}
+
+	result := &database.LargeFileResult{
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		Path:       name,
+		SizeBytes:  r.LargeFileSize,
+		Status:     "completed",
+	}
+	uploadErr := r.uploadLargeFile(ctx, result, branch)
+	if uploadErr != nil {
+		result.Status = "failed"
+		result.Message = uploadErr.Error()
+	}
+	result.CheckedAt = time.Now()
+	if err := r.DB.CreateLargeFileResult(result); err != nil {
+		return err
+	}
+	if uploadErr != nil {
+		return uploadErr
+	}
+
+	r.checkLargeFile(result)
+
+	// The server must hold the whole file, whatever became of the interrupted upload
+	return r.verifyHistoryObjects(r.step(), r.Repo2Dir, true)
+}
+
+// trackLocally stores name in LFS in repoDir only, when no tracked pattern covers it
+func trackLocally(repoDir, name string) error {
+	path := filepath.Join(repoDir, ".git", "info", "attributes")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to track %s: %w", name, err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "%s filter=lfs diff=lfs merge=lfs -text\n", name); err != nil {
+		return fmt.Errorf("failed to track %s: %w", name, err)
+	}
+	return f.Close()
+}
+
+// uploadLargeFile stops the first upload of the large file after LargeFileInterrupt, then
+// pushes to complete it, measuring what the second push sent
+func (r *Runner) uploadLargeFile(ctx *git.Context, result *database.LargeFileResult, branch string) error {
+	interrupt := r.LargeFileInterrupt
+	if interrupt <= 0 {
+		interrupt = DefaultLargeFileInterrupt
+	}
+
+	log.Debugf("Uploading %s, interrupted after %s...\n", result.Path, interrupt)
+	start := time.Now()
+	interrupted, err := ctx.LFSPush(r.Repo2Dir, "origin", branch, interrupt)
+	if err != nil {
+		return err
+	}
+	result.Interrupted = interrupted
+	result.FirstMs = time.Since(start).Milliseconds()
+
+	before, sampled := r.sampleNetwork()
+	start = time.Now()
+	if err := ctx.Push(r.Repo2Dir, "origin", branch); err != nil {
+		return err
+	}
+	result.ResumeMs = time.Since(start).Milliseconds()
+
+	// Traffic on the network interface only tells about servers reached over the network
+	if sampled && interrupted && r.Scenario.Protocol != "local" {
+		if after, ok := r.sampleNetwork(); ok {
+			sent := int64(after.Sub(before).TxBytes)
+			resumed := float64(sent) < resumedFraction*float64(result.SizeBytes)
+			result.ResumeSentBytes = &sent
+			result.Resumed = &resumed
+		}
+	}
+
+	ops, err := r.DB.ListOperations(r.RunID)
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		if op.StepNumber == r.step() && op.Operation == "push" {
+			result.Adapter = op.TransferAdapter
+		}
+	}
+
+	log.Debugf("  %s Uploaded %s in %dms after %dms\n", term.OK(), testdata.FormatSize(result.SizeBytes), result.ResumeMs, result.FirstMs)
+	return nil
+}
+
+// checkLargeFile records as warnings whether the server took the large file in parts and
+// resumed the interrupted upload; servers that do neither are a finding, not a failure
+func (r *Runner) checkLargeFile(result *database.LargeFileResult) {
+	size := testdata.FormatSize(result.SizeBytes)
+
+	if result.Adapter != "" {
+		var whole error
+		if !slices.Contains(chunkedAdapters, result.Adapter) {
+			whole = fmt.Errorf("%s took the %s file with the %s transfer adapter, in one request", r.Scenario.ServerType, size, result.Adapter)
+		}
+		r.verify(result.StepNumber, "large-file-chunked", SeverityWarning, whole)
+	}
+
+	var restarted error
+	switch {
+	case !result.Interrupted:
+		restarted = fmt.Errorf("the upload of the %s file finished in %dms, before it could be interrupted; "+
+			"use a larger file or a shorter interruption", size, result.FirstMs)
+	case result.Resumed == nil:
+		log.Debugf("Whether the upload resumed is unknown without network counters\n")
+		return
+	case !*result.Resumed:
+		restarted = fmt.Errorf("the push after the interruption sent %s of the %s file again: the upload started over",
+			testdata.FormatSize(*result.ResumeSentBytes), size)
+	}
+	r.verify(result.StepNumber, "large-file-resumed", SeverityWarning, restarted)
+}
@@ -0,0 +1,39 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestCheckLargeFile(t *testing.T) {
+	runner := newVerifyRunner(t)
+	sent := int64(100 << 20)
+	resumed := false
+	size := int64(1 << 30)
+
+	runner.checkLargeFile(&database.LargeFileResult{StepNumber: 1, SizeBytes: size, Adapter: "multipart-basic", Interrupted: true})
+	runner.checkLargeFile(&database.LargeFileResult{StepNumber: 2, SizeBytes: size, Adapter: "basic", FirstMs: 900})
+	runner.checkLargeFile(&database.LargeFileResult{StepNumber: 3, SizeBytes: size, Interrupted: true, ResumeSentBytes: &sent, Resumed: &resumed})
+
+	verifications, err := runner.DB.ListVerifications(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListVerifications failed: %v", err)
+	}
+	if len(verifications) != 4 {
+		t.Fatalf("verifications = %+v, want 4", verifications)
+	}
+	if v := verifications[0]; v.Name != "large-file-chunked" || v.Status != "passed" {
+		t.Errorf("multipart upload verification = %+v", v)
+	}
+	if v := verifications[1]; v.Name != "large-file-chunked" || v.Status != "failed" || v.Severity != SeverityWarning {
+		t.Errorf("basic upload verification = %+v", v)
+	}
+	if v := verifications[2]; v.Name != "large-file-resumed" || v.Status != "failed" || !strings.Contains(v.Message, "before it could be interrupted") {
+		t.Errorf("uninterrupted upload verification = %+v", v)
+	}
+	if v := verifications[3]; v.Name != "large-file-resumed" || v.Status != "failed" || !strings.Contains(v.Message, "started over") {
+		t.Errorf("restarted upload verification = %+v", v)
+	}
+}
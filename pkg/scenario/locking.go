@@ -0,0 +1,153 @@
+package scenario
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/git"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+)
+
+// LockingStep is the step number of the file locking API test in DefaultPipeline,
+// which runs when Runner.Locking is set
+const LockingStep = 9
+
+// unsupportedMessages identify servers (or remotes) without the LFS locking API
+var unsupportedMessages = []string{
+	"not support",
+	"not implemented",
+	"404",
+	"405",
+	"501",
+}
+
+// lockingUnsupported reports whether a failed lock means the server has no locking API
+func lockingUnsupported(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, m := range unsupportedMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLock reports whether locks include one on path
+func hasLock(locks []git.Lock, path string) bool {
+	for _, lock := range locks {
+		if lock.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Step9_Locking: Lock a file from the second clone and check the lock from the first
+func (r *Runner) Step9_Locking() error {
+	ctx := &git.Context{
+		DB:         r.DB,
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		Debug:      r.Debug,
+		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
+	}
+
+	result := &database.LockResult{
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		ServerType: r.Scenario.ServerType,
+		Protocol:   r.Scenario.Protocol,
+		CheckedAt:  time.Now(),
+	}
+	lockErr := r.exerciseLocks(ctx, result)
+	if lockErr != nil {
+		result.Message = lockErr.Error()
+	}
+	if err := r.DB.CreateLockResult(result); err != nil {
+		return err
+	}
+	if lockErr != nil {
+		return lockErr
+	}
+
+	if !result.Supported {
+		// Not every server implements locking; that is a finding, not a failure
+		r.verify(r.step(), "locking-api", SeverityWarning, fmt.Errorf("%s does not support the LFS locking API", r.Scenario.ServerType))
+		return nil
+	}
+
+	checks := []struct {
+		name string
+		ok   bool
+		msg  string
+	}{
+		{"lock-visible", result.Visible, "the first clone does not list the second clone's lock on " + result.Path},
+		{"lock-enforced", result.Enforced, "the first clone could lock " + result.Path + " while the second clone held the lock"},
+		{"lock-released", result.Released, "the lock on " + result.Path + " remained after unlocking"},
+	}
+	for _, check := range checks {
+		var err error
+		if !check.ok {
+			err = fmt.Errorf("%s", check.msg)
+		}
+		if err := r.verify(r.step(), check.name, SeverityError, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exerciseLocks locks a file in the second clone, checks from the first clone that the lock is
+// listed and enforced, and unlocks it again, filling in result. A server without the locking
+// API leaves result.Supported false without an error.
+func (r *Runner) exerciseLocks(ctx *git.Context, result *database.LockResult) error {
+	state, err := r.pipelineState(5)
+	if err != nil {
+		return err
+	}
+	if len(state.LFS) == 0 {
+		return fmt.Errorf("no LFS-tracked file to lock")
+	}
+	result.Path = state.LFS[0]
+
+	log.Debugf("Locking %s from the second clone...\n", result.Path)
+	if err := ctx.LFSLock(r.Repo2Dir, result.Path); err != nil {
+		if lockingUnsupported(err) {
+			result.Message = err.Error()
+			return nil
+		}
+		return err
+	}
+	result.Supported = true
+
+	locks, err := ctx.LFSLocks(r.RepoDir)
+	if err == nil {
+		result.Visible = hasLock(locks, result.Path)
+
+		// Taking the same lock from the other clone must fail
+		if ctx.LFSLock(r.RepoDir, result.Path) != nil {
+			result.Enforced = true
+		} else {
+			log.Warnf("the first clone also locked %s\n", result.Path)
+		}
+	}
+
+	// Always release the lock, so the server is left as it was found
+	if unlockErr := ctx.LFSUnlock(r.Repo2Dir, result.Path, !result.Enforced); unlockErr != nil {
+		return unlockErr
+	}
+	if err != nil {
+		return err
+	}
+
+	locks, err = ctx.LFSLocks(r.RepoDir)
+	if err != nil {
+		return err
+	}
+	result.Released = !hasLock(locks, result.Path)
+	return nil
+}
@@ -0,0 +1,47 @@
+package scenario
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/git"
+)
+
+func TestLockingUnsupported(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{`git lfs lock failed (exit 2): Remote "origin" does not support the Git LFS locking API`, true},
+		{"git lfs lock failed (exit 2): api: http error 404: Not Found", true},
+		{"git lfs lock failed (exit 2): lock exists", false},
+		{"git lfs lock failed (exit 2): Authentication required", false},
+	}
+
+	for _, tt := range tests {
+		if got := lockingUnsupported(errors.New(tt.msg)); got != tt.want {
+			t.Errorf("lockingUnsupported(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestHasLock(t *testing.T) {
+	locks := []git.Lock{{ID: "1", Path: "pdf1.pdf"}, {ID: "2", Path: "video2.mov"}}
+	if !hasLock(locks, "video2.mov") {
+		t.Error("hasLock should find video2.mov")
+	}
+	if hasLock(locks, "zip1.zip") || hasLock(nil, "pdf1.pdf") {
+		t.Error("hasLock found a lock that is not held")
+	}
+}
+
+func TestOptionalStepsKeepTheirNumbers(t *testing.T) {
+	r := &Runner{Locking: true}
+	steps := r.steps()
+	if len(steps) != CadenceStep || steps[LockingStep-1] == nil || steps[ConcurrentStep-1] != nil || steps[LargeFileStep-1] != nil {
+		t.Errorf("with only Locking set, step %d should run and step %d should not", LockingStep, ConcurrentStep)
+	}
+	if r.stepCount() != 8 {
+		t.Errorf("stepCount() = %d, want 8", r.stepCount())
+	}
+}
@@ -0,0 +1,78 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// RunMetadataName is the file in each test repository that ties it to its run in the database
+const RunMetadataName = "lfst-run.json"
+
+// RunMetadata is committed to the test repository as RunMetadataName, so anyone examining the
+// repository or its remote later can trace it back to the evaluation run that produced it
+type RunMetadata struct {
+	RunID       int64     `json:"run_id"`
+	Tool        string    `json:"tool"`
+	ToolVersion string    `json:"tool_version"`
+	Host        string    `json:"host,omitempty"`
+	TestData    string    `json:"test_data,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	WrittenAt   time.Time `json:"written_at"`
+	Scenario    *Scenario `json:"scenario"`
+	// LFS API endpoint the run used, when known: the configured server or the git host's own
+	LFSURL string `json:"lfs_url,omitempty"`
+	// Limits the git commands ran under, so a limited-resources run can be repeated
+	Limits *timing.Limits `json:"limits,omitempty"`
+	// Network conditions the transfer steps were shaped to, e.g. "rtt=50ms,rate=100mbit"
+	Network string `json:"network,omitempty"`
+	// Transfer adapter the run forced git-lfs to use, e.g. "basic"
+	TransferAdapter string `json:"transfer_adapter,omitempty"`
+}
+
+// writeRunMetadata writes RunMetadataName into the first repository; step 2 commits and pushes it
+func (r *Runner) writeRunMetadata() error {
+	run, err := r.DB.GetTestRun(r.RunID)
+	if err != nil {
+		return err
+	}
+
+	meta := &RunMetadata{
+		RunID:       r.RunID,
+		Tool:        "lfst-scenario",
+		ToolVersion: r.Version,
+		StartedAt:   run.StartedAt,
+		WrittenAt:   time.Now().UTC().Truncate(time.Second),
+		Scenario:    r.Scenario,
+		LFSURL:      r.lfsEndpoint(),
+		Network:     run.NetworkProfile,
+	}
+	if r.TransferAdapter != "" {
+		meta.TransferAdapter = r.expectedAdapter()
+	}
+	if !r.Limits.IsZero() {
+		meta.Limits = r.Limits
+	}
+	if meta.ToolVersion == "" {
+		meta.ToolVersion = "dev"
+	}
+	meta.Host, _ = os.Hostname()
+	meta.TestData, _ = r.testDataPath()
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(r.RepoDir, RunMetadataName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", RunMetadataName, err)
+	}
+
+	log.Debugf("  %s Wrote %s\n", term.OK(), RunMetadataName)
+	return nil
+}
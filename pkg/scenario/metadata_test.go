@@ -0,0 +1,50 @@
+package scenario
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteRunMetadata(t *testing.T) {
+	runner := newVerifyRunner(t)
+	runner.Scenario = &Scenario{ID: 6, Name: "LFS Test Server - HTTP", ServerType: "lfs-test-server", Protocol: "http", GitServer: "bare", ServerURL: "http://gojira:8079"}
+	runner.Version = "v1.2.3"
+	runner.TestDataPath = "/data/lfs"
+	if err := os.MkdirAll(runner.RepoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runner.writeRunMetadata(); err != nil {
+		t.Fatalf("writeRunMetadata failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(runner.RepoDir, RunMetadataName))
+	if err != nil {
+		t.Fatalf("%s not written: %v", RunMetadataName, err)
+	}
+
+	var meta struct {
+		RunID       int64  `json:"run_id"`
+		ToolVersion string `json:"tool_version"`
+		TestData    string `json:"test_data"`
+		StartedAt   string `json:"started_at"`
+		Scenario    struct {
+			ID        int    `json:"id"`
+			ServerURL string `json:"server_url"`
+		} `json:"scenario"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+	if meta.RunID != runner.RunID || meta.ToolVersion != "v1.2.3" || meta.TestData != "/data/lfs" {
+		t.Errorf("metadata = %+v", meta)
+	}
+	if meta.Scenario.ID != 6 || meta.Scenario.ServerURL != "http://gojira:8079" {
+		t.Errorf("scenario = %+v", meta.Scenario)
+	}
+	if meta.StartedAt == "" {
+		t.Error("started_at should come from the test run")
+	}
+}
@@ -0,0 +1,91 @@
+package scenario
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/netem"
+	"github.com/mslinn/git-lfs-test/pkg/netstat"
+)
+
+// newShaper returns the shaper of the run's Network profile, or nil if the run does not
+// shape the network. It shapes Interface if set; otherwise the loopback interface when
+// the LFS server runs on this machine, and the default route's interface when it does not.
+func (r *Runner) newShaper() (*netem.Shaper, error) {
+	if r.Network == nil {
+		return nil, nil
+	}
+
+	iface := r.Interface
+	if iface == "" && r.serverIsLocal() {
+		iface = "lo"
+	}
+	if iface == "" {
+		var err error
+		if iface, err = netstat.DefaultInterface(); err != nil {
+			return nil, fmt.Errorf("cannot choose the interface to shape (use --interface): %w", err)
+		}
+	}
+	return netem.NewShaper(iface, r.Network), nil
+}
+
+// serverIsLocal reports whether the scenario's LFS server is on a loopback address
+func (r *Runner) serverIsLocal() bool {
+	u, err := url.Parse(r.Scenario.ServerURL)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	if u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
+
+// useNetworkProfile makes a resumed or rerun run shape the network as it was shaped
+// when the run started
+func (r *Runner) useNetworkProfile(run *database.TestRun) error {
+	if run.NetworkProfile == "" {
+		r.Network = nil
+		return nil
+	}
+	profile, err := netem.Parse(run.NetworkProfile)
+	if err != nil {
+		return fmt.Errorf("run %d has an invalid network profile: %w", run.ID, err)
+	}
+	r.Network = profile
+	return nil
+}
+
+// checkShaper reports why the run's network shaping cannot be applied
+func (r *Runner) checkShaper() error {
+	shaper, err := r.newShaper()
+	if err != nil || shaper == nil {
+		return err
+	}
+	if err := shaper.CheckTools(); err != nil {
+		return err
+	}
+	r.shaper = shaper
+	log.Debugf("  Transfers are shaped to %s on %s\n", r.Network, shaper.Interface)
+	return nil
+}
+
+// shape applies the network shaping for a step that transfers data, and returns the
+// function that removes it again; steps that do not transfer data run unshaped
+func (r *Runner) shape(step *Step) (func(), error) {
+	if r.shaper == nil || !step.Transfers {
+		return func() {}, nil
+	}
+	if err := r.shaper.Apply(); err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := r.shaper.Remove(); err != nil {
+			log.Warnf("%v\n", err)
+		}
+	}, nil
+}
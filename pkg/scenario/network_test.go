@@ -0,0 +1,56 @@
+package scenario
+
+import (
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+func TestNewShaper(t *testing.T) {
+	r := &Runner{Scenario: &Scenario{ServerURL: "http://localhost:8079"}}
+	if shaper, err := r.newShaper(); shaper != nil || err != nil {
+		t.Errorf("newShaper without a profile = %v, %v; want nil", shaper, err)
+	}
+
+	if err := r.useNetworkProfile(&database.TestRun{NetworkProfile: "rtt=50ms,rate=100mbit"}); err != nil {
+		t.Fatalf("useNetworkProfile failed: %v", err)
+	}
+	shaper, err := r.newShaper()
+	if err != nil || shaper.Interface != "lo" {
+		t.Errorf("newShaper of a local server = %+v, %v; want lo", shaper, err)
+	}
+
+	r.Scenario.ServerURL = "http://[::1]:8079"
+	if !r.serverIsLocal() {
+		t.Error("::1 should be local")
+	}
+	r.Scenario.ServerURL = "http://gojira:8079"
+	if r.serverIsLocal() {
+		t.Error("gojira should not be local")
+	}
+	r.Interface = "eth1"
+	if shaper, _ := r.newShaper(); shaper.Interface != "eth1" {
+		t.Errorf("newShaper with --interface shapes %s, want eth1", shaper.Interface)
+	}
+
+	// Runs recorded without shaping resume without it
+	if err := r.useNetworkProfile(&database.TestRun{}); err != nil || r.Network != nil {
+		t.Errorf("useNetworkProfile of an unshaped run: Network = %v, err = %v", r.Network, err)
+	}
+	if err := r.useNetworkProfile(&database.TestRun{ID: 3, NetworkProfile: "rtt=fast"}); err == nil {
+		t.Error("useNetworkProfile should reject an invalid profile")
+	}
+}
+
+func TestShapeSkipsLocalSteps(t *testing.T) {
+	r := &Runner{Scenario: &Scenario{}}
+	unshape, err := r.shape(LookupStep("push"))
+	if err != nil {
+		t.Fatalf("shape without a shaper failed: %v", err)
+	}
+	unshape()
+
+	if !LookupStep("pull").Transfers || LookupStep("modify").Transfers {
+		t.Error("pull should transfer data and modify should not")
+	}
+}
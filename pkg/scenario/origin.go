@@ -0,0 +1,152 @@
+package scenario
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/git"
+	"github.com/mslinn/git-lfs-test/pkg/gitserver"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+)
+
+// ServerRepoName is the name of the bare repository in the run's directory that is the
+// origin of scenarios with a bare git server
+const ServerRepoName = "server.git"
+
+// gitServerMode returns how the scenario's clients reach the bare repository: over SSH
+// for SSH scenarios, through git daemon with GitDaemon, and otherwise as a path
+func (r *Runner) gitServerMode() string {
+	switch {
+	case r.Scenario.Protocol == "ssh":
+		return gitserver.ModeSSH
+	case r.GitDaemon:
+		return gitserver.ModeDaemon
+	default:
+		return gitserver.ModePath
+	}
+}
+
+// newGitServer returns the bare repository of the run: on SSHGitHost for SSH scenarios
+// when one is configured, and otherwise in the run's directory
+func (r *Runner) newGitServer() (*gitserver.Server, error) {
+	if r.Scenario.Protocol == "ssh" && r.SSHGitHost != "" {
+		dir := r.SSHGitDir
+		if dir == "" {
+			dir = gitserver.DefaultRemoteDir
+		}
+		return gitserver.NewRemote(r.SSHGitHost, path.Join(dir, filepath.Base(r.RunDir), ServerRepoName))
+	}
+	return gitserver.New(filepath.Join(r.RunDir, ServerRepoName), r.gitServerMode())
+}
+
+// startGitServer exposes the bare repository of the run for the duration of its steps,
+// after checking that clients can reach it and store LFS objects with it. The returned
+// function stops it again.
+func (r *Runner) startGitServer() (func(), error) {
+	server, err := r.newGitServer()
+	if err != nil {
+		return nil, err
+	}
+	if err := server.Start(); err != nil {
+		return nil, err
+	}
+	if r.Scenario.ServerURL == "" {
+		if err := server.CheckLFS(); err != nil {
+			server.Stop()
+			return nil, err
+		}
+	}
+	r.gitServer = server
+	log.Debugf("Serving %s as %s\n", server.Dir, server.URL())
+
+	// Without an LFS server of its own, git-lfs stores objects next to the bare repository
+	settings := make(map[string]string)
+	if url := server.LFSURL(); url != "" && r.Scenario.ServerURL == "" {
+		settings["lfs.url"] = url
+	}
+	if command := server.SSHCommand(); command != "" {
+		settings["core.sshCommand"] = command
+	}
+	git.ExportConfig(settings)
+
+	return func() {
+		keys := make([]string, 0, len(settings))
+		for key := range settings {
+			keys = append(keys, key)
+		}
+		git.UnexportConfig(keys...)
+		if err := server.Stop(); err != nil {
+			log.Warnf("%v\n", err)
+		}
+		r.gitServer = nil
+	}, nil
+}
+
+// gitServerUnavailable records on the run that its bare repository cannot be reached,
+// as a failed 'git-server' verification of step 1 that holds the whole explanation
+func (r *Runner) gitServerUnavailable(run *database.TestRun, err error) error {
+	r.verify(1, "git-server", SeverityError, err)
+
+	// The first line says what is wrong; the rest of the message says what to do about it
+	summary, _, _ := strings.Cut(err.Error(), "\n")
+	completedAt := time.Now()
+	run.Status = "failed"
+	run.CompletedAt = &completedAt
+	run.Notes += fmt.Sprintf(" | Git server unavailable before step 1: %s", summary)
+	if dbErr := r.DB.UpdateTestRun(run); dbErr != nil {
+		log.Warnf("failed to update test run: %v\n", dbErr)
+	}
+	return fmt.Errorf("git server unavailable: %w", err)
+}
+
+// createOrigin creates the bare repository and makes it the origin of repoDir
+func (r *Runner) createOrigin(ctx *git.Context, repoDir string) error {
+	branch, err := ctx.CurrentBranch(repoDir)
+	if err != nil {
+		return err
+	}
+	log.Debugf("Creating bare repository %s...\n", r.gitServer.Dir)
+	if err := r.gitServer.Create(branch); err != nil {
+		return fmt.Errorf("failed to create the bare repository: %w", err)
+	}
+	if err := ctx.AddRemote(repoDir, "origin", r.gitServer.URL()); err != nil {
+		return fmt.Errorf("failed to add remote: %w", err)
+	}
+	return nil
+}
+
+// trackOrigin makes the current branch of repoDir track the same branch of origin, so the
+// first push needs no -u and later pulls know where to pull from
+func (r *Runner) trackOrigin(ctx *git.Context, repoDir string) error {
+	branch, err := ctx.CurrentBranch(repoDir)
+	if err != nil {
+		return err
+	}
+	if err := ctx.SetConfig(repoDir, "branch."+branch+".remote", "origin"); err != nil {
+		return err
+	}
+	return ctx.SetConfig(repoDir, "branch."+branch+".merge", "refs/heads/"+branch)
+}
+
+// hasOrigin reports whether the repositories of the run have a remote to push to and
+// pull from: the bare repository, or the repository created on the git host
+func (r *Runner) hasOrigin() bool {
+	return r.Scenario.UsesBareGitServer() || r.Scenario.UsesHostedGit()
+}
+
+// pushOrigin pushes the current branch of repoDir to origin, if the run has one
+func (r *Runner) pushOrigin(ctx *git.Context, repoDir string) error {
+	if !r.hasOrigin() {
+		return nil
+	}
+	branch, err := ctx.CurrentBranch(repoDir)
+	if err != nil {
+		return err
+	}
+	log.Debugf("Pushing %s to origin...\n", branch)
+	return ctx.Push(repoDir, "origin", branch)
+}
@@ -0,0 +1,70 @@
+package scenario
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNewGitServer(t *testing.T) {
+	runner := newVerifyRunner(t)
+	runner.RunDir = RunDir(runner.WorkDir, 7)
+	runner.Scenario.Protocol = "ssh"
+
+	server, err := runner.newGitServer()
+	if err != nil {
+		t.Fatalf("newGitServer failed: %v", err)
+	}
+	if server.Remote() || !strings.HasPrefix(server.Dir, runner.RunDir) {
+		t.Errorf("without SSHGitHost the repository should be in the run's directory: %+v", server)
+	}
+
+	runner.SSHGitHost = "gojira"
+	if server, err = runner.newGitServer(); err != nil {
+		t.Fatalf("newGitServer failed: %v", err)
+	}
+	if got, want := server.URL(), "ssh://gojira/tmp/lfst-git/run-7/server.git"; got != want {
+		t.Errorf("URL = %s, want %s", got, want)
+	}
+	runner.SSHGitDir = "/srv/git"
+	if server, _ = runner.newGitServer(); server.Dir != "/srv/git/run-7/server.git" {
+		t.Errorf("Dir = %s, want it under SSHGitDir", server.Dir)
+	}
+
+	// Local scenarios never use the SSH host
+	runner.Scenario.Protocol = "local"
+	if server, _ = runner.newGitServer(); server.Remote() {
+		t.Errorf("local scenario got a remote repository: %+v", server)
+	}
+}
+
+func TestGitServerUnavailable(t *testing.T) {
+	runner := newVerifyRunner(t)
+	runner.RunDir = RunDir(runner.WorkDir, runner.RunID)
+	runner.Scenario.Protocol = "ssh"
+	runner.SSHGitHost = "lfst-test.invalid" // Reserved, never resolves
+
+	run, err := runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if err := runner.runSteps(context.Background(), run, nil); err == nil || !strings.Contains(err.Error(), "git server unavailable") {
+		t.Fatalf("runSteps error = %v, want the git server to be unavailable", err)
+	}
+
+	run, err = runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if run.Status != "failed" || run.CompletedAt == nil || !strings.Contains(run.Notes, "Git server unavailable before step 1") {
+		t.Errorf("run = %s, %v, %q; want failed before step 1", run.Status, run.CompletedAt, run.Notes)
+	}
+	verifications, err := runner.DB.ListVerifications(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListVerifications failed: %v", err)
+	}
+	if len(verifications) != 1 || verifications[0].Name != "git-server" || verifications[0].Status != "failed" ||
+		!strings.Contains(verifications[0].Message, "lfst-test.invalid") {
+		t.Errorf("verifications = %+v", verifications)
+	}
+}
@@ -0,0 +1,346 @@
+package scenario
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/git"
+	"github.com/mslinn/git-lfs-test/pkg/lfsverify"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+)
+
+// Step is a named scenario step. A pipeline lists steps by name; each one is numbered
+// by its position in the pipeline, and records its results under that number.
+type Step struct {
+	Name        string
+	Description string
+	// Requires lists steps that must come earlier in a pipeline, e.g. a clone needs a push
+	Requires []string
+	// Enabled reports whether the step runs with the runner's options; a disabled step
+	// keeps its number but is skipped. nil means always enabled.
+	Enabled func(r *Runner) bool
+	// Transfers marks a step that moves data to or from the servers, which Runner.Network shapes
+	Transfers bool
+	Run       func(r *Runner) error
+}
+
+// registry holds every step a pipeline can use, by name
+var registry = make(map[string]*Step)
+
+// RegisterStep makes a step available to pipelines, replacing any step with the same name
+func RegisterStep(step *Step) {
+	registry[step.Name] = step
+}
+
+// LookupStep returns the registered step with the given name, or nil
+func LookupStep(name string) *Step {
+	return registry[name]
+}
+
+// RegisteredSteps returns every registered step, sorted by name
+func RegisteredSteps() []*Step {
+	steps := make([]*Step, 0, len(registry))
+	for _, step := range registry {
+		steps = append(steps, step)
+	}
+	sort.Slice(steps, func(i, j int) bool { return steps[i].Name < steps[j].Name })
+	return steps
+}
+
+// DefaultPipeline is the standard sequence of steps; the optional concurrent, locking, large
+// file and cadence steps keep their numbers (ConcurrentStep, LockingStep, LargeFileStep and
+// CadenceStep) whether or not they run
+var DefaultPipeline = []string{
+	"setup", "push", "modify", "clone", "client2-push", "pull", "untrack", "concurrent", "locking", "large-file",
+	"cadence",
+}
+
+// ParsePipeline resolves a pipeline declaration into its steps in order. Each entry is a
+// step name, optionally followed by *N to run it N times in a row, e.g. "churn*10".
+// Every step must come after the steps it requires.
+func ParsePipeline(entries []string) ([]*Step, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("pipeline has no steps")
+	}
+
+	var steps []*Step
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name, repeat := strings.TrimSpace(entry), 1
+		if i := strings.LastIndex(name, "*"); i >= 0 {
+			n, err := strconv.Atoi(strings.TrimSpace(name[i+1:]))
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid repeat count in pipeline entry '%s'", entry)
+			}
+			name, repeat = strings.TrimSpace(name[:i]), n
+		}
+
+		step := LookupStep(name)
+		if step == nil {
+			return nil, fmt.Errorf("unknown pipeline step '%s' (available: %s)", name, strings.Join(stepNames(), ", "))
+		}
+		for _, required := range step.Requires {
+			if !seen[required] {
+				return nil, fmt.Errorf("pipeline step '%s' must come after '%s'", name, required)
+			}
+		}
+
+		for range repeat {
+			steps = append(steps, step)
+		}
+		seen[name] = true
+	}
+	return steps, nil
+}
+
+// stepNames returns the names of the registered steps
+func stepNames() []string {
+	var names []string
+	for _, step := range RegisteredSteps() {
+		names = append(names, step.Name)
+	}
+	return names
+}
+
+// pipeline returns the steps the runner executes; step N is at index N-1
+func (r *Runner) pipeline() ([]*Step, error) {
+	if len(r.Pipeline) == 0 {
+		return ParsePipeline(DefaultPipeline)
+	}
+	return ParsePipeline(r.Pipeline)
+}
+
+// step returns the number of the running step
+func (r *Runner) step() int {
+	return int(r.currentStep.Load())
+}
+
+// lastStep returns the number of the latest step before the running one with one of the
+// given names, or 0 if the pipeline has none
+func (r *Runner) lastStep(names ...string) int {
+	steps, err := r.pipeline()
+	if err != nil {
+		return 0
+	}
+	for i := r.step() - 1; i >= 1; i-- {
+		for _, name := range names {
+			if steps[i-1].Name == name {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+func init() {
+	for _, step := range []*Step{
+		{
+			Name:        "setup",
+			Description: "Create the repository, configure LFS tracking and copy the v1 test files",
+			Run:         (*Runner).Step1_Setup,
+		},
+		{
+			Name:        "push",
+			Description: "Add, commit and push the files, then verify LFS storage",
+			Requires:    []string{"setup"},
+			Transfers:   true,
+			Run:         (*Runner).Step2_InitialPush,
+		},
+		{
+			Name:        "modify",
+			Description: "Update files with the v2 versions, delete and rename files, and commit the changes",
+			Requires:    []string{"push"},
+			Run:         (*Runner).Step3_Modifications,
+		},
+		{
+			Name:        "clone",
+			Description: "Clone into a second repository and compare it with the first",
+			Requires:    []string{"push"},
+			Transfers:   true,
+			Run:         (*Runner).Step4_SecondClone,
+		},
+		{
+			Name:        "client2-push",
+			Description: "Commit and push a new file from the second clone",
+			Requires:    []string{"clone"},
+			Transfers:   true,
+			Run:         (*Runner).Step5_SecondClientPush,
+		},
+		{
+			Name:        "pull",
+			Description: "Pull the second client's changes into the first repository",
+			Requires:    []string{"setup"},
+			Transfers:   true,
+			Run:         (*Runner).Step6_FirstClientPull,
+		},
+		{
+			Name:        "untrack",
+			Description: "Untrack the LFS patterns and migrate the files out of LFS",
+			Requires:    []string{"push"},
+			Run:         (*Runner).Step7_Untrack,
+		},
+		{
+			Name:        "concurrent",
+			Description: "Push and pull from several clients at once (needs --clients 2 or more)",
+			Requires:    []string{"clone"},
+			Enabled:     func(r *Runner) bool { return r.Clients > 1 },
+			Transfers:   true,
+			Run:         (*Runner).Step8_ConcurrentClients,
+		},
+		{
+			Name:        "locking",
+			Description: "Test the server's file locking API (needs --locking)",
+			Requires:    []string{"clone"},
+			Enabled:     func(r *Runner) bool { return r.Locking },
+			Transfers:   true,
+			Run:         (*Runner).Step9_Locking,
+		},
+		{
+			Name:        "large-file",
+			Description: "Push a very large sparse file, interrupt the upload and complete it (needs --large-file)",
+			Requires:    []string{"clone"},
+			Enabled:     func(r *Runner) bool { return r.LargeFileSize > 0 },
+			Transfers:   true,
+			Run:         (*Runner).Step10_LargeFile,
+		},
+		{
+			Name:        "cadence",
+			Description: "Modify a small LFS file many times, pushing after every commit (needs --cadence)",
+			Requires:    []string{"clone"},
+			Enabled:     func(r *Runner) bool { return r.CadenceCommits > 0 },
+			Transfers:   true,
+			Run:         (*Runner).Step11_Cadence,
+		},
+		{
+			Name:        "churn",
+			Description: "Rewrite one LFS file with new content and commit it; repeat it to grow the history",
+			Requires:    []string{"push"},
+			Run:         (*Runner).StepChurn,
+		},
+		{
+			Name:        "gc",
+			Description: "Run git gc and git lfs prune in the first repository",
+			Requires:    []string{"setup"},
+			Run:         (*Runner).StepGC,
+		},
+	} {
+		RegisterStep(step)
+	}
+}
+
+// StepChurn rewrites the first LFS file of the v1 data with new content and commits it,
+// so every repetition adds a version of the file to the history
+func (r *Runner) StepChurn() error {
+	ctx := &git.Context{
+		DB:         r.DB,
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		Debug:      r.Debug,
+		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
+	}
+
+	state, err := r.expectedState(2)
+	if err != nil {
+		return err
+	}
+	name, err := churnFile(r.RepoDir, state.LFS)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(r.RepoDir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", name, err)
+	}
+	log.Debugf("Rewriting %s (%d bytes)...\n", name, info.Size())
+	if err := writeRandomFile(path, info.Size(), r.RunID*1000+int64(r.step())); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	if err := ctx.Add(r.RepoDir, name); err != nil {
+		return err
+	}
+	if err := ctx.Commit(r.RepoDir, fmt.Sprintf("Rewrite %s in step %d", name, r.step())); err != nil {
+		return err
+	}
+
+	// The pushed objects must have reached the server's storage backend
+	if err := r.verifyStorage(r.step(), r.RepoDir); err != nil {
+		return err
+	}
+	if err := r.verifyHistoryObjects(r.step(), r.RepoDir, true); err != nil {
+		return err
+	}
+	if err := r.verify(r.step(), "lfs-content", SeverityError, lfsverify.VerifyLFSContent(r.RepoDir, r.poolOptions(), r.Debug)); err != nil {
+		return fmt.Errorf("LFS content verification failed: %w", err)
+	}
+
+	checksums, err := r.computeChecksums(r.RepoDir)
+	if err != nil {
+		return fmt.Errorf("failed to compute checksums: %w", err)
+	}
+	if err := r.storeChecksums(checksums); err != nil {
+		return fmt.Errorf("failed to store checksums: %w", err)
+	}
+
+	return nil
+}
+
+// churnFile returns the first of the LFS files, in name order, that is still in repoDir
+func churnFile(repoDir string, lfsFiles []string) (string, error) {
+	names := append([]string(nil), lfsFiles...)
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(repoDir, name)); err == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no LFS file left in %s to rewrite", repoDir)
+}
+
+// StepGC runs git gc and git lfs prune in the first repository, which measures how long
+// housekeeping takes and how much it reclaims
+func (r *Runner) StepGC() error {
+	ctx := &git.Context{
+		DB:         r.DB,
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		Debug:      r.Debug,
+		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
+	}
+
+	log.Debugf("Running git gc...\n")
+	if err := ctx.GC(r.RepoDir); err != nil {
+		return err
+	}
+
+	log.Debugf("Pruning LFS objects...\n")
+	return ctx.LFSPrune(r.RepoDir)
+}
+
+// matchPipeline checks that the steps a run recorded are the steps at the same positions
+// in the runner's pipeline, so a run is resumed with the pipeline it started with
+func (r *Runner) matchPipeline(results []*database.StepResult) error {
+	steps, err := r.pipeline()
+	if err != nil {
+		return err
+	}
+	for _, sr := range results {
+		if sr.Name == "" {
+			continue // Recorded before steps had names
+		}
+		if sr.StepNumber > len(steps) || steps[sr.StepNumber-1].Name != sr.Name {
+			return fmt.Errorf("step %d was '%s', which the pipeline does not have at that position; "+
+				"resume with the pipeline the run started with", sr.StepNumber, sr.Name)
+		}
+	}
+	return nil
+}
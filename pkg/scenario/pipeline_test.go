@@ -0,0 +1,179 @@
+package scenario
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+)
+
+// stepList returns the names of steps, with "-" for a disabled step
+func stepList(steps []*Step) string {
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = "-"
+		if step != nil {
+			names[i] = step.Name
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func TestParsePipeline(t *testing.T) {
+	steps, err := ParsePipeline(DefaultPipeline)
+	if err != nil {
+		t.Fatalf("ParsePipeline(DefaultPipeline) failed: %v", err)
+	}
+	if len(steps) != CadenceStep || steps[ConcurrentStep-1].Name != "concurrent" {
+		t.Errorf("default pipeline = %s", stepList(steps))
+	}
+
+	steps, err = ParsePipeline([]string{"setup", "push", " churn * 3 ", "clone", "gc"})
+	if err != nil {
+		t.Fatalf("ParsePipeline failed: %v", err)
+	}
+	if got := stepList(steps); got != "setup,push,churn,churn,churn,clone,gc" {
+		t.Errorf("repeated steps = %s", got)
+	}
+
+	for _, tt := range []struct {
+		entries []string
+		want    string
+	}{
+		{nil, "no steps"},
+		{[]string{"setup", "compress"}, "unknown pipeline step 'compress'"},
+		{[]string{"setup", "push*0"}, "invalid repeat count"},
+		{[]string{"setup", "push*x"}, "invalid repeat count"},
+		{[]string{"setup", "clone", "push"}, "'clone' must come after 'push'"},
+	} {
+		if _, err := ParsePipeline(tt.entries); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParsePipeline(%v) error = %v, want %q", tt.entries, err, tt.want)
+		}
+	}
+}
+
+func TestCustomPipelineSteps(t *testing.T) {
+	r := &Runner{Pipeline: []string{"setup", "push", "concurrent*0"}}
+	if r.steps() != nil {
+		t.Error("an invalid pipeline should have no steps")
+	}
+	if err := r.validatePrerequisites(); err == nil || !strings.Contains(err.Error(), "repeat count") {
+		t.Errorf("validatePrerequisites should report the invalid pipeline, got %v", err)
+	}
+
+	// Disabled steps keep their position, so the steps after them keep their numbers
+	r.Pipeline = []string{"setup", "push", "clone", "concurrent", "churn*2"}
+	if got := stepList(r.steps()); got != "setup,push,clone,-,churn,churn" {
+		t.Errorf("steps() = %s", got)
+	}
+	if r.stepCount() != 5 {
+		t.Errorf("stepCount() = %d, want 5", r.stepCount())
+	}
+
+	// The clone compares itself with the step that last changed the first repository
+	r.Pipeline = []string{"setup", "push", "churn*2", "clone", "gc"}
+	r.currentStep.Store(5)
+	if got := r.lastStep("push", "modify", "churn"); got != 4 {
+		t.Errorf("lastStep before the clone = %d, want 4", got)
+	}
+	if got := r.lastStep("modify"); got != 0 {
+		t.Errorf("lastStep(modify) = %d, want 0 in a pipeline without it", got)
+	}
+}
+
+func TestPipelineState(t *testing.T) {
+	r := newMiniRunner(t)
+	r.Pipeline = []string{"setup", "push", "clone", "untrack"}
+	r.currentStep.Store(4)
+
+	pushed, err := r.expectedState(2)
+	if err != nil {
+		t.Fatalf("expectedState failed: %v", err)
+	}
+
+	// Without the modify step the clone sees the pushed v1 files, and untracking
+	// takes those files out of LFS
+	state, err := r.pipelineState(7)
+	if err != nil {
+		t.Fatalf("pipelineState failed: %v", err)
+	}
+	if len(state.Absent) != 0 || len(state.LFS) != 0 || strings.Join(state.NotLFS, ",") != strings.Join(pushed.LFS, ",") {
+		t.Errorf("untrack state without modify = %+v", state)
+	}
+
+	r.Pipeline = DefaultPipeline
+	r.currentStep.Store(7)
+	if state, err = r.pipelineState(7); err != nil || len(state.Absent) == 0 {
+		t.Errorf("untrack state after modify = %+v, %v; want the deletions and renames", state, err)
+	}
+}
+
+func TestMatchPipeline(t *testing.T) {
+	r := &Runner{Pipeline: []string{"setup", "push", "churn*2"}}
+	results := []*database.StepResult{
+		{StepNumber: 1, Name: "setup"},
+		{StepNumber: 2, Name: "push"},
+		{StepNumber: 3},
+	}
+	if err := r.matchPipeline(results); err != nil {
+		t.Errorf("matchPipeline failed for the same pipeline: %v", err)
+	}
+
+	r.Pipeline = nil
+	results[1].StepNumber, results[1].Name = 3, "churn"
+	if err := r.matchPipeline(results); err == nil || !strings.Contains(err.Error(), "step 3 was 'churn'") {
+		t.Errorf("matchPipeline with another pipeline: err = %v", err)
+	}
+}
+
+func TestChurnFile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "b.zip"), []byte("b"), 0644)
+	os.WriteFile(filepath.Join(dir, "c.pdf"), []byte("c"), 0644)
+
+	if name, err := churnFile(dir, []string{"c.pdf", "a.mov", "b.zip"}); err != nil || name != "b.zip" {
+		t.Errorf("churnFile = %s, %v; want the first file that still exists", name, err)
+	}
+	if _, err := churnFile(dir, []string{"a.mov"}); err == nil {
+		t.Error("churnFile should fail when no LFS file is left")
+	}
+}
+
+func TestCustomPipelineEndToEnd(t *testing.T) {
+	requireGitLFS(t)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+
+	runner := newMiniRunner(t)
+	runner.Pipeline = []string{"setup", "push", "churn*2", "clone"}
+	if err := runner.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	results, err := runner.DB.ListStepResults(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListStepResults failed: %v", err)
+	}
+	var names []string
+	for _, sr := range results {
+		names = append(names, sr.Name)
+		if sr.Status != "completed" {
+			t.Errorf("Step %d (%s) status = %s: %s", sr.StepNumber, sr.Name, sr.Status, sr.Error)
+		}
+	}
+	if got := strings.Join(names, ","); got != "setup,push,churn,churn,clone" {
+		t.Errorf("recorded steps = %s", got)
+	}
+
+	// Each churn commits a new version of the same file
+	diffs, err := checksum.CompareChecksums(runner.DB, runner.RunID, 3, 4)
+	if err != nil {
+		t.Fatalf("CompareChecksums failed: %v", err)
+	}
+	if len(diffs) != 1 {
+		t.Errorf("the two churn steps differ in %d files, want 1", len(diffs))
+	}
+}
@@ -1,67 +1,309 @@
 package scenario
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/mslinn/git-lfs-test/pkg/checksum"
 	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/environment"
 	"github.com/mslinn/git-lfs-test/pkg/git"
+	"github.com/mslinn/git-lfs-test/pkg/githost"
+	"github.com/mslinn/git-lfs-test/pkg/gitserver"
+	"github.com/mslinn/git-lfs-test/pkg/interference"
 	"github.com/mslinn/git-lfs-test/pkg/lfsverify"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/netem"
+	"github.com/mslinn/git-lfs-test/pkg/netstat"
+	"github.com/mslinn/git-lfs-test/pkg/notify"
+	"github.com/mslinn/git-lfs-test/pkg/preflight"
+	"github.com/mslinn/git-lfs-test/pkg/report"
+	"github.com/mslinn/git-lfs-test/pkg/serverlog"
+	"github.com/mslinn/git-lfs-test/pkg/sshutil"
+	"github.com/mslinn/git-lfs-test/pkg/storage"
+	"github.com/mslinn/git-lfs-test/pkg/term"
 	"github.com/mslinn/git-lfs-test/pkg/testdata"
 	"github.com/mslinn/git-lfs-test/pkg/timing"
+	"github.com/mslinn/git-lfs-test/pkg/workerpool"
 )
 
 // Scenario defines a Git LFS test scenario
 type Scenario struct {
-	ID         int
-	Name       string
-	ServerType string // 'lfs-test-server', 'giftless', 'rudolfs', 'bare'
-	Protocol   string // 'http', 'https', 'ssh', 'local'
-	GitServer  string // 'bare', 'github'
-	ServerURL  string // e.g., "http://gojira:8079"
-	RepoName   string // GitHub repository name (e.g., "username/lfs-eval-test")
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	ServerType string `json:"server_type"`          // 'lfs-test-server', 'giftless', 'rudolfs', 'gitea', 'github', 'bitbucket', 'azure-devops', 'bare'
+	Protocol   string `json:"protocol"`             // 'http', 'https', 'ssh', 'local'
+	GitServer  string `json:"git_server"`           // 'bare', or a githost kind such as 'github', 'gitea' or 'bitbucket'
+	ServerURL  string `json:"server_url,omitempty"` // e.g., "http://gojira:8079"; empty when the git host serves LFS itself
+	RepoName   string `json:"repo_name,omitempty"`  // Hosted repository name (e.g., "username/lfs-eval-test")
+	// TrackPatterns are the git lfs track patterns of the repository; empty means DefaultTrackPatterns
+	TrackPatterns []string `json:"track_patterns,omitempty"`
+	// Fixture declares the expected results; nil uses the data set's fixture.yaml,
+	// or DefaultFixture if it has none
+	Fixture *Fixture `json:"-"`
+}
+
+// DefaultTrackPatterns are the file types the standard test data stores in LFS
+var DefaultTrackPatterns = []string{"*.pdf", "*.mov", "*.avi", "*.ogg", "*.m4v", "*.zip"}
+
+// Patterns returns the patterns the scenario tracks with LFS
+func (s *Scenario) Patterns() []string {
+	if len(s.TrackPatterns) > 0 {
+		return s.TrackPatterns
+	}
+	return DefaultTrackPatterns
+}
+
+// ValidateTrackPatterns reports the first empty or malformed tracking pattern
+func ValidateTrackPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("empty LFS tracking pattern")
+		}
+	}
+	return checksum.Filter{Include: patterns}.Validate()
+}
+
+// UsesExternalServices returns true if the scenario needs GitHub or another hosted service
+func (s *Scenario) UsesExternalServices() bool {
+	switch s.GitServer {
+	case "github", "bitbucket", "azure-devops":
+		return true
+	}
+	return false
+}
+
+// UsesHostedGit returns true if the scenario creates its repository on a git hosting service
+func (s *Scenario) UsesHostedGit() bool {
+	return s.GitServer != "" && s.GitServer != "bare" && s.RepoName != ""
+}
+
+// UsesBareGitServer returns true if the scenario's git server is a bare repository that
+// the run creates for itself (see pkg/gitserver)
+func (s *Scenario) UsesBareGitServer() bool {
+	return s.GitServer == "" || s.GitServer == "bare"
 }
 
 // Runner executes a scenario
 type Runner struct {
-	Scenario   *Scenario
-	DB         *database.DB
-	RunID      int64
-	Debug      bool
-	Force      bool   // Force recreation of existing repositories
-	WorkDir    string // Base directory for test operations
-	RepoDir    string // Repository directory (WorkDir/repo1)
-	Repo2Dir   string // Second clone directory (WorkDir/repo2)
-	GitHubURL  string // GitHub clone URL (set during execution if created)
+	Scenario  *Scenario
+	DB        *database.DB
+	RunID     int64
+	Debug     bool
+	Force     bool   // Force recreation of existing repositories
+	WorkDir   string // Base directory for test operations
+	RunDir    string // Directory of the run's repositories (WorkDir/run-<ID>), recorded in test_runs
+	RepoDir   string // Repository directory (RunDir/repo1)
+	Repo2Dir  string // Second clone directory (RunDir/repo2)
+	HostedURL string // Hosted repository clone URL (set during execution if created)
+	Offline   bool   // Refuse to contact GitHub or other external services
+	// TestDataPath overrides the configured test data location, e.g. with a
+	// corpus from testdata.GenerateMiniCorpus
+	TestDataPath string
+	// Corpus names the corpus under the test data location the scenario copies, such as
+	// small or large (see testdata.OpenCorpus); empty uses the location itself
+	Corpus string
+	// TestDataCache is the directory that keeps copies of remote test data between runs
+	// (see testdata.CopyFiles); empty copies the files from the host every time
+	TestDataCache string
+	Workers       int // Concurrent checksum/verification workers; 0 means one per CPU
+	// Interface whose traffic is recorded for each step; empty means the default route's interface
+	Interface string
+	// ServerStorage is the LFS server's storage directory (local path or host:/path),
+	// measured after every step; empty skips server measurements
+	ServerStorage string
+	// Lenient records failed verifications but lets the scenario continue
+	Lenient bool
+	// Storage checks pushed LFS objects directly in the server's backend (e.g. an S3
+	// bucket) after each push; nil skips the check
+	Storage storage.Probe
+	// Version of the tool running the scenario, recorded in RunMetadataName
+	Version string
+	// Clients is the number of simulated clients that push and pull at the same time in
+	// ConcurrentStep; fewer than 2 skips that step
+	Clients int
+	// ClientFileSize is the size of the LFS file each concurrent client pushes;
+	// 0 means DefaultClientFileSize
+	ClientFileSize int64
+	// Locking adds LockingStep, which tests the server's file locking API
+	Locking bool
+	// LargeFileSize adds LargeFileStep, which pushes a sparse file of this size, interrupts
+	// the upload and pushes again to complete it; 0 skips that step
+	LargeFileSize int64
+	// LargeFileInterrupt is how long the first upload of LargeFileStep runs before it is
+	// stopped; 0 means DefaultLargeFileInterrupt
+	LargeFileInterrupt time.Duration
+	// CadenceCommits adds CadenceStep, which modifies a small LFS file this many times,
+	// committing and pushing after each modification; 0 skips that step
+	CadenceCommits int
+	// CadenceFileSize is the size of the file each commit of CadenceStep writes; 0 means
+	// DefaultCadenceFileSize
+	CadenceFileSize int64
+	// LFSProxy routes git-lfs through a local proxy that records every Batch API
+	// request and response in the lfs_batch_requests table
+	LFSProxy bool
+	// GitDaemon serves the bare repository of scenarios with a bare git server through
+	// git daemon (git://) instead of as a path; SSH scenarios always use SSH
+	GitDaemon bool
+	// SSHGitHost is the host that holds the bare repository of SSH scenarios, which is
+	// created there over SSH; empty keeps it in the run's directory on this machine
+	SSHGitHost string
+	// SSHGitDir is the directory on SSHGitHost for the bare repositories of runs;
+	// empty means gitserver.DefaultRemoteDir
+	SSHGitDir string
+	// MaxDuration is the time budget of a run; once it is used up the operation in flight
+	// is stopped and the run is marked timed-out. 0 means no limit.
+	MaxDuration time.Duration
+	// Pipeline lists the steps to run by name (see ParsePipeline); empty means DefaultPipeline
+	Pipeline []string
+	// Limits runs git and git-lfs under nice, ionice and cgroup CPU/memory limits;
+	// nil runs them unconstrained
+	Limits *timing.Limits
+	// SkipInterferenceCheck turns off the detection of antivirus scanners and file
+	// indexers, which is recorded as an 'interference' warning on every step
+	SkipInterferenceCheck bool
+	// Snapshots saves repo1 and repo2 after every completed step with one of the
+	// SnapshotMethods, so Rerun can run a step again from the state before it; empty takes none
+	Snapshots string
+	// ServerService is the systemd unit or Docker container of the LFS server, whose restarts
+	// and out-of-memory kills are recorded after every step and attributed to the operations
+	// they overlap; nil skips it
+	ServerService *serverlog.Source
+	// ChecksumExclude matches the files left out of checksums (see checksum.Filter);
+	// NewRunner sets checksum.DefaultExclude
+	ChecksumExclude []string
+	// ChecksumStore is where the checksums of steps go: one of checksum.StoreModes.
+	// Manifests are written to ManifestDir, so they outlive the cleanup of the repositories.
+	// Empty means checksum.StoreDatabase.
+	ChecksumStore string
+	// ChecksumConflict is what storing the checksums of a step that has checksums already
+	// does, e.g. when a step is rerun: one of checksum.ConflictModes. Empty means
+	// checksum.ConflictReplace.
+	ChecksumConflict string
+	// NoChecksumCache reads every file whenever a step checksums a repository, rather than
+	// trusting the checksums of files whose size and modification time have not changed
+	// since an earlier step, which the run keeps in CacheFileName in RunDir
+	NoChecksumCache bool
+	// Assertions are checked after every step besides those of the fixture (see Assertion)
+	Assertions []string
+	// AllowedDifferences are the differences the checksum comparisons of steps 4 and 6
+	// ignore, besides those of the fixture (see AllowedDifference)
+	AllowedDifferences []string
+	// ResourceSampling is the interval at which the CPU, memory and disk I/O of every git
+	// command and its children are sampled, and recorded with its operation; 0 takes no samples
+	ResourceSampling time.Duration
+	// TransferAdapter forces git-lfs to move objects with one of TransferAdapters, so servers
+	// that offer faster adapters can be compared with the same one; empty lets git-lfs and
+	// the server choose
+	TransferAdapter string
+	// TransferAgent is the command line of the custom transfer agent of AdapterMultipart and
+	// AdapterStandalone, e.g. "lfs-folderstore /mnt/lfs"
+	TransferAgent string
+	// Network shapes the traffic of the steps that transfer data (see Step.Transfers) with
+	// tc/netem, e.g. to 50ms RTT and 100 Mbit/s; nil leaves the network alone.
+	// Resumed runs use the profile recorded with the run.
+	Network *netem.Profile
+	// Notify is told when the run ends: completed, failed, cancelled or timed out
+	Notify notify.Hooks
+
+	verifyFailures int                  // Failed verifications that did not stop the run
+	fixture        *Fixture             // Resolved by expectedState
+	corpus         *testdata.Corpus     // Opened by testCorpus
+	gitServer      *gitserver.Server    // Origin of scenarios with a bare git server, while steps run
+	currentStep    atomic.Int32         // Number of the running step
+	assertions     []*Assertion         // Resolved by resolveAssertions
+	asserted       map[string]bool      // Assertions checked at least once
+	allowed        []*AllowedDifference // Resolved by resolveAllowedDifferences
+	shaper         *netem.Shaper        // Applies Network; set by checkShaper
+	checksumCache  *checksum.Cache      // Loaded by computeChecksums
+	exec           timing.Options       // Context, deadline, limits and sampling of git commands; set by runSteps
 }
 
 // NewRunner creates a new scenario runner
 func NewRunner(scenario *Scenario, db *database.DB, workDir string, debug, force bool) *Runner {
 	return &Runner{
-		Scenario: scenario,
-		DB:       db,
-		Debug:    debug,
-		Force:    force,
-		WorkDir:  workDir,
-		RepoDir:  workDir + "/repo1",
-		Repo2Dir: workDir + "/repo2",
+		Scenario:        scenario,
+		DB:              db,
+		Debug:           debug,
+		Force:           force,
+		WorkDir:         workDir,
+		RunDir:          workDir,
+		RepoDir:         workDir + "/repo1",
+		Repo2Dir:        workDir + "/repo2",
+		ChecksumExclude: checksum.DefaultExclude,
 	}
 }
 
-// Execute runs the complete 7-step scenario
-func (r *Runner) Execute() error {
-	if r.Debug {
-		fmt.Printf("\n=== Executing Scenario %d: %s ===\n", r.Scenario.ID, r.Scenario.Name)
-		fmt.Printf("Server: %s via %s\n", r.Scenario.ServerType, r.Scenario.Protocol)
-		fmt.Printf("Work directory: %s\n\n", r.WorkDir)
+// RunDir returns the directory of the repositories of a run in workDir
+func RunDir(workDir string, runID int64) string {
+	return filepath.Join(workDir, fmt.Sprintf("run-%d", runID))
+}
+
+// RunRepoDirs returns the directories of the first and second repository of a run:
+// those in the run's own directory, or, for runs made before per-run directories, those
+// directly in workDir
+func RunRepoDirs(run *database.TestRun, workDir string) (string, string) {
+	dir := run.WorkDir
+	if dir == "" {
+		dir = workDir
+	}
+	return filepath.Join(dir, "repo1"), filepath.Join(dir, "repo2")
+}
+
+// SharedDirRun returns the ID of another live run that, like run, has no directory of its
+// own recorded and so works in the same repositories; 0 if there is none. The repositories
+// of run may only be removed if it returns 0 without an error.
+func SharedDirRun(db *database.DB, run *database.TestRun) (int64, error) {
+	runs, err := db.GetAllTestRuns()
+	if err != nil {
+		return 0, err
+	}
+	for _, other := range runs {
+		if other.ID != run.ID && other.WorkDir == "" && other.Status == "running" && ProcessAlive(other.PID) {
+			return other.ID, nil
+		}
 	}
+	return 0, nil
+}
+
+// useRunDir points the runner at the directory recorded for run, so runs sharing
+// WorkDir work in repositories of their own
+func (r *Runner) useRunDir(run *database.TestRun) {
+	r.RunDir = run.WorkDir
+	if r.RunDir == "" {
+		r.RunDir = r.WorkDir
+	}
+	r.RepoDir, r.Repo2Dir = RunRepoDirs(run, r.WorkDir)
+}
+
+// ErrTimedOut is returned by Execute and Resume when a run exceeds MaxDuration
+var ErrTimedOut = errors.New("run timed out")
+
+// ErrCancelled is returned by Execute, Resume and Rerun when their context is cancelled
+var ErrCancelled = errors.New("run cancelled")
+
+// Execute runs the scenario's pipeline of steps. Cancelling ctx stops the command in
+// flight and marks the run cancelled.
+func (r *Runner) Execute(ctx context.Context) error {
+	log.Debugf("\n=== Executing Scenario %d: %s ===\n", r.Scenario.ID, r.Scenario.Name)
+	log.Debugf("Server: %s via %s\n", r.Scenario.ServerType, r.Scenario.Protocol)
+	log.Debugf("Work directory: %s\n\n", r.WorkDir)
 
 	// Validate prerequisites before starting
 	if err := r.validatePrerequisites(); err != nil {
 		return err
 	}
+	if err := r.checkDiskSpace(); err != nil {
+		return err
+	}
 
 	// Create test run
 	run := &database.TestRun{
@@ -70,83 +312,593 @@ func (r *Runner) Execute() error {
 		Protocol:   r.Scenario.Protocol,
 		GitServer:  r.Scenario.GitServer,
 		PID:        os.Getpid(),
+		StartedAt:  time.Now(),
 		Status:     "running",
 		Notes:      fmt.Sprintf("Automated execution of scenario %d", r.Scenario.ID),
 	}
+	if r.TestDataPath != "" {
+		run.Notes += fmt.Sprintf(" using test data from %s", r.TestDataPath)
+	}
+	if r.Corpus != "" {
+		run.Notes += fmt.Sprintf(" using corpus %s", r.Corpus)
+	}
+	if len(r.Pipeline) > 0 {
+		run.Notes += fmt.Sprintf(" with pipeline %s", strings.Join(r.Pipeline, ","))
+	}
+	if len(r.Scenario.TrackPatterns) > 0 {
+		run.Notes += fmt.Sprintf(" tracking %s", strings.Join(r.Scenario.TrackPatterns, ","))
+	}
+	if !r.Limits.IsZero() {
+		run.Notes += fmt.Sprintf(" limited to %s", r.Limits)
+	}
+	if r.ChecksumStore != "" && r.ChecksumStore != checksum.StoreDatabase {
+		run.Notes += fmt.Sprintf(" with checksum store %s", r.ChecksumStore)
+	}
+	if r.TransferAdapter != "" {
+		run.Notes += fmt.Sprintf(" with transfer adapter %s", r.expectedAdapter())
+	}
+	if r.Network != nil {
+		run.NetworkProfile = r.Network.String()
+		run.Notes += fmt.Sprintf(" with network %s", run.NetworkProfile)
+	}
 
 	if err := r.DB.CreateTestRun(run); err != nil {
 		return fmt.Errorf("failed to create test run: %w", err)
 	}
 	r.RunID = run.ID
 
-	if r.Debug {
-		fmt.Printf("Created test run ID: %d\n\n", r.RunID)
+	run.WorkDir = RunDir(r.WorkDir, run.ID)
+	if err := r.DB.UpdateTestRun(run); err != nil {
+		return fmt.Errorf("failed to record the work directory of run %d: %w", run.ID, err)
+	}
+	r.useRunDir(run)
+
+	// Timings mean little without the machine they were measured on
+	env := environment.Collect(run.WorkDir)
+	env.RunID = run.ID
+	if err := r.DB.SaveEnvironment(env); err != nil {
+		log.Warnf("failed to record the environment of run %d: %v\n", run.ID, err)
+	}
+
+	// The corpus tells results apart like the environment does, so tag the run with it
+	if r.Corpus != "" {
+		if err := r.DB.SetRunTag(run.ID, database.Tag{Key: "corpus", Value: r.Corpus}); err != nil {
+			log.Warnf("failed to tag run %d with its corpus: %v\n", run.ID, err)
+		}
+	}
+
+	log.Debugf("Created test run ID: %d\n", r.RunID)
+	log.Debugf("Environment: %s\n\n", env.Summary())
+
+	return r.runSteps(ctx, run, nil)
+}
+
+// Resume continues an interrupted run of this scenario, skipping the steps it already completed
+func (r *Runner) Resume(ctx context.Context, runID int64) error {
+	run, err := r.DB.GetTestRun(runID)
+	if err != nil {
+		return err
+	}
+
+	if run.ScenarioID != r.Scenario.ID {
+		return fmt.Errorf("run %d belongs to scenario %d, not scenario %d", runID, run.ScenarioID, r.Scenario.ID)
+	}
+	if run.Status == "completed" {
+		return fmt.Errorf("run %d already completed", runID)
+	}
+	if run.Status == "running" && ProcessAlive(run.PID) {
+		return fmt.Errorf("run %d is still running (PID %d)", runID, run.PID)
+	}
+
+	results, err := r.DB.ListStepResults(runID)
+	if err != nil {
+		return err
+	}
+	if err := r.matchPipeline(results); err != nil {
+		return fmt.Errorf("cannot resume run %d: %w", runID, err)
+	}
+	completed := make(map[int]bool)
+	for _, sr := range results {
+		if sr.Status == "completed" {
+			completed[sr.StepNumber] = true
+		}
+	}
+
+	// Later steps depend on the repositories left behind by earlier ones
+	r.useRunDir(run)
+	if len(completed) > 0 {
+		if _, err := os.Stat(r.RepoDir); err != nil {
+			return fmt.Errorf("cannot resume run %d: working directory %s is gone", runID, r.RepoDir)
+		}
+	}
+
+	log.Debugf("\n=== Resuming Run %d (Scenario %d: %s) ===\n", runID, r.Scenario.ID, r.Scenario.Name)
+	log.Debugf("Completed steps: %d of %d\n\n", len(completed), r.stepCount())
+
+	if err := r.useNetworkProfile(run); err != nil {
+		return err
+	}
+	if err := r.useCorpus(run); err != nil {
+		return err
+	}
+	if err := r.validatePrerequisites(); err != nil {
+		return err
+	}
+	if !completed[1] {
+		if err := r.checkDiskSpace(); err != nil {
+			return err
+		}
+	}
+
+	r.RunID = run.ID
+	run.Status = "running"
+	run.PID = os.Getpid()
+	run.CompletedAt = nil
+	run.Notes += fmt.Sprintf(" | Resumed after %d completed step(s)", len(completed))
+	if err := r.DB.UpdateTestRun(run); err != nil {
+		return fmt.Errorf("failed to update test run: %w", err)
+	}
+
+	return r.runSteps(ctx, run, completed)
+}
+
+// Rerun runs one step of an earlier run of this scenario again, after restoring the
+// repositories from the snapshot of the step before it; the run must have been made with
+// Snapshots. Later steps that had not completed run as with Resume. Only the local
+// repositories are restored: whatever the run pushed stays on the server.
+func (r *Runner) Rerun(ctx context.Context, runID int64, stepNum int) error {
+	run, err := r.DB.GetTestRun(runID)
+	if err != nil {
+		return err
+	}
+
+	if run.ScenarioID != r.Scenario.ID {
+		return fmt.Errorf("run %d belongs to scenario %d, not scenario %d", runID, run.ScenarioID, r.Scenario.ID)
+	}
+	if run.Status == "running" && ProcessAlive(run.PID) {
+		return fmt.Errorf("run %d is still running (PID %d)", runID, run.PID)
+	}
+
+	results, err := r.DB.ListStepResults(runID)
+	if err != nil {
+		return err
+	}
+	if err := r.matchPipeline(results); err != nil {
+		return fmt.Errorf("cannot rerun run %d: %w", runID, err)
+	}
+	steps := r.steps()
+	if stepNum < 1 || stepNum > len(steps) || steps[stepNum-1] == nil {
+		return fmt.Errorf("run %d has no step %d", runID, stepNum)
+	}
+
+	// Every step before this one must have completed; the last of them left the snapshot
+	completed := make(map[int]bool)
+	for _, sr := range results {
+		if sr.Status == "completed" && sr.StepNumber != stepNum {
+			completed[sr.StepNumber] = true
+		}
+	}
+	previous := 0
+	for i := 1; i < stepNum; i++ {
+		if steps[i-1] == nil {
+			continue
+		}
+		if !completed[i] {
+			return fmt.Errorf("cannot rerun step %d of run %d: step %d did not complete", stepNum, runID, i)
+		}
+		previous = i
 	}
 
-	// Execute each step
-	steps := []func() error{
-		r.Step1_Setup,
-		r.Step2_InitialPush,
-		r.Step3_Modifications,
-		r.Step4_SecondClone,
-		r.Step5_SecondClientPush,
-		r.Step6_FirstClientPull,
-		r.Step7_Untrack,
+	if err := r.useNetworkProfile(run); err != nil {
+		return err
+	}
+	if err := r.useCorpus(run); err != nil {
+		return err
+	}
+	if err := r.validatePrerequisites(); err != nil {
+		return err
 	}
 
+	log.Debugf("\n=== Rerunning Step %d of Run %d (Scenario %d: %s) ===\n", stepNum, runID, r.Scenario.ID, r.Scenario.Name)
+	r.RunID = run.ID
+	r.useRunDir(run)
+	if previous == 0 {
+		// The first step creates the repositories
+		for _, dir := range []string{r.RepoDir, r.Repo2Dir} {
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", dir, err)
+			}
+		}
+	} else if err := r.restore(runID, previous); err != nil {
+		return fmt.Errorf("cannot rerun step %d of run %d: %w", stepNum, runID, err)
+	}
+
+	run.Status = "running"
+	run.PID = os.Getpid()
+	run.CompletedAt = nil
+	run.Notes += fmt.Sprintf(" | Reran step %d", stepNum)
+	if previous > 0 {
+		run.Notes += fmt.Sprintf(" from the snapshot of step %d", previous)
+	}
+	if err := r.DB.UpdateTestRun(run); err != nil {
+		return fmt.Errorf("failed to update test run: %w", err)
+	}
+
+	return r.runSteps(ctx, run, completed)
+}
+
+// steps returns the steps of the runner's pipeline in order; step N is at index N-1.
+// Steps that are not enabled are nil, so every step keeps its number.
+// It returns nil if the pipeline is invalid, which validatePrerequisites reports.
+func (r *Runner) steps() []*Step {
+	steps, err := r.pipeline()
+	if err != nil {
+		return nil
+	}
 	for i, step := range steps {
+		if step.Enabled != nil && !step.Enabled(r) {
+			steps[i] = nil
+		}
+	}
+	return steps
+}
+
+// stepCount returns the number of enabled steps
+func (r *Runner) stepCount() int {
+	count := 0
+	for _, step := range r.steps() {
+		if step != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// runSteps executes every step not in completed, recording each step's result, until
+// ctx is cancelled
+func (r *Runner) runSteps(ctx context.Context, run *database.TestRun, completed map[int]bool) error {
+	defer r.notifyEnd()
+	unauthenticate, err := r.authenticateHost()
+	if err != nil {
+		return r.setupFailed(run, "Git host authentication failed", err)
+	}
+	defer unauthenticate()
+	if r.LFSProxy {
+		stop, err := r.startLFSProxy()
+		if err != nil {
+			return r.setupFailed(run, "LFS proxy failed to start", err)
+		}
+		defer stop()
+	}
+	if r.TransferAdapter != "" {
+		unforce, err := r.forceTransferAdapter()
+		if err != nil {
+			return r.setupFailed(run, "Transfer adapter could not be forced", err)
+		}
+		defer unforce()
+	}
+	if r.Scenario.UsesBareGitServer() {
+		stop, err := r.startGitServer()
+		if err != nil {
+			return r.gitServerUnavailable(run, err)
+		}
+		defer stop()
+	}
+
+	// Commands still running when the run is cancelled or the budget is used up are stopped
+	var deadline time.Time
+	if r.MaxDuration > 0 {
+		deadline = time.Now().Add(r.MaxDuration)
+	}
+	r.exec = timing.Options{Context: ctx, Deadline: deadline, Limits: r.Limits, Sampling: r.ResourceSampling}
+	defer func() { r.exec = timing.Options{} }()
+	// Tag log messages with the run and step
+	log.SetRun(r.RunID)
+	defer log.SetRun(0)
+	defer log.SetStep(0)
+
+	var monitor *interference.Monitor
+	checkedInterference := r.SkipInterferenceCheck
+	if !r.SkipInterferenceCheck {
+		monitor = interference.Watch(r.WorkDir, interferenceInterval)
+		defer monitor.Stop()
+	}
+	done := len(completed)
+
+	for i, step := range r.steps() {
 		stepNum := i + 1
-		if r.Debug {
-			fmt.Printf("--- Step %d ---\n", stepNum)
+		if step == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return r.cancelled(run, stepNum, done)
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return r.timedOut(run, stepNum, done)
 		}
+		if completed[stepNum] {
+			log.Debugf("--- Step %d (already completed, skipping) ---\n\n", stepNum)
+			continue
+		}
+
+		log.Debugf("--- Step %d: %s ---\n", stepNum, step.Name)
 
-		if err := step(); err != nil {
+		// Discard checksums and verifications left by an earlier, interrupted attempt at this step
+		if completed != nil {
+			if err := r.DB.ResetStepChecksums(r.RunID, stepNum); err != nil {
+				return err
+			}
+			if err := r.DB.DeleteVerifications(r.RunID, stepNum); err != nil {
+				return err
+			}
+			if err := r.DB.DeleteStorageVerifications(r.RunID, stepNum); err != nil {
+				return err
+			}
+			if err := r.DB.DeleteServerObjects(r.RunID, stepNum); err != nil {
+				return err
+			}
+			if err := r.DB.DeleteConcurrencyResults(r.RunID, stepNum); err != nil {
+				return err
+			}
+			if err := r.DB.DeleteLockResults(r.RunID, stepNum); err != nil {
+				return err
+			}
+			if err := r.DB.DeleteLargeFileResults(r.RunID, stepNum); err != nil {
+				return err
+			}
+			if err := r.DB.DeleteCadenceResults(r.RunID, stepNum); err != nil {
+				return err
+			}
+			if err := r.DB.DeleteLFSBatchRequests(r.RunID, stepNum); err != nil {
+				return err
+			}
+			if err := r.DB.DeleteServerEvents(r.RunID, stepNum); err != nil {
+				return err
+			}
+		}
+
+		result := &database.StepResult{
+			RunID:      r.RunID,
+			StepNumber: stepNum,
+			Name:       step.Name,
+			Status:     "running",
+			StartedAt:  time.Now(),
+		}
+		if err := r.DB.SaveStepResult(result); err != nil {
+			return err
+		}
+
+		// Scanners found before the first step are reported on it
+		var found *interference.Report
+		if !checkedInterference {
+			found = r.checkInterference()
+			checkedInterference = true
+		}
+		monitor.Drain()
+
+		before, sampled := r.sampleNetwork()
+		r.currentStep.Store(int32(stepNum))
+		log.SetStep(stepNum)
+		unshape, stepErr := r.shape(step)
+		if stepErr == nil {
+			stepErr = step.Run(r)
+			unshape()
+		}
+		if !r.SkipInterferenceCheck {
+			r.recordInterference(stepNum, found, monitor)
+		}
+
+		completedAt := time.Now()
+		result.CompletedAt = &completedAt
+		result.DurationMs = completedAt.Sub(result.StartedAt).Milliseconds()
+		if r.ServerService != nil {
+			// Operations that failed because the server went down name the likely cause
+			if cause := r.recordServerEvents(stepNum, result.StartedAt, completedAt); cause != "" && stepErr != nil {
+				stepErr = fmt.Errorf("%w (server: %s)", stepErr, cause)
+			}
+		}
+		if sampled {
+			r.recordTraffic(result, before)
+		}
+		r.recordSizes(stepNum)
+		if r.TransferAdapter != "" {
+			r.checkTransferAdapter(stepNum)
+		}
+		if stepErr == nil {
+			stepErr = r.checkAssertions(result)
+		}
+		result.Status = "completed"
+		if stepErr != nil {
+			result.Status = "failed"
+			result.Error = stepErr.Error()
+		}
+		cancelled := stepErr != nil && ctx.Err() != nil
+		timedOut := stepErr != nil && !cancelled && !deadline.IsZero() && !completedAt.Before(deadline)
+		switch {
+		case cancelled:
+			result.Status = "cancelled"
+		case timedOut:
+			result.Status = "timed-out"
+		}
+		if err := r.DB.SaveStepResult(result); err != nil {
+			log.Warnf("failed to save step result: %v\n", err)
+		}
+		if stepErr == nil && r.Snapshots != "" {
+			if err := r.snapshot(stepNum); err != nil {
+				log.Warnf("snapshot of step %d failed: %v\n", stepNum, err)
+			}
+		}
+
+		if cancelled {
+			return r.cancelled(run, stepNum, done)
+		}
+		if timedOut {
+			return r.timedOut(run, stepNum, done)
+		}
+		if stepErr != nil {
 			// Mark run as failed
 			run.Status = "failed"
-			run.Notes += fmt.Sprintf(" | Failed at step %d: %v", stepNum, err)
+			run.Notes += fmt.Sprintf(" | Failed at step %d: %v", stepNum, stepErr)
 			r.DB.UpdateTestRun(run)
 
-			// Attempt cleanup
-			if cleanupErr := r.cleanup(); cleanupErr != nil && r.Debug {
-				fmt.Printf("Warning: cleanup failed: %v\n", cleanupErr)
+			// Keep the repositories of a partially completed run so it can be resumed
+			if stepNum == 1 {
+				if cleanupErr := r.cleanup(); cleanupErr != nil {
+					log.Warnf("cleanup failed: %v\n", cleanupErr)
+				}
+			} else {
+				r.keptForResume()
 			}
 
-			return fmt.Errorf("step %d failed: %w", stepNum, err)
+			return fmt.Errorf("step %d failed: %w", stepNum, stepErr)
 		}
 
-		if r.Debug {
-			fmt.Printf("✓ Step %d complete\n\n", stepNum)
-		}
+		done++
+		log.Debugf("%s Step %d complete\n\n", term.OK(), stepNum)
 	}
 
+	r.warnUncheckedAssertions()
+
 	// Mark run as completed
+	completedAt := time.Now()
 	run.Status = "completed"
+	run.CompletedAt = &completedAt
 	run.Notes += " | All steps completed successfully"
+	if r.verifyFailures > 0 {
+		run.Notes += fmt.Sprintf(" | %d verification(s) failed without stopping the run", r.verifyFailures)
+	}
 	if err := r.DB.UpdateTestRun(run); err != nil {
 		return fmt.Errorf("failed to update test run: %w", err)
 	}
+	r.recordTimingSummary()
 
-	if r.Debug {
-		fmt.Printf("=== Scenario %d Complete ===\n", r.Scenario.ID)
-	}
+	log.Debugf("=== Scenario %d Complete ===\n", r.Scenario.ID)
 
 	return nil
 }
 
+// recordTimingSummary stores the breakdown of where the time of the completed run went,
+// which lfst-scenario prints and lfst-run show shows again
+func (r *Runner) recordTimingSummary() {
+	summary, err := report.BuildTimingSummary(r.DB, r.RunID)
+	if err == nil {
+		var text strings.Builder
+		if err = report.RenderTimingSummary(&text, summary); err == nil {
+			err = r.DB.SetTimingSummary(r.RunID, text.String())
+		}
+	}
+	if err != nil {
+		log.Warnf("failed to record the timing summary: %v\n", err)
+	}
+}
+
+// notifyEnd tells Notify that the run ended, unless it is still marked running
+func (r *Runner) notifyEnd() {
+	if !r.Notify.Enabled() {
+		return
+	}
+	payload, err := notify.Build(r.DB, r.RunID)
+	if err != nil {
+		log.Warnf("failed to notify the end of run %d: %v\n", r.RunID, err)
+		return
+	}
+	if payload.Status == "running" {
+		return
+	}
+	log.Debugf("Notifying the end of run %d (%s)\n", r.RunID, payload.Status)
+	if err := r.Notify.Send(payload); err != nil {
+		log.Warnf("failed to notify the end of run %d: %v\n", r.RunID, err)
+	}
+}
+
+// timedOut marks a run that used up MaxDuration in step stepNum as timed-out, records how
+// far it got, and removes its working directories
+func (r *Runner) timedOut(run *database.TestRun, stepNum, done int) error {
+	completedAt := time.Now()
+	run.Status = "timed-out"
+	run.CompletedAt = &completedAt
+	run.Notes += fmt.Sprintf(" | Timed out after %s in step %d with %d of %d steps completed",
+		r.MaxDuration, stepNum, done, r.stepCount())
+	if err := r.DB.UpdateTestRun(run); err != nil {
+		log.Warnf("failed to update test run: %v\n", err)
+	}
+
+	if err := r.cleanup(); err != nil {
+		log.Warnf("cleanup failed: %v\n", err)
+	}
+
+	return fmt.Errorf("%w: exceeded the maximum duration of %s in step %d (%d of %d steps completed)",
+		ErrTimedOut, r.MaxDuration, stepNum, done, r.stepCount())
+}
+
+// keptForResume tells the user that the working directories of a run that stopped were
+// kept, and how to resume it
+func (r *Runner) keptForResume() {
+	log.Infof("Working directories kept; resume with: lfst-scenario --resume %d\n", r.RunID)
+}
+
+// setupFailed marks a run that failed before its steps could run as failed, noting what
+// failed, so it does not stay running and its end is notified
+func (r *Runner) setupFailed(run *database.TestRun, what string, err error) error {
+	// The first line says what is wrong
+	summary, _, _ := strings.Cut(err.Error(), "\n")
+	completedAt := time.Now()
+	run.Status = "failed"
+	run.CompletedAt = &completedAt
+	run.Notes += fmt.Sprintf(" | %s before the steps ran: %s", what, summary)
+	if dbErr := r.DB.UpdateTestRun(run); dbErr != nil {
+		log.Warnf("failed to update test run: %v\n", dbErr)
+	}
+	return err
+}
+
+// cancelled marks a run whose context was cancelled in step stepNum as cancelled and
+// records how far it got. A run with completed steps keeps its working directories so
+// it can be resumed; otherwise they are removed.
+func (r *Runner) cancelled(run *database.TestRun, stepNum, done int) error {
+	completedAt := time.Now()
+	run.Status = "cancelled"
+	run.PID = 0
+	run.CompletedAt = &completedAt
+	run.Notes += fmt.Sprintf(" | Cancelled in step %d with %d of %d steps completed", stepNum, done, r.stepCount())
+	if err := r.DB.UpdateTestRun(run); err != nil {
+		log.Warnf("failed to update test run: %v\n", err)
+	}
+
+	if done > 0 {
+		r.keptForResume()
+	} else if err := r.cleanup(); err != nil {
+		log.Warnf("cleanup failed: %v\n", err)
+	}
+
+	return fmt.Errorf("%w in step %d (%d of %d steps completed)", ErrCancelled, stepNum, done, r.stepCount())
+}
+
+// ProcessAlive reports whether a process with the given PID exists
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
 // Step1_Setup: Create repo, configure LFS, copy initial files, compute checksums
 func (r *Runner) Step1_Setup() error {
 	ctx := &git.Context{
 		DB:         r.DB,
 		RunID:      r.RunID,
-		StepNumber: 1,
+		StepNumber: r.step(),
 		Debug:      r.Debug,
 		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
 	}
 
 	// Initialize repository
-	if r.Debug {
-		fmt.Println("Initializing repository...")
-	}
+	log.Debugf("Initializing repository...\n")
 	if err := ctx.InitRepo(r.RepoDir, false); err != nil {
 		return err
 	}
@@ -156,89 +908,93 @@ func (r *Runner) Step1_Setup() error {
 		return err
 	}
 
-	// Create GitHub repository if needed (scenarios 3-9 with github git server)
-	if r.Scenario.GitServer == "github" && r.Scenario.RepoName != "" {
-		if r.Debug {
-			fmt.Println("Creating GitHub repository...")
+	// Create the hosted repository if needed (GitHub, Gitea, Bitbucket and Azure DevOps scenarios)
+	if r.Scenario.UsesHostedGit() {
+		if err := r.checkOffline(); err != nil {
+			return err
+		}
+		log.Debugf("Creating %s repository...\n", r.Scenario.GitServer)
+		host, err := githost.New(r.Scenario.GitServer, ctx.Record)
+		if err != nil {
+			return err
 		}
-		cloneURL, err := ctx.CreateGitHubRepo(r.Scenario.RepoName, r.Force)
+		cloneURL, err := ctx.CreateHostedRepo(host, r.Scenario.RepoName, r.Force)
 		if err != nil {
-			return fmt.Errorf("failed to create GitHub repo: %w", err)
+			return fmt.Errorf("failed to create %s repo: %w", host.Name(), err)
 		}
-		r.GitHubURL = cloneURL
+		r.HostedURL = cloneURL
 
 		// Add the remote
 		if err := ctx.AddRemote(r.RepoDir, "origin", cloneURL); err != nil {
 			return fmt.Errorf("failed to add remote: %w", err)
 		}
+	} else if r.Scenario.UsesBareGitServer() {
+		if err := r.createOrigin(ctx, r.RepoDir); err != nil {
+			return err
+		}
+	}
+	if r.hasOrigin() {
+		if err := r.trackOrigin(ctx, r.RepoDir); err != nil {
+			return err
+		}
 	}
 
 	// Install git-lfs
-	if r.Debug {
-		fmt.Println("Installing git-lfs...")
-	}
+	log.Debugf("Installing git-lfs...\n")
 	if err := ctx.LFSInstall(r.RepoDir); err != nil {
 		return err
 	}
 
 	// Configure LFS server URL in .lfsconfig (if applicable)
 	if r.Scenario.ServerURL != "" {
-		if r.Debug {
-			fmt.Printf("Configuring LFS server URL: %s\n", r.Scenario.ServerURL)
-		}
+		log.Debugf("Configuring LFS server URL: %s\n", r.Scenario.ServerURL)
 		if err := ctx.ConfigureLFSURL(r.RepoDir, r.Scenario.ServerURL); err != nil {
 			return err
 		}
+	} else if endpoint := r.lfsEndpoint(); endpoint != "" {
+		log.Debugf("LFS endpoint of the %s repository: %s\n", r.Scenario.GitServer, endpoint)
 	}
 
 	// Configure LFS tracking patterns
-	if r.Debug {
-		fmt.Println("Configuring LFS tracking patterns...")
-	}
-	patterns := []string{"*.pdf", "*.mov", "*.avi", "*.ogg", "*.m4v", "*.zip"}
-	for _, pattern := range patterns {
+	log.Debugf("Configuring LFS tracking patterns...\n")
+	for _, pattern := range r.Scenario.Patterns() {
 		if err := ctx.LFSTrack(r.RepoDir, pattern); err != nil {
 			return err
 		}
 	}
 
 	// Generate evaluation README
-	if r.Debug {
-		fmt.Println("Generating evaluation README...")
-	}
+	log.Debugf("Generating evaluation README...\n")
 	if err := r.generateREADME(); err != nil {
 		return fmt.Errorf("failed to generate README: %w", err)
 	}
+	if err := r.writeRunMetadata(); err != nil {
+		return err
+	}
 
 	// Copy initial test files
-	if r.Debug {
-		fmt.Println("Copying initial test files (v1 - 1.3GB)...")
-	}
-	files, err := testdata.RealTestFiles()
+	log.Debugf("Copying initial test files (v1 - 1.3GB)...\n")
+	files, err := r.testFiles()
 	if err != nil {
 		return err
 	}
 
-	if err := testdata.CopyFiles(r.RepoDir, files, r.Debug); err != nil {
+	if err := testdata.CopyFiles(r.RepoDir, files, r.TestDataCache, r.Debug); err != nil {
 		return err
 	}
 
 	// Compute checksums
-	if r.Debug {
-		fmt.Println("Computing checksums...")
-	}
-	checksums, err := checksum.ComputeDirectory(r.RepoDir)
+	log.Debugf("Computing checksums...\n")
+	checksums, err := r.computeChecksums(r.RepoDir)
 	if err != nil {
 		return fmt.Errorf("failed to compute checksums: %w", err)
 	}
 
-	if err := checksum.StoreChecksums(r.DB, r.RunID, 1, checksums); err != nil {
+	if err := r.storeChecksums(checksums); err != nil {
 		return fmt.Errorf("failed to store checksums: %w", err)
 	}
 
-	if r.Debug {
-		fmt.Printf("Stored %d checksums\n", len(checksums))
-	}
+	log.Debugf("Stored %d checksums\n", len(checksums))
 
 	return nil
 }
@@ -248,87 +1004,81 @@ func (r *Runner) Step2_InitialPush() error {
 	ctx := &git.Context{
 		DB:         r.DB,
 		RunID:      r.RunID,
-		StepNumber: 2,
+		StepNumber: r.step(),
 		Debug:      r.Debug,
 		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
 	}
 
 	// Add all files (including .gitattributes from lfs track)
-	if r.Debug {
-		fmt.Println("Adding files to git...")
-	}
+	log.Debugf("Adding files to git...\n")
 	if err := ctx.Add(r.RepoDir, "."); err != nil {
 		return err
 	}
 
 	// Commit
-	if r.Debug {
-		fmt.Println("Committing initial files...")
-	}
+	log.Debugf("Committing initial files...\n")
 	if err := ctx.Commit(r.RepoDir, "Initial commit with LFS files"); err != nil {
 		return err
 	}
 
-	// Push (if remote is configured)
-	if r.Scenario.ServerURL != "" {
-		if r.Debug {
-			fmt.Println("Pushing to remote...")
-		}
-		// TODO: Set up remote first
-		// if err := ctx.Push(r.RepoDir, "origin", "main"); err != nil {
-		// 	return err
-		// }
+	if err := r.pushOrigin(ctx, r.RepoDir); err != nil {
+		return err
+	}
+
+	// The pushed objects must have reached the server's storage backend
+	if err := r.verifyStorage(r.step(), r.RepoDir); err != nil {
+		return err
+	}
+	if err := r.verifyHistoryObjects(r.step(), r.RepoDir, true); err != nil {
+		return err
 	}
 
 	// Compute checksums again to verify
-	checksums, err := checksum.ComputeDirectory(r.RepoDir)
+	checksums, err := r.computeChecksums(r.RepoDir)
 	if err != nil {
 		return fmt.Errorf("failed to compute checksums: %w", err)
 	}
 
-	if err := checksum.StoreChecksums(r.DB, r.RunID, 2, checksums); err != nil {
+	if err := r.storeChecksums(checksums); err != nil {
 		return fmt.Errorf("failed to store checksums: %w", err)
 	}
 
-	if r.Debug {
-		fmt.Printf("Stored %d checksums for step 2\n", len(checksums))
-	}
+	log.Debugf("Stored %d checksums for step %d\n", len(checksums), r.step())
 
 	// Verify LFS is working correctly
-	if r.Debug {
-		fmt.Println("Verifying LFS storage...")
-	}
+	log.Debugf("Verifying LFS storage...\n")
 
-	// Get list of expected LFS files
-	files, err := testdata.RealTestFiles()
+	state, err := r.expectedState(2)
 	if err != nil {
-		return fmt.Errorf("failed to get test files: %w", err)
+		return err
 	}
 
-	// Extract just the filenames
-	var expectedFiles []string
-	for _, f := range files {
-		expectedFiles = append(expectedFiles, f.Name)
+	// Verify the expected files are present
+	if err := r.verify(r.step(), "expected-files", SeverityError, state.CheckFiles(r.RepoDir)); err != nil {
+		return fmt.Errorf("file verification failed: %w", err)
 	}
 
 	// Verify files are stored as LFS pointers
-	if err := lfsverify.VerifyLFSPointers(r.RepoDir, expectedFiles, r.Debug); err != nil {
+	if err := r.verify(r.step(), "lfs-pointers", SeverityError, lfsverify.VerifyLFSPointers(r.RepoDir, state.LFS, r.Debug)); err != nil {
 		return fmt.Errorf("LFS pointer verification failed: %w", err)
 	}
 
 	// Verify LFS objects exist
-	if err := lfsverify.VerifyLFSObjects(r.RepoDir, len(expectedFiles), r.Debug); err != nil {
+	if err := r.verify(r.step(), "lfs-objects", SeverityError, lfsverify.VerifyLFSObjects(r.RepoDir, len(state.LFS), r.Debug)); err != nil {
 		return fmt.Errorf("LFS objects verification failed: %w", err)
 	}
 
-	// Verify repository sizes are correct (LFS objects > git objects)
-	if err := lfsverify.VerifyRepositorySizes(r.RepoDir, r.Debug); err != nil {
-		return fmt.Errorf("repository size verification failed: %w", err)
+	// Verify working file content matches the pointer OIDs
+	if err := r.verify(r.step(), "lfs-content", SeverityError, lfsverify.VerifyLFSContent(r.RepoDir, r.poolOptions(), r.Debug)); err != nil {
+		return fmt.Errorf("LFS content verification failed: %w", err)
 	}
 
-	if r.Debug {
-		fmt.Println("✓ LFS verification passed")
-	}
+	// Verify repository sizes are correct (LFS objects > git objects)
+	// The size comparison is a heuristic, so a failure is only a warning
+	r.verify(r.step(), "repository-sizes", SeverityWarning, lfsverify.VerifyRepositorySizes(r.RepoDir, r.Debug))
+
+	log.Debugf("%s LFS verification passed\n", term.OK())
 
 	return nil
 }
@@ -338,85 +1088,115 @@ func (r *Runner) Step3_Modifications() error {
 	ctx := &git.Context{
 		DB:         r.DB,
 		RunID:      r.RunID,
-		StepNumber: 3,
+		StepNumber: r.step(),
 		Debug:      r.Debug,
 		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
 	}
 
 	// Update files with v2 versions
-	if r.Debug {
-		fmt.Println("Updating files with v2 versions...")
-	}
-	v2Files, err := testdata.RealTestFilesV2()
+	log.Debugf("Updating files with v2 versions...\n")
+	v2Files, err := r.testFilesV2()
 	if err != nil {
 		return fmt.Errorf("failed to get v2 test files: %w", err)
 	}
 
-	if err := testdata.CopyFiles(r.RepoDir, v2Files, r.Debug); err != nil {
+	if err := testdata.CopyFiles(r.RepoDir, v2Files, r.TestDataCache, r.Debug); err != nil {
 		return fmt.Errorf("failed to copy v2 files: %w", err)
 	}
 
-	// Delete some files
-	if r.Debug {
-		fmt.Println("Deleting files...")
+	// A corpus with a v3 commits v2 on its own, so the history holds every version
+	version := "v2"
+	v3Files, err := r.testFilesV3()
+	if err != nil {
+		return fmt.Errorf("failed to get v3 test files: %w", err)
+	}
+	if len(v3Files) > 0 {
+		log.Debugf("Committing v2 versions...\n")
+		if err := ctx.Add(r.RepoDir, "-A"); err != nil {
+			return err
+		}
+		if err := ctx.Commit(r.RepoDir, "Update files (v2)"); err != nil {
+			return err
+		}
+
+		log.Debugf("Updating files with v3 versions...\n")
+		if err := testdata.CopyFiles(r.RepoDir, v3Files, r.TestDataCache, r.Debug); err != nil {
+			return fmt.Errorf("failed to copy v3 files: %w", err)
+		}
+		version = "v3"
 	}
-	filesToDelete := []string{"video1.m4v", "video4.ogg"}
-	for _, file := range filesToDelete {
+
+	fixture, err := r.resolveFixture()
+	if err != nil {
+		return err
+	}
+
+	// Delete the files the fixture declares
+	log.Debugf("Deleting files...\n")
+	for _, file := range fixture.Deletions {
 		if err := testdata.DeleteFile(r.RepoDir, file, r.Debug); err != nil {
 			return fmt.Errorf("failed to delete %s: %w", file, err)
 		}
 	}
 
-	// Rename a file
-	if r.Debug {
-		fmt.Println("Renaming files...")
+	// Rename the files the fixture declares, in a stable order
+	log.Debugf("Renaming files...\n")
+	oldNames := make([]string, 0, len(fixture.Renames))
+	for oldName := range fixture.Renames {
+		oldNames = append(oldNames, oldName)
 	}
-	if err := testdata.RenameFile(r.RepoDir, "zip2.zip", "zip2_renamed.zip", r.Debug); err != nil {
-		return fmt.Errorf("failed to rename zip2.zip: %w", err)
+	sort.Strings(oldNames)
+	for _, oldName := range oldNames {
+		if err := testdata.RenameFile(r.RepoDir, oldName, fixture.Renames[oldName], r.Debug); err != nil {
+			return fmt.Errorf("failed to rename %s: %w", oldName, err)
+		}
 	}
 
 	// Add all changes
-	if r.Debug {
-		fmt.Println("Adding changes to git...")
-	}
+	log.Debugf("Adding changes to git...\n")
 	if err := ctx.Add(r.RepoDir, "-A"); err != nil {
 		return err
 	}
 
 	// Commit changes
-	if r.Debug {
-		fmt.Println("Committing modifications...")
+	log.Debugf("Committing modifications...\n")
+	if err := ctx.Commit(r.RepoDir, fmt.Sprintf("Update, delete, and rename files (%s)", version)); err != nil {
+		return err
 	}
-	if err := ctx.Commit(r.RepoDir, "Update, delete, and rename files (v2)"); err != nil {
+
+	if err := r.pushOrigin(ctx, r.RepoDir); err != nil {
 		return err
 	}
 
-	// Push (if remote is configured)
-	if r.Scenario.ServerURL != "" {
-		if r.Debug {
-			fmt.Println("Pushing modifications to remote...")
-		}
-		// TODO: Set up remote first
-		// if err := ctx.Push(r.RepoDir, "origin", "main"); err != nil {
-		// 	return err
-		// }
+	// The pushed objects must have reached the server's storage backend
+	if err := r.verifyStorage(r.step(), r.RepoDir); err != nil {
+		return err
+	}
+	if err := r.verifyHistoryObjects(r.step(), r.RepoDir, true); err != nil {
+		return err
 	}
 
 	// Compute and store checksums
-	if r.Debug {
-		fmt.Println("Computing checksums after modifications...")
-	}
-	checksums, err := checksum.ComputeDirectory(r.RepoDir)
+	log.Debugf("Computing checksums after modifications...\n")
+	checksums, err := r.computeChecksums(r.RepoDir)
 	if err != nil {
 		return fmt.Errorf("failed to compute checksums: %w", err)
 	}
 
-	if err := checksum.StoreChecksums(r.DB, r.RunID, 3, checksums); err != nil {
+	if err := r.storeChecksums(checksums); err != nil {
 		return fmt.Errorf("failed to store checksums: %w", err)
 	}
 
-	if r.Debug {
-		fmt.Printf("Stored %d checksums for step 3\n", len(checksums))
+	log.Debugf("Stored %d checksums for step %d\n", len(checksums), r.step())
+
+	// Verify the deletions and renames left the expected files
+	state, err := r.expectedState(3)
+	if err != nil {
+		return err
+	}
+	if err := r.verify(r.step(), "expected-files", SeverityError, state.CheckFiles(r.RepoDir)); err != nil {
+		return fmt.Errorf("file verification failed: %w", err)
 	}
 
 	return nil
@@ -427,100 +1207,74 @@ func (r *Runner) Step4_SecondClone() error {
 	ctx := &git.Context{
 		DB:         r.DB,
 		RunID:      r.RunID,
-		StepNumber: 4,
+		StepNumber: r.step(),
 		Debug:      r.Debug,
 		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
 	}
 
-	// Determine the clone URL
-	var cloneURL string
-	if r.Scenario.Protocol == "local" {
-		// For local protocol, use the first repo directory
-		cloneURL = r.RepoDir
-	} else if r.Scenario.ServerURL != "" {
-		// Use the configured server URL
-		cloneURL = r.Scenario.ServerURL
-	} else {
-		return fmt.Errorf("no remote URL configured for cloning")
+	cloneURL, err := r.remoteURL()
+	if err != nil {
+		return err
 	}
 
 	// Clone the repository
-	if r.Debug {
-		fmt.Printf("Cloning from %s to %s...\n", cloneURL, r.Repo2Dir)
-	}
+	log.Debugf("Cloning from %s to %s...\n", cloneURL, r.Repo2Dir)
 	if err := ctx.Clone(cloneURL, r.Repo2Dir); err != nil {
-		return err
-	}
-
-	// Compute checksums in the second clone
-	if r.Debug {
-		fmt.Println("Computing checksums in second clone...")
-	}
-	checksums, err := checksum.ComputeDirectory(r.Repo2Dir)
-	if err != nil {
-		return fmt.Errorf("failed to compute checksums: %w", err)
-	}
-
-	if err := checksum.StoreChecksums(r.DB, r.RunID, 4, checksums); err != nil {
-		return fmt.Errorf("failed to store checksums: %w", err)
+		return err
 	}
 
-	// Compare checksums with step 3
-	if r.Debug {
-		fmt.Println("Comparing checksums with step 3...")
-	}
-	diffs, err := checksum.CompareChecksums(r.DB, r.RunID, 3, 4)
+	// Compute checksums in the second clone
+	log.Debugf("Computing checksums in second clone...\n")
+	checksums, err := r.computeChecksums(r.Repo2Dir)
 	if err != nil {
-		return fmt.Errorf("failed to compare checksums: %w", err)
+		return fmt.Errorf("failed to compute checksums: %w", err)
 	}
 
-	if len(diffs) > 0 {
-		return fmt.Errorf("checksum mismatch: %d differences found between step 3 and step 4", len(diffs))
+	if err := r.storeChecksums(checksums); err != nil {
+		return fmt.Errorf("failed to store checksums: %w", err)
 	}
 
-	if r.Debug {
-		fmt.Printf("✓ Checksums match (%d files)\n", len(checksums))
+	// Compare checksums with the step that last changed the first repository
+	source := r.lastStep("push", "modify", "churn")
+	if err := r.checkChecksumInvariant(source); err != nil {
+		return fmt.Errorf("checksum mismatch: %w", err)
 	}
 
 	// Verify LFS is working in the cloned repository
-	if r.Debug {
-		fmt.Println("Verifying LFS in cloned repository...")
-	}
+	log.Debugf("Verifying LFS in cloned repository...\n")
 
-	// Get list of files that should exist after step 3 modifications
-	// After step 3, we have: pdf1, video2, video3, zip1, zip2_renamed (5 files)
-	// deleted: video1.m4v, video4.ogg
-	v2Files, err := testdata.RealTestFilesV2()
+	// The clone should match the state left by the modifications, if the pipeline made them
+	state, err := r.pipelineState(4)
 	if err != nil {
-		return fmt.Errorf("failed to get v2 files: %w", err)
+		return err
 	}
 
-	var expectedFiles []string
-	for _, f := range v2Files {
-		expectedFiles = append(expectedFiles, f.Name)
+	if err := r.verify(r.step(), "expected-files", SeverityError, state.CheckFiles(r.Repo2Dir)); err != nil {
+		return fmt.Errorf("file verification failed in clone: %w", err)
 	}
-	// Add the renamed file
-	expectedFiles = append(expectedFiles, "zip2_renamed.zip")
 
 	// Verify files are stored as LFS pointers in cloned repo
-	if err := lfsverify.VerifyLFSPointers(r.Repo2Dir, expectedFiles, r.Debug); err != nil {
+	if err := r.verify(r.step(), "lfs-pointers", SeverityError, lfsverify.VerifyLFSPointers(r.Repo2Dir, state.LFS, r.Debug)); err != nil {
 		return fmt.Errorf("LFS pointer verification failed in clone: %w", err)
 	}
 
 	// Verify LFS objects exist in cloned repo
 	// Should have at least the files from step 3 (some may be duplicates from v1/v2)
-	if err := lfsverify.VerifyLFSObjects(r.Repo2Dir, len(expectedFiles), r.Debug); err != nil {
+	if err := r.verify(r.step(), "lfs-objects", SeverityError, lfsverify.VerifyLFSObjects(r.Repo2Dir, len(state.LFS), r.Debug)); err != nil {
 		return fmt.Errorf("LFS objects verification failed in clone: %w", err)
 	}
 
-	// Verify repository sizes
-	if err := lfsverify.VerifyRepositorySizes(r.Repo2Dir, r.Debug); err != nil {
-		return fmt.Errorf("repository size verification failed in clone: %w", err)
+	// Verify the smudged files match their pointer OIDs
+	if err := r.verify(r.step(), "lfs-content", SeverityError, lfsverify.VerifyLFSContent(r.Repo2Dir, r.poolOptions(), r.Debug)); err != nil {
+		return fmt.Errorf("LFS content verification failed in clone: %w", err)
 	}
 
-	if r.Debug {
-		fmt.Println("✓ LFS verification passed in clone")
-	}
+	// Verify repository sizes
+	// The size comparison is a heuristic, so a failure is only a warning
+	r.verify(r.step(), "repository-sizes", SeverityWarning, lfsverify.VerifyRepositorySizes(r.Repo2Dir, r.Debug))
+
+	log.Debugf("%s LFS verification passed in clone\n", term.OK())
 
 	return nil
 }
@@ -530,15 +1284,19 @@ func (r *Runner) Step5_SecondClientPush() error {
 	ctx := &git.Context{
 		DB:         r.DB,
 		RunID:      r.RunID,
-		StepNumber: 5,
+		StepNumber: r.step(),
 		Debug:      r.Debug,
 		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
 	}
 
-	// Create a new file in the second clone
-	if r.Debug {
-		fmt.Println("Creating new file in second clone...")
+	// The second client commits, so it needs an identity
+	if err := r.configureUser(ctx, r.Repo2Dir); err != nil {
+		return err
 	}
+
+	// Create a new file in the second clone
+	log.Debugf("Creating new file in second clone...\n")
 	newFilePath := filepath.Join(r.Repo2Dir, "README.md")
 	content := []byte("# LFS Test Repository\n\nThis file was added during Step 5 testing.\n")
 	if err := os.WriteFile(newFilePath, content, 0644); err != nil {
@@ -546,97 +1304,83 @@ func (r *Runner) Step5_SecondClientPush() error {
 	}
 
 	// Add the new file
-	if r.Debug {
-		fmt.Println("Adding new file to git...")
-	}
+	log.Debugf("Adding new file to git...\n")
 	if err := ctx.Add(r.Repo2Dir, "README.md"); err != nil {
 		return err
 	}
 
 	// Commit the change
-	if r.Debug {
-		fmt.Println("Committing new file...")
-	}
+	log.Debugf("Committing new file...\n")
 	if err := ctx.Commit(r.Repo2Dir, "Add README from second client"); err != nil {
 		return err
 	}
 
-	// Push changes (if remote is configured)
-	if r.Scenario.Protocol != "local" && r.Scenario.ServerURL != "" {
-		if r.Debug {
-			fmt.Println("Pushing changes to remote...")
-		}
-		// TODO: Set up remote first
-		// if err := ctx.Push(r.Repo2Dir, "origin", "main"); err != nil {
-		// 	return err
-		// }
+	if err := r.pushOrigin(ctx, r.Repo2Dir); err != nil {
+		return err
 	}
 
-	// Compute and store checksums
-	if r.Debug {
-		fmt.Println("Computing checksums after changes...")
+	// The pushed objects must have reached the server's storage backend
+	if err := r.verifyStorage(r.step(), r.Repo2Dir); err != nil {
+		return err
+	}
+	if err := r.verifyHistoryObjects(r.step(), r.Repo2Dir, false); err != nil {
+		return err
 	}
-	checksums, err := checksum.ComputeDirectory(r.Repo2Dir)
+
+	// Compute and store checksums
+	log.Debugf("Computing checksums after changes...\n")
+	checksums, err := r.computeChecksums(r.Repo2Dir)
 	if err != nil {
 		return fmt.Errorf("failed to compute checksums: %w", err)
 	}
 
-	if err := checksum.StoreChecksums(r.DB, r.RunID, 5, checksums); err != nil {
+	if err := r.storeChecksums(checksums); err != nil {
 		return fmt.Errorf("failed to store checksums: %w", err)
 	}
 
-	if r.Debug {
-		fmt.Printf("Stored %d checksums for step 5\n", len(checksums))
-	}
+	log.Debugf("Stored %d checksums for step %d\n", len(checksums), r.step())
 
 	return nil
 }
 
 // Step6_FirstClientPull: Pull changes to first client
 func (r *Runner) Step6_FirstClientPull() error {
-	// Pull changes from remote (if configured)
-	if r.Scenario.Protocol != "local" && r.Scenario.ServerURL != "" {
-		if r.Debug {
-			fmt.Println("Pulling changes from remote...")
-		}
-		// TODO: Set up remote and use ctx.Pull
-		// ctx := &git.Context{DB: r.DB, RunID: r.RunID, StepNumber: 6, Debug: r.Debug, WorkDir: r.WorkDir}
-		// if err := ctx.Pull(r.RepoDir); err != nil {
-		// 	return err
-		// }
-		if r.Debug {
-			fmt.Println("  (Skipping pull - remote not yet configured)")
-		}
-	} else if r.Scenario.Protocol == "local" {
-		if r.Debug {
-			fmt.Println("Pulling changes from local repo...")
-		}
-		// For local protocol, we need to manually sync
-		// In real scenario, this would use git pull from the first repo
-		// For now, we'll just note this needs to be implemented
-		if r.Debug {
-			fmt.Println("  (Skipping local pull - requires bare repo setup)")
+	ctx := &git.Context{
+		DB:         r.DB,
+		RunID:      r.RunID,
+		StepNumber: r.step(),
+		Debug:      r.Debug,
+		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
+	}
+
+	// Pull the second client's changes from origin
+	if r.hasOrigin() {
+		log.Debugf("Pulling changes from origin...\n")
+		if err := ctx.Pull(r.RepoDir); err != nil {
+			return err
 		}
 	}
 
 	// Compute checksums in first clone
-	if r.Debug {
-		fmt.Println("Computing checksums in first clone...")
-	}
-	checksums, err := checksum.ComputeDirectory(r.RepoDir)
+	log.Debugf("Computing checksums in first clone...\n")
+	checksums, err := r.computeChecksums(r.RepoDir)
 	if err != nil {
 		return fmt.Errorf("failed to compute checksums: %w", err)
 	}
 
-	if err := checksum.StoreChecksums(r.DB, r.RunID, 6, checksums); err != nil {
+	if err := r.storeChecksums(checksums); err != nil {
 		return fmt.Errorf("failed to store checksums: %w", err)
 	}
+	log.Debugf("Stored %d checksums for step %d\n", len(checksums), r.step())
 
-	// Note: We can't compare with step 5 until pull is working
-	// The checksums should match step 5 after successful pull
-	if r.Debug {
-		fmt.Printf("Stored %d checksums for step 6\n", len(checksums))
-		fmt.Println("  Note: Checksum comparison with step 5 requires working pull")
+	// After the pull, the first repository must match the second client's
+	source := r.lastStep("client2-push")
+	if !r.hasOrigin() || source == 0 {
+		return nil
+	}
+	if err := r.checkChecksumInvariant(source); err != nil {
+		return fmt.Errorf("checksum mismatch after pull: %w", err)
 	}
 
 	return nil
@@ -647,95 +1391,76 @@ func (r *Runner) Step7_Untrack() error {
 	ctx := &git.Context{
 		DB:         r.DB,
 		RunID:      r.RunID,
-		StepNumber: 7,
+		StepNumber: r.step(),
 		Debug:      r.Debug,
 		WorkDir:    r.WorkDir,
+		Exec:       r.exec,
 	}
 
 	// Untrack patterns from LFS
-	if r.Debug {
-		fmt.Println("Untracking patterns from LFS...")
-	}
-	patterns := []string{"*.pdf", "*.mov", "*.avi", "*.ogg", "*.m4v", "*.zip"}
-	for _, pattern := range patterns {
+	log.Debugf("Untracking patterns from LFS...\n")
+	for _, pattern := range r.Scenario.Patterns() {
 		if err := ctx.LFSUntrack(r.RepoDir, pattern); err != nil {
 			return err
 		}
 	}
 
 	// Add .gitattributes changes
-	if r.Debug {
-		fmt.Println("Adding .gitattributes changes...")
-	}
+	log.Debugf("Adding .gitattributes changes...\n")
 	if err := ctx.Add(r.RepoDir, ".gitattributes"); err != nil {
 		return err
 	}
 
 	// Commit the untrack changes (required before migrate export)
-	if r.Debug {
-		fmt.Println("Committing LFS untrack...")
-	}
+	log.Debugf("Committing LFS untrack...\n")
 	if err := ctx.Commit(r.RepoDir, "Untrack files from LFS"); err != nil {
 		return err
 	}
 
 	// Use git lfs migrate to convert files back to regular git
 	// This requires a clean working directory (no uncommitted changes)
-	if r.Debug {
-		fmt.Println("Migrating files out of LFS...")
-	}
+	log.Debugf("Migrating files out of LFS...\n")
 	if err := ctx.LFSMigrate(r.RepoDir); err != nil {
 		return err
 	}
 
 	// Verify files are NO LONGER stored as LFS pointers
-	if r.Debug {
-		fmt.Println("Verifying files are no longer in LFS...")
-	}
+	log.Debugf("Verifying files are no longer in LFS...\n")
 
-	// Get list of files that should still exist (not deleted)
-	v2Files, err := testdata.RealTestFilesV2()
+	state, err := r.pipelineState(7)
 	if err != nil {
-		return fmt.Errorf("failed to get v2 files: %w", err)
-	}
-
-	var expectedFiles []string
-	for _, f := range v2Files {
-		expectedFiles = append(expectedFiles, f.Name)
+		return err
 	}
-	// Add the renamed file
-	expectedFiles = append(expectedFiles, "zip2_renamed.zip")
 
 	// Verify files are NOT LFS pointers anymore
-	if err := lfsverify.VerifyNotLFSPointers(r.RepoDir, expectedFiles, r.Debug); err != nil {
+	if err := r.verify(r.step(), "not-lfs-pointers", SeverityError, lfsverify.VerifyNotLFSPointers(r.RepoDir, state.NotLFS, r.Debug)); err != nil {
 		return fmt.Errorf("LFS migration verification failed: %w", err)
 	}
 
-	if r.Debug {
-		fmt.Println("✓ Files successfully migrated out of LFS")
-	}
+	log.Debugf("%s Files successfully migrated out of LFS\n", term.OK())
 
 	// Compute final checksums
-	if r.Debug {
-		fmt.Println("Computing final checksums...")
-	}
-	checksums, err := checksum.ComputeDirectory(r.RepoDir)
+	log.Debugf("Computing final checksums...\n")
+	checksums, err := r.computeChecksums(r.RepoDir)
 	if err != nil {
 		return fmt.Errorf("failed to compute checksums: %w", err)
 	}
 
-	if err := checksum.StoreChecksums(r.DB, r.RunID, 7, checksums); err != nil {
+	if err := r.storeChecksums(checksums); err != nil {
 		return fmt.Errorf("failed to store checksums: %w", err)
 	}
 
-	if r.Debug {
-		fmt.Printf("Stored %d checksums for step 7\n", len(checksums))
-		fmt.Println("✓ Files successfully untracked from LFS")
-	}
+	log.Debugf("Stored %d checksums for step %d\n", len(checksums), r.step())
+	log.Debugf("%s Files successfully untracked from LFS\n", term.OK())
 
 	return nil
 }
 
+// configureUser sets the git identity of the commits made in a clone of the repository
+func (r *Runner) configureUser(ctx *git.Context, repoDir string) error {
+	return ctx.ConfigUser(repoDir, "LFS Test", "test@example.com")
+}
+
 // generateREADME creates an evaluation README.md file
 func (r *Runner) generateREADME() error {
 	readmePath := filepath.Join(r.RepoDir, "README.md")
@@ -804,17 +1529,28 @@ Generated automatically by lfst-scenario command.
 		return fmt.Errorf("failed to write README: %w", err)
 	}
 
-	if r.Debug {
-		fmt.Printf("  ✓ Created README.md\n")
-	}
+	log.Debugf("  %s Created README.md\n", term.OK())
 
 	return nil
 }
 
 // validatePrerequisites checks if all prerequisites are met before starting scenario
 func (r *Runner) validatePrerequisites() error {
-	if r.Debug {
-		fmt.Println("Validating prerequisites...")
+	log.Debugf("Validating prerequisites...\n")
+
+	if _, err := r.pipeline(); err != nil {
+		return err
+	}
+	if _, err := checksum.NewStore(r.ChecksumStore, r.DB, r.ManifestDir(), r.ChecksumConflict); err != nil {
+		return err
+	}
+
+	// Fail before touching anything if offline mode forbids this scenario
+	if err := r.checkOffline(); err != nil {
+		return err
+	}
+	if err := r.checkShaper(); err != nil {
+		return err
 	}
 
 	// Check if git is available
@@ -822,21 +1558,17 @@ func (r *Runner) validatePrerequisites() error {
 	if result.Error != nil || result.ExitCode != 0 {
 		return fmt.Errorf("git is not installed or not in PATH")
 	}
-	if r.Debug {
-		fmt.Println("  ✓ git is available")
-	}
+	log.Debugf("  %s git is available\n", term.OK())
 
 	// Check if git-lfs is available
 	result = timing.Run("git", []string{"lfs", "version"}, nil)
 	if result.Error != nil || result.ExitCode != 0 {
 		return fmt.Errorf("git-lfs is not installed or not in PATH\n\nInstall with: apt-get install git-lfs")
 	}
-	if r.Debug {
-		fmt.Println("  ✓ git-lfs is available")
-	}
+	log.Debugf("  %s git-lfs is available\n", term.OK())
 
 	// Try to get test data path
-	dataPath, err := testdata.GetTestDataPath()
+	dataPath, err := r.testDataPath()
 	if err != nil {
 		return fmt.Errorf("test data not found: %w\n\nPlease set LFS_TEST_DATA environment variable or place data in standard locations.\nSee: https://www.mslinn.com/git/5600-git-lfs-evaluation.html#git_lfs_test_data", err)
 	}
@@ -849,13 +1581,11 @@ func (r *Runner) validatePrerequisites() error {
 		if result.Error != nil || result.ExitCode != 0 {
 			return fmt.Errorf("rsync is not installed or not in PATH\n\nRsync is required for remote test data.\nInstall with: apt-get install rsync")
 		}
-		if r.Debug {
-			fmt.Println("  ✓ rsync is available (for remote test data)")
-		}
+		log.Debugf("  %s rsync is available (for remote test data)\n", term.OK())
 	}
 
 	// Validate that v1 test files actually exist
-	files, err := testdata.RealTestFiles()
+	files, err := r.testFiles()
 	if err != nil {
 		return fmt.Errorf("failed to get test file list: %w", err)
 	}
@@ -869,7 +1599,7 @@ func (r *Runner) validatePrerequisites() error {
 	if isRemote {
 		// For remote, check via SSH
 		remotePath, _ := testdata.ParseRemotePath(firstFile.SourcePath)
-		result := timing.Run("ssh", []string{remotePath.Host, "test", "-f", remotePath.Path}, nil)
+		result := timing.Run("ssh", append(sshutil.Args(remotePath.Host), "test", "-f", remotePath.Path), nil)
 		if result.Error != nil || result.ExitCode != 0 {
 			return fmt.Errorf("test data directory found at %s but files are missing\n\nExpected file not found: %s\nPlease ensure test data files are present in v1/ subdirectory.\nSee: https://www.mslinn.com/git/5600-git-lfs-evaluation.html#git_lfs_test_data", dataPath, firstFile.SourcePath)
 		}
@@ -880,18 +1610,104 @@ func (r *Runner) validatePrerequisites() error {
 		}
 	}
 
-	if r.Debug {
-		fmt.Printf("  ✓ Test data found at: %s (%d files)\n", dataPath, len(files))
+	log.Debugf("  %s Test data found at: %s (%d files)\n", term.OK(), dataPath, len(files))
+
+	if _, err := r.resolveAssertions(); err != nil {
+		return err
+	}
+	if _, err := r.resolveAllowedDifferences(); err != nil {
+		return err
+	}
+	if r.TransferAdapter != "" {
+		if _, err := r.adapterSettings(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// checkDiskSpace checks that the work directory has room for every version of the test
+// data in both clones, the server store and the snapshots, so a full disk stops the run
+// before it starts rather than mid-push
+func (r *Runner) checkDiskSpace() error {
+	needs := preflight.SpaceNeeds{LocalStore: r.storesLocally()}
+	for _, version := range []string{"v1", "v2", "v3"} {
+		files, err := r.testVersion(version)
+		if err != nil {
+			return err
+		}
+		needs.Versions = append(needs.Versions, files)
+	}
+	// Copy-on-write snapshots share their blocks with the clones
+	if r.Snapshots == SnapshotTar {
+		needs.Snapshots = r.stepCount()
+	}
+	need, err := preflight.NeededSpace(needs)
+	if err != nil {
+		// A missing file fails step 1 with a clearer message
+		log.Warnf("cannot estimate the disk space the test data needs: %v\n", err)
+		return nil
+	}
+	if err := preflight.CheckDiskSpace(r.WorkDir, need); err != nil {
+		return fmt.Errorf("%w\n\nFree up space or choose another work directory with --work-dir", err)
+	}
+	log.Debugf("  %s Disk space for about %s of test data and repositories in %s\n", term.OK(), testdata.FormatSize(need), r.WorkDir)
+	return nil
+}
+
+// storesLocally reports whether the server keeps LFS objects in the work directory: a
+// bare repository served from the run directory without an LFS server of its own
+func (r *Runner) storesLocally() bool {
+	if !r.Scenario.UsesBareGitServer() || r.Scenario.ServerURL != "" {
+		return false
+	}
+	return r.Scenario.Protocol != "ssh" || r.SSHGitHost == ""
+}
+
+// sampleNetwork reads the counters of the traffic interface, choosing it on first use.
+// It returns false if counters are unavailable, e.g. on platforms without /proc/net/dev.
+func (r *Runner) sampleNetwork() (netstat.Counters, bool) {
+	if !netstat.Available() {
+		return netstat.Counters{}, false
+	}
+
+	if r.Interface == "" {
+		iface, err := netstat.DefaultInterface()
+		if err != nil {
+			log.Warnf("cannot determine network interface: %v\n", err)
+			return netstat.Counters{}, false
+		}
+		r.Interface = iface
+	}
+
+	c, err := netstat.Read(r.Interface)
+	if err != nil {
+		log.Warnf("cannot sample network traffic: %v\n", err)
+		return netstat.Counters{}, false
+	}
+	return c, true
+}
+
+// recordTraffic stores the bytes received and sent on the traffic interface since before
+func (r *Runner) recordTraffic(result *database.StepResult, before netstat.Counters) {
+	after, ok := r.sampleNetwork()
+	if !ok {
+		return
+	}
+
+	d := after.Sub(before)
+	rx, tx := int64(d.RxBytes), int64(d.TxBytes)
+	result.Interface = r.Interface
+	result.RxBytes = &rx
+	result.TxBytes = &tx
+
+	log.Debugf("  Network (%s): %.1f MB received, %.1f MB sent\n", r.Interface, float64(rx)/1024/1024, float64(tx)/1024/1024)
+}
+
 // cleanup removes working directories after failure
 func (r *Runner) cleanup() error {
-	if r.Debug {
-		fmt.Println("\nCleaning up working directories...")
-	}
+	log.Debugf("\nCleaning up working directories...\n")
 
 	var errs []error
 
@@ -899,8 +1715,8 @@ func (r *Runner) cleanup() error {
 	if _, err := os.Stat(r.RepoDir); err == nil {
 		if err := os.RemoveAll(r.RepoDir); err != nil {
 			errs = append(errs, fmt.Errorf("failed to remove %s: %w", r.RepoDir, err))
-		} else if r.Debug {
-			fmt.Printf("  ✓ Removed %s\n", r.RepoDir)
+		} else {
+			log.Debugf("  %s Removed %s\n", term.OK(), r.RepoDir)
 		}
 	}
 
@@ -908,8 +1724,38 @@ func (r *Runner) cleanup() error {
 	if _, err := os.Stat(r.Repo2Dir); err == nil {
 		if err := os.RemoveAll(r.Repo2Dir); err != nil {
 			errs = append(errs, fmt.Errorf("failed to remove %s: %w", r.Repo2Dir, err))
-		} else if r.Debug {
-			fmt.Printf("  ✓ Removed %s\n", r.Repo2Dir)
+		} else {
+			log.Debugf("  %s Removed %s\n", term.OK(), r.Repo2Dir)
+		}
+	}
+
+	// Remove the bare repository of a bare git server on another host
+	if r.gitServer != nil && r.gitServer.Remote() {
+		if err := r.gitServer.Remove(); err != nil {
+			errs = append(errs, err)
+		} else {
+			log.Debugf("  %s Removed %s\n", term.OK(), r.gitServer.URL())
+		}
+	}
+
+	// Remove the bare repository of a bare git server
+	serverDir := filepath.Join(r.RunDir, ServerRepoName)
+	if _, err := os.Stat(serverDir); err == nil {
+		if err := os.RemoveAll(serverDir); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", serverDir, err))
+		} else {
+			log.Debugf("  %s Removed %s\n", term.OK(), serverDir)
+		}
+	}
+
+	if err := r.removeSnapshots(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove snapshots: %w", err))
+	}
+
+	// The run's own directory also holds the clones of concurrent clients
+	if r.RunDir != r.WorkDir && len(errs) == 0 {
+		if err := os.RemoveAll(r.RunDir); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", r.RunDir, err))
 		}
 	}
 
@@ -919,3 +1765,313 @@ func (r *Runner) cleanup() error {
 
 	return nil
 }
+
+// testCorpus returns the corpus the scenario copies, opening it on first use
+func (r *Runner) testCorpus() (*testdata.Corpus, error) {
+	if r.corpus != nil {
+		return r.corpus, nil
+	}
+
+	root := r.TestDataPath
+	if root == "" {
+		var err error
+		if root, err = testdata.GetTestDataPath(); err != nil {
+			return nil, err
+		}
+	}
+	corpus, err := testdata.OpenCorpus(root, r.Corpus)
+	if err != nil {
+		return nil, err
+	}
+	r.corpus = corpus
+	return corpus, nil
+}
+
+// useCorpus makes a resumed or rerun run copy the corpus it started with, which Execute
+// recorded as its corpus tag
+func (r *Runner) useCorpus(run *database.TestRun) error {
+	tags, err := r.DB.ListRunTags(run.ID)
+	if err != nil {
+		return err
+	}
+	r.Corpus, r.corpus = "", nil
+	for _, tag := range tags {
+		if tag.Key == "corpus" {
+			r.Corpus = tag.Value
+		}
+	}
+	return nil
+}
+
+// testDataPath returns the directory of the corpus, holding its manifest.yaml, its
+// fixture.yaml and a directory per version of the test files
+func (r *Runner) testDataPath() (string, error) {
+	corpus, err := r.testCorpus()
+	if err != nil {
+		return "", err
+	}
+	return corpus.Path, nil
+}
+
+// testFiles returns the v1 test files copied in step 1
+func (r *Runner) testFiles() ([]testdata.FileSpec, error) {
+	return r.testVersion("v1")
+}
+
+// testFilesV2 returns the v2 test files copied in step 3
+func (r *Runner) testFilesV2() ([]testdata.FileSpec, error) {
+	return r.testVersion("v2")
+}
+
+// testFilesV3 returns the v3 test files step 3 copies after committing v2, or none if
+// the corpus has no v3
+func (r *Runner) testFilesV3() ([]testdata.FileSpec, error) {
+	return r.testVersion("v3")
+}
+
+// testVersion returns the test files of a version of the corpus
+func (r *Runner) testVersion(version string) ([]testdata.FileSpec, error) {
+	corpus, err := r.testCorpus()
+	if err != nil {
+		return nil, err
+	}
+	return corpus.Files(version), nil
+}
+
+// resolveFixture returns the scenario's fixture, the data set's fixture.yaml, or the default
+func (r *Runner) resolveFixture() (*Fixture, error) {
+	if r.fixture != nil {
+		return r.fixture, nil
+	}
+
+	r.fixture = r.Scenario.Fixture
+	if r.fixture == nil {
+		dataPath, err := r.testDataPath()
+		if err != nil {
+			return nil, err
+		}
+		if r.fixture, err = LoadFixture(dataPath); err != nil {
+			return nil, fmt.Errorf("failed to load fixture: %w", err)
+		}
+	}
+	if r.fixture == nil {
+		r.fixture = DefaultFixture()
+	}
+	return r.fixture, nil
+}
+
+// expectedState returns the repository state the fixture expects after step
+func (r *Runner) expectedState(step int) (*ExpectedState, error) {
+	fixture, err := r.resolveFixture()
+	if err != nil {
+		return nil, err
+	}
+
+	v1, err := r.testFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test files: %w", err)
+	}
+	v2, err := r.testFilesV2()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get v2 test files: %w", err)
+	}
+	v3, err := r.testFilesV3()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get v3 test files: %w", err)
+	}
+
+	// Step 3 deletes and renames files after copying both later versions
+	state := fixture.State(step, fileNames(v1), fileNames(append(v2, v3...)))
+	if _, declared := fixture.Expected[step]; !declared {
+		// Only the files matching the scenario's patterns are stored in LFS; the others
+		// are committed as regular files
+		tracked := checksum.Filter{Include: r.Scenario.Patterns()}
+		var lfs []string
+		for _, name := range state.LFS {
+			if tracked.Match(name) {
+				lfs = append(lfs, name)
+			} else {
+				state.NotLFS = append(state.NotLFS, name)
+			}
+		}
+		state.LFS = lfs
+	}
+	return state, nil
+}
+
+// pipelineState returns the state expected after the running step, which stands in for
+// standard step std. In a pipeline without an earlier modify step the v1 files stay as
+// they were pushed, and after untracking they are no longer in LFS.
+func (r *Runner) pipelineState(std int) (*ExpectedState, error) {
+	if std <= 2 || r.lastStep("modify") > 0 {
+		return r.expectedState(std)
+	}
+
+	state, err := r.expectedState(2)
+	if err != nil {
+		return nil, err
+	}
+	if std >= 7 {
+		return &ExpectedState{Files: state.Files, NotLFS: state.LFS}, nil
+	}
+	return state, nil
+}
+
+// fileNames returns the names of the given test files
+func fileNames(specs []testdata.FileSpec) []string {
+	names := make([]string, len(specs))
+	for i, spec := range specs {
+		names[i] = spec.Name
+	}
+	return names
+}
+
+// poolOptions returns the worker pool settings for checksumming and content verification,
+// reporting progress only in debug mode
+func (r *Runner) poolOptions() workerpool.Options {
+	opts := workerpool.Options{Workers: r.Workers}
+	if r.Debug {
+		opts.Progress = os.Stdout
+	}
+	return opts
+}
+
+// computeChecksums checksums the files of dir, taking those of the files that have not
+// changed since an earlier step from the run's checksum cache, unless NoChecksumCache is set
+func (r *Runner) computeChecksums(dir string) ([]*checksum.FileChecksum, error) {
+	if r.NoChecksumCache {
+		return checksum.ComputeDirectoryWith(dir, r.ChecksumExclude, r.poolOptions())
+	}
+	if r.checksumCache == nil {
+		cache, err := checksum.LoadCache(filepath.Join(r.RunDir, checksum.CacheFileName))
+		if err != nil {
+			// A damaged cache only costs time
+			log.Warnf("%v; reading every file\n", err)
+			cache = checksum.NewCache()
+		}
+		r.checksumCache = cache
+	}
+
+	hits, misses := r.checksumCache.Stats()
+	checksums, err := checksum.ComputeDirectoryCached(dir, r.ChecksumExclude, r.checksumCache, r.poolOptions())
+	if err != nil {
+		return nil, err
+	}
+	newHits, newMisses := r.checksumCache.Stats()
+	log.Debugf("Checksum cache: %d files unchanged, %d read\n", newHits-hits, newMisses-misses)
+	if err := r.checksumCache.Save(); err != nil {
+		log.Warnf("%v\n", err)
+	}
+	return checksums, nil
+}
+
+// ManifestDir returns the directory of the run's checksum manifests
+func (r *Runner) ManifestDir() string {
+	return filepath.Join(r.RunDir, checksum.ManifestDirName)
+}
+
+// storeChecksums stores the checksums of the running step in the run's checksum store
+func (r *Runner) storeChecksums(checksums []*checksum.FileChecksum) error {
+	store, err := checksum.NewStore(r.ChecksumStore, r.DB, r.ManifestDir(), r.ChecksumConflict)
+	if err != nil {
+		return err
+	}
+	return store.Store(r.RunID, r.step(), checksums)
+}
+
+// compareChecksums compares the checksums of an earlier step with those of the running step
+func (r *Runner) compareChecksums(source int) ([]*checksum.Difference, error) {
+	store, err := checksum.NewStore(r.ChecksumStore, r.DB, r.ManifestDir(), r.ChecksumConflict)
+	if err != nil {
+		return nil, err
+	}
+	return checksum.CompareStored(store, r.RunID, source, r.step())
+}
+
+// remoteURL returns the URL that second and later clients clone
+func (r *Runner) remoteURL() (string, error) {
+	switch {
+	case r.Scenario.UsesBareGitServer() && r.gitServer != nil:
+		// Clone the bare repository created in step 1
+		return r.gitServer.URL(), nil
+	case r.Scenario.UsesHostedGit():
+		// Clone the repository created on the git host in step 1
+		return r.hostedCloneURL()
+	case r.Scenario.ServerURL != "":
+		// Use the configured server URL
+		return r.Scenario.ServerURL, nil
+	default:
+		return "", fmt.Errorf("no remote URL configured for cloning")
+	}
+}
+
+// hostedCloneURL returns the clone URL of the hosted repository, asking the host
+// when step 1 ran in an earlier process (e.g. before a resume)
+func (r *Runner) hostedCloneURL() (string, error) {
+	if r.HostedURL != "" {
+		return r.HostedURL, nil
+	}
+	host, err := githost.New(r.Scenario.GitServer, nil)
+	if err != nil {
+		return "", err
+	}
+	return host.CloneURL(r.Scenario.RepoName)
+}
+
+// lfsEndpoint returns the LFS API endpoint of the run: the configured server, or the one a
+// hosted repository serves itself. It is empty when git-lfs has to derive it from the remote.
+func (r *Runner) lfsEndpoint() string {
+	if r.Scenario.ServerURL != "" {
+		return r.Scenario.ServerURL
+	}
+	if !r.Scenario.UsesHostedGit() {
+		return ""
+	}
+	host, err := githost.New(r.Scenario.GitServer, nil)
+	if err != nil {
+		return ""
+	}
+	endpoint, ok := host.(githost.LFSEndpoint)
+	if !ok {
+		return ""
+	}
+	url, err := endpoint.LFSURL(r.Scenario.RepoName)
+	if err != nil {
+		return ""
+	}
+	return url
+}
+
+// authenticateHost passes the git host's credentials to every git command of the run.
+// The returned function stops passing them, so they do not reach the scenarios after it.
+func (r *Runner) authenticateHost() (func(), error) {
+	if !r.Scenario.UsesHostedGit() {
+		return func() {}, nil
+	}
+	host, err := githost.New(r.Scenario.GitServer, nil)
+	if err != nil {
+		return nil, err
+	}
+	auth, ok := host.(githost.Authenticator)
+	if !ok {
+		return func() {}, nil
+	}
+	settings := auth.GitConfig()
+	git.ExportConfig(settings)
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	return func() { git.UnexportConfig(keys...) }, nil
+}
+
+// checkOffline returns an error if offline mode is enabled and the scenario needs external services
+func (r *Runner) checkOffline() error {
+	if r.Offline && r.Scenario.UsesExternalServices() {
+		return fmt.Errorf("scenario %d (%s) uses git server '%s', but offline mode is enabled\n\n"+
+			"Offline mode never contacts GitHub or other external services.\n"+
+			"Choose a scenario with a bare git server, or disable offline mode (lfst-config set offline false)",
+			r.Scenario.ID, r.Scenario.Name, r.Scenario.GitServer)
+	}
+	return nil
+}
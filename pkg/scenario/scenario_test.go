@@ -0,0 +1,428 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/githost"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+)
+
+// requireGitLFS skips the test unless git and git-lfs are installed
+func requireGitLFS(t *testing.T) {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("skipping end-to-end scenario in short mode")
+	}
+	if err := exec.Command("git", "lfs", "version").Run(); err != nil {
+		t.Skip("git-lfs is not installed")
+	}
+}
+
+// newMiniRunner creates a runner for a local bare-repo scenario backed by the mini corpus
+func newMiniRunner(t *testing.T) *Runner {
+	t.Helper()
+
+	workDir := t.TempDir()
+	dataDir := filepath.Join(workDir, "mini-data")
+	if err := testdata.GenerateMiniCorpus(dataDir); err != nil {
+		t.Fatalf("GenerateMiniCorpus failed: %v", err)
+	}
+
+	db, err := database.Open(filepath.Join(workDir, "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	scen := &Scenario{ID: 1, Name: "Bare repo - local", ServerType: "bare", Protocol: "local", GitServer: "bare"}
+	runner := NewRunner(scen, db, workDir, false, false)
+	runner.TestDataPath = dataDir
+	return runner
+}
+
+func TestMiniScenarioEndToEnd(t *testing.T) {
+	requireGitLFS(t)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+
+	runner := newMiniRunner(t)
+	if err := runner.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	run, err := runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if run.Status != "completed" {
+		t.Errorf("Run status = %s, want completed", run.Status)
+	}
+
+	results, err := runner.DB.ListStepResults(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListStepResults failed: %v", err)
+	}
+	if len(results) != 7 {
+		t.Fatalf("Expected 7 step results, got %d", len(results))
+	}
+	for _, sr := range results {
+		if sr.Status != "completed" {
+			t.Errorf("Step %d status = %s, want completed", sr.StepNumber, sr.Status)
+		}
+	}
+
+	for step := 1; step <= 7; step++ {
+		checksums, err := runner.DB.ListChecksums(runner.RunID, step)
+		if err != nil {
+			t.Fatalf("ListChecksums failed: %v", err)
+		}
+		if len(checksums) == 0 {
+			t.Errorf("Step %d recorded no checksums", step)
+		}
+	}
+
+	ops, err := runner.DB.ListOperations(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListOperations failed: %v", err)
+	}
+	for _, op := range ops {
+		if op.Status != "success" {
+			t.Errorf("Operation %s in step %d failed: %s", op.Operation, op.StepNumber, op.Error)
+		}
+	}
+
+	sizes, err := runner.DB.ListRepositorySizes(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListRepositorySizes failed: %v", err)
+	}
+	measured := make(map[int]bool)
+	for _, rs := range sizes {
+		measured[rs.StepNumber] = true
+	}
+	for step := 1; step <= 7; step++ {
+		if !measured[step] {
+			t.Errorf("Step %d recorded no repository sizes", step)
+		}
+	}
+
+	// The run metadata is committed, so it travels with every push
+	out, err := exec.Command("git", "-C", runner.RepoDir, "ls-files", RunMetadataName).Output()
+	if err != nil || len(out) == 0 {
+		t.Errorf("%s is not committed: %v", RunMetadataName, err)
+	}
+}
+
+func TestMaxDurationTimesOutRun(t *testing.T) {
+	runner := newVerifyRunner(t)
+	runner.MaxDuration = time.Nanosecond
+
+	run, err := runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if err := runner.runSteps(context.Background(), run, nil); !errors.Is(err, ErrTimedOut) {
+		t.Fatalf("runSteps error = %v, want ErrTimedOut", err)
+	}
+
+	run, err = runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if run.Status != "timed-out" || run.CompletedAt == nil {
+		t.Errorf("run status = %s, completed at %v; want timed-out with a completion time", run.Status, run.CompletedAt)
+	}
+	if !strings.Contains(run.Notes, "in step 1 with 0 of 7 steps completed") {
+		t.Errorf("run notes should record how far it got: %s", run.Notes)
+	}
+}
+
+func TestTrackPatterns(t *testing.T) {
+	runner := newMiniRunner(t)
+	if ext, tracked := runner.clientFileExt(); ext != ".pdf" || !tracked {
+		t.Errorf("clientFileExt() = %s, %v with the default patterns", ext, tracked)
+	}
+
+	// Only the PDFs are stored in LFS; the other files are committed as regular files
+	runner.Scenario.TrackPatterns = []string{"*.pdf"}
+	state, err := runner.expectedState(2)
+	if err != nil {
+		t.Fatalf("expectedState failed: %v", err)
+	}
+	if len(state.LFS) == 0 || len(state.NotLFS) == 0 {
+		t.Fatalf("expected LFS and regular files, got %+v", state)
+	}
+	for _, name := range state.LFS {
+		if !strings.HasSuffix(name, ".pdf") {
+			t.Errorf("%s is expected in LFS without a matching pattern", name)
+		}
+	}
+	for _, name := range state.NotLFS {
+		if strings.HasSuffix(name, ".pdf") {
+			t.Errorf("%s is expected outside LFS although *.pdf is tracked", name)
+		}
+	}
+
+	// Concurrent clients need a file name the patterns track
+	runner.Scenario.TrackPatterns = []string{"models/**", "*.onnx"}
+	if name := runner.clientFileName(3); name != "client03.onnx" {
+		t.Errorf("clientFileName(3) = %s, want client03.onnx", name)
+	}
+	runner.Scenario.TrackPatterns = []string{"models/**"}
+	if ext, tracked := runner.clientFileExt(); ext != untrackedClientExt || tracked {
+		t.Errorf("clientFileExt() = %s, %v without a *.EXT pattern", ext, tracked)
+	}
+
+	if err := ValidateTrackPatterns([]string{"*.psd", "[a-"}); err == nil {
+		t.Error("ValidateTrackPatterns should reject a malformed pattern")
+	}
+	if err := ValidateTrackPatterns([]string{"*.psd", " "}); err == nil {
+		t.Error("ValidateTrackPatterns should reject an empty pattern")
+	}
+}
+
+func TestCorpus(t *testing.T) {
+	// A corpus with a v3 and its own fixture, next to the standard data of the root
+	runner := newMiniRunner(t)
+	small := filepath.Join(runner.TestDataPath, "small")
+	files := map[string]string{
+		"v1/a.pdf":            "a v1",
+		"v1/b.zip":            "b v1",
+		"v2/a.pdf":            "a v2",
+		"v3/a.pdf":            "a v3",
+		"v3/c.zip":            "c v3",
+		testdata.ManifestName: "v1: [a.pdf, b.zip]\nv2: [a.pdf]\nv3: [a.pdf, c.zip]\n",
+		FixtureName:           "deletions: [b.zip]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(small, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	runner.Corpus = "small"
+	runner.Pipeline = []string{"setup", "push", "modify"}
+
+	// After step 3 the files of v1, v2 and v3 are there, less the fixture's deletion
+	state, err := runner.expectedState(3)
+	if err != nil {
+		t.Fatalf("expectedState failed: %v", err)
+	}
+	if strings.Join(state.Files, ",") != "a.pdf,c.zip" || strings.Join(state.Absent, ",") != "b.zip" {
+		t.Errorf("expectedState(3) = %+v, want a.pdf and c.zip without b.zip", state)
+	}
+
+	// A resumed run copies the corpus it started with, which the run's tag records
+	tagged := &database.TestRun{ScenarioID: 1, StartedAt: time.Now(), Status: "failed"}
+	if err := runner.DB.CreateTestRun(tagged); err != nil {
+		t.Fatalf("CreateTestRun failed: %v", err)
+	}
+	if err := runner.DB.SetRunTag(tagged.ID, database.Tag{Key: "corpus", Value: "small"}); err != nil {
+		t.Fatalf("SetRunTag failed: %v", err)
+	}
+	resumed := newMiniRunner(t)
+	resumed.DB = runner.DB
+	if err := resumed.useCorpus(tagged); err != nil || resumed.Corpus != "small" {
+		t.Errorf("useCorpus = %s, %v; want small", resumed.Corpus, err)
+	}
+
+	requireGitLFS(t)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+	if err := runner.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// Step 3 commits v2, then v3 with the deletion
+	subjects, err := exec.Command("git", "-C", runner.RepoDir, "log", "--format=%s", "-2").Output()
+	if err != nil {
+		t.Fatalf("git log failed: %v", err)
+	}
+	if want := "Update, delete, and rename files (v3)\nUpdate files (v2)\n"; string(subjects) != want {
+		t.Errorf("last commits = %q, want %q", subjects, want)
+	}
+	for name, want := range map[string]string{"a.pdf": "a v3", "c.zip": "c v3"} {
+		if data, err := os.ReadFile(filepath.Join(runner.RepoDir, name)); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(runner.RepoDir, "b.zip")); err == nil {
+		t.Error("b.zip should have been deleted by the corpus's fixture")
+	}
+
+	tags, err := runner.DB.ListRunTags(runner.RunID)
+	if err != nil || len(tags) != 1 || tags[0].String() != "corpus=small" {
+		t.Errorf("run tags = %v, %v; want corpus=small", tags, err)
+	}
+}
+
+func TestProcessAlive(t *testing.T) {
+	if !ProcessAlive(os.Getpid()) {
+		t.Errorf("ProcessAlive(%d) = false for the test process", os.Getpid())
+	}
+
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot run true: %v", err)
+	}
+	if ProcessAlive(cmd.Process.Pid) {
+		t.Errorf("ProcessAlive(%d) = true for an exited process", cmd.Process.Pid)
+	}
+	if ProcessAlive(0) {
+		t.Errorf("ProcessAlive(0) = true")
+	}
+}
+
+func TestRunRepoDirs(t *testing.T) {
+	run := &database.TestRun{ID: 12, WorkDir: RunDir("/tmp/lfst", 12)}
+	if repo1, repo2 := RunRepoDirs(run, "/elsewhere"); repo1 != "/tmp/lfst/run-12/repo1" || repo2 != "/tmp/lfst/run-12/repo2" {
+		t.Errorf("RunRepoDirs = %s, %s", repo1, repo2)
+	}
+
+	// Runs made before per-run directories kept their repositories directly in the work directory
+	legacy := &database.TestRun{ID: 3}
+	if repo1, repo2 := RunRepoDirs(legacy, "/tmp/lfst"); repo1 != "/tmp/lfst/repo1" || repo2 != "/tmp/lfst/repo2" {
+		t.Errorf("RunRepoDirs of a legacy run = %s, %s", repo1, repo2)
+	}
+}
+
+func TestSharedDirRun(t *testing.T) {
+	runner := newVerifyRunner(t)
+	run, err := runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+
+	// Runs that do not work in the same repositories, or are not alive, let them be removed
+	for _, other := range []*database.TestRun{
+		{ScenarioID: 1, Status: "running", PID: os.Getpid(), WorkDir: RunDir(runner.WorkDir, 99)},
+		{ScenarioID: 1, Status: "running", PID: 0},
+		{ScenarioID: 1, Status: "completed", PID: os.Getpid()},
+	} {
+		other.StartedAt = time.Now()
+		if err := runner.DB.CreateTestRun(other); err != nil {
+			t.Fatalf("CreateTestRun failed: %v", err)
+		}
+	}
+	if other, err := SharedDirRun(runner.DB, run); err != nil || other != 0 {
+		t.Errorf("SharedDirRun = %d, %v; want no run sharing the repositories", other, err)
+	}
+
+	// A live run without a directory of its own keeps them
+	live := &database.TestRun{ScenarioID: 1, StartedAt: time.Now(), Status: "running", PID: os.Getpid()}
+	if err := runner.DB.CreateTestRun(live); err != nil {
+		t.Fatalf("CreateTestRun failed: %v", err)
+	}
+	if other, err := SharedDirRun(runner.DB, run); err != nil || other != live.ID {
+		t.Errorf("SharedDirRun = %d, %v; want run %d", other, err, live.ID)
+	}
+
+	// Without the runs, nothing says the repositories are free
+	runner.DB.Close()
+	if _, err := SharedDirRun(runner.DB, run); err == nil {
+		t.Error("SharedDirRun succeeded on a closed database")
+	}
+}
+
+func TestCancelledRun(t *testing.T) {
+	runner := newVerifyRunner(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	run, err := runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if err := runner.runSteps(ctx, run, nil); !errors.Is(err, ErrCancelled) {
+		t.Fatalf("runSteps error = %v, want ErrCancelled", err)
+	}
+
+	run, err = runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if run.Status != "cancelled" || run.CompletedAt == nil || run.PID != 0 {
+		t.Errorf("run = %s, %v, PID %d; want cancelled with a completion time", run.Status, run.CompletedAt, run.PID)
+	}
+	if !strings.Contains(run.Notes, "Cancelled in step 1 with 0 of 7 steps completed") {
+		t.Errorf("run notes should record how far it got: %s", run.Notes)
+	}
+
+	// A run with completed steps can be resumed, so its repositories are kept
+	runner.useRunDir(&database.TestRun{WorkDir: RunDir(runner.WorkDir, runner.RunID)})
+	if err := os.MkdirAll(runner.RepoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.cancelled(run, 3, 2); !errors.Is(err, ErrCancelled) {
+		t.Errorf("cancelled = %v, want ErrCancelled", err)
+	}
+	if _, err := os.Stat(runner.RepoDir); err != nil {
+		t.Errorf("repo1 of a resumable run was removed: %v", err)
+	}
+}
+
+// authenticatedMock is a mock git host whose traffic needs credentials
+type authenticatedMock struct {
+	*githost.Mock
+}
+
+func (authenticatedMock) GitConfig() map[string]string {
+	return map[string]string{"http.https://git.example.com/.extraHeader": "Authorization: Bearer secret"}
+}
+
+func TestAuthenticateHost(t *testing.T) {
+	t.Setenv("GIT_CONFIG_COUNT", "0")
+	githost.Register("test-authenticated", func(githost.Recorder) (githost.GitHost, error) {
+		return authenticatedMock{githost.NewMock()}, nil
+	})
+	runner := newVerifyRunner(t)
+	runner.Scenario.GitServer = "test-authenticated"
+	runner.Scenario.RepoName = "lfst-auth"
+
+	unauthenticate, err := runner.authenticateHost()
+	if err != nil {
+		t.Fatalf("authenticateHost failed: %v", err)
+	}
+	if os.Getenv("GIT_CONFIG_COUNT") != "1" || os.Getenv("GIT_CONFIG_VALUE_0") != "Authorization: Bearer secret" {
+		t.Errorf("the host's credentials were not exported")
+	}
+	// The credentials must not reach the scenarios run after this one
+	unauthenticate()
+	if os.Getenv("GIT_CONFIG_COUNT") != "0" || os.Getenv("GIT_CONFIG_KEY_0") != "" {
+		t.Errorf("the host's credentials are still exported: GIT_CONFIG_COUNT=%s", os.Getenv("GIT_CONFIG_COUNT"))
+	}
+}
+
+func TestHostAuthenticationFailed(t *testing.T) {
+	githost.Register("test-unauthenticated", func(githost.Recorder) (githost.GitHost, error) {
+		return nil, errors.New("no token found in LFST_TEST_TOKEN")
+	})
+	runner := newVerifyRunner(t)
+	runner.Scenario.GitServer = "test-unauthenticated"
+	runner.Scenario.RepoName = "lfst-auth"
+
+	run, err := runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if err := runner.runSteps(context.Background(), run, nil); err == nil {
+		t.Fatal("runSteps succeeded without the host's token")
+	}
+
+	run, err = runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if run.Status != "failed" || run.CompletedAt == nil || !strings.Contains(run.Notes, "Git host authentication failed before the steps ran: no token found") {
+		t.Errorf("run = %s, %v, %q; want failed before the steps ran", run.Status, run.CompletedAt, run.Notes)
+	}
+}
@@ -0,0 +1,56 @@
+package scenario
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/serverlog"
+)
+
+// serverEventSlack widens the window in which a server event is attributed to an operation,
+// for log timestamps that lag and server clocks that differ slightly from the client's
+const serverEventSlack = 5 * time.Second
+
+// recordServerEvents reads the restarts, stops and out-of-memory kills of the server
+// during a step, records them, annotates the operations they overlap, and records a
+// 'server-events' warning if there were any. It returns their description, "" if none.
+func (r *Runner) recordServerEvents(step int, start, end time.Time) string {
+	events, err := r.ServerService.Events(start.Add(-serverEventSlack), end.Add(serverEventSlack))
+	if err != nil {
+		log.Warnf("failed to read the events of %s: %v\n", r.ServerService, err)
+		return ""
+	}
+
+	for _, e := range events {
+		se := &database.ServerEvent{RunID: r.RunID, StepNumber: step, OccurredAt: e.At, Kind: e.Kind, Message: e.Message}
+		if err := r.DB.CreateServerEvent(se); err != nil {
+			log.Warnf("failed to record server event: %v\n", err)
+		}
+	}
+
+	ops, err := r.DB.ListOperations(r.RunID)
+	if err != nil {
+		log.Warnf("failed to list operations: %v\n", err)
+	}
+	for _, op := range ops {
+		if op.StepNumber != step {
+			continue
+		}
+		opEnd := op.StartedAt.Add(time.Duration(op.DurationMs) * time.Millisecond)
+		if overlapping := serverlog.Overlapping(events, op.StartedAt, opEnd, serverEventSlack); len(overlapping) > 0 {
+			if err := r.DB.SetOperationServerEvent(op.ID, serverlog.Describe(overlapping)); err != nil {
+				log.Warnf("%v\n", err)
+			}
+		}
+	}
+
+	description := serverlog.Describe(events)
+	var found error
+	if description != "" {
+		found = errors.New("the server was restarted, stopped or ran out of memory: " + description)
+	}
+	r.verify(step, "server-events", SeverityWarning, found)
+	return description
+}
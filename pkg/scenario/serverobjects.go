@@ -0,0 +1,122 @@
+package scenario
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/checksum"
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/lfsverify"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/storage"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+)
+
+// Methods of counting the LFS objects on the server, recorded in server_objects
+const (
+	ServerObjectsFilesystem = "filesystem" // The object store is a directory on this machine
+	ServerObjectsSSH        = "ssh"        // The object store is a directory on another machine
+	ServerObjectsAPI        = "api"        // The server was asked through the Batch API
+)
+
+// serverObjectStore returns the LFS server's object store, as a local path or host:/path:
+// --server-storage, or the LFS store of the run's bare repository when the scenario has no
+// LFS server of its own. It returns "" when the server can only be asked through its API.
+func (r *Runner) serverObjectStore() string {
+	if r.ServerStorage != "" {
+		return r.ServerStorage
+	}
+	if r.gitServer == nil || r.Scenario.ServerURL != "" {
+		return ""
+	}
+	dir := path.Join(r.gitServer.Dir, "lfs", "objects")
+	if r.gitServer.Remote() {
+		return r.gitServer.Host + ":" + dir
+	}
+	return dir
+}
+
+// verifyServerObjects checks, after a push, that the LFS server holds every object that
+// counts says the pushed history references, and that it stores identical content once
+// even when several files have it. The outcome is recorded in server_objects and as the
+// verifications server-objects and, when the store can be listed, server-dedup.
+func (r *Runner) verifyServerObjects(step int, counts *lfsverify.HistoryCounts) error {
+	o := &database.ServerObjects{
+		RunID:           r.RunID,
+		StepNumber:      step,
+		ReferencedFiles: counts.Files,
+		ExpectedObjects: counts.Referenced,
+		ExpectedBytes:   counts.Bytes,
+		Status:          "passed",
+	}
+
+	var problems []string
+	var duplicates error
+	if store := r.serverObjectStore(); store != "" {
+		o.Location = store
+		o.Method = ServerObjectsFilesystem
+		if _, isRemote := testdata.ParseRemotePath(store); isRemote {
+			o.Method = ServerObjectsSSH
+		}
+		log.Debugf("Counting LFS objects in %s...\n", store)
+		contents, err := storage.ScanStore(store, counts.Sizes)
+		if err != nil {
+			return r.verify(step, "server-objects", SeverityWarning, err)
+		}
+		o.StoredObjects = contents.Objects
+		o.StoredCopies = &contents.Copies
+		o.StoredBytes = &contents.Bytes
+		o.OtherFiles = &contents.Other
+		if len(contents.Damaged) > 0 {
+			problems = append(problems, fmt.Sprintf("%d stored with the wrong size", len(contents.Damaged)))
+		}
+		if n := contents.Duplicates(); n > 0 {
+			duplicates = fmt.Errorf("%d redundant copies of %d objects in %s: identical content is not deduplicated",
+				n, contents.Objects, store)
+		}
+	} else {
+		if counts.Server < 0 {
+			log.Debugf("Server objects not counted: %v\n", counts.ServerError)
+			return nil
+		}
+		o.Location = counts.Endpoint
+		o.Method = ServerObjectsAPI
+		o.StoredObjects = counts.Server
+	}
+	if missing := o.ExpectedObjects - o.StoredObjects; missing > 0 {
+		problems = append(problems, fmt.Sprintf("%d of %d referenced objects missing", missing, o.ExpectedObjects))
+	}
+
+	var checkErr error
+	switch {
+	case len(problems) > 0:
+		checkErr = fmt.Errorf("LFS objects on the server (%s): %s", o.Location, strings.Join(problems, ", "))
+		o.Status = "failed"
+		o.Message = checkErr.Error()
+		if duplicates != nil {
+			o.Message += "; " + duplicates.Error()
+		}
+	case duplicates != nil:
+		o.Status = "failed"
+		o.Message = duplicates.Error()
+	default:
+		o.Message = fmt.Sprintf("%d objects (%s) for %d files", o.StoredObjects, checksum.FormatSize(o.ExpectedBytes), o.ReferencedFiles)
+		if o.StoredCopies != nil {
+			o.Message = fmt.Sprintf("%d objects (%s) stored once for %d files", o.StoredObjects, checksum.FormatSize(o.ExpectedBytes), o.ReferencedFiles)
+		}
+		log.Debugf("  %s Server holds %s\n", term.OK(), o.Message)
+	}
+	o.CheckedAt = time.Now()
+	if err := r.DB.CreateServerObjects(o); err != nil {
+		log.Warnf("failed to record server objects: %v\n", err)
+	}
+
+	if o.Method != ServerObjectsAPI {
+		// Servers that keep a store per repository may hold the same object twice legitimately
+		r.verify(step, "server-dedup", SeverityWarning, duplicates)
+	}
+	return r.verify(step, "server-objects", SeverityError, checkErr)
+}
@@ -0,0 +1,89 @@
+package scenario
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/lfsverify"
+)
+
+func TestVerifyServerObjects(t *testing.T) {
+	a, b := strings.Repeat("a", 64), strings.Repeat("b", 64)
+	counts := &lfsverify.HistoryCounts{Referenced: 2, Files: 3, Bytes: 8, Sizes: map[string]int64{a: 3, b: 5}}
+
+	runner := newVerifyRunner(t)
+	runner.ServerStorage = t.TempDir()
+	writeObject := func(rel, content string) {
+		path := filepath.Join(runner.ServerStorage, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Step 2: one of the objects was not pushed
+	writeObject("aa/aa/"+a, "aaa")
+	if err := runner.verifyServerObjects(2, counts); err == nil || !strings.Contains(err.Error(), "1 of 2 referenced objects missing") {
+		t.Errorf("verifyServerObjects with a missing object = %v", err)
+	}
+
+	// Step 5: every object is there, one of them twice
+	writeObject("bb/bb/"+b, "bbbbb")
+	writeObject("repo2/"+b, "bbbbb")
+	if err := runner.verifyServerObjects(5, counts); err != nil {
+		t.Errorf("verifyServerObjects with a redundant copy failed: %v", err)
+	}
+
+	list, err := runner.DB.ListServerObjects(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListServerObjects failed: %v", err)
+	}
+	if len(list) != 2 || list[0].Status != "failed" || list[0].StoredObjects != 1 || list[0].Method != ServerObjectsFilesystem {
+		t.Fatalf("server objects = %+v, want a failed count of step 2 first", list)
+	}
+	if o := list[1]; o.StoredObjects != 2 || *o.StoredCopies != 3 || *o.StoredBytes != 13 ||
+		!strings.Contains(o.Message, "1 redundant copies of 2 objects") {
+		t.Errorf("step 5 count = %+v", o)
+	}
+
+	verifications, err := runner.DB.ListVerifications(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListVerifications failed: %v", err)
+	}
+	statuses := map[string]string{}
+	for _, v := range verifications {
+		statuses[fmt.Sprintf("%s %d", v.Name, v.StepNumber)] = v.Status
+	}
+	want := map[string]string{
+		"server-objects 2": "failed", "server-dedup 2": "passed",
+		"server-objects 5": "passed", "server-dedup 5": "failed",
+	}
+	for key, status := range want {
+		if statuses[key] != status {
+			t.Errorf("verification %s = %q, want %q", key, statuses[key], status)
+		}
+	}
+}
+
+func TestVerifyServerObjectsAPI(t *testing.T) {
+	runner := newVerifyRunner(t)
+	runner.Scenario.ServerURL = "https://lfs.example.com/repo.git"
+	counts := &lfsverify.HistoryCounts{Referenced: 4, Files: 4, Bytes: 400, Server: 4, Endpoint: "https://lfs.example.com/repo.git/info/lfs"}
+
+	if err := runner.verifyServerObjects(3, counts); err != nil {
+		t.Fatalf("verifyServerObjects failed: %v", err)
+	}
+	list, err := runner.DB.ListServerObjects(runner.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Method != ServerObjectsAPI || list[0].Location != counts.Endpoint ||
+		list[0].StoredCopies != nil || list[0].Status != "passed" {
+		t.Errorf("server objects = %+v, want a passed API count", list)
+	}
+}
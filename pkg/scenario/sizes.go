@@ -0,0 +1,154 @@
+package scenario
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/sshutil"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// recordSizes measures the git and LFS storage of each client repository, and the
+// server storage when ServerStorage is set, and records them for a step.
+// Measurement failures are reported in debug mode but never fail the step.
+func (r *Runner) recordSizes(stepNum int) {
+	if err := r.DB.DeleteRepositorySizes(r.RunID, stepNum); err != nil {
+		log.Warnf("failed to reset repository sizes: %v\n", err)
+	}
+
+	clients := []struct{ dir, prefix string }{
+		{r.RepoDir, "client"},
+		{r.Repo2Dir, "client2"},
+	}
+	for _, c := range clients {
+		if _, err := os.Stat(filepath.Join(c.dir, ".git")); err != nil {
+			continue // Not created yet
+		}
+
+		if size, count, err := gitObjectsSize(c.dir); err == nil {
+			r.saveSize(stepNum, c.prefix+"-git", size, count)
+		} else {
+			log.Warnf("failed to measure git objects in %s: %v\n", c.dir, err)
+		}
+
+		if size, count, err := dirSize(filepath.Join(c.dir, ".git", "lfs", "objects")); err == nil {
+			r.saveSize(stepNum, c.prefix+"-lfs", size, count)
+		} else {
+			log.Warnf("failed to measure LFS objects in %s: %v\n", c.dir, err)
+		}
+	}
+
+	if r.ServerStorage != "" {
+		if size, count, err := storageSize(r.ServerStorage); err == nil {
+			r.saveSize(stepNum, "server", size, count)
+		} else {
+			log.Warnf("failed to measure server storage %s: %v\n", r.ServerStorage, err)
+		}
+	}
+}
+
+// saveSize records one measurement
+func (r *Runner) saveSize(stepNum int, location string, size int64, count int) {
+	rs := &database.RepositorySize{
+		RunID:      r.RunID,
+		StepNumber: stepNum,
+		Location:   location,
+		SizeBytes:  size,
+		FileCount:  &count,
+		MeasuredAt: time.Now(),
+	}
+	if err := r.DB.CreateRepositorySize(rs); err != nil {
+		log.Warnf("failed to record %s size: %v\n", location, err)
+	}
+}
+
+// gitObjectsSize returns the bytes and number of git objects in a repository, loose and packed
+func gitObjectsSize(repoDir string) (int64, int, error) {
+	result := timing.Run("git", []string{"-C", repoDir, "count-objects", "-v"}, nil)
+	if !result.Success() {
+		return 0, 0, fmt.Errorf("git count-objects failed: %s", strings.TrimSpace(result.Stderr))
+	}
+	return parseCountObjects(result.Stdout)
+}
+
+// parseCountObjects parses `git count-objects -v` output, whose sizes are in KiB
+func parseCountObjects(output string) (int64, int, error) {
+	values := make(map[string]int64)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		values[key] = n
+	}
+
+	if _, ok := values["count"]; !ok {
+		return 0, 0, fmt.Errorf("unexpected git count-objects output")
+	}
+	size := (values["size"] + values["size-pack"]) * 1024
+	count := int(values["count"] + values["in-pack"])
+	return size, count, nil
+}
+
+// dirSize returns the total size and number of regular files under dir; a missing dir is empty
+func dirSize(dir string) (int64, int, error) {
+	var size int64
+	var count int
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+			count++
+		}
+		return nil
+	})
+	return size, count, err
+}
+
+// storageSize measures a local directory or, for host:/path, a directory on a remote host
+func storageSize(location string) (int64, int, error) {
+	remote, isRemote := testdata.ParseRemotePath(location)
+	if !isRemote {
+		return dirSize(location)
+	}
+
+	quoted := "'" + strings.ReplaceAll(remote.Path, "'", `'\''`) + "'"
+	cmd := sshutil.Command(remote.Host, fmt.Sprintf("du -sb %s | cut -f1 && find %s -type f | wc -l", quoted, quoted))
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("ssh %s failed: %w", remote.Host, err)
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected output from %s: %q", remote.Host, string(output))
+	}
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse size: %w", err)
+	}
+	count, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse file count: %w", err)
+	}
+	return size, count, nil
+}
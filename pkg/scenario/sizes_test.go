@@ -0,0 +1,56 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCountObjects(t *testing.T) {
+	output := `count: 4
+size: 12
+in-pack: 20
+packs: 1
+size-pack: 100
+prune-packable: 0
+garbage: 0
+size-garbage: 0
+`
+	size, count, err := parseCountObjects(output)
+	if err != nil {
+		t.Fatalf("parseCountObjects failed: %v", err)
+	}
+	if size != 112*1024 {
+		t.Errorf("size = %d, want %d", size, 112*1024)
+	}
+	if count != 24 {
+		t.Errorf("count = %d, want 24", count)
+	}
+
+	if _, _, err := parseCountObjects("fatal: not a git repository"); err == nil {
+		t.Error("Expected error for unexpected output")
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "ab", "cd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "ab", "cd", "obj1"), make([]byte, 1000), 0644)
+	os.WriteFile(filepath.Join(dir, "obj2"), make([]byte, 24), 0644)
+
+	size, count, err := dirSize(dir)
+	if err != nil {
+		t.Fatalf("dirSize failed: %v", err)
+	}
+	if size != 1024 || count != 2 {
+		t.Errorf("dirSize = %d bytes, %d files; want 1024 bytes, 2 files", size, count)
+	}
+
+	// LFS object directories do not exist until the first LFS file is added
+	size, count, err = dirSize(filepath.Join(dir, "missing"))
+	if err != nil || size != 0 || count != 0 {
+		t.Errorf("dirSize(missing) = %d, %d, %v; want empty", size, count, err)
+	}
+}
@@ -0,0 +1,246 @@
+package scenario
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+)
+
+// Snapshot methods
+const (
+	SnapshotAuto    = "auto"    // Reflink if the file system supports it, else tar
+	SnapshotReflink = "reflink" // Copy-on-write clones (cp --reflink), e.g. on btrfs, XFS or ZFS 2.2+
+	SnapshotTar     = "tar"     // A tar archive of each repository; works everywhere, costs a full copy
+)
+
+// SnapshotMethods lists the valid values of Runner.Snapshots
+var SnapshotMethods = []string{SnapshotAuto, SnapshotReflink, SnapshotTar}
+
+// SnapshotsDir is the directory of the work directory holding the snapshots, one subdirectory per run
+const SnapshotsDir = "snapshots"
+
+// snapshotDir returns the directory holding the repositories as they were after a step of run runID
+func (r *Runner) snapshotDir(runID int64, step int) string {
+	return filepath.Join(r.WorkDir, SnapshotsDir, strconv.FormatInt(runID, 10), fmt.Sprintf("step-%d", step))
+}
+
+// snapshot saves repo1 and repo2 as they are after a step, replacing an earlier snapshot
+// of the step. With SnapshotAuto, the first failed reflink switches the runner to tar.
+func (r *Runner) snapshot(step int) error {
+	start := time.Now()
+	dir := r.snapshotDir(r.RunID, step)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove old snapshot: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	for _, repo := range []string{r.RepoDir, r.Repo2Dir} {
+		if _, err := os.Stat(repo); err != nil {
+			continue // Not created yet
+		}
+		name := filepath.Base(repo)
+
+		if r.Snapshots == SnapshotAuto || r.Snapshots == SnapshotReflink {
+			err := reflinkCopy(repo, filepath.Join(dir, name))
+			if err == nil {
+				continue
+			}
+			if r.Snapshots == SnapshotReflink {
+				return err
+			}
+			log.Debugf("  Copy-on-write snapshots unavailable (%v); using tar\n", err)
+			os.RemoveAll(filepath.Join(dir, name))
+			r.Snapshots = SnapshotTar
+		}
+		if err := tarDir(repo, filepath.Join(dir, name+".tar")); err != nil {
+			return err
+		}
+	}
+
+	log.Debugf("  %s Snapshot of step %d (%s) in %s\n", term.OK(), step, r.Snapshots, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// restore replaces repo1 and repo2 with their snapshot after a step of run runID.
+// A repository missing from the snapshot did not exist yet, so it is removed.
+func (r *Runner) restore(runID int64, step int) error {
+	dir := r.snapshotDir(runID, step)
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("no snapshot of step %d of run %d in %s (run it with --snapshots)", step, runID, dir)
+	}
+
+	for _, repo := range []string{r.RepoDir, r.Repo2Dir} {
+		if err := os.RemoveAll(repo); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", repo, err)
+		}
+		name := filepath.Base(repo)
+
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			if err := reflinkCopy(filepath.Join(dir, name), repo); err != nil {
+				return err
+			}
+		} else if _, err := os.Stat(filepath.Join(dir, name+".tar")); err == nil {
+			if err := untarDir(filepath.Join(dir, name+".tar"), repo); err != nil {
+				return err
+			}
+		} else {
+			continue
+		}
+		log.Debugf("  %s Restored %s from the snapshot of step %d\n", term.OK(), repo, step)
+	}
+	return nil
+}
+
+// removeSnapshots removes the snapshots of the runner's run
+func (r *Runner) removeSnapshots() error {
+	return os.RemoveAll(filepath.Join(r.WorkDir, SnapshotsDir, strconv.FormatInt(r.RunID, 10)))
+}
+
+// reflinkCopy copies a directory tree, sharing file data with the original where the
+// file system supports copy-on-write clones; it fails where it does not
+func reflinkCopy(src, dst string) error {
+	out, err := exec.Command("cp", "-a", "--reflink=always", src, dst).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to clone %s: %s", src, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// tarDir archives a directory tree into file
+func tarDir(dir, file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", dir, err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", dir, err)
+	}
+	return f.Close()
+}
+
+// untarDir extracts an archive written by tarDir into dir
+func untarDir(file, dir string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	// Directories are made read-only last, so their files can be written first
+	type dirMode struct {
+		path string
+		mode fs.FileMode
+	}
+	var dirs []dirMode
+
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot %s: %w", file, err)
+		}
+		if !filepath.IsLocal(header.Name) {
+			return fmt.Errorf("snapshot %s has an unsafe path: %s", file, header.Name)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(header.Name))
+		mode := header.FileInfo().Mode()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0755)
+			dirs = append(dirs, dirMode{path, mode.Perm()})
+		case tar.TypeSymlink:
+			err = os.Symlink(header.Linkname, path)
+		case tar.TypeReg:
+			if err = extractFile(tr, path, mode.Perm()); err == nil {
+				err = os.Chtimes(path, header.ModTime, header.ModTime)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", path, err)
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Chmod(dirs[i].path, dirs[i].mode)
+	}
+	return nil
+}
+
+// extractFile writes the current entry of an archive to path
+func extractFile(tr *tar.Reader, path string, perm fs.FileMode) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, tr); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,104 @@
+package scenario
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	for _, method := range []string{SnapshotTar, SnapshotAuto} {
+		t.Run(method, func(t *testing.T) {
+			workDir := t.TempDir()
+			runner := NewRunner(&Scenario{ID: 1}, nil, workDir, false, false)
+			runner.RunID = 7
+			runner.Snapshots = method
+
+			object := filepath.Join(runner.RepoDir, ".git", "objects", "ab", "cdef")
+			write := func(path, content string, perm os.FileMode) {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), perm); err != nil {
+					t.Fatal(err)
+				}
+			}
+			write(filepath.Join(runner.RepoDir, "a.txt"), "original", 0644)
+			write(object, "object", 0444)
+			if err := os.Symlink("a.txt", filepath.Join(runner.RepoDir, "link")); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := runner.snapshot(1); err != nil {
+				t.Fatalf("snapshot failed: %v", err)
+			}
+
+			// What a later step might do
+			write(filepath.Join(runner.RepoDir, "a.txt"), "modified", 0644)
+			write(filepath.Join(runner.RepoDir, "b.txt"), "added", 0644)
+			write(filepath.Join(runner.Repo2Dir, "c.txt"), "cloned", 0644)
+
+			if err := runner.restore(7, 1); err != nil {
+				t.Fatalf("restore failed: %v", err)
+			}
+			if data, _ := os.ReadFile(filepath.Join(runner.RepoDir, "a.txt")); string(data) != "original" {
+				t.Errorf("a.txt = %q after restore, want original", data)
+			}
+			if _, err := os.Stat(filepath.Join(runner.RepoDir, "b.txt")); !os.IsNotExist(err) {
+				t.Errorf("b.txt survived the restore")
+			}
+			if _, err := os.Stat(runner.Repo2Dir); !os.IsNotExist(err) {
+				t.Errorf("repo2 survived the restore of a snapshot taken before it existed")
+			}
+			if info, err := os.Stat(object); err != nil || info.Mode().Perm() != 0444 {
+				t.Errorf("read-only object not restored: %v %v", info, err)
+			}
+			if target, err := os.Readlink(filepath.Join(runner.RepoDir, "link")); err != nil || target != "a.txt" {
+				t.Errorf("symlink not restored: %q %v", target, err)
+			}
+
+			if err := runner.restore(7, 2); err == nil {
+				t.Errorf("restore of a step without a snapshot should fail")
+			}
+			if err := runner.removeSnapshots(); err != nil {
+				t.Fatalf("removeSnapshots failed: %v", err)
+			}
+			if _, err := os.Stat(runner.snapshotDir(7, 1)); !os.IsNotExist(err) {
+				t.Errorf("snapshot survived removeSnapshots")
+			}
+		})
+	}
+}
+
+func TestRerun(t *testing.T) {
+	requireGitLFS(t)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+
+	runner := newMiniRunner(t)
+	runner.Snapshots = SnapshotAuto
+	if err := runner.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	runID := runner.RunID
+
+	rerun := NewRunner(runner.Scenario, runner.DB, runner.WorkDir, false, false)
+	rerun.TestDataPath = runner.TestDataPath
+	if err := rerun.Rerun(context.Background(), runID, 4); err != nil {
+		t.Fatalf("Rerun of step 4 failed: %v", err)
+	}
+	run, err := rerun.DB.GetTestRun(runID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if run.Status != "completed" {
+		t.Errorf("run status = %s after rerun, want completed", run.Status)
+	}
+
+	if err := os.RemoveAll(filepath.Join(runner.WorkDir, SnapshotsDir)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rerun.Rerun(context.Background(), runID, 4); err == nil {
+		t.Errorf("Rerun without snapshots should fail")
+	}
+}
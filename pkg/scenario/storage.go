@@ -0,0 +1,71 @@
+package scenario
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/lfsverify"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/storage"
+	"github.com/mslinn/git-lfs-test/pkg/term"
+)
+
+// verifyStorage checks that every LFS object in repoDir's local store is in the server's
+// storage backend with the right size, recording each object in storage_verification.
+// It does nothing unless the runner has a storage probe.
+func (r *Runner) verifyStorage(step int, repoDir string) error {
+	if r.Storage == nil {
+		return nil
+	}
+	log.Debugf("Checking LFS objects in %s...\n", r.Storage.Location())
+
+	objects, err := storage.LocalObjects(repoDir)
+	if err != nil {
+		return r.verify(step, "storage-objects", SeverityError, err)
+	}
+
+	results := storage.Check(r.Storage, objects)
+	for _, result := range results {
+		v := &database.StorageVerification{
+			RunID:        r.RunID,
+			StepNumber:   step,
+			Backend:      r.Storage.Location(),
+			ObjectKey:    result.Key,
+			OID:          result.OID,
+			ExpectedSize: result.Size,
+			ActualSize:   result.ActualSize,
+			Status:       result.Status,
+			Message:      result.Message,
+			CheckedAt:    time.Now(),
+		}
+		if err := r.DB.CreateStorageVerification(v); err != nil {
+			log.Warnf("failed to record storage check of %s: %v\n", result.OID, err)
+		}
+	}
+
+	var checkErr error
+	if failures := storage.Failures(results); failures > 0 {
+		checkErr = fmt.Errorf("%d of %d LFS objects missing or damaged in %s (see lfst-query stats)",
+			failures, len(results), r.Storage.Location())
+	} else {
+		log.Debugf("  %s All %d LFS objects present in storage\n", term.OK(), len(results))
+	}
+	return r.verify(step, "storage-objects", SeverityError, checkErr)
+}
+
+// verifyHistoryObjects compares the number of LFS objects referenced anywhere in repoDir's
+// history with the objects in its local store, if requireLocal, and on the server, to catch
+// history objects lost by a partial push, then counts the objects in the server's store
+// (see verifyServerObjects). A repository whose history cannot be listed, e.g. with a
+// git-lfs older than 3.0, only gets a warning.
+func (r *Runner) verifyHistoryObjects(step int, repoDir string, requireLocal bool) error {
+	counts, err := lfsverify.VerifyHistoryObjects(repoDir, requireLocal)
+	if counts == nil {
+		return r.verify(step, "lfs-history-objects", SeverityWarning, err)
+	}
+	if err := r.verify(step, "lfs-history-objects", SeverityError, err); err != nil {
+		return err
+	}
+	return r.verifyServerObjects(step, counts)
+}
@@ -0,0 +1,54 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bucketProbe is a storage backend holding the given object sizes
+type bucketProbe map[string]int64
+
+func (b bucketProbe) Location() string      { return "s3://test-bucket" }
+func (b bucketProbe) Key(oid string) string { return oid }
+func (b bucketProbe) Stat(oid string) (int64, bool, error) {
+	size, ok := b[oid]
+	return size, ok, nil
+}
+
+func TestVerifyStorage(t *testing.T) {
+	runner := newVerifyRunner(t)
+
+	// Without a probe there is nothing to check or record
+	if err := runner.verifyStorage(2, runner.RepoDir); err != nil {
+		t.Fatalf("verifyStorage without a probe returned %v", err)
+	}
+
+	oid := strings.Repeat("c", 64)
+	dir := filepath.Join(runner.RepoDir, ".git", "lfs", "objects", "cc", "cc")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, oid), []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner.Storage = bucketProbe{oid: 7}
+	if err := runner.verifyStorage(2, runner.RepoDir); err != nil {
+		t.Errorf("verifyStorage with the object stored returned %v", err)
+	}
+
+	runner.Storage = bucketProbe{}
+	if err := runner.verifyStorage(3, runner.RepoDir); err == nil {
+		t.Error("verifyStorage should fail when the bucket lost an object")
+	}
+
+	checks, err := runner.DB.ListStorageVerifications(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListStorageVerifications failed: %v", err)
+	}
+	if len(checks) != 2 || checks[0].Status != "ok" || checks[1].Status != "missing" {
+		t.Errorf("storage checks = %d rows, want ok at step 2 and missing at step 3", len(checks))
+	}
+}
@@ -0,0 +1,66 @@
+package scenario
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/git"
+	"github.com/mslinn/git-lfs-test/pkg/lfsproxy"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+)
+
+// startLFSProxy routes git-lfs through an lfsproxy.Proxy in front of the scenario's LFS
+// server, recording every Batch API exchange under the step that made it.
+// The returned function stops the proxy.
+func (r *Runner) startLFSProxy() (func(), error) {
+	if u, err := url.Parse(r.Scenario.ServerURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("--lfs-proxy needs a scenario with an HTTP(S) LFS server, but scenario %d has server URL '%s'",
+			r.Scenario.ID, r.Scenario.ServerURL)
+	}
+
+	proxy, err := lfsproxy.Start(r.Scenario.ServerURL, r.recordExchange)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Recording LFS Batch API requests through %s\n", proxy.URL)
+
+	// lfs.url from git config overrides the committed .lfsconfig, so clones use the proxy too
+	git.ExportConfig(map[string]string{"lfs.url": proxy.URL})
+	return func() {
+		git.UnexportConfig("lfs.url")
+		proxy.Close()
+	}, nil
+}
+
+// recordExchange stores a Batch API exchange for the running step
+func (r *Runner) recordExchange(exchange *lfsproxy.Exchange) {
+	request := &database.LFSBatchRequest{
+		RunID:       r.RunID,
+		StepNumber:  int(r.currentStep.Load()),
+		Operation:   exchange.Operation,
+		Transfers:   exchange.Transfers,
+		Ref:         exchange.Ref,
+		StatusCode:  exchange.StatusCode,
+		Transfer:    exchange.Transfer,
+		Message:     exchange.Message,
+		DurationMs:  exchange.Duration.Milliseconds(),
+		RequestedAt: exchange.StartedAt,
+	}
+	for _, obj := range exchange.Objects {
+		request.Objects = append(request.Objects, &database.LFSBatchObject{
+			OID:          obj.OID,
+			Size:         obj.Size,
+			Actions:      obj.Actions,
+			Href:         obj.Href,
+			VerifyHref:   obj.VerifyHref,
+			ExpiresIn:    obj.ExpiresIn,
+			ErrorCode:    obj.ErrorCode,
+			ErrorMessage: obj.ErrorMessage,
+		})
+	}
+
+	if err := r.DB.CreateLFSBatchRequest(request); err != nil {
+		log.Warnf("failed to record LFS batch request: %v\n", err)
+	}
+}
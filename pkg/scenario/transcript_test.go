@@ -0,0 +1,95 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/mockserver"
+)
+
+// exportedLFSURL returns the lfs.url passed to git commands through GIT_CONFIG_COUNT, if any
+func exportedLFSURL() string {
+	count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	for i := 0; i < count; i++ {
+		if os.Getenv(fmt.Sprintf("GIT_CONFIG_KEY_%d", i)) == "lfs.url" {
+			return os.Getenv(fmt.Sprintf("GIT_CONFIG_VALUE_%d", i))
+		}
+	}
+	return ""
+}
+
+func TestLFSProxyRecordsBatchRequests(t *testing.T) {
+	t.Setenv("GIT_CONFIG_COUNT", "0")
+	server := mockserver.New()
+	defer server.Close()
+
+	runner := newVerifyRunner(t)
+	runner.Scenario = &Scenario{ID: 6, ServerType: "lfs-test-server", Protocol: "http", ServerURL: server.LFSURL()}
+
+	stop, err := runner.startLFSProxy()
+	if err != nil {
+		t.Fatalf("startLFSProxy failed: %v", err)
+	}
+	proxyURL := exportedLFSURL()
+	if !strings.HasPrefix(proxyURL, "http://127.0.0.1:") {
+		t.Fatalf("lfs.url should point git-lfs at the proxy, got '%s'", proxyURL)
+	}
+
+	runner.currentStep.Store(2)
+	body := `{"operation": "upload", "objects": [{"oid": "` + mockserver.OID([]byte("x")) + `", "size": 1}]}`
+	resp, err := http.Post(proxyURL+"/objects/batch", mockserver.MediaType, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("batch request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	stop()
+	if url := exportedLFSURL(); url != "" {
+		t.Errorf("lfs.url should be unexported when the proxy stops, got '%s'", url)
+	}
+
+	requests, err := runner.DB.ListLFSBatchRequests(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListLFSBatchRequests failed: %v", err)
+	}
+	if len(requests) != 1 || requests[0].StepNumber != 2 || requests[0].Operation != "upload" || len(requests[0].Objects) != 1 {
+		t.Fatalf("recorded requests = %+v", requests)
+	}
+	if actions := strings.Join(requests[0].Objects[0].Actions, ","); actions != "upload,verify" {
+		t.Errorf("recorded actions = %s", actions)
+	}
+}
+
+func TestLFSProxyNeedsHTTPServer(t *testing.T) {
+	runner := newVerifyRunner(t)
+	if _, err := runner.startLFSProxy(); err == nil || !strings.Contains(err.Error(), "HTTP(S) LFS server") {
+		t.Errorf("startLFSProxy for a local scenario: err = %v", err)
+	}
+}
+
+func TestLFSProxyFailureFailsRun(t *testing.T) {
+	runner := newVerifyRunner(t)
+	runner.LFSProxy = true
+	runner.Scenario.ServerURL = "ssh://lfs.example.com/repo.git"
+
+	run, err := runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if err := runner.runSteps(context.Background(), run, nil); err == nil {
+		t.Fatal("runSteps succeeded without its LFS proxy")
+	}
+
+	run, err = runner.DB.GetTestRun(runner.RunID)
+	if err != nil {
+		t.Fatalf("GetTestRun failed: %v", err)
+	}
+	if run.Status != "failed" || run.CompletedAt == nil || !strings.Contains(run.Notes, "LFS proxy failed to start before the steps ran") {
+		t.Errorf("run = %s, %v, %q; want failed before the steps ran", run.Status, run.CompletedAt, run.Notes)
+	}
+}
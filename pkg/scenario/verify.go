@@ -0,0 +1,53 @@
+package scenario
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+)
+
+// Verification severities. A failed error check fails its step unless the runner is
+// lenient; a failed warning check is recorded and reported but never stops the run.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// verify records the outcome of a check made during step, where err is the check's result.
+// It returns err only if the check failed with error severity and the runner is not lenient,
+// so callers can wrap and return it exactly as they would the unchecked error.
+func (r *Runner) verify(step int, name, severity string, err error) error {
+	v := &database.Verification{
+		RunID:      r.RunID,
+		StepNumber: step,
+		Name:       name,
+		Severity:   severity,
+		Status:     "passed",
+		CheckedAt:  time.Now(),
+	}
+	if err != nil {
+		v.Status = "failed"
+		v.Message = err.Error()
+	}
+
+	if dbErr := r.DB.CreateVerification(v); dbErr != nil {
+		log.Warnf("failed to record verification %s: %v\n", name, dbErr)
+	}
+
+	if err == nil {
+		return nil
+	}
+	if severity == SeverityError && !r.Lenient {
+		return err
+	}
+
+	r.verifyFailures++
+	label := "Warning"
+	if severity == SeverityError {
+		label = "Verification failed (lenient, continuing)"
+	}
+	fmt.Printf("%s: step %d %s: %v\n", label, step, name, err)
+	return nil
+}
@@ -0,0 +1,103 @@
+package scenario
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/database"
+	"github.com/mslinn/git-lfs-test/pkg/interference"
+)
+
+// newVerifyRunner creates a runner with a database and a test run, without test data
+func newVerifyRunner(t *testing.T) *Runner {
+	t.Helper()
+
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	run := &database.TestRun{ScenarioID: 1, ServerType: "bare", Protocol: "local", GitServer: "bare", StartedAt: time.Now(), Status: "running"}
+	if err := db.CreateTestRun(run); err != nil {
+		t.Fatalf("CreateTestRun failed: %v", err)
+	}
+
+	runner := NewRunner(&Scenario{ID: 1}, db, t.TempDir(), false, false)
+	runner.RunID = run.ID
+	return runner
+}
+
+func TestVerifySeverity(t *testing.T) {
+	runner := newVerifyRunner(t)
+	failure := errors.New("git objects larger than LFS objects")
+
+	if err := runner.verify(2, "lfs-pointers", SeverityError, nil); err != nil {
+		t.Errorf("Passing check returned %v", err)
+	}
+	if err := runner.verify(2, "repository-sizes", SeverityWarning, failure); err != nil {
+		t.Errorf("Failed warning returned %v, want nil", err)
+	}
+	if err := runner.verify(2, "lfs-objects", SeverityError, failure); !errors.Is(err, failure) {
+		t.Errorf("Failed error check returned %v, want the failure", err)
+	}
+
+	runner.Lenient = true
+	if err := runner.verify(4, "lfs-content", SeverityError, failure); err != nil {
+		t.Errorf("Failed error check in lenient mode returned %v, want nil", err)
+	}
+
+	verifications, err := runner.DB.ListVerifications(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListVerifications failed: %v", err)
+	}
+	if len(verifications) != 4 {
+		t.Fatalf("Expected 4 verifications, got %d", len(verifications))
+	}
+
+	want := []struct{ name, status string }{
+		{"lfs-pointers", "passed"},
+		{"repository-sizes", "failed"},
+		{"lfs-objects", "failed"},
+		{"lfs-content", "failed"},
+	}
+	for i, w := range want {
+		if verifications[i].Name != w.name || verifications[i].Status != w.status {
+			t.Errorf("Verification %d = %s/%s, want %s/%s", i, verifications[i].Name, verifications[i].Status, w.name, w.status)
+		}
+	}
+	if verifications[1].Message != failure.Error() {
+		t.Errorf("Message = %q, want %q", verifications[1].Message, failure.Error())
+	}
+
+	// Only the failures that did not stop the run are counted for the run notes
+	if runner.verifyFailures != 2 {
+		t.Errorf("verifyFailures = %d, want 2", runner.verifyFailures)
+	}
+}
+
+func TestRecordInterference(t *testing.T) {
+	runner := newVerifyRunner(t)
+
+	runner.recordInterference(1, &interference.Report{FileOpTime: time.Microsecond}, nil)
+	runner.recordInterference(2, &interference.Report{
+		Scanners: []interference.Process{{Name: "MsMpEng.exe", What: "Microsoft Defender"}},
+	}, nil)
+
+	verifications, err := runner.DB.ListVerifications(runner.RunID)
+	if err != nil {
+		t.Fatalf("ListVerifications failed: %v", err)
+	}
+	if len(verifications) != 2 {
+		t.Fatalf("Expected 2 verifications, got %d", len(verifications))
+	}
+	if v := verifications[0]; v.Name != "interference" || v.Status != "passed" {
+		t.Errorf("clean step: %s/%s", v.Name, v.Status)
+	}
+	if v := verifications[1]; v.Status != "failed" || v.Severity != SeverityWarning || !strings.Contains(v.Message, "MsMpEng.exe") {
+		t.Errorf("step with a scanner: %+v", v)
+	}
+}
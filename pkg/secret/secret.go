@@ -0,0 +1,196 @@
+// Package secret resolves the credentials of servers, e.g. access tokens, from where they
+// are kept, and redacts them from logs and the database. A setting that holds a secret
+// can name where to find it instead of holding it:
+//
+//	env:NAME                  the environment variable NAME
+//	file:PATH                 the first line of a file only its owner can read
+//	keychain:SERVICE/ACCOUNT  the OS keychain: the login keychain on macOS, the Secret
+//	                          Service (GNOME Keyring, KWallet) through secret-tool on Linux
+//
+// Any other value is the secret itself.
+package secret
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Mask replaces secrets in redacted text
+const Mask = "******"
+
+// MinLength is the length below which a value is not redacted, as it would hide ordinary
+// words and numbers rather than a credential
+const MinLength = 6
+
+// Sources of secrets, as reference prefixes
+const (
+	SourceEnv      = "env"
+	SourceFile     = "file"
+	SourceKeychain = "keychain"
+)
+
+var (
+	mu     sync.RWMutex
+	values []string // Registered secrets, longest first
+)
+
+// IsReference reports whether a value names where a secret is kept, rather than being one
+func IsReference(value string) bool {
+	source, _, ok := strings.Cut(value, ":")
+	return ok && (source == SourceEnv || source == SourceFile || source == SourceKeychain)
+}
+
+// Check returns an error if a reference is malformed, without resolving it
+func Check(value string) error {
+	_, err := parse(value)
+	return err
+}
+
+// reference is where a secret is kept
+type reference struct {
+	source  string
+	name    string // Environment variable or file
+	service string // Keychain item
+	account string
+}
+
+func parse(value string) (*reference, error) {
+	source, rest, _ := strings.Cut(value, ":")
+	ref := &reference{source: source, name: rest}
+	switch source {
+	case SourceEnv:
+		if rest == "" {
+			return nil, fmt.Errorf("invalid secret reference '%s' (use env:NAME)", value)
+		}
+	case SourceFile:
+		if rest == "" {
+			return nil, fmt.Errorf("invalid secret reference '%s' (use file:PATH)", value)
+		}
+	case SourceKeychain:
+		service, account, ok := strings.Cut(rest, "/")
+		if !ok || service == "" || account == "" {
+			return nil, fmt.Errorf("invalid secret reference '%s' (use keychain:SERVICE/ACCOUNT)", value)
+		}
+		ref.service, ref.account = service, account
+	default:
+		return nil, fmt.Errorf("invalid secret reference '%s' (use env:, file: or keychain:)", value)
+	}
+	return ref, nil
+}
+
+// Resolve returns the secret a value refers to, or the value itself if it is not a
+// reference, and registers the secret for redaction
+func Resolve(value string) (string, error) {
+	if !IsReference(value) {
+		Register(value)
+		return value, nil
+	}
+	ref, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+
+	var secret string
+	switch ref.source {
+	case SourceEnv:
+		secret = os.Getenv(ref.name)
+		if secret == "" {
+			return "", fmt.Errorf("environment variable %s is not set", ref.name)
+		}
+	case SourceFile:
+		secret, err = readFile(ref.name)
+	case SourceKeychain:
+		secret, err = Keychain(ref.service, ref.account)
+	}
+	if err != nil {
+		return "", err
+	}
+	Register(secret)
+	return secret, nil
+}
+
+// readFile returns the first line of a secret file, refusing files that others can read,
+// as ssh does for private keys
+func readFile(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + path[1:]
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("secret file %s can be read by others; restrict it with: chmod 600 %s", path, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	if line = strings.TrimSpace(line); line == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return line, nil
+}
+
+// Keychain returns the secret of an item of the OS keychain
+func Keychain(service, account string) (string, error) {
+	var cmd *exec.Cmd
+	var hint string
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+		hint = fmt.Sprintf("security add-generic-password -s %s -a %s -w", service, account)
+	} else {
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return "", fmt.Errorf("secret-tool is needed to read the keychain; install it with: sudo apt install libsecret-tools")
+		}
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "account", account)
+		hint = fmt.Sprintf("secret-tool store --label lfst service %s account %s", service, account)
+	}
+	output, err := cmd.Output()
+	secret := strings.TrimSpace(string(output))
+	if err != nil || secret == "" {
+		return "", fmt.Errorf("no keychain item for service %s and account %s; add one with: %s", service, account, hint)
+	}
+	return secret, nil
+}
+
+// Register adds secrets to those Redact hides; values shorter than MinLength are ignored
+func Register(secrets ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range secrets {
+		if len(s) < MinLength || slices.Contains(values, s) {
+			continue
+		}
+		values = append(values, s)
+	}
+	// Longest first, so a secret that contains another is hidden whole
+	slices.SortStableFunc(values, func(a, b string) int { return len(b) - len(a) })
+}
+
+// Reset forgets the registered secrets
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	values = nil
+}
+
+// Redact replaces the registered secrets in text with Mask
+func Redact(text string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, s := range values {
+		if strings.Contains(text, s) {
+			text = strings.ReplaceAll(text, s, Mask)
+		}
+	}
+	return text
+}
@@ -0,0 +1,278 @@
+// Package serverlog reads the restarts, stops and out-of-memory kills of an LFS server
+// from its service manager, systemd or Docker, on the server's host, so failed operations
+// can be traced to what happened on the server while they ran.
+package serverlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mslinn/git-lfs-test/pkg/sshutil"
+)
+
+// Service managers
+const (
+	ManagerSystemd = "systemd"
+	ManagerDocker  = "docker"
+)
+
+// Kinds of Event
+const (
+	KindStart = "start" // The server started or was restarted
+	KindStop  = "stop"  // The server stopped, exited or was killed
+	KindOOM   = "oom"   // The kernel or the container runtime killed a process for lack of memory
+)
+
+// Event is something that happened to the server
+type Event struct {
+	At      time.Time
+	Kind    string
+	Message string
+}
+
+// String describes the event, e.g. "oom at 14:02:03: Out of memory: Killed process 812 (rudolfs)"
+func (e Event) String() string {
+	return fmt.Sprintf("%s at %s: %s", e.Kind, e.At.Local().Format("15:04:05"), e.Message)
+}
+
+// Source is the service running an LFS server
+type Source struct {
+	Host    string // SSH host; empty means this machine
+	Manager string // ManagerSystemd or ManagerDocker
+	Name    string // systemd unit or Docker container
+}
+
+// ParseSource parses [HOST:]systemd:UNIT or [HOST:]docker:CONTAINER
+func ParseSource(spec string) (*Source, error) {
+	parts := strings.Split(spec, ":")
+	var s Source
+	switch len(parts) {
+	case 2:
+		s.Manager, s.Name = parts[0], parts[1]
+	case 3:
+		s.Host, s.Manager, s.Name = parts[0], parts[1], parts[2]
+	default:
+		return nil, fmt.Errorf("invalid server service '%s' (use [HOST:]systemd:UNIT or [HOST:]docker:CONTAINER)", spec)
+	}
+	if s.Manager != ManagerSystemd && s.Manager != ManagerDocker {
+		return nil, fmt.Errorf("unknown service manager '%s' in '%s' (use %s or %s)", s.Manager, spec, ManagerSystemd, ManagerDocker)
+	}
+	if s.Name == "" {
+		return nil, fmt.Errorf("server service '%s' has no name", spec)
+	}
+	return &s, nil
+}
+
+// String returns the source in the form ParseSource accepts
+func (s *Source) String() string {
+	if s.Host == "" {
+		return s.Manager + ":" + s.Name
+	}
+	return s.Host + ":" + s.Manager + ":" + s.Name
+}
+
+// command runs a program on the service's host
+func (s *Source) command(name string, args ...string) *exec.Cmd {
+	if s.Host == "" {
+		return exec.Command(name, args...)
+	}
+	remote := []string{name}
+	for _, arg := range args {
+		remote = append(remote, "'"+strings.ReplaceAll(arg, "'", `'\''`)+"'")
+	}
+	return sshutil.Command(s.Host, strings.Join(remote, " "))
+}
+
+// program is the command that reads the service manager's events
+func (s *Source) program() string {
+	if s.Manager == ManagerDocker {
+		return "docker"
+	}
+	return "journalctl"
+}
+
+// CheckTools checks that the service manager's command can be run on the host
+func (s *Source) CheckTools() error {
+	if err := s.command(s.program(), "--version").Run(); err != nil {
+		where := "this machine"
+		if s.Host != "" {
+			where = s.Host
+		}
+		return fmt.Errorf("cannot run %s on %s: %w", s.program(), where, err)
+	}
+	return nil
+}
+
+// Events returns what happened to the service between since and until, oldest first
+func (s *Source) Events(since, until time.Time) ([]Event, error) {
+	if s.Manager == ManagerDocker {
+		out, err := s.command("docker", "events",
+			"--since", strconv.FormatInt(since.Unix(), 10), "--until", strconv.FormatInt(until.Unix()+1, 10),
+			"--filter", "container="+s.Name, "--format", "{{json .}}").Output()
+		if err != nil {
+			return nil, fmt.Errorf("docker events failed: %w", err)
+		}
+		return ParseDockerEvents(strings.NewReader(string(out)))
+	}
+
+	window := []string{"--since", fmt.Sprintf("@%d", since.Unix()), "--until", fmt.Sprintf("@%d", until.Unix()+1),
+		"--output", "json", "--no-pager", "--quiet"}
+	unit, err := s.command("journalctl", append([]string{"--unit", s.Name}, window...)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("journalctl failed: %w", err)
+	}
+	events, err := ParseJournal(strings.NewReader(string(unit)))
+	if err != nil {
+		return nil, err
+	}
+
+	// Kernel messages need access to the system journal; do without them if it is denied
+	kernel, err := s.command("journalctl", append([]string{"--dmesg", "--priority", "err"}, window...)...).Output()
+	if err == nil {
+		oom, err := ParseJournal(strings.NewReader(string(kernel)))
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, oom...)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	return events, nil
+}
+
+// journalEntry holds the fields of journalctl --output json that matter here
+type journalEntry struct {
+	Timestamp  string          `json:"__REALTIME_TIMESTAMP"` // Microseconds since the epoch
+	Identifier string          `json:"SYSLOG_IDENTIFIER"`
+	Message    json.RawMessage `json:"MESSAGE"` // A string, or an array of bytes if it is not valid UTF-8
+}
+
+// ParseJournal extracts events from journalctl --output json: the messages of systemd
+// about the unit, and the kernel's out-of-memory kills. What the server itself logs is ignored.
+func ParseJournal(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry journalEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse journal entry: %w", err)
+		}
+		usec, err := strconv.ParseInt(entry.Timestamp, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		var message string
+		if err := json.Unmarshal(entry.Message, &message); err != nil {
+			var raw []byte
+			if json.Unmarshal(entry.Message, &raw) != nil {
+				continue
+			}
+			message = string(raw)
+		}
+
+		kind := ""
+		switch entry.Identifier {
+		case "kernel":
+			if strings.Contains(message, "Out of memory") || strings.Contains(message, "Memory cgroup out of memory") {
+				kind = KindOOM
+			}
+		case "systemd":
+			kind = systemdKind(message)
+		}
+		if kind != "" {
+			events = append(events, Event{At: time.UnixMicro(usec), Kind: kind, Message: message})
+		}
+	}
+	return events, scanner.Err()
+}
+
+// systemdKind classifies a message of systemd about a unit; it returns "" for the others
+func systemdKind(message string) string {
+	switch {
+	case strings.Contains(message, "OOM killer"), strings.Contains(message, "oom-kill"):
+		return KindOOM
+	case strings.Contains(message, "Main process exited"), strings.HasPrefix(message, "Stopped "),
+		strings.Contains(message, "Failed with result"):
+		return KindStop
+	case strings.HasPrefix(message, "Started "), strings.Contains(message, "Scheduled restart job"):
+		return KindStart
+	}
+	return ""
+}
+
+// dockerEvent holds the fields of docker events --format '{{json .}}' that matter here
+type dockerEvent struct {
+	Action   string `json:"Action"`
+	TimeNano int64  `json:"timeNano"`
+	Actor    struct {
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+}
+
+// ParseDockerEvents extracts events from docker events --format '{{json .}}'
+func ParseDockerEvents(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e dockerEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse docker event: %w", err)
+		}
+
+		name := e.Actor.Attributes["name"]
+		event := Event{At: time.Unix(0, e.TimeNano)}
+		switch e.Action {
+		case "oom":
+			event.Kind, event.Message = KindOOM, fmt.Sprintf("container %s ran out of memory", name)
+		case "die":
+			event.Kind, event.Message = KindStop, fmt.Sprintf("container %s exited with code %s", name, e.Actor.Attributes["exitCode"])
+		case "kill":
+			event.Kind, event.Message = KindStop, fmt.Sprintf("container %s was sent signal %s", name, e.Actor.Attributes["signal"])
+		case "start":
+			event.Kind, event.Message = KindStart, fmt.Sprintf("container %s started", name)
+		case "restart":
+			event.Kind, event.Message = KindStart, fmt.Sprintf("container %s restarted", name)
+		default:
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// Overlapping returns the events between start-slack and end+slack; slack allows for
+// log timestamps that lag, and for server and client clocks that differ slightly
+func Overlapping(events []Event, start, end time.Time, slack time.Duration) []Event {
+	var overlapping []Event
+	for _, e := range events {
+		if !e.At.Before(start.Add(-slack)) && !e.At.After(end.Add(slack)) {
+			overlapping = append(overlapping, e)
+		}
+	}
+	return overlapping
+}
+
+// Describe joins the descriptions of events, e.g. to annotate an operation
+func Describe(events []Event) string {
+	descriptions := make([]string, len(events))
+	for i, e := range events {
+		descriptions[i] = e.String()
+	}
+	return strings.Join(descriptions, "; ")
+}
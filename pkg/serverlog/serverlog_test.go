@@ -0,0 +1,100 @@
+package serverlog
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Source
+	}{
+		{"systemd:giftless", Source{Manager: ManagerSystemd, Name: "giftless"}},
+		{"gojira:docker:rudolfs", Source{Host: "gojira", Manager: ManagerDocker, Name: "rudolfs"}},
+	}
+	for _, tt := range tests {
+		s, err := ParseSource(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSource(%s) failed: %v", tt.spec, err)
+			continue
+		}
+		if *s != tt.want || s.String() != tt.spec {
+			t.Errorf("ParseSource(%s) = %+v", tt.spec, s)
+		}
+	}
+
+	for _, spec := range []string{"giftless", "gojira:podman:giftless", "systemd:", "a:b:c:d"} {
+		if _, err := ParseSource(spec); err == nil {
+			t.Errorf("ParseSource(%s) should fail", spec)
+		}
+	}
+}
+
+func TestParseJournal(t *testing.T) {
+	journal := `{"__REALTIME_TIMESTAMP":"1760000000000000","SYSLOG_IDENTIFIER":"systemd","MESSAGE":"Started Giftless LFS server."}
+{"__REALTIME_TIMESTAMP":"1760000001000000","SYSLOG_IDENTIFIER":"giftless","MESSAGE":"Started worker 3"}
+{"__REALTIME_TIMESTAMP":"1760000002000000","SYSLOG_IDENTIFIER":"kernel","MESSAGE":"Out of memory: Killed process 812 (giftless) total-vm:2048kB"}
+{"__REALTIME_TIMESTAMP":"1760000002500000","SYSLOG_IDENTIFIER":"systemd","MESSAGE":"giftless.service: A process of this unit has been killed by the OOM killer."}
+{"__REALTIME_TIMESTAMP":"1760000003000000","SYSLOG_IDENTIFIER":"systemd","MESSAGE":"giftless.service: Main process exited, code=killed, status=9/KILL"}
+{"__REALTIME_TIMESTAMP":"1760000004000000","SYSLOG_IDENTIFIER":"systemd","MESSAGE":[83,116,111,112,112,101,100,32,103,105,102,116,108,101,115,115,46]}
+`
+	events, err := ParseJournal(strings.NewReader(journal))
+	if err != nil {
+		t.Fatalf("ParseJournal failed: %v", err)
+	}
+
+	kinds := []string{KindStart, KindOOM, KindOOM, KindStop, KindStop}
+	if len(events) != len(kinds) {
+		t.Fatalf("ParseJournal found %d events, want %d: %v", len(events), len(kinds), events)
+	}
+	for i, kind := range kinds {
+		if events[i].Kind != kind {
+			t.Errorf("event %d (%s) is %s, want %s", i, events[i].Message, events[i].Kind, kind)
+		}
+	}
+	if !events[1].At.Equal(time.Unix(1760000002, 0)) {
+		t.Errorf("event time = %v", events[1].At)
+	}
+	if events[4].Message != "Stopped giftless." {
+		t.Errorf("a message given as bytes = %q", events[4].Message)
+	}
+}
+
+func TestParseDockerEvents(t *testing.T) {
+	out := `{"Type":"container","Action":"oom","Actor":{"ID":"3f2a","Attributes":{"name":"rudolfs"}},"time":1760000000,"timeNano":1760000000100000000}
+{"Type":"container","Action":"die","Actor":{"ID":"3f2a","Attributes":{"exitCode":"137","name":"rudolfs"}},"time":1760000000,"timeNano":1760000000200000000}
+{"Type":"container","Action":"exec_start","Actor":{"ID":"3f2a","Attributes":{"name":"rudolfs"}},"time":1760000001,"timeNano":1760000001000000000}
+{"Type":"container","Action":"restart","Actor":{"ID":"3f2a","Attributes":{"name":"rudolfs"}},"time":1760000002,"timeNano":1760000002000000000}
+`
+	events, err := ParseDockerEvents(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("ParseDockerEvents failed: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("ParseDockerEvents found %d events, want 3: %v", len(events), events)
+	}
+	if events[0].Kind != KindOOM || events[1].Message != "container rudolfs exited with code 137" ||
+		events[2].Kind != KindStart || events[2].Message != "container rudolfs restarted" {
+		t.Errorf("ParseDockerEvents = %v", events)
+	}
+}
+
+func TestOverlapping(t *testing.T) {
+	start := time.Unix(1760000000, 0)
+	events := []Event{
+		{At: start.Add(-10 * time.Second), Kind: KindStop},
+		{At: start.Add(-2 * time.Second), Kind: KindStop},
+		{At: start.Add(30 * time.Second), Kind: KindOOM},
+		{At: start.Add(64 * time.Second), Kind: KindStart},
+		{At: start.Add(70 * time.Second), Kind: KindStart},
+	}
+	overlapping := Overlapping(events, start, start.Add(time.Minute), 5*time.Second)
+	if len(overlapping) != 3 || overlapping[0] != events[1] || overlapping[2] != events[3] {
+		t.Errorf("Overlapping = %v", overlapping)
+	}
+	if description := Describe(overlapping[1:2]); !strings.HasPrefix(description, "oom at ") {
+		t.Errorf("Describe = %s", description)
+	}
+}
@@ -0,0 +1,150 @@
+package sshutil
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HostOptions are the SSH connection settings for one host
+type HostOptions struct {
+	Port         int    `yaml:"port,omitempty"`          // SSH port; 0 means the ssh default
+	IdentityFile string `yaml:"identity_file,omitempty"` // Private key file (~/ is expanded)
+	ProxyJump    string `yaml:"proxy_jump,omitempty"`    // Bastion host(s), as for ssh -J
+}
+
+// Fields lists the per-host option names accepted by Set and Get
+var Fields = []string{"port", "identity_file", "proxy_jump"}
+
+var (
+	mu    sync.RWMutex
+	hosts map[string]HostOptions
+)
+
+// Configure sets the per-host options applied to every ssh and rsync command built by this package.
+// A "*" entry applies to hosts without their own entry.
+func Configure(h map[string]HostOptions) {
+	mu.Lock()
+	defer mu.Unlock()
+	hosts = h
+}
+
+// Lookup returns the options for host, which may include a user ("user@host")
+func Lookup(host string) HostOptions {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if opts, ok := hosts[host]; ok {
+		return opts
+	}
+	if _, name, ok := strings.Cut(host, "@"); ok {
+		if opts, ok := hosts[name]; ok {
+			return opts
+		}
+	}
+	return hosts["*"]
+}
+
+// Options returns the ssh command-line options for these settings
+func (o HostOptions) Options() []string {
+	var args []string
+	if o.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(o.Port))
+	}
+	if o.IdentityFile != "" {
+		args = append(args, "-i", expandHome(o.IdentityFile))
+	}
+	if o.ProxyJump != "" {
+		args = append(args, "-J", o.ProxyJump)
+	}
+	return args
+}
+
+// Set assigns one option by name, as used in config keys like ssh_hosts.HOST.port
+func (o *HostOptions) Set(field, value string) error {
+	switch field {
+	case "port":
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 0 || port > 65535 {
+			return fmt.Errorf("invalid port '%s'", value)
+		}
+		o.Port = port
+	case "identity_file":
+		o.IdentityFile = value
+	case "proxy_jump":
+		o.ProxyJump = value
+	default:
+		return unknownField(field)
+	}
+	return nil
+}
+
+// Get returns one option by name
+func (o HostOptions) Get(field string) (string, error) {
+	switch field {
+	case "port":
+		if o.Port == 0 {
+			return "", nil
+		}
+		return strconv.Itoa(o.Port), nil
+	case "identity_file":
+		return o.IdentityFile, nil
+	case "proxy_jump":
+		return o.ProxyJump, nil
+	default:
+		return "", unknownField(field)
+	}
+}
+
+// Args returns the arguments for ssh up to and including the host: the configured
+// options for host, then extra (e.g. "-o", "BatchMode=yes"), then host itself.
+// Append the remote command to the result.
+func Args(host string, extra ...string) []string {
+	args := Lookup(host).Options()
+	args = append(args, extra...)
+	return append(args, host)
+}
+
+// Command builds an ssh command that runs remote on host with the configured options
+func Command(host string, remote ...string) *exec.Cmd {
+	return exec.Command("ssh", append(Args(host), remote...)...)
+}
+
+// Quote quotes s for the POSIX shell that runs remote commands, unless it needs no quoting
+func Quote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=@:+,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RsyncShell returns the value for rsync's -e option when connecting to host
+func RsyncShell(host string) string {
+	parts := []string{"ssh"}
+	for _, arg := range Lookup(host).Options() {
+		if strings.ContainsAny(arg, " \t'\"") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// expandHome expands a leading ~/ to the user's home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, path[2:])
+		}
+	}
+	return path
+}
+
+// unknownField reports an option name that is not in Fields
+func unknownField(field string) error {
+	return fmt.Errorf("unknown SSH option '%s' (use %s)", field, strings.Join(Fields, ", "))
+}
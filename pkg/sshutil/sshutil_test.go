@@ -0,0 +1,75 @@
+package sshutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArgs(t *testing.T) {
+	Configure(map[string]HostOptions{
+		"lab": {Port: 2222, IdentityFile: "/keys/lab", ProxyJump: "admin@bastion"},
+		"*":   {ProxyJump: "gateway"},
+	})
+	defer Configure(nil)
+
+	tests := []struct {
+		host  string
+		extra []string
+		want  []string
+	}{
+		{"lab", nil, []string{"-p", "2222", "-i", "/keys/lab", "-J", "admin@bastion", "lab"}},
+		{"me@lab", []string{"-o", "BatchMode=yes"}, []string{"-p", "2222", "-i", "/keys/lab", "-J", "admin@bastion", "-o", "BatchMode=yes", "me@lab"}},
+		{"other", nil, []string{"-J", "gateway", "other"}},
+	}
+
+	for _, tt := range tests {
+		if got := Args(tt.host, tt.extra...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Args(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestRsyncShell(t *testing.T) {
+	Configure(map[string]HostOptions{
+		"lab": {Port: 2222, IdentityFile: "/my keys/lab"},
+	})
+	defer Configure(nil)
+
+	if got, want := RsyncShell("lab"), "ssh -p 2222 -i '/my keys/lab'"; got != want {
+		t.Errorf("RsyncShell(lab) = %q, want %q", got, want)
+	}
+	if got := RsyncShell("plain"); got != "ssh" {
+		t.Errorf("RsyncShell(plain) = %q, want %q", got, "ssh")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"/data/v1/pdf1.pdf": "/data/v1/pdf1.pdf",
+		"/my data/a.pdf":    "'/my data/a.pdf'",
+		"/data/it's.pdf":    `'/data/it'\''s.pdf'`,
+		"a; rm -rf ~":       "'a; rm -rf ~'",
+		"":                  "''",
+	}
+	for s, want := range tests {
+		if got := Quote(s); got != want {
+			t.Errorf("Quote(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestSetGet(t *testing.T) {
+	var opts HostOptions
+	if err := opts.Set("port", "2222"); err != nil {
+		t.Fatalf("Set port failed: %v", err)
+	}
+	if err := opts.Set("port", "abc"); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+	if err := opts.Set("user", "me"); err == nil {
+		t.Error("Expected error for unknown option")
+	}
+	if value, _ := opts.Get("port"); value != "2222" {
+		t.Errorf("Get port = %q, want 2222", value)
+	}
+}
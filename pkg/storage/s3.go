@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// S3 checks objects in an S3 or S3-compatible (e.g. MinIO) bucket with the aws CLI
+type S3 struct {
+	Bucket   string
+	Prefix   string // Key prefix, e.g. "org/repo" for Giftless; empty for none
+	Layout   Layout // nil uses the plain OID layout
+	Endpoint string // Endpoint URL of an S3-compatible service; empty for AWS
+	Profile  string // aws CLI profile; empty for the default
+}
+
+// ParseS3URL parses a location such as s3://bucket or s3://bucket/org/repo
+func ParseS3URL(url string) (*S3, error) {
+	rest, ok := strings.CutPrefix(url, "s3://")
+	if !ok {
+		return nil, fmt.Errorf("invalid S3 location '%s' (use s3://BUCKET/PREFIX)", url)
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid S3 location '%s': missing bucket", url)
+	}
+	return &S3{Bucket: bucket, Prefix: strings.Trim(prefix, "/")}, nil
+}
+
+// Location returns the bucket and prefix as an s3:// URL
+func (s *S3) Location() string {
+	if s.Prefix == "" {
+		return "s3://" + s.Bucket
+	}
+	return "s3://" + s.Bucket + "/" + s.Prefix
+}
+
+// Key returns the object key of an OID
+func (s *S3) Key(oid string) string {
+	layout := s.Layout
+	if layout == nil {
+		layout = LayoutFor("")
+	}
+	if s.Prefix == "" {
+		return layout(oid)
+	}
+	return strings.Trim(s.Prefix, "/") + "/" + layout(oid)
+}
+
+// args appends the endpoint and profile options to an aws command
+func (s *S3) args(args ...string) []string {
+	if s.Endpoint != "" {
+		args = append(args, "--endpoint-url", s.Endpoint)
+	}
+	if s.Profile != "" {
+		args = append(args, "--profile", s.Profile)
+	}
+	return args
+}
+
+// CheckCLI returns an error if the aws CLI is not installed
+func (s *S3) CheckCLI() error {
+	result := timing.Run("aws", []string{"--version"}, nil)
+	if result.Error != nil || result.ExitCode != 0 {
+		return fmt.Errorf("aws CLI not available - install with: sudo apt install awscli")
+	}
+	return nil
+}
+
+// Stat returns the stored size of an object using aws s3api head-object
+func (s *S3) Stat(oid string) (int64, bool, error) {
+	result := timing.Run("aws", s.args("s3api", "head-object", "--bucket", s.Bucket, "--key", s.Key(oid), "--output", "json"), nil)
+	return parseHeadObject(result)
+}
+
+// parseHeadObject reads the object size from head-object output; a 404 means the object is missing
+func parseHeadObject(result *timing.Result) (int64, bool, error) {
+	if result.ExitCode == -1 {
+		return 0, false, fmt.Errorf("aws s3api head-object failed: %v", result.Error)
+	}
+	if result.ExitCode != 0 {
+		stderr := strings.TrimSpace(result.Stderr)
+		if strings.Contains(stderr, "(404)") || strings.Contains(stderr, "Not Found") {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("aws s3api head-object failed (exit %d): %s", result.ExitCode, stderr)
+	}
+
+	var head struct {
+		ContentLength int64 `json:"ContentLength"`
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), &head); err != nil {
+		return 0, false, fmt.Errorf("failed to parse head-object output: %w", err)
+	}
+	return head.ContentLength, true, nil
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Object is an LFS object that should be in the server's storage backend
+type Object struct {
+	OID  string
+	Size int64
+}
+
+// Probe looks up objects in an LFS server's storage backend
+type Probe interface {
+	// Location describes the backend, e.g. "s3://bucket/prefix"
+	Location() string
+	// Key returns the backend key of an object
+	Key(oid string) string
+	// Stat returns the stored size of an object; exists is false if it is missing
+	Stat(oid string) (size int64, exists bool, err error)
+}
+
+// Statuses of a Result
+const (
+	StatusOK           = "ok"
+	StatusMissing      = "missing"
+	StatusSizeMismatch = "size-mismatch"
+	StatusError        = "error"
+)
+
+// Result is the outcome of checking one object
+type Result struct {
+	Object
+	Key        string
+	ActualSize *int64 // nil if the object is missing or could not be checked
+	Status     string
+	Message    string
+}
+
+// Layout names an object in a bucket, relative to the backend's key prefix
+type Layout func(oid string) string
+
+// Layouts maps LFS server types to how they name objects in a bucket
+var Layouts = map[string]Layout{
+	// Rudolfs fans objects out by the first two byte pairs of the OID
+	"rudolfs": func(oid string) string {
+		if len(oid) < 4 {
+			return "objects/" + oid
+		}
+		return fmt.Sprintf("objects/%s/%s/%s", oid[0:2], oid[2:4], oid)
+	},
+	// Giftless stores each object under the OID, below an org/repo prefix
+	"giftless": func(oid string) string { return oid },
+}
+
+// LayoutFor returns the object layout of a server type, or the plain OID layout if it is unknown
+func LayoutFor(serverType string) Layout {
+	if layout, ok := Layouts[serverType]; ok {
+		return layout
+	}
+	return Layouts["giftless"]
+}
+
+// Check looks up each object and compares its stored size with the expected size
+func Check(probe Probe, objects []Object) []*Result {
+	results := make([]*Result, 0, len(objects))
+	for _, obj := range objects {
+		result := &Result{Object: obj, Key: probe.Key(obj.OID), Status: StatusOK}
+
+		size, exists, err := probe.Stat(obj.OID)
+		switch {
+		case err != nil:
+			result.Status = StatusError
+			result.Message = err.Error()
+		case !exists:
+			result.Status = StatusMissing
+			result.Message = fmt.Sprintf("%s not found in %s", result.Key, probe.Location())
+		default:
+			result.ActualSize = &size
+			if size != obj.Size {
+				result.Status = StatusSizeMismatch
+				result.Message = fmt.Sprintf("stored size %d, expected %d", size, obj.Size)
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Failures counts the results that are not ok
+func Failures(results []*Result) int {
+	failures := 0
+	for _, r := range results {
+		if r.Status != StatusOK {
+			failures++
+		}
+	}
+	return failures
+}
+
+// LocalObjects lists the objects in a repository's local LFS store (.git/lfs/objects), sorted by OID.
+// After a push, every one of them should also be in the server's storage.
+func LocalObjects(repoDir string) ([]Object, error) {
+	root := filepath.Join(repoDir, ".git", "lfs", "objects")
+
+	var objects []Object
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		// Objects are named by their 64-character SHA-256 OID; skip temporary and incomplete files
+		if d.IsDir() || len(d.Name()) != 64 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{OID: d.Name(), Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list LFS objects: %w", err)
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].OID < objects[j].OID })
+	return objects, nil
+}
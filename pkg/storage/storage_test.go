@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mslinn/git-lfs-test/pkg/timing"
+)
+
+// fakeProbe serves object sizes from a map
+type fakeProbe struct {
+	sizes map[string]int64
+	err   error
+}
+
+func (f *fakeProbe) Location() string      { return "fake://bucket" }
+func (f *fakeProbe) Key(oid string) string { return "objects/" + oid }
+func (f *fakeProbe) Stat(oid string) (int64, bool, error) {
+	if f.err != nil {
+		return 0, false, f.err
+	}
+	size, ok := f.sizes[oid]
+	return size, ok, nil
+}
+
+func TestCheck(t *testing.T) {
+	probe := &fakeProbe{sizes: map[string]int64{"aa": 10, "bb": 5}}
+	objects := []Object{{OID: "aa", Size: 10}, {OID: "bb", Size: 20}, {OID: "cc", Size: 30}}
+
+	results := Check(probe, objects)
+
+	want := []string{StatusOK, StatusSizeMismatch, StatusMissing}
+	for i, r := range results {
+		if r.Status != want[i] {
+			t.Errorf("%s: Status = %s, want %s", r.OID, r.Status, want[i])
+		}
+	}
+	if results[1].ActualSize == nil || *results[1].ActualSize != 5 {
+		t.Errorf("size mismatch should record the stored size, got %v", results[1].ActualSize)
+	}
+	if results[2].ActualSize != nil || results[2].Key != "objects/cc" {
+		t.Errorf("missing object = %+v", results[2])
+	}
+	if Failures(results) != 2 {
+		t.Errorf("Failures = %d, want 2", Failures(results))
+	}
+
+	probe.err = errors.New("access denied")
+	if results := Check(probe, objects[:1]); results[0].Status != StatusError || results[0].Message != "access denied" {
+		t.Errorf("probe error = %+v", results[0])
+	}
+}
+
+func TestLocalObjects(t *testing.T) {
+	repo := t.TempDir()
+
+	if objects, err := LocalObjects(repo); err != nil || len(objects) != 0 {
+		t.Fatalf("LocalObjects without an LFS store = %v, %v; want none", objects, err)
+	}
+
+	oidA := strings.Repeat("a", 64)
+	oidB := strings.Repeat("b", 64)
+	for oid, content := range map[string]string{oidB: "bbbb", oidA: "aa"} {
+		dir := filepath.Join(repo, ".git", "lfs", "objects", oid[0:2], oid[2:4])
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, oid), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Incomplete downloads live in a tmp directory under other names
+	tmp := filepath.Join(repo, ".git", "lfs", "objects", "tmp")
+	os.MkdirAll(tmp, 0755)
+	os.WriteFile(filepath.Join(tmp, "partial"), []byte("x"), 0644)
+
+	objects, err := LocalObjects(repo)
+	if err != nil {
+		t.Fatalf("LocalObjects failed: %v", err)
+	}
+	if len(objects) != 2 || objects[0] != (Object{OID: oidA, Size: 2}) || objects[1] != (Object{OID: oidB, Size: 4}) {
+		t.Errorf("LocalObjects = %+v", objects)
+	}
+}
+
+func TestS3Keys(t *testing.T) {
+	oid := "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+
+	s3, err := ParseS3URL("s3://lfs-bucket/org/repo/")
+	if err != nil {
+		t.Fatalf("ParseS3URL failed: %v", err)
+	}
+	if s3.Bucket != "lfs-bucket" || s3.Location() != "s3://lfs-bucket/org/repo" {
+		t.Errorf("ParseS3URL = %+v", s3)
+	}
+	if got := s3.Key(oid); got != "org/repo/"+oid {
+		t.Errorf("Giftless key = %s", got)
+	}
+
+	s3 = &S3{Bucket: "lfs", Layout: LayoutFor("rudolfs")}
+	if got := s3.Key(oid); got != "objects/4d/7a/"+oid {
+		t.Errorf("Rudolfs key = %s", got)
+	}
+
+	for _, bad := range []string{"lfs-bucket", "s3://", "https://lfs-bucket"} {
+		if _, err := ParseS3URL(bad); err == nil {
+			t.Errorf("ParseS3URL(%q) should fail", bad)
+		}
+	}
+}
+
+func TestParseHeadObject(t *testing.T) {
+	size, exists, err := parseHeadObject(&timing.Result{Stdout: `{"ContentLength": 1234, "ETag": "\"x\""}`})
+	if err != nil || !exists || size != 1234 {
+		t.Errorf("present object = %d, %v, %v", size, exists, err)
+	}
+
+	missing := &timing.Result{ExitCode: 254, Stderr: "An error occurred (404) when calling the HeadObject operation: Not Found"}
+	if _, exists, err := parseHeadObject(missing); err != nil || exists {
+		t.Errorf("missing object = %v, %v; want not found without error", exists, err)
+	}
+
+	denied := &timing.Result{ExitCode: 254, Stderr: "An error occurred (403) when calling the HeadObject operation: Forbidden"}
+	if _, _, err := parseHeadObject(denied); err == nil {
+		t.Error("a 403 should be an error, not a missing object")
+	}
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/sshutil"
+	"github.com/mslinn/git-lfs-test/pkg/testdata"
+)
+
+// Contents is what an LFS server's object store holds of a set of objects. Servers name
+// each object file after its OID, in whatever directories they fan objects out into.
+type Contents struct {
+	Objects int   // Objects of the set found in the store
+	Copies  int   // Files holding them; more than Objects if identical content is stored twice
+	Bytes   int64 // Summed size of those files
+	Other   int   // Files holding none of them, e.g. the objects of other repositories
+	// Damaged lists the OIDs of the set whose stored size differs from the expected size
+	Damaged []string
+}
+
+// Duplicates returns the number of redundant copies of objects
+func (c *Contents) Duplicates() int {
+	return c.Copies - c.Objects
+}
+
+// ScanStore looks for objects, given as OIDs and their sizes, in the object store at
+// location: a local directory, or host:/path for a directory on another machine
+func ScanStore(location string, objects map[string]int64) (*Contents, error) {
+	remote, isRemote := testdata.ParseRemotePath(location)
+	if !isRemote {
+		return scanLocalStore(location, objects)
+	}
+
+	quoted := "'" + strings.ReplaceAll(remote.Path, "'", `'\''`) + "'"
+	cmd := sshutil.Command(remote.Host, fmt.Sprintf("find %s -type f -printf '%%s %%f\\n'", quoted))
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s failed: %w", remote.Host, err)
+	}
+	return parseStoreListing(strings.NewReader(string(output)), objects)
+}
+
+// scanLocalStore walks a local object store; a missing directory is empty
+func scanLocalStore(dir string, objects map[string]int64) (*Contents, error) {
+	c := newContents()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		c.add(d.Name(), info.Size(), objects)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	return c.finish(), nil
+}
+
+// parseStoreListing reads "SIZE NAME" lines, as printed by find -printf '%s %f\n'
+func parseStoreListing(r io.Reader, objects map[string]int64) (*Contents, error) {
+	c := newContents()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		sizeText, name, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		size, err := strconv.ParseInt(sizeText, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected line in object store listing: %q", scanner.Text())
+		}
+		c.add(path.Base(name), size, objects)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c.finish(), nil
+}
+
+// contentsBuilder counts the files of a store as they are found
+type contentsBuilder struct {
+	Contents
+	found map[string]bool
+}
+
+func newContents() *contentsBuilder {
+	return &contentsBuilder{found: make(map[string]bool)}
+}
+
+// add counts the file name of size bytes
+func (c *contentsBuilder) add(name string, size int64, objects map[string]int64) {
+	expected, ok := objects[name]
+	if !ok {
+		c.Other++
+		return
+	}
+	c.Copies++
+	c.Bytes += size
+	if size != expected {
+		c.Damaged = append(c.Damaged, name)
+	}
+	if !c.found[name] {
+		c.found[name] = true
+		c.Objects++
+	}
+}
+
+func (c *contentsBuilder) finish() *Contents {
+	return &c.Contents
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanStore(t *testing.T) {
+	a := strings.Repeat("a", 64)
+	b := strings.Repeat("b", 64)
+	c := strings.Repeat("c", 64)
+	objects := map[string]int64{a: 3, b: 5, c: 7}
+
+	dir := t.TempDir()
+	for rel, content := range map[string]string{
+		"aa/aa/" + a:            "aaa",
+		"org/repo1/" + b:        "bbbbb",
+		"org/repo2/" + b:        "bbbbb", // The same content again
+		"tmp/" + c:              "cc",    // Truncated
+		"aa/bb/" + "other-file": "x",
+	} {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	contents, err := ScanStore(dir, objects)
+	if err != nil {
+		t.Fatalf("ScanStore failed: %v", err)
+	}
+	if contents.Objects != 3 || contents.Copies != 4 || contents.Duplicates() != 1 || contents.Bytes != 15 || contents.Other != 1 {
+		t.Errorf("ScanStore = %+v", contents)
+	}
+	if len(contents.Damaged) != 1 || contents.Damaged[0] != c {
+		t.Errorf("Damaged = %v, want the truncated object", contents.Damaged)
+	}
+
+	contents, err = ScanStore(filepath.Join(dir, "missing"), objects)
+	if err != nil || contents.Objects != 0 || contents.Copies != 0 {
+		t.Errorf("ScanStore of a missing store = %+v, %v; want it empty", contents, err)
+	}
+}
+
+func TestParseStoreListing(t *testing.T) {
+	a := strings.Repeat("a", 64)
+	listing := "3 " + a + "\n3 " + a + "\n12 lock\n"
+
+	contents, err := parseStoreListing(strings.NewReader(listing), map[string]int64{a: 3})
+	if err != nil {
+		t.Fatalf("parseStoreListing failed: %v", err)
+	}
+	if contents.Objects != 1 || contents.Copies != 2 || contents.Other != 1 || contents.Bytes != 6 {
+		t.Errorf("parseStoreListing = %+v", contents)
+	}
+
+	if _, err := parseStoreListing(strings.NewReader("x y\n"), nil); err == nil {
+		t.Error("parseStoreListing should reject a line without a size")
+	}
+}
@@ -0,0 +1,103 @@
+// Package term adapts command output to where it goes. Quiet mode leaves only errors,
+// and status symbols are colored on a terminal but plain ASCII in logs and captures.
+package term
+
+import (
+	"os"
+)
+
+// QuietEnv enables quiet mode when set; lfst sets it for --quiet before running a subcommand
+const QuietEnv = "LFST_QUIET"
+
+// NoColorEnv disables color when set (see https://no-color.org)
+const NoColorEnv = "NO_COLOR"
+
+// ANSI escape sequences
+const (
+	green  = "\033[32m"
+	red    = "\033[31m"
+	yellow = "\033[1;33m"
+	reset  = "\033[0m"
+)
+
+var (
+	quiet bool
+	color = IsTerminal(os.Stdout) && os.Getenv(NoColorEnv) == "" && os.Getenv("TERM") != "dumb"
+)
+
+// Setup applies the --quiet and --no-color flags, which the QuietEnv and NoColorEnv
+// environment variables also set. In quiet mode standard output is discarded, so only
+// errors, which go to standard error, remain. Call it once, after parsing flags.
+func Setup(quietFlag, noColorFlag bool) error {
+	if noColorFlag || os.Getenv(NoColorEnv) != "" {
+		color = false
+	}
+
+	if quietFlag || os.Getenv(QuietEnv) != "" {
+		devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		quiet = true
+		color = false
+		os.Stdout = devNull
+	}
+	return nil
+}
+
+// Quiet reports whether quiet mode is on
+func Quiet() bool {
+	return quiet
+}
+
+// Color reports whether output is colored
+func Color() bool {
+	return color
+}
+
+// OK returns the marker of a successful action: a green check mark on a terminal, otherwise "OK"
+func OK() string {
+	if !color {
+		return "OK"
+	}
+	return green + "✓" + reset
+}
+
+// Fail returns the marker of a failed action: a red cross on a terminal, otherwise "FAIL"
+func Fail() string {
+	if !color {
+		return "FAIL"
+	}
+	return red + "✗" + reset
+}
+
+// Warn returns the marker of a problem that is not fatal: a yellow exclamation mark on a
+// terminal, otherwise "WARN"
+func Warn() string {
+	if !color {
+		return "WARN"
+	}
+	return yellow + "!" + reset
+}
+
+// Skip returns the marker of an action that was skipped: a dash on a terminal, otherwise "SKIP"
+func Skip() string {
+	if !color {
+		return "SKIP"
+	}
+	return "-"
+}
+
+// Highlight returns s in bold yellow on a terminal, otherwise unchanged
+func Highlight(s string) string {
+	if !color {
+		return s
+	}
+	return yellow + s + reset
+}
+
+// IsTerminal reports whether f is a character device such as a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
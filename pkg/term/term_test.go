@@ -0,0 +1,49 @@
+package term
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMarkers(t *testing.T) {
+	defer func(saved bool) { color = saved }(color)
+
+	color = true
+	if OK() != "\033[32m✓\033[0m" || Fail() != "\033[31m✗\033[0m" {
+		t.Errorf("colored markers = %q, %q", OK(), Fail())
+	}
+
+	if Highlight("changed") != "\033[1;33mchanged\033[0m" {
+		t.Errorf("colored Highlight = %q", Highlight("changed"))
+	}
+
+	color = false
+	if OK() != "OK" || Fail() != "FAIL" || Warn() != "WARN" || Skip() != "SKIP" {
+		t.Errorf("plain markers = %q, %q, %q, %q", OK(), Fail(), Warn(), Skip())
+	}
+	if Highlight("changed") != "changed" {
+		t.Errorf("plain Highlight = %q", Highlight("changed"))
+	}
+}
+
+func TestSetup(t *testing.T) {
+	defer func(stdout *os.File, q, c bool) { os.Stdout, quiet, color = stdout, q, c }(os.Stdout, quiet, color)
+
+	color = true
+	t.Setenv(NoColorEnv, "1")
+	if err := Setup(false, false); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if Color() || Quiet() {
+		t.Errorf("NO_COLOR: Color() = %v, Quiet() = %v; want false, false", Color(), Quiet())
+	}
+
+	stdout := os.Stdout
+	t.Setenv(QuietEnv, "1")
+	if err := Setup(false, false); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if !Quiet() || os.Stdout == stdout {
+		t.Error("LFST_QUIET should enable quiet mode and discard standard output")
+	}
+}
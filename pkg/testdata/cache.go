@@ -0,0 +1,134 @@
+package testdata
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/progress"
+	"github.com/mslinn/git-lfs-test/pkg/sshutil"
+)
+
+// CacheUsage returns the number of files in a test data cache and their total size
+func CacheUsage(cacheDir string) (int, int64, error) {
+	files := 0
+	var size int64
+	err := filepath.WalkDir(cacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() || strings.HasSuffix(path, ".partial") {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files++
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to measure the test data cache: %w", err)
+	}
+	return files, size, nil
+}
+
+// copyCachedRemoteFile copies a remote file through the cache in cacheDir: from the cache
+// if it holds the file with its current size and modification time, else by fetching it
+// into the cache first. It copies the file directly if the cache cannot be used.
+func copyCachedRemoteFile(cacheDir, host, remotePath, destPath string, debug bool, bar *progress.Bar) error {
+	size, mtime, err := remoteFileInfo(host, remotePath)
+	if err != nil {
+		log.Warnf("cannot cache %s:%s: %v\n", host, remotePath, err)
+		return copyRemoteFile(host, remotePath, destPath, debug, bar)
+	}
+
+	entry := cacheEntry(cacheDir, host, remotePath, size, mtime)
+	if err := os.MkdirAll(filepath.Dir(entry), 0755); err != nil {
+		log.Warnf("cannot cache %s:%s: %v\n", host, remotePath, err)
+		return copyRemoteFile(host, remotePath, destPath, debug, bar)
+	}
+
+	cached, err := fillCache(entry, func(path string) error {
+		return copyRemoteFile(host, remotePath, path, debug, bar)
+	})
+	if err != nil {
+		return err
+	}
+	if cached {
+		log.Debugf("  Copying %s from the cache\n", filepath.Base(destPath))
+		return copyFile(entry, destPath, "", debug, bar)
+	}
+	// Fetching counted the bytes already
+	return copyFile(entry, destPath, "", debug, nil)
+}
+
+// cacheEntry returns the path of the cache entry of a remote file: its host and path
+// under the cache directory, followed by its size and modification time, e.g.
+// CACHE/gojira/data/v1/pdf1.pdf@107374182-1700000000
+func cacheEntry(cacheDir, host, remotePath string, size, mtime int64) string {
+	return filepath.Join(cacheDir, host, filepath.Clean("/"+remotePath)) + fmt.Sprintf("@%d-%d", size, mtime)
+}
+
+// fillCache makes sure a cache entry exists, fetching it with fetch otherwise, and removes
+// the entries of other versions of the same file. It reports whether the entry was cached
+// already. fetch writes to a temporary file that is renamed into place, so an interrupted
+// fetch or a concurrent run never leaves a partial entry.
+func fillCache(entry string, fetch func(path string) error) (bool, error) {
+	if _, err := os.Stat(entry); err == nil {
+		return true, nil
+	}
+
+	tmp := fmt.Sprintf("%s.%d.partial", entry, os.Getpid())
+	defer os.Remove(tmp)
+	if err := fetch(tmp); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp, entry); err != nil {
+		return false, fmt.Errorf("failed to cache %s: %w", filepath.Base(entry), err)
+	}
+
+	// Versions of the file fetched before it changed on the host are of no further use
+	base := entry[:strings.LastIndex(entry, "@")+1]
+	older, _ := filepath.Glob(globEscape(base) + "*")
+	for _, path := range older {
+		// Only SIZE-MTIME entries of this file, not a file named like it, e.g. a.pdf@b.pdf
+		if path == entry || strings.Trim(strings.TrimPrefix(path, base), "0123456789") != "-" {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			log.Warnf("failed to remove stale cache entry %s: %v\n", path, err)
+		}
+	}
+	return false, nil
+}
+
+// globEscape escapes the characters of a path that filepath.Glob treats specially
+func globEscape(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// remoteFileInfo returns the size and modification time (Unix seconds) of a remote file.
+// It tries the stat of GNU coreutils, then that of macOS and the BSDs.
+func remoteFileInfo(host, path string) (int64, int64, error) {
+	quoted := sshutil.Quote(path)
+	script := fmt.Sprintf("stat -c '%%s %%Y' %s 2>/dev/null || stat -f '%%z %%m' %s", quoted, quoted)
+	output, err := sshutil.Command(host, script).Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to stat remote file: %w", err)
+	}
+
+	var size, mtime int64
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(output)), "%d %d", &size, &mtime); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse the size and time of %s: %w", path, err)
+	}
+	return size, mtime, nil
+}
@@ -0,0 +1,75 @@
+package testdata
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheEntry(t *testing.T) {
+	got := cacheEntry("/cache", "mslinn@gojira", "/data/v1/../v1/pdf1.pdf", 107374182, 1700000000)
+	if want := "/cache/mslinn@gojira/data/v1/pdf1.pdf@107374182-1700000000"; got != want {
+		t.Errorf("cacheEntry = %s, want %s", got, want)
+	}
+	// A relative remote path stays inside the cache
+	if got := cacheEntry("/cache", "gojira", "../etc/passwd", 1, 2); got != "/cache/gojira/etc/passwd@1-2" {
+		t.Errorf("cacheEntry of a relative path = %s", got)
+	}
+}
+
+func TestFillCache(t *testing.T) {
+	dir := t.TempDir()
+	fetches := 0
+	fetch := func(content string) func(string) error {
+		return func(path string) error {
+			fetches++
+			return os.WriteFile(path, []byte(content), 0644)
+		}
+	}
+
+	v1 := filepath.Join(dir, "pdf1.pdf@2-100")
+	if cached, err := fillCache(v1, fetch("v1")); err != nil || cached {
+		t.Fatalf("first fillCache = %v, %v; want a fetch", cached, err)
+	}
+	if cached, err := fillCache(v1, fetch("v1")); err != nil || !cached || fetches != 1 {
+		t.Fatalf("second fillCache = %v, %v after %d fetches; want it cached", cached, err, fetches)
+	}
+
+	// A file named like the cached one is left alone when a new version replaces it
+	neighbour := filepath.Join(dir, "pdf1.pdf@b.pdf@1-1")
+	if err := os.WriteFile(neighbour, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	v2 := filepath.Join(dir, "pdf1.pdf@3-200")
+	if cached, err := fillCache(v2, fetch("new")); err != nil || cached {
+		t.Fatalf("fillCache of a changed file = %v, %v; want a fetch", cached, err)
+	}
+	if _, err := os.Stat(v1); err == nil {
+		t.Error("the entry of the older version should have been removed")
+	}
+	if data, err := os.ReadFile(v2); err != nil || string(data) != "new" {
+		t.Errorf("new entry = %q, %v", data, err)
+	}
+	if _, err := os.Stat(neighbour); err != nil {
+		t.Errorf("another file's entry was removed: %v", err)
+	}
+
+	// The cache holds the new version and the other file's entry
+	if files, size, err := CacheUsage(dir); err != nil || files != 2 || size != 3 {
+		t.Errorf("CacheUsage = %d files, %d bytes, %v; want 2 files, 3 bytes", files, size, err)
+	}
+
+	// A failed fetch leaves no entry, partial or not
+	failed := filepath.Join(dir, "zip1.zip@5-300")
+	_, err := fillCache(failed, func(path string) error {
+		os.WriteFile(path, []byte("par"), 0644)
+		return errors.New("connection reset")
+	})
+	if err == nil {
+		t.Fatal("fillCache should return the error of the fetch")
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "zip1.zip*")); len(matches) > 0 {
+		t.Errorf("failed fetch left %v", matches)
+	}
+}
@@ -0,0 +1,218 @@
+package testdata
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mslinn/git-lfs-test/pkg/sshutil"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestName is the file in the root of a corpus that lists its files
+const ManifestName = "manifest.yaml"
+
+// Manifest describes a corpus: the files of each version, found in the subdirectory of
+// the same name. Step 1 copies v1 and step 3 updates the files with v2, then with v3 if
+// the corpus has one.
+type Manifest struct {
+	Description string   `yaml:"description,omitempty"`
+	V1          []string `yaml:"v1"`
+	V2          []string `yaml:"v2,omitempty"`
+	V3          []string `yaml:"v3,omitempty"`
+}
+
+// Versions are the versions a manifest may list, in the order the scenarios copy them
+var Versions = []string{"v1", "v2", "v3"}
+
+// DefaultManifest returns the manifest of the standard test data, which a test data root
+// without a manifest.yaml is assumed to hold:
+// - v1: 7 files totaling 1.3GB, 103M - 308M each (pdf, m4v, mov, avi, ogg, zip)
+// - v2: updated versions of 4 of them, totaling 1.1GB
+func DefaultManifest() *Manifest {
+	return &Manifest{
+		Description: "Standard test data",
+		V1:          []string{"pdf1.pdf", "video1.m4v", "video2.mov", "video3.avi", "video4.ogg", "zip1.zip", "zip2.zip"},
+		V2:          []string{"pdf1.pdf", "video2.mov", "video3.avi", "zip1.zip"},
+	}
+}
+
+// Files returns the file names of a version, or nil if the manifest does not list it
+func (m *Manifest) Files(version string) []string {
+	switch version {
+	case "v1":
+		return m.V1
+	case "v2":
+		return m.V2
+	case "v3":
+		return m.V3
+	}
+	return nil
+}
+
+// Validate checks that the manifest lists v1 files and that every name is a plain file
+// name, as the files are copied into the root of the repository
+func (m *Manifest) Validate() error {
+	if len(m.V1) == 0 {
+		return fmt.Errorf("%s lists no v1 files", ManifestName)
+	}
+	for _, version := range Versions {
+		seen := make(map[string]bool)
+		for _, name := range m.Files(version) {
+			if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+				return fmt.Errorf("%s: invalid %s file name '%s' (use a name without directories)", ManifestName, version, name)
+			}
+			if seen[name] {
+				return fmt.Errorf("%s: %s lists %s twice", ManifestName, version, name)
+			}
+			seen[name] = true
+		}
+	}
+	return nil
+}
+
+// ParseManifest parses a manifest.yaml, rejecting unknown keys such as a misspelt version
+func ParseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ManifestName, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Corpus is a set of test data described by a manifest
+type Corpus struct {
+	Name     string // Empty for the test data root itself
+	Path     string // Local path or host:/path
+	Manifest *Manifest
+}
+
+// OpenCorpus opens the corpus called name under the test data root (local path or
+// host:/path), such as small, medium or large. A named corpus must have a manifest.yaml.
+// An empty name opens the root itself, whose files are those of DefaultManifest unless
+// it has a manifest.yaml.
+func OpenCorpus(root, name string) (*Corpus, error) {
+	c := &Corpus{Name: name, Path: root}
+	if name != "" {
+		if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return nil, fmt.Errorf("invalid corpus name '%s'", name)
+		}
+		c.Path = joinPath(root, name)
+		if _, remote := ParseRemotePath(root); !remote {
+			if _, err := os.Stat(c.Path); err != nil {
+				return nil, fmt.Errorf("corpus '%s' not found in %s%s", name, root, availableCorpora(root))
+			}
+		}
+	}
+
+	data, err := readManifest(c.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case data != nil:
+		if c.Manifest, err = ParseManifest(data); err != nil {
+			return nil, fmt.Errorf("corpus %s: %w", c.Path, err)
+		}
+	case name != "":
+		return nil, fmt.Errorf("corpus '%s' has no %s in %s\n"+
+			"List the files of each version in it, e.g.:\n  v1: [a.psd, b.zip]\n  v2: [a.psd]", name, ManifestName, c.Path)
+	default:
+		c.Manifest = DefaultManifest()
+	}
+	return c, nil
+}
+
+// FindCorpus opens the corpus called name under the test data root of GetTestDataPath
+func FindCorpus(name string) (*Corpus, error) {
+	root, err := GetTestDataPath()
+	if err != nil {
+		return nil, err
+	}
+	return OpenCorpus(root, name)
+}
+
+// Files returns the files of a version of the corpus in manifest order, or nil if the
+// corpus does not have that version
+func (c *Corpus) Files(version string) []FileSpec {
+	names := c.Manifest.Files(version)
+	if len(names) == 0 {
+		return nil
+	}
+	versionPath := joinPath(c.Path, version)
+	specs := make([]FileSpec, len(names))
+	for i, name := range names {
+		specs[i] = FileSpec{Name: name, SourcePath: joinPath(versionPath, name)}
+	}
+	return specs
+}
+
+// String names the corpus for messages: its name, or its path for the test data root
+func (c *Corpus) String() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Path
+}
+
+// ListCorpora lists the names of the corpora under a local test data root: the
+// subdirectories with a manifest.yaml
+func ListCorpora(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list corpora: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(root, entry.Name(), ManifestName)); err == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// availableCorpora returns a note listing the corpora under root, for error messages
+func availableCorpora(root string) string {
+	names, err := ListCorpora(root)
+	if err != nil || len(names) == 0 {
+		return " (it has no corpora)"
+	}
+	return fmt.Sprintf(" (available: %s)", strings.Join(names, ", "))
+}
+
+// readManifest reads the manifest.yaml of a corpus (local path or host:/path).
+// It returns nil without error if the corpus has none.
+func readManifest(corpusPath string) ([]byte, error) {
+	var data []byte
+	var err error
+
+	if remote, isRemote := ParseRemotePath(corpusPath); isRemote {
+		path := sshutil.Quote(remote.Path + "/" + ManifestName)
+		// A missing file is not an error, so test for it on the remote side
+		data, err = sshutil.Command(remote.Host, fmt.Sprintf("test ! -f %s || cat %s", path, path)).Output()
+	} else {
+		data, err = os.ReadFile(filepath.Join(corpusPath, ManifestName))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ManifestName, err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return data, nil
+}
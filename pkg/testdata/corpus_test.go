@@ -0,0 +1,98 @@
+package testdata
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOpenCorpus(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"small/v1", "small/v2", "small/v3", "large/v1", "empty/v1"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	manifests := map[string]string{
+		"small": "description: Small files\nv1: [a.psd, b.zip]\nv2: [a.psd]\nv3: [a.psd, c.onnx]\n",
+		"large": "v1: [big.iso]\n",
+	}
+	for name, manifest := range manifests {
+		if err := os.WriteFile(filepath.Join(root, name, ManifestName), []byte(manifest), 0644); err != nil {
+			t.Fatalf("Failed to write manifest of %s: %v", name, err)
+		}
+	}
+
+	small, err := OpenCorpus(root, "small")
+	if err != nil {
+		t.Fatalf("OpenCorpus(small) failed: %v", err)
+	}
+	if small.Manifest.Description != "Small files" || small.String() != "small" {
+		t.Errorf("OpenCorpus(small) = %+v", small)
+	}
+	want := []FileSpec{
+		{Name: "a.psd", SourcePath: filepath.Join(root, "small", "v3", "a.psd")},
+		{Name: "c.onnx", SourcePath: filepath.Join(root, "small", "v3", "c.onnx")},
+	}
+	if got := small.Files("v3"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Files(v3) = %v, want %v", got, want)
+	}
+
+	large, err := OpenCorpus(root, "large")
+	if err != nil {
+		t.Fatalf("OpenCorpus(large) failed: %v", err)
+	}
+	if len(large.Files("v1")) != 1 || large.Files("v2") != nil || large.Files("v3") != nil {
+		t.Errorf("large corpus has v1 %v, v2 %v, v3 %v; want only one v1 file", large.Files("v1"), large.Files("v2"), large.Files("v3"))
+	}
+
+	// The root itself holds the standard test data unless it has a manifest
+	standard, err := OpenCorpus(root, "")
+	if err != nil {
+		t.Fatalf("OpenCorpus of the root failed: %v", err)
+	}
+	if !reflect.DeepEqual(standard.Manifest, DefaultManifest()) || standard.String() != root {
+		t.Errorf("OpenCorpus of the root = %+v, want the default manifest", standard)
+	}
+
+	names, err := ListCorpora(root)
+	if err != nil || !reflect.DeepEqual(names, []string{"large", "small"}) {
+		t.Errorf("ListCorpora = %v, %v; want [large small]", names, err)
+	}
+
+	errorTests := []struct {
+		name, want string
+	}{
+		{"medium", "not found in " + root + " (available: large, small)"},
+		{"empty", "has no manifest.yaml"},
+		{"../small", "invalid corpus name"},
+	}
+	for _, tt := range errorTests {
+		if _, err := OpenCorpus(root, tt.name); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("OpenCorpus(%s) error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		manifest, wantErr string
+	}{
+		{"v1: [a.psd]\nv2: [a.psd]\n", ""},
+		{"v2: [a.psd]\n", "lists no v1 files"},
+		{"v1: [a.psd]\nv4: [a.psd]\n", "field v4 not found"},
+		{"v1: [dir/a.psd]\n", "invalid v1 file name"},
+		{"v1: [a.psd, a.psd]\n", "lists a.psd twice"},
+	}
+	for _, tt := range tests {
+		_, err := ParseManifest([]byte(tt.manifest))
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("ParseManifest(%q) failed: %v", tt.manifest, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("ParseManifest(%q) error = %v, want %q", tt.manifest, err, tt.wantErr)
+		}
+	}
+}
@@ -9,6 +9,10 @@ import (
 	"strings"
 
 	"github.com/mslinn/git-lfs-test/pkg/config"
+	"github.com/mslinn/git-lfs-test/pkg/log"
+	"github.com/mslinn/git-lfs-test/pkg/progress"
+	"github.com/mslinn/git-lfs-test/pkg/sshutil"
+	"github.com/mslinn/git-lfs-test/pkg/term"
 )
 
 // FileSpec describes a test file to copy
@@ -20,17 +24,23 @@ type FileSpec struct {
 // CopyFile copies a single file to the destination
 // Supports both local and remote sources (host:/path format)
 func CopyFile(srcPath, destPath string, debug bool) error {
+	return copyFile(srcPath, destPath, "", debug, nil)
+}
+
+// copyFile copies a single file, counting the bytes copied on bar. Remote files go
+// through the cache in cacheDir unless it is empty.
+func copyFile(srcPath, destPath, cacheDir string, debug bool, bar *progress.Bar) error {
 	// Check if source is remote
 	if remotePath, isRemote := ParseRemotePath(srcPath); isRemote {
-		return CopyRemoteFile(remotePath.Host, remotePath.Path, destPath, debug)
+		if cacheDir != "" {
+			return copyCachedRemoteFile(cacheDir, remotePath.Host, remotePath.Path, destPath, debug, bar)
+		}
+		return copyRemoteFile(remotePath.Host, remotePath.Path, destPath, debug, bar)
 	}
 
 	// Local file copy
-	if debug {
-		info, err := os.Stat(srcPath)
-		if err == nil {
-			fmt.Printf("  Copying %s (%s)\n", filepath.Base(destPath), FormatSize(info.Size()))
-		}
+	if info, err := os.Stat(srcPath); err == nil {
+		log.Debugf("  Copying %s (%s)\n", filepath.Base(destPath), FormatSize(info.Size()))
 	}
 
 	// Create parent directory if needed
@@ -54,7 +64,11 @@ func CopyFile(srcPath, destPath string, debug bool) error {
 	defer dst.Close()
 
 	// Copy content
-	if _, err := io.Copy(dst, src); err != nil {
+	var w io.Writer = dst
+	if bar != nil {
+		w = io.MultiWriter(dst, bar)
+	}
+	if _, err := io.Copy(w, src); err != nil {
 		return err
 	}
 
@@ -63,9 +77,12 @@ func CopyFile(srcPath, destPath string, debug bool) error {
 
 // CopyRemoteFile copies a file from a remote host using rsync over SSH
 func CopyRemoteFile(host, remotePath, destPath string, debug bool) error {
-	if debug {
-		fmt.Printf("  Copying %s from %s via rsync\n", filepath.Base(destPath), host)
-	}
+	return copyRemoteFile(host, remotePath, destPath, debug, nil)
+}
+
+// copyRemoteFile copies a file from a remote host, counting the bytes rsync reports on bar
+func copyRemoteFile(host, remotePath, destPath string, debug bool, bar *progress.Bar) error {
+	log.Debugf("  Copying %s from %s via rsync\n", filepath.Base(destPath), host)
 
 	// Create parent directory if needed
 	dir := filepath.Dir(destPath)
@@ -75,10 +92,14 @@ func CopyRemoteFile(host, remotePath, destPath string, debug bool) error {
 
 	// Use rsync for efficient remote copying
 	// -a: archive mode (preserves permissions, timestamps)
-	// -q: quiet mode (unless debug)
-	// -e ssh: use SSH
-	args := []string{"-a", "-e", "ssh"}
-	if !debug {
+	// -q: quiet mode (unless debug, or showing progress)
+	// --progress: report the bytes copied, for the progress display
+	// -e ssh: use SSH, with any per-host port, identity file or jump host
+	args := []string{"-a", "-e", sshutil.RsyncShell(host)}
+	switch {
+	case bar != nil:
+		args = append(args, "--progress")
+	case !debug:
 		args = append(args, "-q")
 	}
 	args = append(args, fmt.Sprintf("%s:%s", host, remotePath), destPath)
@@ -88,30 +109,57 @@ func CopyRemoteFile(host, remotePath, destPath string, debug bool) error {
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 	}
+	if bar != nil {
+		// rsync reports the bytes of the file being copied
+		base := bar.Bytes()
+		counter := progress.LineWriter(func(line string) {
+			if r, ok := progress.ParseLine(line); ok {
+				bar.Set(base + r.Bytes)
+			}
+		})
+		if debug {
+			cmd.Stdout = io.MultiWriter(os.Stdout, counter)
+		} else {
+			cmd.Stdout = counter
+		}
+	}
 
 	return cmd.Run()
 }
 
-// CopyFiles copies multiple test files
-func CopyFiles(destDir string, specs []FileSpec, debug bool) error {
-	if debug {
-		fmt.Printf("Copying %d test files to %s\n", len(specs), destDir)
-	}
+// CopyFiles copies multiple test files. Remote files are cached in cacheDir, usually
+// test_data_cache of the config; empty copies them directly.
+func CopyFiles(destDir string, specs []FileSpec, cacheDir string, debug bool) error {
+	log.Debugf("Copying %d test files to %s\n", len(specs), destDir)
+
+	bar := startCopyProgress(specs)
+	defer bar.Finish()
 
 	for _, spec := range specs {
 		destPath := filepath.Join(destDir, spec.Name)
-		if err := CopyFile(spec.SourcePath, destPath, debug); err != nil {
+		if err := copyFile(spec.SourcePath, destPath, cacheDir, debug, bar); err != nil {
 			return fmt.Errorf("failed to copy %s: %w", spec.Name, err)
 		}
 	}
 
-	if debug {
-		fmt.Printf("✓ Copied %d files\n", len(specs))
-	}
+	log.Debugf("%s Copied %d files\n", term.OK(), len(specs))
 
 	return nil
 }
 
+// startCopyProgress shows the progress of copying specs, or returns nil when progress
+// is not shown
+func startCopyProgress(specs []FileSpec) *progress.Bar {
+	if !progress.Enabled() {
+		return nil
+	}
+	total, err := TotalSize(specs)
+	if err != nil {
+		total = 0 // The copy reports the missing file
+	}
+	return progress.Start(fmt.Sprintf("Copying %d test files", len(specs)), total)
+}
+
 // RemotePath represents a remote path (host:/path)
 type RemotePath struct {
 	Host string
@@ -142,7 +190,8 @@ func ParseRemotePath(path string) (*RemotePath, bool) {
 
 // IsRemoteAccessible checks if a remote host is accessible via SSH
 func IsRemoteAccessible(host string) error {
-	cmd := exec.Command("ssh", "-o", "ConnectTimeout=5", "-o", "BatchMode=yes", host, "echo", "ok")
+	args := sshutil.Args(host, "-o", "ConnectTimeout=5", "-o", "BatchMode=yes")
+	cmd := exec.Command("ssh", append(args, "echo", "ok")...)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("cannot connect to %s via SSH: %w", host, err)
 	}
@@ -151,7 +200,7 @@ func IsRemoteAccessible(host string) error {
 
 // CheckRemoteDir checks if a directory exists on a remote host
 func CheckRemoteDir(host, path string) error {
-	cmd := exec.Command("ssh", host, "test", "-d", path)
+	cmd := sshutil.Command(host, "test", "-d", path)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("remote directory %s:%s does not exist", host, path)
 	}
@@ -288,59 +337,11 @@ func joinPath(base, component string) string {
 	return filepath.Join(base, component)
 }
 
-// RealTestFiles returns the actual large test files from v1/
-// These are the files described in the evaluation procedure:
-// - 7 files totaling 1.3GB
-// - File sizes: 103M - 308M
-// - File types: pdf, m4v, mov, avi, ogg, zip
-// Supports both local and remote test data paths
-func RealTestFiles() ([]FileSpec, error) {
-	basePath, err := GetTestDataPath()
-	if err != nil {
-		return nil, err
-	}
-
-	v1Path := joinPath(basePath, "v1")
-
-	return []FileSpec{
-		{Name: "pdf1.pdf", SourcePath: joinPath(v1Path, "pdf1.pdf")},
-		{Name: "video1.m4v", SourcePath: joinPath(v1Path, "video1.m4v")},
-		{Name: "video2.mov", SourcePath: joinPath(v1Path, "video2.mov")},
-		{Name: "video3.avi", SourcePath: joinPath(v1Path, "video3.avi")},
-		{Name: "video4.ogg", SourcePath: joinPath(v1Path, "video4.ogg")},
-		{Name: "zip1.zip", SourcePath: joinPath(v1Path, "zip1.zip")},
-		{Name: "zip2.zip", SourcePath: joinPath(v1Path, "zip2.zip")},
-	}, nil
-}
-
-// RealTestFilesV2 returns the updated test files from v2/
-// These are used for testing file modifications/updates:
-// - 4 files totaling 1.1GB
-// - Updated versions of some v1 files (larger sizes)
-// Supports both local and remote test data paths
-func RealTestFilesV2() ([]FileSpec, error) {
-	basePath, err := GetTestDataPath()
-	if err != nil {
-		return nil, err
-	}
-
-	v2Path := joinPath(basePath, "v2")
-
-	return []FileSpec{
-		{Name: "pdf1.pdf", SourcePath: joinPath(v2Path, "pdf1.pdf")},       // 205M (was 103M)
-		{Name: "video2.mov", SourcePath: joinPath(v2Path, "video2.mov")},   // 398M (was 238M)
-		{Name: "video3.avi", SourcePath: joinPath(v2Path, "video3.avi")},   // 272M (was 150M)
-		{Name: "zip1.zip", SourcePath: joinPath(v2Path, "zip1.zip")},       // 200M (was 308M)
-	}, nil
-}
-
 // DeleteFile deletes a file from the destination directory
 func DeleteFile(destDir, fileName string, debug bool) error {
 	filePath := filepath.Join(destDir, fileName)
 
-	if debug {
-		fmt.Printf("  Deleting %s\n", fileName)
-	}
+	log.Debugf("  Deleting %s\n", fileName)
 
 	if err := os.Remove(filePath); err != nil {
 		return fmt.Errorf("failed to delete file: %w", err)
@@ -354,9 +355,7 @@ func RenameFile(destDir, oldName, newName string, debug bool) error {
 	oldPath := filepath.Join(destDir, oldName)
 	newPath := filepath.Join(destDir, newName)
 
-	if debug {
-		fmt.Printf("  Renaming %s to %s\n", oldName, newName)
-	}
+	log.Debugf("  Renaming %s to %s\n", oldName, newName)
 
 	if err := os.Rename(oldPath, newPath); err != nil {
 		return fmt.Errorf("failed to rename file: %w", err)
@@ -412,7 +411,7 @@ func TotalSize(specs []FileSpec) (int64, error) {
 
 // GetRemoteFileSize gets the size of a file on a remote host via SSH
 func GetRemoteFileSize(host, path string) (int64, error) {
-	cmd := exec.Command("ssh", host, "stat", "-c", "%s", path)
+	cmd := sshutil.Command(host, "stat", "-c", "%s", path)
 	output, err := cmd.Output()
 	if err != nil {
 		return 0, fmt.Errorf("failed to stat remote file: %w", err)
@@ -194,7 +194,7 @@ func TestCopyFiles(t *testing.T) {
 	}
 
 	// Copy files
-	if err := CopyFiles(dstDir, specs, false); err != nil {
+	if err := CopyFiles(dstDir, specs, "", false); err != nil {
 		t.Fatalf("CopyFiles failed: %v", err)
 	}
 
@@ -332,7 +332,7 @@ func TestGetTestDataPath_EnvPriority(t *testing.T) {
 	}
 }
 
-func TestRealTestFiles_Structure(t *testing.T) {
+func TestDefaultCorpus_Structure(t *testing.T) {
 	// Save original environment
 	orig := os.Getenv("LFS_TEST_DATA")
 	defer os.Setenv("LFS_TEST_DATA", orig)
@@ -365,15 +365,16 @@ func TestRealTestFiles_Structure(t *testing.T) {
 	os.Setenv("LFS_TEST_DATA", tempDir)
 
 	// Get test files
-	specs, err := RealTestFiles()
+	corpus, err := FindCorpus("")
 	if err != nil {
-		t.Fatalf("RealTestFiles() failed: %v", err)
+		t.Fatalf("FindCorpus() failed: %v", err)
 	}
+	specs := corpus.Files("v1")
 
 	// Verify we got the expected number of files
 	expectedCount := 7
 	if len(specs) != expectedCount {
-		t.Errorf("RealTestFiles() returned %d files, want %d", len(specs), expectedCount)
+		t.Errorf("Files(v1) returned %d files, want %d", len(specs), expectedCount)
 	}
 
 	// Verify each file has proper structure
@@ -387,7 +388,7 @@ func TestRealTestFiles_Structure(t *testing.T) {
 	}
 }
 
-func TestRealTestFiles_SourceFromV1(t *testing.T) {
+func TestDefaultCorpus_SourceFromV1(t *testing.T) {
 	// Save original environment
 	orig := os.Getenv("LFS_TEST_DATA")
 	defer os.Setenv("LFS_TEST_DATA", orig)
@@ -427,10 +428,11 @@ func TestRealTestFiles_SourceFromV1(t *testing.T) {
 	os.Setenv("LFS_TEST_DATA", tempDir)
 
 	// Get test files
-	specs, err := RealTestFiles()
+	corpus, err := FindCorpus("")
 	if err != nil {
-		t.Fatalf("RealTestFiles() failed: %v", err)
+		t.Fatalf("FindCorpus() failed: %v", err)
 	}
+	specs := corpus.Files("v1")
 
 	// Verify each file's source path points to v1
 	for _, spec := range specs {
@@ -454,7 +456,7 @@ func TestRealTestFiles_SourceFromV1(t *testing.T) {
 	}
 }
 
-func TestRealTestFilesV2_Structure(t *testing.T) {
+func TestDefaultCorpusV2_Structure(t *testing.T) {
 	// Save original environment
 	orig := os.Getenv("LFS_TEST_DATA")
 	defer os.Setenv("LFS_TEST_DATA", orig)
@@ -485,15 +487,16 @@ func TestRealTestFilesV2_Structure(t *testing.T) {
 	os.Setenv("LFS_TEST_DATA", tempDir)
 
 	// Get test files
-	specs, err := RealTestFilesV2()
+	corpus, err := FindCorpus("")
 	if err != nil {
-		t.Fatalf("RealTestFilesV2() failed: %v", err)
+		t.Fatalf("FindCorpus() failed: %v", err)
 	}
+	specs := corpus.Files("v2")
 
 	// Verify we got the expected number of files
 	expectedCount := 4
 	if len(specs) != expectedCount {
-		t.Errorf("RealTestFilesV2() returned %d files, want %d", len(specs), expectedCount)
+		t.Errorf("Files(v2) returned %d files, want %d", len(specs), expectedCount)
 	}
 
 	// Verify each file has proper structure
@@ -507,7 +510,7 @@ func TestRealTestFilesV2_Structure(t *testing.T) {
 	}
 }
 
-func TestRealTestFilesV2_SourceFromV2(t *testing.T) {
+func TestDefaultCorpusV2_SourceFromV2(t *testing.T) {
 	// Save original environment
 	orig := os.Getenv("LFS_TEST_DATA")
 	defer os.Setenv("LFS_TEST_DATA", orig)
@@ -561,10 +564,11 @@ func TestRealTestFilesV2_SourceFromV2(t *testing.T) {
 	os.Setenv("LFS_TEST_DATA", tempDir)
 
 	// Get v2 test files
-	specs, err := RealTestFilesV2()
+	corpus, err := FindCorpus("")
 	if err != nil {
-		t.Fatalf("RealTestFilesV2() failed: %v", err)
+		t.Fatalf("FindCorpus() failed: %v", err)
 	}
+	specs := corpus.Files("v2")
 
 	// Verify each file's source path points to v2
 	for _, spec := range specs {
@@ -701,3 +705,44 @@ func TestGetTestDataPath_ConfigFileWithEnvVar(t *testing.T) {
 		t.Errorf("GetTestDataPath() = %v, want %v", path, expectedPath)
 	}
 }
+
+func TestGenerateMiniCorpus(t *testing.T) {
+	dir := t.TempDir()
+	if err := GenerateMiniCorpus(dir); err != nil {
+		t.Fatalf("GenerateMiniCorpus failed: %v", err)
+	}
+
+	// Every file the scenarios copy must exist, as its manifest lists them
+	corpus, err := OpenCorpus(dir, "")
+	if err != nil {
+		t.Fatalf("OpenCorpus failed: %v", err)
+	}
+	if corpus.Manifest.Description == DefaultManifest().Description {
+		t.Error("Mini corpus should have its own manifest")
+	}
+	specs := append(corpus.Files("v1"), corpus.Files("v2")...)
+	for _, spec := range specs {
+		if _, err := os.Stat(spec.SourcePath); err != nil {
+			t.Errorf("Missing mini corpus file %s: %v", spec.SourcePath, err)
+		}
+	}
+
+	total, err := TotalSize(specs)
+	if err != nil {
+		t.Fatalf("TotalSize failed: %v", err)
+	}
+	if total > 10*1024*1024 {
+		t.Errorf("Mini corpus is %s, want under 10 MB", FormatSize(total))
+	}
+
+	// Content is deterministic so resumed runs see identical files
+	first, _ := os.ReadFile(filepath.Join(dir, "v1", "zip1.zip"))
+	again := t.TempDir()
+	if err := GenerateMiniCorpus(again); err != nil {
+		t.Fatalf("GenerateMiniCorpus failed: %v", err)
+	}
+	second, _ := os.ReadFile(filepath.Join(again, "v1", "zip1.zip"))
+	if string(first) != string(second) {
+		t.Error("GenerateMiniCorpus should produce identical content each time")
+	}
+}
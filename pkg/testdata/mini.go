@@ -0,0 +1,71 @@
+package testdata
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// miniFiles lists the file names and sizes of the miniature corpus.
+// The names match DefaultManifest so every scenario step works unchanged;
+// v2 files differ in content and size from their v1 versions.
+var miniFiles = map[string][]struct {
+	name string
+	size int
+}{
+	"v1": {
+		{"pdf1.pdf", 300 * 1024},
+		{"video1.m4v", 500 * 1024},
+		{"video2.mov", 400 * 1024},
+		{"video3.avi", 350 * 1024},
+		{"video4.ogg", 250 * 1024},
+		{"zip1.zip", 600 * 1024},
+		{"zip2.zip", 200 * 1024},
+	},
+	"v2": {
+		{"pdf1.pdf", 450 * 1024},
+		{"video2.mov", 550 * 1024},
+		{"video3.avi", 500 * 1024},
+		{"zip1.zip", 400 * 1024},
+	},
+}
+
+// GenerateMiniCorpus writes a miniature test corpus (about 5 MB) with the same
+// v1/ and v2/ layout as the real test data, and its manifest.yaml. Content is
+// pseudo-random but deterministic, so checksums are identical from one run to the next.
+func GenerateMiniCorpus(dir string) error {
+	manifest := &Manifest{Description: "Miniature corpus generated by lfst-scenario --mini"}
+	for seed, version := range []string{"v1", "v2"} {
+		versionDir := filepath.Join(dir, version)
+		if err := os.MkdirAll(versionDir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+
+		rng := rand.New(rand.NewSource(int64(seed + 1)))
+		for _, f := range miniFiles[version] {
+			content := make([]byte, f.size)
+			rng.Read(content)
+			if err := os.WriteFile(filepath.Join(versionDir, f.name), content, 0644); err != nil {
+				return fmt.Errorf("failed to write %s/%s: %w", version, f.name, err)
+			}
+			if version == "v1" {
+				manifest.V1 = append(manifest.V1, f.name)
+			} else {
+				manifest.V2 = append(manifest.V2, f.name)
+			}
+		}
+	}
+
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", ManifestName, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ManifestName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package testdata
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+)
+
+// SparseStride is the distance between the blocks of content of a file written by
+// WriteSparseFile
+const SparseStride = 64 * 1024 * 1024
+
+// WriteSparseFile writes a file of size bytes that takes little disk space: a random
+// block of 64 KB at the start of every SparseStride bytes, with holes between them that
+// read as zeros. The blocks make the content differ from one seed to another, so each
+// file is a new LFS object, and the same seed always writes the same content.
+// File systems without sparse files store the zeros.
+func WriteSparseFile(path string, size, seed int64) error {
+	if size <= 0 {
+		return fmt.Errorf("size must be positive")
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Extend the file first, so the blocks written below leave holes between them
+	if err := file.Truncate(size); err != nil {
+		return err
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	block := make([]byte, blockSize)
+	for offset := int64(0); offset < size; offset += SparseStride {
+		rng.Read(block)
+		n := min(int64(len(block)), size-offset)
+		if _, err := file.WriteAt(block[:n], offset); err != nil {
+			return err
+		}
+	}
+
+	return file.Close()
+}
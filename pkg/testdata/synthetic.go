@@ -0,0 +1,192 @@
+package testdata
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GenerateOptions controls the synthetic corpus written by GenerateCorpus
+type GenerateOptions struct {
+	TotalSize    int64   // Total bytes across all files
+	Files        int     // Number of files
+	Distribution string  // File size distribution: 'fixed', 'uniform' or 'lognormal'
+	Compressible float64 // Fraction (0-1) of each file's content that compresses well
+	Seed         int64   // Random seed; the same options and seed always produce identical files
+}
+
+// GeneratedFile describes a file written by GenerateCorpus
+type GeneratedFile struct {
+	Name string
+	Size int64
+}
+
+// Distributions lists the supported file size distributions
+var Distributions = []string{"fixed", "uniform", "lognormal"}
+
+// blockSize is the unit in which compressible and random content are interleaved
+const blockSize = 64 * 1024
+
+// words is the vocabulary of the compressible portion of generated files
+var words = []string{
+	"git", "lfs", "large", "file", "storage", "object", "pointer", "batch", "upload",
+	"download", "commit", "push", "pull", "clone", "track", "server", "client", "oid",
+}
+
+// GenerateCorpus writes opts.Files deterministic binary files into dir and returns
+// their names and sizes. File names are file001.bin, file002.bin, and so on.
+func GenerateCorpus(dir string, opts GenerateOptions) ([]GeneratedFile, error) {
+	if opts.Files <= 0 {
+		return nil, fmt.Errorf("number of files must be positive")
+	}
+	if opts.TotalSize < int64(opts.Files) {
+		return nil, fmt.Errorf("total size %d is too small for %d files", opts.TotalSize, opts.Files)
+	}
+	if opts.Compressible < 0 || opts.Compressible > 1 {
+		return nil, fmt.Errorf("compressible fraction must be between 0 and 1")
+	}
+
+	sizes, err := fileSizes(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	width := len(strconv.Itoa(opts.Files))
+	if width < 3 {
+		width = 3
+	}
+
+	files := make([]GeneratedFile, 0, len(sizes))
+	for i, size := range sizes {
+		name := fmt.Sprintf("file%0*d.bin", width, i+1)
+		// Each file has its own seed, so changing the file count does not change earlier files' content
+		rng := rand.New(rand.NewSource(opts.Seed*1000003 + int64(i)))
+		if err := writeSyntheticFile(filepath.Join(dir, name), size, opts.Compressible, rng); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		files = append(files, GeneratedFile{Name: name, Size: size})
+	}
+
+	return files, nil
+}
+
+// fileSizes splits opts.TotalSize into opts.Files sizes following opts.Distribution.
+// Sizes always add up to exactly TotalSize and every file has at least one byte.
+func fileSizes(opts GenerateOptions) ([]int64, error) {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	weights := make([]float64, opts.Files)
+
+	for i := range weights {
+		switch opts.Distribution {
+		case "fixed", "":
+			weights[i] = 1
+		case "uniform":
+			weights[i] = 0.5 + rng.Float64()
+		case "lognormal":
+			weights[i] = math.Exp(rng.NormFloat64())
+		default:
+			return nil, fmt.Errorf("unknown distribution '%s' (use %s)", opts.Distribution, strings.Join(Distributions, ", "))
+		}
+	}
+
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+
+	// Reserve one byte per file, then share out the rest by weight
+	spare := opts.TotalSize - int64(opts.Files)
+	sizes := make([]int64, opts.Files)
+	var assigned int64
+	for i, w := range weights {
+		sizes[i] = 1 + int64(float64(spare)*w/sum)
+		assigned += sizes[i]
+	}
+	sizes[len(sizes)-1] += opts.TotalSize - assigned
+
+	return sizes, nil
+}
+
+// writeSyntheticFile writes size bytes in which the leading compressible fraction
+// of every block is text drawn from a small vocabulary and the rest is random
+func writeSyntheticFile(path string, size int64, compressible float64, rng *rand.Rand) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriterSize(file, blockSize)
+	block := make([]byte, blockSize)
+	textLen := int(float64(blockSize) * compressible)
+
+	for remaining := size; remaining > 0; {
+		fillText(block[:textLen], rng)
+		rng.Read(block[textLen:])
+
+		n := int64(len(block))
+		if remaining < n {
+			n = remaining
+		}
+		if _, err := w.Write(block[:n]); err != nil {
+			return err
+		}
+		remaining -= n
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// fillText fills buf with space-separated words
+func fillText(buf []byte, rng *rand.Rand) {
+	for i := 0; i < len(buf); {
+		word := words[rng.Intn(len(words))]
+		i += copy(buf[i:], word)
+		if i < len(buf) {
+			buf[i] = ' '
+			i++
+		}
+	}
+}
+
+// ParseSize parses a size such as '2GB', '500M', '1.5 GiB' or '1024' into bytes.
+// Units are binary (1 KB = 1024 bytes).
+func ParseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	str = strings.TrimSuffix(strings.TrimSuffix(str, "IB"), "B")
+
+	multiplier := int64(1)
+	if str != "" {
+		if idx := strings.IndexByte("KMGT", str[len(str)-1]); idx >= 0 {
+			multiplier = int64(1) << (10 * (idx + 1))
+			str = strings.TrimSpace(str[:len(str)-1])
+		}
+	}
+
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// ParsePercent parses a percentage such as '30%' or '30' into a fraction (0.3)
+func ParsePercent(s string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || value < 0 || value > 100 {
+		return 0, fmt.Errorf("invalid percentage '%s' (use 0-100)", s)
+	}
+	return value / 100, nil
+}
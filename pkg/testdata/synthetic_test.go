@@ -0,0 +1,172 @@
+package testdata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"1024":    1024,
+		"2GB":     2 << 30,
+		"500M":    500 << 20,
+		"1.5 GiB": 3 << 29,
+		"64kb":    64 << 10,
+	}
+	for input, want := range tests {
+		got, err := ParseSize(input)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+
+	for _, input := range []string{"", "GB", "-1MB", "lots"} {
+		if _, err := ParseSize(input); err == nil {
+			t.Errorf("ParseSize(%q) should fail", input)
+		}
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	if got, err := ParsePercent("30%"); err != nil || got != 0.3 {
+		t.Errorf("ParsePercent(30%%) = %v, %v; want 0.3", got, err)
+	}
+	if _, err := ParsePercent("150%"); err == nil {
+		t.Error("ParsePercent(150%) should fail")
+	}
+}
+
+func TestFileSizes(t *testing.T) {
+	for _, dist := range Distributions {
+		opts := GenerateOptions{TotalSize: 10 << 20, Files: 25, Distribution: dist, Seed: 7}
+		sizes, err := fileSizes(opts)
+		if err != nil {
+			t.Fatalf("fileSizes(%s) failed: %v", dist, err)
+		}
+
+		var total int64
+		for _, size := range sizes {
+			if size < 1 {
+				t.Errorf("%s: file size %d is not positive", dist, size)
+			}
+			total += size
+		}
+		if total != opts.TotalSize {
+			t.Errorf("%s: sizes add up to %d, want %d", dist, total, opts.TotalSize)
+		}
+	}
+
+	if _, err := fileSizes(GenerateOptions{TotalSize: 100, Files: 2, Distribution: "zipf"}); err == nil {
+		t.Error("Unknown distribution should fail")
+	}
+}
+
+func TestGenerateCorpusIsDeterministic(t *testing.T) {
+	opts := GenerateOptions{TotalSize: 300 << 10, Files: 3, Distribution: "lognormal", Compressible: 0.5, Seed: 42}
+	dir1, dir2 := t.TempDir(), t.TempDir()
+
+	files, err := GenerateCorpus(dir1, opts)
+	if err != nil {
+		t.Fatalf("GenerateCorpus failed: %v", err)
+	}
+	if _, err := GenerateCorpus(dir2, opts); err != nil {
+		t.Fatalf("GenerateCorpus failed: %v", err)
+	}
+
+	for _, f := range files {
+		a, _ := os.ReadFile(filepath.Join(dir1, f.Name))
+		b, _ := os.ReadFile(filepath.Join(dir2, f.Name))
+		if int64(len(a)) != f.Size {
+			t.Errorf("%s is %d bytes, want %d", f.Name, len(a), f.Size)
+		}
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s differs between runs with the same seed", f.Name)
+		}
+	}
+
+	opts.Seed = 43
+	dir3 := t.TempDir()
+	if _, err := GenerateCorpus(dir3, opts); err != nil {
+		t.Fatalf("GenerateCorpus failed: %v", err)
+	}
+	a, _ := os.ReadFile(filepath.Join(dir1, files[0].Name))
+	c, _ := os.ReadFile(filepath.Join(dir3, files[0].Name))
+	if bytes.Equal(a, c) {
+		t.Error("Different seeds should produce different content")
+	}
+}
+
+func TestGenerateCorpusCompressible(t *testing.T) {
+	ratio := func(compressible float64) float64 {
+		dir := t.TempDir()
+		files, err := GenerateCorpus(dir, GenerateOptions{TotalSize: 256 << 10, Files: 1, Compressible: compressible, Seed: 1})
+		if err != nil {
+			t.Fatalf("GenerateCorpus failed: %v", err)
+		}
+		content, _ := os.ReadFile(filepath.Join(dir, files[0].Name))
+
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(content)
+		zw.Close()
+		return float64(buf.Len()) / float64(len(content))
+	}
+
+	random, half := ratio(0), ratio(0.5)
+	if random < 0.99 {
+		t.Errorf("Random content compressed to %.2f, expected no gain", random)
+	}
+	if half > 0.75 {
+		t.Errorf("50%% compressible content compressed to %.2f, expected well below 0.75", half)
+	}
+}
+
+func TestWriteSparseFile(t *testing.T) {
+	dir := t.TempDir()
+	size := int64(2*SparseStride + 100)
+
+	path := filepath.Join(dir, "large.bin")
+	if err := WriteSparseFile(path, size, 7); err != nil {
+		t.Fatalf("WriteSparseFile failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != size {
+		t.Errorf("size = %d, want %d", info.Size(), size)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Count(content[SparseStride:SparseStride+blockSize], []byte{0}) == blockSize {
+		t.Error("the block at the second stride is empty")
+	}
+	if bytes.Count(content[blockSize:SparseStride], []byte{0}) != SparseStride-blockSize {
+		t.Error("the hole between the first two blocks is not zeros")
+	}
+
+	// The same seed writes the same file, another seed another
+	again := filepath.Join(dir, "again.bin")
+	other := filepath.Join(dir, "other.bin")
+	if err := WriteSparseFile(again, size, 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSparseFile(other, size, 8); err != nil {
+		t.Fatal(err)
+	}
+	againContent, _ := os.ReadFile(again)
+	otherContent, _ := os.ReadFile(other)
+	if !bytes.Equal(content, againContent) || bytes.Equal(content, otherContent) {
+		t.Error("content does not follow the seed")
+	}
+
+	if err := WriteSparseFile(filepath.Join(dir, "empty.bin"), 0, 1); err == nil {
+		t.Error("WriteSparseFile should reject a size of 0")
+	}
+}
@@ -0,0 +1,144 @@
+package timing
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Limits constrains the CPU, I/O and memory of the commands Run starts, so an evaluation
+// on a shared machine does not starve other workloads, and runs with limited resources
+// can be repeated. Child processes such as git-lfs inherit the limits of git.
+type Limits struct {
+	Nice int `json:"nice,omitempty"` // Niceness, 1 to 19; 0 leaves the priority alone
+	// IOClass is the ionice scheduling class: "idle" or "best-effort"; empty leaves it alone
+	IOClass string `json:"io_class,omitempty"`
+	// IOLevel is the best-effort priority, 0 (highest) to 7 (lowest)
+	IOLevel int `json:"io_level,omitempty"`
+	// CPUQuota caps the CPU time of each command in CPUs, e.g. 1.5; 0 means no cap
+	CPUQuota float64 `json:"cpu_quota,omitempty"`
+	// MemoryMax caps the memory of each command in bytes; 0 means no cap
+	MemoryMax int64 `json:"memory_max,omitempty"`
+}
+
+// IsZero reports whether l sets no limit
+func (l *Limits) IsZero() bool {
+	return l == nil || (l.Nice == 0 && l.IOClass == "" && l.CPUQuota == 0 && l.MemoryMax == 0)
+}
+
+// UsesCgroup reports whether l needs a cgroup, which systemd-run creates
+func (l *Limits) UsesCgroup() bool {
+	return l != nil && (l.CPUQuota > 0 || l.MemoryMax > 0)
+}
+
+// Validate reports the first limit out of range
+func (l *Limits) Validate() error {
+	if l.Nice < 0 || l.Nice > 19 {
+		return fmt.Errorf("niceness must be between 0 and 19, got %d", l.Nice)
+	}
+	switch l.IOClass {
+	case "", "idle", "best-effort":
+	default:
+		return fmt.Errorf("unknown I/O class '%s' (use idle or best-effort)", l.IOClass)
+	}
+	if l.IOLevel < 0 || l.IOLevel > 7 {
+		return fmt.Errorf("I/O priority must be between 0 and 7, got %d", l.IOLevel)
+	}
+	if l.CPUQuota < 0 {
+		return fmt.Errorf("CPU quota must be positive, got %g", l.CPUQuota)
+	}
+	if l.MemoryMax < 0 {
+		return fmt.Errorf("memory limit must be positive, got %d", l.MemoryMax)
+	}
+	return nil
+}
+
+// CheckTools reports a missing program that l needs: nice, ionice (util-linux) or
+// systemd-run (a systemd user session)
+func (l *Limits) CheckTools() error {
+	for _, tool := range l.tools() {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("%s is needed to apply the resource limits but was not found in PATH", tool)
+		}
+	}
+	if l.UsesCgroup() {
+		cmd := exec.Command("systemd-run", "--user", "--scope", "--quiet", "true")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("systemd-run cannot create a user scope for the CPU and memory limits: %s",
+				strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// tools returns the programs that wrap commands for l
+func (l *Limits) tools() []string {
+	var tools []string
+	if l.UsesCgroup() {
+		tools = append(tools, "systemd-run")
+	}
+	if l.IOClass != "" {
+		tools = append(tools, "ionice")
+	}
+	if l.Nice > 0 {
+		tools = append(tools, "nice")
+	}
+	return tools
+}
+
+// Wrap returns the command line that runs command with args under l. Each wrapper
+// execs the next, so stopping the returned command stops command itself.
+func (l *Limits) Wrap(command string, args []string) (string, []string) {
+	if l.IsZero() {
+		return command, args
+	}
+
+	var line []string
+	if l.UsesCgroup() {
+		line = append(line, "systemd-run", "--user", "--scope", "--quiet", "--collect")
+		if l.CPUQuota > 0 {
+			line = append(line, "-p", fmt.Sprintf("CPUQuota=%d%%", int(l.CPUQuota*100+0.5)))
+		}
+		if l.MemoryMax > 0 {
+			line = append(line, "-p", "MemoryMax="+strconv.FormatInt(l.MemoryMax, 10))
+		}
+		line = append(line, "--")
+	}
+	switch l.IOClass {
+	case "idle":
+		line = append(line, "ionice", "-c", "3")
+	case "best-effort":
+		line = append(line, "ionice", "-c", "2", "-n", strconv.Itoa(l.IOLevel))
+	}
+	if l.Nice > 0 {
+		line = append(line, "nice", "-n", strconv.Itoa(l.Nice))
+	}
+
+	line = append(line, command)
+	return line[0], append(line[1:], args...)
+}
+
+// String describes l for run notes, e.g. "nice 10, ionice idle, 1.5 CPUs, 2147483648 bytes of memory"
+func (l *Limits) String() string {
+	if l.IsZero() {
+		return "none"
+	}
+	var parts []string
+	if l.Nice > 0 {
+		parts = append(parts, fmt.Sprintf("nice %d", l.Nice))
+	}
+	switch l.IOClass {
+	case "idle":
+		parts = append(parts, "ionice idle")
+	case "best-effort":
+		parts = append(parts, fmt.Sprintf("ionice best-effort %d", l.IOLevel))
+	}
+	if l.CPUQuota > 0 {
+		parts = append(parts, fmt.Sprintf("%g CPUs", l.CPUQuota))
+	}
+	if l.MemoryMax > 0 {
+		parts = append(parts, fmt.Sprintf("%d bytes of memory", l.MemoryMax))
+	}
+	return strings.Join(parts, ", ")
+}
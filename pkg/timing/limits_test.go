@@ -0,0 +1,71 @@
+package timing
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLimitsWrap(t *testing.T) {
+	var none *Limits
+	if cmd, args := none.Wrap("git", []string{"push"}); cmd != "git" || len(args) != 1 {
+		t.Errorf("Wrap without limits = %s %v", cmd, args)
+	}
+
+	limits := &Limits{Nice: 10, IOClass: "best-effort", IOLevel: 7, CPUQuota: 1.5, MemoryMax: 1 << 30}
+	cmd, args := limits.Wrap("git", []string{"push", "origin"})
+	want := "systemd-run --user --scope --quiet --collect -p CPUQuota=150% -p MemoryMax=1073741824 -- " +
+		"ionice -c 2 -n 7 nice -n 10 git push origin"
+	if got := cmd + " " + strings.Join(args, " "); got != want {
+		t.Errorf("Wrap = %s\nwant %s", got, want)
+	}
+
+	cmd, args = (&Limits{IOClass: "idle"}).Wrap("git", nil)
+	if got := cmd + " " + strings.Join(args, " "); got != "ionice -c 3 git" {
+		t.Errorf("Wrap with an idle I/O class = %s", got)
+	}
+
+	if got := limits.String(); got != "nice 10, ionice best-effort 7, 1.5 CPUs, 1073741824 bytes of memory" {
+		t.Errorf("String() = %s", got)
+	}
+}
+
+func TestLimitsValidate(t *testing.T) {
+	for _, limits := range []*Limits{
+		{Nice: 20},
+		{IOClass: "realtime"},
+		{IOClass: "best-effort", IOLevel: 8},
+		{CPUQuota: -1},
+		{MemoryMax: -1},
+	} {
+		if err := limits.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should fail", limits)
+		}
+	}
+	if err := (&Limits{Nice: 19, IOClass: "idle", CPUQuota: 0.5}).Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+}
+
+func TestRunWithLimits(t *testing.T) {
+	if _, err := exec.LookPath("nice"); err != nil {
+		t.Skip("nice not installed")
+	}
+	base := Run("nice", nil, nil)
+	niceness, err := strconv.Atoi(strings.TrimSpace(base.Stdout))
+	if err != nil || niceness > 14 {
+		t.Skipf("cannot lower the priority further from %q", base.Stdout)
+	}
+
+	result := Run("nice", nil, &Options{Limits: &Limits{Nice: 5}})
+	if !result.Success() {
+		t.Fatalf("Run failed: %v", result.Error)
+	}
+	if got := strings.TrimSpace(result.Stdout); got != strconv.Itoa(niceness+5) {
+		t.Errorf("niceness under the limits = %s, want %d", got, niceness+5)
+	}
+	if result.Command != "nice" || len(result.Args) != 0 {
+		t.Errorf("the result should name the command, not its wrappers: %s %v", result.Command, result.Args)
+	}
+}
@@ -3,11 +3,22 @@ package timing
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"time"
 )
 
+// ErrDeadline is the error of a command that was still running when the deadline in its
+// Options passed
+var ErrDeadline = errors.New("deadline exceeded")
+
+// ErrCancelled is the error of a command that was still running when the context in its
+// Options was cancelled
+var ErrCancelled = errors.New("cancelled")
+
 // Result contains the results of a timed command execution
 type Result struct {
 	Command    string
@@ -17,6 +28,7 @@ type Result struct {
 	Stderr     string
 	ExitCode   int
 	Error      error
+	Usage      *Usage // Resources the command used, when sampled (see Options.Sampling); nil otherwise
 }
 
 // Options configures command execution
@@ -24,6 +36,21 @@ type Options struct {
 	Dir     string        // Working directory
 	Timeout time.Duration // Command timeout (0 for no timeout)
 	Debug   bool          // Enable debug output
+	// Progress also receives standard error as the command writes it, e.g. for a progress display
+	Progress io.Writer
+	Env      []string // Environment variables added to the command's, as KEY=VALUE
+
+	// Context stops the command when it is cancelled; a command started after that fails
+	// at once. nil means none.
+	Context context.Context
+	// Deadline stops the command if it is still running then; a command started after it
+	// fails at once. The zero time means none.
+	Deadline time.Time
+	Limits   *Limits // Resource limits to start the command under; nil for none
+	// Sampling measures the resource usage of the command, sampling its process tree at
+	// this interval; 0 means none. Sampling needs /proc (Linux); elsewhere only the totals
+	// are measured.
+	Sampling time.Duration
 }
 
 // Run executes a command and measures its execution time with millisecond precision
@@ -37,30 +64,54 @@ func Run(command string, args []string, opts *Options) *Result {
 		Args:    args,
 	}
 
-	// Create context with timeout if specified
-	var ctx context.Context
+	// Create context with timeout if specified, under the context of the options
+	base := opts.Context
+	if base == nil {
+		base = context.Background()
+	}
+	ctx := base
 	var cancel context.CancelFunc
 	if opts.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(context.Background(), opts.Timeout)
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	stopAt := opts.Deadline
+	if !stopAt.IsZero() {
+		ctx, cancel = context.WithDeadline(ctx, stopAt)
 		defer cancel()
-	} else {
-		ctx = context.Background()
 	}
 
-	// Create command
-	cmd := exec.CommandContext(ctx, command, args...)
+	// Create command, under the limits of the options
+	name, argv := opts.Limits.Wrap(command, args)
+	cmd := exec.CommandContext(ctx, name, argv...)
 	if opts.Dir != "" {
 		cmd.Dir = opts.Dir
 	}
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
 
 	// Capture stdout and stderr
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	if opts.Progress != nil {
+		cmd.Stderr = io.MultiWriter(&stderr, opts.Progress)
+	}
 
 	// Time the execution
 	start := time.Now()
-	err := cmd.Run()
+	var err error
+	if interval := opts.Sampling; interval > 0 {
+		err = cmd.Start()
+		if err == nil {
+			s := startSampler(cmd.Process.Pid, interval)
+			err = cmd.Wait()
+			result.Usage = s.finish(cmd.ProcessState)
+		}
+	} else {
+		err = cmd.Run()
+	}
 	duration := time.Since(start)
 
 	result.DurationMs = duration.Milliseconds()
@@ -69,6 +120,12 @@ func Run(command string, args []string, opts *Options) *Result {
 
 	if err != nil {
 		result.Error = err
+		switch {
+		case base.Err() != nil:
+			result.Error = fmt.Errorf("%w: %v", ErrCancelled, err)
+		case !stopAt.IsZero() && !time.Now().Before(stopAt):
+			result.Error = fmt.Errorf("%w: %v", ErrDeadline, err)
+		}
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			result.ExitCode = exitErr.ExitCode()
 		} else {
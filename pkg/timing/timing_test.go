@@ -1,7 +1,10 @@
 package timing
 
 import (
+	"context"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -101,6 +104,20 @@ func TestRun_StderrCapture(t *testing.T) {
 	}
 }
 
+func TestRun_ProgressAndEnv(t *testing.T) {
+	// Standard error reaches the progress writer as well as the result
+	var progress strings.Builder
+	opts := &Options{Progress: &progress, Env: []string{"LFST_TEST_VALUE=42"}}
+	result := Run("sh", []string{"-c", "echo value=$LFST_TEST_VALUE >&2"}, opts)
+
+	if !result.Success() {
+		t.Fatalf("Run failed: %v", result.Error)
+	}
+	if result.Stderr != "value=42\n" || progress.String() != result.Stderr {
+		t.Errorf("Stderr = %q, progress got %q; want both value=42", result.Stderr, progress.String())
+	}
+}
+
 func TestRun_Timing(t *testing.T) {
 	// Run a command that takes a known amount of time
 	start := time.Now()
@@ -245,3 +262,51 @@ func TestRun_NilArgs(t *testing.T) {
 		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
 	}
 }
+
+func TestRun_Deadline(t *testing.T) {
+	opts := &Options{Deadline: time.Now().Add(200 * time.Millisecond)}
+
+	// A command still running at the deadline is stopped
+	result := Run("sleep", []string{"10"}, opts)
+	if !errors.Is(result.Error, ErrDeadline) {
+		t.Errorf("Error = %v, want ErrDeadline", result.Error)
+	}
+	if result.DurationMs > 5000 {
+		t.Errorf("sleep ran %dms; it should have been stopped at the deadline", result.DurationMs)
+	}
+
+	// Commands started after the deadline fail at once
+	if result := Run("echo", []string{"late"}, opts); !errors.Is(result.Error, ErrDeadline) {
+		t.Errorf("Error after the deadline = %v, want ErrDeadline", result.Error)
+	}
+
+	// The deadline applies only to the commands given it
+	if result := Run("echo", []string{"again"}, nil); result.Error != nil {
+		t.Errorf("Run without the deadline failed: %v", result.Error)
+	}
+}
+
+func TestRun_Context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := &Options{Context: ctx}
+
+	// A command still running when the context is cancelled is stopped
+	time.AfterFunc(200*time.Millisecond, cancel)
+	result := Run("sleep", []string{"10"}, opts)
+	if !errors.Is(result.Error, ErrCancelled) {
+		t.Errorf("Error = %v, want ErrCancelled", result.Error)
+	}
+	if result.DurationMs > 5000 {
+		t.Errorf("sleep ran %dms; it should have been stopped when the context was cancelled", result.DurationMs)
+	}
+
+	// Commands started later fail at once
+	if result := Run("echo", []string{"late"}, opts); !errors.Is(result.Error, ErrCancelled) {
+		t.Errorf("Error after cancelling = %v, want ErrCancelled", result.Error)
+	}
+
+	// The context applies only to the commands given it
+	if result := Run("echo", []string{"again"}, nil); result.Error != nil {
+		t.Errorf("Run without the context failed: %v", result.Error)
+	}
+}
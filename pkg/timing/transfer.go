@@ -0,0 +1,102 @@
+package timing
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Transfer summarizes what a git or git-lfs command reported moving or changing
+type Transfer struct {
+	LFSObjects   int   // LFS objects uploaded, downloaded or filtered
+	LFSBytes     int64 // Bytes of those LFS objects
+	GitObjects   int   // Git objects written (push) or received (clone, fetch)
+	GitBytes     int64 // Size of the git pack sent or received
+	FilesChanged int   // Files changed by a commit
+}
+
+var (
+	// e.g. "Uploading LFS objects: 100% (3/3), 15 MB | 2.1 MB/s, done."
+	// and  "Filtering content: 100% (2/2), 1.51 MiB | 0 B/s, done."
+	lfsProgressRE = regexp.MustCompile(`(?:Uploading|Downloading) LFS objects:\s+\d+% \((\d+)/\d+\), ([\d.]+) ([KMGT]?i?B)|Filtering content:\s+\d+% \((\d+)/\d+\), ([\d.]+) ([KMGT]?i?B)`)
+	// e.g. "Writing objects: 100% (5/5), 1.23 KiB | 1.23 MiB/s, done."
+	gitProgressRE = regexp.MustCompile(`(?:Writing|Receiving) objects:\s+\d+% \((\d+)/\d+\)(?:, ([\d.]+) (bytes|[KMGT]iB))?`)
+	// e.g. " 3 files changed, 10 insertions(+)"
+	filesChangedRE = regexp.MustCompile(`(\d+) files? changed`)
+)
+
+// ParseTransfer extracts transfer statistics from git and git-lfs output.
+// Progress meters rewrite their line with \r, so the last report of each kind wins.
+func ParseTransfer(output string) Transfer {
+	var t Transfer
+
+	if m := lastMatch(lfsProgressRE, output); m != nil {
+		if m[1] == "" {
+			m = append(m[:1], m[4:]...) // Filtering content alternative
+		}
+		t.LFSObjects, _ = strconv.Atoi(m[1])
+		t.LFSBytes = ParseBytes(m[2], m[3])
+	}
+
+	if m := lastMatch(gitProgressRE, output); m != nil {
+		t.GitObjects, _ = strconv.Atoi(m[1])
+		if m[2] != "" {
+			t.GitBytes = ParseBytes(m[2], m[3])
+		}
+	}
+
+	if m := lastMatch(filesChangedRE, output); m != nil {
+		t.FilesChanged, _ = strconv.Atoi(m[1])
+	}
+
+	return t
+}
+
+// FileCount returns the number of files the command acted on, if it reported one
+func (t Transfer) FileCount() (int, bool) {
+	switch {
+	case t.LFSObjects > 0:
+		return t.LFSObjects, true
+	case t.FilesChanged > 0:
+		return t.FilesChanged, true
+	}
+	return 0, false
+}
+
+// TotalBytes returns the bytes transferred by git and git-lfs together, if any were reported
+func (t Transfer) TotalBytes() (int64, bool) {
+	total := t.LFSBytes + t.GitBytes
+	return total, total > 0
+}
+
+// lastMatch returns the submatches of the last occurrence of re in s, or nil
+func lastMatch(re *regexp.Regexp, s string) []string {
+	all := re.FindAllStringSubmatch(s, -1)
+	if len(all) == 0 {
+		return nil
+	}
+	return all[len(all)-1]
+}
+
+// ParseBytes converts a value and unit as printed by git ("bytes", "KiB") or
+// git-lfs ("MB" decimal, "MiB" binary) into bytes
+func ParseBytes(value, unit string) int64 {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+
+	base := 1000.0
+	if strings.Contains(unit, "i") {
+		base = 1024
+	}
+
+	multiplier := 1.0
+	if unit != "" && unit != "B" && unit != "bytes" {
+		exp := strings.IndexByte("KMGT", unit[0]) + 1
+		for i := 0; i < exp; i++ {
+			multiplier *= base
+		}
+	}
+	return int64(v * multiplier)
+}
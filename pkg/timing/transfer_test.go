@@ -0,0 +1,57 @@
+package timing
+
+import "testing"
+
+func TestParseTransfer(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   Transfer
+	}{
+		{
+			name: "lfs push",
+			output: "Uploading LFS objects:  33% (1/3), 5.0 MB | 2.1 MB/s\r" +
+				"Uploading LFS objects: 100% (3/3), 15 MB | 2.1 MB/s, done.\n" +
+				"Enumerating objects: 5, done.\n" +
+				"Writing objects:  40% (2/5)\rWriting objects: 100% (5/5), 1.50 KiB | 1.50 MiB/s, done.\n",
+			want: Transfer{LFSObjects: 3, LFSBytes: 15000000, GitObjects: 5, GitBytes: 1536},
+		},
+		{
+			name:   "clone with smudge",
+			output: "Receiving objects: 100% (12/12), 250 bytes | 250.00 KiB/s, done.\nFiltering content: 100% (2/2), 1.50 MiB | 0 B/s, done.\n",
+			want:   Transfer{LFSObjects: 2, LFSBytes: 1572864, GitObjects: 12, GitBytes: 250},
+		},
+		{
+			name:   "commit",
+			output: "[master (root-commit) 1a2b3c4] Initial\n 4 files changed, 12 insertions(+)\n",
+			want:   Transfer{FilesChanged: 4},
+		},
+		{
+			name:   "no progress",
+			output: "Everything up-to-date\n",
+			want:   Transfer{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseTransfer(tt.output); got != tt.want {
+				t.Errorf("ParseTransfer() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferTotals(t *testing.T) {
+	tr := Transfer{LFSObjects: 3, LFSBytes: 1000, GitObjects: 5, GitBytes: 24, FilesChanged: 4}
+	if n, ok := tr.FileCount(); !ok || n != 3 {
+		t.Errorf("FileCount() = %d, %v; want 3 LFS objects", n, ok)
+	}
+	if n, ok := tr.TotalBytes(); !ok || n != 1024 {
+		t.Errorf("TotalBytes() = %d, %v; want 1024", n, ok)
+	}
+
+	if _, ok := (Transfer{}).TotalBytes(); ok {
+		t.Error("Expected no total for an empty transfer")
+	}
+}
@@ -0,0 +1,183 @@
+package timing
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Usage is the CPU time, memory and disk I/O of a command and its child processes
+// (e.g. git-lfs started by git). Totals come from the operating system when the command
+// exits; the peaks and the average memory come from sampling the process tree.
+type Usage struct {
+	CPUMs          int64   // User and system CPU time
+	PeakCPUPercent float64 // Highest CPU use between two samples; 100 is one full CPU
+	PeakRSSBytes   int64   // Highest resident memory of the process tree at once
+	AvgRSSBytes    int64   // Mean resident memory of the process tree over the samples
+	ReadBytes      int64   // Bytes read from storage
+	WriteBytes     int64   // Bytes written to storage
+	Samples        int     // Samples taken while the command ran
+}
+
+// clockTicks is the unit of the CPU times in /proc/PID/stat (USER_HZ), 100 on every
+// mainstream Linux architecture
+const clockTicks = 100
+
+// procStat is what sampling reads from /proc/PID/stat
+type procStat struct {
+	ppid     int
+	cpuTicks int64 // utime + stime + cutime + cstime
+	rssPages int64
+}
+
+// parseProcStat reads the fields of a /proc/PID/stat line. The command name in
+// parentheses may contain spaces, so the fields are counted from its closing parenthesis.
+func parseProcStat(line string) (procStat, bool) {
+	end := strings.LastIndexByte(line, ')')
+	if end < 0 {
+		return procStat{}, false
+	}
+	// Fields after the name start with field 3, state
+	fields := strings.Fields(line[end+1:])
+	if len(fields) < 22 {
+		return procStat{}, false
+	}
+	var s procStat
+	var err error
+	if s.ppid, err = strconv.Atoi(fields[1]); err != nil {
+		return procStat{}, false
+	}
+	for _, i := range []int{11, 12, 13, 14} { // utime, stime, cutime, cstime
+		n, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil {
+			return procStat{}, false
+		}
+		s.cpuTicks += n
+	}
+	if s.rssPages, err = strconv.ParseInt(fields[21], 10, 64); err != nil {
+		return procStat{}, false
+	}
+	return s, true
+}
+
+// readProcesses returns the stat of every process in /proc by PID
+func readProcesses() map[int]procStat {
+	paths, _ := filepath.Glob("/proc/[0-9]*/stat")
+	procs := make(map[int]procStat, len(paths))
+	for _, path := range paths {
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(path)))
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // The process exited
+		}
+		if s, ok := parseProcStat(string(data)); ok {
+			procs[pid] = s
+		}
+	}
+	return procs
+}
+
+// treeTotals sums the CPU ticks and resident pages of root and its descendants
+func treeTotals(procs map[int]procStat, root int) (cpuTicks, rssPages int64) {
+	children := make(map[int][]int)
+	for pid, s := range procs {
+		children[s.ppid] = append(children[s.ppid], pid)
+	}
+	queue := []int{root}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		if s, ok := procs[pid]; ok {
+			cpuTicks += s.cpuTicks
+			rssPages += s.rssPages
+		}
+		queue = append(queue, children[pid]...)
+	}
+	return cpuTicks, rssPages
+}
+
+// sampler samples the process tree of a running command
+type sampler struct {
+	stop chan struct{}
+	done chan struct{}
+
+	usage    Usage
+	rssTotal int64
+}
+
+// startSampler samples the process tree of pid every interval until stop is called
+func startSampler(pid int, interval time.Duration) *sampler {
+	s := &sampler{stop: make(chan struct{}), done: make(chan struct{})}
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		close(s.done)
+		return s
+	}
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		pageSize := int64(os.Getpagesize())
+		var lastTicks int64
+		last := time.Now()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case now := <-ticker.C:
+				ticks, pages := treeTotals(readProcesses(), pid)
+				if pages == 0 {
+					continue // Not started yet, or already exited
+				}
+				rss := pages * pageSize
+				s.usage.Samples++
+				s.rssTotal += rss
+				s.usage.PeakRSSBytes = max(s.usage.PeakRSSBytes, rss)
+				if s.usage.Samples > 1 && ticks > lastTicks {
+					cpu := float64(ticks-lastTicks) / clockTicks / now.Sub(last).Seconds() * 100
+					s.usage.PeakCPUPercent = max(s.usage.PeakCPUPercent, cpu)
+				}
+				lastTicks, last = ticks, now
+			}
+		}
+	}()
+	return s
+}
+
+// finish stops sampling and completes the usage with the totals of the exited command
+func (s *sampler) finish(state *os.ProcessState) *Usage {
+	close(s.stop)
+	<-s.done
+
+	usage := s.usage
+	if usage.Samples > 0 {
+		usage.AvgRSSBytes = s.rssTotal / int64(usage.Samples)
+	}
+	if state == nil {
+		return &usage
+	}
+
+	usage.CPUMs = (state.UserTime() + state.SystemTime()).Milliseconds()
+	if ru, ok := state.SysUsage().(*syscall.Rusage); ok {
+		// The largest single process is a lower bound for short commands sampling missed
+		maxRSS := int64(ru.Maxrss)
+		if runtime.GOOS == "linux" {
+			maxRSS *= 1024 // Kilobytes on Linux, bytes on macOS
+		}
+		usage.PeakRSSBytes = max(usage.PeakRSSBytes, maxRSS)
+		usage.ReadBytes = int64(ru.Inblock) * 512
+		usage.WriteBytes = int64(ru.Oublock) * 512
+	}
+	if usage.Samples == 0 {
+		usage.AvgRSSBytes = usage.PeakRSSBytes
+	}
+	return &usage
+}
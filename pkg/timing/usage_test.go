@@ -0,0 +1,57 @@
+package timing
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	line := "4242 (git-lfs (x) y) S 4200 4242 4200 0 -1 4194560 9000 0 0 0 150 30 7 3 20 0 12 0 1000 800000000 5120 18446744073709551615"
+	s, ok := parseProcStat(line)
+	if !ok {
+		t.Fatal("parseProcStat failed")
+	}
+	if s.ppid != 4200 || s.cpuTicks != 190 || s.rssPages != 5120 {
+		t.Errorf("parseProcStat = %+v, want ppid 4200, 190 ticks and 5120 pages", s)
+	}
+
+	if _, ok := parseProcStat("4242 (git) S 1"); ok {
+		t.Error("parseProcStat should reject a truncated line")
+	}
+}
+
+func TestTreeTotals(t *testing.T) {
+	procs := map[int]procStat{
+		10: {ppid: 1, cpuTicks: 5, rssPages: 100},
+		11: {ppid: 10, cpuTicks: 7, rssPages: 200}, // git-lfs started by git
+		12: {ppid: 11, cpuTicks: 1, rssPages: 10},
+		20: {ppid: 1, cpuTicks: 99, rssPages: 999}, // Unrelated
+	}
+	ticks, pages := treeTotals(procs, 10)
+	if ticks != 13 || pages != 310 {
+		t.Errorf("treeTotals = %d ticks, %d pages; want 13 and 310", ticks, pages)
+	}
+}
+
+func TestRunWithSampling(t *testing.T) {
+	opts := &Options{Sampling: 10 * time.Millisecond}
+	result := Run("sh", []string{"-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done; sleep 0.1"}, opts)
+	if !result.Success() {
+		t.Fatalf("Run failed: %v", result.Error)
+	}
+	u := result.Usage
+	if u == nil {
+		t.Fatal("Run with sampling should measure the usage")
+	}
+	if u.PeakRSSBytes <= 0 || u.AvgRSSBytes <= 0 || u.AvgRSSBytes > u.PeakRSSBytes {
+		t.Errorf("Usage = %+v, want a positive average memory no higher than the peak", u)
+	}
+	if _, err := os.Stat("/proc/self/stat"); err == nil && u.Samples == 0 {
+		t.Errorf("Usage = %+v, want samples of a 100ms command", u)
+	}
+
+	if result := Run("true", nil, nil); result.Usage != nil {
+		t.Errorf("Run without sampling measured %+v", result.Usage)
+	}
+}
@@ -0,0 +1,119 @@
+package workerpool
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Options controls how a pool processes files
+type Options struct {
+	Workers  int       // Number of concurrent workers; 0 means one per CPU
+	Progress io.Writer // Where to report progress; nil disables reporting
+}
+
+// workers returns the effective worker count for n items
+func (o Options) workers(n int) int {
+	w := o.Workers
+	if w <= 0 {
+		w = runtime.NumCPU()
+	}
+	if w > n {
+		w = n
+	}
+	return w
+}
+
+// Run calls fn for each index in [0, n) using up to opts.Workers goroutines.
+// All items are processed even if some fail; the error of the lowest failing index is returned.
+func Run(n int, opts Options, fn func(i int) error) error {
+	if n == 0 {
+		return nil
+	}
+
+	errs := make([]error, n)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < opts.workers(n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Progress reports how many items and bytes a pool has processed.
+// It is safe for concurrent use; a nil *Progress ignores all calls.
+type Progress struct {
+	w          io.Writer
+	label      string
+	total      int
+	totalBytes int64
+
+	mu        sync.Mutex
+	done      int
+	bytes     int64
+	lastPrint time.Time
+}
+
+// NewProgress creates a progress reporter, or returns nil if w is nil
+func NewProgress(w io.Writer, label string, total int, totalBytes int64) *Progress {
+	if w == nil {
+		return nil
+	}
+	return &Progress{w: w, label: label, total: total, totalBytes: totalBytes}
+}
+
+// Add records one finished item of the given size, printing at most a few updates per second
+func (p *Progress) Add(bytes int64) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	p.bytes += bytes
+	if p.done == p.total || time.Since(p.lastPrint) >= 250*time.Millisecond {
+		p.print()
+		p.lastPrint = time.Now()
+	}
+}
+
+// Finish ends the progress line
+func (p *Progress) Finish() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.print()
+	fmt.Fprintln(p.w)
+}
+
+// print writes the current progress over the previous line
+func (p *Progress) print() {
+	fmt.Fprintf(p.w, "\r  %s: %d/%d files (%.1f/%.1f MB)", p.label, p.done, p.total,
+		float64(p.bytes)/1024/1024, float64(p.totalBytes)/1024/1024)
+}
@@ -0,0 +1,57 @@
+package workerpool
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunProcessesEveryItem(t *testing.T) {
+	var sum int64
+	err := Run(100, Options{Workers: 4}, func(i int) error {
+		atomic.AddInt64(&sum, int64(i))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if sum != 4950 {
+		t.Errorf("Sum = %d, want 4950", sum)
+	}
+}
+
+func TestRunReturnsLowestError(t *testing.T) {
+	var calls int64
+	err := Run(10, Options{Workers: 3}, func(i int) error {
+		atomic.AddInt64(&calls, 1)
+		if i == 7 || i == 3 {
+			return fmt.Errorf("item %d failed", i)
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "item 3 failed" {
+		t.Errorf("Error = %v, want item 3 failed", err)
+	}
+	if calls != 10 {
+		t.Errorf("Calls = %d, want all 10 items processed", calls)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewProgress(&buf, "Hashing", 2, 3*1024*1024)
+	p.Add(1024 * 1024)
+	p.Add(2 * 1024 * 1024)
+	p.Finish()
+
+	if !strings.Contains(buf.String(), "Hashing: 2/2 files (3.0/3.0 MB)") {
+		t.Errorf("Unexpected progress output: %q", buf.String())
+	}
+
+	// A nil reporter ignores calls
+	none := NewProgress(nil, "Hashing", 1, 0)
+	none.Add(1)
+	none.Finish()
+}